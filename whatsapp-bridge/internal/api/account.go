@@ -0,0 +1,126 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"whatsapp-bridge/internal/health"
+	"whatsapp-bridge/internal/tenant"
+	"whatsapp-bridge/internal/types"
+	"whatsapp-bridge/internal/whatsapp"
+)
+
+// healthHistory is how far back restrictions count against account health
+const healthHistory = 7 * 24 * time.Hour
+
+// handleAccountHealth handles GET /api/account/health: a heuristic 0-100 score
+// of how close recent sending is to getting the account restricted, built from
+// temporary bans and rate limits in the last week and send failures, identical
+// bulk texts, unsolicited recipients and recipients who appear to have blocked
+// the account in the last day. Restrictions apply to the whole account; the
+// send signals count only the calling tenant's sends, or every tenant's for
+// the operator.
+//
+// Response: { success: bool, data: AccountHealth }
+func (s *Server) handleAccountHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	owner := ""
+	if viewer := APIKeyName(r); !tenant.IsOperator(viewer) {
+		owner = viewer
+	}
+	sends, err := s.messageStore.GetSendStats(owner, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		SendJSONError(w, fmt.Sprintf("Failed to get send stats: %v", err), http.StatusInternalServerError)
+		return
+	}
+	restrictions, err := s.messageStore.GetAccountRestrictions(time.Now().Add(-healthHistory))
+	if err != nil {
+		SendJSONError(w, fmt.Sprintf("Failed to get account restrictions: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	signals := types.AccountHealthSignals{Sends: sends}
+	for _, restriction := range restrictions {
+		switch restriction.Reason {
+		case whatsapp.RestrictionTemporaryBan:
+			signals.TemporaryBans++
+		case whatsapp.RestrictionRateLimited:
+			signals.RateLimits++
+		}
+	}
+
+	var current *types.AccountRestriction
+	if restriction, ok := s.client.Restriction(); ok {
+		current = &restriction
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    health.Score(signals, current),
+	})
+}
+
+// handleBlocklist handles GET /api/blocklist for the contacts the account has
+// blocked and the recent history of blocks and unblocks.
+//
+// Query parameters:
+//   - since: RFC3339 time to list changes from (optional, defaults to 30 days ago)
+//   - limit: Maximum changes to return (default 100, max 1000)
+//
+// Response: { success: bool, data: { blocked: BlockedContact[], changes: BlocklistChange[] } }
+func (s *Server) handleBlocklist(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	since := time.Now().AddDate(0, 0, -30)
+	if v := r.URL.Query().Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			SendJSONError(w, "since must be an RFC3339 time", http.StatusBadRequest)
+			return
+		}
+		since = t
+	}
+
+	limit := 100
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 1000 {
+			SendJSONError(w, "limit must be between 1 and 1000", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	blocked, err := s.messageStore.GetBlockedContacts()
+	if err != nil {
+		SendJSONError(w, fmt.Sprintf("Failed to get blocked contacts: %v", err), http.StatusInternalServerError)
+		return
+	}
+	changes, err := s.messageStore.GetBlocklistChanges(since, limit)
+	if err != nil {
+		SendJSONError(w, fmt.Sprintf("Failed to get blocklist changes: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data": map[string]interface{}{
+			"blocked": blocked,
+			"changes": changes,
+		},
+	})
+}
@@ -0,0 +1,309 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"whatsapp-bridge/internal/approval"
+	"whatsapp-bridge/internal/database"
+	"whatsapp-bridge/internal/tenant"
+	"whatsapp-bridge/internal/types"
+)
+
+// Held send listing limits
+const (
+	defaultApprovalLimit = 50
+	maxApprovalLimit     = 500
+)
+
+// maxHeldBody caps the request body kept for a held send
+const maxHeldBody = 1 << 20
+
+// SetApprovals holds sends made with the configured keys for approval, and
+// enables /api/approvals and /api/settings/approvals
+func (s *Server) SetApprovals(queue *approval.Queue) {
+	s.approvals = queue
+	queue.SetExecutor(s.executeApproved)
+}
+
+// holdForApproval holds POSTs to a send endpoint made with a key that needs
+// approval, instead of passing them to next: 202 with { success, status:
+// "pending_approval", approval_id, recipient, expires_at }; follow them with
+// GET /api/approvals. Once approved the request is run by next as it was
+// made. Every send endpoint goes through it (see sendRoutes), so no endpoint
+// lets a held key send without approval.
+func (s *Server) holdForApproval(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		keyName := APIKeyName(r)
+		if r.Method != http.MethodPost || s.approvals == nil || !s.approvals.Required(keyName) {
+			next(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxHeldBody+1))
+		if err != nil || len(body) > maxHeldBody {
+			SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+		req, err := heldRequest(route, body)
+		if err != nil {
+			SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+
+		held, err := s.approvals.Hold(keyName, route, req, string(body))
+		if err != nil {
+			SendJSONError(w, fmt.Sprintf("Failed to hold send for approval: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":     true,
+			"status":      "pending_approval",
+			"approval_id": held.ID,
+			"recipient":   held.Recipient,
+			"expires_at":  held.ExpiresAt,
+		})
+	}
+}
+
+// heldRequest reads a held request body as an /api/send request. For other
+// routes only the recipient is kept, from whichever field the route names
+// it in.
+func heldRequest(route string, body []byte) (types.SendMessageRequest, error) {
+	var req types.SendMessageRequest
+	if route == approval.SendRoute {
+		err := json.Unmarshal(body, &req)
+		return req, err
+	}
+
+	var target struct {
+		Recipient string `json:"recipient"`
+		ChatJID   string `json:"chat_jid"`
+		JID       string `json:"jid"`
+	}
+	if err := json.Unmarshal(body, &target); err != nil {
+		return req, err
+	}
+	for _, r := range []string{target.Recipient, target.ChatJID, target.JID} {
+		if r != "" {
+			req.Recipient = r
+			break
+		}
+	}
+	return req, nil
+}
+
+// executeApproved runs a send held for approval once it is approved, by
+// replaying the request to the endpoint it was made to
+func (s *Server) executeApproved(ctx context.Context, a *types.PendingApproval) (types.SendResult, error) {
+	route, body := a.Route, a.Body
+	if body == "" {
+		// Held before whole requests were kept; only /api/send held then
+		route = approval.SendRoute
+		data, err := json.Marshal(a.Request)
+		if err != nil {
+			return types.SendResult{}, err
+		}
+		body = string(data)
+	}
+	handler, ok := s.sendRoutes()[route]
+	if !ok {
+		return types.SendResult{}, fmt.Errorf("%s is not a send endpoint", route)
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, route, bytes.NewReader([]byte(body)))
+	if err != nil {
+		return types.SendResult{}, err
+	}
+	r.Header.Set("Content-Type", "application/json")
+	resp := &bufferedResponse{header: make(http.Header)}
+	handler(resp, r)
+
+	var out struct {
+		Success   bool   `json:"success"`
+		Message   string `json:"message"`
+		Error     string `json:"error"`
+		MessageID string `json:"message_id"`
+		ErrorCode string `json:"error_code"`
+		Retryable bool   `json:"retryable"`
+	}
+	if err := json.Unmarshal(resp.body.Bytes(), &out); err != nil {
+		return types.SendResult{}, fmt.Errorf("unreadable response from %s (HTTP %d)", route, resp.status)
+	}
+	result := types.SendResult{Success: out.Success, MessageID: out.MessageID, Code: out.ErrorCode, Retryable: out.Retryable}
+	if !out.Success {
+		result.Error = out.Error
+		if result.Error == "" {
+			result.Error = out.Message
+		}
+		if result.Error == "" {
+			result.Error = fmt.Sprintf("%s answered HTTP %d", route, resp.status)
+		}
+	}
+	return result, nil
+}
+
+// handleApprovalConfig handles GET/PUT /api/settings/approvals.
+//
+// PUT Request body (replaces the whole configuration):
+//   - enabled: boolean; while true requests to any send endpoint made with
+//     the listed keys are held until approved
+//   - keys: API key names whose sends need approval ("*" for all)
+//   - approvers: User JIDs asked about each held send over WhatsApp; they
+//     approve by reacting 👍 to the request and reject with 👎
+//   - expire_hours: Held sends nobody decides on expire after this long (default 24)
+//
+// Response: { success: bool, data: ApprovalConfig }
+func (s *Server) handleApprovalConfig(w http.ResponseWriter, r *http.Request) {
+	if s.approvals == nil {
+		SendJSONError(w, "Send approval is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    s.approvals.Config(),
+		})
+
+	case http.MethodPut:
+		var cfg types.ApprovalConfig
+		if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+			SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+
+		if err := approval.ValidateConfig(cfg); err != nil {
+			SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := s.messageStore.SetJSONSetting(database.SettingApprovals, cfg); err != nil {
+			SendJSONError(w, fmt.Sprintf("Failed to store approval config: %v", err), http.StatusInternalServerError)
+			return
+		}
+		_ = s.approvals.SetConfig(cfg)
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    s.approvals.Config(),
+		})
+
+	default:
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleApprovals handles GET /api/approvals for sends held for approval,
+// newest first. Tenants see their own; the operator sees every tenant's.
+//
+// Query parameters (all optional):
+//   - status: pending, rejected, expired, sent or failed
+//   - limit: Maximum held sends (default 50, max 500)
+//
+// Response: { success: bool, data: PendingApproval[] }
+func (s *Server) handleApprovals(w http.ResponseWriter, r *http.Request) {
+	if s.approvals == nil {
+		SendJSONError(w, "Send approval is not available", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != http.MethodGet {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := defaultApprovalLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxApprovalLimit {
+			SendJSONError(w, fmt.Sprintf("limit must be between 1 and %d", maxApprovalLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	owner := ""
+	if viewer := APIKeyName(r); !tenant.IsOperator(viewer) {
+		owner = viewer
+	}
+	approvals, err := s.approvals.List(r.URL.Query().Get("status"), owner, limit)
+	if err != nil {
+		SendJSONError(w, fmt.Sprintf("Failed to list held sends: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    approvals,
+	})
+}
+
+// handleApprovalDecision handles POST /api/approvals/approve and
+// /api/approvals/reject. Approving sends the held message and waits for it;
+// a send cannot be decided with the key that made it.
+//
+// Request body:
+//   - id: The held send (required)
+//   - reason: Why it was rejected (optional, reject only)
+//
+// Response: { success: bool, data: PendingApproval } with the send's outcome
+// in status, message_id and error
+func (s *Server) handleApprovalDecision(approve bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.approvals == nil {
+			SendJSONError(w, "Send approval is not available", http.StatusServiceUnavailable)
+			return
+		}
+		if r.Method != http.MethodPost {
+			SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			ID     int64  `json:"id"`
+			Reason string `json:"reason"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID <= 0 {
+			SendJSONError(w, "id is required", http.StatusBadRequest)
+			return
+		}
+
+		approver := approval.APIApprover(APIKeyName(r))
+		var held *types.PendingApproval
+		var err error
+		if approve {
+			held, err = s.approvals.Approve(r.Context(), req.ID, approver)
+		} else {
+			held, err = s.approvals.Reject(req.ID, approver, req.Reason)
+		}
+		switch {
+		case errors.Is(err, approval.ErrNotFound):
+			SendJSONError(w, err.Error(), http.StatusNotFound)
+			return
+		case errors.Is(err, approval.ErrSelfApproval):
+			SendJSONError(w, err.Error(), http.StatusForbidden)
+			return
+		case err != nil:
+			SendJSONError(w, err.Error(), http.StatusConflict)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    held,
+		})
+	}
+}
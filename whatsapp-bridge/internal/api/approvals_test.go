@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	waLog "go.mau.fi/whatsmeow/util/log"
+
+	"whatsapp-bridge/internal/approval"
+	"whatsapp-bridge/internal/database"
+	"whatsapp-bridge/internal/tenant"
+	"whatsapp-bridge/internal/types"
+)
+
+func TestHoldForApprovalOnEverySendRoute(t *testing.T) {
+	t.Chdir(t.TempDir())
+	store, err := database.NewMessageStore()
+	if err != nil {
+		t.Fatalf("NewMessageStore: %v", err)
+	}
+	defer store.Close()
+
+	queue := approval.New(store, nil, waLog.Noop)
+	if err := queue.SetConfig(types.ApprovalConfig{Enabled: true, Keys: []string{"marketing"}}); err != nil {
+		t.Fatalf("SetConfig: %v", err)
+	}
+	s := &Server{messageStore: store}
+	s.SetApprovals(queue)
+
+	post := func(route, key, body string) *httptest.ResponseRecorder {
+		sent := false
+		h := s.holdForApproval(route, func(w http.ResponseWriter, r *http.Request) {
+			sent = true
+			w.WriteHeader(http.StatusOK)
+		})
+		r := httptest.NewRequest(http.MethodPost, route, strings.NewReader(body))
+		r = r.WithContext(tenant.WithName(r.Context(), key))
+		rec := httptest.NewRecorder()
+		h(rec, r)
+		if sent != (rec.Code == http.StatusOK) {
+			t.Errorf("%s by %s: handler ran = %v with status %d", route, key, sent, rec.Code)
+		}
+		return rec
+	}
+
+	body := `{"recipient":"15550102030","chat_jid":"15550102030@s.whatsapp.net","message":"hi"}`
+	for route := range s.sendRoutes() {
+		rec := post(route, "marketing", body)
+		var resp struct {
+			Success    bool   `json:"success"`
+			Status     string `json:"status"`
+			ApprovalID int64  `json:"approval_id"`
+			Recipient  string `json:"recipient"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || rec.Code != http.StatusAccepted || resp.Status != "pending_approval" || resp.ApprovalID == 0 || resp.Recipient != "15550102030" {
+			t.Errorf("%s by a held key = %d %s", route, rec.Code, rec.Body.String())
+			continue
+		}
+
+		held, err := store.GetPendingApproval(resp.ApprovalID)
+		if err != nil || held == nil || held.Route != route || held.Body != body || held.Tenant != "marketing" {
+			t.Errorf("%s held as %+v, %v", route, held, err)
+		}
+
+		// Other keys send straight away
+		if rec := post(route, "support", body); rec.Code != http.StatusOK {
+			t.Errorf("%s by a key without approval = %d", route, rec.Code)
+		}
+	}
+
+	if rec := post("/api/poll", "marketing", "not json"); rec.Code != http.StatusBadRequest {
+		t.Errorf("unreadable held request = %d", rec.Code)
+	}
+}
+
+func TestExecuteApprovedReplaysRequest(t *testing.T) {
+	s := &Server{}
+	// Rejected by the endpoint's own checks, before anything is sent
+	result, err := s.executeApproved(context.Background(), &types.PendingApproval{Route: "/api/poll", Body: `{"chat_jid":"15550102030@s.whatsapp.net"}`})
+	if err != nil || result.Success || !strings.Contains(result.Error, "question") {
+		t.Errorf("replayed poll = %+v, %v", result, err)
+	}
+
+	if _, err := s.executeApproved(context.Background(), &types.PendingApproval{Route: "/api/settings/cors", Body: `{}`}); err == nil {
+		t.Error("replayed a request to an endpoint that does not send")
+	}
+}
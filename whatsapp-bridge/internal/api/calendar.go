@@ -0,0 +1,176 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"whatsapp-bridge/internal/ical"
+)
+
+// Calendar event listing limits
+const (
+	defaultCalendarLimit = 100
+	maxCalendarLimit     = 1000
+)
+
+// SetCalendarFeedToken lets calendar apps, which cannot send an API key,
+// subscribe to a chat's ICS feed with ?token= instead. The token is the
+// secret each chat's feed token is signed with, so a feed link opens only
+// the chat it was made for. Empty disables it.
+func (s *Server) SetCalendarFeedToken(token string) {
+	s.calendarToken = token
+}
+
+// chatFeedToken returns the token that opens the ICS feed of one chat
+func (s *Server) chatFeedToken(chatJID string) string {
+	mac := hmac.New(sha256.New, []byte(s.calendarToken))
+	mac.Write([]byte(chatJID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// calendarFeed serves ICS requests carrying a chat's feed token without the
+// API key; everything else goes through the usual authentication
+func (s *Server) calendarFeed(next http.HandlerFunc) http.HandlerFunc {
+	secured := s.secure(next)
+	open := SecurityHeadersMiddleware(RateLimitMiddleware(RecoverMiddleware(next)))
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		token, chatJID := query.Get("token"), query.Get("chat_jid")
+		if s.calendarToken != "" && token != "" && chatJID != "" && query.Get("format") == "ics" &&
+			subtle.ConstantTimeCompare([]byte(token), []byte(s.chatFeedToken(chatJID))) == 1 {
+			open(w, r)
+			return
+		}
+		secured(w, r)
+	}
+}
+
+// handleCalendarFeedLink handles GET /api/events/calendar/feed, returning the
+// link calendar apps subscribe to for one chat's events.
+//
+// Query parameters:
+//   - chat_jid: The chat whose events the feed lists (required)
+//
+// Response: { success: bool, data: { chat_jid, token, path } }
+func (s *Server) handleCalendarFeedLink(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.calendarToken == "" {
+		SendJSONError(w, "Calendar feed links are disabled; set CALENDAR_FEED_TOKEN", http.StatusNotFound)
+		return
+	}
+	chatJID := r.URL.Query().Get("chat_jid")
+	if chatJID == "" {
+		SendJSONError(w, "chat_jid is required", http.StatusBadRequest)
+		return
+	}
+
+	token := s.chatFeedToken(chatJID)
+	link := url.Values{"format": {"ics"}, "chat_jid": {chatJID}, "token": {token}}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data": map[string]string{
+			"chat_jid": chatJID,
+			"token":    token,
+			"path":     "/api/events/calendar?" + link.Encode(),
+		},
+	})
+}
+
+// handleCalendar handles GET /api/events/calendar for events planned in
+// chats with WhatsApp's events feature, soonest first.
+//
+// Query parameters (all optional):
+//   - chat_jid: Only events planned in this chat
+//   - since, until: RFC3339 times; events that ended before since or start at
+//     or after until are left out. since defaults to 30 days ago.
+//   - include_canceled: "true" to list canceled events too (always listed, as
+//     cancelled, in the ICS feed so calendars drop them)
+//   - limit: Maximum events (default 100, max 1000)
+//   - format: "ics" for an iCalendar feed to subscribe to instead of JSON
+//   - token: The chat's feed token from /api/events/calendar/feed, accepted
+//     instead of the API key with format=ics and that chat_jid
+//
+// Response: { success: bool, data: CalendarEvent[] }, or text/calendar
+func (s *Server) handleCalendar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format != "" && format != "json" && format != "ics" {
+		SendJSONError(w, "format must be \"json\" or \"ics\"", http.StatusBadRequest)
+		return
+	}
+
+	since := time.Now().AddDate(0, 0, -30)
+	var until time.Time
+	for name, t := range map[string]*time.Time{"since": &since, "until": &until} {
+		if v := query.Get(name); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				SendJSONError(w, name+" must be an RFC3339 time", http.StatusBadRequest)
+				return
+			}
+			*t = parsed
+		}
+	}
+
+	includeCanceled := format == "ics"
+	if v := query.Get("include_canceled"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			SendJSONError(w, "include_canceled must be true or false", http.StatusBadRequest)
+			return
+		}
+		includeCanceled = includeCanceled || b
+	}
+
+	limit := defaultCalendarLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxCalendarLimit {
+			SendJSONError(w, fmt.Sprintf("limit must be between 1 and %d", maxCalendarLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	chatJID := query.Get("chat_jid")
+	events, err := s.messageStore.GetCalendarEvents(chatJID, since, until, includeCanceled, limit)
+	if err != nil {
+		SendJSONError(w, fmt.Sprintf("Failed to get calendar events: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if format == "ics" {
+		name := "WhatsApp events"
+		if chatJID != "" && len(events) > 0 && events[0].ChatName != "" {
+			name = events[0].ChatName
+		}
+		w.Header().Set("Content-Type", ical.ContentType)
+		if err := ical.Write(w, name, events); err != nil {
+			logger.Warnf("Failed to write calendar feed: %v", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    events,
+	})
+}
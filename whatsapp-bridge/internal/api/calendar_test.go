@@ -0,0 +1,42 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestCalendarFeedToken(t *testing.T) {
+	t.Setenv("API_KEY", "primary")
+	s := &Server{calendarToken: "feed secret"}
+	feed := s.calendarFeed(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+
+	call := func(chatJID, token string) int {
+		query := url.Values{"format": {"ics"}, "token": {token}}
+		if chatJID != "" {
+			query.Set("chat_jid", chatJID)
+		}
+		rec := httptest.NewRecorder()
+		feed(rec, httptest.NewRequest(http.MethodGet, "/api/events/calendar?"+query.Encode(), nil))
+		return rec.Code
+	}
+
+	family, team := "120363000000000001@g.us", "120363000000000002@g.us"
+	tests := []struct {
+		name    string
+		chatJID string
+		token   string
+		want    int
+	}{
+		{"chat's own token", family, s.chatFeedToken(family), http.StatusNoContent},
+		{"another chat's token", team, s.chatFeedToken(family), http.StatusUnauthorized},
+		{"token without a chat", "", s.chatFeedToken(family), http.StatusUnauthorized},
+		{"the signing secret", family, "feed secret", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		if got := call(tt.chatJID, tt.token); got != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
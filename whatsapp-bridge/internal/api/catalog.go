@@ -0,0 +1,112 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"whatsapp-bridge/internal/types"
+)
+
+// handleCatalog handles GET /api/catalog for one page of a business catalog.
+//
+// Query parameters:
+//   - jid: Business account whose catalog to fetch (optional, defaults to the linked account)
+//   - limit: Products per page (default and max 100)
+//   - cursor: next_cursor from the previous page (optional)
+//
+// Response: { success: bool, data: { owner_jid, products: CatalogProduct[], next_cursor } }
+// Prices are in thousandths of the currency unit.
+func (s *Server) handleCatalog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+
+	limit := 0
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 100 {
+			SendJSONError(w, "limit must be between 1 and 100", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	catalog, err := s.client.GetCatalog(r.Context(), query.Get("jid"), limit, query.Get("cursor"))
+	if err != nil {
+		SendJSONError(w, fmt.Sprintf("Failed to get catalog: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    catalog,
+	})
+}
+
+// handleSendProduct handles POST /api/send/product to send a product from the
+// linked account's catalog.
+//
+// Request body:
+//   - recipient: Phone number or JID (required)
+//   - product_id: Catalog product ID (required)
+//   - body: Text shown with the product (optional)
+//   - footer: Footer text (optional)
+//
+// Response: same shape as POST /api/send; error_code is "product_not_found"
+// when the product is not in the catalog.
+func (s *Server) handleSendProduct(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req types.SendProductRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	if req.Recipient == "" || req.ProductID == "" {
+		SendJSONError(w, "recipient and product_id are required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := queuedResult(s.outbox.SendProduct(r.Context(), req))
+	writeQueuedSend(w, result, err, req.Recipient)
+}
+
+// handleSendCatalog handles POST /api/send/catalog to share the linked
+// account's catalog.
+//
+// Request body:
+//   - recipient: Phone number or JID (required)
+//   - message: Text shown above the catalog link (optional)
+//
+// Response: same shape as POST /api/send
+func (s *Server) handleSendCatalog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req types.SendCatalogRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	if req.Recipient == "" {
+		SendJSONError(w, "Recipient is required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := queuedResult(s.outbox.SendCatalog(r.Context(), req))
+	writeQueuedSend(w, result, err, req.Recipient)
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"whatsapp-bridge/internal/types"
+)
+
+const (
+	// maxChaosOffline caps a simulated outage; the watchdog restarts the
+	// process after three minutes disconnected
+	maxChaosOffline = 10 * time.Minute
+
+	// maxChaosKeepAliveFailures caps the simulated keepalive timeouts
+	maxChaosKeepAliveFailures = 10
+)
+
+// handleChaos handles POST /api/admin/chaos/{fault}, which injects a
+// connection fault so reconnect handling and consumers' resilience can be
+// tested on demand. Only registered in dev mode (DEV_MODE=true).
+//
+// Faults and their request bodies (all fields optional):
+//   - disconnect: { offline_seconds } drops the connection; 0 (default)
+//     reconnects at once through whatsmeow's auto-reconnect
+//   - keepalive-timeout: { failures } consecutive keepalive timeouts (default 3,
+//     which makes the bridge force a reconnect)
+//   - stream-error: { code } runs a stream error through whatsmeow (default "503")
+//   - history-sync: { sync_type, chat_jid, messages } dispatches a history sync
+//     of text messages (default "recent", the account's own chat, 5 messages)
+//
+// Response: { success: bool, message: string }
+func (s *Server) handleChaos(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req types.ChaosRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var message string
+	switch fault := strings.TrimPrefix(r.URL.Path, "/api/admin/chaos/"); fault {
+	case "disconnect":
+		offline := time.Duration(req.OfflineSeconds) * time.Second
+		if offline < 0 || offline > maxChaosOffline {
+			SendJSONError(w, fmt.Sprintf("offline_seconds must be between 0 and %d", int(maxChaosOffline.Seconds())), http.StatusBadRequest)
+			return
+		}
+		s.client.SimulateDisconnect(offline)
+		message = "Connection dropped"
+
+	case "keepalive-timeout":
+		failures := req.Failures
+		if failures == 0 {
+			failures = 3
+		}
+		if failures < 1 || failures > maxChaosKeepAliveFailures {
+			SendJSONError(w, fmt.Sprintf("failures must be between 1 and %d", maxChaosKeepAliveFailures), http.StatusBadRequest)
+			return
+		}
+		s.client.SimulateKeepAliveTimeout(failures)
+		message = fmt.Sprintf("Dispatched %d keepalive timeouts", failures)
+
+	case "stream-error":
+		code := req.Code
+		if code == "" {
+			code = "503"
+		}
+		if err := s.client.SimulateStreamError(code); err != nil {
+			SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		message = "Stream error " + code + " handled"
+
+	case "history-sync":
+		syncType, chatJID, count := req.SyncType, req.ChatJID, req.Messages
+		if syncType == "" {
+			syncType = "recent"
+		}
+		if count == 0 {
+			count = 5
+		}
+		if chatJID == "" {
+			self, ok := s.client.SelfJID()
+			if !ok {
+				SendJSONError(w, "Not logged in to WhatsApp; give chat_jid", http.StatusBadRequest)
+				return
+			}
+			chatJID = self.String()
+		}
+		if err := s.client.SimulateHistorySync(syncType, chatJID, count); err != nil {
+			SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		message = fmt.Sprintf("Dispatched a %s history sync of %d messages", syncType, count)
+
+	default:
+		SendJSONError(w, "Unknown fault; use disconnect, keepalive-timeout, stream-error or history-sync", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": message,
+	})
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"whatsapp-bridge/internal/commands"
+	"whatsapp-bridge/internal/database"
+	"whatsapp-bridge/internal/types"
+)
+
+// SetCommandRouter enables /api/settings/commands
+func (s *Server) SetCommandRouter(router *commands.Router) {
+	s.commands = router
+}
+
+// handleCommandConfig handles GET/PUT /api/settings/commands.
+//
+// PUT Request body (replaces the whole configuration):
+//   - enabled: boolean; while true admins' direct messages starting with the
+//     prefix are run as commands
+//   - prefix: Marks a message as a command (default "!", at most 3 characters)
+//   - admins: Users allowed to send commands, each { jid, commands }, where
+//     commands lists the names they may run ("*" for all; help is always allowed)
+//
+// Commands are help, status, mute <jid> [duration], unmute <jid>,
+// send <jid> <text>, and approvals, approve <id> and reject <id> [reason] for
+// sends held for approval. Each is answered in the admin's chat and written to the
+// audit log; command messages do not reach webhooks or auto-replies.
+//
+// Response: { success: bool, data: CommandConfig }
+func (s *Server) handleCommandConfig(w http.ResponseWriter, r *http.Request) {
+	if s.commands == nil {
+		SendJSONError(w, "Chat commands are not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    s.commands.Config(),
+		})
+
+	case http.MethodPut:
+		var cfg types.CommandConfig
+		if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+			SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+
+		if err := commands.ValidateConfig(cfg); err != nil {
+			SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := s.messageStore.SetJSONSetting(database.SettingCommands, cfg); err != nil {
+			SendJSONError(w, fmt.Sprintf("Failed to store command config: %v", err), http.StatusInternalServerError)
+			return
+		}
+		_ = s.commands.SetConfig(cfg)
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    s.commands.Config(),
+		})
+
+	default:
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
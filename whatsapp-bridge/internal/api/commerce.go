@@ -0,0 +1,56 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Commerce message listing limits
+const (
+	defaultCommerceLimit = 100
+	maxCommerceLimit     = 1000
+)
+
+// handleCommerceMessages handles GET /api/commerce for captured order,
+// catalog and payment messages.
+//
+// Query parameters:
+//   - chat_jid: Only messages in this chat (optional)
+//   - kind: order, product, payment_request, payment_sent, payment_invite,
+//     payment_declined or payment_canceled (optional)
+//   - limit: Maximum messages (default 100, max 1000)
+//
+// Response: { success: bool, data: CommerceMessage[] } ordered newest first
+func (s *Server) handleCommerceMessages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+
+	limit := defaultCommerceLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxCommerceLimit {
+			SendJSONError(w, fmt.Sprintf("limit must be between 1 and %d", maxCommerceLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	messages, err := s.messageStore.GetCommerceMessages(query.Get("chat_jid"), query.Get("kind"), limit)
+	if err != nil {
+		SendJSONError(w, fmt.Sprintf("Failed to get commerce messages: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    messages,
+	})
+}
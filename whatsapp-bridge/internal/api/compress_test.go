@@ -0,0 +1,85 @@
+package api
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCacheMiddleware(t *testing.T) {
+	body := `{"success":true,"data":"` + strings.Repeat("chat ", 500) + `"}`
+	handler := CacheMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("fail") != "" {
+			SendJSONError(w, "nope", http.StatusBadRequest)
+			return
+		}
+		io.WriteString(w, body)
+	})
+
+	// A plain request gets the body and its ETag
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/chats", nil))
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || rec.Body.String() != body || !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("plain response = %d, etag %q", rec.Code, etag)
+	}
+	if rec.Header().Get("Content-Encoding") != "" {
+		t.Error("gzipped without Accept-Encoding")
+	}
+
+	// A client that accepts gzip gets it, with the same ETag
+	req := httptest.NewRequest(http.MethodGet, "/api/chats", nil)
+	req.Header.Set("Accept-Encoding", "br, gzip")
+	rec = httptest.NewRecorder()
+	handler(rec, req)
+	if rec.Header().Get("Content-Encoding") != "gzip" || rec.Header().Get("ETag") != etag || rec.Body.Len() >= len(body) {
+		t.Fatalf("gzip response: encoding %q, etag %q, %d bytes", rec.Header().Get("Content-Encoding"), rec.Header().Get("ETag"), rec.Body.Len())
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	if plain, _ := io.ReadAll(zr); string(plain) != body {
+		t.Error("gzipped body differs")
+	}
+
+	// gzip;q=0 refuses it
+	req = httptest.NewRequest(http.MethodGet, "/api/chats", nil)
+	req.Header.Set("Accept-Encoding", "gzip;q=0")
+	rec = httptest.NewRecorder()
+	handler(rec, req)
+	if rec.Header().Get("Content-Encoding") != "" {
+		t.Error("gzipped although the client refused it")
+	}
+
+	// A matching If-None-Match gets 304 without a body, strong or weak
+	for _, inm := range []string{etag, strings.TrimPrefix(etag, "W/"), `"other", ` + etag, "*"} {
+		req = httptest.NewRequest(http.MethodGet, "/api/chats", nil)
+		req.Header.Set("If-None-Match", inm)
+		rec = httptest.NewRecorder()
+		handler(rec, req)
+		if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 || rec.Header().Get("ETag") != etag {
+			t.Errorf("If-None-Match %s = %d with %d bytes", inm, rec.Code, rec.Body.Len())
+		}
+	}
+
+	// A stale one gets the body
+	req = httptest.NewRequest(http.MethodGet, "/api/chats", nil)
+	req.Header.Set("If-None-Match", `W/"stale"`)
+	rec = httptest.NewRecorder()
+	handler(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != body {
+		t.Errorf("stale If-None-Match = %d", rec.Code)
+	}
+
+	// Errors pass through without an ETag
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/chats?fail=1", nil))
+	if rec.Code != http.StatusBadRequest || rec.Header().Get("ETag") != "" || !strings.Contains(rec.Body.String(), "nope") {
+		t.Errorf("error response = %d, etag %q", rec.Code, rec.Header().Get("ETag"))
+	}
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"whatsapp-bridge/internal/types"
+)
+
+// MaxCORSOrigins caps the configured origins
+const MaxCORSOrigins = 100
+
+// corsPolicy is the CORS configuration in effect, read on every request so
+// changes through /api/settings/cors apply at once
+var corsPolicy = struct {
+	sync.RWMutex
+	config types.CORSConfig
+}{config: DefaultCORSConfig()}
+
+// DefaultCORSConfig allows the bundled UIs on localhost, with credentials,
+// plus any origins in CORS_ORIGINS (comma-separated)
+func DefaultCORSConfig() types.CORSConfig {
+	origins := []types.CORSOrigin{
+		{Origin: "http://localhost:8089", AllowCredentials: true}, // Webhook UI
+		{Origin: "http://localhost:8082", AllowCredentials: true}, // Gradio UI
+		{Origin: "http://localhost:8090", AllowCredentials: true}, // Pairing UI
+	}
+
+	if extra := os.Getenv("CORS_ORIGINS"); extra != "" {
+		for _, origin := range strings.Split(extra, ",") {
+			if origin = strings.TrimSpace(origin); origin != "" {
+				origins = append(origins, types.CORSOrigin{Origin: origin, AllowCredentials: origin != "*"})
+			}
+		}
+	}
+
+	return types.CORSConfig{
+		Disabled: os.Getenv("CORS_DISABLED") == "true",
+		Origins:  origins,
+	}
+}
+
+// ValidateCORSConfig checks that each origin is "*" or scheme://host[:port]
+// with an optional leading "*." wildcard label, and that "*" is not combined
+// with credentials
+func ValidateCORSConfig(cfg types.CORSConfig) error {
+	if len(cfg.Origins) > MaxCORSOrigins {
+		return fmt.Errorf("at most %d origins are allowed", MaxCORSOrigins)
+	}
+
+	for _, o := range cfg.Origins {
+		if o.Origin == "*" {
+			if o.AllowCredentials {
+				return fmt.Errorf("allow_credentials cannot be combined with the \"*\" origin")
+			}
+			continue
+		}
+
+		u, err := url.Parse(o.Origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+			u.Path != "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+			return fmt.Errorf("invalid origin %q: use scheme://host[:port]", o.Origin)
+		}
+		host := strings.TrimPrefix(u.Hostname(), "*.")
+		if host == "" || strings.Contains(host, "*") {
+			return fmt.Errorf("invalid origin %q: a wildcard is only allowed as the first label", o.Origin)
+		}
+	}
+	return nil
+}
+
+// SetCORSConfig validates and applies a CORS configuration
+func SetCORSConfig(cfg types.CORSConfig) error {
+	if err := ValidateCORSConfig(cfg); err != nil {
+		return err
+	}
+	if cfg.Origins == nil {
+		cfg.Origins = []types.CORSOrigin{}
+	}
+
+	corsPolicy.Lock()
+	defer corsPolicy.Unlock()
+	corsPolicy.config = cfg
+	return nil
+}
+
+// CORSConfig returns the CORS configuration in effect
+func CORSConfig() types.CORSConfig {
+	corsPolicy.RLock()
+	defer corsPolicy.RUnlock()
+	return corsPolicy.config
+}
+
+// matchCORSOrigin returns the configured origin that allows a request's
+// Origin header. An exact match is preferred over a wildcard.
+func matchCORSOrigin(cfg types.CORSConfig, origin string) (types.CORSOrigin, bool) {
+	if cfg.Disabled || origin == "" {
+		return types.CORSOrigin{}, false
+	}
+
+	for _, o := range cfg.Origins {
+		if o.Origin == origin {
+			return o, true
+		}
+	}
+	for _, o := range cfg.Origins {
+		if o.Origin == "*" || wildcardOriginMatches(o.Origin, origin) {
+			return o, true
+		}
+	}
+	return types.CORSOrigin{}, false
+}
+
+// wildcardOriginMatches reports whether origin is a subdomain of a
+// "scheme://*.domain[:port]" pattern. The domain itself does not match.
+func wildcardOriginMatches(pattern, origin string) bool {
+	scheme, rest, ok := strings.Cut(pattern, "://*.")
+	if !ok {
+		return false
+	}
+	prefix := scheme + "://"
+	if !strings.HasPrefix(origin, prefix) {
+		return false
+	}
+
+	host := strings.TrimPrefix(origin, prefix)
+	sub, ok := strings.CutSuffix(host, "."+rest)
+	return ok && sub != "" && !strings.ContainsAny(sub, "/:")
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"whatsapp-bridge/internal/types"
+)
+
+func TestValidateCORSConfig(t *testing.T) {
+	valid := []string{"http://localhost:8089", "https://app.example.com", "https://*.example.com", "https://*.example.com:8443", "*"}
+	for _, origin := range valid {
+		if err := ValidateCORSConfig(types.CORSConfig{Origins: []types.CORSOrigin{{Origin: origin}}}); err != nil {
+			t.Errorf("origin %q rejected: %v", origin, err)
+		}
+	}
+
+	invalid := []string{"", "example.com", "ftp://example.com", "https://example.com/app", "https://a.*.example.com", "https://*", "https://*.*.example.com"}
+	for _, origin := range invalid {
+		if err := ValidateCORSConfig(types.CORSConfig{Origins: []types.CORSOrigin{{Origin: origin}}}); err == nil {
+			t.Errorf("origin %q accepted", origin)
+		}
+	}
+
+	if err := ValidateCORSConfig(types.CORSConfig{Origins: []types.CORSOrigin{{Origin: "*", AllowCredentials: true}}}); err == nil {
+		t.Error("\"*\" with credentials accepted")
+	}
+}
+
+func TestMatchCORSOrigin(t *testing.T) {
+	cfg := types.CORSConfig{Origins: []types.CORSOrigin{
+		{Origin: "https://*.example.com"},
+		{Origin: "https://admin.example.com", AllowCredentials: true},
+		{Origin: "http://localhost:8089", AllowCredentials: true},
+	}}
+
+	tests := []struct {
+		origin      string
+		want        bool
+		credentials bool
+	}{
+		{"http://localhost:8089", true, true},
+		{"https://app.example.com", true, false},
+		{"https://a.b.example.com", true, false},
+		{"https://admin.example.com", true, true}, // exact match preferred
+		{"https://example.com", false, false},
+		{"http://app.example.com", false, false},
+		{"https://app.example.com:8443", false, false},
+		{"https://evilexample.com", false, false},
+		{"https://app.example.com.evil.com", false, false},
+		{"", false, false},
+	}
+	for _, tt := range tests {
+		got, ok := matchCORSOrigin(cfg, tt.origin)
+		if ok != tt.want || got.AllowCredentials != tt.credentials {
+			t.Errorf("matchCORSOrigin(%q) = %+v, %v, want %v (credentials %v)", tt.origin, got, ok, tt.want, tt.credentials)
+		}
+	}
+
+	cfg.Disabled = true
+	if _, ok := matchCORSOrigin(cfg, "http://localhost:8089"); ok {
+		t.Error("origin matched while CORS is disabled")
+	}
+}
+
+func TestCorsMiddleware(t *testing.T) {
+	defer func() { _ = SetCORSConfig(DefaultCORSConfig()) }()
+
+	handler := CorsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	request := func(method, origin string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/api/send", nil)
+		r.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w
+	}
+
+	if err := SetCORSConfig(types.CORSConfig{Origins: []types.CORSOrigin{{Origin: "*"}}}); err != nil {
+		t.Fatal(err)
+	}
+	w := request(http.MethodOptions, "https://anywhere.test")
+	if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "https://anywhere.test" || w.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Errorf("preflight with \"*\": %d %v", w.Code, w.Header())
+	}
+
+	if err := SetCORSConfig(types.CORSConfig{Disabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	w = request(http.MethodOptions, "https://anywhere.test")
+	if w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Methods") != "" {
+		t.Errorf("disabled CORS still answered: %d %v", w.Code, w.Header())
+	}
+}
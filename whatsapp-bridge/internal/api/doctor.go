@@ -0,0 +1,39 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"whatsapp-bridge/internal/doctor"
+)
+
+// SetDoctor enables /api/admin/doctor
+func (s *Server) SetDoctor(d *doctor.Doctor) {
+	s.doctor = d
+}
+
+// handleDoctor handles GET /api/admin/doctor, which re-runs the startup
+// configuration checks: store directory, database integrity, clock skew,
+// conflicting settings, Redis and webhook reachability.
+//
+// Response: { success: bool, data: DoctorReport }. Each check has a status
+// (pass, warn, fail), a message and, unless it passed, a remedy. success is
+// false when any check failed.
+func (s *Server) handleDoctor(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.doctor == nil {
+		SendJSONError(w, "Configuration checks are not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	report := s.doctor.Run(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": report.Healthy,
+		"data":    report,
+	})
+}
@@ -1,29 +1,74 @@
 package api
 
 import (
-	"database/sql"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"whatsapp-bridge/internal/automation"
+	"whatsapp-bridge/internal/database"
+	"whatsapp-bridge/internal/diskspace"
+	"whatsapp-bridge/internal/outbox"
+	"whatsapp-bridge/internal/phone"
+	"whatsapp-bridge/internal/secrets"
+	"whatsapp-bridge/internal/tenant"
+	"whatsapp-bridge/internal/textfmt"
 	"whatsapp-bridge/internal/types"
+	"whatsapp-bridge/internal/whatsapp"
 )
 
 // handleSendMessage handles POST /api/send for sending WhatsApp messages.
 //
 // Request body:
-//   - recipient: WhatsApp JID (required, e.g., "1234567890@s.whatsapp.net")
+//   - recipient: WhatsApp JID or phone number (required, e.g., "1234567890@s.whatsapp.net"
+//     or "+1 (234) 567-890"; see /api/normalize)
 //   - message: Text content (required if media_path not provided)
-//   - media_path: Path to media file (optional, for images/videos/documents)
+//   - format: "markdown" converts basic Markdown to WhatsApp formatting
+//   - split: Send a message over the length limit as numbered parts instead of
+//     rejecting it; media goes with the first part
+//   - media_path: Path to media file (optional, for images/videos/documents;
+//     .gif is sent as a looping video, .webp as a sticker and .ogg as a voice
+//     note, as are .mp3, .m4a and .wav with VOICE_NOTE_TRANSCODE=true)
+//   - mentions: Users to @-mention, by JID or phone number. They are notified
+//     even if the text does not tag them; in groups, @phone tokens in the text,
+//     e.g. "@+15550102030", are also turned into mentions
+//   - generate_preview: Preview the first link in a text message with the page's
+//     title, description and image, as the official client does. Pages on
+//     private addresses are not fetched; if the page cannot be read, the text is
+//     sent without a preview
+//   - priority: "high" (default) or "low"; low priority sends yield to high ones
+//   - force: Send even if it repeats a recent send (see /api/settings/duplicate-send)
+//   - origin: Name of the bot or rule making the send, e.g. a webhook consumer replying
+//     automatically; such sends are tagged and subject to the loop breaker
+//     (see /api/settings/loop-breaker)
 //
 // Response:
 //   - success: boolean
-//   - message_id: string (WhatsApp message ID on success)
+//   - message_id: string (WhatsApp message ID on success; the last part's when split)
+//   - message_ids: []string (every part sent, in order, when split)
 //   - timestamp: int64 (Unix timestamp)
 //   - recipient: string (echo of recipient JID)
+//   - status: string ("server_ack" on success; track further with GET /api/send/status)
 //   - error: string (on failure)
+//   - error_code: string (on failure, e.g. "not_on_whatsapp", "timeout", "message_too_large")
+//   - retryable: boolean (on failure, true if the same request may succeed later)
+//   - duplicate: boolean (true if sent although it repeats a recent send)
+//
+// Sends wait their turn within the send rates (see /api/settings/send-rate)
+// and are tried up to four times while they fail with a lost connection or
+// a WhatsApp server error. Timeouts are not retried, since the message may
+// have gone through. Queued sends are kept in the database, so a send still
+// waiting when the bridge restarts goes out after the restart.
+//
+// Sends made with a key that needs approval (see /api/settings/approvals) are
+// held instead, as on every send endpoint (see holdForApproval).
 func (s *Server) handleSendMessage(w http.ResponseWriter, r *http.Request) {
 	// Only allow POST requests
 	if r.Method != http.MethodPost {
@@ -49,55 +94,333 @@ func (s *Server) handleSendMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Send the message
-	result := s.client.SendMessage(s.messageStore, req.Recipient, req.Message, req.MediaPath)
+	if !outbox.ValidPriority(req.Priority) {
+		SendJSONError(w, "priority must be \"high\" or \"low\"", http.StatusBadRequest)
+		return
+	}
+
+	if req.Format != "" && req.Format != types.FormatMarkdown {
+		SendJSONError(w, "format must be \"markdown\"", http.StatusBadRequest)
+		return
+	}
+
+	if automation.Reserved(req.Origin) {
+		SendJSONError(w, fmt.Sprintf("origin %q is reserved for the bridge's own automations", req.Origin), http.StatusBadRequest)
+		return
+	}
+
+	// Normalize phone numbers up front, so "+1 (555) 010-2030" and
+	// "15550102030" are the same recipient to the duplicate and loop checks
+	if !strings.Contains(req.Recipient, "@") {
+		number, err := phone.Parse(req.Recipient, "")
+		if err != nil {
+			writeSendResult(w, types.SendResult{Error: err.Error(), Code: whatsapp.SendErrInvalidRecipient}, req.Recipient)
+			return
+		}
+		req.Recipient = number.Digits
+	}
+
+	mentions, err := whatsapp.ParseMentions(req.Mentions)
+	if err != nil {
+		SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := s.sendMessage(r.Context(), req, mentions)
+	writeQueuedSend(w, result, err, req.Recipient)
+}
+
+// queuedResult turns the outbox refusing a send into the failed result
+// reported for it. Any other error means ctx ended before the send did and
+// is returned as is.
+func queuedResult(result types.SendResult, err error) (types.SendResult, error) {
+	switch err {
+	case outbox.ErrQueueFull:
+		return types.SendResult{Error: err.Error(), Code: outbox.SendErrQueueFull, Retryable: true}, nil
+	case outbox.ErrDuplicate:
+		return types.SendResult{Error: err.Error(), Code: outbox.SendErrDuplicate}, nil
+	case outbox.ErrAutomationPaused:
+		return types.SendResult{Error: err.Error(), Code: outbox.SendErrAutomationPaused}, nil
+	case outbox.ErrAccountRestricted:
+		return types.SendResult{Error: err.Error(), Code: outbox.SendErrAccountRestricted, Retryable: true}, nil
+	}
+	return result, err
+}
+
+// writeQueuedSend answers a send made through the outbox. When the request
+// ended before the send, the send still goes on in the background.
+func writeQueuedSend(w http.ResponseWriter, result types.SendResult, err error, recipient string) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		SendJSONError(w, "Timed out waiting for the send; it continues in the background", http.StatusGatewayTimeout)
+		return
+	} else if err != nil {
+		// Client went away; the send continues in the background
+		return
+	}
+	writeSendResult(w, result, recipient)
+}
+
+// sendMessage sends a validated /api/send request: text formatting, mentions
+// and splitting, then each part through the outbox. The error is only set
+// when ctx ends before the send does.
+func (s *Server) sendMessage(ctx context.Context, req types.SendMessageRequest, mentions []string) (types.SendResult, error) {
+	message := req.Message
+	if req.Format == types.FormatMarkdown {
+		message = textfmt.MarkdownToWhatsApp(message)
+	}
+	if strings.HasSuffix(req.Recipient, "@g.us") {
+		var tagged []string
+		message, tagged = whatsapp.ConvertMentionTokens(message)
+		for _, jid := range tagged {
+			if !slices.Contains(mentions, jid) {
+				mentions = append(mentions, jid)
+			}
+		}
+	}
+	parts := []string{message}
+	if req.Split {
+		parts = textfmt.Split(message, whatsapp.MaxTextLength)
+	}
+	partMentions := whatsapp.SplitMentions(parts, mentions)
+
+	// Queue the message in its priority lane and wait for the send. Parts go
+	// one at a time so they arrive in order; the first failure stops the rest.
+	send := s.outbox.Send
+	if req.Force {
+		send = s.outbox.SendForced
+	}
+	if req.Origin != "" {
+		ctx = automation.WithOrigin(ctx, req.Origin)
+	}
+	if req.GeneratePreview {
+		ctx = whatsapp.WithLinkPreview(ctx)
+	}
+	var result types.SendResult
+	var sent []string
+	for i, part := range parts {
+		mediaPath := ""
+		if i == 0 {
+			mediaPath = req.MediaPath
+		}
+
+		partCtx := ctx
+		if len(partMentions[i]) > 0 {
+			partCtx = whatsapp.WithMentions(ctx, partMentions[i])
+		}
+
+		var err error
+		result, err = queuedResult(send(partCtx, req.Priority, req.Recipient, part, mediaPath))
+		if err != nil {
+			return result, err
+		}
+		if !result.Success {
+			break
+		}
+		sent = append(sent, result.MessageID)
+	}
+	if len(parts) > 1 {
+		result.PartIDs = sent
+	}
+
+	return result, nil
+}
+
+// handleSendContact handles POST /api/send/contact to share contact cards.
+//
+// Request body:
+//   - recipient: Phone number or JID (required)
+//   - name, phone: Display name and phone number of one contact, or
+//   - vcard: A raw vCard for one contact, sent as is (name then defaults to its FN)
+//   - contacts: Several contacts, each with name and phone or vcard, sent together
+//     instead of the fields above
+//
+// Response: same shape as POST /api/send; error_code is "invalid_contact"
+// when a card cannot be built.
+func (s *Server) handleSendContact(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req types.SendContactRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	if req.Recipient == "" {
+		SendJSONError(w, "Recipient is required", http.StatusBadRequest)
+		return
+	}
+
+	cards := req.Contacts
+	inline := req.ContactCard != (types.ContactCard{})
+	switch {
+	case len(cards) > 0 && inline:
+		SendJSONError(w, "Give either contacts or name, phone and vcard, not both", http.StatusBadRequest)
+		return
+	case len(cards) == 0 && !inline:
+		SendJSONError(w, "name and phone, vcard, or contacts are required", http.StatusBadRequest)
+		return
+	case inline:
+		cards = []types.ContactCard{req.ContactCard}
+	}
+
+	result, err := queuedResult(s.outbox.SendContacts(r.Context(), req.Recipient, cards))
+	writeQueuedSend(w, result, err, req.Recipient)
+}
+
+// handleSendStatus handles GET /api/send/status?message_id=X for the
+// acknowledgment status of a message sent through /api/send.
+//
+// Response: { success: bool, data: { message_id, chat_jid, content, status, error, created_at, updated_at } }
+// status is one of pending, server_ack, delivered, read, failed.
+func (s *Server) handleSendStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
 
-	// Set response headers
 	w.Header().Set("Content-Type", "application/json")
 
-	// Set appropriate status code
-	if !result.Success {
-		w.WriteHeader(http.StatusInternalServerError)
+	messageID := r.URL.Query().Get("message_id")
+	if messageID == "" {
+		SendJSONError(w, "message_id is required", http.StatusBadRequest)
+		return
+	}
+
+	msg, err := s.messageStore.GetOutgoingMessage(messageID)
+	if err != nil {
+		SendJSONError(w, fmt.Sprintf("Failed to get message status: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if msg == nil || !tenant.Visible(APIKeyName(r), msg.Tenant) {
+		SendJSONError(w, "Message not found", http.StatusNotFound)
+		return
 	}
 
-	// Send response with message_id, timestamp, recipient
-	_ = json.NewEncoder(w).Encode(types.SendMessageResponse{
-		Success:   result.Success,
-		Message:   result.Error,
-		MessageID: result.MessageID,
-		Timestamp: result.Timestamp,
-		Recipient: req.Recipient,
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    msg,
+	})
+}
+
+// handleOutbox handles GET /api/outbox for the state of the send priority lanes.
+// delayed counts sends waiting for a retry or a send rate slot; rate is the
+// configured send rates (see /api/settings/send-rate).
+//
+// Response: { success: bool, data: { capacity, in_flight, delayed, lanes: { high: {depth, sent, failed, retried}, low: {...} }, rate } }
+func (s *Server) handleOutbox(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    s.outbox.Stats(),
+	})
+}
+
+// maxWebhookLimit is the most webhooks listed at once; by default all are
+const maxWebhookLimit = 1000
+
+// webhookSortFields are the fields the webhook list can be sorted by
+var webhookSortFields = map[string]string{"id": "id", "name": "name", "created_at": "created_at", "updated_at": "updated_at"}
+
+// sortWebhooks sorts the webhook list by a field of webhookSortFields,
+// keeping ID order among equals
+func sortWebhooks(webhooks []types.WebhookConfigResponse, field string, desc bool) {
+	compare := func(a, b types.WebhookConfigResponse) int {
+		switch field {
+		case "name":
+			return strings.Compare(a.Name, b.Name)
+		case "created_at":
+			return a.CreatedAt.Compare(b.CreatedAt)
+		case "updated_at":
+			return a.UpdatedAt.Compare(b.UpdatedAt)
+		}
+		return 0
+	}
+	slices.SortStableFunc(webhooks, func(a, b types.WebhookConfigResponse) int {
+		c := compare(a, b)
+		if c == 0 {
+			c = a.ID - b.ID
+		}
+		if desc {
+			return -c
+		}
+		return c
 	})
 }
 
 // handleWebhooks handles GET/POST /api/webhooks for webhook management.
 //
-// GET: List all webhook configurations (secrets are masked)
-// POST: Create a new webhook configuration
+// GET: List the caller's webhook configurations (secrets are masked; the operator sees all)
+// POST: Create a new webhook configuration owned by the caller
+//
+// GET query parameters (all optional):
+//   - limit, cursor, offset, fields: As for every list (see listquery.go);
+//     page size 1000 by default and at most
+//   - sort: id, name, created_at or updated_at, "-" first for descending
+//     (default by ID)
 //
 // POST Request body:
 //   - name: Webhook name (required)
 //   - webhook_url: HTTP(S) URL to POST to (required)
-//   - secret_token: HMAC-SHA256 signing secret (optional)
+//   - secret_token: HMAC-SHA256 signing secret (optional); the admin key may
+//     give a vault:// or awssm:// reference instead, see package secrets
 //   - enabled: boolean (default true)
 //   - triggers: array of trigger configurations
+//   - payload_version: payload schema to receive, 1 (default) or 2, which
+//     adds the message's context, reaction and send receipt
+//   - content_type: "application/json" (default), "application/x-www-form-urlencoded"
+//     (payload fields flattened, e.g. message_content) or "text/plain"
+//   - body_template: text/plain body with {field} placeholders, e.g.
+//     "{message_sender_name}: {message_content}"
+//   - max_content_length: cut message content to this many bytes (0 = no limit)
+//   - max_payload_bytes: payloads larger than this, at least 1024, are sent
+//     without the message content for the receiver to fetch by message.ref
+//   - oversize_action: "reference" (default) or "drop" to send nothing instead
 //
-// Response: { success: bool, data: WebhookConfig[] | WebhookConfig }
+// Response: { success: bool, data: WebhookConfig[] | WebhookConfig, plus total, limit, offset and next_cursor for GET }
 func (s *Server) handleWebhooks(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	switch r.Method {
 	case http.MethodGet:
-		// List all webhook configurations (with masked secrets)
-		configs := s.webhookManager.GetWebhookConfigs()
-		responses := make([]types.WebhookConfigResponse, len(configs))
-		for i := range configs {
-			responses[i] = configs[i].ToResponse()
-		}
-		_ = json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": true,
-			"data":    responses,
+		// List all webhook configurations (with masked secrets). Read from
+		// the database rather than the loaded set so trigger hit counts are
+		// current.
+		list, err := parseListQuery(r, listParams{
+			defaultLimit: maxWebhookLimit,
+			maxLimit:     maxWebhookLimit,
+			sortFields:   webhookSortFields,
+			item:         types.WebhookConfigResponse{},
 		})
+		if err != nil {
+			SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		configs, err := s.messageStore.GetAllWebhookConfigs()
+		if err != nil {
+			SendJSONError(w, fmt.Sprintf("Failed to get webhook configs: %v", err), http.StatusInternalServerError)
+			return
+		}
+		viewer := APIKeyName(r)
+		responses := []types.WebhookConfigResponse{}
+		for _, config := range configs {
+			if tenant.Visible(viewer, config.Tenant) {
+				responses = append(responses, config.ToResponse())
+			}
+		}
+		sortWebhooks(responses, list.Sort, list.Desc)
+
+		total := len(responses)
+		page := responses[min(list.Offset, total):min(list.Offset+list.Limit, total)]
+		writeList(w, list, page, total, nil)
 
 	case http.MethodPost:
 		// Create new webhook configuration
@@ -107,6 +430,15 @@ func (s *Server) handleWebhooks(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// Only the operator may create webhooks on behalf of another tenant
+		if viewer := APIKeyName(r); !tenant.IsOperator(viewer) || config.Tenant == "" {
+			config.Tenant = viewer
+		}
+		if err := checkSecretToken(r, config.SecretToken, ""); err != nil {
+			SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		// Validate configuration
 		if err := s.webhookManager.ValidateWebhookConfig(&config); err != nil {
 			SendJSONError(w, err.Error(), http.StatusBadRequest)
@@ -132,6 +464,23 @@ func (s *Server) handleWebhooks(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// checkSecretToken vets a webhook secret_token sent by an API caller. The
+// bridge fetches secret store references with its own credentials, so only
+// the admin key may give one; file references are refused outright so no
+// caller can probe the bridge's filesystem. A token left as it was passes.
+func checkSecretToken(r *http.Request, token, current string) error {
+	if !secrets.IsReference(token) || token == current {
+		return nil
+	}
+	if secrets.IsFile(token) {
+		return fmt.Errorf("secret_token cannot refer to a file")
+	}
+	if !tenant.IsOperator(APIKeyName(r)) {
+		return fmt.Errorf("only the admin API key may set secret_token to a secret store reference")
+	}
+	return nil
+}
+
 // handleWebhookByID handles operations on individual webhooks.
 //
 // Routes:
@@ -139,8 +488,14 @@ func (s *Server) handleWebhooks(w http.ResponseWriter, r *http.Request) {
 //   - PUT    /api/webhooks/{id}        - Update webhook config
 //   - DELETE /api/webhooks/{id}        - Delete webhook
 //   - POST   /api/webhooks/{id}/test   - Test webhook delivery
+//   - POST   /api/webhooks/{id}/dry-run - Explain which triggers a sample message matches
 //   - GET    /api/webhooks/{id}/logs   - Get delivery logs
 //   - POST   /api/webhooks/{id}/enable - Enable/disable webhook
+//
+// The test body may carry a sample message (chat_jid, chat_name, sender,
+// sender_name, content, media_type) to send instead of the default one; the
+// response then says whether the webhook's triggers match it:
+// { success: bool, message: string, matched: bool, trigger?: WebhookTrigger }
 func (s *Server) handleWebhookByID(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -158,17 +513,19 @@ func (s *Server) handleWebhookByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Other tenants' webhooks are reported as missing
+	owned, err := s.messageStore.GetWebhookConfig(webhookID)
+	if err != nil || !tenant.Visible(APIKeyName(r), owned.Tenant) {
+		SendJSONError(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+
 	// Handle different sub-paths
 	switch {
 	case len(pathParts) == 1: // /api/webhooks/{id}
 		switch r.Method {
 		case http.MethodGet:
-			// Get specific webhook configuration (with masked secret)
-			config, err := s.messageStore.GetWebhookConfig(webhookID)
-			if err != nil {
-				SendJSONError(w, fmt.Sprintf("Webhook not found: %v", err), http.StatusNotFound)
-				return
-			}
+			config := owned
 
 			_ = json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": true,
@@ -184,6 +541,11 @@ func (s *Server) handleWebhookByID(w http.ResponseWriter, r *http.Request) {
 			}
 
 			config.ID = webhookID // Ensure ID matches URL
+			config.Tenant = owned.Tenant
+			if err := checkSecretToken(r, config.SecretToken, owned.SecretToken); err != nil {
+				SendJSONError(w, err.Error(), http.StatusBadRequest)
+				return
+			}
 
 			// Validate configuration
 			if err := s.webhookManager.ValidateWebhookConfig(&config); err != nil {
@@ -212,8 +574,9 @@ func (s *Server) handleWebhookByID(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 
-			// Reload configurations
+			// Reload configurations, and the routing profiles it was detached from
 			_ = s.webhookManager.LoadWebhookConfigs()
+			_ = s.webhookManager.LoadRoutingProfiles()
 
 			_ = json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": true,
@@ -230,42 +593,109 @@ func (s *Server) handleWebhookByID(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		// Get webhook configuration
-		config, err := s.messageStore.GetWebhookConfig(webhookID)
-		if err != nil {
-			SendJSONError(w, fmt.Sprintf("Webhook not found: %v", err), http.StatusNotFound)
-			return
+		config := owned
+
+		// An optional sample message replaces the default test message
+		var sample *types.WebhookTestRequest
+		if r.ContentLength != 0 {
+			sample = &types.WebhookTestRequest{}
+			if err := json.NewDecoder(r.Body).Decode(sample); err == io.EOF {
+				sample = nil
+			} else if err != nil {
+				SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+				return
+			} else if err := s.webhookManager.ValidateTestRequest(sample); err != nil {
+				SendJSONError(w, err.Error(), http.StatusBadRequest)
+				return
+			}
 		}
 
 		// Test webhook
-		if err := s.webhookManager.TestWebhook(config); err != nil {
+		matched, err := s.webhookManager.TestWebhook(config, sample)
+		if err != nil {
 			SendJSONError(w, fmt.Sprintf("Webhook test failed: %v", err), http.StatusInternalServerError)
 			return
 		}
 
-		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		response := map[string]interface{}{
 			"success": true,
 			"message": "Webhook test successful",
-		})
+		}
+		if sample != nil {
+			// Whether live messages like the sample would reach the webhook
+			response["matched"] = matched != nil
+			if matched != nil {
+				response["trigger"] = matched
+			}
+		}
+		_ = json.NewEncoder(w).Encode(response)
 
-	case len(pathParts) == 2 && pathParts[1] == "logs": // /api/webhooks/{id}/logs
-		if r.Method != http.MethodGet {
+	case len(pathParts) == 2 && pathParts[1] == "dry-run": // /api/webhooks/{id}/dry-run
+		if r.Method != http.MethodPost {
 			SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 
-		// Get webhook logs
-		logs, err := s.messageStore.GetWebhookLogs(webhookID, 100) // Limit to 100 recent logs
+		// The sample message is required; nothing is delivered
+		var sample types.WebhookTestRequest
+		if err := json.NewDecoder(r.Body).Decode(&sample); err != nil {
+			SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+		if err := s.webhookManager.ValidateTestRequest(&sample); err != nil {
+			SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		result, err := s.webhookManager.DryRun(owned, &sample)
 		if err != nil {
-			SendJSONError(w, fmt.Sprintf("Failed to get webhook logs: %v", err), http.StatusInternalServerError)
+			SendJSONError(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
 		_ = json.NewEncoder(w).Encode(map[string]interface{}{
 			"success": true,
-			"data":    logs,
+			"data":    result,
 		})
 
+	case len(pathParts) == 2 && pathParts[1] == "logs": // /api/webhooks/{id}/logs
+		if r.Method != http.MethodGet {
+			SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// Get webhook logs, paged like every list
+		list, err := parseListQuery(r, listParams{
+			defaultLimit: defaultWebhookLogLimit,
+			maxLimit:     maxWebhookLogLimit,
+			sortFields:   database.WebhookLogSortFields,
+			item:         types.WebhookLog{},
+		})
+		if err != nil {
+			SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter := types.WebhookLogFilter{
+			WebhookConfigID: webhookID,
+			Limit:           list.Limit,
+			Offset:          list.Offset,
+			Order:           types.ListOrder{Field: list.Sort, Desc: list.Desc},
+		}
+		logs, err := s.messageStore.SearchWebhookLogs(filter)
+		if err != nil {
+			SendJSONError(w, fmt.Sprintf("Failed to get webhook logs: %v", err), http.StatusInternalServerError)
+			return
+		}
+		summary, err := s.messageStore.SummarizeWebhookLogs(filter)
+		if err != nil {
+			SendJSONError(w, fmt.Sprintf("Failed to get webhook logs: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if logs == nil {
+			logs = []*types.WebhookLog{}
+		}
+		writeList(w, list, logs, summary.Total, nil)
+
 	case len(pathParts) == 2 && pathParts[1] == "enable": // /api/webhooks/{id}/enable
 		if r.Method != http.MethodPost {
 			SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -281,12 +711,7 @@ func (s *Server) handleWebhookByID(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		// Get current config
-		config, err := s.messageStore.GetWebhookConfig(webhookID)
-		if err != nil {
-			SendJSONError(w, fmt.Sprintf("Webhook not found: %v", err), http.StatusNotFound)
-			return
-		}
+		config := owned
 
 		// Update enabled status
 		config.Enabled = req.Enabled
@@ -309,10 +734,32 @@ func (s *Server) handleWebhookByID(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// handleWebhookLogs handles GET /api/webhook-logs for all webhook delivery logs.
-//
-// Returns the last 100 webhook delivery attempts across all webhooks.
+// Webhook log page limits
+const (
+	defaultWebhookLogLimit = 100
+	maxWebhookLogLimit     = 1000
+)
+
+// handleWebhookLogs handles GET /api/webhook-logs for webhook delivery logs
+// across the caller's webhooks (all webhooks for the operator), newest first.
 // For logs of a specific webhook, use GET /api/webhooks/{id}/logs instead.
+//
+// Query parameters (all optional):
+//   - webhook_id: Only this webhook's deliveries
+//   - chat_jid: Only deliveries for this chat
+//   - status: Receiver response class: "2xx", "3xx", "4xx", "5xx", or "none"
+//     when the request got no response
+//   - failed: "true" for failed attempts only
+//   - since, until: RFC3339 times bounding when the attempt was made
+//   - limit, cursor, offset, fields: As for every list (see listquery.go);
+//     page size 100 by default, at most 1000
+//   - sort: created_at, webhook_config_id, chat_jid, response_status or
+//     attempt_count, "-" first for descending (default newest first)
+//
+// The summary counts every matching attempt, not just the page; the total is
+// also sent in the X-Total-Count header.
+//
+// Response: { success: bool, data: WebhookLog[], summary: WebhookLogSummary, total: int, limit: int, offset: int, next_cursor: string }
 func (s *Server) handleWebhookLogs(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -321,17 +768,69 @@ func (s *Server) handleWebhookLogs(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 
-	// Get all webhook logs
-	logs, err := s.messageStore.GetWebhookLogs(0, 100) // Get last 100 logs for all webhooks
+	query := r.URL.Query()
+	filter := types.WebhookLogFilter{
+		ChatJID:     query.Get("chat_jid"),
+		StatusClass: query.Get("status"),
+		FailedOnly:  query.Get("failed") == "true",
+	}
+	if viewer := APIKeyName(r); !tenant.IsOperator(viewer) {
+		filter.Tenant = viewer
+	}
+
+	switch filter.StatusClass {
+	case "", types.WebhookStatus2xx, types.WebhookStatus3xx, types.WebhookStatus4xx, types.WebhookStatus5xx, types.WebhookStatusNoReply:
+	default:
+		SendJSONError(w, "status must be 2xx, 3xx, 4xx, 5xx or none", http.StatusBadRequest)
+		return
+	}
+
+	if v := query.Get("webhook_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id <= 0 {
+			SendJSONError(w, "Invalid webhook_id", http.StatusBadRequest)
+			return
+		}
+		filter.WebhookConfigID = id
+	}
+	for name, t := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if v := query.Get(name); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				SendJSONError(w, name+" must be an RFC3339 time", http.StatusBadRequest)
+				return
+			}
+			*t = parsed
+		}
+	}
+	list, err := parseListQuery(r, listParams{
+		defaultLimit: defaultWebhookLogLimit,
+		maxLimit:     maxWebhookLogLimit,
+		sortFields:   database.WebhookLogSortFields,
+		item:         types.WebhookLog{},
+	})
+	if err != nil {
+		SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.Limit, filter.Offset = list.Limit, list.Offset
+	filter.Order = types.ListOrder{Field: list.Sort, Desc: list.Desc}
+
+	logs, err := s.messageStore.SearchWebhookLogs(filter)
 	if err != nil {
 		SendJSONError(w, fmt.Sprintf("Failed to get webhook logs: %v", err), http.StatusInternalServerError)
 		return
 	}
+	summary, err := s.messageStore.SummarizeWebhookLogs(filter)
+	if err != nil {
+		SendJSONError(w, fmt.Sprintf("Failed to get webhook logs: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if logs == nil {
+		logs = []*types.WebhookLog{}
+	}
 
-	_ = json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": true,
-		"data":    logs,
-	})
+	writeList(w, list, logs, summary.Total, map[string]interface{}{"summary": summary})
 }
 
 // handleReaction handles POST /api/reaction for sending emoji reactions.
@@ -361,8 +860,9 @@ func (s *Server) handleReaction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.client.SendReaction(req.ChatJID, req.MessageID, req.Emoji); err != nil {
-		SendJSONError(w, fmt.Sprintf("Failed to send reaction: %v", err), http.StatusInternalServerError)
+	result, err := queuedResult(s.outbox.SendReaction(r.Context(), req))
+	if err != nil || !result.Success {
+		writeQueuedSend(w, result, err, req.ChatJID)
 		return
 	}
 
@@ -399,7 +899,7 @@ func (s *Server) handleEditMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.client.EditMessage(req.ChatJID, req.MessageID, req.NewContent); err != nil {
+	if err := s.client.EditMessage(r.Context(), req.ChatJID, req.MessageID, req.NewContent); err != nil {
 		SendJSONError(w, fmt.Sprintf("Failed to edit message: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -437,7 +937,7 @@ func (s *Server) handleDeleteMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.client.DeleteMessage(req.ChatJID, req.MessageID, req.SenderJID); err != nil {
+	if err := s.client.DeleteMessage(r.Context(), req.ChatJID, req.MessageID, req.SenderJID); err != nil {
 		SendJSONError(w, fmt.Sprintf("Failed to delete message: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -472,7 +972,7 @@ func (s *Server) handleGetGroupInfo(w http.ResponseWriter, r *http.Request) {
 
 	groupJID := pathParts[0]
 
-	groupInfo, err := s.client.GetGroupInfo(groupJID)
+	groupInfo, err := s.client.GetGroupInfo(r.Context(), groupJID)
 	if err != nil {
 		SendJSONError(w, fmt.Sprintf("Failed to get group info: %v", err), http.StatusInternalServerError)
 		return
@@ -529,7 +1029,7 @@ func (s *Server) handleMarkRead(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.client.MarkMessagesRead(req.ChatJID, req.MessageIDs, req.SenderJID); err != nil {
+	if err := s.client.MarkMessagesRead(r.Context(), req.ChatJID, req.MessageIDs, req.SenderJID); err != nil {
 		SendJSONError(w, fmt.Sprintf("Failed to mark messages as read: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -547,8 +1047,12 @@ func (s *Server) handleMarkRead(w http.ResponseWriter, r *http.Request) {
 // Request body:
 //   - name: Group name (required)
 //   - participants: Array of JIDs to add (optional)
+//   - disappearing: Initial disappearing timer "off", "24h", "7d", "90d" (optional)
+//   - announce_only: Only admins can send messages (optional)
+//   - approval_required: Admins must approve new members (optional)
+//   - description: Group description (optional)
 //
-// Response: { success: bool, group_jid: string, name: string }
+// Response: { success: bool, group_jid: string, name: string, warning?: string }
 func (s *Server) handleCreateGroup(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -568,17 +1072,23 @@ func (s *Server) handleCreateGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	groupInfo, err := s.client.CreateGroup(req.Name, req.Participants)
-	if err != nil {
+	groupInfo, err := s.client.CreateGroup(r.Context(), req)
+	if groupInfo == nil {
 		SendJSONError(w, fmt.Sprintf("Failed to create group: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+	resp := map[string]interface{}{
 		"success":   true,
 		"group_jid": groupInfo.JID.String(),
 		"name":      groupInfo.Name,
-	})
+	}
+	if err != nil {
+		// Group exists; only the follow-up description update failed
+		resp["warning"] = err.Error()
+	}
+
+	_ = json.NewEncoder(w).Encode(resp)
 }
 
 // handleAddGroupMembers handles POST /api/group/add for adding group members.
@@ -586,8 +1096,9 @@ func (s *Server) handleCreateGroup(w http.ResponseWriter, r *http.Request) {
 // Request body:
 //   - group_jid: Target group (required)
 //   - participants: Array of JIDs to add (required)
+//   - invite_fallback: DM the invite link to users whose privacy settings block adding (optional)
 //
-// Response: { success: bool, participants: [{jid, error}] }
+// Response: { success: bool, participants: [{jid, added, error_code, reason, resolution, invite_error}] }
 func (s *Server) handleAddGroupMembers(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -607,24 +1118,15 @@ func (s *Server) handleAddGroupMembers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	results, err := s.client.AddGroupParticipants(req.GroupJID, req.Participants)
+	results, err := s.client.AddGroupParticipantsWithFallback(r.Context(), s.messageStore, req.GroupJID, req.Participants, req.InviteFallback)
 	if err != nil {
 		SendJSONError(w, fmt.Sprintf("Failed to add members: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	// Convert results to JSON-friendly format
-	added := make([]map[string]interface{}, len(results))
-	for i, p := range results {
-		added[i] = map[string]interface{}{
-			"jid":   p.JID.String(),
-			"error": p.Error,
-		}
-	}
-
 	_ = json.NewEncoder(w).Encode(map[string]interface{}{
 		"success":      true,
-		"participants": added,
+		"participants": results,
 	})
 }
 
@@ -654,7 +1156,7 @@ func (s *Server) handleRemoveGroupMembers(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	results, err := s.client.RemoveGroupParticipants(req.GroupJID, req.Participants)
+	results, err := s.client.RemoveGroupParticipants(r.Context(), req.GroupJID, req.Participants)
 	if err != nil {
 		SendJSONError(w, fmt.Sprintf("Failed to remove members: %v", err), http.StatusInternalServerError)
 		return
@@ -700,7 +1202,7 @@ func (s *Server) handlePromoteAdmin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err := s.client.PromoteGroupParticipant(req.GroupJID, req.Participant)
+	_, err := s.client.PromoteGroupParticipant(r.Context(), req.GroupJID, req.Participant)
 	if err != nil {
 		SendJSONError(w, fmt.Sprintf("Failed to promote admin: %v", err), http.StatusInternalServerError)
 		return
@@ -740,7 +1242,7 @@ func (s *Server) handleDemoteAdmin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err := s.client.DemoteGroupParticipant(req.GroupJID, req.Participant)
+	_, err := s.client.DemoteGroupParticipant(r.Context(), req.GroupJID, req.Participant)
 	if err != nil {
 		SendJSONError(w, fmt.Sprintf("Failed to demote admin: %v", err), http.StatusInternalServerError)
 		return
@@ -779,7 +1281,7 @@ func (s *Server) handleLeaveGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := s.client.LeaveGroup(req.GroupJID)
+	err := s.client.LeaveGroup(r.Context(), req.GroupJID)
 	if err != nil {
 		SendJSONError(w, fmt.Sprintf("Failed to leave group: %v", err), http.StatusInternalServerError)
 		return
@@ -827,13 +1329,13 @@ func (s *Server) handleUpdateGroup(w http.ResponseWriter, r *http.Request) {
 	var errors []string
 
 	if req.Name != "" {
-		if err := s.client.SetGroupName(req.GroupJID, req.Name); err != nil {
+		if err := s.client.SetGroupName(r.Context(), req.GroupJID, req.Name); err != nil {
 			errors = append(errors, fmt.Sprintf("name: %v", err))
 		}
 	}
 
 	if req.Topic != "" {
-		if err := s.client.SetGroupTopic(req.GroupJID, req.Topic); err != nil {
+		if err := s.client.SetGroupTopic(r.Context(), req.GroupJID, req.Topic); err != nil {
 			errors = append(errors, fmt.Sprintf("topic: %v", err))
 		}
 	}
@@ -884,9 +1386,9 @@ func (s *Server) handleCreatePoll(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := s.client.CreatePoll(req.ChatJID, req.Question, req.Options, req.MultiSelect)
-	if err != nil {
-		SendJSONError(w, fmt.Sprintf("Failed to create poll: %v", err), http.StatusInternalServerError)
+	result, err := queuedResult(s.outbox.CreatePoll(r.Context(), req))
+	if err != nil || !result.Success {
+		writeQueuedSend(w, result, err, req.ChatJID)
 		return
 	}
 
@@ -900,6 +1402,106 @@ func (s *Server) handleCreatePoll(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handlePollVote handles POST /api/poll/vote for voting in a poll someone
+// sent, or one of our own. A new vote replaces our earlier one.
+//
+// Request body:
+//   - chat_jid: Chat the poll is in (required)
+//   - message_id: ID of the poll's message (required)
+//   - options: Indices of the options to pick, from 0; empty takes the vote back
+//
+// Response: { success: bool, data: PollVote }
+func (s *Server) handlePollVote(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req types.PollVoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+	if req.ChatJID == "" || req.MessageID == "" {
+		SendJSONError(w, "chat_jid and message_id are required", http.StatusBadRequest)
+		return
+	}
+
+	poll, err := s.messageStore.GetPoll(req.ChatJID, req.MessageID)
+	if err != nil {
+		SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if poll == nil {
+		SendJSONError(w, "Poll not found", http.StatusNotFound)
+		return
+	}
+	options, err := whatsapp.PollSelection(poll, req.Options)
+	if err != nil {
+		SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, vote, err := s.outbox.VotePoll(r.Context(), poll.ChatJID, poll.MessageID, options)
+	if result, err = queuedResult(result, err); err != nil || !result.Success {
+		writeQueuedSend(w, result, err, req.ChatJID)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    vote,
+	})
+}
+
+// handlePollResults handles GET /api/poll/{message_id}/results for the votes
+// on a poll, whether created through the API, on the phone or by another
+// member. Only polls created while the bridge was running are known.
+//
+// Query parameters:
+//   - chat_jid: Chat the poll is in (optional; any chat when omitted)
+//
+// Response: { success: bool, data: PollResults } with a count and the voters
+// for each option, in the poll's order
+func (s *Server) handlePollResults(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// Parse path: /api/poll/{message_id}/results
+	pathParts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/poll/"), "/")
+	if len(pathParts) != 2 || pathParts[0] == "" || pathParts[1] != "results" {
+		SendJSONError(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	poll, err := s.messageStore.GetPoll(r.URL.Query().Get("chat_jid"), pathParts[0])
+	if err != nil {
+		SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if poll == nil {
+		SendJSONError(w, "Poll not found", http.StatusNotFound)
+		return
+	}
+
+	results, err := s.messageStore.GetPollResults(poll)
+	if err != nil {
+		SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    results,
+	})
+}
+
 // Phase 4: History Sync
 
 // handleRequestHistory handles POST /api/history for requesting older messages.
@@ -935,7 +1537,7 @@ func (s *Server) handleRequestHistory(w http.ResponseWriter, r *http.Request) {
 		req.Count = 50
 	}
 
-	err := s.client.RequestChatHistory(req.ChatJID, req.OldestMsgID, req.OldestMsgFromMe, req.OldestMsgTimestamp, req.Count)
+	err := s.client.RequestChatHistory(r.Context(), req.ChatJID, req.OldestMsgID, req.OldestMsgFromMe, req.OldestMsgTimestamp, req.Count)
 	if err != nil {
 		SendJSONError(w, fmt.Sprintf("Failed to request history: %v", err), http.StatusInternalServerError)
 		return
@@ -976,7 +1578,7 @@ func (s *Server) handleSetPresence(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := s.client.SetPresence(req.Presence)
+	err := s.client.SetPresence(r.Context(), req.Presence)
 	if err != nil {
 		SendJSONError(w, fmt.Sprintf("Failed to set presence: %v", err), http.StatusInternalServerError)
 		return
@@ -1013,7 +1615,7 @@ func (s *Server) handleSubscribePresence(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err := s.client.SubscribeToPresence(req.JID)
+	err := s.client.SubscribeToPresence(r.Context(), req.JID)
 	if err != nil {
 		SendJSONError(w, fmt.Sprintf("Failed to subscribe to presence: %v", err), http.StatusInternalServerError)
 		return
@@ -1062,7 +1664,7 @@ func (s *Server) handleGetProfilePicture(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	info, err := s.client.GetProfilePicture(jid, preview)
+	info, err := s.client.GetProfilePicture(r.Context(), jid, preview)
 	if err != nil {
 		SendJSONError(w, fmt.Sprintf("Failed to get profile picture: %v", err), http.StatusInternalServerError)
 		return
@@ -1099,7 +1701,7 @@ func (s *Server) handleGetBlocklist(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 
-	users, err := s.client.GetBlockedUsers()
+	users, err := s.client.GetBlockedUsers(r.Context())
 	if err != nil {
 		SendJSONError(w, fmt.Sprintf("Failed to get blocklist: %v", err), http.StatusInternalServerError)
 		return
@@ -1138,7 +1740,7 @@ func (s *Server) handleUpdateBlocklist(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := s.client.UpdateBlockedUser(req.JID, req.Action)
+	err := s.client.UpdateBlockedUser(r.Context(), req.JID, req.Action)
 	if err != nil {
 		SendJSONError(w, fmt.Sprintf("Failed to update blocklist: %v", err), http.StatusInternalServerError)
 		return
@@ -1176,7 +1778,7 @@ func (s *Server) handleFollowNewsletter(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err := s.client.FollowNewsletterChannel(req.JID)
+	err := s.client.FollowNewsletterChannel(r.Context(), req.JID)
 	if err != nil {
 		SendJSONError(w, fmt.Sprintf("Failed to follow newsletter: %v", err), http.StatusInternalServerError)
 		return
@@ -1214,7 +1816,7 @@ func (s *Server) handleUnfollowNewsletter(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err := s.client.UnfollowNewsletterChannel(req.JID)
+	err := s.client.UnfollowNewsletterChannel(r.Context(), req.JID)
 	if err != nil {
 		SendJSONError(w, fmt.Sprintf("Failed to unfollow newsletter: %v", err), http.StatusInternalServerError)
 		return
@@ -1253,7 +1855,7 @@ func (s *Server) handleCreateNewsletter(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	info, err := s.client.CreateNewsletterChannel(req.Name, req.Description)
+	info, err := s.client.CreateNewsletterChannel(r.Context(), req.Name, req.Description)
 	if err != nil {
 		SendJSONError(w, fmt.Sprintf("Failed to create newsletter: %v", err), http.StatusInternalServerError)
 		return
@@ -1267,6 +1869,219 @@ func (s *Server) handleCreateNewsletter(w http.ResponseWriter, r *http.Request)
 	})
 }
 
+// handleNewsletterMessage handles GET /api/newsletter/{jid}/message/{server_id}
+// for channel post engagement metrics.
+//
+// Response: { success: bool, data: { jid, server_id, message_id, type, timestamp,
+// content, views_count, reaction_counts, total_reactions } }
+func (s *Server) handleNewsletterMessage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// Parse path: /api/newsletter/{jid}/message/{server_id}
+	pathParts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/newsletter/"), "/")
+	if len(pathParts) != 3 || pathParts[0] == "" || pathParts[1] != "message" {
+		SendJSONError(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	serverID := 0
+	if _, err := fmt.Sscanf(pathParts[2], "%d", &serverID); err != nil || serverID <= 0 {
+		SendJSONError(w, "Invalid message server ID", http.StatusBadRequest)
+		return
+	}
+
+	if !s.client.IsConnected() {
+		SendJSONError(w, "Not connected to WhatsApp", http.StatusServiceUnavailable)
+		return
+	}
+
+	stats, err := s.client.GetNewsletterMessageStats(r.Context(), pathParts[0], serverID)
+	if err != nil {
+		SendJSONError(w, fmt.Sprintf("Failed to get newsletter message: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if stats == nil {
+		SendJSONError(w, "Newsletter message not found", http.StatusNotFound)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    stats,
+	})
+}
+
+// handleNewsletterReact handles POST /api/newsletter/react for reacting to channel posts.
+//
+// Request body:
+//   - jid: Newsletter/channel JID (required)
+//   - server_id: Post server ID (required)
+//   - reaction: Emoji (empty string to remove reaction)
+//
+// Response: { success: bool, message }
+func (s *Server) handleNewsletterReact(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req types.NewsletterReactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	if req.JID == "" || req.ServerID <= 0 {
+		SendJSONError(w, "jid and server_id are required", http.StatusBadRequest)
+		return
+	}
+
+	if !s.client.IsConnected() {
+		SendJSONError(w, "Not connected to WhatsApp", http.StatusServiceUnavailable)
+		return
+	}
+
+	result, err := queuedResult(s.outbox.ReactToNewsletterMessage(r.Context(), req))
+	if err != nil || !result.Success {
+		writeQueuedSend(w, result, err, req.JID)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Reaction sent",
+	})
+}
+
+// handleNewsletterMute handles POST /api/newsletter/mute for muting followed channels.
+//
+// Request body:
+//   - jid: Newsletter/channel JID (required)
+//   - mute: true to mute, false to unmute
+//
+// Response: { success: bool, data: NewsletterSettings }
+func (s *Server) handleNewsletterMute(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req types.NewsletterMuteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	if !strings.HasSuffix(req.JID, "@newsletter") {
+		SendJSONError(w, "A newsletter jid is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.client.MuteNewsletterChannel(r.Context(), req.JID, req.Mute); err != nil {
+		SendJSONError(w, fmt.Sprintf("Failed to update newsletter mute: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	settings, err := s.updateNewsletterSettings(req.JID, func(ns *types.NewsletterSettings) {
+		ns.Muted = req.Mute
+	})
+	if err != nil {
+		SendJSONError(w, fmt.Sprintf("Failed to store newsletter settings: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    settings,
+	})
+}
+
+// handleNewsletterSettings handles GET/PUT /api/newsletter/settings.
+// Controls whether a newsletter's posts are stored in the archive and delivered to webhooks.
+//
+// PUT Request body:
+//   - jid: Newsletter/channel JID (required)
+//   - persist: Store posts in the message archive (optional)
+//   - webhooks: Deliver posts to webhooks (optional)
+//
+// Response: { success: bool, data: NewsletterSettings } (GET returns all settings keyed by JID)
+func (s *Server) handleNewsletterSettings(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    s.client.NewsletterSettings(),
+		})
+
+	case http.MethodPut:
+		var req types.UpdateNewsletterSettingsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+
+		if !strings.HasSuffix(req.JID, "@newsletter") {
+			SendJSONError(w, "A newsletter jid is required", http.StatusBadRequest)
+			return
+		}
+		if req.Persist == nil && req.Webhooks == nil {
+			SendJSONError(w, "persist or webhooks is required", http.StatusBadRequest)
+			return
+		}
+
+		settings, err := s.updateNewsletterSettings(req.JID, func(ns *types.NewsletterSettings) {
+			if req.Persist != nil {
+				ns.Persist = *req.Persist
+			}
+			if req.Webhooks != nil {
+				ns.Webhooks = *req.Webhooks
+			}
+		})
+		if err != nil {
+			SendJSONError(w, fmt.Sprintf("Failed to store newsletter settings: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    settings,
+		})
+
+	default:
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// updateNewsletterSettings applies fn to a newsletter's settings, persists the
+// full set and hands it to the client. Concurrent changes are applied one at
+// a time, so none is lost.
+func (s *Server) updateNewsletterSettings(jid string, fn func(*types.NewsletterSettings)) (types.NewsletterSettings, error) {
+	s.newsletterMu.Lock()
+	defer s.newsletterMu.Unlock()
+
+	all := s.client.NewsletterSettings()
+	settings := s.client.NewsletterSettingsFor(jid)
+	fn(&settings)
+	all[jid] = settings
+
+	if err := s.messageStore.SetJSONSetting(database.SettingNewsletters, all); err != nil {
+		return settings, err
+	}
+	s.client.SetNewsletterSettings(all)
+	return settings, nil
+}
+
 // Phase 6: Chat Features
 
 // handleSendTyping handles POST /api/typing for sending typing indicators.
@@ -1300,7 +2115,7 @@ func (s *Server) handleSendTyping(w http.ResponseWriter, r *http.Request) {
 		req.State = "typing"
 	}
 
-	err := s.client.SendTypingIndicator(req.ChatJID, req.State)
+	err := s.client.SendTypingIndicator(r.Context(), req.ChatJID, req.State)
 	if err != nil {
 		SendJSONError(w, fmt.Sprintf("Failed to send typing indicator: %v", err), http.StatusInternalServerError)
 		return
@@ -1333,7 +2148,7 @@ func (s *Server) handleSetAbout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := s.client.SetAboutText(req.Text)
+	err := s.client.SetAboutText(r.Context(), req.Text)
 	if err != nil {
 		SendJSONError(w, fmt.Sprintf("Failed to set about text: %v", err), http.StatusInternalServerError)
 		return
@@ -1376,7 +2191,7 @@ func (s *Server) handleSetDisappearingTimer(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	err := s.client.SetDisappearingTimer(req.ChatJID, req.Duration)
+	err := s.client.SetDisappearingTimer(r.Context(), req.ChatJID, req.Duration)
 	if err != nil {
 		SendJSONError(w, fmt.Sprintf("Failed to set disappearing timer: %v", err), http.StatusInternalServerError)
 		return
@@ -1389,20 +2204,49 @@ func (s *Server) handleSetDisappearingTimer(w http.ResponseWriter, r *http.Reque
 	})
 }
 
-// handleGetPrivacySettings handles GET /api/privacy for fetching privacy settings.
+// handlePrivacySettings handles GET/POST /api/privacy for the account's privacy settings.
+//
+// POST Request body (only the settings to change):
+//   - last_seen, profile, status (About): "all", "contacts", "contact_blacklist" or "none"
+//   - group_add: "all", "contacts", "contact_blacklist" or "none"
+//   - read_receipts: "all" or "none"
+//   - online: "all" or "match_last_seen"
+//   - call_add: "all" or "known"
 //
 // Response: { success: bool, settings: { group_add, last_seen, status, profile, read_receipts, call_add, online } }
-func (s *Server) handleGetPrivacySettings(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
+func (s *Server) handlePrivacySettings(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	settings, err := s.client.GetPrivacySettings()
-	if err != nil {
-		SendJSONError(w, fmt.Sprintf("Failed to fetch privacy settings: %v", err), http.StatusInternalServerError)
+	var settings map[string]string
+	var err error
+
+	switch r.Method {
+	case http.MethodGet:
+		settings, err = s.client.GetPrivacySettings(r.Context())
+		if err != nil {
+			SendJSONError(w, fmt.Sprintf("Failed to fetch privacy settings: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+	case http.MethodPost:
+		var changes map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&changes); err != nil {
+			SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+		if err := whatsapp.ValidatePrivacySettings(changes); err != nil {
+			SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		settings, err = s.client.SetPrivacySettings(r.Context(), changes)
+		if err != nil {
+			SendJSONError(w, fmt.Sprintf("Failed to update privacy settings: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+	default:
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
@@ -1440,9 +2284,9 @@ func (s *Server) handlePinChat(w http.ResponseWriter, r *http.Request) {
 
 	var err error
 	if req.Pin {
-		err = s.client.PinChat(req.ChatJID)
+		err = s.client.PinChat(r.Context(), req.ChatJID)
 	} else {
-		err = s.client.UnpinChat(req.ChatJID)
+		err = s.client.UnpinChat(r.Context(), req.ChatJID)
 	}
 
 	if err != nil {
@@ -1490,9 +2334,9 @@ func (s *Server) handleMuteChat(w http.ResponseWriter, r *http.Request) {
 			SendJSONError(w, "duration is required when muting", http.StatusBadRequest)
 			return
 		}
-		err = s.client.MuteChat(req.ChatJID, req.Duration)
+		err = s.client.MuteChat(r.Context(), req.ChatJID, req.Duration)
 	} else {
-		err = s.client.UnmuteChat(req.ChatJID)
+		err = s.client.UnmuteChat(r.Context(), req.ChatJID)
 	}
 
 	if err != nil {
@@ -1540,9 +2384,9 @@ func (s *Server) handleArchiveChat(w http.ResponseWriter, r *http.Request) {
 
 	var err error
 	if req.Archive {
-		err = s.client.ArchiveChat(req.ChatJID)
+		err = s.client.ArchiveChat(r.Context(), req.ChatJID)
 	} else {
-		err = s.client.UnarchiveChat(req.ChatJID)
+		err = s.client.UnarchiveChat(r.Context(), req.ChatJID)
 	}
 
 	if err != nil {
@@ -1578,7 +2422,7 @@ func (s *Server) handlePairPhone(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	code, err := s.client.PairWithPhone(req.PhoneNumber)
+	code, err := s.client.PairWithPhone(r.Context(), req.PhoneNumber)
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -1593,7 +2437,33 @@ func (s *Server) handlePairPhone(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(types.PairPhoneResponse{
 		Success:   true,
 		Code:      code,
-		ExpiresIn: 160,
+		ExpiresIn: int(whatsapp.PairingCodeLifetime.Seconds()),
+	})
+}
+
+// handlePairCancel ends phone number pairing; the code is discarded and no
+// longer renewed when it expires
+// POST /api/pair/cancel
+// Response: { success: bool, message: string }
+func (s *Server) handlePairCancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := s.client.CancelPairing(); err != nil {
+		if errors.Is(err, whatsapp.ErrNoPairing) {
+			SendJSONError(w, "No pairing in progress", http.StatusConflict)
+			return
+		}
+		SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Pairing cancelled",
 	})
 }
 
@@ -1624,7 +2494,14 @@ func (s *Server) handlePairingStatus(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(resp)
 }
 
-// handleHealth returns 200 if connected, 503 if not. No auth required.
+// SetDiskMonitor reports the store volume's free space on /api/health
+func (s *Server) SetDiskMonitor(m *diskspace.Monitor) {
+	s.disk = m
+}
+
+// handleHealth returns 200 if connected, 503 if not. No auth required. A
+// store volume low on space is reported as disk.low without failing the
+// check: restarting the bridge would not free any.
 // GET /api/health
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -1632,6 +2509,19 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Read replicas have no WhatsApp connection; they are healthy while the
+	// shared database answers
+	if s.readReplica {
+		w.Header().Set("Content-Type", "application/json")
+		resp := map[string]interface{}{"mode": "read_replica"}
+		if err := s.messageStore.GetDB().Ping(); err != nil {
+			resp["database_error"] = err.Error()
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+
 	connected := s.client.IsConnected()
 	startedAt, lastConn, discAt, reconnErrs := s.client.ConnectionState()
 
@@ -1641,11 +2531,16 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 		"reconnect_errs": reconnErrs,
 	}
 	if !lastConn.IsZero() {
-		resp["last_connected"] = lastConn.Format(time.RFC3339)
+		resp["last_connected"] = lastConn.UTC().Format(time.RFC3339)
 	}
 	if !discAt.IsZero() {
 		resp["disconnected_for"] = time.Since(discAt).Round(time.Second).String()
 	}
+	if s.disk != nil {
+		if disk, ok := s.disk.Status(); ok {
+			resp["disk"] = disk
+		}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if !connected {
@@ -1667,7 +2562,7 @@ func (s *Server) handleReconnect(w http.ResponseWriter, r *http.Request) {
 	go func() {
 		time.Sleep(2 * time.Second)
 		if err := s.client.Client.Connect(); err != nil {
-			fmt.Printf("Reconnect failed: %v\n", err)
+			logger.Errorf("Reconnect failed: %v", err)
 		}
 	}()
 
@@ -1678,7 +2573,9 @@ func (s *Server) handleReconnect(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// handleConnectionStatus returns WhatsApp connection state
+// handleConnectionStatus returns WhatsApp connection state, including any
+// temporary ban or rate limit WhatsApp has placed on the account and the
+// latest ping round trip and event handling lag
 // GET /api/connection
 func (s *Server) handleConnectionStatus(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -1702,11 +2599,17 @@ func (s *Server) handleConnectionStatus(w http.ResponseWriter, r *http.Request)
 		resp.JID = s.client.Store.ID.String()
 	}
 	if !lastConn.IsZero() {
-		resp.LastConnected = lastConn.Format(time.RFC3339)
+		resp.LastConnected = lastConn.UTC().Format(time.RFC3339)
 	}
 	if !discAt.IsZero() {
 		resp.DisconnectedFor = time.Since(discAt).Round(time.Second).String()
 	}
+	if restriction, ok := s.client.Restriction(); ok {
+		resp.Restriction = &restriction
+	}
+	if quality, ok := s.client.ConnectionQuality(); ok {
+		resp.Quality = &quality
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(resp)
@@ -1724,24 +2627,19 @@ func (s *Server) handleSyncStatus(w http.ResponseWriter, r *http.Request) {
 	msgCount, _ := s.messageStore.GetMessageCount()
 	chatCount, _ := s.messageStore.GetChatCount()
 
-	// Get last synced message timestamp
-	var lastSync string
-	var lastSyncStr sql.NullString
-	err := s.messageStore.GetDB().QueryRow("SELECT MAX(timestamp) FROM messages").Scan(&lastSyncStr)
-	if err == nil && lastSyncStr.Valid {
-		// Parse the timestamp string and reformat as RFC3339
-		if t, parseErr := time.Parse("2006-01-02 15:04:05-07:00", lastSyncStr.String); parseErr == nil {
-			lastSync = t.Format(time.RFC3339)
-		}
-	}
-
 	resp := types.SyncStatusResponse{
 		Success:           true,
 		Syncing:           false,
 		SyncProgress:      100,
-		LastSync:          lastSync,
 		MessageCount:      msgCount,
 		ConversationCount: chatCount,
+		DisplayTimezone:   s.displayTimezone().String(),
+	}
+
+	// Last synced message time, in UTC and in the display time zone
+	if lastSync, err := s.messageStore.GetLastMessageTime(); err == nil && !lastSync.IsZero() {
+		resp.LastSync = lastSync.UTC().Format(time.RFC3339)
+		resp.LastSyncLocal = lastSync.In(s.displayTimezone()).Format(time.RFC3339)
 	}
 
 	// Provide sync troubleshooting recommendations
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+// SetListenAddress limits the TCP listener to one host address, e.g.
+// "127.0.0.1" so only a proxy on the same host can reach the API. Empty
+// listens on all interfaces.
+func (s *Server) SetListenAddress(host string) {
+	s.bindHost = host
+}
+
+// SetUnixSocket serves the API on a Unix domain socket at path instead of
+// TCP. The socket file is created with mode permissions, so access can be
+// limited to the proxy's user or group.
+func (s *Server) SetUnixSocket(path string, mode os.FileMode) {
+	s.socketPath = path
+	s.socketMode = mode
+}
+
+// Address describes where the API listens, for logs and startup output
+func (s *Server) Address() string {
+	if s.socketPath != "" {
+		return "unix:" + s.socketPath
+	}
+	return net.JoinHostPort(s.bindHost, strconv.Itoa(s.port))
+}
+
+// listen opens the configured listener. A socket file left behind by a
+// previous run is removed first; any other file at the path is an error.
+func (s *Server) listen() (net.Listener, error) {
+	if s.socketPath == "" {
+		return net.Listen("tcp", net.JoinHostPort(s.bindHost, strconv.Itoa(s.port)))
+	}
+
+	if info, err := os.Lstat(s.socketPath); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", s.socketPath)
+		}
+		if err := os.Remove(s.socketPath); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+
+	ln, err := net.Listen("unix", s.socketPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(s.socketPath, s.socketMode); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+	return ln, nil
+}
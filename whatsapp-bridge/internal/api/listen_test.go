@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListenUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api.sock")
+
+	// A socket left behind by a previous run is replaced
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	s := &Server{}
+	s.SetUnixSocket(path, 0600)
+	ln, err := s.listen()
+	if err != nil {
+		t.Fatalf("listen() error = %v", err)
+	}
+	defer ln.Close()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if info.Mode()&os.ModeSocket == 0 || info.Mode().Perm() != 0600 {
+		t.Errorf("socket mode = %v, want socket with 0600", info.Mode())
+	}
+	if got := s.Address(); got != "unix:"+path {
+		t.Errorf("Address() = %q", got)
+	}
+}
+
+func TestListenUnixSocketRefusesOtherFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api.sock")
+	if err := os.WriteFile(path, []byte("data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{}
+	s.SetUnixSocket(path, 0660)
+	if ln, err := s.listen(); err == nil {
+		ln.Close()
+		t.Fatal("listen() replaced a regular file")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("regular file removed: %v", err)
+	}
+}
+
+func TestListenLoopback(t *testing.T) {
+	s := &Server{}
+	s.SetListenAddress("127.0.0.1")
+	ln, err := s.listen()
+	if err != nil {
+		t.Fatalf("listen() error = %v", err)
+	}
+	defer ln.Close()
+
+	if ip := ln.Addr().(*net.TCPAddr).IP; !ip.IsLoopback() {
+		t.Errorf("listening on %v, want loopback", ip)
+	}
+	if got := s.Address(); got != "127.0.0.1:0" {
+		t.Errorf("Address() = %q", got)
+	}
+}
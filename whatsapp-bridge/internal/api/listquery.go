@@ -0,0 +1,204 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// List endpoints share one set of query parameters:
+//   - limit: Page size, between 1 and the endpoint's maximum
+//   - cursor: next_cursor from the previous page, to read the next one
+//   - offset: Items to skip; cursor is preferred and wins when both are given
+//   - sort: Field to sort by, "-" first for descending, e.g. "-timestamp"
+//   - fields: Comma-separated item fields to return, e.g. "id,content"
+//
+// and answer with { success: bool, data: [], total: int, limit: int,
+// offset: int, next_cursor: string }. next_cursor is left out on the last
+// page; the total is also sent in the X-Total-Count header.
+
+// listParams describes how one list endpoint pages and sorts
+type listParams struct {
+	defaultLimit int
+	maxLimit     int
+	sortFields   map[string]string // allowed sort fields by JSON name; nil when the list has a fixed order
+	item         interface{}       // an item of the list, for the fields it has
+}
+
+// listQuery is a list request's paging, sorting and field selection
+type listQuery struct {
+	Limit  int
+	Offset int
+	Sort   string // JSON name of the sort field; empty for the default order
+	Desc   bool
+	Fields []string // empty for every field
+}
+
+// parseListQuery reads the list parameters of a request
+func parseListQuery(r *http.Request, p listParams) (listQuery, error) {
+	query := r.URL.Query()
+	q := listQuery{Limit: p.defaultLimit}
+
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > p.maxLimit {
+			return q, fmt.Errorf("limit must be between 1 and %d", p.maxLimit)
+		}
+		q.Limit = n
+	}
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return q, fmt.Errorf("offset must not be negative")
+		}
+		q.Offset = n
+	}
+	if v := query.Get("cursor"); v != "" {
+		offset, err := decodeCursor(v)
+		if err != nil {
+			return q, err
+		}
+		q.Offset = offset
+	}
+
+	if v := query.Get("sort"); v != "" {
+		field := strings.TrimPrefix(v, "-")
+		if p.sortFields == nil {
+			return q, fmt.Errorf("this list cannot be sorted")
+		}
+		if _, ok := p.sortFields[field]; !ok {
+			return q, fmt.Errorf("sort must be one of: %s", strings.Join(sortedKeys(p.sortFields), ", "))
+		}
+		q.Sort, q.Desc = field, strings.HasPrefix(v, "-")
+	}
+
+	if v := query.Get("fields"); v != "" {
+		known := jsonFields(reflect.TypeOf(p.item))
+		for _, field := range strings.Split(v, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			if !known[field] {
+				return q, fmt.Errorf("unknown field %q", field)
+			}
+			q.Fields = append(q.Fields, field)
+		}
+	}
+	return q, nil
+}
+
+// writeList sends a page of a list. extra adds endpoint-specific keys to
+// the response, such as a summary.
+func writeList(w http.ResponseWriter, q listQuery, data interface{}, total int, extra map[string]interface{}) {
+	response := map[string]interface{}{
+		"success": true,
+		"data":    data,
+		"total":   total,
+		"limit":   q.Limit,
+		"offset":  q.Offset,
+	}
+	for k, v := range extra {
+		response[k] = v
+	}
+	if next := q.Offset + q.Limit; next < total {
+		response["next_cursor"] = encodeCursor(next)
+	}
+
+	if len(q.Fields) > 0 {
+		selected, err := selectFields(data, q.Fields)
+		if err != nil {
+			SendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		response["data"] = selected
+	}
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// Cursors are opaque to clients, so the paging scheme behind them can change
+// without breaking them
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("o:" + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil {
+		if v, ok := strings.CutPrefix(string(raw), "o:"); ok {
+			if offset, err := strconv.Atoi(v); err == nil && offset >= 0 {
+				return offset, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("invalid cursor")
+}
+
+// selectFields keeps only the named fields of each item
+func selectFields(items interface{}, fields []string) ([]map[string]json.RawMessage, error) {
+	encoded, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+	var all []map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &all); err != nil {
+		return nil, err
+	}
+
+	selected := make([]map[string]json.RawMessage, len(all))
+	for i, item := range all {
+		selected[i] = make(map[string]json.RawMessage, len(fields))
+		for _, field := range fields {
+			if v, ok := item[field]; ok {
+				selected[i][field] = v
+			}
+		}
+	}
+	return selected, nil
+}
+
+// jsonFields returns the JSON names of a struct's fields, including those of
+// embedded structs
+func jsonFields(t reflect.Type) map[string]bool {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	fields := make(map[string]bool)
+	if t == nil || t.Kind() != reflect.Struct {
+		return fields
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			for embedded := range jsonFields(f.Type) {
+				fields[embedded] = true
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = true
+	}
+	return fields
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"whatsapp-bridge/internal/types"
+)
+
+func TestParseListQuery(t *testing.T) {
+	params := listParams{
+		defaultLimit: 50,
+		maxLimit:     100,
+		sortFields:   map[string]string{"timestamp": "timestamp", "sender": "sender"},
+		item:         types.StoredMessage{},
+	}
+	parse := func(query string) (listQuery, error) {
+		return parseListQuery(httptest.NewRequest(http.MethodGet, "/api/messages?"+query, nil), params)
+	}
+
+	q, err := parse("")
+	if err != nil || q.Limit != 50 || q.Offset != 0 || q.Sort != "" || q.Fields != nil {
+		t.Errorf("defaults = %+v, %v", q, err)
+	}
+
+	q, err = parse("limit=10&offset=20&sort=-timestamp&fields=id,%20content")
+	if err != nil || q.Limit != 10 || q.Offset != 20 || q.Sort != "timestamp" || !q.Desc || len(q.Fields) != 2 || q.Fields[1] != "content" {
+		t.Errorf("parsed = %+v, %v", q, err)
+	}
+
+	// A cursor wins over an offset
+	q, err = parse("offset=5&cursor=" + encodeCursor(40))
+	if err != nil || q.Offset != 40 {
+		t.Errorf("cursor = %+v, %v", q, err)
+	}
+
+	for _, bad := range []string{"limit=0", "limit=101", "offset=-1", "cursor=nope", "sort=content", "fields=id,bogus"} {
+		if _, err := parse(bad); err == nil {
+			t.Errorf("%s accepted", bad)
+		}
+	}
+
+	params.sortFields = nil
+	if _, err := parse("sort=timestamp"); err == nil {
+		t.Error("sort accepted by a list without sort fields")
+	}
+}
+
+func TestWriteList(t *testing.T) {
+	items := []types.ChatSummary{{JID: "a@s.whatsapp.net", Name: "Ana", MessageCount: 3}, {JID: "b@g.us", Name: "Team", IsGroup: true}}
+
+	rec := httptest.NewRecorder()
+	writeList(rec, listQuery{Limit: 2, Offset: 0, Fields: []string{"jid", "message_count"}}, items, 5, map[string]interface{}{"summary": "x"})
+
+	var got struct {
+		Data       []map[string]interface{} `json:"data"`
+		Total      int                      `json:"total"`
+		NextCursor string                   `json:"next_cursor"`
+		Summary    string                   `json:"summary"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Total != 5 || rec.Header().Get("X-Total-Count") != "5" || got.Summary != "x" {
+		t.Errorf("response = %+v", got)
+	}
+	if offset, err := decodeCursor(got.NextCursor); err != nil || offset != 2 {
+		t.Errorf("next_cursor = %q (%d, %v)", got.NextCursor, offset, err)
+	}
+	if len(got.Data) != 2 || len(got.Data[0]) != 2 || got.Data[0]["jid"] != "a@s.whatsapp.net" || got.Data[0]["message_count"] != float64(3) {
+		t.Errorf("data = %v, want only jid and message_count", got.Data)
+	}
+
+	// The last page has no next cursor
+	rec = httptest.NewRecorder()
+	writeList(rec, listQuery{Limit: 2, Offset: 4}, items[:1], 5, nil)
+	var last map[string]interface{}
+	json.NewDecoder(rec.Body).Decode(&last)
+	if _, ok := last["next_cursor"]; ok {
+		t.Errorf("last page = %v, want no next_cursor", last)
+	}
+}
+
+func TestSortWebhooks(t *testing.T) {
+	webhooks := []types.WebhookConfigResponse{{ID: 3, Name: "b"}, {ID: 1, Name: "c"}, {ID: 2, Name: "b"}}
+
+	sortWebhooks(webhooks, "", false)
+	if webhooks[0].ID != 1 || webhooks[1].ID != 2 || webhooks[2].ID != 3 {
+		t.Errorf("default order = %+v", webhooks)
+	}
+	sortWebhooks(webhooks, "name", true)
+	if webhooks[0].Name != "c" || webhooks[1].ID != 3 || webhooks[2].ID != 2 {
+		t.Errorf("by name descending = %+v", webhooks)
+	}
+}
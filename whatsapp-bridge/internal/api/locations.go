@@ -0,0 +1,85 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Live location history limits
+const (
+	defaultLocationLimit = 100
+	maxLocationLimit     = 1000
+)
+
+// handleLiveLocation handles GET /api/locations/{jid}/live for a sender's
+// live location track.
+//
+// Query parameters:
+//   - chat_jid: Only points shared in this chat (optional)
+//   - since: RFC3339 timestamp; only newer points (optional)
+//   - limit: Maximum history points (default 100, max 1000)
+//
+// Response: { success: bool, data: { jid, latest: LocationPoint|null, history: LocationPoint[] } }
+// history is ordered newest first.
+func (s *Server) handleLiveLocation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// Parse path: /api/locations/{jid}/live
+	pathParts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/locations/"), "/")
+	if len(pathParts) != 2 || pathParts[0] == "" || pathParts[1] != "live" {
+		SendJSONError(w, "Not found", http.StatusNotFound)
+		return
+	}
+	senderJID := pathParts[0]
+
+	query := r.URL.Query()
+
+	limit := defaultLocationLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxLocationLimit {
+			SendJSONError(w, fmt.Sprintf("limit must be between 1 and %d", maxLocationLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	var since time.Time
+	if v := query.Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			SendJSONError(w, "since must be an RFC3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		since = t
+	}
+
+	points, err := s.messageStore.GetLocationPoints(senderJID, query.Get("chat_jid"), since, limit)
+	if err != nil {
+		SendJSONError(w, fmt.Sprintf("Failed to get location points: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]interface{}{
+		"jid":     senderJID,
+		"latest":  nil,
+		"history": points,
+	}
+	if len(points) > 0 {
+		data["latest"] = points[0]
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    data,
+	})
+}
@@ -0,0 +1,876 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"whatsapp-bridge/internal/database"
+	"whatsapp-bridge/internal/msgref"
+	"whatsapp-bridge/internal/phone"
+	"whatsapp-bridge/internal/tenant"
+	"whatsapp-bridge/internal/types"
+	"whatsapp-bridge/internal/whatsapp"
+)
+
+// Annotation search limits
+const (
+	defaultAnnotationLimit = 50
+	maxAnnotationLimit     = 500
+)
+
+// Message history page limits
+const (
+	defaultMessageLimit = 50
+	maxMessageLimit     = 1000
+)
+
+// Message search page limits
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 500
+)
+
+// Longest a message query may wait for new messages, and how often a
+// waiting query re-reads the database in case another process (the writer,
+// for a read replica) stored them
+const (
+	maxMessageWait     = 60 * time.Second
+	messageWaitRecheck = 2 * time.Second
+)
+
+// Chat list page limits
+const (
+	defaultChatLimit = 100
+	maxChatLimit     = 1000
+)
+
+// handleMessages handles GET /api/messages for reading the message archive,
+// newest first.
+//
+// Query parameters (all optional):
+//   - chat_jid: Only messages in this chat
+//   - sender: Only messages from this sender, by JID or phone number
+//   - media_type: Only this kind of media, e.g. "image"; "text" for messages without media
+//   - is_from_me: "true" or "false"
+//   - since, until: RFC3339 times bounding the message timestamp
+//   - include_archive: "true" to also read archived months (HISTORY_ARCHIVE_MONTHS);
+//     slower, so bound old ranges with since and until
+//   - limit, cursor, offset, fields: As for every list (see listquery.go);
+//     page size 50 by default, at most 1000
+//   - sort: timestamp, chat_jid, sender, sender_name or media_type, "-" first
+//     for descending (default newest first)
+//   - after: Ref of a message; only messages stored after it, in the order
+//     they were stored, so one delivered late with an older timestamp is not
+//     missed. Pass the ref of the last message received to read only new ones
+//   - wait_seconds: With after, how long to wait (at most 60) for a new
+//     message when there is none yet, for clients that cannot use the
+//     WebSocket or SSE streams. The response comes as soon as one is stored,
+//     or with no messages when the time (or REQUEST_TIMEOUT, if shorter) is up
+//
+// The total counts every matching message, not just the page; it is also
+// sent in the X-Total-Count header.
+//
+// Response: { success: bool, data: StoredMessage[], total: int, limit: int, offset: int, next_cursor: string }
+func (s *Server) handleMessages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	q := types.MessageQuery{
+		ChatJID:   query.Get("chat_jid"),
+		Sender:    query.Get("sender"),
+		MediaType: query.Get("media_type"),
+	}
+
+	if q.Sender != "" && !strings.Contains(q.Sender, "@") {
+		number, err := phone.Parse(q.Sender, "")
+		if err != nil {
+			SendJSONError(w, fmt.Sprintf("Invalid sender: %v", err), http.StatusBadRequest)
+			return
+		}
+		q.Sender = number.Digits
+	}
+	if v := query.Get("is_from_me"); v != "" {
+		fromMe, err := strconv.ParseBool(v)
+		if err != nil {
+			SendJSONError(w, "is_from_me must be true or false", http.StatusBadRequest)
+			return
+		}
+		q.IsFromMe = &fromMe
+	}
+	if v := query.Get("include_archive"); v != "" {
+		includeArchive, err := strconv.ParseBool(v)
+		if err != nil {
+			SendJSONError(w, "include_archive must be true or false", http.StatusBadRequest)
+			return
+		}
+		q.IncludeArchive = includeArchive
+	}
+	for name, t := range map[string]*time.Time{"since": &q.Since, "until": &q.Until} {
+		if v := query.Get(name); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				SendJSONError(w, name+" must be an RFC3339 time", http.StatusBadRequest)
+				return
+			}
+			*t = parsed
+		}
+	}
+	list, err := parseListQuery(r, listParams{
+		defaultLimit: defaultMessageLimit,
+		maxLimit:     maxMessageLimit,
+		sortFields:   database.MessageSortFields,
+		item:         types.StoredMessage{},
+	})
+	if err != nil {
+		SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q.Limit, q.Offset = list.Limit, list.Offset
+	q.Order = types.ListOrder{Field: list.Sort, Desc: list.Desc}
+
+	if ref := query.Get("after"); ref != "" {
+		chatJID, id, err := msgref.Decode(ref)
+		if err != nil {
+			SendJSONError(w, "Invalid after: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if q.AfterSeq, err = s.messageStore.MessageSeq(chatJID, id); err != nil {
+			SendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if q.AfterSeq == 0 {
+			SendJSONError(w, "after message not found", http.StatusNotFound)
+			return
+		}
+	}
+	var wait time.Duration
+	if v := query.Get("wait_seconds"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil || seconds < 0 || time.Duration(seconds)*time.Second > maxMessageWait {
+			SendJSONError(w, fmt.Sprintf("wait_seconds must be between 0 and %d", int(maxMessageWait.Seconds())), http.StatusBadRequest)
+			return
+		}
+		if q.AfterSeq == 0 {
+			SendJSONError(w, "wait_seconds needs after", http.StatusBadRequest)
+			return
+		}
+		wait = time.Duration(seconds) * time.Second
+	}
+
+	messages, err := s.waitForMessages(r.Context(), q, wait)
+	if err != nil {
+		SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	total, err := s.messageStore.CountMessages(q)
+	if err != nil {
+		SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, msg := range messages {
+		msg.Ref = msgref.Encode(msg.ChatJID, msg.ID)
+	}
+
+	writeList(w, list, messages, total, nil)
+}
+
+// waitForMessages queries messages, waiting up to wait for some to match
+// when none do yet. The wait also ends with ctx, and then the empty result
+// is returned rather than an error.
+func (s *Server) waitForMessages(ctx context.Context, q types.MessageQuery, wait time.Duration) ([]*types.StoredMessage, error) {
+	if wait <= 0 {
+		return s.messageStore.QueryMessages(q)
+	}
+
+	// Subscribe before the first query, so a message stored in between
+	// still wakes us
+	stored, cancel := s.messageStore.SubscribeMessages()
+	defer cancel()
+	timeout := time.NewTimer(wait)
+	defer timeout.Stop()
+	recheck := time.NewTicker(messageWaitRecheck)
+	defer recheck.Stop()
+
+	for {
+		messages, err := s.messageStore.QueryMessages(q)
+		if err != nil || len(messages) > 0 {
+			return messages, err
+		}
+		select {
+		case <-stored:
+		case <-recheck.C:
+		case <-timeout.C:
+			return messages, nil
+		case <-ctx.Done():
+			return messages, nil
+		}
+	}
+}
+
+// handleSearch handles GET /api/search, a full-text search over stored
+// message text.
+//
+// Query parameters:
+//   - q: Search query in SQLite FTS syntax (required, e.g. "invoice OR receipt", "deliver*")
+//   - chat_jid: Only messages in this chat (optional)
+//   - sender: Only messages from this sender, by JID or phone number (optional)
+//   - since, until: RFC3339 times bounding the message timestamp (optional)
+//   - order: "relevance" (default; ranks the newest 1000 matches) or "newest"
+//   - limit: Page size (default 50, max 500)
+//   - offset: Matches to skip, for later pages
+//
+// The total counts every match, not just the page; it is also sent in the
+// X-Total-Count header.
+//
+// Response: { success: bool, data: MessageSearchResult[], total: int, limit: int, offset: int }
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	q := types.MessageSearch{
+		Query:   strings.TrimSpace(query.Get("q")),
+		ChatJID: query.Get("chat_jid"),
+		Sender:  query.Get("sender"),
+		Order:   query.Get("order"),
+		Limit:   defaultSearchLimit,
+	}
+	if q.Query == "" {
+		SendJSONError(w, "q is required", http.StatusBadRequest)
+		return
+	}
+
+	if q.Sender != "" && !strings.Contains(q.Sender, "@") {
+		number, err := phone.Parse(q.Sender, "")
+		if err != nil {
+			SendJSONError(w, fmt.Sprintf("Invalid sender: %v", err), http.StatusBadRequest)
+			return
+		}
+		q.Sender = number.Digits
+	}
+	switch q.Order {
+	case "":
+		q.Order = database.SearchOrderRelevance
+	case database.SearchOrderRelevance, database.SearchOrderNewest:
+	default:
+		SendJSONError(w, "order must be relevance or newest", http.StatusBadRequest)
+		return
+	}
+	for name, t := range map[string]*time.Time{"since": &q.Since, "until": &q.Until} {
+		if v := query.Get(name); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				SendJSONError(w, name+" must be an RFC3339 time", http.StatusBadRequest)
+				return
+			}
+			*t = parsed
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			SendJSONError(w, fmt.Sprintf("limit must be between 1 and %d", maxSearchLimit), http.StatusBadRequest)
+			return
+		}
+		q.Limit = n
+	}
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			SendJSONError(w, "offset must not be negative", http.StatusBadRequest)
+			return
+		}
+		q.Offset = n
+	}
+
+	results, total, err := s.messageStore.SearchMessages(q)
+	if err != nil {
+		// A malformed FTS query is the caller's mistake
+		if strings.Contains(err.Error(), "MATCH") || strings.Contains(err.Error(), "syntax error") {
+			SendJSONError(w, fmt.Sprintf("Invalid search query: %v", err), http.StatusBadRequest)
+			return
+		}
+		SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, result := range results {
+		result.Ref = msgref.Encode(result.ChatJID, result.ID)
+	}
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    results,
+		"total":   total,
+		"limit":   q.Limit,
+		"offset":  q.Offset,
+	})
+}
+
+// handleMessage handles GET /api/messages/{ref} for one archived message.
+// ref is the opaque message reference returned as message_ref by the send
+// endpoints and as message.ref in webhook payloads.
+//
+// Response: { success: bool, data: StoredMessage }
+func (s *Server) handleMessage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	ref := strings.TrimPrefix(r.URL.Path, "/api/messages/")
+	if ref == "" || strings.Contains(ref, "/") {
+		SendJSONError(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	chatJID, id, err := msgref.Decode(ref)
+	if err != nil {
+		SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	msg, err := s.messageStore.GetMessage(chatJID, id)
+	if err != nil {
+		SendJSONError(w, fmt.Sprintf("Failed to get message: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if msg == nil {
+		SendJSONError(w, "Message not found", http.StatusNotFound)
+		return
+	}
+	msg.Ref = ref
+
+	if !msg.MetadataOnly {
+		annotations, err := s.messageStore.GetAnnotations(chatJID, id)
+		if err != nil {
+			SendJSONError(w, fmt.Sprintf("Failed to get annotations: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if len(annotations) > 0 {
+			msg.Annotations = annotations
+		}
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    msg,
+	})
+}
+
+// handleChats handles GET /api/chats for the chat list, most recently
+// active first. Each chat carries its stored message count and a preview
+// of its newest stored message.
+//
+// Query parameters (all optional):
+//   - limit, cursor, offset, fields: As for every list (see listquery.go);
+//     page size 100 by default, at most 1000
+//   - sort: last_message_time, name, jid or message_count, "-" first for
+//     descending (default most recently active first)
+//
+// The total counts every chat, not just the page; it is also sent in the
+// X-Total-Count header.
+//
+// Response: { success: bool, data: ChatSummary[], total: int, limit: int, offset: int, next_cursor: string }
+func (s *Server) handleChats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	list, err := parseListQuery(r, listParams{
+		defaultLimit: defaultChatLimit,
+		maxLimit:     maxChatLimit,
+		sortFields:   database.ChatSortFields,
+		item:         types.ChatSummary{},
+	})
+	if err != nil {
+		SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	chats, err := s.messageStore.ListChats(list.Limit, list.Offset, types.ListOrder{Field: list.Sort, Desc: list.Desc})
+	if err != nil {
+		SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	total, err := s.messageStore.GetChatCount()
+	if err != nil {
+		SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, chat := range chats {
+		if chat.LastMessage != nil {
+			chat.LastMessage.Ref = msgref.Encode(chat.JID, chat.LastMessage.ID)
+		}
+	}
+
+	writeList(w, list, chats, total, nil)
+}
+
+// handleUnreadChats handles GET /api/chats/unread for the chats with unread
+// messages. Counts start from the phone's state at history sync and follow
+// incoming messages and reads on any of the account's devices.
+//
+// Response: { success: bool, data: ChatReadState[] }
+func (s *Server) handleUnreadChats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	chats, err := s.messageStore.GetUnreadChats()
+	if err != nil {
+		SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    chats,
+	})
+}
+
+// handleAnnotationSearch handles GET /api/annotations/search, a full-text
+// search over text extracted from media, such as voice note transcripts and
+// text read from images.
+//
+// Query parameters:
+//   - q: Search query in SQLite FTS syntax (required, e.g. "invoice OR receipt")
+//   - kind: Only annotations of this kind: transcript or ocr (optional)
+//   - limit: Maximum matches (default 50, max 500)
+//
+// Response: { success: bool, data: AnnotationMatch[] } ordered newest first
+func (s *Server) handleAnnotationSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	q := strings.TrimSpace(query.Get("q"))
+	if q == "" {
+		SendJSONError(w, "q is required", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultAnnotationLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxAnnotationLimit {
+			SendJSONError(w, fmt.Sprintf("limit must be between 1 and %d", maxAnnotationLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	matches, err := s.messageStore.SearchAnnotations(q, query.Get("kind"), limit)
+	if err != nil {
+		// A malformed FTS query is the caller's mistake
+		if strings.Contains(err.Error(), "MATCH") || strings.Contains(err.Error(), "syntax error") {
+			SendJSONError(w, fmt.Sprintf("Invalid search query: %v", err), http.StatusBadRequest)
+			return
+		}
+		SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    matches,
+	})
+}
+
+// handleDownload handles POST /api/download for fetching a stored message's
+// media. The file is downloaded from WhatsApp, decrypted with the stored
+// media key and saved under store/media/{chat}/; media downloaded before is
+// served from there.
+//
+// Request body:
+//   - message_ref: The message's ref, or
+//   - chat_jid and message_id: The message's chat and ID
+//   - stream: Answer with the file itself instead of its path (optional)
+//
+// GET /api/download takes the same fields as query parameters and always
+// streams; webhooks link to it as media_download_url.
+//
+// Response: { success: bool, data: DownloadedMedia }, or the file when streamed
+func (s *Server) handleDownload(w http.ResponseWriter, r *http.Request) {
+	var req types.DownloadMediaRequest
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		req = types.DownloadMediaRequest{
+			MessageRef: q.Get("message_ref"),
+			ChatJID:    q.Get("chat_jid"),
+			MessageID:  q.Get("message_id"),
+			Stream:     true,
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+	default:
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	chatJID, id := req.ChatJID, req.MessageID
+	if req.MessageRef != "" {
+		var err error
+		if chatJID, id, err = msgref.Decode(req.MessageRef); err != nil {
+			SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if chatJID == "" || id == "" {
+		SendJSONError(w, "message_ref, or chat_jid and message_id, are required", http.StatusBadRequest)
+		return
+	}
+
+	media, err := s.messageStore.GetMessageMedia(chatJID, id)
+	if err != nil {
+		SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if media == nil {
+		SendJSONError(w, "Message not found or has no downloadable media", http.StatusNotFound)
+		return
+	}
+
+	downloaded, err := s.client.DownloadMessageMedia(r.Context(), media)
+	if errors.Is(err, whatsapp.ErrLowOnSpace) {
+		SendJSONError(w, err.Error(), http.StatusInsufficientStorage)
+		return
+	}
+	if err != nil {
+		SendJSONError(w, fmt.Sprintf("Failed to download media: %v", err), http.StatusBadGateway)
+		return
+	}
+	if media.LocalPath != downloaded.Path {
+		if err := s.messageStore.SetMediaPath(chatJID, id, downloaded.Path); err != nil {
+			logger.Warnf("Failed to record media path of %s: %v", id, err)
+		}
+	}
+
+	if req.Stream {
+		file, err := os.Open(downloaded.Path)
+		if err != nil {
+			SendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer file.Close()
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": downloaded.Filename}))
+		http.ServeContent(w, r, downloaded.Filename, time.Time{}, file)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    downloaded,
+	})
+}
+
+// handleMedia handles GET /api/media/{message} for playing or previewing
+// media that has been downloaded, automatically or through /api/download.
+// {message} is the message's ref, or its ID; an ID is looked up in the chat
+// given by the chat_jid query parameter, or else among all chats.
+//
+// The file is served with its Content-Type and Content-Length, and Range
+// requests are answered with partial content so players can seek.
+//
+// Response: the file, or 404 if the media has not been downloaded
+func (s *Server) handleMedia(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/api/media/")
+	if key == "" || strings.Contains(key, "/") {
+		SendJSONError(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	var media *types.MessageMedia
+	var err error
+	if chatJID, id, decodeErr := msgref.Decode(key); decodeErr == nil {
+		media, err = s.messageStore.GetMessageMedia(chatJID, id)
+	} else if chatJID := r.URL.Query().Get("chat_jid"); chatJID != "" {
+		media, err = s.messageStore.GetMessageMedia(chatJID, key)
+	} else {
+		media, err = s.messageStore.FindDownloadedMedia(key)
+	}
+	if err != nil {
+		SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if media == nil || media.LocalPath == "" {
+		SendJSONError(w, "Media not found or not downloaded; download it with /api/download first", http.StatusNotFound)
+		return
+	}
+
+	file, err := os.Open(media.LocalPath)
+	if os.IsNotExist(err) {
+		SendJSONError(w, "Downloaded media file is missing; download it again with /api/download", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if contentType := mediaContentType(media.LocalPath); contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": media.Filename}))
+	http.ServeContent(w, r, media.LocalPath, info.ModTime(), file)
+}
+
+// mediaContentType is the Content-Type of a downloaded media file by its
+// extension, covering WhatsApp's voice note and video formats that the
+// system table may lack. Empty leaves http.ServeContent to sniff it.
+func mediaContentType(path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	if t := mime.TypeByExtension(ext); t != "" {
+		return t
+	}
+	switch ext {
+	case ".ogg", ".opus":
+		return "audio/ogg"
+	case ".mp4":
+		return "video/mp4"
+	}
+	return ""
+}
+
+// handlePinMessage handles POST /api/messages/pin for pinning a message in a
+// chat for everyone in it, or unpinning it. Distinct from /api/pin, which
+// pins a chat in the chat list.
+//
+// Request body:
+//   - chat_jid: Chat containing the message (required)
+//   - message_id: Message to pin (required)
+//   - sender_jid: Author of the message; found from the archive when omitted,
+//     but required for a message the archive does not hold and for another
+//     member's message in a group
+//   - duration: How long the pin lasts: "24h", "7d" (default) or "30d"
+//   - unpin: true to remove the pin instead
+//
+// A chat holds at most three pins; pinning another replaces the oldest.
+//
+// Response: { success: bool, data: PinnedMessage } (data omitted on unpin)
+func (s *Server) handlePinMessage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req types.PinMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	if req.ChatJID == "" || req.MessageID == "" {
+		SendJSONError(w, "chat_jid and message_id are required", http.StatusBadRequest)
+		return
+	}
+	duration, err := whatsapp.ParsePinDuration(req.Duration)
+	if err != nil {
+		SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	senderJID := req.SenderJID
+	if senderJID == "" {
+		stored, err := s.messageStore.GetMessage(req.ChatJID, req.MessageID)
+		if err != nil {
+			SendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		switch {
+		case stored == nil:
+			SendJSONError(w, "sender_jid is required to pin a message that is not stored", http.StatusBadRequest)
+			return
+		case stored.IsFromMe:
+			// Our own message, as for /api/delete
+		case !strings.HasSuffix(req.ChatJID, "@g.us"):
+			senderJID = req.ChatJID
+		default:
+			SendJSONError(w, "sender_jid is required to pin another member's message in a group", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if req.Unpin {
+		result, err := queuedResult(s.outbox.UnpinMessage(r.Context(), req.ChatJID, req.MessageID, senderJID))
+		if err != nil || !result.Success {
+			writeQueuedSend(w, result, err, req.ChatJID)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+		})
+		return
+	}
+
+	result, pin, err := s.outbox.PinMessage(r.Context(), req.ChatJID, req.MessageID, senderJID, duration)
+	if result, err = queuedResult(result, err); err != nil || !result.Success {
+		writeQueuedSend(w, result, err, req.ChatJID)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    pin,
+	})
+}
+
+// handlePinnedMessages handles GET /api/messages/pinned for the messages
+// currently pinned in a chat, including pins made on the phone or by other
+// members.
+//
+// Query parameters:
+//   - chat_jid: Chat to list (required)
+//
+// Response: { success: bool, data: PinnedMessage[] } newest first
+func (s *Server) handlePinnedMessages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	chatJID := r.URL.Query().Get("chat_jid")
+	if chatJID == "" {
+		SendJSONError(w, "chat_jid is required", http.StatusBadRequest)
+		return
+	}
+
+	pins, err := s.messageStore.GetPinnedMessages(chatJID)
+	if err != nil {
+		SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    pins,
+	})
+}
+
+// handleMessageInfo handles what is known about a single message:
+//
+//	GET /api/message/{chat_jid}/{id}/status - how far a message we sent got:
+//	    whether it was delivered, read or played, by each recipient. Messages
+//	    sent from the phone have receipts too; those sent through the API
+//	    also have their send status.
+//	GET /api/message/{id}/reactions[?chat_jid=] - the emoji reactions to a
+//	    message, one per person, oldest first
+//	GET /api/message/{chat_jid}/{id}/edits - the text a message had before
+//	    each edit, oldest first; the first is the message as sent
+//
+// Response: { success: bool, data: MessageStatus | []MessageReaction | []MessageEdit }
+func (s *Server) handleMessageInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	pathParts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/message/"), "/")
+	var chatJID, messageID, resource string
+	switch {
+	case len(pathParts) == 3 && pathParts[0] != "" && pathParts[1] != "" && (pathParts[2] == "status" || pathParts[2] == "edits"):
+		chatJID, messageID, resource = pathParts[0], pathParts[1], pathParts[2]
+	case len(pathParts) == 2 && pathParts[0] != "" && pathParts[1] == "reactions":
+		chatJID, messageID, resource = r.URL.Query().Get("chat_jid"), pathParts[0], "reactions"
+	default:
+		SendJSONError(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	// Messages another tenant sent are not theirs to see
+	outgoing, err := s.messageStore.GetOutgoingMessage(messageID)
+	if err != nil {
+		SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if outgoing != nil && !tenant.Visible(APIKeyName(r), outgoing.Tenant) {
+		SendJSONError(w, "Message not found", http.StatusNotFound)
+		return
+	}
+
+	var data interface{}
+	switch resource {
+	case "reactions":
+		reactions, err := s.messageStore.GetMessageReactions(chatJID, messageID)
+		if err != nil {
+			SendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		data = reactions
+	case "edits":
+		edits, err := s.messageStore.GetMessageEdits(chatJID, messageID)
+		if err != nil {
+			SendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		data = edits
+	default:
+		status, err := s.messageStore.GetMessageStatus(chatJID, messageID)
+		if err != nil {
+			SendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if status == nil {
+			SendJSONError(w, "Message not found", http.StatusNotFound)
+			return
+		}
+		data = status
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    data,
+	})
+}
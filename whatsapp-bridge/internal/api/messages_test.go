@@ -0,0 +1,29 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"whatsapp-bridge/internal/database"
+)
+
+func TestPinMessageNeedsSender(t *testing.T) {
+	t.Chdir(t.TempDir())
+	store, err := database.NewMessageStore()
+	if err != nil {
+		t.Fatalf("NewMessageStore: %v", err)
+	}
+	defer store.Close()
+	s := &Server{messageStore: store}
+
+	// The author of a message the archive does not hold is unknown, even in
+	// a direct chat, so it is not guessed
+	body := `{"chat_jid":"15550102030@s.whatsapp.net","message_id":"UNSTORED"}`
+	rec := httptest.NewRecorder()
+	s.handlePinMessage(rec, httptest.NewRequest(http.MethodPost, "/api/messages/pin", strings.NewReader(body)))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "sender_jid") {
+		t.Errorf("pin of an unstored message = %d %s, want sender_jid required", rec.Code, rec.Body.String())
+	}
+}
@@ -1,50 +1,70 @@
 package api
 
 import (
+	"context"
 	"crypto/subtle"
+	"fmt"
+	"math"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
-	"sync"
 	"time"
 
+	"whatsapp-bridge/internal/recovery"
+	"whatsapp-bridge/internal/secrets"
 	"whatsapp-bridge/internal/security"
+	"whatsapp-bridge/internal/tenant"
+	"whatsapp-bridge/internal/usage"
 )
 
-// Rate limiter state
-var (
-	rateLimitMu     sync.Mutex
-	requestCounts   = make(map[string]int)
-	requestWindows  = make(map[string]time.Time)
-	rateLimit       = 100 // requests per window
-	rateLimitWindow = time.Minute
-)
+// DefaultKeyName identifies the primary API_KEY; it is also used for all
+// requests when authentication is disabled
+const DefaultKeyName = tenant.Default
 
-// getAllowedOrigins returns the list of allowed CORS origins
-func getAllowedOrigins() map[string]bool {
-	origins := map[string]bool{
-		"http://localhost:8089": true, // Webhook UI
-		"http://localhost:8082": true, // Gradio UI
-		"http://localhost:8090": true, // Pairing UI
-	}
+// APIKeyName returns the name of the API key that authenticated the request,
+// which is also the request's tenant
+func APIKeyName(r *http.Request) string {
+	return tenant.FromContext(r.Context())
+}
 
-	// Allow additional origins from env var (comma-separated)
-	if extra := os.Getenv("CORS_ORIGINS"); extra != "" {
-		for _, origin := range strings.Split(extra, ",") {
-			origins[strings.TrimSpace(origin)] = true
+// configuredAPIKeys returns the accepted API keys by name: API_KEY as
+// "default" plus any name:key pairs in API_KEYS (comma-separated). Keys may
+// be secret store references; one that cannot be read is kept but empty, so
+// auth stays on and the key accepts nothing.
+func configuredAPIKeys() map[string]string {
+	keys := make(map[string]string)
+	if key := os.Getenv("API_KEY"); key != "" {
+		keys[DefaultKeyName] = resolveAPIKey(key)
+	}
+	for _, pair := range strings.Split(os.Getenv("API_KEYS"), ",") {
+		name, key, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || name == "" || key == "" || name == DefaultKeyName {
+			continue
 		}
+		keys[name] = resolveAPIKey(key)
 	}
+	return keys
+}
 
-	return origins
+// resolveAPIKey reads an API key that may be a secret store reference,
+// returning "" when it cannot be read
+func resolveAPIKey(key string) string {
+	resolved, err := secrets.Resolve(key)
+	if err != nil {
+		return ""
+	}
+	return resolved
 }
 
 // AuthMiddleware validates API key authentication using constant-time comparison
+// and records which named key was used
 func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		expectedKey := os.Getenv("API_KEY")
+		keys := configuredAPIKeys()
 
 		// Skip auth if no API_KEY is configured (dev mode)
-		if expectedKey == "" {
+		if len(keys) == 0 {
 			next(w, r)
 			return
 		}
@@ -57,18 +77,105 @@ func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 
 		// Check X-API-Key header using constant-time comparison to prevent timing attacks
 		apiKey := r.Header.Get("X-API-Key")
-		if subtle.ConstantTimeCompare([]byte(apiKey), []byte(expectedKey)) != 1 {
+		keyName := ""
+		for name, expectedKey := range keys {
+			if expectedKey != "" && subtle.ConstantTimeCompare([]byte(apiKey), []byte(expectedKey)) == 1 {
+				keyName = name
+			}
+		}
+		if keyName == "" {
 			security.LogAuthFailure(ip, r.Header.Get("User-Agent"), "Invalid API key")
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
 
 		security.LogAuthSuccess(ip, r.URL.Path)
+		next(w, r.WithContext(tenant.WithName(r.Context(), keyName)))
+	}
+}
+
+// AdminMiddleware restricts a route to the primary API_KEY, the only key
+// with tenant.RoleAdmin
+func AdminMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !tenant.IsOperator(APIKeyName(r)) {
+			SendJSONError(w, "Admin API key required", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// keyRoles returns the roles API_KEY_ROLES gives named API keys, e.g.
+// "dashboard=reader,crm=sender"
+func keyRoles() map[string]string {
+	roles, _ := tenant.ParseRoles(os.Getenv("API_KEY_ROLES"))
+	return roles
+}
+
+// RequireRole restricts a route to API keys whose role is at least role
+func RequireRole(role string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if have := tenant.RoleOf(APIKeyName(r), keyRoles()); !tenant.Allows(have, role) {
+			SendJSONError(w, fmt.Sprintf("API key role %s cannot use this endpoint; it needs %s", have, role), http.StatusForbidden)
+			return
+		}
 		next(w, r)
 	}
 }
 
-// RateLimitMiddleware limits requests per IP address
+// RequireRoles restricts a route that both reads and changes something:
+// its GET and HEAD requests need read, everything else needs write
+func RequireRoles(read, write string, next http.HandlerFunc) http.HandlerFunc {
+	reads, writes := RequireRole(read, next), RequireRole(write, next)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			reads(w, r)
+			return
+		}
+		writes(w, r)
+	}
+}
+
+// UsageMiddleware counts the request against its API key's monthly usage and
+// rejects it with 429 once the key's hard quota for the category is used up.
+// Requests past the soft quota succeed with an X-Quota-Warning header.
+func UsageMiddleware(meter *usage.Meter, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		category := usage.Classify(r)
+		if meter == nil || category == "" {
+			next(w, r)
+			return
+		}
+
+		keyName := APIKeyName(r)
+		decision, err := meter.Record(keyName, category, time.Now())
+		if err != nil {
+			// Accounting problems must not take the API down
+			logger.Warnf("Usage accounting failed for %s: %v", keyName, err)
+		}
+
+		if !decision.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(secondsUntilNextPeriod(time.Now())))
+			SendJSONError(w, fmt.Sprintf("Monthly %s quota of %d exceeded for API key %s", category, decision.Quota.Hard, keyName), http.StatusTooManyRequests)
+			return
+		}
+		if decision.SoftExceeded {
+			w.Header().Set("X-Quota-Warning", fmt.Sprintf("%s usage %d exceeds soft quota %d", category, decision.Count, decision.Quota.Soft))
+		}
+
+		next(w, r)
+	}
+}
+
+// secondsUntilNextPeriod returns the time left in the current monthly usage period
+func secondsUntilNextPeriod(now time.Time) int {
+	now = now.UTC()
+	next := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	return int(next.Sub(now).Seconds())
+}
+
+// RateLimitMiddleware limits requests per IP address (see ratelimit.go)
 func RateLimitMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Get client IP
@@ -77,22 +184,9 @@ func RateLimitMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			ip = strings.Split(forwarded, ",")[0]
 		}
 
-		rateLimitMu.Lock()
-		now := time.Now()
-
-		// Reset window if expired
-		if window, exists := requestWindows[ip]; !exists || now.Sub(window) > rateLimitWindow {
-			requestWindows[ip] = now
-			requestCounts[ip] = 0
-		}
-
-		requestCounts[ip]++
-		count := requestCounts[ip]
-		rateLimitMu.Unlock()
-
-		if count > rateLimit {
+		if ok, retryAfter := limiter.allow(ip, r.URL.Path); !ok {
 			security.LogRateLimitExceeded(ip)
-			w.Header().Set("Retry-After", "60")
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
 			http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
 			return
 		}
@@ -101,19 +195,26 @@ func RateLimitMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
-// CorsMiddleware adds CORS headers with restricted origins
+// CorsMiddleware adds CORS headers for the origins allowed by the CORS
+// configuration (see cors.go). When CORS is disabled no headers are sent and
+// preflight requests reach the handlers like any other.
 func CorsMiddleware(next http.HandlerFunc) http.HandlerFunc {
-	allowedOrigins := getAllowedOrigins()
-
 	return func(w http.ResponseWriter, r *http.Request) {
-		origin := r.Header.Get("Origin")
+		cfg := CORSConfig()
+		if cfg.Disabled {
+			next(w, r)
+			return
+		}
 
-		// Check if origin is allowed
-		if allowedOrigins[origin] {
+		// Only allowed origins get Access-Control-Allow-Origin (others are blocked by the browser)
+		w.Header().Add("Vary", "Origin")
+		origin := r.Header.Get("Origin")
+		if allowed, ok := matchCORSOrigin(cfg, origin); ok {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
-			w.Header().Set("Access-Control-Allow-Credentials", "true")
+			if allowed.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
 		}
-		// If origin not allowed, don't set Access-Control-Allow-Origin (browser blocks)
 
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
@@ -149,7 +250,38 @@ func SecurityHeadersMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// RecoverMiddleware turns a panic in a handler into a 500 JSON error, logging
+// it with the request and the calling API key
+func RecoverMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		detail := fmt.Sprintf("%s %s, key %s", r.Method, r.URL.Path, APIKeyName(r))
+		defer recovery.Handle(recovery.SourceHTTP, detail, func() {
+			w.Header().Set("Content-Type", "application/json")
+			SendJSONError(w, "Internal server error", http.StatusInternalServerError)
+		})
+		next(w, r)
+	}
+}
+
 // SecureMiddleware chains security headers, auth, rate limiting, and CORS middleware
 func SecureMiddleware(next http.HandlerFunc) http.HandlerFunc {
-	return SecurityHeadersMiddleware(CorsMiddleware(RateLimitMiddleware(AuthMiddleware(next))))
+	return SecurityHeadersMiddleware(CorsMiddleware(RateLimitMiddleware(AuthMiddleware(RecoverMiddleware(next)))))
+}
+
+// TimeoutMiddleware gives the request context a deadline. The context also ends
+// when the client disconnects, so calls that honour it stop in either case.
+func TimeoutMiddleware(timeout time.Duration, next http.HandlerFunc) http.HandlerFunc {
+	if timeout <= 0 {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// secure applies SecureMiddleware with per-key usage accounting after authentication
+func (s *Server) secure(next http.HandlerFunc) http.HandlerFunc {
+	return SecureMiddleware(UsageMiddleware(s.usage, TimeoutMiddleware(s.requestTimeout, next)))
 }
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"whatsapp-bridge/internal/tenant"
+)
+
+func TestRequireRoles(t *testing.T) {
+	t.Setenv("API_KEY_ROLES", "dashboard=reader,crm=sender")
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+	webhooks := RequireRoles(tenant.RoleReader, tenant.RoleOperator, ok)
+	send := RequireRole(tenant.RoleSender, ok)
+
+	call := func(h http.HandlerFunc, key, method string) int {
+		r := httptest.NewRequest(method, "/api/webhooks", nil)
+		r = r.WithContext(tenant.WithName(r.Context(), key))
+		rec := httptest.NewRecorder()
+		h(rec, r)
+		return rec.Code
+	}
+
+	tests := []struct {
+		handler http.HandlerFunc
+		key     string
+		method  string
+		want    int
+	}{
+		{webhooks, "dashboard", http.MethodGet, http.StatusNoContent},
+		{webhooks, "dashboard", http.MethodPost, http.StatusForbidden},
+		{webhooks, "crm", http.MethodDelete, http.StatusForbidden},
+		{webhooks, "unlisted", http.MethodPost, http.StatusNoContent},
+		{webhooks, tenant.Default, http.MethodPut, http.StatusNoContent},
+		{send, "dashboard", http.MethodPost, http.StatusForbidden},
+		{send, "crm", http.MethodPost, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		if got := call(tt.handler, tt.key, tt.method); got != tt.want {
+			t.Errorf("%s %s = %d, want %d", tt.key, tt.method, got, tt.want)
+		}
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	t.Setenv("API_KEY", "")
+	deadline := func(s *Server) (time.Time, bool) {
+		var at time.Time
+		var ok bool
+		h := s.secure(func(w http.ResponseWriter, r *http.Request) { at, ok = r.Context().Deadline() })
+		h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/chats", nil))
+		return at, ok
+	}
+
+	// WhatsApp calls made with the request context give up with it
+	start := time.Now()
+	if at, ok := deadline(&Server{requestTimeout: 30 * time.Second}); !ok || at.Before(start.Add(29*time.Second)) || at.After(time.Now().Add(30*time.Second)) {
+		t.Errorf("deadline = %v, %v; want 30s after the request", at, ok)
+	}
+	if _, ok := deadline(&Server{}); ok {
+		t.Error("request given a deadline with no timeout configured")
+	}
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"whatsapp-bridge/internal/database"
+	"whatsapp-bridge/internal/types"
+	"whatsapp-bridge/internal/whatsapp"
+)
+
+func TestUpdateNewsletterSettingsConcurrently(t *testing.T) {
+	t.Chdir(t.TempDir())
+	store, err := database.NewMessageStore()
+	if err != nil {
+		t.Fatalf("NewMessageStore: %v", err)
+	}
+	defer store.Close()
+	s := &Server{client: &whatsapp.Client{}, messageStore: store}
+
+	const n = 20
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			jid := fmt.Sprintf("1203630000000000%02d@newsletter", i)
+			if _, err := s.updateNewsletterSettings(jid, func(ns *types.NewsletterSettings) { ns.Muted = true }); err != nil {
+				t.Errorf("updateNewsletterSettings(%s): %v", jid, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	var stored map[string]types.NewsletterSettings
+	if _, err := store.GetJSONSetting(database.SettingNewsletters, &stored); err != nil {
+		t.Fatalf("GetJSONSetting: %v", err)
+	}
+	if len(stored) != n || len(s.client.NewsletterSettings()) != n {
+		t.Errorf("stored %d and applied %d newsletters, want all %d changes kept", len(stored), len(s.client.NewsletterSettings()), n)
+	}
+}
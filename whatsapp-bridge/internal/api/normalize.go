@@ -0,0 +1,69 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"whatsapp-bridge/internal/phone"
+	"whatsapp-bridge/internal/types"
+)
+
+// MaxNormalizeNumbers caps the numbers in one normalize request
+const MaxNormalizeNumbers = 1000
+
+// handleNormalize handles POST /api/normalize, which converts free-form
+// phone numbers into E.164 numbers and WhatsApp JIDs the same way /api/send
+// reads recipients. Numbers without a country code are read in region, or
+// PHONE_REGION when region is not given. Nothing is checked with WhatsApp,
+// so a valid number may still have no account.
+//
+// Request: { numbers: ["+1 (555) 010-2030", "07911 123456"], region?: "GB" }
+// Response: { success: bool, results: [{ input, valid, e164, jid, country_code, region, error }] }
+func (s *Server) handleNormalize(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req types.NormalizeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+	if len(req.Numbers) == 0 {
+		SendJSONError(w, "numbers is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Numbers) > MaxNormalizeNumbers {
+		SendJSONError(w, fmt.Sprintf("At most %d numbers per request", MaxNormalizeNumbers), http.StatusBadRequest)
+		return
+	}
+
+	results := make([]types.NormalizedNumber, len(req.Numbers))
+	for i, input := range req.Numbers {
+		result := types.NormalizedNumber{Input: input}
+		number, err := phone.Parse(input, req.Region)
+		if errors.Is(err, phone.ErrUnknownRegion) {
+			SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Valid = true
+			result.E164 = number.E164
+			result.JID = number.JID()
+			result.CountryCode = number.CountryCode
+			result.Region = number.Region
+		}
+		results[i] = result
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"results": results,
+	})
+}
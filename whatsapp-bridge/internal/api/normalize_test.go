@@ -0,0 +1,51 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"whatsapp-bridge/internal/types"
+)
+
+func TestHandleNormalize(t *testing.T) {
+	s := &Server{}
+
+	body := `{"numbers": ["+1 (555) 010-2030", "07911 123456", "call me"], "region": "GB"}`
+	w := httptest.NewRecorder()
+	s.handleNormalize(w, httptest.NewRequest(http.MethodPost, "/api/normalize", strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+
+	var resp struct {
+		Results []types.NormalizedNumber `json:"results"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Results) != 3 {
+		t.Fatalf("got %d results, want 3", len(resp.Results))
+	}
+	if r := resp.Results[0]; !r.Valid || r.JID != "15550102030@s.whatsapp.net" || r.E164 != "+15550102030" {
+		t.Errorf("results[0] = %+v", r)
+	}
+	if r := resp.Results[1]; !r.Valid || r.JID != "447911123456@s.whatsapp.net" || r.Region != "GB" {
+		t.Errorf("results[1] = %+v", r)
+	}
+	if r := resp.Results[2]; r.Valid || r.Error == "" || r.Input != "call me" {
+		t.Errorf("results[2] = %+v, want an error", r)
+	}
+}
+
+func TestHandleNormalizeUnknownRegion(t *testing.T) {
+	s := &Server{}
+
+	w := httptest.NewRecorder()
+	s.handleNormalize(w, httptest.NewRequest(http.MethodPost, "/api/normalize", strings.NewReader(`{"numbers": ["555"], "region": "Narnia"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+}
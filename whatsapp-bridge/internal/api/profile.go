@@ -0,0 +1,228 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+
+	"whatsapp-bridge/internal/types"
+	"whatsapp-bridge/internal/whatsapp"
+)
+
+const (
+	// maxProfilePhotoBytes caps a profile photo source image
+	maxProfilePhotoBytes = 10 << 20
+
+	// profilePhotoFetchTimeout bounds downloading an image_url
+	profilePhotoFetchTimeout = 30 * time.Second
+)
+
+// handleProfilePhoto handles POST/DELETE /api/profile/photo for the account's
+// own profile photo.
+//
+// POST Request body (exactly one source):
+//   - image_path: Image file on the bridge host, under the media directories
+//     files may be sent from
+//   - image_url: http(s) URL to download the image from; private and
+//     reserved addresses are refused
+//   - image_base64: Base64-encoded image data
+//
+// JPEG, PNG and GIF images are accepted; they are cropped to a centred square
+// and scaled down to 640x640.
+//
+// Response: { success: bool, picture_id: string } (POST) or { success: bool, message: string } (DELETE)
+func (s *Server) handleProfilePhoto(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		var req types.SetProfilePhotoRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+
+		data, err := loadProfilePhoto(r.Context(), req)
+		if err != nil {
+			SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		pictureID, err := s.client.SetProfilePhoto(r.Context(), data)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, whatsmeow.ErrInvalidImageFormat) || errors.Is(err, whatsapp.ErrUnsupportedImage) {
+				status = http.StatusBadRequest
+			}
+			SendJSONError(w, fmt.Sprintf("Failed to set profile photo: %v", err), status)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":    true,
+			"picture_id": pictureID,
+		})
+
+	case http.MethodDelete:
+		if err := s.client.RemoveProfilePhoto(r.Context()); err != nil {
+			SendJSONError(w, fmt.Sprintf("Failed to remove profile photo: %v", err), http.StatusInternalServerError)
+			return
+		}
+		SendJSONSuccess(w, nil, "Profile photo removed")
+
+	default:
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// loadProfilePhoto reads the image from whichever source the request names
+func loadProfilePhoto(ctx context.Context, req types.SetProfilePhotoRequest) ([]byte, error) {
+	sources := 0
+	for _, v := range []string{req.ImagePath, req.ImageURL, req.ImageBase64} {
+		if v != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return nil, fmt.Errorf("exactly one of image_path, image_url or image_base64 is required")
+	}
+
+	var data []byte
+	var err error
+	switch {
+	case req.ImagePath != "":
+		if err := whatsapp.ValidateMediaPath(req.ImagePath); err != nil {
+			return nil, err
+		}
+		data, err = os.ReadFile(req.ImagePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image: %v", err)
+		}
+
+	case req.ImageURL != "":
+		if !strings.HasPrefix(req.ImageURL, "http://") && !strings.HasPrefix(req.ImageURL, "https://") {
+			return nil, fmt.Errorf("image_url must be an http(s) URL")
+		}
+		data, err = fetchImage(ctx, req.ImageURL)
+		if err != nil {
+			return nil, err
+		}
+
+	default:
+		data, err = base64.StdEncoding.DecodeString(req.ImageBase64)
+		if err != nil {
+			return nil, fmt.Errorf("image_base64 is not valid base64")
+		}
+	}
+
+	if len(data) > maxProfilePhotoBytes {
+		return nil, fmt.Errorf("image exceeds %d MB", maxProfilePhotoBytes>>20)
+	}
+	return data, nil
+}
+
+// fetchImage downloads an image, reading at most one byte past the size cap.
+// Private and reserved addresses are refused, as for link previews.
+func fetchImage(ctx context.Context, url string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, profilePhotoFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image_url: %v", err)
+	}
+	resp, err := whatsapp.PublicHTTPClient(profilePhotoFetchTimeout).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download image: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download image: status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxProfilePhotoBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download image: %v", err)
+	}
+	return data, nil
+}
+
+// handleProfileName handles POST /api/profile/name for the account's push
+// name, the display name shown to contacts who have not saved the number.
+//
+// Request body:
+//   - name: New display name (required, at most 25 characters)
+//
+// Response: { success: bool, name: string }
+func (s *Server) handleProfileName(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req types.SetPushNameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+	if _, err := whatsapp.ValidatePushName(req.Name); err != nil {
+		SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	name, err := s.client.SetPushName(r.Context(), req.Name)
+	if err != nil {
+		SendJSONError(w, fmt.Sprintf("Failed to set push name: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"name":    name,
+	})
+}
+
+// handleDefaultDisappearing handles POST /api/disappearing/default for the
+// disappearing messages timer WhatsApp applies to every new chat. Existing
+// chats keep their own timers.
+//
+// Request body:
+//   - duration: "off", "24h", "7d", or "90d" (required)
+//
+// Response: { success: bool, duration: string }
+func (s *Server) handleDefaultDisappearing(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req types.SetDefaultDisappearingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+	if !whatsapp.ValidDisappearingDuration(req.Duration) {
+		SendJSONError(w, "duration must be 'off', '24h', '7d', or '90d'", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.client.SetDefaultDisappearingTimer(r.Context(), req.Duration); err != nil {
+		SendJSONError(w, fmt.Sprintf("Failed to set default disappearing timer: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"duration": req.Duration,
+	})
+}
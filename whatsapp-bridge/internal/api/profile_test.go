@@ -0,0 +1,63 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"whatsapp-bridge/internal/types"
+	"whatsapp-bridge/internal/whatsapp"
+)
+
+func TestLoadProfilePhotoSources(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("image"))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		req     types.SetProfilePhotoRequest
+		wantErr string
+	}{
+		{"file outside the media directories", types.SetProfilePhotoRequest{ImagePath: "/etc/passwd"}, "outside allowed directories"},
+		{"path traversal", types.SetProfilePhotoRequest{ImagePath: "/app/media/../../etc/passwd"}, "traversal"},
+		{"private address", types.SetProfilePhotoRequest{ImageURL: srv.URL}, "private address"},
+		{"two sources", types.SetProfilePhotoRequest{ImagePath: "/tmp/a.jpg", ImageBase64: "aW1hZ2U="}, "exactly one"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadProfilePhoto(context.Background(), tt.req)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("loadProfilePhoto() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// The check can be lifted for development, as for webhooks
+	t.Setenv("DISABLE_SSRF_CHECK", "true")
+	data, err := loadProfilePhoto(context.Background(), types.SetProfilePhotoRequest{ImageURL: srv.URL})
+	if err != nil || string(data) != "image" {
+		t.Errorf("loadProfilePhoto() = %q, %v with the check lifted", data, err)
+	}
+}
+
+func TestDefaultDisappearingDuration(t *testing.T) {
+	for _, d := range []string{"off", "24h", "7d", "90d"} {
+		if !whatsapp.ValidDisappearingDuration(d) {
+			t.Errorf("%q refused", d)
+		}
+	}
+
+	// Durations WhatsApp does not offer are refused before reaching it
+	s := &Server{}
+	for _, body := range []string{`{"duration":"3d"}`, `{}`, `not json`} {
+		rec := httptest.NewRecorder()
+		s.handleDefaultDisappearing(rec, httptest.NewRequest(http.MethodPost, "/api/disappearing/default", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", body, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+const (
+	defaultRateLimit = 100 // requests per window
+	rateLimitWindow  = time.Minute
+)
+
+// RateCounter counts requests in fixed windows shared between API instances
+type RateCounter interface {
+	// CountWindow increments key, which expires ttl after its first
+	// increment, and returns it with the count at previousKey (0 if it does
+	// not exist) in a single atomic call
+	CountWindow(key, previousKey string, ttl time.Duration) (current, previous int64, err error)
+}
+
+// rateLimiter limits requests per client IP with a sliding window: the count
+// is the current minute's requests plus the previous minute's, weighted by
+// how much of the previous minute the sliding window still covers. This
+// avoids the double burst a fixed window allows across its boundary.
+type rateLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	routes []routeLimit // longest prefix first
+
+	// counters holds the per-process windows by client and route; entries
+	// idle for two windows are swept out
+	counters  map[string]*windowCount
+	lastSweep time.Time
+
+	// shared, when set, replaces the in-memory counters so every API
+	// instance enforces one limit per client
+	shared RateCounter
+	logger waLog.Logger
+	// sharedDown is set while counting falls back to memory, so the outage
+	// is logged once rather than per request
+	sharedDown bool
+
+	now func() time.Time
+}
+
+// routeLimit overrides the limit for paths starting with prefix
+type routeLimit struct {
+	prefix string
+	limit  int
+}
+
+// windowCount is one client's requests in the current and previous window
+type windowCount struct {
+	start    time.Time
+	current  int
+	previous int
+}
+
+var limiter = newRateLimiter()
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		limit:    defaultRateLimit,
+		counters: make(map[string]*windowCount),
+		logger:   waLog.Noop,
+		now:      time.Now,
+	}
+}
+
+// UseSharedRateCounter makes the rate limiter count requests in counter
+// instead of in process memory, logging to logger when it falls back
+func UseSharedRateCounter(counter RateCounter, logger waLog.Logger) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	limiter.shared = counter
+	limiter.logger = logger
+}
+
+// ConfigureRateLimit sets the requests each client may make per minute, and
+// per-route limits by path prefix. A route limit replaces the global limit
+// for matching paths and is counted separately; the longest prefix wins.
+// limit <= 0 keeps the default.
+func ConfigureRateLimit(limit int, routes map[string]int) {
+	limiter.configure(limit, routes)
+}
+
+func (l *rateLimiter) configure(limit int, routes map[string]int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if limit > 0 {
+		l.limit = limit
+	}
+	l.routes = l.routes[:0]
+	for prefix, n := range routes {
+		if prefix != "" && n > 0 {
+			l.routes = append(l.routes, routeLimit{prefix, n})
+		}
+	}
+	sort.Slice(l.routes, func(i, j int) bool {
+		if len(l.routes[i].prefix) != len(l.routes[j].prefix) {
+			return len(l.routes[i].prefix) > len(l.routes[j].prefix)
+		}
+		return l.routes[i].prefix < l.routes[j].prefix
+	})
+}
+
+// allow records a request from ip to path and reports whether it is within
+// the limit. When it is not, retryAfter is when the current window ends.
+func (l *rateLimiter) allow(ip, path string) (ok bool, retryAfter time.Duration) {
+	l.mu.Lock()
+	limit, key := l.limit, ip
+	for _, route := range l.routes {
+		if strings.HasPrefix(path, route.prefix) {
+			limit, key = route.limit, ip+" "+route.prefix
+			break
+		}
+	}
+	shared := l.shared
+	now := l.now()
+	l.mu.Unlock()
+
+	start := now.Truncate(rateLimitWindow)
+	elapsed := now.Sub(start)
+
+	var current, previous int
+	if shared != nil {
+		var err error
+		current, previous, err = l.countShared(shared, key, start)
+		l.sharedAvailable(err)
+		if err != nil {
+			// Fall back to per-instance counting while the shared store is unavailable
+			current, previous = l.countLocal(key, start, now)
+		}
+	} else {
+		current, previous = l.countLocal(key, start, now)
+	}
+
+	weight := float64(rateLimitWindow-elapsed) / float64(rateLimitWindow)
+	if int(float64(previous)*weight)+current > limit {
+		return false, rateLimitWindow - elapsed
+	}
+	return true, 0
+}
+
+// countLocal counts a request in process memory
+func (l *rateLimiter) countLocal(key string, start, now time.Time) (current, previous int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) >= rateLimitWindow {
+		l.sweep(start)
+		l.lastSweep = now
+	}
+
+	c, ok := l.counters[key]
+	if !ok {
+		c = &windowCount{start: start}
+		l.counters[key] = c
+	}
+	if !c.start.Equal(start) {
+		if start.Sub(c.start) == rateLimitWindow {
+			c.previous = c.current
+		} else {
+			c.previous = 0
+		}
+		c.current = 0
+		c.start = start
+	}
+	c.current++
+	return c.current, c.previous
+}
+
+// sweep drops counters that no longer affect any window. l.mu must be held.
+func (l *rateLimiter) sweep(start time.Time) {
+	for key, c := range l.counters {
+		if start.Sub(c.start) > rateLimitWindow {
+			delete(l.counters, key)
+		}
+	}
+}
+
+// countShared counts a request in the shared store. Keys live for two
+// windows so the previous window can still be read.
+func (l *rateLimiter) countShared(shared RateCounter, key string, start time.Time) (current, previous int, err error) {
+	cur, prev, err := shared.CountWindow(
+		fmt.Sprintf("ratelimit:%s:%d", key, start.Unix()),
+		fmt.Sprintf("ratelimit:%s:%d", key, start.Add(-rateLimitWindow).Unix()),
+		2*rateLimitWindow,
+	)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(cur), int(prev), nil
+}
+
+// sharedAvailable logs when the shared store stops answering and when it
+// is back
+func (l *rateLimiter) sharedAvailable(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	switch {
+	case err != nil && !l.sharedDown:
+		l.logger.Warnf("Shared rate limiter unavailable, counting in memory: %v", err)
+	case err == nil && l.sharedDown:
+		l.logger.Infof("Shared rate limiter available again")
+	}
+	l.sharedDown = err != nil
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterSlidingWindow(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	l := newRateLimiter()
+	l.now = func() time.Time { return now }
+	l.configure(10, nil)
+
+	// Fill the limit at the end of one window
+	now = now.Add(50 * time.Second)
+	for i := 0; i < 10; i++ {
+		if ok, _ := l.allow("1.2.3.4", "/api/send"); !ok {
+			t.Fatalf("request %d rejected", i+1)
+		}
+	}
+	if ok, retry := l.allow("1.2.3.4", "/api/send"); ok || retry != 10*time.Second {
+		t.Errorf("11th request: ok=%v retry=%v, want rejected until the window ends", ok, retry)
+	}
+
+	// Just after the boundary the previous window still counts almost fully,
+	// so a fixed window's double burst is not possible
+	now = now.Add(15 * time.Second)
+	if ok, _ := l.allow("1.2.3.4", "/api/send"); ok {
+		t.Error("burst across the window boundary allowed")
+	}
+	if ok, _ := l.allow("5.6.7.8", "/api/send"); !ok {
+		t.Error("another client was limited")
+	}
+
+	// Half way through the next window, half of the previous window's 11
+	// attempts count (5), plus the one made at the boundary
+	now = now.Add(25 * time.Second)
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		if ok, _ := l.allow("1.2.3.4", "/api/send"); ok {
+			allowed++
+		}
+	}
+	if allowed != 4 {
+		t.Errorf("allowed %d requests half way through the window, want 4", allowed)
+	}
+}
+
+func TestRateLimiterRoutes(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	l := newRateLimiter()
+	l.now = func() time.Time { return now }
+	l.configure(5, map[string]int{"/api/send": 2, "/api/send/status": 4})
+
+	check := func(path string, want int) {
+		t.Helper()
+		allowed := 0
+		for i := 0; i < 10; i++ {
+			if ok, _ := l.allow("1.2.3.4", path); ok {
+				allowed++
+			}
+		}
+		if allowed != want {
+			t.Errorf("%s: allowed %d, want %d", path, allowed, want)
+		}
+	}
+
+	check("/api/send", 2)
+	check("/api/send/status", 4) // longest prefix wins, counted separately
+	check("/api/chats", 5)
+}
+
+func TestRateLimiterEviction(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	l := newRateLimiter()
+	l.now = func() time.Time { return now }
+
+	l.allow("1.2.3.4", "/api/send")
+	l.allow("5.6.7.8", "/api/send")
+
+	// Still needed as the previous window
+	now = now.Add(time.Minute)
+	l.allow("5.6.7.8", "/api/send")
+	if len(l.counters) != 2 {
+		t.Fatalf("have %d counters, want 2", len(l.counters))
+	}
+
+	now = now.Add(2 * time.Minute)
+	l.allow("9.9.9.9", "/api/send")
+	if len(l.counters) != 1 {
+		t.Errorf("have %d counters after two idle windows, want 1", len(l.counters))
+	}
+}
+
+// fakeRateCounter is an in-memory RateCounter
+type fakeRateCounter struct {
+	counts map[string]int64
+	err    error
+}
+
+func (f *fakeRateCounter) CountWindow(key, previousKey string, ttl time.Duration) (int64, int64, error) {
+	if f.err != nil {
+		return 0, 0, f.err
+	}
+	f.counts[key]++
+	return f.counts[key], f.counts[previousKey], nil
+}
+
+func TestRateLimiterShared(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	shared := &fakeRateCounter{counts: make(map[string]int64)}
+	l := newRateLimiter()
+	l.now = func() time.Time { return now }
+	l.configure(2, nil)
+	l.shared = shared
+
+	l.allow("1.2.3.4", "/api/send")
+	l.allow("1.2.3.4", "/api/send")
+	if ok, _ := l.allow("1.2.3.4", "/api/send"); ok {
+		t.Error("shared limit not enforced")
+	}
+	if shared.counts["ratelimit:1.2.3.4:1714564800"] != 3 {
+		t.Errorf("shared counts = %v", shared.counts)
+	}
+
+	// The previous window is read back from the shared store
+	now = now.Add(time.Minute)
+	if ok, _ := l.allow("1.2.3.4", "/api/send"); ok {
+		t.Error("previous shared window ignored")
+	}
+
+	// Falls back to in-memory counting when the store fails
+	shared.err = errors.New("down")
+	if ok, _ := l.allow("1.2.3.4", "/api/send"); !ok {
+		t.Error("request rejected while falling back")
+	}
+	if len(l.counters) != 1 {
+		t.Errorf("have %d local counters, want 1", len(l.counters))
+	}
+	if !l.sharedDown {
+		t.Error("outage not noted")
+	}
+	shared.err = nil
+	l.allow("1.2.3.4", "/api/send")
+	if l.sharedDown {
+		t.Error("recovery not noted")
+	}
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"whatsapp-bridge/internal/msgrate"
+	"whatsapp-bridge/internal/types"
+)
+
+// Message rate listing limits
+const (
+	defaultRateWindow = 5 * time.Minute
+	defaultRatesLimit = 20
+	maxRatesLimit     = 1000
+)
+
+// SetRateTracker enables /api/analytics/rates
+func (s *Server) SetRateTracker(t *msgrate.Tracker) {
+	s.rates = t
+}
+
+// handleMessageRates handles GET /api/analytics/rates for the busiest chats
+// or senders over a recent window, to spot floods and spam.
+//
+// Query parameters (all optional):
+//   - scope: "chat" (default) or "sender"
+//   - window: Whole minutes up to 1h, e.g. "5m" (default) or "1h"
+//   - jid: Only this chat or sender, listed even when quiet
+//   - min: Leave out those with fewer messages
+//   - limit: Most to list (default 20, max 1000)
+//
+// Counts cover incoming messages since the bridge started, in one minute
+// steps; the newest minute is still filling.
+//
+// Response: { success: bool, data: MessageRate[] }, busiest first
+func (s *Server) handleMessageRates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.rates == nil {
+		SendJSONError(w, "Message rates are not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	q := r.URL.Query()
+	scope := q.Get("scope")
+	if scope == "" {
+		scope = msgrate.ScopeChat
+	}
+	if scope != msgrate.ScopeChat && scope != msgrate.ScopeSender {
+		SendJSONError(w, "scope must be 'chat' or 'sender'", http.StatusBadRequest)
+		return
+	}
+
+	window := defaultRateWindow
+	if v := q.Get("window"); v != "" {
+		var err error
+		if window, err = msgrate.ParseWindow(v); err != nil {
+			SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	minCount := 0
+	if v := q.Get("min"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			SendJSONError(w, "min must be a non-negative number", http.StatusBadRequest)
+			return
+		}
+		minCount = n
+	}
+
+	limit := defaultRatesLimit
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxRatesLimit {
+			SendJSONError(w, "limit must be between 1 and 1000", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	var rates []types.MessageRate
+	if jid := q.Get("jid"); jid != "" {
+		rates = []types.MessageRate{s.rates.Rate(scope, jid, window)}
+	} else {
+		rates = s.rates.Top(scope, window, minCount, limit)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    rates,
+	})
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"whatsapp-bridge/internal/database"
+	"whatsapp-bridge/internal/relay"
+	"whatsapp-bridge/internal/types"
+)
+
+// handleRelayConfig handles GET/PUT /api/settings/relay.
+//
+// PUT Request body (replaces the whole configuration):
+//   - links: Chats mirrored out to peer bridges, each with chat_jid, peer_url
+//     (the peer's API base URL), peer_api_key, peer_chat_jid and enabled
+//   - inbound_chats: Chats on this bridge that peers may relay into
+//
+// Incoming messages in a linked chat are posted to the peer's /api/relay; the
+// bridge's own messages are never relayed, so linking both ways is safe.
+//
+// Response: { success: bool, data: RelayConfig }
+func (s *Server) handleRelayConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    s.relay.Config(),
+		})
+
+	case http.MethodPut:
+		var cfg types.RelayConfig
+		if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+			SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+
+		if err := relay.ValidateConfig(cfg); err != nil {
+			SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := s.messageStore.SetJSONSetting(database.SettingRelay, cfg); err != nil {
+			SendJSONError(w, fmt.Sprintf("Failed to store relay config: %v", err), http.StatusInternalServerError)
+			return
+		}
+		_ = s.relay.SetConfig(cfg)
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    s.relay.Config(),
+		})
+
+	default:
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleRelayInbound handles POST /api/relay, called by a peer bridge to
+// deliver a message from one of its linked chats. The message is sent into
+// chat_jid prefixed with the original sender's name.
+//
+// Request body: RelayMessage (chat_jid and sender required; content or media_type)
+//
+// Response: as /api/send. A chat_jid not in inbound_chats is refused with 403.
+func (s *Server) handleRelayInbound(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var msg types.RelayMessage
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	if msg.ChatJID == "" || msg.Sender == "" {
+		SendJSONError(w, "chat_jid and sender are required", http.StatusBadRequest)
+		return
+	}
+	if msg.Content == "" && msg.MediaType == "" {
+		SendJSONError(w, "content or media_type is required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := s.relay.Deliver(r.Context(), msg)
+	if err == relay.ErrNotAccepted {
+		SendJSONError(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	result, err = queuedResult(result, err)
+	writeQueuedSend(w, result, err, msg.ChatJID)
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"whatsapp-bridge/internal/database"
+)
+
+func TestReadReplicaServer(t *testing.T) {
+	t.Chdir(t.TempDir())
+	store, err := database.NewMessageStore()
+	if err != nil {
+		t.Fatalf("NewMessageStore: %v", err)
+	}
+	defer store.Close()
+	s := NewReadReplicaServer(store, nil, 0)
+
+	get := func(h http.HandlerFunc, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	// Health reports the replica without a WhatsApp connection to ask
+	if rec := get(s.handleHealth, "/api/health"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "read_replica") {
+		t.Errorf("health = %d %s", rec.Code, rec.Body.String())
+	}
+
+	// Reads are answered from the shared database
+	if rec := get(s.handleCalendar, "/api/events/calendar"); rec.Code != http.StatusOK {
+		t.Errorf("calendar = %d %s", rec.Code, rec.Body.String())
+	}
+
+	// Anything that needs the bridge is refused without reaching the handler
+	reached := false
+	needsBridge := s.bridge(func(w http.ResponseWriter, r *http.Request) { reached = true })
+	if rec := get(needsBridge, "/api/catalog"); rec.Code != http.StatusServiceUnavailable || reached {
+		t.Errorf("bridge endpoint = %d, reached %v; want 503 without calling it", rec.Code, reached)
+	}
+}
@@ -3,6 +3,10 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+
+	"whatsapp-bridge/internal/outbox"
+	"whatsapp-bridge/internal/types"
+	"whatsapp-bridge/internal/whatsapp"
 )
 
 // Response represents a standard API response
@@ -37,3 +41,54 @@ func SendJSONSuccess(w http.ResponseWriter, data interface{}, message string) {
 	}
 	_ = json.NewEncoder(w).Encode(response)
 }
+
+// sendErrorStatus maps a classified send failure to an HTTP status code
+func sendErrorStatus(code string) int {
+	switch code {
+	case whatsapp.SendErrNotConnected, outbox.SendErrQueueFull, outbox.SendErrAccountRestricted:
+		return http.StatusServiceUnavailable
+	case whatsapp.SendErrInvalidRecipient, whatsapp.SendErrInvalidMedia:
+		return http.StatusBadRequest
+	case whatsapp.SendErrNotOnWhatsApp, whatsapp.SendErrProductNotFound:
+		return http.StatusNotFound
+	case whatsapp.SendErrBlocked:
+		return http.StatusForbidden
+	case whatsapp.SendErrTimeout:
+		return http.StatusGatewayTimeout
+	case whatsapp.SendErrMediaRejected:
+		return http.StatusUnsupportedMediaType
+	case whatsapp.SendErrTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case whatsapp.SendErrServerRejected:
+		return http.StatusBadGateway
+	case outbox.SendErrDuplicate:
+		return http.StatusConflict
+	case outbox.SendErrAutomationPaused:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeSendResult writes a send outcome as a SendMessageResponse, with the
+// HTTP status derived from the failure code
+func writeSendResult(w http.ResponseWriter, result types.SendResult, recipient string) {
+	w.Header().Set("Content-Type", "application/json")
+	if !result.Success {
+		w.WriteHeader(sendErrorStatus(result.Code))
+	}
+
+	_ = json.NewEncoder(w).Encode(types.SendMessageResponse{
+		Success:    result.Success,
+		Message:    result.Error,
+		MessageID:  result.MessageID,
+		MessageRef: result.MessageRef,
+		Timestamp:  result.Timestamp,
+		Recipient:  recipient,
+		Status:     result.Status,
+		ErrorCode:  result.Code,
+		Retryable:  result.Retryable,
+		Duplicate:  result.Duplicate,
+		MessageIDs: result.PartIDs,
+	})
+}
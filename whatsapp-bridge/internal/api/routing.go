@@ -0,0 +1,219 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"whatsapp-bridge/internal/tenant"
+	"whatsapp-bridge/internal/types"
+)
+
+// handleRoutingProfiles handles GET/POST /api/routing for routing profile management.
+//
+// GET: List the caller's routing profiles (the operator sees all)
+// POST: Create a routing profile owned by the caller
+//
+// POST Request body:
+//   - name: Profile name (required, unique)
+//   - description: Free-form description (optional)
+//   - webhook_ids: Webhooks that belong to this profile (required)
+//   - chat_jids: Chats the profile is attached to (optional)
+//   - tags: Chat tags the profile is attached to (optional)
+//   - exclusive: Suppress webhooks outside this profile for attached chats (default false)
+//   - enabled: boolean
+//
+// Response: { success: bool, data: RoutingProfile[] | RoutingProfile }
+func (s *Server) handleRoutingProfiles(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		viewer := APIKeyName(r)
+		profiles := []*types.RoutingProfile{}
+		for _, profile := range s.webhookManager.GetRoutingProfiles() {
+			if tenant.Visible(viewer, profile.Tenant) {
+				profiles = append(profiles, profile)
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    profiles,
+		})
+
+	case http.MethodPost:
+		var profile types.RoutingProfile
+		if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
+			SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+
+		// Only the operator may create profiles on behalf of another tenant
+		if viewer := APIKeyName(r); !tenant.IsOperator(viewer) || profile.Tenant == "" {
+			profile.Tenant = viewer
+		}
+
+		if err := s.webhookManager.ValidateRoutingProfile(&profile); err != nil {
+			SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := s.messageStore.StoreRoutingProfile(&profile); err != nil {
+			SendJSONError(w, fmt.Sprintf("Failed to store routing profile: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		_ = s.webhookManager.LoadRoutingProfiles()
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    profile,
+		})
+
+	default:
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleRoutingProfileByID handles operations on individual routing profiles.
+//
+// Routes:
+//   - GET    /api/routing/{id} - Get routing profile
+//   - PUT    /api/routing/{id} - Replace routing profile
+//   - DELETE /api/routing/{id} - Delete routing profile
+func (s *Server) handleRoutingProfileByID(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	idStr := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/routing/"), "/")
+	profileID := 0
+	if _, err := fmt.Sscanf(idStr, "%d", &profileID); err != nil || idStr == "" {
+		SendJSONError(w, "Invalid routing profile ID", http.StatusBadRequest)
+		return
+	}
+
+	// Other tenants' profiles are reported as missing
+	existing, err := s.messageStore.GetRoutingProfile(profileID)
+	if err == sql.ErrNoRows || (err == nil && !tenant.Visible(APIKeyName(r), existing.Tenant)) {
+		SendJSONError(w, "Routing profile not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		SendJSONError(w, fmt.Sprintf("Failed to get routing profile: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    existing,
+		})
+
+	case http.MethodPut:
+		var profile types.RoutingProfile
+		if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
+			SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+
+		profile.ID = profileID // Ensure ID matches URL
+		profile.Tenant = existing.Tenant
+
+		if err := s.webhookManager.ValidateRoutingProfile(&profile); err != nil {
+			SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := s.messageStore.UpdateRoutingProfile(&profile); err != nil {
+			SendJSONError(w, fmt.Sprintf("Failed to update routing profile: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		_ = s.webhookManager.LoadRoutingProfiles()
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    profile,
+		})
+
+	case http.MethodDelete:
+		if err := s.messageStore.DeleteRoutingProfile(profileID); err != nil {
+			SendJSONError(w, fmt.Sprintf("Failed to delete routing profile: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		_ = s.webhookManager.LoadRoutingProfiles()
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Routing profile deleted successfully",
+		})
+
+	default:
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleChatTags handles GET/POST /api/routing/tags for chat tag assignment.
+//
+// Tags are private to the calling tenant and only attach that tenant's profiles.
+//
+// GET: Map of chat JID to tags
+// POST: Replace the tags of one chat
+//
+// POST Request body:
+//   - chat_jid: Chat to tag (required)
+//   - tags: Array of tags (empty array clears tags)
+func (s *Server) handleChatTags(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		tags, err := s.messageStore.GetChatTags(APIKeyName(r))
+		if err != nil {
+			SendJSONError(w, fmt.Sprintf("Failed to get chat tags: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    tags,
+		})
+
+	case http.MethodPost:
+		var req types.ChatTagsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+
+		if req.ChatJID == "" {
+			SendJSONError(w, "chat_jid is required", http.StatusBadRequest)
+			return
+		}
+
+		tags := make([]string, 0, len(req.Tags))
+		for _, tag := range req.Tags {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+
+		if err := s.messageStore.SetChatTags(APIKeyName(r), req.ChatJID, tags); err != nil {
+			SendJSONError(w, fmt.Sprintf("Failed to set chat tags: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		_ = s.webhookManager.LoadRoutingProfiles()
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":  true,
+			"chat_jid": req.ChatJID,
+			"tags":     tags,
+		})
+
+	default:
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"whatsapp-bridge/internal/outbox"
+	"whatsapp-bridge/internal/types"
+)
+
+const (
+	defaultSelfTestTimeout = 30 * time.Second
+	maxSelfTestTimeout     = 120 * time.Second
+)
+
+// handleSelfTest handles POST /api/selftest, a smoke test for uptime
+// monitors. A canary message is sent to the account's own number (the
+// message-yourself chat) and followed through the pipeline, timing each stage:
+//   - send: queued through the outbox until the server acknowledged it
+//   - echo: until the message came back acknowledged by another of the
+//     account's devices (the phone) through WhatsApp; the bridge's own
+//     receipts do not count
+//   - storage: the message is in the message store
+//   - webhook: a selftest event was delivered to every webhook with a selftest
+//     trigger (skipped when there are none)
+//
+// Request body (optional):
+//   - timeout_seconds: Overall limit (default 30, max 120)
+//
+// Response: { success: bool, data: SelfTestReport }, with status 503 when a
+// stage failed so monitors can alert on the status code alone.
+func (s *Server) handleSelfTest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req types.SelfTestRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+	}
+
+	timeout := defaultSelfTestTimeout
+	if req.TimeoutSeconds < 0 || time.Duration(req.TimeoutSeconds)*time.Second > maxSelfTestTimeout {
+		SendJSONError(w, fmt.Sprintf("timeout_seconds must be between 1 and %d", int(maxSelfTestTimeout.Seconds())), http.StatusBadRequest)
+		return
+	} else if req.TimeoutSeconds > 0 {
+		timeout = time.Duration(req.TimeoutSeconds) * time.Second
+	}
+
+	self, ok := s.client.SelfJID()
+	if !ok {
+		SendJSONError(w, "Not logged in to WhatsApp", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	report := s.runSelfTest(ctx, self.String())
+
+	w.Header().Set("Content-Type", "application/json")
+	if !report.Success {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": report.Success,
+		"data":    report,
+	})
+}
+
+// runSelfTest runs the stages in order, stopping at the first failure
+func (s *Server) runSelfTest(ctx context.Context, chatJID string) types.SelfTestReport {
+	start := time.Now()
+	report := types.SelfTestReport{ChatJID: chatJID}
+	stage := func(name string, run func(st *types.SelfTestStage)) bool {
+		st := types.SelfTestStage{Name: name}
+		began := time.Now()
+		run(&st)
+		st.LatencyMs = time.Since(began).Milliseconds()
+		report.Stages = append(report.Stages, st)
+		return st.Success
+	}
+
+	content := "Bridge self-test " + start.UTC().Format(time.RFC3339)
+	var sent types.SendResult
+
+	report.Success = stage("send", func(st *types.SelfTestStage) {
+		result, err := s.outbox.SendForced(ctx, outbox.PriorityHigh, chatJID, content, "")
+		switch {
+		case err != nil:
+			st.Error = err.Error()
+		case !result.Success:
+			st.Error = result.Error
+		default:
+			st.Success = true
+			sent = result
+			report.MessageID = result.MessageID
+		}
+	}) && stage("echo", func(st *types.SelfTestStage) {
+		if _, err := s.client.WaitForEcho(ctx, sent.MessageID); err != nil {
+			st.Error = "no acknowledgment from the account's other devices: " + err.Error()
+			return
+		}
+		st.Success = true
+	}) && stage("storage", func(st *types.SelfTestStage) {
+		msg, err := s.messageStore.GetMessage(chatJID, sent.MessageID)
+		switch {
+		case err != nil:
+			st.Error = err.Error()
+		case msg == nil:
+			st.Error = "message was not stored"
+		default:
+			st.Success = true
+		}
+	}) && stage("webhook", func(st *types.SelfTestStage) {
+		deliveries := s.webhookManager.DeliverSelfTest(types.WebhookMessageInfo{
+			ID:        sent.MessageID,
+			ChatJID:   chatJID,
+			Content:   content,
+			Timestamp: sent.Timestamp.UTC().Format(time.RFC3339),
+			IsFromMe:  true,
+		})
+		if len(deliveries) == 0 {
+			st.Success, st.Skipped = true, true
+			st.Detail = "no webhook has a selftest trigger"
+			return
+		}
+
+		var failed []string
+		for _, d := range deliveries {
+			if !d.Success {
+				failed = append(failed, fmt.Sprintf("%d (%s)", d.WebhookID, d.Error))
+			}
+		}
+		st.Detail = fmt.Sprintf("%d of %d webhooks received the event", len(deliveries)-len(failed), len(deliveries))
+		if len(failed) > 0 {
+			st.Error = "delivery failed to webhook " + strings.Join(failed, ", ")
+			return
+		}
+		st.Success = true
+	})
+
+	report.TotalMs = time.Since(start).Milliseconds()
+	return report
+}
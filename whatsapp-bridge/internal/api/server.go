@@ -3,12 +3,41 @@ package api
 import (
 	"fmt"
 	"net/http"
+	"os"
+	"sync"
+	"time"
 
+	waLog "go.mau.fi/whatsmeow/util/log"
+
+	"whatsapp-bridge/internal/approval"
+	"whatsapp-bridge/internal/autoread"
+	"whatsapp-bridge/internal/businesshours"
+	"whatsapp-bridge/internal/commands"
 	"whatsapp-bridge/internal/database"
+	"whatsapp-bridge/internal/diskspace"
+	"whatsapp-bridge/internal/doctor"
+	"whatsapp-bridge/internal/maintenance"
+	"whatsapp-bridge/internal/metrics"
+	"whatsapp-bridge/internal/msgrate"
+	"whatsapp-bridge/internal/outbox"
+	"whatsapp-bridge/internal/redact"
+	"whatsapp-bridge/internal/relay"
+	"whatsapp-bridge/internal/tenant"
+	"whatsapp-bridge/internal/usage"
 	"whatsapp-bridge/internal/webhook"
 	"whatsapp-bridge/internal/whatsapp"
 )
 
+// logger reports failures of background work done for requests, such as
+// usage accounting, that must not fail the request itself
+var logger = redact.Logger(waLog.Stdout("API", "INFO", true))
+
+// SetLogger replaces the API logger. Messages are scrubbed of registered
+// secrets before they reach l. Must be called before the server starts.
+func SetLogger(l waLog.Logger) {
+	logger = redact.Logger(l)
+}
+
 // Server is the HTTP REST API server for the WhatsApp bridge.
 // It exposes endpoints for sending messages, managing webhooks,
 // group operations, and other WhatsApp features.
@@ -16,7 +45,54 @@ type Server struct {
 	client         *whatsapp.Client
 	messageStore   *database.MessageStore
 	webhookManager *webhook.Manager
+	autoReader     *autoread.Marker
+	outbox         *outbox.Dispatcher
+	maintenance    *maintenance.Responder
+	businessHours  *businesshours.Responder
+	usage          *usage.Meter
+	relay          *relay.Relay
 	port           int
+
+	// bindHost limits the TCP listener to one address; socketPath replaces
+	// it with a Unix domain socket (see listen.go)
+	bindHost   string
+	socketPath string
+	socketMode os.FileMode
+
+	// requestTimeout bounds the WhatsApp calls made by one request; 0 means no limit
+	requestTimeout time.Duration
+
+	// displayLocation renders human-facing times; nil means UTC
+	displayLocation *time.Location
+
+	// readReplica serves database reads only; there is no WhatsApp connection
+	readReplica bool
+
+	// devMode serves the fault injection endpoints
+	devMode bool
+
+	// doctor re-runs the startup configuration checks on request
+	doctor *doctor.Doctor
+
+	// commands runs admin commands sent over WhatsApp (see commands.go)
+	commands *commands.Router
+
+	// rates counts incoming messages per chat and sender (see rates.go)
+	rates *msgrate.Tracker
+
+	// disk watches free space on the store volume (see handleHealth)
+	disk *diskspace.Monitor
+
+	// approvals holds sends made with selected keys for approval (see approvals.go)
+	approvals *approval.Queue
+
+	// calendarToken, when set, signs the per-chat tokens that open the ICS
+	// calendar feed to calendar apps without the API key (see calendar.go)
+	calendarToken string
+
+	// newsletterMu serializes changes to the stored newsletter settings,
+	// which are read, changed and saved whole
+	newsletterMu sync.Mutex
 }
 
 // NewServer creates a new API server with the given dependencies.
@@ -25,44 +101,249 @@ type Server struct {
 //   - client: WhatsApp client for sending messages and interacting with WhatsApp
 //   - messageStore: Database for message history and webhook configurations
 //   - webhookManager: Manager for webhook trigger matching and delivery
+//   - autoReader: Automatic read receipt batcher
+//   - dispatcher: Priority outbox that all sends go through
+//   - responder: Maintenance mode auto-responder
+//   - hours: Out-of-hours auto-responder
+//   - meter: Per-API-key usage accounting and quotas
+//   - relayer: Chat mirroring to and from peer bridges
 //   - port: TCP port to listen on (e.g., 8080)
-func NewServer(client *whatsapp.Client, messageStore *database.MessageStore, webhookManager *webhook.Manager, port int) *Server {
+func NewServer(client *whatsapp.Client, messageStore *database.MessageStore, webhookManager *webhook.Manager, autoReader *autoread.Marker, dispatcher *outbox.Dispatcher, responder *maintenance.Responder, hours *businesshours.Responder, meter *usage.Meter, relayer *relay.Relay, port int) *Server {
 	return &Server{
 		client:         client,
 		messageStore:   messageStore,
 		webhookManager: webhookManager,
+		autoReader:     autoReader,
+		outbox:         dispatcher,
+		maintenance:    responder,
+		businessHours:  hours,
+		usage:          meter,
+		relay:          relayer,
 		port:           port,
 	}
 }
 
+// NewReadReplicaServer creates an API server for a read replica: an instance
+// with no WhatsApp connection that answers read endpoints from the shared
+// database and rejects everything that needs the connected bridge with 503.
+func NewReadReplicaServer(messageStore *database.MessageStore, meter *usage.Meter, port int) *Server {
+	return &Server{
+		messageStore: messageStore,
+		usage:        meter,
+		port:         port,
+		readReplica:  true,
+	}
+}
+
+// SetRequestTimeout bounds the context of every authenticated request, so a
+// WhatsApp call that hangs fails instead of holding the handler forever
+func (s *Server) SetRequestTimeout(timeout time.Duration) {
+	s.requestTimeout = timeout
+}
+
+// SetDisplayTimezone sets the time zone for human-facing times. Stored and
+// machine-readable timestamps stay in UTC.
+func (s *Server) SetDisplayTimezone(loc *time.Location) {
+	s.displayLocation = loc
+}
+
+// SetDevMode enables the fault injection endpoints under /api/admin/chaos/.
+// They can disconnect the bridge at will, so never enable this in production.
+func (s *Server) SetDevMode(enabled bool) {
+	s.devMode = enabled
+}
+
+func (s *Server) displayTimezone() *time.Location {
+	if s.displayLocation == nil {
+		return time.UTC
+	}
+	return s.displayLocation
+}
+
+// bridge marks a handler that needs the connected bridge instance; read
+// replicas answer it with 503
+func (s *Server) bridge(next http.HandlerFunc) http.HandlerFunc {
+	if !s.readReplica {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		SendJSONError(w, "Not available on a read replica; use the bridge instance", http.StatusServiceUnavailable)
+	}
+}
+
+// sendRoutes returns the endpoints that send on the account, by route. They
+// need the sender role and are held for approval for the keys that need it.
+func (s *Server) sendRoutes() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"/api/send":              s.handleSendMessage,
+		"/api/send/product":      s.handleSendProduct,
+		"/api/send/catalog":      s.handleSendCatalog,
+		"/api/send/contact":      s.handleSendContact,
+		"/api/send/sticker-pack": s.handleSendStickerPack,
+		"/api/relay":             s.handleRelayInbound, // messages relayed from peer bridges (see /api/settings/relay)
+		"/api/poll":              s.handleCreatePoll,
+		"/api/poll/vote":         s.handlePollVote,
+		"/api/newsletter/react":  s.handleNewsletterReact,
+		"/api/messages/pin":      s.handlePinMessage,
+	}
+}
+
 // Start launches the HTTP server in a background goroutine.
-// The server listens on the configured port and serves the REST API.
-// This method returns immediately; use a blocking mechanism in main().
-func (s *Server) Start() {
+// The server listens on the configured address and serves the REST API.
+// This method returns once the listener is open, or with an error if it
+// cannot be; use a blocking mechanism in main().
+func (s *Server) Start() error {
 	// Register handlers
 	s.registerHandlers()
 
-	// Start the server
-	serverAddr := fmt.Sprintf(":%d", s.port)
-	fmt.Printf("Starting REST API server on %s...\n", serverAddr)
+	// Open the listener here so a bad address fails startup
+	ln, err := s.listen()
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.Address(), err)
+	}
+	logger.Infof("Starting REST API server on %s...", s.Address())
 
 	// Run server in a goroutine so it doesn't block
 	go func() {
-		if err := http.ListenAndServe(serverAddr, nil); err != nil {
-			fmt.Printf("REST API server error: %v\n", err)
+		if err := http.Serve(ln, nil); err != nil {
+			logger.Errorf("REST API server error: %v", err)
 		}
 	}()
+	return nil
 }
 
 // registerHandlers sets up all API routes with security middleware.
 // All endpoints are protected by SecureMiddleware which enforces:
-// API key authentication, rate limiting, CORS, and security headers.
+// API key authentication, rate limiting, CORS, and security headers,
+// and are metered against the calling key's usage quotas.
+// Routes wrapped in s.bridge need the WhatsApp connection or in-memory
+// bridge state and are unavailable on read replicas. Every route names the
+// least role a key needs (see tenant.RoleReader and the roles after it);
+// AdminMiddleware routes are the primary key's alone.
 func (s *Server) registerHandlers() {
 	// Health check - no auth (for Docker healthcheck / load balancers)
-	http.HandleFunc("/api/health", CorsMiddleware(s.handleHealth))
+	http.HandleFunc("/api/health", CorsMiddleware(RecoverMiddleware(s.handleHealth)))
+
+	// WhatsApp connection state, account restrictions, health and blocklist
+	http.HandleFunc("/api/connection", s.secure(RequireRole(tenant.RoleReader, s.bridge(s.handleConnectionStatus))))
+	http.HandleFunc("/api/account/health", s.secure(RequireRole(tenant.RoleReader, s.bridge(s.handleAccountHealth))))
+	http.HandleFunc("/api/blocklist", s.secure(RequireRole(tenant.RoleReader, s.handleBlocklist)))
+
+	// End-to-end smoke test for uptime monitors; sends on the account, so admin-only
+	http.HandleFunc("/api/selftest", s.secure(AdminMiddleware(s.bridge(s.handleSelfTest))))
+
+	// Sending endpoints, including polls, reactions, pins and relayed
+	// messages; sends made with a key that needs approval are held
+	for route, handler := range s.sendRoutes() {
+		http.HandleFunc(route, s.secure(RequireRole(tenant.RoleSender, s.bridge(s.holdForApproval(route, handler)))))
+	}
+	http.HandleFunc("/api/send/status", s.secure(RequireRole(tenant.RoleReader, s.handleSendStatus)))
+	http.HandleFunc("/api/outbox", s.secure(RequireRole(tenant.RoleReader, s.bridge(s.handleOutbox))))
+
+	// Sends held for approval (see /api/settings/approvals); deciding is admin-only
+	http.HandleFunc("/api/approvals", s.secure(RequireRole(tenant.RoleReader, s.bridge(s.handleApprovals))))
+	http.HandleFunc("/api/approvals/approve", s.secure(AdminMiddleware(s.bridge(s.handleApprovalDecision(true)))))
+	http.HandleFunc("/api/approvals/reject", s.secure(AdminMiddleware(s.bridge(s.handleApprovalDecision(false)))))
+
+	// Phone number to JID conversion, as /api/send reads recipients
+	http.HandleFunc("/api/normalize", s.secure(RequireRole(tenant.RoleReader, s.handleNormalize)))
+
+	// Prometheus-format metrics
+	http.HandleFunc("/api/metrics", s.secure(RequireRole(tenant.RoleReader, metrics.Handler)))
 
-	// Message sending endpoint
-	http.HandleFunc("/api/send", SecureMiddleware(s.handleSendMessage))
+	// Device pairing (phone number code flow + browser QR page); the account is
+	// shared by all tenants, so only the admin may pair it
+	http.HandleFunc("/api/pair", s.secure(AdminMiddleware(s.bridge(s.handlePairPhone))))
+	http.HandleFunc("/api/pair/cancel", s.secure(AdminMiddleware(s.bridge(s.handlePairCancel))))
+	http.HandleFunc("/api/pairing", s.secure(AdminMiddleware(s.bridge(s.handlePairingStatus))))
+	http.HandleFunc("/ui/pair", UIMiddleware(s.bridge(s.handlePairPage)))
+	http.HandleFunc("/ui/pair/qr.png", UIMiddleware(s.bridge(s.handlePairQR)))
+	http.HandleFunc("/ui/pair/events", UIMiddleware(s.bridge(s.handlePairEvents)))
 
-	// All other routes disabled — send-only mode.
+	// Webhook management and per-chat routing profiles
+	http.HandleFunc("/api/webhooks", s.secure(RequireRoles(tenant.RoleReader, tenant.RoleOperator, s.bridge(s.handleWebhooks))))
+	http.HandleFunc("/api/webhooks/", s.secure(RequireRoles(tenant.RoleReader, tenant.RoleOperator, s.bridge(s.handleWebhookByID))))
+	http.HandleFunc("/api/webhook-logs", s.secure(RequireRole(tenant.RoleReader, s.handleWebhookLogs)))
+	http.HandleFunc("/api/routing", s.secure(RequireRoles(tenant.RoleReader, tenant.RoleOperator, s.bridge(s.handleRoutingProfiles))))
+	http.HandleFunc("/api/routing/", s.secure(RequireRoles(tenant.RoleReader, tenant.RoleOperator, s.bridge(s.handleRoutingProfileByID))))
+	http.HandleFunc("/api/routing/tags", s.secure(RequireRoles(tenant.RoleReader, tenant.RoleOperator, s.bridge(s.handleChatTags))))
+
+	// Account profile and privacy; the account is shared, so admin-only
+	http.HandleFunc("/api/privacy", s.secure(AdminMiddleware(s.bridge(s.handlePrivacySettings))))
+	http.HandleFunc("/api/profile/photo", s.secure(AdminMiddleware(s.bridge(s.handleProfilePhoto))))
+	http.HandleFunc("/api/profile/name", s.secure(AdminMiddleware(s.bridge(s.handleProfileName))))
+	http.HandleFunc("/api/set-about", s.secure(AdminMiddleware(s.bridge(s.handleSetAbout))))
+	http.HandleFunc("/api/disappearing/default", s.secure(AdminMiddleware(s.bridge(s.handleDefaultDisappearing))))
+
+	// Group provisioning
+	http.HandleFunc("/api/group/create", s.secure(RequireRole(tenant.RoleOperator, s.bridge(s.handleCreateGroup))))
+	http.HandleFunc("/api/group/add", s.secure(RequireRole(tenant.RoleOperator, s.bridge(s.handleAddGroupMembers))))
+
+	// Poll results; polls are created and voted on through sendRoutes
+	http.HandleFunc("/api/poll/", s.secure(RequireRole(tenant.RoleReader, s.handlePollResults)))
+
+	// Newsletter (channel) engagement and handling
+	http.HandleFunc("/api/newsletter/mute", s.secure(RequireRole(tenant.RoleOperator, s.bridge(s.handleNewsletterMute))))
+	http.HandleFunc("/api/newsletter/settings", s.secure(RequireRoles(tenant.RoleReader, tenant.RoleOperator, s.bridge(s.handleNewsletterSettings))))
+	http.HandleFunc("/api/newsletter/", s.secure(RequireRole(tenant.RoleReader, s.bridge(s.handleNewsletterMessage))))
+
+	// Archived messages by opaque reference. The message history, chat list
+	// and calendar feed are polled, so they carry ETags and are gzipped.
+	http.HandleFunc("/api/messages", s.secure(RequireRole(tenant.RoleReader, CacheMiddleware(s.handleMessages))))
+	http.HandleFunc("/api/messages/", s.secure(RequireRole(tenant.RoleReader, s.handleMessage)))
+	http.HandleFunc("/api/download", s.secure(RequireRole(tenant.RoleReader, s.bridge(s.handleDownload))))
+	http.HandleFunc("/api/media/", s.secure(RequireRole(tenant.RoleReader, s.handleMedia)))
+	http.HandleFunc("/api/analytics/rates", s.secure(RequireRole(tenant.RoleReader, s.bridge(s.handleMessageRates))))
+	http.HandleFunc("/api/messages/pinned", s.secure(RequireRole(tenant.RoleReader, s.handlePinnedMessages)))
+	http.HandleFunc("/api/message/", s.secure(RequireRole(tenant.RoleReader, s.handleMessageInfo)))
+	http.HandleFunc("/api/chats", s.secure(RequireRole(tenant.RoleReader, CacheMiddleware(s.handleChats))))
+	http.HandleFunc("/api/chats/unread", s.secure(RequireRole(tenant.RoleReader, CacheMiddleware(s.handleUnreadChats))))
+	http.HandleFunc("/api/search", s.secure(RequireRole(tenant.RoleReader, s.handleSearch)))
+	http.HandleFunc("/api/annotations/search", s.secure(RequireRole(tenant.RoleReader, s.handleAnnotationSearch)))
+
+	// Live location tracks
+	http.HandleFunc("/api/locations/", s.secure(RequireRole(tenant.RoleReader, s.handleLiveLocation)))
+
+	// Captured orders, catalog items and payments
+	http.HandleFunc("/api/commerce", s.secure(RequireRole(tenant.RoleReader, s.handleCommerceMessages)))
+	http.HandleFunc("/api/catalog", s.secure(RequireRole(tenant.RoleReader, s.bridge(s.handleCatalog))))
+
+	// Events planned in chats, as JSON or an ICS feed to subscribe to
+	http.HandleFunc("/api/events/calendar", s.calendarFeed(RequireRole(tenant.RoleReader, CacheMiddleware(s.handleCalendar))))
+	http.HandleFunc("/api/events/calendar/feed", s.secure(RequireRole(tenant.RoleReader, s.handleCalendarFeedLink)))
+
+	// Sticker packs seen in chats
+	http.HandleFunc("/api/stickers/packs", s.secure(RequireRole(tenant.RoleReader, s.handleStickerPacks)))
+	http.HandleFunc("/api/stickers/packs/", s.secure(RequireRole(tenant.RoleReader, s.handleStickerPack)))
+
+	// Runtime settings apply to every tenant and are admin-only
+	http.HandleFunc("/api/settings", s.secure(AdminMiddleware(s.bridge(s.handleSettings))))
+	http.HandleFunc("/api/settings/receipts", s.secure(AdminMiddleware(s.bridge(s.handleReceiptPolicy))))
+	http.HandleFunc("/api/settings/auto-read", s.secure(AdminMiddleware(s.bridge(s.handleAutoReadConfig))))
+	http.HandleFunc("/api/settings/maintenance", s.secure(AdminMiddleware(s.bridge(s.handleMaintenanceConfig))))
+	http.HandleFunc("/api/settings/business-hours", s.secure(AdminMiddleware(s.bridge(s.handleBusinessHoursConfig))))
+	http.HandleFunc("/api/settings/storage", s.secure(AdminMiddleware(s.bridge(s.handleStoragePolicy))))
+	http.HandleFunc("/api/settings/chat-scope", s.secure(AdminMiddleware(s.bridge(s.handleChatScope))))
+	http.HandleFunc("/api/settings/duplicate-send", s.secure(AdminMiddleware(s.bridge(s.handleDuplicateSendConfig))))
+	http.HandleFunc("/api/settings/loop-breaker", s.secure(AdminMiddleware(s.bridge(s.handleLoopBreakerConfig))))
+	http.HandleFunc("/api/settings/send-rate", s.secure(AdminMiddleware(s.bridge(s.handleSendRateConfig))))
+	http.HandleFunc("/api/settings/relay", s.secure(AdminMiddleware(s.bridge(s.handleRelayConfig))))
+	http.HandleFunc("/api/settings/cors", s.secure(AdminMiddleware(s.handleCORSConfig)))
+	http.HandleFunc("/api/settings/commands", s.secure(AdminMiddleware(s.bridge(s.handleCommandConfig))))
+	http.HandleFunc("/api/settings/approvals", s.secure(AdminMiddleware(s.bridge(s.handleApprovalConfig))))
+	http.HandleFunc("/api/automations", s.secure(AdminMiddleware(s.bridge(s.handleAutomations))))
+	http.HandleFunc("/api/automations/resume", s.secure(AdminMiddleware(s.bridge(s.handleResumeAutomation))))
+
+	// Usage accounting (primary API key only)
+	http.HandleFunc("/api/admin/usage", s.secure(AdminMiddleware(s.handleUsage)))
+
+	// Configuration checks (admin only)
+	http.HandleFunc("/api/admin/doctor", s.secure(AdminMiddleware(s.handleDoctor)))
+	http.HandleFunc("/api/admin/quotas", s.secure(AdminMiddleware(s.bridge(s.handleUsageQuotas))))
+
+	// Fault injection for testing reconnects and consumers (dev mode only)
+	if s.devMode {
+		http.HandleFunc("/api/admin/chaos/", s.secure(AdminMiddleware(s.bridge(s.handleChaos))))
+	}
 }
@@ -0,0 +1,641 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"whatsapp-bridge/internal/automation"
+	"whatsapp-bridge/internal/autoread"
+	"whatsapp-bridge/internal/businesshours"
+	"whatsapp-bridge/internal/database"
+	"whatsapp-bridge/internal/maintenance"
+	"whatsapp-bridge/internal/outbox"
+	"whatsapp-bridge/internal/types"
+	"whatsapp-bridge/internal/whatsapp"
+)
+
+// handleSettings handles GET /api/settings for the bridge's runtime settings.
+//
+// Response: { success: bool, data: { receipts: ReceiptPolicy, auto_read: AutoReadConfig,
+// newsletters: { jid: NewsletterSettings }, maintenance: MaintenanceConfig,
+// business_hours: BusinessHoursConfig, storage: StoragePolicy, chat_scope: ChatScope,
+// duplicate_send: DuplicateSendConfig, loop_breaker: LoopBreakerConfig,
+// relay: RelayConfig, cors: CORSConfig, commands: CommandConfig } }
+func (s *Server) handleSettings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    s.settingsSnapshot(),
+	})
+}
+
+// settingsSnapshot collects every runtime setting for GET /api/settings
+func (s *Server) settingsSnapshot() map[string]interface{} {
+	settings := map[string]interface{}{
+		"receipts":       s.client.ReceiptPolicy(),
+		"auto_read":      s.autoReader.Config(),
+		"newsletters":    s.client.NewsletterSettings(),
+		"maintenance":    s.maintenance.Config(),
+		"business_hours": s.businessHours.Config(),
+		"storage":        s.client.StoragePolicy(),
+		"chat_scope":     s.client.ChatScope(),
+		"duplicate_send": s.outbox.DuplicateConfig(),
+		"loop_breaker":   s.outbox.LoopBreaker().Config(),
+		"send_rate":      s.outbox.RateConfig(),
+		"relay":          s.relay.Config(),
+		"cors":           CORSConfig(),
+	}
+	if s.commands != nil {
+		settings["commands"] = s.commands.Config()
+	}
+	return settings
+}
+
+// handleReceiptPolicy handles GET/PUT /api/settings/receipts.
+//
+// PUT Request body (all fields optional):
+//   - read_receipts: Send automatic read receipts (global, or for chat_jid when given)
+//   - delivery_receipts: Send active delivery receipts (account-wide only)
+//   - chat_jid: Apply read_receipts as an override for this chat
+//   - clear_override: Remove the override for chat_jid
+//
+// Response: { success: bool, data: ReceiptPolicy }
+func (s *Server) handleReceiptPolicy(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    s.client.ReceiptPolicy(),
+		})
+
+	case http.MethodPut:
+		var req types.UpdateReceiptPolicyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+
+		policy := s.client.ReceiptPolicy()
+
+		if req.ChatJID != "" {
+			if req.DeliveryReceipts != nil {
+				SendJSONError(w, "delivery_receipts cannot be set per chat", http.StatusBadRequest)
+				return
+			}
+			switch {
+			case req.ClearOverride:
+				delete(policy.ChatReadReceipts, req.ChatJID)
+			case req.ReadReceipts != nil:
+				policy.ChatReadReceipts[req.ChatJID] = *req.ReadReceipts
+			default:
+				SendJSONError(w, "read_receipts or clear_override is required with chat_jid", http.StatusBadRequest)
+				return
+			}
+		} else {
+			if req.ClearOverride {
+				SendJSONError(w, "clear_override requires chat_jid", http.StatusBadRequest)
+				return
+			}
+			if req.ReadReceipts != nil {
+				policy.ReadReceipts = *req.ReadReceipts
+			}
+			if req.DeliveryReceipts != nil {
+				policy.DeliveryReceipts = *req.DeliveryReceipts
+			}
+		}
+
+		if err := s.messageStore.SetJSONSetting(database.SettingReceiptPolicy, policy); err != nil {
+			SendJSONError(w, fmt.Sprintf("Failed to store receipt policy: %v", err), http.StatusInternalServerError)
+			return
+		}
+		s.client.SetReceiptPolicy(policy)
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    policy,
+		})
+
+	default:
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAutoReadConfig handles GET/PUT /api/settings/auto-read.
+//
+// PUT Request body (replaces the whole configuration):
+//   - enabled: boolean
+//   - delay_seconds: Wait before sending a chat's batched receipts (0-3600)
+//   - rules: Filter expressions; matching messages are marked read
+//   - mark_on_webhook_delivery: Mark messages read once a webhook for them is delivered
+//
+// Response: { success: bool, data: AutoReadConfig }
+func (s *Server) handleAutoReadConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    s.autoReader.Config(),
+		})
+
+	case http.MethodPut:
+		var cfg types.AutoReadConfig
+		if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+			SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+
+		if _, err := autoread.ValidateConfig(cfg); err != nil {
+			SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := s.messageStore.SetJSONSetting(database.SettingAutoRead, cfg); err != nil {
+			SendJSONError(w, fmt.Sprintf("Failed to store auto-read config: %v", err), http.StatusInternalServerError)
+			return
+		}
+		_ = s.autoReader.SetConfig(cfg)
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    s.autoReader.Config(),
+		})
+
+	default:
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleMaintenanceConfig handles GET/PUT /api/settings/maintenance.
+//
+// PUT Request body (replaces the whole configuration):
+//   - enabled: boolean; while true webhooks and auto-read rules are suppressed
+//   - message: Auto-reply sent to each contact that DMs the bridge (once per day)
+//   - translations: The message in other languages, by ISO 639-1 code, e.g.
+//     {"es": "..."}; a contact writing in one of them gets it instead (optional).
+//     Languages are guessed as for /api/settings/business-hours
+//
+// Response: { success: bool, data: MaintenanceConfig }
+func (s *Server) handleMaintenanceConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    s.maintenance.Config(),
+		})
+
+	case http.MethodPut:
+		var cfg types.MaintenanceConfig
+		if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+			SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+
+		if err := maintenance.ValidateConfig(cfg); err != nil {
+			SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := s.messageStore.SetJSONSetting(database.SettingMaintenance, cfg); err != nil {
+			SendJSONError(w, fmt.Sprintf("Failed to store maintenance config: %v", err), http.StatusInternalServerError)
+			return
+		}
+		_ = s.maintenance.SetConfig(cfg)
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    s.maintenance.Config(),
+		})
+
+	default:
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleDuplicateSendConfig handles GET/PUT /api/settings/duplicate-send.
+//
+// PUT Request body (replaces the whole configuration):
+//   - window_seconds: Repeats of a send within this many seconds are duplicates (0 disables)
+//   - action: "reject" (default) fails the send with 409; "flag" sends it with duplicate: true
+//
+// A send is a repeat when the same API key sends the same text and media to the
+// same recipient. /api/send with force: true skips the check; auto-replies never do.
+//
+// Response: { success: bool, data: DuplicateSendConfig }
+func (s *Server) handleDuplicateSendConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    s.outbox.DuplicateConfig(),
+		})
+
+	case http.MethodPut:
+		var cfg types.DuplicateSendConfig
+		if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+			SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+
+		if err := outbox.ValidateDuplicateConfig(cfg); err != nil {
+			SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := s.messageStore.SetJSONSetting(database.SettingDuplicateSend, cfg); err != nil {
+			SendJSONError(w, fmt.Sprintf("Failed to store duplicate send config: %v", err), http.StatusInternalServerError)
+			return
+		}
+		_ = s.outbox.SetDuplicateConfig(cfg)
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    s.outbox.DuplicateConfig(),
+		})
+
+	default:
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleSendRateConfig handles GET/PUT /api/settings/send-rate.
+//
+// PUT Request body (replaces the whole configuration):
+//   - global_per_minute: Most sends per minute to all chats together (0, the default, is no limit)
+//   - chat_per_minute: Most sends per minute to any one chat (0, the default, is no limit)
+//
+// Sends through the outbox are spaced evenly to stay within both: a send over
+// the rate waits for its turn instead of failing, so a request may take
+// longer to answer. Rates are at most 600.
+//
+// Response: { success: bool, data: SendRateConfig }
+func (s *Server) handleSendRateConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    s.outbox.RateConfig(),
+		})
+
+	case http.MethodPut:
+		var cfg types.SendRateConfig
+		if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+			SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+
+		if err := outbox.ValidateRateConfig(cfg); err != nil {
+			SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := s.messageStore.SetJSONSetting(database.SettingSendRate, cfg); err != nil {
+			SendJSONError(w, fmt.Sprintf("Failed to store send rate config: %v", err), http.StatusInternalServerError)
+			return
+		}
+		_ = s.outbox.SetRateConfig(cfg)
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    s.outbox.RateConfig(),
+		})
+
+	default:
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleLoopBreakerConfig handles GET/PUT /api/settings/loop-breaker.
+//
+// PUT Request body (replaces the whole configuration):
+//   - max_messages: An automation sending more than this many messages to one chat
+//     within the window is paused (0 disables loop detection; default 10)
+//   - window_seconds: Length of the window (default 60)
+//   - admin_jid: Chat alerted when an automation is paused (optional)
+//
+// Automations are the maintenance and business hours auto-replies and any
+// /api/send caller that sets origin. Paused automations are listed at
+// /api/automations.
+//
+// Response: { success: bool, data: LoopBreakerConfig }
+func (s *Server) handleLoopBreakerConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	breaker := s.outbox.LoopBreaker()
+
+	switch r.Method {
+	case http.MethodGet:
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    breaker.Config(),
+		})
+
+	case http.MethodPut:
+		var cfg types.LoopBreakerConfig
+		if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+			SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+
+		if err := automation.ValidateConfig(cfg); err != nil {
+			SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := s.messageStore.SetJSONSetting(database.SettingLoopBreaker, cfg); err != nil {
+			SendJSONError(w, fmt.Sprintf("Failed to store loop breaker config: %v", err), http.StatusInternalServerError)
+			return
+		}
+		_ = breaker.SetConfig(cfg)
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    breaker.Config(),
+		})
+
+	default:
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleCORSConfig handles GET/PUT /api/settings/cors.
+//
+// PUT Request body (replaces the whole configuration, including the default
+// localhost origins and CORS_ORIGINS):
+//   - disabled: Send no CORS headers at all (API-only deployments)
+//   - origins: Allowed origins, each { origin, allow_credentials }; origin is
+//     scheme://host[:port], "scheme://*.domain" for any subdomain, or "*" for
+//     any origin (never with allow_credentials)
+//
+// Response: { success: bool, data: CORSConfig }
+func (s *Server) handleCORSConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    CORSConfig(),
+		})
+
+	case http.MethodPut:
+		var cfg types.CORSConfig
+		if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+			SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+
+		if err := ValidateCORSConfig(cfg); err != nil {
+			SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := s.messageStore.SetJSONSetting(database.SettingCORS, cfg); err != nil {
+			SendJSONError(w, fmt.Sprintf("Failed to store CORS config: %v", err), http.StatusInternalServerError)
+			return
+		}
+		_ = SetCORSConfig(cfg)
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    CORSConfig(),
+		})
+
+	default:
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAutomations handles GET /api/automations for the automations the
+// loop breaker has paused. Pauses last until resumed or the bridge restarts.
+//
+// Response: { success: bool, data: { paused: PausedAutomation[] } }
+func (s *Server) handleAutomations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data": map[string]interface{}{
+			"paused": s.outbox.LoopBreaker().Paused(),
+		},
+	})
+}
+
+// handleResumeAutomation handles POST /api/automations/resume.
+//
+// Request body:
+//   - origin: The paused automation to let send again (required)
+//
+// Response: { success: bool, message: string }
+func (s *Server) handleResumeAutomation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Origin string `json:"origin"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Origin == "" {
+		SendJSONError(w, "origin is required", http.StatusBadRequest)
+		return
+	}
+
+	if !s.outbox.LoopBreaker().Resume(req.Origin) {
+		SendJSONError(w, "Automation is not paused", http.StatusNotFound)
+		return
+	}
+	SendJSONSuccess(w, nil, fmt.Sprintf("Automation %s resumed", req.Origin))
+}
+
+// handleBusinessHoursConfig handles GET/PUT /api/settings/business-hours.
+//
+// PUT Request body (replaces the whole configuration):
+//   - enabled: boolean
+//   - timezone: IANA time zone for the opening hours (default UTC)
+//   - hours: [{day: "mon".."sun", open: "HH:MM", close: "HH:MM"}]
+//   - message: Reply template; {next_open}, {next_open_date} and {name} are substituted
+//   - translations: The template in other languages, by ISO 639-1 code, e.g.
+//     {"es": "..."}; a contact writing in one of them gets it instead (optional)
+//
+// The language is guessed from the contact's message. Latin-script messages
+// can be told apart for en, es, pt, fr, de, it, nl, id and tr; ru, ar, he,
+// el, hi, th, ko, ja and zh go by their script. Messages too short to tell,
+// like "ok", get message.
+//
+// Response: { success: bool, data: BusinessHoursConfig }
+func (s *Server) handleBusinessHoursConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    s.businessHours.Config(),
+		})
+
+	case http.MethodPut:
+		var cfg types.BusinessHoursConfig
+		if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+			SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+
+		if _, err := businesshours.Compile(cfg); err != nil {
+			SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := s.messageStore.SetJSONSetting(database.SettingBusinessHours, cfg); err != nil {
+			SendJSONError(w, fmt.Sprintf("Failed to store business hours config: %v", err), http.StatusInternalServerError)
+			return
+		}
+		_ = s.businessHours.SetConfig(cfg)
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    s.businessHours.Config(),
+		})
+
+	default:
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleStoragePolicy handles GET/PUT /api/settings/storage.
+//
+// PUT Request body (all fields optional):
+//   - metadata_only: Store sender, chat, timestamp and type but no content
+//     (global, or for chat_jid when given)
+//   - chat_jid: Apply metadata_only as an override for this chat
+//   - clear_override: Remove the override for chat_jid
+//
+// Messages already stored keep their content; webhooks still receive it.
+//
+// Response: { success: bool, data: StoragePolicy }
+func (s *Server) handleStoragePolicy(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    s.client.StoragePolicy(),
+		})
+
+	case http.MethodPut:
+		var req types.UpdateStoragePolicyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+
+		policy := s.client.StoragePolicy()
+
+		if req.ChatJID != "" {
+			switch {
+			case req.ClearOverride:
+				delete(policy.ChatMetadataOnly, req.ChatJID)
+			case req.MetadataOnly != nil:
+				policy.ChatMetadataOnly[req.ChatJID] = *req.MetadataOnly
+			default:
+				SendJSONError(w, "metadata_only or clear_override is required with chat_jid", http.StatusBadRequest)
+				return
+			}
+		} else {
+			if req.ClearOverride {
+				SendJSONError(w, "clear_override requires chat_jid", http.StatusBadRequest)
+				return
+			}
+			if req.MetadataOnly != nil {
+				policy.MetadataOnly = *req.MetadataOnly
+			}
+		}
+
+		if err := s.messageStore.SetJSONSetting(database.SettingStoragePolicy, policy); err != nil {
+			SendJSONError(w, fmt.Sprintf("Failed to store storage policy: %v", err), http.StatusInternalServerError)
+			return
+		}
+		s.client.SetStoragePolicy(policy)
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    policy,
+		})
+
+	default:
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleChatScope handles GET/PUT /api/settings/chat-scope.
+//
+// PUT Request body (replaces the whole configuration):
+//   - allow_chats: When non-empty, only these chat JIDs are stored and webhooked
+//   - deny_chats: Chat JIDs that are never stored or webhooked
+//   - exclude_groups: Drop group chats not listed in allow_chats
+//   - exclude_newsletters: Drop newsletters not listed in allow_chats
+//
+// The scope applies to history sync and real-time messages received after the
+// change; messages already stored are kept. Chats out of scope also get no
+// maintenance or out-of-hours replies, auto-read receipts or relays. Admin
+// commands and approvals are answered from any chat.
+//
+// Response: { success: bool, data: ChatScope }
+func (s *Server) handleChatScope(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    s.client.ChatScope(),
+		})
+
+	case http.MethodPut:
+		var scope types.ChatScope
+		if err := json.NewDecoder(r.Body).Decode(&scope); err != nil {
+			SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+
+		if err := whatsapp.ValidateChatScope(scope); err != nil {
+			SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := s.messageStore.SetJSONSetting(database.SettingChatScope, scope); err != nil {
+			SendJSONError(w, fmt.Sprintf("Failed to store chat scope: %v", err), http.StatusInternalServerError)
+			return
+		}
+		s.client.SetChatScope(scope)
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    s.client.ChatScope(),
+		})
+
+	default:
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"whatsapp-bridge/internal/types"
+)
+
+// handleStickerPacks handles GET /api/stickers/packs for sticker packs seen
+// in incoming and outgoing messages.
+//
+// Response: { success: bool, data: StickerPack[] } ordered newest first
+func (s *Server) handleStickerPacks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	packs, err := s.messageStore.GetStickerPacks()
+	if err != nil {
+		SendJSONError(w, fmt.Sprintf("Failed to get sticker packs: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    packs,
+	})
+}
+
+// handleStickerPack handles the per-pack routes under /api/stickers/packs/:
+//
+//	GET  /api/stickers/packs/{id}           the pack and its sticker list
+//	POST /api/stickers/packs/{id}/download  fetch and unpack its stickers
+//
+// The download response is { success: bool, data: { directory, files: string[] } }
+// with paths relative to the bridge's working directory.
+func (s *Server) handleStickerPack(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/stickers/packs/")
+	id, action, _ := strings.Cut(path, "/")
+	if id == "" || (action != "" && action != "download") {
+		SendJSONError(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	pack, err := s.messageStore.GetStickerPack(id)
+	if err != nil {
+		SendJSONError(w, fmt.Sprintf("Failed to get sticker pack: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if pack == nil {
+		SendJSONError(w, "Sticker pack not found", http.StatusNotFound)
+		return
+	}
+
+	if action == "" {
+		if r.Method != http.MethodGet {
+			SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    pack,
+		})
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.readReplica {
+		SendJSONError(w, "Not available on a read replica; use the bridge instance", http.StatusServiceUnavailable)
+		return
+	}
+
+	dir, files, err := s.client.DownloadStickerPack(r.Context(), pack)
+	if err != nil {
+		SendJSONError(w, fmt.Sprintf("Failed to download sticker pack: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data": map[string]interface{}{
+			"directory": dir,
+			"files":     files,
+		},
+	})
+}
+
+// handleSendStickerPack handles POST /api/send/sticker-pack to send a custom
+// sticker pack built from a directory of images.
+//
+// Request body:
+//   - recipient: Phone number or JID (required)
+//   - directory: Directory of PNG, JPEG, GIF or WebP images, 3 to 30 (required)
+//   - name: Pack name (required)
+//   - publisher: Pack publisher (optional)
+//   - description: Pack description (optional)
+//
+// Response: same shape as POST /api/send
+func (s *Server) handleSendStickerPack(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req types.SendStickerPackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	if req.Recipient == "" || req.Directory == "" || req.Name == "" {
+		SendJSONError(w, "recipient, directory and name are required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := queuedResult(s.outbox.SendStickerPack(r.Context(), req))
+	writeQueuedSend(w, result, err, req.Recipient)
+}
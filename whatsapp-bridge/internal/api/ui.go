@@ -0,0 +1,179 @@
+package api
+
+import (
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"rsc.io/qr"
+
+	"whatsapp-bridge/internal/security"
+	"whatsapp-bridge/internal/types"
+)
+
+//go:embed ui/pair.html
+var pairPageHTML []byte
+
+// UIMiddleware protects browser-facing pages. Browsers cannot attach the
+// X-API-Key header to page loads, <img> tags or EventSource streams, so the
+// key may also be supplied as the "key" query parameter. Pairing links the
+// account, so only the primary API_KEY opens these pages; with only named
+// keys configured they stay locked.
+func UIMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	next = RecoverMiddleware(next)
+	auth := func(w http.ResponseWriter, r *http.Request) {
+		keys := configuredAPIKeys()
+		if len(keys) == 0 {
+			next(w, r)
+			return
+		}
+		expectedKey := keys[DefaultKeyName]
+
+		ip := r.RemoteAddr
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			ip = strings.Split(forwarded, ",")[0]
+		}
+
+		apiKey := r.Header.Get("X-API-Key")
+		if apiKey == "" {
+			apiKey = r.URL.Query().Get("key")
+		}
+		if expectedKey == "" || subtle.ConstantTimeCompare([]byte(apiKey), []byte(expectedKey)) != 1 {
+			security.LogAuthFailure(ip, r.Header.Get("User-Agent"), "Invalid API key")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		security.LogAuthSuccess(ip, r.URL.Path)
+		next(w, r)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("X-Frame-Options", "DENY")
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Content-Security-Policy",
+			"default-src 'self'; img-src 'self'; script-src 'unsafe-inline'; style-src 'unsafe-inline'; connect-src 'self'; frame-ancestors 'none'")
+		RateLimitMiddleware(auth)(w, r)
+	}
+}
+
+// handlePairPage serves the browser pairing page.
+// GET /ui/pair
+func (s *Server) handlePairPage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(pairPageHTML)
+}
+
+// handlePairQR renders the current login QR code as a PNG image.
+// Returns 404 when no QR login is pending (already linked or not yet connected).
+// GET /ui/pair/qr.png
+func (s *Server) handlePairQR(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	code, _ := s.client.GetQRCode()
+	if code == "" {
+		http.Error(w, "No QR code available", http.StatusNotFound)
+		return
+	}
+
+	img, err := qr.Encode(code, qr.L)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to render QR code: %v", err), http.StatusInternalServerError)
+		return
+	}
+	img.Scale = 8
+
+	w.Header().Set("Content-Type", "image/png")
+	_, _ = w.Write(img.PNG())
+}
+
+// handlePairEvents streams pairing updates as Server-Sent Events.
+// The first event describes the current state; later events mirror QR
+// rotations, phone pairing codes, and PairSuccess/PairError outcomes.
+// GET /ui/pair/events
+func (s *Server) handlePairEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	events, cancel := s.client.SubscribePairing()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Connection", "keep-alive")
+
+	writeEvent := func(evt types.PairingEvent) {
+		data, _ := json.Marshal(evt)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", evt.Event, data)
+		flusher.Flush()
+	}
+
+	writeEvent(s.currentPairingState())
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case evt, ok := <-events:
+			if !ok {
+				return
+			}
+			writeEvent(evt)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// currentPairingState summarizes the pairing state as a "status" event.
+func (s *Server) currentPairingState() types.PairingEvent {
+	evt := types.PairingEvent{Event: "status"}
+
+	if s.client.Store.ID != nil {
+		evt.Event = "success"
+		evt.JID = s.client.Store.ID.ToNonAD().String()
+		return evt
+	}
+
+	if code, expiresIn := s.client.GetQRCode(); code != "" {
+		evt.Event = "code"
+		evt.ExpiresIn = expiresIn
+		return evt
+	}
+
+	inProgress, pairCode, expiresIn, _, err := s.client.GetPairingStatus()
+	if inProgress && pairCode != "" {
+		evt.Event = "pair_code"
+		evt.PairCode = pairCode
+		evt.ExpiresIn = expiresIn
+	}
+	if err != nil {
+		evt.Error = err.Error()
+	}
+	return evt
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUIMiddlewareNamedKeysOnly(t *testing.T) {
+	t.Setenv("API_KEY", "")
+	t.Setenv("API_KEYS", "crm:crm-secret")
+	page := UIMiddleware(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	call := func(target string) int {
+		rec := httptest.NewRecorder()
+		page(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec.Code
+	}
+
+	// Named keys are not dev mode, and none of them may link a phone
+	if code := call("/ui/pair"); code != http.StatusUnauthorized {
+		t.Errorf("/ui/pair without a key = %d, want 401", code)
+	}
+	if code := call("/ui/pair?key=crm-secret"); code != http.StatusUnauthorized {
+		t.Errorf("/ui/pair with a named key = %d, want 401", code)
+	}
+
+	t.Setenv("API_KEY", "primary")
+	if code := call("/ui/pair?key=primary"); code != http.StatusOK {
+		t.Errorf("/ui/pair with the primary key = %d, want 200", code)
+	}
+}
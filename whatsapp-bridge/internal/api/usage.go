@@ -0,0 +1,97 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"whatsapp-bridge/internal/database"
+	"whatsapp-bridge/internal/types"
+	"whatsapp-bridge/internal/usage"
+)
+
+// handleUsage handles GET /api/admin/usage for per-API-key request counts.
+//
+// Query parameters:
+//   - period: Month as YYYY-MM (optional, defaults to the current UTC month)
+//
+// Response: { success: bool, data: { period, usage: UsageCounter[] } }
+// Each counter carries the key's soft/hard quota for its category.
+func (s *Server) handleUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	period := r.URL.Query().Get("period")
+	if period == "" {
+		period = usage.Period(time.Now())
+	} else if _, err := time.Parse("2006-01", period); err != nil {
+		SendJSONError(w, "period must be formatted as YYYY-MM", http.StatusBadRequest)
+		return
+	}
+
+	counters, err := s.usage.Report(period)
+	if err != nil {
+		SendJSONError(w, fmt.Sprintf("Failed to get usage: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data": map[string]interface{}{
+			"period": period,
+			"usage":  counters,
+		},
+	})
+}
+
+// handleUsageQuotas handles GET/PUT /api/admin/quotas.
+//
+// PUT Request body (replaces all quotas), keyed by API key name then category:
+//
+//	{ "team-a": { "send": { "soft": 9000, "hard": 10000 }, "read": { "hard": 50000 } } }
+//
+// Categories are send, read and webhook_test; 0 or missing means unlimited.
+//
+// Response: { success: bool, data: UsageQuotas }
+func (s *Server) handleUsageQuotas(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    s.usage.Quotas(),
+		})
+
+	case http.MethodPut:
+		var quotas types.UsageQuotas
+		if err := json.NewDecoder(r.Body).Decode(&quotas); err != nil {
+			SendJSONError(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+
+		if err := usage.ValidateQuotas(quotas); err != nil {
+			SendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := s.messageStore.SetJSONSetting(database.SettingUsageQuotas, quotas); err != nil {
+			SendJSONError(w, fmt.Sprintf("Failed to store usage quotas: %v", err), http.StatusInternalServerError)
+			return
+		}
+		_ = s.usage.SetQuotas(quotas)
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    s.usage.Quotas(),
+		})
+
+	default:
+		SendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	waLog "go.mau.fi/whatsmeow/util/log"
+
+	"whatsapp-bridge/internal/database"
+	"whatsapp-bridge/internal/tenant"
+	"whatsapp-bridge/internal/types"
+	"whatsapp-bridge/internal/webhook"
+)
+
+func TestCheckSecretToken(t *testing.T) {
+	check := func(key, token, current string) error {
+		r := httptest.NewRequest("POST", "/api/webhooks", nil)
+		r = r.WithContext(tenant.WithName(r.Context(), key))
+		return checkSecretToken(r, token, current)
+	}
+
+	if err := check("crm", "plain secret", ""); err != nil {
+		t.Errorf("plain secret refused: %v", err)
+	}
+	if err := check("crm", "vault://secret/data/bridge#hmac", ""); err == nil {
+		t.Error("tenant allowed to set a vault reference")
+	}
+	if err := check("crm", "vault://secret/data/bridge#hmac", "vault://secret/data/bridge#hmac"); err != nil {
+		t.Errorf("tenant refused the reference the operator set: %v", err)
+	}
+	if err := check(tenant.Default, "vault://secret/data/bridge#hmac", ""); err != nil {
+		t.Errorf("admin refused a vault reference: %v", err)
+	}
+	if err := check(tenant.Default, "file:///etc/passwd", ""); err == nil {
+		t.Error("admin allowed to set a file reference")
+	}
+}
+
+func TestDeleteWebhookReloadsRouting(t *testing.T) {
+	t.Chdir(t.TempDir())
+	store, err := database.NewMessageStore()
+	if err != nil {
+		t.Fatalf("NewMessageStore: %v", err)
+	}
+	defer store.Close()
+
+	config := &types.WebhookConfig{Name: "vip", WebhookURL: "https://example.com/hook", Enabled: true}
+	if err := store.StoreWebhookConfig(config); err != nil {
+		t.Fatalf("StoreWebhookConfig: %v", err)
+	}
+	profile := &types.RoutingProfile{Name: "vip", Enabled: true, WebhookIDs: []int{config.ID}, ChatJIDs: []string{"vip@g.us"}}
+	if err := store.StoreRoutingProfile(profile); err != nil {
+		t.Fatalf("StoreRoutingProfile: %v", err)
+	}
+
+	wm := webhook.NewManager(store, waLog.Noop)
+	if err := wm.LoadWebhookConfigs(); err != nil {
+		t.Fatalf("LoadWebhookConfigs: %v", err)
+	}
+	if err := wm.LoadRoutingProfiles(); err != nil {
+		t.Fatalf("LoadRoutingProfiles: %v", err)
+	}
+	s := &Server{messageStore: store, webhookManager: wm}
+
+	r := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/api/webhooks/%d", config.ID), nil)
+	r = r.WithContext(tenant.WithName(r.Context(), tenant.Default))
+	w := httptest.NewRecorder()
+	s.handleWebhookByID(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("delete status = %d: %s", w.Code, w.Body)
+	}
+
+	profiles := wm.GetRoutingProfiles()
+	if len(profiles) != 1 || len(profiles[0].WebhookIDs) != 0 {
+		t.Errorf("loaded profiles = %+v, want the deleted webhook detached", profiles)
+	}
+}
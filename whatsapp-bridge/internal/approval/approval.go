@@ -0,0 +1,399 @@
+// Package approval holds sends made with selected API keys until a second
+// person accepts them. A held send is stored, the configured approvers are
+// asked over WhatsApp, and it goes out once one of them reacts with a thumbs
+// up, runs the approve command or an operator approves it through the API.
+package approval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	waLog "go.mau.fi/whatsmeow/util/log"
+
+	"whatsapp-bridge/internal/automation"
+	"whatsapp-bridge/internal/database"
+	"whatsapp-bridge/internal/outbox"
+	"whatsapp-bridge/internal/recovery"
+	"whatsapp-bridge/internal/tenant"
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+const (
+	// AllKeys in the key list holds every API key's sends
+	AllKeys = "*"
+
+	// SendRoute is the endpoint for text and media sends, the one whose
+	// requests are held as a SendMessageRequest
+	SendRoute = "/api/send"
+
+	// DefaultExpireHours is how long a send waits for a decision when none
+	// is configured
+	DefaultExpireHours = 24
+
+	// MaxExpireHours caps the configured wait
+	MaxExpireHours = 30 * 24
+
+	// MaxApprovers caps the configured approvers
+	MaxApprovers = 20
+
+	// sendTimeout bounds running an approved send
+	sendTimeout = 2 * time.Minute
+
+	// previewLength caps the message text quoted to approvers
+	previewLength = 1000
+)
+
+// Errors returned when a send cannot be decided
+var (
+	ErrNotFound     = errors.New("no such held send")
+	ErrExpired      = errors.New("held send has expired")
+	ErrSelfApproval = errors.New("a send cannot be decided by the API key that made it")
+)
+
+// Executor runs an approved send as the endpoint it was made to would have,
+// on behalf of the tenant carried by ctx
+type Executor func(ctx context.Context, a *localTypes.PendingApproval) (localTypes.SendResult, error)
+
+// Queue holds sends for approval and runs them once approved
+type Queue struct {
+	store   *database.MessageStore
+	outbox  *outbox.Dispatcher
+	logger  waLog.Logger
+	execute Executor
+
+	mu     sync.RWMutex
+	config localTypes.ApprovalConfig
+
+	// notify messages an approver and returns the message ID; replaced in tests
+	notify func(ctx context.Context, chatJID, text string) (string, error)
+}
+
+// New creates a queue with approval disabled
+func New(store *database.MessageStore, dispatcher *outbox.Dispatcher, logger waLog.Logger) *Queue {
+	q := &Queue{
+		store:  store,
+		outbox: dispatcher,
+		logger: logger,
+		config: localTypes.ApprovalConfig{Keys: []string{}, Approvers: []string{}, ExpireHours: DefaultExpireHours},
+	}
+	q.notify = q.sendNotification
+	return q
+}
+
+// ValidateConfig checks the key names, approver JIDs and expiry
+func ValidateConfig(cfg localTypes.ApprovalConfig) error {
+	if cfg.Enabled && len(cfg.Keys) == 0 {
+		return fmt.Errorf("at least one key is required when approval is enabled")
+	}
+	for i, key := range cfg.Keys {
+		if strings.TrimSpace(key) == "" {
+			return fmt.Errorf("key %d: name is required", i)
+		}
+	}
+
+	if len(cfg.Approvers) > MaxApprovers {
+		return fmt.Errorf("at most %d approvers are allowed", MaxApprovers)
+	}
+	for i, approver := range cfg.Approvers {
+		jid, err := types.ParseJID(approver)
+		if err != nil || approver == "" || (jid.Server != types.DefaultUserServer && jid.Server != types.HiddenUserServer) {
+			return fmt.Errorf("approver %d: must be a user JID such as 15551234567@s.whatsapp.net", i)
+		}
+	}
+
+	if cfg.ExpireHours < 0 || cfg.ExpireHours > MaxExpireHours {
+		return fmt.Errorf("expire_hours must be between 1 and %d, or 0 for the default", MaxExpireHours)
+	}
+	return nil
+}
+
+// SetConfig validates and applies a new configuration
+func (q *Queue) SetConfig(cfg localTypes.ApprovalConfig) error {
+	if err := ValidateConfig(cfg); err != nil {
+		return err
+	}
+	if cfg.Keys == nil {
+		cfg.Keys = []string{}
+	}
+	if cfg.Approvers == nil {
+		cfg.Approvers = []string{}
+	}
+	if cfg.ExpireHours == 0 {
+		cfg.ExpireHours = DefaultExpireHours
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.config = cfg
+	return nil
+}
+
+// Config returns the current configuration
+func (q *Queue) Config() localTypes.ApprovalConfig {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.config
+}
+
+// SetExecutor sets how approved sends are run
+func (q *Queue) SetExecutor(execute Executor) {
+	q.execute = execute
+}
+
+// Required reports whether sends made with the named API key are held
+func (q *Queue) Required(keyName string) bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.config.Enabled && (slices.Contains(q.config.Keys, AllKeys) || slices.Contains(q.config.Keys, keyName))
+}
+
+// Hold parks a send made with the named API key to route and asks the
+// approvers about it in the background. body is the request as sent; req is
+// it read as an /api/send request, or just its recipient for other routes.
+func (q *Queue) Hold(keyName, route string, req localTypes.SendMessageRequest, body string) (*localTypes.PendingApproval, error) {
+	cfg := q.Config()
+	now := time.Now().UTC()
+	a := &localTypes.PendingApproval{
+		Tenant:    keyName,
+		Recipient: req.Recipient,
+		Route:     route,
+		Request:   req,
+		Body:      body,
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Duration(cfg.ExpireHours) * time.Hour),
+	}
+	if err := q.store.CreatePendingApproval(a); err != nil {
+		return nil, err
+	}
+
+	if len(cfg.Approvers) > 0 {
+		text := notificationText(a)
+		recovery.Go(fmt.Sprintf("approval request %d", a.ID), func() {
+			ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			defer cancel()
+			for _, approver := range cfg.Approvers {
+				messageID, err := q.notify(ctx, approver, text)
+				if err != nil {
+					q.logger.Warnf("Failed to ask %s to approve send %d: %v", approver, a.ID, err)
+					continue
+				}
+				if err := q.store.AddApprovalNotification(messageID, a.ID); err != nil {
+					q.logger.Warnf("Failed to record approval request %d: %v", a.ID, err)
+				}
+			}
+		})
+	}
+	return a, nil
+}
+
+// List returns held sends, newest first, after expiring those nobody
+// decided on in time. Empty status or owner do not filter.
+func (q *Queue) List(status, owner string, limit int) ([]localTypes.PendingApproval, error) {
+	if _, err := q.store.ExpirePendingApprovals(time.Now()); err != nil {
+		return nil, err
+	}
+	return q.store.ListPendingApprovals(status, owner, limit)
+}
+
+// Approve runs a held send on behalf of the API key that made it and
+// returns it with the outcome
+func (q *Queue) Approve(ctx context.Context, id int64, approver string) (*localTypes.PendingApproval, error) {
+	a, err := q.decide(id, database.ApprovalApproved, approver, "")
+	if err != nil {
+		return nil, err
+	}
+
+	status, messageID, errMsg := database.ApprovalSent, "", ""
+	result, err := q.run(ctx, a)
+	switch {
+	case err != nil:
+		status, errMsg = database.ApprovalFailed, err.Error()
+	case !result.Success:
+		status, errMsg = database.ApprovalFailed, result.Error
+	default:
+		messageID = result.MessageID
+	}
+	if err := q.store.FinishPendingApproval(id, status, messageID, errMsg); err != nil {
+		q.logger.Warnf("Failed to record the outcome of approved send %d: %v", id, err)
+	}
+	a.Status, a.MessageID, a.Error = status, messageID, errMsg
+	return a, nil
+}
+
+// Reject drops a held send
+func (q *Queue) Reject(id int64, approver, reason string) (*localTypes.PendingApproval, error) {
+	return q.decide(id, database.ApprovalRejected, approver, reason)
+}
+
+// decide moves a pending send to status, telling why it cannot be
+func (q *Queue) decide(id int64, status, approver, reason string) (*localTypes.PendingApproval, error) {
+	a, err := q.store.GetPendingApproval(id)
+	if err != nil {
+		return nil, err
+	}
+	if a == nil {
+		return nil, ErrNotFound
+	}
+	if approver == APIApprover(a.Tenant) {
+		return nil, ErrSelfApproval
+	}
+
+	now := time.Now().UTC()
+	ok, err := q.store.DecidePendingApproval(id, status, approver, reason, now)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if a.Status == database.ApprovalPending && !a.ExpiresAt.After(now) {
+			_, _ = q.store.ExpirePendingApprovals(now)
+			return nil, ErrExpired
+		}
+		if a, err = q.store.GetPendingApproval(id); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("held send is already %s", a.Status)
+	}
+
+	a.Status, a.DecidedBy, a.DecidedAt, a.Reason = status, approver, &now, reason
+	return a, nil
+}
+
+// run sends an approved message. It is not abandoned when the approver
+// goes away, so its outcome can be recorded.
+func (q *Queue) run(ctx context.Context, a *localTypes.PendingApproval) (localTypes.SendResult, error) {
+	if q.execute == nil {
+		return localTypes.SendResult{}, fmt.Errorf("sending is not available")
+	}
+	ctx, cancel := context.WithTimeout(tenant.WithName(context.WithoutCancel(ctx), a.Tenant), sendTimeout)
+	defer cancel()
+	return q.execute(ctx, a)
+}
+
+// APIApprover names an approver deciding through the API with the named key
+func APIApprover(keyName string) string {
+	return "api:" + tenant.Owner(keyName)
+}
+
+// HandleReaction decides a held send when an approver reacts to the message
+// that asked about it: thumbs up approves, thumbs down rejects. It reports
+// whether the reaction was such a decision.
+func (q *Queue) HandleReaction(msg *events.Message) bool {
+	reaction := msg.Message.GetReactionMessage()
+	if reaction == nil || msg.Info.IsFromMe || msg.Info.IsGroup {
+		return false
+	}
+
+	approve := strings.HasPrefix(reaction.GetText(), "👍")
+	if !approve && !strings.HasPrefix(reaction.GetText(), "👎") {
+		return false
+	}
+	approver, ok := q.approver(msg.Info.Sender, msg.Info.SenderAlt)
+	if !ok {
+		return false
+	}
+	id, ok, err := q.store.GetApprovalForNotification(reaction.GetKey().GetID())
+	if err != nil {
+		q.logger.Warnf("Failed to look up approval request %s: %v", reaction.GetKey().GetID(), err)
+		return false
+	}
+	if !ok {
+		return false
+	}
+
+	chatJID := msg.Info.Chat.ToNonAD().String()
+	recovery.Go(fmt.Sprintf("approval decision %d", id), func() {
+		ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+		defer cancel()
+
+		var reply string
+		if approve {
+			a, err := q.Approve(ctx, id, approver)
+			reply = DecisionText(id, a, err)
+		} else {
+			a, err := q.Reject(id, approver, "")
+			reply = DecisionText(id, a, err)
+		}
+		if _, err := q.notify(ctx, chatJID, reply); err != nil {
+			q.logger.Warnf("Failed to confirm decision on send %d to %s: %v", id, approver, err)
+		}
+	})
+	return true
+}
+
+// approver returns the configured approver that sent a message, tried by
+// phone number and by LID
+func (q *Queue) approver(addrs ...types.JID) (string, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	for _, addr := range addrs {
+		if addr.IsEmpty() {
+			continue
+		}
+		if jid := addr.ToNonAD().String(); slices.Contains(q.config.Approvers, jid) {
+			return jid, true
+		}
+	}
+	return "", false
+}
+
+// DecisionText describes the outcome of approving or rejecting a send
+func DecisionText(id int64, a *localTypes.PendingApproval, err error) string {
+	if err != nil {
+		return fmt.Sprintf("Send #%d: %v.", id, err)
+	}
+	switch a.Status {
+	case database.ApprovalSent:
+		return fmt.Sprintf("Send #%d approved and sent to %s (%s).", id, a.Recipient, a.MessageID)
+	case database.ApprovalFailed:
+		return fmt.Sprintf("Send #%d approved but failed: %s", id, a.Error)
+	default:
+		return fmt.Sprintf("Send #%d %s.", id, a.Status)
+	}
+}
+
+// notificationText asks an approver about a held send
+func notificationText(a *localTypes.PendingApproval) string {
+	preview := a.Request.Message
+	if utf8.RuneCountInString(preview) > previewLength {
+		preview = string([]rune(preview)[:previewLength]) + "…"
+	}
+
+	lines := []string{fmt.Sprintf("Approval needed for send #%d by %s to %s:", a.ID, a.Tenant, a.Recipient), ""}
+	if a.Route != "" && a.Route != SendRoute {
+		// Other endpoints are shown as the request that was made
+		preview = a.Body
+		if utf8.RuneCountInString(preview) > previewLength {
+			preview = string([]rune(preview)[:previewLength]) + "…"
+		}
+		lines = append(lines, "[request] POST "+a.Route)
+	}
+	if a.Request.MediaPath != "" {
+		lines = append(lines, "[media] "+a.Request.MediaPath)
+	}
+	if preview != "" {
+		lines = append(lines, preview)
+	}
+	lines = append(lines, "", "React 👍 to approve or 👎 to reject. Expires "+a.ExpiresAt.Format(time.RFC3339)+".")
+	return strings.Join(lines, "\n")
+}
+
+// sendNotification messages an approver. Like command replies these skip
+// the duplicate check, as two held sends may well look alike.
+func (q *Queue) sendNotification(ctx context.Context, chatJID, text string) (string, error) {
+	result, err := q.outbox.SendForced(automation.WithOrigin(ctx, automation.OriginApprovals), outbox.PriorityHigh, chatJID, text, "")
+	if err != nil {
+		return "", err
+	}
+	if !result.Success {
+		return "", errors.New(result.Error)
+	}
+	return result.MessageID, nil
+}
@@ -0,0 +1,139 @@
+package approval
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/proto/waCommon"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	waLog "go.mau.fi/whatsmeow/util/log"
+	"google.golang.org/protobuf/proto"
+
+	"whatsapp-bridge/internal/database"
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+const approver = "15550000001@s.whatsapp.net"
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     localTypes.ApprovalConfig
+		wantErr bool
+	}{
+		{"disabled empty", localTypes.ApprovalConfig{}, false},
+		{"enabled without keys", localTypes.ApprovalConfig{Enabled: true}, true},
+		{"blank key", localTypes.ApprovalConfig{Keys: []string{" "}}, true},
+		{"group approver", localTypes.ApprovalConfig{Keys: []string{"marketing"}, Approvers: []string{"123@g.us"}}, true},
+		{"long expiry", localTypes.ApprovalConfig{Keys: []string{"marketing"}, ExpireHours: MaxExpireHours + 1}, true},
+		{"valid", localTypes.ApprovalConfig{Enabled: true, Keys: []string{"marketing"}, Approvers: []string{approver}, ExpireHours: 4}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateConfig(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRequired(t *testing.T) {
+	q := New(nil, nil, waLog.Noop)
+	if q.Required("marketing") {
+		t.Error("sends held while approval is disabled")
+	}
+	if q.Config().ExpireHours != DefaultExpireHours {
+		t.Errorf("default expire_hours = %d", q.Config().ExpireHours)
+	}
+
+	if err := q.SetConfig(localTypes.ApprovalConfig{Enabled: true, Keys: []string{"marketing"}}); err != nil {
+		t.Fatalf("SetConfig() error = %v", err)
+	}
+	if !q.Required("marketing") || q.Required("support") {
+		t.Error("only the listed key should be held")
+	}
+
+	_ = q.SetConfig(localTypes.ApprovalConfig{Enabled: true, Keys: []string{AllKeys}})
+	if !q.Required("support") || !q.Required("default") {
+		t.Error("every key should be held with *")
+	}
+}
+
+func TestHandleReactionIgnoresOthers(t *testing.T) {
+	q := New(nil, nil, waLog.Noop)
+	_ = q.SetConfig(localTypes.ApprovalConfig{Enabled: true, Keys: []string{"marketing"}, Approvers: []string{approver}})
+
+	reaction := func(sender, emoji string) *events.Message {
+		jid, _ := types.ParseJID(sender)
+		return &events.Message{
+			Info: types.MessageInfo{MessageSource: types.MessageSource{Chat: jid, Sender: jid}},
+			Message: &waE2E.Message{ReactionMessage: &waE2E.ReactionMessage{
+				Key:  &waCommon.MessageKey{ID: proto.String("3EB0AA")},
+				Text: proto.String(emoji),
+			}},
+		}
+	}
+
+	// None of these reach the store, which is nil here
+	for name, msg := range map[string]*events.Message{
+		"not an approver": reaction("15550000009@s.whatsapp.net", "👍"),
+		"other emoji":     reaction(approver, "❤️"),
+		"removed":         reaction(approver, ""),
+		"not a reaction":  {Info: reaction(approver, "👍").Info, Message: &waE2E.Message{Conversation: proto.String("👍")}},
+	} {
+		if q.HandleReaction(msg) {
+			t.Errorf("%s: reaction handled", name)
+		}
+	}
+}
+
+func TestNotificationText(t *testing.T) {
+	expires := time.Date(2024, 6, 1, 18, 0, 0, 0, time.UTC)
+	a := &localTypes.PendingApproval{
+		ID:        7,
+		Tenant:    "marketing",
+		Recipient: "15550102030",
+		Request:   localTypes.SendMessageRequest{Message: strings.Repeat("ä", previewLength+5), MediaPath: "/srv/flyer.jpg"},
+		ExpiresAt: expires,
+	}
+
+	text := notificationText(a)
+	for _, want := range []string{"send #7 by marketing to 15550102030", "[media] /srv/flyer.jpg", "React 👍", "2024-06-01T18:00:00Z"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("notification is missing %q:\n%s", want, text)
+		}
+	}
+	if strings.Count(text, "ä") != previewLength || !strings.Contains(text, "…") {
+		t.Error("long message not shortened")
+	}
+
+	// Other endpoints show the request made
+	a = &localTypes.PendingApproval{ID: 8, Tenant: "marketing", Recipient: "120363000000000000@g.us", Route: "/api/poll",
+		Request: localTypes.SendMessageRequest{Recipient: "120363000000000000@g.us"}, Body: `{"question":"Lunch?"}`, ExpiresAt: expires}
+	text = notificationText(a)
+	for _, want := range []string{"send #8 by marketing", "[request] POST /api/poll", `{"question":"Lunch?"}`} {
+		if !strings.Contains(text, want) {
+			t.Errorf("notification is missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestDecisionText(t *testing.T) {
+	sent := &localTypes.PendingApproval{Recipient: "15550102030", Status: database.ApprovalSent, MessageID: "3EB0FF"}
+	if got := DecisionText(3, sent, nil); got != "Send #3 approved and sent to 15550102030 (3EB0FF)." {
+		t.Errorf("sent = %q", got)
+	}
+	if got := DecisionText(3, &localTypes.PendingApproval{Status: database.ApprovalRejected}, nil); got != "Send #3 rejected." {
+		t.Errorf("rejected = %q", got)
+	}
+	if got := DecisionText(3, nil, ErrExpired); got != "Send #3: held send has expired." {
+		t.Errorf("error = %q", got)
+	}
+	if APIApprover("") != "api:default" {
+		t.Errorf("APIApprover(\"\") = %q", APIApprover(""))
+	}
+}
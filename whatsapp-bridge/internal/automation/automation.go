@@ -0,0 +1,201 @@
+// Package automation tags sends made by the bridge's own automations, and by
+// external bots that identify themselves, and breaks reply loops: when one
+// automation sends too many messages to one chat in a short time it is paused
+// until an operator resumes it.
+package automation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+// Origins of the bridge's built-in automations
+const (
+	OriginMaintenance   = "maintenance"
+	OriginBusinessHours = "business_hours"
+	OriginCommands      = "commands"
+	OriginApprovals     = "approvals"
+)
+
+// Reserved reports whether origin names one of the built-in automations,
+// which API callers may not claim
+func Reserved(origin string) bool {
+	switch origin {
+	case OriginMaintenance, OriginBusinessHours, OriginCommands, OriginApprovals:
+		return true
+	}
+	return false
+}
+
+// Loop breaker defaults: more than 10 messages from one automation to one
+// chat within a minute is treated as a loop
+const (
+	DefaultMaxMessages   = 10
+	DefaultWindowSeconds = 60
+	MaxWindowSeconds     = 60 * 60
+)
+
+type contextKey struct{}
+
+// WithOrigin returns a context marking sends as made by the named automation
+func WithOrigin(ctx context.Context, origin string) context.Context {
+	return context.WithValue(ctx, contextKey{}, origin)
+}
+
+// Origin returns the automation carried by ctx, or "" for a manual send
+func Origin(ctx context.Context) string {
+	origin, _ := ctx.Value(contextKey{}).(string)
+	return origin
+}
+
+// DefaultConfig is the loop breaker configuration until one is stored
+func DefaultConfig() localTypes.LoopBreakerConfig {
+	return localTypes.LoopBreakerConfig{
+		MaxMessages:   DefaultMaxMessages,
+		WindowSeconds: DefaultWindowSeconds,
+	}
+}
+
+// ValidateConfig checks a loop breaker configuration
+func ValidateConfig(cfg localTypes.LoopBreakerConfig) error {
+	if cfg.MaxMessages < 0 {
+		return fmt.Errorf("max_messages must not be negative")
+	}
+	if cfg.MaxMessages > 0 && (cfg.WindowSeconds <= 0 || cfg.WindowSeconds > MaxWindowSeconds) {
+		return fmt.Errorf("window_seconds must be between 1 and %d", MaxWindowSeconds)
+	}
+	return nil
+}
+
+// Breaker counts each automation's sends per chat and pauses an automation
+// that exceeds the configured rate. Paused automations stay paused until
+// Resume is called or the bridge restarts.
+type Breaker struct {
+	mu     sync.Mutex
+	config localTypes.LoopBreakerConfig
+	sends  map[string][]time.Time // origin + chat -> recent send times
+	paused map[string]localTypes.PausedAutomation
+	now    func() time.Time
+
+	lastPrune time.Time // when chats with no recent sends were last dropped
+}
+
+// NewBreaker creates a breaker with the default configuration
+func NewBreaker() *Breaker {
+	return &Breaker{
+		config: DefaultConfig(),
+		sends:  make(map[string][]time.Time),
+		paused: make(map[string]localTypes.PausedAutomation),
+		now:    time.Now,
+	}
+}
+
+// SetConfig validates and applies a configuration. MaxMessages 0 disables
+// loop detection; automations already paused stay paused.
+func (b *Breaker) SetConfig(cfg localTypes.LoopBreakerConfig) error {
+	if err := ValidateConfig(cfg); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.config = cfg
+	b.sends = make(map[string][]time.Time)
+	return nil
+}
+
+// Config returns the current configuration
+func (b *Breaker) Config() localTypes.LoopBreakerConfig {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.config
+}
+
+// Allow records a send by origin to chatJID. It returns false when the
+// automation is paused, and tripped is true when this send is the one that
+// paused it. Manual sends (empty origin) are always allowed.
+func (b *Breaker) Allow(origin, chatJID string) (allowed, tripped bool) {
+	if origin == "" {
+		return true, false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.paused[origin]; ok {
+		return false, false
+	}
+	if b.config.MaxMessages == 0 {
+		return true, false
+	}
+
+	now := b.now()
+	cutoff := now.Add(-time.Duration(b.config.WindowSeconds) * time.Second)
+	key := origin + "\x00" + chatJID
+
+	if now.Sub(b.lastPrune) > time.Duration(b.config.WindowSeconds)*time.Second {
+		for k, times := range b.sends {
+			if !times[len(times)-1].After(cutoff) {
+				delete(b.sends, k)
+			}
+		}
+		b.lastPrune = now
+	}
+
+	recent := b.sends[key][:0]
+	for _, at := range b.sends[key] {
+		if at.After(cutoff) {
+			recent = append(recent, at)
+		}
+	}
+	recent = append(recent, now)
+
+	if len(recent) > b.config.MaxMessages {
+		delete(b.sends, key)
+		b.paused[origin] = localTypes.PausedAutomation{
+			Origin:   origin,
+			ChatJID:  chatJID,
+			Messages: len(recent) - 1,
+			PausedAt: now.UTC(),
+		}
+		return false, true
+	}
+	b.sends[key] = recent
+	return true, false
+}
+
+// Paused lists the paused automations, oldest first
+func (b *Breaker) Paused() []localTypes.PausedAutomation {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	paused := make([]localTypes.PausedAutomation, 0, len(b.paused))
+	for _, p := range b.paused {
+		paused = append(paused, p)
+	}
+	sort.Slice(paused, func(i, j int) bool { return paused[i].PausedAt.Before(paused[j].PausedAt) })
+	return paused
+}
+
+// Resume lets a paused automation send again. Returns false if it was not paused.
+func (b *Breaker) Resume(origin string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.paused[origin]; !ok {
+		return false
+	}
+	delete(b.paused, origin)
+	for key := range b.sends {
+		if strings.HasPrefix(key, origin+"\x00") {
+			delete(b.sends, key)
+		}
+	}
+	return true
+}
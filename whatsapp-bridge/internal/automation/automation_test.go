@@ -0,0 +1,126 @@
+package automation
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+func TestOrigin(t *testing.T) {
+	if got := Origin(context.Background()); got != "" {
+		t.Errorf("Origin of a plain context = %q", got)
+	}
+	if got := Origin(WithOrigin(context.Background(), "support-bot")); got != "support-bot" {
+		t.Errorf("Origin = %q", got)
+	}
+}
+
+func TestBreakerTrips(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	b := NewBreaker()
+	b.now = func() time.Time { return now }
+	if err := b.SetConfig(localTypes.LoopBreakerConfig{MaxMessages: 3, WindowSeconds: 10}); err != nil {
+		t.Fatalf("SetConfig: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if allowed, _ := b.Allow("bot", "chat-a"); !allowed {
+			t.Fatalf("Send %d blocked", i+1)
+		}
+		now = now.Add(time.Second)
+	}
+
+	// Other chats and manual sends are counted separately
+	if allowed, _ := b.Allow("bot", "chat-b"); !allowed {
+		t.Error("Send to another chat blocked")
+	}
+	if allowed, _ := b.Allow("", "chat-a"); !allowed {
+		t.Error("Manual send blocked")
+	}
+
+	allowed, tripped := b.Allow("bot", "chat-a")
+	if allowed || !tripped {
+		t.Fatalf("Expected the 4th send within the window to trip, got allowed=%v tripped=%v", allowed, tripped)
+	}
+	if allowed, tripped := b.Allow("bot", "chat-b"); allowed || tripped {
+		t.Errorf("Expected the paused automation to be blocked everywhere, got allowed=%v tripped=%v", allowed, tripped)
+	}
+
+	paused := b.Paused()
+	if len(paused) != 1 || paused[0].Origin != "bot" || paused[0].ChatJID != "chat-a" || paused[0].Messages != 3 {
+		t.Fatalf("Unexpected paused list: %+v", paused)
+	}
+
+	if !b.Resume("bot") || b.Resume("bot") {
+		t.Error("Expected Resume to succeed once")
+	}
+	if allowed, _ := b.Allow("bot", "chat-a"); !allowed {
+		t.Error("Resumed automation blocked")
+	}
+}
+
+func TestBreakerWindow(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	b := NewBreaker()
+	b.now = func() time.Time { return now }
+	_ = b.SetConfig(localTypes.LoopBreakerConfig{MaxMessages: 2, WindowSeconds: 10})
+
+	for i := 0; i < 10; i++ {
+		if allowed, _ := b.Allow("maintenance", "chat"); !allowed {
+			t.Fatalf("Send %d blocked although spaced beyond the window", i+1)
+		}
+		now = now.Add(6 * time.Second)
+	}
+
+	_ = b.SetConfig(localTypes.LoopBreakerConfig{})
+	for i := 0; i < 20; i++ {
+		if allowed, _ := b.Allow("maintenance", "chat"); !allowed {
+			t.Fatal("Send blocked with loop detection disabled")
+		}
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	for _, cfg := range []localTypes.LoopBreakerConfig{
+		{MaxMessages: -1},
+		{MaxMessages: 5},
+		{MaxMessages: 5, WindowSeconds: MaxWindowSeconds + 1},
+	} {
+		if err := ValidateConfig(cfg); err == nil {
+			t.Errorf("Expected %+v to be invalid", cfg)
+		}
+	}
+	if err := ValidateConfig(localTypes.LoopBreakerConfig{}); err != nil {
+		t.Errorf("Expected a disabled config to be valid: %v", err)
+	}
+}
+
+func TestBreakerPrunesIdleChats(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	b := NewBreaker()
+	b.now = func() time.Time { return now }
+	_ = b.SetConfig(localTypes.LoopBreakerConfig{MaxMessages: 5, WindowSeconds: 10})
+
+	for i := 0; i < 100; i++ {
+		b.Allow("bot", fmt.Sprintf("chat-%d", i))
+	}
+	now = now.Add(time.Minute)
+	b.Allow("bot", "chat-new")
+	if len(b.sends) != 1 {
+		t.Errorf("breaker keeps %d chats, want only the one sent to in the window", len(b.sends))
+	}
+}
+
+func TestReserved(t *testing.T) {
+	for _, origin := range []string{OriginMaintenance, OriginBusinessHours, OriginCommands, OriginApprovals} {
+		if !Reserved(origin) {
+			t.Errorf("%q not reserved", origin)
+		}
+	}
+	if Reserved("support-bot") || Reserved("") {
+		t.Error("a caller's own origin is reserved")
+	}
+}
@@ -0,0 +1,257 @@
+// Package autoread sends read receipts for incoming messages that automation
+// has already processed, so the phone's unread badge reflects what is left.
+package autoread
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow/types/events"
+	waLog "go.mau.fi/whatsmeow/util/log"
+
+	localTypes "whatsapp-bridge/internal/types"
+	"whatsapp-bridge/internal/webhook"
+	"whatsapp-bridge/internal/whatsapp"
+)
+
+const (
+	// MaxDelaySeconds caps how long receipts may be held back
+	MaxDelaySeconds = 3600
+
+	// seenTTL is how long a message waits for a webhook delivery before it is forgotten
+	seenTTL = 10 * time.Minute
+)
+
+// batchKey groups receipts: one MarkRead call per chat and sender
+type batchKey struct {
+	chat   string
+	sender string
+}
+
+// seenMessage remembers an incoming message until its webhook is delivered
+type seenMessage struct {
+	key  batchKey
+	seen time.Time
+}
+
+// deliveredMessage remembers a webhook delivery that arrived before the
+// message reached HandleMessage
+type deliveredMessage struct {
+	chat string
+	at   time.Time
+}
+
+// Marker batches and sends automatic read receipts
+type Marker struct {
+	client *whatsapp.Client
+	logger waLog.Logger
+
+	mu      sync.Mutex
+	config  localTypes.AutoReadConfig
+	rules   []*webhook.Expression
+	batches map[batchKey][]string
+	seen    map[string]seenMessage // message ID -> origin, for MarkOnWebhookDelivery
+
+	// delivered holds deliveries that beat their message here: webhooks are
+	// sent asynchronously by the time HandleMessage runs, so a fast receiver
+	// can be acknowledged first
+	delivered map[string]deliveredMessage
+
+	lastPrune time.Time
+}
+
+// NewMarker creates a disabled marker; call SetConfig to enable it
+func NewMarker(client *whatsapp.Client, logger waLog.Logger) *Marker {
+	return &Marker{
+		client:    client,
+		logger:    logger,
+		config:    localTypes.AutoReadConfig{Rules: []string{}},
+		batches:   make(map[batchKey][]string),
+		seen:      make(map[string]seenMessage),
+		delivered: make(map[string]deliveredMessage),
+	}
+}
+
+// ValidateConfig checks delay bounds and compiles every rule
+func ValidateConfig(cfg localTypes.AutoReadConfig) ([]*webhook.Expression, error) {
+	if cfg.DelaySeconds < 0 || cfg.DelaySeconds > MaxDelaySeconds {
+		return nil, fmt.Errorf("delay_seconds must be between 0 and %d", MaxDelaySeconds)
+	}
+
+	rules := make([]*webhook.Expression, 0, len(cfg.Rules))
+	for i, rule := range cfg.Rules {
+		expr, err := webhook.CompileExpression(rule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule %d: %v", i, err)
+		}
+		rules = append(rules, expr)
+	}
+	return rules, nil
+}
+
+// SetConfig validates and applies a new configuration
+func (m *Marker) SetConfig(cfg localTypes.AutoReadConfig) error {
+	rules, err := ValidateConfig(cfg)
+	if err != nil {
+		return err
+	}
+	if cfg.Rules == nil {
+		cfg.Rules = []string{}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.config = cfg
+	m.rules = rules
+	if !cfg.MarkOnWebhookDelivery {
+		m.seen = make(map[string]seenMessage)
+		m.delivered = make(map[string]deliveredMessage)
+	}
+	return nil
+}
+
+// Config returns the current configuration
+func (m *Marker) Config() localTypes.AutoReadConfig {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.config
+}
+
+// HandleMessage queues msg for a read receipt if a rule matches it, or
+// remembers it until a webhook delivery confirms it was handled. It runs
+// after the message's webhooks were dispatched, so a delivery may already
+// have been reported; that message is queued right away.
+func (m *Marker) HandleMessage(msg *events.Message, chatName string) {
+	if msg.Info.IsFromMe {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.config.Enabled {
+		return
+	}
+
+	key := batchKey{chat: msg.Info.Chat.String()}
+	if msg.Info.IsGroup {
+		key.sender = msg.Info.Sender.ToNonAD().String()
+	}
+
+	if len(m.rules) > 0 {
+		content := whatsapp.ExtractTextContent(msg.Message)
+		mediaType, _, _, _, _, _, _ := whatsapp.ExtractMediaInfo(msg.Message)
+		env := webhook.MessageExpressionEnv(msg, content, mediaType, chatName)
+
+		for _, rule := range m.rules {
+			ok, err := rule.Eval(env)
+			if err != nil {
+				m.logger.Debugf("Auto-read rule %q failed: %v", rule, err)
+				continue
+			}
+			if ok {
+				m.enqueueLocked(key, msg.Info.ID)
+				return
+			}
+		}
+	}
+
+	if m.config.MarkOnWebhookDelivery {
+		m.pruneSeenLocked()
+		if delivered, ok := m.delivered[msg.Info.ID]; ok && delivered.chat == key.chat {
+			delete(m.delivered, msg.Info.ID)
+			m.enqueueLocked(key, msg.Info.ID)
+			return
+		}
+		m.seen[msg.Info.ID] = seenMessage{key: key, seen: time.Now()}
+	}
+}
+
+// HandleWebhookDelivered queues a read receipt for a message whose webhook
+// was delivered. Intended as the webhook manager's delivery hook.
+func (m *Marker) HandleWebhookDelivered(chatJID, messageID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.config.Enabled || !m.config.MarkOnWebhookDelivery {
+		return
+	}
+
+	seen, ok := m.seen[messageID]
+	if !ok {
+		// HandleMessage has not seen it yet
+		m.pruneSeenLocked()
+		m.delivered[messageID] = deliveredMessage{chat: chatJID, at: time.Now()}
+		return
+	}
+	if seen.key.chat != chatJID {
+		return
+	}
+	delete(m.seen, messageID)
+	m.enqueueLocked(seen.key, messageID)
+}
+
+// enqueueLocked adds a message to its batch, starting the batch timer on the
+// first entry. Caller must hold m.mu.
+func (m *Marker) enqueueLocked(key batchKey, messageID string) {
+	if !m.client.ReadReceiptsAllowed(key.chat) {
+		return
+	}
+
+	pending, exists := m.batches[key]
+	m.batches[key] = append(pending, messageID)
+	if exists {
+		return
+	}
+
+	delay := time.Duration(m.config.DelaySeconds) * time.Second
+	time.AfterFunc(delay, func() { m.flush(key) })
+}
+
+// flush sends the read receipt for one batch
+func (m *Marker) flush(key batchKey) {
+	m.mu.Lock()
+	ids := m.batches[key]
+	delete(m.batches, key)
+	m.mu.Unlock()
+
+	if len(ids) == 0 {
+		return
+	}
+
+	// Policy may have changed while the batch was waiting
+	if !m.client.ReadReceiptsAllowed(key.chat) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), whatsapp.DefaultTimeout)
+	defer cancel()
+	if err := m.client.MarkMessagesRead(ctx, key.chat, ids, key.sender); err != nil {
+		m.logger.Warnf("Auto-read: failed to mark %d messages read in %s: %v", len(ids), key.chat, err)
+		return
+	}
+	m.logger.Debugf("Auto-read: marked %d messages read in %s", len(ids), key.chat)
+}
+
+// pruneSeenLocked drops messages that never got a webhook delivery, and
+// deliveries whose message never arrived. Caller must hold m.mu.
+func (m *Marker) pruneSeenLocked() {
+	if time.Since(m.lastPrune) < time.Minute {
+		return
+	}
+	m.lastPrune = time.Now()
+
+	cutoff := time.Now().Add(-seenTTL)
+	for id, seen := range m.seen {
+		if seen.seen.Before(cutoff) {
+			delete(m.seen, id)
+		}
+	}
+	for id, delivered := range m.delivered {
+		if delivered.at.Before(cutoff) {
+			delete(m.delivered, id)
+		}
+	}
+}
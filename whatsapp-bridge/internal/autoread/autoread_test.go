@@ -0,0 +1,57 @@
+package autoread
+
+import (
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	waLog "go.mau.fi/whatsmeow/util/log"
+	"google.golang.org/protobuf/proto"
+
+	localTypes "whatsapp-bridge/internal/types"
+	"whatsapp-bridge/internal/whatsapp"
+)
+
+func TestDeliveryBeforeMessage(t *testing.T) {
+	client := &whatsapp.Client{}
+	client.SetReceiptPolicy(localTypes.ReceiptPolicy{ReadReceipts: true})
+	m := NewMarker(client, waLog.Noop)
+	if err := m.SetConfig(localTypes.AutoReadConfig{Enabled: true, MarkOnWebhookDelivery: true, DelaySeconds: MaxDelaySeconds}); err != nil {
+		t.Fatalf("SetConfig: %v", err)
+	}
+
+	chat := types.NewJID("15550102030", types.DefaultUserServer)
+	message := func(id string) *events.Message {
+		return &events.Message{
+			Info: types.MessageInfo{
+				MessageSource: types.MessageSource{Chat: chat, Sender: chat},
+				ID:            id,
+				Timestamp:     time.Now(),
+			},
+			Message: &waE2E.Message{Conversation: proto.String("hi")},
+		}
+	}
+	queued := func() []string {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return m.batches[batchKey{chat: chat.String()}]
+	}
+
+	// Webhook delivered after the message was handled
+	m.HandleMessage(message("M1"), "Ann")
+	m.HandleWebhookDelivered(chat.String(), "M1")
+
+	// Webhook delivered before HandleMessage ran
+	m.HandleWebhookDelivered(chat.String(), "M2")
+	m.HandleMessage(message("M2"), "Ann")
+
+	// Delivered for another chat
+	m.HandleWebhookDelivered("other@s.whatsapp.net", "M3")
+	m.HandleMessage(message("M3"), "Ann")
+
+	if ids := queued(); len(ids) != 2 || ids[0] != "M1" || ids[1] != "M2" {
+		t.Errorf("queued receipts = %v, want M1 and M2", ids)
+	}
+}
@@ -0,0 +1,231 @@
+// Package businesshours answers the first direct message a contact sends
+// outside configured opening hours with a templated "we're closed" reply, in
+// the contact's language when it has a translation.
+package businesshours
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata" // IANA zones for minimal container images
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	waLog "go.mau.fi/whatsmeow/util/log"
+
+	"whatsapp-bridge/internal/automation"
+	"whatsapp-bridge/internal/database"
+	"whatsapp-bridge/internal/lang"
+	"whatsapp-bridge/internal/outbox"
+	localTypes "whatsapp-bridge/internal/types"
+	"whatsapp-bridge/internal/whatsapp"
+)
+
+// MaxMessageLength caps the auto-reply template
+const MaxMessageLength = 4096
+
+// autoReplyKind identifies business hours replies in the auto-reply log
+const autoReplyKind = "business_hours"
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// openRange is one opening period within a day, in minutes after midnight
+type openRange struct {
+	open, close int
+}
+
+// Schedule is a compiled set of weekly opening hours in one time zone
+type Schedule struct {
+	loc    *time.Location
+	ranges map[time.Weekday][]openRange
+}
+
+// Compile validates a configuration and builds its schedule
+func Compile(cfg localTypes.BusinessHoursConfig) (*Schedule, error) {
+	if len(cfg.Message) > MaxMessageLength {
+		return nil, fmt.Errorf("message must be at most %d characters", MaxMessageLength)
+	}
+	if cfg.Enabled && cfg.Message == "" {
+		return nil, fmt.Errorf("message is required when business hours are enabled")
+	}
+	if err := lang.ValidateVariants(cfg.Translations, MaxMessageLength); err != nil {
+		return nil, err
+	}
+	if cfg.Enabled && len(cfg.Hours) == 0 {
+		return nil, fmt.Errorf("at least one opening period is required when business hours are enabled")
+	}
+
+	tz := cfg.Timezone
+	if tz == "" {
+		tz = "UTC"
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %v", cfg.Timezone, err)
+	}
+
+	s := &Schedule{loc: loc, ranges: make(map[time.Weekday][]openRange)}
+	for i, h := range cfg.Hours {
+		day, ok := weekdays[strings.ToLower(h.Day)]
+		if !ok {
+			return nil, fmt.Errorf("hours[%d]: invalid day %q (use mon..sun)", i, h.Day)
+		}
+		open, err := parseClock(h.Open)
+		if err != nil {
+			return nil, fmt.Errorf("hours[%d]: invalid open time: %v", i, err)
+		}
+		closing, err := parseClock(h.Close)
+		if err != nil {
+			return nil, fmt.Errorf("hours[%d]: invalid close time: %v", i, err)
+		}
+		if closing <= open {
+			return nil, fmt.Errorf("hours[%d]: close must be after open", i)
+		}
+		s.ranges[day] = append(s.ranges[day], openRange{open, closing})
+	}
+
+	for day := range s.ranges {
+		sort.Slice(s.ranges[day], func(a, b int) bool { return s.ranges[day][a].open < s.ranges[day][b].open })
+	}
+	return s, nil
+}
+
+// parseClock parses "HH:MM" (24h, "24:00" allowed as end of day) into minutes
+func parseClock(v string) (int, error) {
+	var h, m int
+	if _, err := fmt.Sscanf(v, "%d:%d", &h, &m); err != nil || len(v) != 5 {
+		return 0, fmt.Errorf("%q is not HH:MM", v)
+	}
+	if h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("%q is out of range", v)
+	}
+	return h*60 + m, nil
+}
+
+// Status reports whether t falls within opening hours and, if not, when the
+// next opening period starts. nextOpen is zero if the schedule never opens.
+func (s *Schedule) Status(t time.Time) (open bool, nextOpen time.Time) {
+	t = t.In(s.loc)
+	y, mo, d := t.Date()
+
+	for offset := 0; offset <= 7; offset++ {
+		day := time.Date(y, mo, d+offset, 0, 0, 0, 0, s.loc)
+		for _, r := range s.ranges[day.Weekday()] {
+			start := time.Date(y, mo, d+offset, 0, r.open, 0, 0, s.loc)
+			end := time.Date(y, mo, d+offset, 0, r.close, 0, 0, s.loc)
+			if !t.Before(start) && t.Before(end) {
+				return true, time.Time{}
+			}
+			if start.After(t) {
+				return false, start
+			}
+		}
+	}
+	return false, time.Time{}
+}
+
+// Render fills the reply template's placeholders:
+// {next_open} ("Monday 09:00"), {next_open_date} ("Mon, 3 Mar 09:00 CET") and {name}.
+func Render(template string, nextOpen time.Time, name string) string {
+	return strings.NewReplacer(
+		"{next_open}", nextOpen.Format("Monday 15:04"),
+		"{next_open_date}", nextOpen.Format("Mon, 2 Jan 15:04 MST"),
+		"{name}", name,
+	).Replace(template)
+}
+
+// Responder sends the out-of-hours reply, once per contact per closed period
+type Responder struct {
+	messageStore *database.MessageStore
+	outbox       *outbox.Dispatcher
+	logger       waLog.Logger
+
+	mu       sync.RWMutex
+	config   localTypes.BusinessHoursConfig
+	schedule *Schedule
+}
+
+// NewResponder creates a disabled responder; call SetConfig to enable it
+func NewResponder(messageStore *database.MessageStore, dispatcher *outbox.Dispatcher, logger waLog.Logger) *Responder {
+	return &Responder{
+		messageStore: messageStore,
+		outbox:       dispatcher,
+		logger:       logger,
+		config:       localTypes.BusinessHoursConfig{Hours: []localTypes.BusinessHoursPeriod{}},
+	}
+}
+
+// SetConfig validates and applies a new configuration
+func (r *Responder) SetConfig(cfg localTypes.BusinessHoursConfig) error {
+	schedule, err := Compile(cfg)
+	if err != nil {
+		return err
+	}
+	if cfg.Hours == nil {
+		cfg.Hours = []localTypes.BusinessHoursPeriod{}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.config = cfg
+	r.schedule = schedule
+	return nil
+}
+
+// Config returns the current configuration
+func (r *Responder) Config() localTypes.BusinessHoursConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.config
+}
+
+// HandleMessage replies to an incoming direct message received outside
+// opening hours, unless the contact was already answered this closed period.
+func (r *Responder) HandleMessage(msg *events.Message) {
+	if msg.Info.IsFromMe || msg.Info.IsGroup {
+		return
+	}
+	if server := msg.Info.Chat.Server; server != types.DefaultUserServer && server != types.HiddenUserServer {
+		return
+	}
+
+	r.mu.RLock()
+	cfg, schedule := r.config, r.schedule
+	r.mu.RUnlock()
+	if !cfg.Enabled || schedule == nil {
+		return
+	}
+
+	open, nextOpen := schedule.Status(msg.Info.Timestamp)
+	if open || nextOpen.IsZero() {
+		return
+	}
+
+	// The next opening time identifies the closed period
+	chatJID := msg.Info.Chat.ToNonAD().String()
+	claimed, err := r.messageStore.ClaimAutoReply(autoReplyKind, chatJID, nextOpen.UTC().Format(time.RFC3339))
+	if err != nil {
+		r.logger.Warnf("Failed to check business hours reply for %s: %v", chatJID, err)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	template := lang.Pick(whatsapp.ExtractTextContent(msg.Message), cfg.Translations, cfg.Message)
+	reply := Render(template, nextOpen, msg.Info.PushName)
+	go func() {
+		result, err := r.outbox.Send(automation.WithOrigin(context.Background(), automation.OriginBusinessHours), outbox.PriorityHigh, chatJID, reply, "")
+		if err != nil {
+			r.logger.Warnf("Failed to queue business hours reply to %s: %v", chatJID, err)
+		} else if !result.Success {
+			r.logger.Warnf("Failed to send business hours reply to %s: %s", chatJID, result.Error)
+		}
+	}()
+}
@@ -0,0 +1,78 @@
+package businesshours
+
+import (
+	"testing"
+	"time"
+
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+func TestScheduleStatus(t *testing.T) {
+	schedule, err := Compile(localTypes.BusinessHoursConfig{
+		Enabled:  true,
+		Timezone: "Europe/Berlin",
+		Message:  "closed",
+		Hours: []localTypes.BusinessHoursPeriod{
+			{Day: "mon", Open: "09:00", Close: "12:00"},
+			{Day: "mon", Open: "13:00", Close: "17:00"},
+			{Day: "fri", Open: "09:00", Close: "15:00"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	at := func(day, hour, min int) time.Time { return time.Date(2025, 3, day, hour, min, 0, 0, berlin) }
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		wantOpen bool
+		wantNext time.Time
+	}{
+		{"monday morning open", at(3, 10, 0), true, time.Time{}},
+		{"monday lunch closed", at(3, 12, 30), false, at(3, 13, 0)},
+		{"monday evening", at(3, 18, 0), false, at(7, 9, 0)},
+		{"friday close is exclusive", at(7, 15, 0), false, at(10, 9, 0)},
+		{"sunday", at(9, 11, 0), false, at(10, 9, 0)},
+		{"utc input", time.Date(2025, 3, 3, 8, 30, 0, 0, time.UTC), true, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			open, next := schedule.Status(tt.now)
+			if open != tt.wantOpen || !next.Equal(tt.wantNext) {
+				t.Errorf("Status(%v) = (%v, %v), want (%v, %v)", tt.now, open, next, tt.wantOpen, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestCompileRejectsInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  localTypes.BusinessHoursConfig
+	}{
+		{"bad day", localTypes.BusinessHoursConfig{Hours: []localTypes.BusinessHoursPeriod{{Day: "funday", Open: "09:00", Close: "10:00"}}}},
+		{"bad time", localTypes.BusinessHoursConfig{Hours: []localTypes.BusinessHoursPeriod{{Day: "mon", Open: "9am", Close: "10:00"}}}},
+		{"close before open", localTypes.BusinessHoursConfig{Hours: []localTypes.BusinessHoursPeriod{{Day: "mon", Open: "10:00", Close: "09:00"}}}},
+		{"bad timezone", localTypes.BusinessHoursConfig{Timezone: "Mars/Olympus"}},
+		{"enabled without message", localTypes.BusinessHoursConfig{Enabled: true, Hours: []localTypes.BusinessHoursPeriod{{Day: "mon", Open: "09:00", Close: "10:00"}}}},
+		{"unknown language", localTypes.BusinessHoursConfig{Message: "Closed", Translations: map[string]string{"klingon": "Closed"}}},
+	}
+
+	for _, tt := range tests {
+		if _, err := Compile(tt.cfg); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	next := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	got := Render("Hi {name}, we're back {next_open}.", next, "Ana")
+	if want := "Hi Ana, we're back Monday 09:00."; got != want {
+		t.Errorf("Render = %q, want %q", got, want)
+	}
+}
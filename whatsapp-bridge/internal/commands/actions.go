@@ -0,0 +1,159 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"whatsapp-bridge/internal/approval"
+	"whatsapp-bridge/internal/database"
+	"whatsapp-bridge/internal/outbox"
+)
+
+// maxListedApprovals caps the held sends listed by the approvals command
+const maxListedApprovals = 20
+
+var errApprovalsUnavailable = errors.New("send approval is not available")
+
+// status reports the connection, the outbox lanes, paused automations and
+// maintenance mode
+func (r *Router) status(_ context.Context, _ call) (string, error) {
+	lines := []string{"Bridge status:"}
+
+	_, lastConnected, disconnectedAt, _ := r.client.ConnectionState()
+	switch {
+	case r.client.IsConnected():
+		lines = append(lines, "Connected since "+lastConnected.UTC().Format(time.RFC3339))
+	case !disconnectedAt.IsZero():
+		lines = append(lines, "Disconnected since "+disconnectedAt.UTC().Format(time.RFC3339))
+	default:
+		lines = append(lines, "Not connected")
+	}
+
+	stats := r.outbox.Stats()
+	high, low := stats.Lanes[outbox.PriorityHigh], stats.Lanes[outbox.PriorityLow]
+	lines = append(lines, fmt.Sprintf("Outbox: %d queued (%d high, %d low), %d in flight, %d sent, %d failed",
+		high.Depth+low.Depth, high.Depth, low.Depth, stats.InFlight, high.Sent+low.Sent, high.Failed+low.Failed))
+
+	if paused := r.outbox.LoopBreaker().Paused(); len(paused) > 0 {
+		origins := make([]string, len(paused))
+		for i, p := range paused {
+			origins[i] = p.Origin
+		}
+		lines = append(lines, "Paused automations: "+strings.Join(origins, ", "))
+	}
+	if r.maintenance.Active() {
+		lines = append(lines, "Maintenance mode is on")
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// mute mutes a chat, forever unless a duration is given
+func (r *Router) mute(ctx context.Context, c call) (string, error) {
+	duration := c.args
+	if duration == "" {
+		duration = "forever"
+	}
+	if err := r.client.MuteChat(ctx, c.target, duration); err != nil {
+		return "", err
+	}
+	if duration == "forever" || duration == "0" {
+		return fmt.Sprintf("Muted %s.", c.target), nil
+	}
+	return fmt.Sprintf("Muted %s for %s.", c.target, duration), nil
+}
+
+func (r *Router) unmute(ctx context.Context, c call) (string, error) {
+	if err := r.client.UnmuteChat(ctx, c.target); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Unmuted %s.", c.target), nil
+}
+
+// send queues a message like /api/send would, duplicate check included
+func (r *Router) send(ctx context.Context, c call) (string, error) {
+	if c.args == "" {
+		return "", fmt.Errorf("message text is required")
+	}
+	result, err := r.outbox.Send(ctx, outbox.PriorityHigh, c.target, c.args, "")
+	if err != nil {
+		return "", err
+	}
+	if !result.Success {
+		return "", errors.New(result.Error)
+	}
+	return fmt.Sprintf("Sent to %s (%s).", c.target, result.MessageID), nil
+}
+
+// listApprovals lists the sends waiting for approval, newest first
+func (r *Router) listApprovals(_ context.Context, _ call) (string, error) {
+	if r.approvals == nil {
+		return "", errApprovalsUnavailable
+	}
+	held, err := r.approvals.List(database.ApprovalPending, "", maxListedApprovals)
+	if err != nil {
+		return "", err
+	}
+	if len(held) == 0 {
+		return "No sends are waiting for approval.", nil
+	}
+
+	lines := []string{"Waiting for approval:"}
+	for _, a := range held {
+		preview := a.Request.Message
+		if a.Route != "" && a.Route != approval.SendRoute {
+			preview = a.Route + " " + a.Body
+		}
+		if len(preview) > 60 {
+			preview = strings.ToValidUTF8(preview[:60], "") + "…"
+		}
+		lines = append(lines, fmt.Sprintf("#%d by %s to %s: %s", a.ID, a.Tenant, a.Recipient, preview))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// approve sends a held message
+func (r *Router) approve(ctx context.Context, c call) (string, error) {
+	if r.approvals == nil {
+		return "", errApprovalsUnavailable
+	}
+	id, _, err := approvalID(c.args)
+	if err != nil {
+		return "", err
+	}
+	held, err := r.approvals.Approve(ctx, id, c.sender)
+	if err != nil {
+		return "", err
+	}
+	return approval.DecisionText(id, held, nil), nil
+}
+
+// reject drops a held message, with an optional reason
+func (r *Router) reject(_ context.Context, c call) (string, error) {
+	if r.approvals == nil {
+		return "", errApprovalsUnavailable
+	}
+	id, reason, err := approvalID(c.args)
+	if err != nil {
+		return "", err
+	}
+	held, err := r.approvals.Reject(id, c.sender, reason)
+	if err != nil {
+		return "", err
+	}
+	return approval.DecisionText(id, held, nil), nil
+}
+
+// approvalID reads a held send's ID, with or without its "#", and returns
+// the rest of the line
+func approvalID(args string) (int64, string, error) {
+	first, rest := nextWord(args)
+	id, err := strconv.ParseInt(strings.TrimPrefix(first, "#"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, "", fmt.Errorf("the ID of a held send is required")
+	}
+	return id, rest, nil
+}
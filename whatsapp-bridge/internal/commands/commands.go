@@ -0,0 +1,359 @@
+// Package commands lets configured admins administer the bridge from
+// WhatsApp itself: a direct message to the bridge's number that starts with
+// the command prefix, such as "!status" or "!mute <jid> 8h", is run with the
+// sender's permissions, answered in the same chat and written to the audit
+// log.
+package commands
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	waLog "go.mau.fi/whatsmeow/util/log"
+
+	"whatsapp-bridge/internal/approval"
+	"whatsapp-bridge/internal/automation"
+	"whatsapp-bridge/internal/maintenance"
+	"whatsapp-bridge/internal/outbox"
+	"whatsapp-bridge/internal/phone"
+	"whatsapp-bridge/internal/recovery"
+	"whatsapp-bridge/internal/security"
+	localTypes "whatsapp-bridge/internal/types"
+	"whatsapp-bridge/internal/whatsapp"
+)
+
+const (
+	// DefaultPrefix marks a message as a command when none is configured
+	DefaultPrefix = "!"
+
+	// MaxAdmins caps the configured admins
+	MaxAdmins = 50
+
+	// AllCommands in an admin's command list allows every command
+	AllCommands = "*"
+
+	// runTimeout bounds one command, including its reply
+	runTimeout = 30 * time.Second
+)
+
+// Audit statuses, as used by the security audit log
+const (
+	auditSuccess = "success"
+	auditFailure = "failure"
+	auditBlocked = "blocked"
+)
+
+// command is one action admins can run
+type command struct {
+	usage string // arguments, e.g. "<jid> <text>"
+	help  string
+
+	// target means the first argument is a chat, given as a JID or phone
+	// number, and passed to run separately
+	target bool
+
+	run func(r *Router, ctx context.Context, c call) (string, error)
+}
+
+// call is one invocation of a command
+type call struct {
+	sender string // admin JID
+	target string // chat JID, for commands that take one
+	args   string // the rest of the line
+}
+
+// commands is filled in init, as help refers to it
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"help":   {help: "list the commands you may run", run: (*Router).help},
+		"status": {help: "connection, outbox and automation status", run: (*Router).status},
+		"mute": {usage: "<jid> [15m|1h|8h|1w|forever]", help: "mute a chat (default forever)", target: true,
+			run: (*Router).mute},
+		"unmute": {usage: "<jid>", help: "unmute a chat", target: true, run: (*Router).unmute},
+		"send":   {usage: "<jid> <text>", help: "send a text message", target: true, run: (*Router).send},
+
+		"approvals": {help: "list sends waiting for approval", run: (*Router).listApprovals},
+		"approve":   {usage: "<id>", help: "approve and send a held message", run: (*Router).approve},
+		"reject":    {usage: "<id> [reason]", help: "reject a held message", run: (*Router).reject},
+	}
+}
+
+// Router recognizes commands in incoming messages and runs them
+type Router struct {
+	client      *whatsapp.Client
+	outbox      *outbox.Dispatcher
+	maintenance *maintenance.Responder
+	approvals   *approval.Queue
+	logger      waLog.Logger
+
+	mu     sync.RWMutex
+	config localTypes.CommandConfig
+	admins map[string][]string // sender JID -> allowed commands
+
+	// reply answers a command; replaced in tests
+	reply func(ctx context.Context, chatJID, text string)
+}
+
+// NewRouter creates a router with commands disabled
+func NewRouter(client *whatsapp.Client, dispatcher *outbox.Dispatcher, responder *maintenance.Responder, logger waLog.Logger) *Router {
+	r := &Router{
+		client:      client,
+		outbox:      dispatcher,
+		maintenance: responder,
+		logger:      logger,
+		config:      localTypes.CommandConfig{Prefix: DefaultPrefix, Admins: []localTypes.CommandAdmin{}},
+	}
+	r.reply = r.sendReply
+	return r
+}
+
+// SetApprovals lets admins decide held sends with the approve and reject
+// commands
+func (r *Router) SetApprovals(queue *approval.Queue) {
+	r.approvals = queue
+}
+
+// ValidateConfig checks the prefix, admin JIDs and command names
+func ValidateConfig(cfg localTypes.CommandConfig) error {
+	if strings.TrimSpace(cfg.Prefix) != cfg.Prefix || len(cfg.Prefix) > 3 {
+		return fmt.Errorf("prefix must be at most 3 characters without spaces")
+	}
+	if cfg.Enabled && len(cfg.Admins) == 0 {
+		return fmt.Errorf("at least one admin is required when commands are enabled")
+	}
+	if len(cfg.Admins) > MaxAdmins {
+		return fmt.Errorf("at most %d admins are allowed", MaxAdmins)
+	}
+
+	seen := make(map[string]bool)
+	for i, admin := range cfg.Admins {
+		jid, err := types.ParseJID(admin.JID)
+		if err != nil || admin.JID == "" || (jid.Server != types.DefaultUserServer && jid.Server != types.HiddenUserServer) {
+			return fmt.Errorf("admin %d: jid must be a user JID such as 15551234567@s.whatsapp.net", i)
+		}
+		if seen[admin.JID] {
+			return fmt.Errorf("admin %d: %s is listed more than once", i, admin.JID)
+		}
+		seen[admin.JID] = true
+
+		for _, name := range admin.Commands {
+			if _, ok := commands[name]; !ok && name != AllCommands {
+				return fmt.Errorf("admin %d: unknown command %q", i, name)
+			}
+		}
+	}
+	return nil
+}
+
+// SetConfig validates and applies a new configuration
+func (r *Router) SetConfig(cfg localTypes.CommandConfig) error {
+	if err := ValidateConfig(cfg); err != nil {
+		return err
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = DefaultPrefix
+	}
+	if cfg.Admins == nil {
+		cfg.Admins = []localTypes.CommandAdmin{}
+	}
+
+	admins := make(map[string][]string, len(cfg.Admins))
+	for _, admin := range cfg.Admins {
+		admins[admin.JID] = admin.Commands
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.config = cfg
+	r.admins = admins
+	return nil
+}
+
+// Config returns the current configuration
+func (r *Router) Config() localTypes.CommandConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.config
+}
+
+// HandleMessage runs a command sent by an admin in a direct chat, in the
+// background. It reports whether the message was a command, so the caller
+// can keep it away from webhooks and auto-replies. Messages from anyone else
+// are left alone, without a reply that would reveal the feature.
+func (r *Router) HandleMessage(msg *events.Message) bool {
+	if msg.Info.IsFromMe || msg.Info.IsGroup {
+		return false
+	}
+	if server := msg.Info.Chat.Server; server != types.DefaultUserServer && server != types.HiddenUserServer {
+		return false
+	}
+
+	r.mu.RLock()
+	enabled, prefix := r.config.Enabled, r.config.Prefix
+	r.mu.RUnlock()
+	if !enabled {
+		return false
+	}
+
+	text := strings.TrimSpace(whatsapp.ExtractTextContent(msg.Message))
+	if !strings.HasPrefix(text, prefix) {
+		return false
+	}
+	sender, ok := r.admin(msg.Info.Sender, msg.Info.SenderAlt)
+	if !ok {
+		return false
+	}
+
+	chatJID := msg.Info.Chat.ToNonAD().String()
+	recovery.Go("command from "+sender, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), runTimeout)
+		defer cancel()
+		r.reply(ctx, chatJID, r.Execute(ctx, sender, strings.TrimPrefix(text, prefix)))
+	})
+	return true
+}
+
+// admin returns the configured admin that sent a message. Senders may be
+// addressed by phone number or by LID, so both of a message's addresses are
+// tried.
+func (r *Router) admin(addrs ...types.JID) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, addr := range addrs {
+		if addr.IsEmpty() {
+			continue
+		}
+		jid := addr.ToNonAD().String()
+		if _, ok := r.admins[jid]; ok {
+			return jid, true
+		}
+	}
+	return "", false
+}
+
+// Execute runs a command line, without the prefix, for sender and returns
+// the reply. Every attempt by an admin is audited.
+func (r *Router) Execute(ctx context.Context, sender, line string) string {
+	name, args := nextWord(line)
+	name = strings.ToLower(name)
+
+	cmd, ok := commands[name]
+	if !ok {
+		security.LogChatCommand(sender, name, "", auditFailure, "unknown command")
+		return fmt.Sprintf("Unknown command %q. Send %shelp for the list.", name, r.Config().Prefix)
+	}
+	if !r.allowed(sender, name) {
+		security.LogChatCommand(sender, name, "", auditBlocked, "not permitted")
+		return fmt.Sprintf("You are not allowed to run %s.", name)
+	}
+
+	c := call{sender: sender, args: args}
+	if cmd.target {
+		first, rest := nextWord(args)
+		target, err := parseTarget(first)
+		if err != nil {
+			security.LogChatCommand(sender, name, "", auditFailure, err.Error())
+			return fmt.Sprintf("%v\nUsage: %s%s %s", err, r.Config().Prefix, name, cmd.usage)
+		}
+		c.target, c.args = target, rest
+	}
+
+	reply, err := cmd.run(r, ctx, c)
+	if err != nil {
+		security.LogChatCommand(sender, name, c.target, auditFailure, err.Error())
+		return fmt.Sprintf("%s failed: %v", name, err)
+	}
+	security.LogChatCommand(sender, name, c.target, auditSuccess, "")
+	return reply
+}
+
+// allowed reports whether sender may run the named command
+func (r *Router) allowed(sender, name string) bool {
+	if name == "help" {
+		return true
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, allowed := range r.admins[sender] {
+		if allowed == name || allowed == AllCommands {
+			return true
+		}
+	}
+	return false
+}
+
+// nextWord splits the first word off s. The rest keeps its inner line
+// breaks, so "!send" can carry a multi-line message.
+func nextWord(s string) (word, rest string) {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexFunc(s, unicode.IsSpace); i >= 0 {
+		return s[:i], strings.TrimSpace(s[i:])
+	}
+	return s, ""
+}
+
+// parseTarget reads a chat given as a JID or, for personal chats, a phone
+// number
+func parseTarget(arg string) (string, error) {
+	if arg == "" {
+		return "", fmt.Errorf("a chat is required")
+	}
+	if strings.Contains(arg, "@") {
+		jid, err := types.ParseJID(arg)
+		if err != nil {
+			return "", fmt.Errorf("invalid chat %q: %v", arg, err)
+		}
+		return jid.ToNonAD().String(), nil
+	}
+	number, err := phone.Parse(arg, "")
+	if err != nil {
+		return "", err
+	}
+	return number.JID(), nil
+}
+
+// sendReply answers a command in its chat. Replies are tagged as an
+// automation, so a reply loop with another bot is broken, but skip the
+// duplicate check: asking for the status twice gets two answers.
+func (r *Router) sendReply(ctx context.Context, chatJID, text string) {
+	result, err := r.outbox.SendForced(automation.WithOrigin(ctx, automation.OriginCommands), outbox.PriorityHigh, chatJID, text, "")
+	if err != nil {
+		r.logger.Warnf("Failed to queue command reply to %s: %v", chatJID, err)
+	} else if !result.Success {
+		r.logger.Warnf("Failed to send command reply to %s: %s", chatJID, result.Error)
+	}
+}
+
+// help lists the commands the caller may run
+func (r *Router) help(_ context.Context, c call) (string, error) {
+	prefix := r.Config().Prefix
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := []string{"Commands:"}
+	for _, name := range names {
+		if !r.allowed(c.sender, name) {
+			continue
+		}
+		cmd := commands[name]
+		line := prefix + name
+		if cmd.usage != "" {
+			line += " " + cmd.usage
+		}
+		lines = append(lines, line+" - "+cmd.help)
+	}
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	waLog "go.mau.fi/whatsmeow/util/log"
+	"google.golang.org/protobuf/proto"
+
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+const (
+	owner  = "15550000001@s.whatsapp.net"
+	helper = "15550000002@s.whatsapp.net"
+)
+
+func newTestRouter(t *testing.T) *Router {
+	t.Helper()
+	r := NewRouter(nil, nil, nil, waLog.Noop)
+	err := r.SetConfig(localTypes.CommandConfig{
+		Enabled: true,
+		Admins: []localTypes.CommandAdmin{
+			{JID: owner, Commands: []string{AllCommands}},
+			{JID: helper, Commands: []string{"status"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("SetConfig() error = %v", err)
+	}
+	return r
+}
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     localTypes.CommandConfig
+		wantErr bool
+	}{
+		{"disabled empty", localTypes.CommandConfig{}, false},
+		{"enabled without admins", localTypes.CommandConfig{Enabled: true}, true},
+		{"group admin", localTypes.CommandConfig{Admins: []localTypes.CommandAdmin{{JID: "123@g.us"}}}, true},
+		{"unknown command", localTypes.CommandConfig{Admins: []localTypes.CommandAdmin{{JID: owner, Commands: []string{"reboot"}}}}, true},
+		{"duplicate admin", localTypes.CommandConfig{Admins: []localTypes.CommandAdmin{{JID: owner}, {JID: owner}}}, true},
+		{"long prefix", localTypes.CommandConfig{Prefix: "!!!!"}, true},
+		{"spaced prefix", localTypes.CommandConfig{Prefix: "! "}, true},
+		{"valid", localTypes.CommandConfig{Enabled: true, Prefix: "/", Admins: []localTypes.CommandAdmin{{JID: owner, Commands: []string{"mute", "send"}}}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateConfig(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExecutePermissions(t *testing.T) {
+	r := newTestRouter(t)
+	ctx := context.Background()
+
+	if reply := r.Execute(ctx, helper, "mute 15551234567"); !strings.Contains(reply, "not allowed") {
+		t.Errorf("helper mute reply = %q, want a refusal", reply)
+	}
+	if reply := r.Execute(ctx, owner, "reboot"); !strings.Contains(reply, "Unknown command") {
+		t.Errorf("unknown command reply = %q", reply)
+	}
+	if reply := r.Execute(ctx, owner, "mute"); !strings.Contains(reply, "Usage: !mute") {
+		t.Errorf("mute without a chat reply = %q, want usage", reply)
+	}
+
+	// help lists only what the sender may run
+	reply := r.Execute(ctx, helper, "HELP")
+	if !strings.Contains(reply, "!status") || strings.Contains(reply, "!send") {
+		t.Errorf("helper help = %q, want status but not send", reply)
+	}
+	if reply := r.Execute(ctx, owner, "help"); !strings.Contains(reply, "!send <jid> <text>") {
+		t.Errorf("owner help = %q, want every command", reply)
+	}
+}
+
+func TestNextWord(t *testing.T) {
+	word, rest := nextWord("  send 15551234567\nline one\nline two ")
+	if word != "send" || rest != "15551234567\nline one\nline two" {
+		t.Errorf("nextWord = %q, %q", word, rest)
+	}
+}
+
+func TestApprovalID(t *testing.T) {
+	id, reason, err := approvalID("#12 wrong audience")
+	if err != nil || id != 12 || reason != "wrong audience" {
+		t.Errorf("approvalID = %d, %q, %v", id, reason, err)
+	}
+	if _, _, err := approvalID("twelve"); err == nil {
+		t.Error("approvalID accepted a word")
+	}
+
+	// Without a queue the commands say so rather than panic
+	r := newTestRouter(t)
+	if reply := r.Execute(context.Background(), owner, "approve 12"); !strings.Contains(reply, "not available") {
+		t.Errorf("approve reply = %q", reply)
+	}
+}
+
+func TestHandleMessage(t *testing.T) {
+	r := newTestRouter(t)
+	replies := make(chan string, 1)
+	r.reply = func(ctx context.Context, chatJID, text string) { replies <- chatJID + ": " + text }
+
+	message := func(sender, text string) *events.Message {
+		jid, _ := types.ParseJID(sender)
+		return &events.Message{
+			Info: directMessageInfo(jid),
+			Message: &waE2E.Message{
+				Conversation: proto.String(text),
+			},
+		}
+	}
+
+	if r.HandleMessage(message("15559999999@s.whatsapp.net", "!help")) {
+		t.Error("command from a non-admin was handled")
+	}
+	if r.HandleMessage(message(owner, "hello")) {
+		t.Error("message without the prefix was handled")
+	}
+	if !r.HandleMessage(message(owner, "!help")) {
+		t.Fatal("admin command was not handled")
+	}
+
+	select {
+	case reply := <-replies:
+		if !strings.HasPrefix(reply, owner+": Commands:") {
+			t.Errorf("reply = %q, want the command list in the admin's chat", reply)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no reply")
+	}
+}
+
+// directMessageInfo is a direct message from jid
+func directMessageInfo(jid types.JID) types.MessageInfo {
+	return types.MessageInfo{MessageSource: types.MessageSource{Chat: jid, Sender: jid}}
+}
@@ -3,26 +3,136 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // Config holds application configuration
 type Config struct {
 	APIPort int
 
+	// Where the API listens: APIBind limits the TCP listener to one address
+	// (e.g. 127.0.0.1 behind a local proxy); APISocket replaces it with a Unix
+	// domain socket created with APISocketMode permissions
+	APIBind       string      // API_BIND env var (default all interfaces)
+	APISocket     string      // API_SOCKET env var (path)
+	APISocketMode os.FileMode // API_SOCKET_MODE env var (octal, default 0660)
+
 	// History sync configuration (Phase 4)
 	HistorySyncDaysLimit uint32 // HISTORY_SYNC_DAYS_LIMIT env var
 	HistorySyncSizeMB    uint32 // HISTORY_SYNC_SIZE_MB env var
 	StorageQuotaMB       uint32 // STORAGE_QUOTA_MB env var
+
+	// Receipt policy defaults (overridden by settings stored via /api/settings/receipts)
+	ReadReceipts     bool // READ_RECEIPTS env var
+	DeliveryReceipts bool // DELIVERY_RECEIPTS env var
+
+	// Outgoing messages still pending after this long are marked failed
+	SendAckTimeout time.Duration // SEND_ACK_TIMEOUT env var (seconds)
+
+	// Upper bound on the WhatsApp calls made while serving one API request
+	RequestTimeout time.Duration // REQUEST_TIMEOUT env var (seconds)
+
+	// Time zone for human-facing times; stored and API timestamps are always UTC
+	DisplayTimezone *time.Location // DISPLAY_TIMEZONE env var (IANA name, default UTC)
+
+	// Region national phone numbers are read in when a request gives none;
+	// unset, recipients must be in international form
+	PhoneRegion string // PHONE_REGION env var (ISO 3166-1 alpha-2, e.g. US)
+
+	// Requests per minute per client IP; RateLimitRoutes overrides it by path prefix
+	RateLimit       int            // RATE_LIMIT env var (default 100)
+	RateLimitRoutes map[string]int // RATE_LIMIT_ROUTES env var, e.g. "/api/send=30,/api/selftest=5"
+
+	// Optional Redis server shared by API instances for coordination state
+	RedisURL string // REDIS_URL env var
+
+	// Serve read endpoints from the shared database without a WhatsApp connection
+	ReadReplica bool // READ_REPLICA env var
+
+	// Hot standby: instances sharing the database elect one to connect to WhatsApp
+	LeaderElection bool   // LEADER_ELECTION env var
+	InstanceID     string // INSTANCE_ID env var (defaults to the hostname)
+
+	// Optional OpenAI-compatible speech-to-text endpoint for incoming voice notes
+	TranscriptionURL    string // TRANSCRIPTION_URL env var (e.g. https://api.openai.com/v1/audio/transcriptions)
+	TranscriptionAPIKey string // TRANSCRIPTION_API_KEY env var
+	TranscriptionModel  string // TRANSCRIPTION_MODEL env var (default whisper-1)
+
+	// Optional OpenAI-compatible vision endpoint that reads text from incoming images
+	OCRURL    string // OCR_URL env var (e.g. https://api.openai.com/v1/chat/completions)
+	OCRAPIKey string // OCR_API_KEY env var
+	OCRModel  string // OCR_MODEL env var (default gpt-4o-mini)
+
+	// Incoming media of these types is downloaded under store/media/ as it
+	// arrives, unless larger than MediaAutoDownloadMaxMB (0 means no limit)
+	MediaAutoDownload      []string // MEDIA_AUTO_DOWNLOAD env var, e.g. "image,audio" or "all"
+	MediaAutoDownloadMaxMB uint32   // MEDIA_AUTO_DOWNLOAD_MAX_MB env var (default 16)
+
+	// Convert mp3, m4a and wav media to Ogg Opus with ffmpeg and send them as
+	// voice notes; otherwise they are sent as documents
+	VoiceNoteTranscode bool // VOICE_NOTE_TRANSCODE env var
+
+	// Messages from months at least this old are moved out of the messages
+	// table into monthly archive tables; 0 keeps all history in one table
+	HistoryArchiveMonths uint32 // HISTORY_ARCHIVE_MONTHS env var
+
+	// Remove stored messages when their disappearing timer runs out, as the
+	// phone does; messages kept in the chat stay
+	ExpireDisappearing bool // EXPIRE_DISAPPEARING env var
+
+	// Secret the per-chat calendar feed tokens are signed with. Calendar apps
+	// pass a chat's token (from /api/events/calendar/feed) as ?token= to
+	// subscribe to its ICS feed without the API key; empty disables it.
+	CalendarFeedToken string // CALENDAR_FEED_TOKEN env var
+
+	// Base URL webhooks use to link back to the API, e.g. for media downloads
+	PublicURL string // PUBLIC_URL env var (default http://localhost:{API_PORT})
+
+	// Optional StatsD server the metrics served at /api/metrics are also pushed to
+	StatsDAddr   string // STATSD_ADDR env var (host:port)
+	StatsDPrefix string // STATSD_PREFIX env var, prepended to metric names
+
+	// Serve fault injection endpoints under /api/admin/chaos/; never in production
+	DevMode bool // DEV_MODE env var
+
+	// How often secrets given as secret store references (vault://, awssm://,
+	// file://) are fetched again, so rotated values are picked up
+	SecretsRefresh time.Duration // SECRETS_REFRESH env var (seconds, default 300)
+
+	// How often the WhatsApp ping round trip and event handling lag are
+	// sampled, and the lag above which event_lag_high is raised
+	QualitySampleInterval time.Duration // QUALITY_SAMPLE_INTERVAL env var (seconds, default 30)
+	EventLagThreshold     time.Duration // EVENT_LAG_THRESHOLD_MS env var (default 5000)
+
+	// Free space on the store volume below which media downloads and history
+	// sync ingestion pause and disk_space_low is raised, and how often it is
+	// checked; a threshold of 0 turns the check off
+	DiskFreeThresholdMB uint64        // DISK_FREE_THRESHOLD_MB env var (default 500)
+	DiskCheckInterval   time.Duration // DISK_CHECK_INTERVAL env var (seconds, default 60)
 }
 
 // NewConfig creates a new configuration with default values
 func NewConfig() *Config {
 	cfg := &Config{
-		APIPort: 8080,
+		APIPort:       8080,
+		APISocketMode: 0660,
 		// History sync defaults
-		HistorySyncDaysLimit: 365,   // 1 year default
-		HistorySyncSizeMB:    5000,  // 5GB default
-		StorageQuotaMB:       10240, // 10GB default
+		HistorySyncDaysLimit:   365,   // 1 year default
+		HistorySyncSizeMB:      5000,  // 5GB default
+		StorageQuotaMB:         10240, // 10GB default
+		ReadReceipts:           true,
+		DeliveryReceipts:       true,
+		SendAckTimeout:         2 * time.Minute,
+		RequestTimeout:         30 * time.Second,
+		RateLimit:              100,
+		DisplayTimezone:        time.UTC,
+		MediaAutoDownloadMaxMB: 16,
+		SecretsRefresh:         5 * time.Minute,
+		QualitySampleInterval:  30 * time.Second,
+		EventLagThreshold:      5 * time.Second,
+		DiskFreeThresholdMB:    500,
+		DiskCheckInterval:      time.Minute,
 	}
 
 	// Override with environment variables if set
@@ -32,6 +142,14 @@ func NewConfig() *Config {
 		}
 	}
 
+	cfg.APIBind = os.Getenv("API_BIND")
+	cfg.APISocket = os.Getenv("API_SOCKET")
+	if mode := os.Getenv("API_SOCKET_MODE"); mode != "" {
+		if m, err := strconv.ParseUint(mode, 8, 32); err == nil && m <= 0777 {
+			cfg.APISocketMode = os.FileMode(m)
+		}
+	}
+
 	if days := os.Getenv("HISTORY_SYNC_DAYS_LIMIT"); days != "" {
 		if d, err := strconv.ParseUint(days, 10, 32); err == nil {
 			cfg.HistorySyncDaysLimit = uint32(d)
@@ -50,5 +168,139 @@ func NewConfig() *Config {
 		}
 	}
 
+	if v := os.Getenv("READ_RECEIPTS"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			cfg.ReadReceipts = b
+		}
+	}
+
+	if v := os.Getenv("DELIVERY_RECEIPTS"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			cfg.DeliveryReceipts = b
+		}
+	}
+
+	if v := os.Getenv("SEND_ACK_TIMEOUT"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+			cfg.SendAckTimeout = time.Duration(secs) * time.Second
+		}
+	}
+
+	if v := os.Getenv("REQUEST_TIMEOUT"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+			cfg.RequestTimeout = time.Duration(secs) * time.Second
+		}
+	}
+
+	if v := os.Getenv("DISPLAY_TIMEZONE"); v != "" {
+		if loc, err := time.LoadLocation(v); err == nil {
+			cfg.DisplayTimezone = loc
+		}
+	}
+
+	cfg.PhoneRegion = os.Getenv("PHONE_REGION")
+
+	if v := os.Getenv("RATE_LIMIT"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.RateLimit = n
+		}
+	}
+
+	cfg.RateLimitRoutes = parseRouteLimits(os.Getenv("RATE_LIMIT_ROUTES"))
+
+	cfg.RedisURL = os.Getenv("REDIS_URL")
+	cfg.ReadReplica = os.Getenv("READ_REPLICA") == "true"
+
+	cfg.LeaderElection = os.Getenv("LEADER_ELECTION") == "true"
+	cfg.InstanceID = os.Getenv("INSTANCE_ID")
+	if cfg.InstanceID == "" {
+		cfg.InstanceID, _ = os.Hostname()
+	}
+
+	cfg.TranscriptionURL = os.Getenv("TRANSCRIPTION_URL")
+	cfg.TranscriptionAPIKey = os.Getenv("TRANSCRIPTION_API_KEY")
+	cfg.TranscriptionModel = os.Getenv("TRANSCRIPTION_MODEL")
+
+	cfg.VoiceNoteTranscode = os.Getenv("VOICE_NOTE_TRANSCODE") == "true"
+
+	cfg.OCRURL = os.Getenv("OCR_URL")
+	cfg.OCRAPIKey = os.Getenv("OCR_API_KEY")
+	cfg.OCRModel = os.Getenv("OCR_MODEL")
+
+	for _, t := range strings.Split(os.Getenv("MEDIA_AUTO_DOWNLOAD"), ",") {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			cfg.MediaAutoDownload = append(cfg.MediaAutoDownload, t)
+		}
+	}
+	if v := os.Getenv("MEDIA_AUTO_DOWNLOAD_MAX_MB"); v != "" {
+		if mb, err := strconv.ParseUint(v, 10, 32); err == nil {
+			cfg.MediaAutoDownloadMaxMB = uint32(mb)
+		}
+	}
+
+	if v := os.Getenv("HISTORY_ARCHIVE_MONTHS"); v != "" {
+		if months, err := strconv.ParseUint(v, 10, 32); err == nil {
+			cfg.HistoryArchiveMonths = uint32(months)
+		}
+	}
+
+	cfg.ExpireDisappearing = os.Getenv("EXPIRE_DISAPPEARING") == "true"
+
+	cfg.CalendarFeedToken = os.Getenv("CALENDAR_FEED_TOKEN")
+
+	cfg.PublicURL = os.Getenv("PUBLIC_URL")
+	if cfg.PublicURL == "" {
+		cfg.PublicURL = "http://localhost:" + strconv.Itoa(cfg.APIPort)
+	}
+
+	cfg.StatsDAddr = os.Getenv("STATSD_ADDR")
+	cfg.StatsDPrefix = os.Getenv("STATSD_PREFIX")
+
+	cfg.DevMode = os.Getenv("DEV_MODE") == "true"
+
+	if v := os.Getenv("SECRETS_REFRESH"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+			cfg.SecretsRefresh = time.Duration(secs) * time.Second
+		}
+	}
+
+	if v := os.Getenv("QUALITY_SAMPLE_INTERVAL"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+			cfg.QualitySampleInterval = time.Duration(secs) * time.Second
+		}
+	}
+	if v := os.Getenv("EVENT_LAG_THRESHOLD_MS"); v != "" {
+		if ms, err := strconv.Atoi(v); err == nil && ms > 0 {
+			cfg.EventLagThreshold = time.Duration(ms) * time.Millisecond
+		}
+	}
+
+	if v := os.Getenv("DISK_FREE_THRESHOLD_MB"); v != "" {
+		if mb, err := strconv.ParseUint(v, 10, 64); err == nil {
+			cfg.DiskFreeThresholdMB = mb
+		}
+	}
+	if v := os.Getenv("DISK_CHECK_INTERVAL"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+			cfg.DiskCheckInterval = time.Duration(secs) * time.Second
+		}
+	}
+
 	return cfg
 }
+
+// parseRouteLimits parses comma-separated prefix=limit pairs, skipping
+// malformed ones
+func parseRouteLimits(v string) map[string]int {
+	routes := make(map[string]int)
+	for _, pair := range strings.Split(v, ",") {
+		prefix, limit, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || !strings.HasPrefix(prefix, "/") {
+			continue
+		}
+		if n, err := strconv.Atoi(limit); err == nil && n > 0 {
+			routes[prefix] = n
+		}
+	}
+	return routes
+}
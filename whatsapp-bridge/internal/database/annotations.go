@@ -0,0 +1,92 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"whatsapp-bridge/internal/msgref"
+	"whatsapp-bridge/internal/types"
+)
+
+// StoreAnnotation records text extracted from a message's media, such as a
+// voice note transcript, and indexes it for full-text search, both on its
+// own and with the message for SearchMessages. A message has at most one
+// annotation of each kind; storing another replaces it.
+func (store *MessageStore) StoreAnnotation(chatJID, messageID, kind, text string) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to store annotation: %v", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var id int64
+	err = tx.QueryRow(
+		`INSERT INTO message_annotations (chat_jid, message_id, kind, text, created_at)
+		 VALUES (?, ?, ?, ?, ?)
+		 ON CONFLICT(chat_jid, message_id, kind) DO UPDATE SET text = excluded.text, created_at = excluded.created_at
+		 RETURNING id`,
+		chatJID, messageID, kind, text, time.Now().UTC(),
+	).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("failed to store annotation: %v", err)
+	}
+
+	if _, err := tx.Exec(`INSERT OR REPLACE INTO message_annotations_fts (docid, text) VALUES (?, ?)`, id, text); err != nil {
+		return fmt.Errorf("failed to index annotation: %v", err)
+	}
+	if err := reindexMessage(tx, chatJID, messageID); err != nil {
+		return fmt.Errorf("failed to index annotation: %v", err)
+	}
+	return tx.Commit()
+}
+
+// GetAnnotations returns a message's annotations keyed by kind
+func (store *MessageStore) GetAnnotations(chatJID, messageID string) (map[string]string, error) {
+	rows, err := store.db.Query(
+		`SELECT kind, text FROM message_annotations WHERE chat_jid = ? AND message_id = ?`,
+		chatJID, messageID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query annotations: %v", err)
+	}
+	defer rows.Close()
+
+	annotations := make(map[string]string)
+	for rows.Next() {
+		var kind, text string
+		if err := rows.Scan(&kind, &text); err != nil {
+			return nil, fmt.Errorf("failed to scan annotation: %v", err)
+		}
+		annotations[kind] = text
+	}
+	return annotations, rows.Err()
+}
+
+// SearchAnnotations runs a full-text query (SQLite FTS syntax) over stored
+// annotations, optionally limited to one kind, newest first
+func (store *MessageStore) SearchAnnotations(query, kind string, limit int) ([]types.AnnotationMatch, error) {
+	rows, err := store.db.Query(
+		`SELECT a.chat_jid, a.message_id, a.kind, a.text, snippet(message_annotations_fts, '[', ']', '…'), a.created_at
+		 FROM message_annotations_fts f
+		 JOIN message_annotations a ON a.id = f.docid
+		 WHERE message_annotations_fts MATCH ? AND (? = '' OR a.kind = ?)
+		 ORDER BY a.created_at DESC
+		 LIMIT ?`,
+		query, kind, kind, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search annotations: %v", err)
+	}
+	defer rows.Close()
+
+	matches := []types.AnnotationMatch{}
+	for rows.Next() {
+		var m types.AnnotationMatch
+		if err := rows.Scan(&m.ChatJID, &m.MessageID, &m.Kind, &m.Text, &m.Snippet, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan annotation: %v", err)
+		}
+		m.Ref = msgref.Encode(m.ChatJID, m.MessageID)
+		matches = append(matches, m)
+	}
+	return matches, rows.Err()
+}
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"whatsapp-bridge/internal/types"
+)
+
+func TestAnnotations(t *testing.T) {
+	tempDB := "test_annotations.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+	chat := "123@s.whatsapp.net"
+
+	if err := store.StoreAnnotation(chat, "MSG1", "transcript", "please send the invoice"); err != nil {
+		t.Fatalf("StoreAnnotation: %v", err)
+	}
+	if err := store.StoreAnnotation(chat, "MSG2", "transcript", "call me tomorrow"); err != nil {
+		t.Fatalf("StoreAnnotation: %v", err)
+	}
+
+	matches, err := store.SearchAnnotations("invoice", "", 10)
+	if err != nil {
+		t.Fatalf("SearchAnnotations: %v", err)
+	}
+	if len(matches) != 1 || matches[0].MessageID != "MSG1" || matches[0].Snippet != "please send the [invoice]" || matches[0].Ref == "" {
+		t.Fatalf("Unexpected matches: %+v", matches)
+	}
+
+	// A new transcript replaces the old one in the index too
+	if err := store.StoreAnnotation(chat, "MSG1", "transcript", "please send the receipt"); err != nil {
+		t.Fatalf("StoreAnnotation: %v", err)
+	}
+	if matches, _ := store.SearchAnnotations("invoice", "", 10); len(matches) != 0 {
+		t.Errorf("Expected replaced text to be unindexed, got %+v", matches)
+	}
+	if matches, _ := store.SearchAnnotations("receipt", "ocr", 10); len(matches) != 0 {
+		t.Errorf("Expected kind filter to exclude transcripts, got %+v", matches)
+	}
+
+	annotations, err := store.GetAnnotations(chat, "MSG1")
+	if err != nil {
+		t.Fatalf("GetAnnotations: %v", err)
+	}
+	if len(annotations) != 1 || annotations["transcript"] != "please send the receipt" {
+		t.Errorf("Unexpected annotations: %v", annotations)
+	}
+}
+
+func TestAnnotationsSearchedWithMessages(t *testing.T) {
+	tempDB := "test_annotations_search.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+	chat := "123@s.whatsapp.net"
+	if err := store.StoreChat(chat, "Ana", time.Now()); err != nil {
+		t.Fatalf("Failed to store chat: %v", err)
+	}
+	search := func(query string) int {
+		t.Helper()
+		_, total, err := store.SearchMessages(types.MessageSearch{Query: query})
+		if err != nil {
+			t.Fatalf("SearchMessages(%q): %v", query, err)
+		}
+		return total
+	}
+
+	// A voice note has no text of its own until it is transcribed
+	if err := store.StoreMessage("VOICE", chat, "123", "Ana", "", time.Now(), false, "audio", "", "", nil, nil, nil, 0, nil); err != nil {
+		t.Fatalf("Failed to store message: %v", err)
+	}
+	if err := store.StoreAnnotation(chat, "VOICE", "transcript", "please send the invoice"); err != nil {
+		t.Fatalf("StoreAnnotation: %v", err)
+	}
+	if n := search("invoice"); n != 1 {
+		t.Errorf("transcript found in %d messages, want 1", n)
+	}
+
+	// A caption and the text read from the image are both found
+	if err := store.StoreMessage("PHOTO", chat, "123", "Ana", "my receipt", time.Now(), false, "image", "", "", nil, nil, nil, 0, nil); err != nil {
+		t.Fatalf("Failed to store message: %v", err)
+	}
+	if err := store.StoreAnnotation(chat, "PHOTO", "ocr", "total 42 euros"); err != nil {
+		t.Fatalf("StoreAnnotation: %v", err)
+	}
+	if search("receipt") != 1 || search("euros") != 1 {
+		t.Error("caption or OCR text not found")
+	}
+
+	// Editing the caption or storing the message again keeps the annotation indexed
+	if _, err := store.EditMessage(chat, "PHOTO", "my bill", nil, time.Now()); err != nil {
+		t.Fatalf("EditMessage: %v", err)
+	}
+	if err := store.StoreMessage("VOICE", chat, "123", "Ana", "", time.Now(), false, "audio", "", "", nil, nil, nil, 0, nil); err != nil {
+		t.Fatalf("Failed to store message: %v", err)
+	}
+	if search("receipt") != 0 || search("bill") != 1 || search("euros") != 1 || search("invoice") != 1 {
+		t.Error("index out of step after an edit or a second store")
+	}
+}
@@ -0,0 +1,191 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"whatsapp-bridge/internal/tenant"
+	"whatsapp-bridge/internal/types"
+)
+
+// Pending approval statuses. approved is held only while the send runs.
+const (
+	ApprovalPending  = "pending"
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
+	ApprovalExpired  = "expired"
+	ApprovalSent     = "sent"
+	ApprovalFailed   = "failed"
+)
+
+// CreatePendingApproval parks a send for approval and sets its ID and status
+func (store *MessageStore) CreatePendingApproval(a *types.PendingApproval) error {
+	request, err := json.Marshal(a.Request)
+	if err != nil {
+		return fmt.Errorf("failed to encode send request: %v", err)
+	}
+
+	a.Tenant = tenant.Owner(a.Tenant)
+	if a.Route == "" {
+		a.Route = "/api/send"
+	}
+	a.Status = ApprovalPending
+	a.CreatedAt = a.CreatedAt.UTC()
+	a.ExpiresAt = a.ExpiresAt.UTC()
+
+	result, err := store.db.Exec(
+		`INSERT INTO pending_approvals (tenant, recipient, route, request, body, status, created_at, expires_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		a.Tenant, a.Recipient, a.Route, string(request), a.Body, a.Status, a.CreatedAt, a.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store pending approval: %v", err)
+	}
+	a.ID, err = result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get pending approval ID: %v", err)
+	}
+	return nil
+}
+
+// GetPendingApproval retrieves a held send by ID (nil if not found)
+func (store *MessageStore) GetPendingApproval(id int64) (*types.PendingApproval, error) {
+	rows, err := store.db.Query(pendingApprovalColumns+` WHERE id = ?`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending approval: %v", err)
+	}
+	approvals, err := scanPendingApprovals(rows)
+	if err != nil || len(approvals) == 0 {
+		return nil, err
+	}
+	return &approvals[0], nil
+}
+
+// ListPendingApprovals returns held sends, newest first. An empty status or
+// owner does not filter.
+func (store *MessageStore) ListPendingApprovals(status, owner string, limit int) ([]types.PendingApproval, error) {
+	query := pendingApprovalColumns + ` WHERE 1 = 1`
+	var args []interface{}
+	if status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+	if owner != "" {
+		query += " AND tenant = ?"
+		args = append(args, owner)
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := store.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending approvals: %v", err)
+	}
+	return scanPendingApprovals(rows)
+}
+
+// DecidePendingApproval moves a send still pending, and not yet expired at
+// now, to status (approved or rejected). Returns false when it was already
+// decided, has expired or does not exist, so two approvers deciding at once
+// cannot both run it.
+func (store *MessageStore) DecidePendingApproval(id int64, status, decidedBy, reason string, now time.Time) (bool, error) {
+	result, err := store.db.Exec(
+		`UPDATE pending_approvals SET status = ?, decided_by = ?, decided_at = ?, reason = ?
+		 WHERE id = ? AND status = ? AND expires_at > ?`,
+		status, decidedBy, now.UTC(), reason, id, ApprovalPending, now.UTC(),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to decide pending approval: %v", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %v", err)
+	}
+	return rows > 0, nil
+}
+
+// FinishPendingApproval records the outcome of an approved send
+func (store *MessageStore) FinishPendingApproval(id int64, status, messageID, errMsg string) error {
+	_, err := store.db.Exec(
+		`UPDATE pending_approvals SET status = ?, message_id = ?, error = ? WHERE id = ? AND status = ?`,
+		status, messageID, errMsg, id, ApprovalApproved,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to finish pending approval: %v", err)
+	}
+	return nil
+}
+
+// ExpirePendingApprovals marks sends nobody decided on by now as expired
+// and returns how many there were
+func (store *MessageStore) ExpirePendingApprovals(now time.Time) (int64, error) {
+	result, err := store.db.Exec(
+		`UPDATE pending_approvals SET status = ? WHERE status = ? AND expires_at <= ?`,
+		ApprovalExpired, ApprovalPending, now.UTC(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire pending approvals: %v", err)
+	}
+	return result.RowsAffected()
+}
+
+// AddApprovalNotification remembers the message that asked an approver about
+// a held send, so a reaction to it can decide the send
+func (store *MessageStore) AddApprovalNotification(messageID string, approvalID int64) error {
+	_, err := store.db.Exec(
+		`INSERT OR REPLACE INTO approval_notifications (message_id, approval_id) VALUES (?, ?)`,
+		messageID, approvalID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store approval notification: %v", err)
+	}
+	return nil
+}
+
+// GetApprovalForNotification returns the held send an approval request
+// message was about; ok is false for any other message
+func (store *MessageStore) GetApprovalForNotification(messageID string) (id int64, ok bool, err error) {
+	err = store.db.QueryRow(
+		`SELECT approval_id FROM approval_notifications WHERE message_id = ?`, messageID,
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get approval notification: %v", err)
+	}
+	return id, true, nil
+}
+
+const pendingApprovalColumns = `SELECT id, tenant, recipient, route, request, body, status, created_at, expires_at,
+	 decided_by, decided_at, reason, message_id, error FROM pending_approvals`
+
+func scanPendingApprovals(rows *sql.Rows) ([]types.PendingApproval, error) {
+	defer rows.Close()
+
+	approvals := []types.PendingApproval{}
+	for rows.Next() {
+		var a types.PendingApproval
+		var request string
+		var decidedAt sql.NullTime
+		if err := rows.Scan(&a.ID, &a.Tenant, &a.Recipient, &a.Route, &request, &a.Body, &a.Status, &a.CreatedAt, &a.ExpiresAt,
+			&a.DecidedBy, &decidedAt, &a.Reason, &a.MessageID, &a.Error); err != nil {
+			return nil, fmt.Errorf("failed to scan pending approval: %v", err)
+		}
+		if err := json.Unmarshal([]byte(request), &a.Request); err != nil {
+			return nil, fmt.Errorf("failed to decode send request of approval %d: %v", a.ID, err)
+		}
+		if decidedAt.Valid {
+			t := decidedAt.Time
+			a.DecidedAt = &t
+		}
+		approvals = append(approvals, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read pending approvals: %v", err)
+	}
+	return approvals, nil
+}
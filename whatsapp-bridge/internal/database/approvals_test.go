@@ -0,0 +1,100 @@
+package database
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"whatsapp-bridge/internal/types"
+)
+
+func TestPendingApprovals(t *testing.T) {
+	tempDB := "test_approvals.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+	now := time.Now().UTC()
+
+	held := types.PendingApproval{
+		Tenant:    "marketing",
+		Recipient: "15550102030",
+		Request:   types.SendMessageRequest{Recipient: "15550102030", Message: "Sale starts today", Priority: "low"},
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Hour),
+	}
+	if err := store.CreatePendingApproval(&held); err != nil || held.ID == 0 || held.Status != ApprovalPending {
+		t.Fatalf("CreatePendingApproval = %+v, %v", held, err)
+	}
+	stale := types.PendingApproval{Tenant: "marketing", Recipient: "15550102031", Route: "/api/poll", Body: `{"chat_jid":"15550102031","question":"Lunch?"}`,
+		Request: types.SendMessageRequest{Recipient: "15550102031"}, CreatedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Hour)}
+	if err := store.CreatePendingApproval(&stale); err != nil {
+		t.Fatalf("CreatePendingApproval: %v", err)
+	}
+
+	got, err := store.GetPendingApproval(held.ID)
+	if err != nil || got == nil || got.Request.Message != "Sale starts today" || got.Request.Priority != "low" || got.Route != "/api/send" || got.DecidedAt != nil {
+		t.Fatalf("GetPendingApproval = %+v, %v", got, err)
+	}
+	if got, err := store.GetPendingApproval(stale.ID); err != nil || got.Route != "/api/poll" || got.Body != stale.Body {
+		t.Errorf("held poll = %+v, %v", got, err)
+	}
+	if missing, err := store.GetPendingApproval(999); err != nil || missing != nil {
+		t.Errorf("GetPendingApproval(999) = %+v, %v", missing, err)
+	}
+
+	// An expired send cannot be approved, and is marked expired by the sweep
+	if ok, err := store.DecidePendingApproval(stale.ID, ApprovalApproved, "approver", "", now); err != nil || ok {
+		t.Errorf("approving an expired send = %v, %v", ok, err)
+	}
+	if n, err := store.ExpirePendingApprovals(now); err != nil || n != 1 {
+		t.Errorf("ExpirePendingApprovals = %d, %v", n, err)
+	}
+
+	// Only the first decision counts
+	if ok, err := store.DecidePendingApproval(held.ID, ApprovalApproved, "15550000001@s.whatsapp.net", "", now); err != nil || !ok {
+		t.Fatalf("DecidePendingApproval = %v, %v", ok, err)
+	}
+	if ok, err := store.DecidePendingApproval(held.ID, ApprovalRejected, "api:default", "no", now); err != nil || ok {
+		t.Errorf("second decision = %v, %v", ok, err)
+	}
+	if err := store.FinishPendingApproval(held.ID, ApprovalSent, "3EB0FF", ""); err != nil {
+		t.Fatalf("FinishPendingApproval: %v", err)
+	}
+
+	got, _ = store.GetPendingApproval(held.ID)
+	if got.Status != ApprovalSent || got.MessageID != "3EB0FF" || got.DecidedBy != "15550000001@s.whatsapp.net" || got.DecidedAt == nil {
+		t.Errorf("finished approval = %+v", got)
+	}
+
+	list, err := store.ListPendingApprovals("", "marketing", 10)
+	if err != nil || len(list) != 2 || list[0].ID != stale.ID || list[0].Status != ApprovalExpired {
+		t.Errorf("ListPendingApprovals = %+v, %v", list, err)
+	}
+	if list, _ := store.ListPendingApprovals(ApprovalPending, "", 10); len(list) != 0 {
+		t.Errorf("pending = %+v", list)
+	}
+	if list, _ := store.ListPendingApprovals("", "support", 10); len(list) != 0 {
+		t.Errorf("another tenant's approvals = %+v", list)
+	}
+
+	if err := store.AddApprovalNotification("3EB0AA", held.ID); err != nil {
+		t.Fatalf("AddApprovalNotification: %v", err)
+	}
+	if id, ok, err := store.GetApprovalForNotification("3EB0AA"); err != nil || !ok || id != held.ID {
+		t.Errorf("GetApprovalForNotification = %d, %v, %v", id, ok, err)
+	}
+	if _, ok, err := store.GetApprovalForNotification("3EB0AB"); err != nil || ok {
+		t.Errorf("unknown notification = %v, %v", ok, err)
+	}
+}
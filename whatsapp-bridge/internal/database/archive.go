@@ -0,0 +1,238 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"whatsapp-bridge/internal/types"
+)
+
+// archiveTablePrefix names the monthly archive tables, e.g.
+// messages_archive_202401 for January 2024
+const archiveTablePrefix = "messages_archive_"
+
+// archiveBatch is how many messages one archiving transaction moves, so
+// archiving a busy month does not hold the write lock for long
+const archiveBatch = 500
+
+// notKept selects messages not kept from cleanup, see SetMessageKept
+const notKept = ` AND NOT EXISTS (SELECT 1 FROM kept_messages k WHERE k.chat_jid = messages.chat_jid AND k.message_id = messages.id)`
+
+// notExpiring selects messages without a disappearing timer still to run
+// out, see SetMessageExpiry; those are left for DeleteExpiredMessages
+const notExpiring = ` AND NOT EXISTS (SELECT 1 FROM disappearing_messages d WHERE d.chat_jid = messages.chat_jid AND d.message_id = messages.id)`
+
+// archivedColumns are the messages columns copied into an archive table
+const archivedColumns = `id, chat_jid, sender, sender_name, content, timestamp, is_from_me, media_type, filename, url,
+	media_key, file_sha256, file_enc_sha256, file_length, metadata_only, context, local_path`
+
+// archivePartition is one month of archived messages
+type archivePartition struct {
+	table string
+	start time.Time // first instant of the month, UTC
+}
+
+func (p archivePartition) end() time.Time {
+	return p.start.AddDate(0, 1, 0)
+}
+
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func archiveTable(month time.Time) string {
+	return archiveTablePrefix + month.Format("200601")
+}
+
+// createArchiveTable creates the archive table for a month, shaped like
+// messages but without its full-text index
+func createArchiveTable(tx *sql.Tx, table string) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS ` + table + ` (
+			id TEXT,
+			chat_jid TEXT,
+			sender TEXT,
+			sender_name TEXT,
+			content TEXT,
+			timestamp TIMESTAMP,
+			is_from_me BOOLEAN,
+			media_type TEXT,
+			filename TEXT,
+			url TEXT,
+			media_key BLOB,
+			file_sha256 BLOB,
+			file_enc_sha256 BLOB,
+			file_length INTEGER,
+			metadata_only BOOLEAN NOT NULL DEFAULT 0,
+			context TEXT,
+			local_path TEXT,
+			PRIMARY KEY (id, chat_jid)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_` + table + `_chat ON ` + table + `(chat_jid, timestamp);
+	`)
+	return err
+}
+
+// archivePartitions lists the monthly archive tables, oldest first
+func (store *MessageStore) archivePartitions() ([]archivePartition, error) {
+	rows, err := store.db.Query(
+		`SELECT name FROM sqlite_master WHERE type = 'table' AND name GLOB ? ORDER BY name`,
+		archiveTablePrefix+"[0-9][0-9][0-9][0-9][0-9][0-9]",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list archive tables: %v", err)
+	}
+	defer rows.Close()
+
+	var partitions []archivePartition
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return nil, fmt.Errorf("failed to list archive tables: %v", err)
+		}
+		start, err := time.Parse("200601", strings.TrimPrefix(table, archiveTablePrefix))
+		if err != nil {
+			continue
+		}
+		partitions = append(partitions, archivePartition{table: table, start: start})
+	}
+	return partitions, rows.Err()
+}
+
+// ArchiveMessages moves messages from before the month holding cutoff out of
+// the messages table into monthly archive tables, keeping the messages
+// table, its indexes and the full-text index to recent history. Kept
+// messages, and disappearing messages yet to expire, stay where they are. Archived messages are listed only by
+// queries with IncludeArchive set, but looked up one by one as before, with
+// their annotations, reactions, edits and pins, which stay keyed by chat
+// and message ID. It returns how many messages were moved.
+func (store *MessageStore) ArchiveMessages(cutoff time.Time) (int, error) {
+	cutoff = monthStart(cutoff)
+	moved := 0
+	for {
+		var oldest time.Time
+		err := store.db.QueryRow(`SELECT timestamp FROM messages WHERE timestamp < ?`+notKept+notExpiring+` ORDER BY timestamp LIMIT 1`, cutoff).Scan(&oldest)
+		if err == sql.ErrNoRows {
+			return moved, nil
+		}
+		if err != nil {
+			return moved, fmt.Errorf("failed to find messages to archive: %v", err)
+		}
+
+		n, err := store.archiveMonth(monthStart(oldest))
+		if err != nil {
+			return moved, err
+		}
+		moved += n
+	}
+}
+
+// archiveMonth moves one month of messages into its archive table, a batch
+// per transaction
+func (store *MessageStore) archiveMonth(month time.Time) (int, error) {
+	moved := 0
+	for {
+		n, err := store.archiveBatch(month)
+		moved += n
+		if err != nil || n < archiveBatch {
+			return moved, err
+		}
+	}
+}
+
+// archiveBatch moves up to archiveBatch messages of a month into its
+// archive table
+func (store *MessageStore) archiveBatch(month time.Time) (int, error) {
+	table := archiveTable(month)
+	end := month.AddDate(0, 1, 0)
+
+	tx, err := store.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to archive %s: %v", table, err)
+	}
+	defer tx.Rollback()
+
+	if err := createArchiveTable(tx, table); err != nil {
+		return 0, fmt.Errorf("failed to create %s: %v", table, err)
+	}
+	batch := `SELECT rowid FROM messages WHERE timestamp >= ? AND timestamp < ?` + notKept + notExpiring + ` ORDER BY rowid LIMIT ?`
+	// A message stored again after it was archived, e.g. by a history sync,
+	// replaces the archived copy
+	_, err = tx.Exec(
+		`INSERT OR REPLACE INTO `+table+` (`+archivedColumns+`)
+		 SELECT `+archivedColumns+` FROM messages WHERE rowid IN (`+batch+`)`,
+		month, end, archiveBatch,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to copy messages into %s: %v", table, err)
+	}
+	res, err := tx.Exec(`DELETE FROM messages WHERE rowid IN (`+batch+`)`, month, end, archiveBatch)
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove archived messages: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to archive %s: %v", table, err)
+	}
+
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// archivedMessageTable returns the archive table holding a message, or ""
+// when none does
+func (store *MessageStore) archivedMessageTable(chatJID, id string) (string, error) {
+	partitions, err := store.archivePartitions()
+	if err != nil {
+		return "", err
+	}
+	for i := len(partitions) - 1; i >= 0; i-- {
+		var found bool
+		err := store.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM `+partitions[i].table+` WHERE chat_jid = ? AND id = ?)`, chatJID, id).Scan(&found)
+		if err != nil {
+			return "", fmt.Errorf("failed to look up archived message: %v", err)
+		}
+		if found {
+			return partitions[i].table, nil
+		}
+	}
+	return "", nil
+}
+
+// messageSources returns the tables q reads: messages, plus the archive
+// tables overlapping q's time range when q includes the archive
+func (store *MessageStore) messageSources(q types.MessageQuery) ([]string, error) {
+	sources := []string{"messages"}
+	// Sequences are those of the live table; archived messages are older
+	// than any it holds
+	if !q.IncludeArchive || q.AfterSeq > 0 {
+		return sources, nil
+	}
+
+	partitions, err := store.archivePartitions()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range partitions {
+		if !q.Since.IsZero() && !p.end().After(q.Since) {
+			continue
+		}
+		if !q.Until.IsZero() && !p.start.Before(q.Until) {
+			continue
+		}
+		sources = append(sources, p.table)
+	}
+	return sources, nil
+}
+
+// fromSource reads a source table under the name messages, so column
+// references such as those in storedMessageColumns work for any of them
+func fromSource(table string) string {
+	if table == "messages" {
+		return " FROM messages"
+	}
+	return " FROM " + table + " AS messages"
+}
@@ -0,0 +1,160 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"whatsapp-bridge/internal/types"
+)
+
+func TestArchiveMessages(t *testing.T) {
+	tempDB := "test_archive.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+	chat := "15550000001@s.whatsapp.net"
+	if err := store.StoreChat(chat, "Ana", time.Now()); err != nil {
+		t.Fatalf("Failed to store chat: %v", err)
+	}
+	for id, at := range map[string]time.Time{
+		"JAN": time.Date(2024, 1, 20, 9, 0, 0, 0, time.UTC),
+		"FEB": time.Date(2024, 2, 29, 23, 59, 0, 0, time.UTC),
+		"MAR": time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+	} {
+		if err := store.StoreMessage(id, chat, "15550000001", "Ana", "invoice "+id, at, false, "", "", "", nil, nil, nil, 0, nil); err != nil {
+			t.Fatalf("Failed to store message: %v", err)
+		}
+	}
+
+	// Everything before March moves out, a month at a time
+	moved, err := store.ArchiveMessages(time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC))
+	if err != nil || moved != 2 {
+		t.Fatalf("ArchiveMessages = %d, %v, want 2 moved", moved, err)
+	}
+	partitions, err := store.archivePartitions()
+	if err != nil || len(partitions) != 2 || partitions[0].table != "messages_archive_202401" || partitions[1].table != "messages_archive_202402" {
+		t.Fatalf("archivePartitions = %+v, %v", partitions, err)
+	}
+
+	// Hot queries and search only see March
+	hot, err := store.QueryMessages(types.MessageQuery{ChatJID: chat})
+	if err != nil || len(hot) != 1 || hot[0].ID != "MAR" {
+		t.Errorf("QueryMessages = %+v, %v, want only MAR", hot, err)
+	}
+	if _, total, err := store.SearchMessages(types.MessageSearch{Query: "invoice"}); err != nil || total != 1 {
+		t.Errorf("SearchMessages total = %d, %v, want 1", total, err)
+	}
+
+	all, err := store.QueryMessages(types.MessageQuery{ChatJID: chat, IncludeArchive: true})
+	if err != nil || len(all) != 3 || all[0].ID != "MAR" || all[2].ID != "JAN" {
+		t.Errorf("QueryMessages(archive) = %+v, %v, want MAR, FEB, JAN", all, err)
+	}
+	if count, err := store.CountMessages(types.MessageQuery{IncludeArchive: true}); err != nil || count != 3 {
+		t.Errorf("CountMessages(archive) = %d, %v, want 3", count, err)
+	}
+
+	// A range only reads the months it overlaps
+	q := types.MessageQuery{
+		Since:          time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		Until:          time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		IncludeArchive: true,
+	}
+	if sources, err := store.messageSources(q); err != nil || len(sources) != 2 || sources[1] != "messages_archive_202402" {
+		t.Errorf("messageSources = %v, %v, want messages and February", sources, err)
+	}
+	feb, err := store.QueryMessages(q)
+	if err != nil || len(feb) != 1 || feb[0].ID != "FEB" {
+		t.Errorf("QueryMessages(February) = %+v, %v, want FEB", feb, err)
+	}
+
+	// A message synced again after archiving replaces the archived copy
+	if err := store.StoreMessage("JAN", chat, "15550000001", "Ana", "invoice JAN v2", time.Date(2024, 1, 20, 9, 0, 0, 0, time.UTC), false, "", "", "", nil, nil, nil, 0, nil); err != nil {
+		t.Fatalf("Failed to store message: %v", err)
+	}
+	if moved, err := store.ArchiveMessages(time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)); err != nil || moved != 1 {
+		t.Fatalf("ArchiveMessages(again) = %d, %v, want 1 moved", moved, err)
+	}
+	all, err = store.QueryMessages(types.MessageQuery{IncludeArchive: true})
+	if err != nil || len(all) != 3 || all[2].Content != "invoice JAN v2" {
+		t.Errorf("QueryMessages(archive) = %+v, %v, want the synced copy of JAN", all, err)
+	}
+}
+
+func TestArchiveKeepsLookups(t *testing.T) {
+	tempDB := "test_archive_lookups.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+	chat := "15550000001@s.whatsapp.net"
+	if err := store.StoreChat(chat, "Ana", time.Now()); err != nil {
+		t.Fatalf("Failed to store chat: %v", err)
+	}
+
+	// More than a batch in one month
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < archiveBatch+20; i++ {
+		if err := store.StoreMessage(fmt.Sprintf("M%d", i), chat, "15550000001", "Ana", fmt.Sprintf("note %d", i), jan.Add(time.Duration(i)*time.Minute), false, "", "", "", nil, nil, nil, 0, nil); err != nil {
+			t.Fatalf("Failed to store message: %v", err)
+		}
+	}
+	if err := store.StoreMessage("PHOTO", chat, "15550000001", "Ana", "", jan.Add(time.Hour*24), false, "image", "a.jpg", "https://mmg.whatsapp.net/a", []byte{1}, []byte{2}, []byte{3}, 10, nil); err != nil {
+		t.Fatalf("Failed to store message: %v", err)
+	}
+	if err := store.SetMessageKept(chat, "M3", "support", time.Now(), true); err != nil {
+		t.Fatalf("SetMessageKept: %v", err)
+	}
+	if err := store.StorePinnedMessage(&types.PinnedMessage{ChatJID: chat, MessageID: "M7", PinnedBy: chat, PinnedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatalf("StorePinnedMessage: %v", err)
+	}
+
+	moved, err := store.ArchiveMessages(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil || moved != archiveBatch+20 {
+		t.Fatalf("ArchiveMessages = %d, %v, want %d moved", moved, err, archiveBatch+20)
+	}
+
+	// The kept message stays in the hot table
+	hot, err := store.QueryMessages(types.MessageQuery{ChatJID: chat})
+	if err != nil || len(hot) != 1 || hot[0].ID != "M3" || !hot[0].Kept {
+		t.Errorf("hot messages = %+v, %v, want only the kept M3", hot, err)
+	}
+
+	// Archived messages are still found one by one
+	if msg, err := store.GetMessage(chat, "M9"); err != nil || msg == nil || msg.Content != "note 9" {
+		t.Errorf("GetMessage(archived) = %+v, %v", msg, err)
+	}
+	if msg, err := store.GetMessage(chat, "NOPE"); err != nil || msg != nil {
+		t.Errorf("GetMessage(unknown) = %+v, %v", msg, err)
+	}
+	if err := store.SetMediaPath(chat, "PHOTO", "/store/a.jpg"); err != nil {
+		t.Fatalf("SetMediaPath(archived): %v", err)
+	}
+	if media, err := store.GetMessageMedia(chat, "PHOTO"); err != nil || media == nil || media.URL != "https://mmg.whatsapp.net/a" || media.LocalPath != "/store/a.jpg" {
+		t.Errorf("GetMessageMedia(archived) = %+v, %v", media, err)
+	}
+	if pins, err := store.GetPinnedMessages(chat); err != nil || len(pins) != 1 || pins[0].Content != "note 7" {
+		t.Errorf("GetPinnedMessages = %+v, %v, want the archived text", pins, err)
+	}
+}
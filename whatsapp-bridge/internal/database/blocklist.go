@@ -0,0 +1,211 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"whatsapp-bridge/internal/types"
+)
+
+// Blocklist change actions
+const (
+	BlocklistBlock   = "block"
+	BlocklistUnblock = "unblock"
+)
+
+// ApplyBlocklistChange blocks or unblocks a contact in the local mirror and
+// records the change. Returns false, recording nothing, when the mirror
+// already matched.
+func (store *MessageStore) ApplyBlocklistChange(jid, action string, at time.Time) (bool, error) {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	changed, err := applyBlocklistChange(tx, jid, action, at.UTC())
+	if err != nil || !changed {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit blocklist change: %v", err)
+	}
+	return true, nil
+}
+
+// SeedBlocklist makes the local mirror match the full blocklist the first
+// time it is fetched, without recording changes, so blocks made before the
+// bridge was linked are not reported as new. It reports whether it seeded;
+// once seeded it does nothing, and ReplaceBlocklist reports changes.
+func (store *MessageStore) SeedBlocklist(jids []string, at time.Time) (bool, error) {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var seeded int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM bridge_settings WHERE key = ?`, SettingBlocklistSeeded).Scan(&seeded); err != nil {
+		return false, fmt.Errorf("failed to check blocklist seed: %v", err)
+	}
+	if seeded > 0 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(`DELETE FROM blocked_contacts`); err != nil {
+		return false, fmt.Errorf("failed to clear blocked contacts: %v", err)
+	}
+	for _, jid := range jids {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO blocked_contacts (jid, blocked_at) VALUES (?, ?)`, jid, at.UTC()); err != nil {
+			return false, fmt.Errorf("failed to store blocked contact: %v", err)
+		}
+	}
+	if _, err := tx.Exec(
+		`INSERT INTO bridge_settings (key, value, updated_at) VALUES (?, ?, CURRENT_TIMESTAMP)`,
+		SettingBlocklistSeeded, at.UTC().Format(time.RFC3339),
+	); err != nil {
+		return false, fmt.Errorf("failed to record blocklist seed: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit blocklist: %v", err)
+	}
+	return true, nil
+}
+
+// ReplaceBlocklist makes the local mirror match the full blocklist and returns
+// the changes that took, each recorded. Seed the mirror with SeedBlocklist
+// first, so existing blocks are not reported as new.
+func (store *MessageStore) ReplaceBlocklist(jids []string, at time.Time) ([]types.BlocklistChange, error) {
+	at = at.UTC()
+
+	tx, err := store.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	current := make(map[string]bool)
+	rows, err := tx.Query(`SELECT jid FROM blocked_contacts`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query blocked contacts: %v", err)
+	}
+	for rows.Next() {
+		var jid string
+		if err := rows.Scan(&jid); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan blocked contact: %v", err)
+		}
+		current[jid] = true
+	}
+	rows.Close()
+
+	want := make(map[string]bool, len(jids))
+	var changes []types.BlocklistChange
+	for _, jid := range jids {
+		want[jid] = true
+		if !current[jid] {
+			changes = append(changes, types.BlocklistChange{JID: jid, Action: BlocklistBlock, ChangedAt: at})
+		}
+	}
+	for jid := range current {
+		if !want[jid] {
+			changes = append(changes, types.BlocklistChange{JID: jid, Action: BlocklistUnblock, ChangedAt: at})
+		}
+	}
+
+	for _, change := range changes {
+		if _, err := applyBlocklistChange(tx, change.JID, change.Action, at); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit blocklist: %v", err)
+	}
+	return changes, nil
+}
+
+func applyBlocklistChange(tx *sql.Tx, jid, action string, at time.Time) (bool, error) {
+	var result sql.Result
+	var err error
+	switch action {
+	case BlocklistBlock:
+		result, err = tx.Exec(`INSERT OR IGNORE INTO blocked_contacts (jid, blocked_at) VALUES (?, ?)`, jid, at)
+	case BlocklistUnblock:
+		result, err = tx.Exec(`DELETE FROM blocked_contacts WHERE jid = ?`, jid)
+	default:
+		return false, fmt.Errorf("invalid blocklist action: %s", action)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to update blocked contacts: %v", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %v", err)
+	}
+	if rows == 0 {
+		return false, nil
+	}
+
+	_, err = tx.Exec(`INSERT INTO blocklist_changes (jid, action, changed_at) VALUES (?, ?, ?)`, jid, action, at)
+	if err != nil {
+		return false, fmt.Errorf("failed to record blocklist change: %v", err)
+	}
+	return true, nil
+}
+
+// IsBlocked reports whether a contact is on the local blocklist mirror
+func (store *MessageStore) IsBlocked(jid string) (bool, error) {
+	var n int
+	err := store.db.QueryRow(`SELECT COUNT(*) FROM blocked_contacts WHERE jid = ?`, jid).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("failed to check blocklist: %v", err)
+	}
+	return n > 0, nil
+}
+
+// GetBlockedContacts returns the local blocklist mirror, most recent first
+func (store *MessageStore) GetBlockedContacts() ([]types.BlockedContact, error) {
+	rows, err := store.db.Query(`SELECT jid, blocked_at FROM blocked_contacts ORDER BY blocked_at DESC, jid`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query blocked contacts: %v", err)
+	}
+	defer rows.Close()
+
+	contacts := []types.BlockedContact{}
+	for rows.Next() {
+		var c types.BlockedContact
+		if err := rows.Scan(&c.JID, &c.BlockedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan blocked contact: %v", err)
+		}
+		contacts = append(contacts, c)
+	}
+	return contacts, rows.Err()
+}
+
+// GetBlocklistChanges returns up to limit blocklist changes made since the
+// given time, most recent first
+func (store *MessageStore) GetBlocklistChanges(since time.Time, limit int) ([]types.BlocklistChange, error) {
+	rows, err := store.db.Query(
+		`SELECT jid, action, changed_at FROM blocklist_changes
+		 WHERE changed_at >= ? ORDER BY changed_at DESC, id DESC LIMIT ?`,
+		since.UTC(), limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query blocklist changes: %v", err)
+	}
+	defer rows.Close()
+
+	changes := []types.BlocklistChange{}
+	for rows.Next() {
+		var c types.BlocklistChange
+		if err := rows.Scan(&c.JID, &c.Action, &c.ChangedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan blocklist change: %v", err)
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
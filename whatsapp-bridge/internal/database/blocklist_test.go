@@ -0,0 +1,102 @@
+package database
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestBlocklist(t *testing.T) {
+	tempDB := "test_blocklist.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+	now := time.Now()
+
+	// The first full sync seeds the mirror without recording changes
+	seeded, err := store.SeedBlocklist([]string{"1@s.whatsapp.net", "2@s.whatsapp.net"}, now)
+	if err != nil || !seeded {
+		t.Fatalf("SeedBlocklist = %v, %v", seeded, err)
+	}
+	if blocked, _ := store.IsBlocked("2@s.whatsapp.net"); !blocked {
+		t.Error("seeded contact is not blocked")
+	}
+	if seeded, err := store.SeedBlocklist(nil, now); err != nil || seeded {
+		t.Errorf("SeedBlocklist again = %v, %v", seeded, err)
+	}
+
+	changed, err := store.ApplyBlocklistChange("3@s.whatsapp.net", BlocklistBlock, now)
+	if err != nil || !changed {
+		t.Fatalf("ApplyBlocklistChange(block) = %v, %v", changed, err)
+	}
+	if changed, _ := store.ApplyBlocklistChange("3@s.whatsapp.net", BlocklistBlock, now); changed {
+		t.Error("blocking an already blocked contact was recorded")
+	}
+	if _, err := store.ApplyBlocklistChange("3@s.whatsapp.net", "mute", now); err == nil {
+		t.Error("invalid action accepted")
+	}
+
+	// A later full sync reports what differs: 1 unblocked, 4 blocked
+	changes, err := store.ReplaceBlocklist([]string{"2@s.whatsapp.net", "3@s.whatsapp.net", "4@s.whatsapp.net"}, now)
+	if err != nil {
+		t.Fatalf("ReplaceBlocklist failed: %v", err)
+	}
+	got := map[string]string{}
+	for _, c := range changes {
+		got[c.JID] = c.Action
+	}
+	if len(got) != 2 || got["1@s.whatsapp.net"] != BlocklistUnblock || got["4@s.whatsapp.net"] != BlocklistBlock {
+		t.Errorf("ReplaceBlocklist changes = %+v", changes)
+	}
+
+	blocked, err := store.GetBlockedContacts()
+	if err != nil || len(blocked) != 3 {
+		t.Fatalf("GetBlockedContacts = %+v, %v; want 3 contacts", blocked, err)
+	}
+
+	history, err := store.GetBlocklistChanges(now.Add(-time.Minute), 10)
+	if err != nil {
+		t.Fatalf("GetBlocklistChanges failed: %v", err)
+	}
+	if len(history) != 3 {
+		t.Errorf("GetBlocklistChanges returned %d changes, want 3: %+v", len(history), history)
+	}
+}
+
+func TestBlocklistSeededEmpty(t *testing.T) {
+	tempDB := "test_blocklist_empty.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+	now := time.Now()
+
+	// Linked with nobody blocked: the first block made afterwards is reported
+	if seeded, err := store.SeedBlocklist(nil, now); err != nil || !seeded {
+		t.Fatalf("SeedBlocklist = %v, %v", seeded, err)
+	}
+	changes, err := store.ReplaceBlocklist([]string{"1@s.whatsapp.net"}, now)
+	if err != nil || len(changes) != 1 || changes[0].Action != BlocklistBlock {
+		t.Errorf("first block after an empty seed = %+v, %v", changes, err)
+	}
+}
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"whatsapp-bridge/internal/types"
+)
+
+// StoreCalendarEvent records an event planned in a chat, or an edit of one.
+// The creator and creation time of a stored event are kept, and a version
+// older than the stored one, e.g. the original replayed by a history sync
+// after an edit, is ignored.
+func (store *MessageStore) StoreCalendarEvent(ev *types.CalendarEvent) error {
+	var endTime interface{}
+	if ev.EndTime != nil {
+		endTime = ev.EndTime.UTC()
+	}
+
+	_, err := store.db.Exec(
+		`INSERT INTO calendar_events
+		 (chat_jid, message_id, creator_jid, name, description, location, latitude, longitude,
+		  join_link, start_time, end_time, canceled, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT (chat_jid, message_id) DO UPDATE SET
+			name = excluded.name,
+			description = excluded.description,
+			location = excluded.location,
+			latitude = excluded.latitude,
+			longitude = excluded.longitude,
+			join_link = excluded.join_link,
+			start_time = excluded.start_time,
+			end_time = excluded.end_time,
+			canceled = excluded.canceled,
+			updated_at = excluded.updated_at
+		 WHERE excluded.updated_at >= calendar_events.updated_at`,
+		ev.ChatJID, ev.MessageID, ev.CreatorJID, ev.Name, ev.Description, ev.Location, ev.Latitude, ev.Longitude,
+		ev.JoinLink, ev.StartTime.UTC(), endTime, ev.Canceled, ev.CreatedAt.UTC(), ev.UpdatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store calendar event: %v", err)
+	}
+	return nil
+}
+
+// GetCalendarEvents returns the events that have not ended before since nor
+// start at or after until, soonest first. Zero times and an empty chatJID do
+// not filter; canceled events are only included when asked for.
+func (store *MessageStore) GetCalendarEvents(chatJID string, since, until time.Time, includeCanceled bool, limit int) ([]types.CalendarEvent, error) {
+	query := `SELECT e.chat_jid, c.name, e.message_id, e.creator_jid, e.name, e.description, e.location, e.latitude, e.longitude,
+		 e.join_link, e.start_time, e.end_time, e.canceled, e.created_at, e.updated_at
+		 FROM calendar_events e LEFT JOIN chats c ON c.jid = e.chat_jid WHERE 1 = 1`
+	var args []interface{}
+
+	if chatJID != "" {
+		query += " AND e.chat_jid = ?"
+		args = append(args, chatJID)
+	}
+	if !since.IsZero() {
+		query += " AND COALESCE(e.end_time, e.start_time) >= ?"
+		args = append(args, since.UTC())
+	}
+	if !until.IsZero() {
+		query += " AND e.start_time < ?"
+		args = append(args, until.UTC())
+	}
+	if !includeCanceled {
+		query += " AND e.canceled = 0"
+	}
+	query += " ORDER BY e.start_time, e.message_id LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := store.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query calendar events: %v", err)
+	}
+	defer rows.Close()
+
+	events := []types.CalendarEvent{}
+	for rows.Next() {
+		var ev types.CalendarEvent
+		var chatName, description, location, joinLink sql.NullString
+		var endTime sql.NullTime
+		if err := rows.Scan(&ev.ChatJID, &chatName, &ev.MessageID, &ev.CreatorJID, &ev.Name, &description, &location,
+			&ev.Latitude, &ev.Longitude, &joinLink, &ev.StartTime, &endTime, &ev.Canceled, &ev.CreatedAt, &ev.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan calendar event: %v", err)
+		}
+
+		ev.ChatName = chatName.String
+		ev.Description = description.String
+		ev.Location = location.String
+		ev.JoinLink = joinLink.String
+		if endTime.Valid {
+			ev.EndTime = &endTime.Time
+		}
+		events = append(events, ev)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,93 @@
+package database
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"whatsapp-bridge/internal/types"
+)
+
+func TestCalendarEvents(t *testing.T) {
+	tempDB := "test_calendar.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+	group := "120363000000000000@g.us"
+	if err := store.StoreChat(group, "Climbing club", time.Now()); err != nil {
+		t.Fatalf("Failed to store chat: %v", err)
+	}
+
+	start := time.Date(2024, 6, 1, 18, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Hour)
+	created := start.Add(-48 * time.Hour)
+	event := types.CalendarEvent{
+		ChatJID:    group,
+		MessageID:  "3EB0A1",
+		CreatorJID: "15550000001@s.whatsapp.net",
+		Name:       "Bouldering",
+		StartTime:  start,
+		EndTime:    &end,
+		CreatedAt:  created,
+		UpdatedAt:  created,
+	}
+	if err := store.StoreCalendarEvent(&event); err != nil {
+		t.Fatalf("StoreCalendarEvent: %v", err)
+	}
+	later := types.CalendarEvent{ChatJID: group, MessageID: "3EB0A2", CreatorJID: event.CreatorJID, Name: "Gear swap",
+		StartTime: start.Add(7 * 24 * time.Hour), CreatedAt: created, UpdatedAt: created}
+	if err := store.StoreCalendarEvent(&later); err != nil {
+		t.Fatalf("StoreCalendarEvent: %v", err)
+	}
+
+	events, err := store.GetCalendarEvents(group, time.Time{}, time.Time{}, false, 10)
+	if err != nil || len(events) != 2 || events[0].MessageID != "3EB0A1" || events[0].ChatName != "Climbing club" {
+		t.Fatalf("GetCalendarEvents = %+v, %v", events, err)
+	}
+	if events[0].EndTime == nil || !events[0].EndTime.Equal(end) || events[1].EndTime != nil {
+		t.Errorf("end times = %v, %v", events[0].EndTime, events[1].EndTime)
+	}
+
+	// An event still running at since is listed; one starting at until is not
+	events, err = store.GetCalendarEvents("", start.Add(time.Hour), later.StartTime, false, 10)
+	if err != nil || len(events) != 1 || events[0].MessageID != "3EB0A1" {
+		t.Errorf("GetCalendarEvents(range) = %+v, %v, want only the running event", events, err)
+	}
+
+	// The creator moves the event and then cancels it; a replay of the
+	// original does not undo that
+	edit := event
+	edit.StartTime = start.Add(time.Hour)
+	edit.Canceled = true
+	edit.CreatorJID = "ignored@s.whatsapp.net"
+	edit.UpdatedAt = created.Add(time.Hour)
+	if err := store.StoreCalendarEvent(&edit); err != nil {
+		t.Fatalf("StoreCalendarEvent(edit): %v", err)
+	}
+	if err := store.StoreCalendarEvent(&event); err != nil {
+		t.Fatalf("StoreCalendarEvent(replay): %v", err)
+	}
+
+	if events, _ := store.GetCalendarEvents(group, time.Time{}, time.Time{}, false, 10); len(events) != 1 {
+		t.Errorf("canceled event listed: %+v", events)
+	}
+	events, err = store.GetCalendarEvents(group, time.Time{}, time.Time{}, true, 10)
+	if err != nil || len(events) != 2 {
+		t.Fatalf("GetCalendarEvents(canceled) = %+v, %v", events, err)
+	}
+	got := events[0]
+	if !got.Canceled || !got.StartTime.Equal(edit.StartTime) || got.CreatorJID != event.CreatorJID || !got.CreatedAt.Equal(created) {
+		t.Errorf("edited event = %+v", got)
+	}
+}
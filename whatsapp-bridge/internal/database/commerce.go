@@ -0,0 +1,86 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"whatsapp-bridge/internal/types"
+)
+
+// StoreCommerceMessage stores an order, catalog or payment message. A
+// redelivered message replaces the existing row.
+func (store *MessageStore) StoreCommerceMessage(cm *types.CommerceMessage) error {
+	var items []byte
+	if len(cm.Items) > 0 {
+		var err error
+		if items, err = json.Marshal(cm.Items); err != nil {
+			return fmt.Errorf("failed to encode commerce items: %v", err)
+		}
+	}
+
+	_, err := store.db.Exec(
+		`INSERT OR REPLACE INTO commerce_messages
+		 (message_id, chat_jid, sender_jid, is_from_me, kind, reference_id, title, status, note,
+		  seller_jid, item_count, items, amount_1000, currency, timestamp)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		cm.MessageID, cm.ChatJID, cm.SenderJID, cm.IsFromMe, cm.Kind, cm.ReferenceID, cm.Title, cm.Status, cm.Note,
+		cm.SellerJID, cm.ItemCount, string(items), cm.Amount1000, cm.Currency, cm.Timestamp.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store commerce message: %v", err)
+	}
+	return nil
+}
+
+// GetCommerceMessages returns commerce messages, newest first. chatJID and
+// kind are optional filters.
+func (store *MessageStore) GetCommerceMessages(chatJID, kind string, limit int) ([]types.CommerceMessage, error) {
+	query := `SELECT message_id, chat_jid, sender_jid, is_from_me, kind, reference_id, title, status, note,
+		 seller_jid, item_count, items, amount_1000, currency, timestamp FROM commerce_messages WHERE 1 = 1`
+	var args []interface{}
+
+	if chatJID != "" {
+		query += " AND chat_jid = ?"
+		args = append(args, chatJID)
+	}
+	if kind != "" {
+		query += " AND kind = ?"
+		args = append(args, kind)
+	}
+	query += " ORDER BY timestamp DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := store.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query commerce messages: %v", err)
+	}
+	defer rows.Close()
+
+	messages := []types.CommerceMessage{}
+	for rows.Next() {
+		var cm types.CommerceMessage
+		var referenceID, title, status, note, sellerJID, items, currency sql.NullString
+
+		if err := rows.Scan(&cm.MessageID, &cm.ChatJID, &cm.SenderJID, &cm.IsFromMe, &cm.Kind,
+			&referenceID, &title, &status, &note, &sellerJID, &cm.ItemCount, &items,
+			&cm.Amount1000, &currency, &cm.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan commerce message: %v", err)
+		}
+
+		cm.ReferenceID = referenceID.String
+		cm.Title = title.String
+		cm.Status = status.String
+		cm.Note = note.String
+		cm.SellerJID = sellerJID.String
+		cm.Currency = currency.String
+		if items.String != "" {
+			if err := json.Unmarshal([]byte(items.String), &cm.Items); err != nil {
+				return nil, fmt.Errorf("failed to decode commerce items: %v", err)
+			}
+		}
+		messages = append(messages, cm)
+	}
+
+	return messages, rows.Err()
+}
@@ -0,0 +1,109 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"whatsapp-bridge/internal/types"
+)
+
+// EditMessage replaces a stored message's text with an edit, keeping the
+// text it replaces in the edit history. An edit no newer than the latest one
+// applied, e.g. one delivered again, changes nothing. Messages stored
+// without their content only record that they were edited. It reports
+// whether the message was stored.
+func (store *MessageStore) EditMessage(chatJID, messageID, content string, msgContext *types.MessageContext, editedAt time.Time) (bool, error) {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to edit message: %v", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var previous string
+	var metadataOnly bool
+	err = tx.QueryRow(`SELECT content, metadata_only FROM messages WHERE chat_jid = ? AND id = ?`, chatJID, messageID).Scan(&previous, &metadataOnly)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to look up edited message: %v", err)
+	}
+
+	var latest time.Time
+	err = tx.QueryRow(
+		`SELECT edited_at FROM message_edits WHERE chat_jid = ? AND message_id = ? ORDER BY edited_at DESC LIMIT 1`,
+		chatJID, messageID,
+	).Scan(&latest)
+	if err != nil && err != sql.ErrNoRows {
+		return false, fmt.Errorf("failed to look up earlier edits: %v", err)
+	}
+	if err == nil && !editedAt.After(latest) {
+		return true, nil
+	}
+
+	if _, err := tx.Exec(
+		`INSERT INTO message_edits (chat_jid, message_id, content, edited_at) VALUES (?, ?, ?, ?)`,
+		chatJID, messageID, previous, editedAt.UTC(),
+	); err != nil {
+		return false, fmt.Errorf("failed to store edit: %v", err)
+	}
+
+	if !metadataOnly {
+		var contextJSON sql.NullString
+		if msgContext != nil {
+			data, err := json.Marshal(msgContext)
+			if err != nil {
+				return false, fmt.Errorf("failed to encode message context: %v", err)
+			}
+			contextJSON = sql.NullString{String: string(data), Valid: true}
+		}
+		if _, err := tx.Exec(
+			`UPDATE messages SET content = ?, context = ? WHERE chat_jid = ? AND id = ?`,
+			content, contextJSON, chatJID, messageID,
+		); err != nil {
+			return false, fmt.Errorf("failed to edit message: %v", err)
+		}
+	}
+	return true, tx.Commit()
+}
+
+// GetMessageEdits returns the versions of a message replaced by edits,
+// oldest first: the first is the message as originally sent
+func (store *MessageStore) GetMessageEdits(chatJID, messageID string) ([]types.MessageEdit, error) {
+	rows, err := store.db.Query(
+		`SELECT chat_jid, message_id, content, edited_at FROM message_edits
+		 WHERE chat_jid = ? AND message_id = ?
+		 ORDER BY edited_at`,
+		chatJID, messageID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query edits: %v", err)
+	}
+	defer rows.Close()
+
+	edits := []types.MessageEdit{}
+	for rows.Next() {
+		var e types.MessageEdit
+		if err := rows.Scan(&e.ChatJID, &e.MessageID, &e.Content, &e.EditedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan edit: %v", err)
+		}
+		edits = append(edits, e)
+	}
+	return edits, rows.Err()
+}
+
+// RevokeMessage marks a message as deleted for everyone. Its content is
+// kept; the first revoke recorded stands. A revoke may arrive for a message
+// not stored yet, and applies once it is.
+func (store *MessageStore) RevokeMessage(chatJID, messageID, revokedBy string, revokedAt time.Time) error {
+	_, err := store.db.Exec(
+		`INSERT OR IGNORE INTO revoked_messages (chat_jid, message_id, revoked_by, revoked_at) VALUES (?, ?, ?, ?)`,
+		chatJID, messageID, revokedBy, revokedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store revoke: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"whatsapp-bridge/internal/types"
+)
+
+func TestMessageEditsAndRevokes(t *testing.T) {
+	tempDB := "test_edits.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+	chat := "15550000001@s.whatsapp.net"
+	sent := time.Now().UTC().Truncate(time.Second)
+
+	if err := store.StoreChat(chat, "Ana", sent); err != nil {
+		t.Fatalf("StoreChat: %v", err)
+	}
+	if err := store.StoreMessage("M1", chat, "15550000001", "Ana", "see you at 5", sent, false, "", "", "", nil, nil, nil, 0, nil); err != nil {
+		t.Fatalf("StoreMessage: %v", err)
+	}
+
+	edit := func(content string, at time.Duration) bool {
+		t.Helper()
+		ok, err := store.EditMessage(chat, "M1", content, &types.MessageContext{Mentions: []string{"15550000002@s.whatsapp.net"}}, sent.Add(at))
+		if err != nil {
+			t.Fatalf("EditMessage: %v", err)
+		}
+		return ok
+	}
+	if !edit("see you at 6", time.Minute) || !edit("see you at 7", 2*time.Minute) {
+		t.Fatal("edit of a stored message reported it missing")
+	}
+	// Delivered again, and an older edit arriving late: both change nothing
+	edit("see you at 7", 2*time.Minute)
+	edit("see you at 6", time.Minute)
+
+	msg, err := store.GetMessage(chat, "M1")
+	if err != nil || msg == nil {
+		t.Fatalf("GetMessage: %+v, %v", msg, err)
+	}
+	if msg.Content != "see you at 7" || !msg.Edited || msg.EditedAt == nil || !msg.EditedAt.Equal(sent.Add(2*time.Minute)) || msg.Revoked {
+		t.Errorf("edited message = %+v", msg)
+	}
+	if msg.Context == nil || len(msg.Context.Mentions) != 1 {
+		t.Errorf("context not replaced: %+v", msg.Context)
+	}
+
+	edits, err := store.GetMessageEdits(chat, "M1")
+	if err != nil || len(edits) != 2 || edits[0].Content != "see you at 5" || edits[1].Content != "see you at 6" {
+		t.Fatalf("GetMessageEdits = %+v, %v", edits, err)
+	}
+
+	// The edited text is what search finds
+	if results, _, err := store.SearchMessages(types.MessageSearch{Query: "7"}); err != nil || len(results) != 1 {
+		t.Errorf("search for edited text = %+v, %v", results, err)
+	}
+
+	if ok, err := store.EditMessage(chat, "M9", "hi", nil, sent); err != nil || ok {
+		t.Errorf("edit of an unknown message = %v, %v", ok, err)
+	}
+
+	// Revoked: content kept, flagged; the first revoke stands
+	if err := store.RevokeMessage(chat, "M1", chat, sent.Add(time.Hour)); err != nil {
+		t.Fatalf("RevokeMessage: %v", err)
+	}
+	if err := store.RevokeMessage(chat, "M1", chat, sent.Add(2*time.Hour)); err != nil {
+		t.Fatalf("RevokeMessage again: %v", err)
+	}
+	msg, _ = store.GetMessage(chat, "M1")
+	if !msg.Revoked || msg.RevokedAt == nil || !msg.RevokedAt.Equal(sent.Add(time.Hour)) || msg.Content != "see you at 7" {
+		t.Errorf("revoked message = %+v", msg)
+	}
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// RenameChat sets a stored chat's name and returns the name it replaces.
+// A chat that is not stored is left for its first message to add.
+func (store *MessageStore) RenameChat(jid, name string) (string, error) {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var old sql.NullString
+	err = tx.QueryRow(`SELECT name FROM chats WHERE jid = ?`, jid).Scan(&old)
+	if err != nil && err != sql.ErrNoRows {
+		return "", fmt.Errorf("failed to read chat name: %v", err)
+	}
+
+	if _, err := tx.Exec(`UPDATE chats SET name = ? WHERE jid = ?`, name, jid); err != nil {
+		return "", fmt.Errorf("failed to rename chat: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit chat name: %v", err)
+	}
+	return old.String, nil
+}
+
+// StoreGroupSetting records the current value of one of a group's settings
+// and returns the value it replaces. known is false the first time the
+// setting is stored for the group.
+func (store *MessageStore) StoreGroupSetting(groupJID, setting, value string, at time.Time) (old string, known bool, err error) {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return "", false, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`SELECT value FROM group_settings WHERE group_jid = ? AND setting = ?`, groupJID, setting).Scan(&old)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return "", false, fmt.Errorf("failed to read group setting: %v", err)
+	default:
+		known = true
+	}
+
+	_, err = tx.Exec(
+		`INSERT INTO group_settings (group_jid, setting, value, updated_at) VALUES (?, ?, ?, ?)
+		 ON CONFLICT(group_jid, setting) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`,
+		groupJID, setting, value, at.UTC(),
+	)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to store group setting: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return "", false, fmt.Errorf("failed to commit group setting: %v", err)
+	}
+	return old, known, nil
+}
+
+// SeedGroupSettings records the values of a group's settings seen when the
+// group first appears. Settings already stored, e.g. by a change that
+// arrived meanwhile, are kept.
+func (store *MessageStore) SeedGroupSettings(groupJID string, values map[string]string, at time.Time) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	for setting, value := range values {
+		_, err := tx.Exec(
+			`INSERT INTO group_settings (group_jid, setting, value, updated_at) VALUES (?, ?, ?, ?)
+			 ON CONFLICT(group_jid, setting) DO NOTHING`,
+			groupJID, setting, value, at.UTC(),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to store group setting: %v", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit group settings: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package database
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"whatsapp-bridge/internal/types"
+)
+
+func TestGroupSettings(t *testing.T) {
+	tempDB := "test_groups.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+	group := "120363000000000000@g.us"
+	at := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+
+	if old, known, err := store.StoreGroupSetting(group, "announce", "true", at); err != nil || known || old != "" {
+		t.Fatalf("first StoreGroupSetting = %q, %v, %v, want nothing known", old, known, err)
+	}
+	if old, known, _ := store.StoreGroupSetting(group, "announce", "false", at.Add(time.Hour)); !known || old != "true" {
+		t.Errorf("second StoreGroupSetting = %q, %v, want the earlier true", old, known)
+	}
+	if _, known, _ := store.StoreGroupSetting(group, "locked", "true", at); known {
+		t.Error("locked read as known from the announce setting")
+	}
+
+	// Renaming keeps the chat's last message time
+	if err := store.StoreChat(group, "Team", at); err != nil {
+		t.Fatalf("Failed to store chat: %v", err)
+	}
+	if old, err := store.RenameChat(group, "Launch"); err != nil || old != "Team" {
+		t.Errorf("RenameChat = %q, %v, want Team", old, err)
+	}
+	chats, _ := store.ListChats(0, 0, types.ListOrder{})
+	if len(chats) != 1 || chats[0].Name != "Launch" || chats[0].LastMessageTime == nil || !chats[0].LastMessageTime.Equal(at) {
+		t.Errorf("chats after rename = %+v", chats)
+	}
+
+	if old, err := store.RenameChat("120363000000000001@g.us", "New"); err != nil || old != "" {
+		t.Errorf("RenameChat of an unknown chat = %q, %v", old, err)
+	}
+	if n, _ := store.GetChatCount(); n != 1 {
+		t.Errorf("chat count = %d, want the unknown chat left out", n)
+	}
+}
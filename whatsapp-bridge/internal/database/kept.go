@@ -0,0 +1,74 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// SetMessageKept records that a message in a disappearing chat was kept,
+// so it stays in the chat after the timer, or removes the mark when the
+// keep is undone
+func (store *MessageStore) SetMessageKept(chatJID, messageID, keptBy string, keptAt time.Time, kept bool) error {
+	var err error
+	if kept {
+		_, err = store.db.Exec(
+			`INSERT OR REPLACE INTO kept_messages (chat_jid, message_id, kept_by, kept_at) VALUES (?, ?, ?, ?)`,
+			chatJID, messageID, keptBy, keptAt.UTC(),
+		)
+	} else {
+		_, err = store.db.Exec(`DELETE FROM kept_messages WHERE chat_jid = ? AND message_id = ?`, chatJID, messageID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update kept message: %v", err)
+	}
+	return nil
+}
+
+// SetMessageExpiry records when a message sent with a disappearing timer
+// disappears from the chat
+func (store *MessageStore) SetMessageExpiry(chatJID, messageID string, expiresAt time.Time) error {
+	_, err := store.db.Exec(
+		`INSERT OR REPLACE INTO disappearing_messages (chat_jid, message_id, expires_at) VALUES (?, ?, ?)`,
+		chatJID, messageID, expiresAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record message expiry: %v", err)
+	}
+	return nil
+}
+
+// DeleteExpiredMessages removes the messages whose disappearing timer ran
+// out by now, as the phone does. Kept messages stay. It returns how many
+// messages were removed.
+func (store *MessageStore) DeleteExpiredMessages(now time.Time) (int, error) {
+	now = now.UTC()
+	tx, err := store.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(
+		`DELETE FROM messages WHERE EXISTS (SELECT 1 FROM disappearing_messages d
+		   WHERE d.chat_jid = messages.chat_jid AND d.message_id = messages.id AND d.expires_at <= ?)`+notKept,
+		now,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired messages: %v", err)
+	}
+	// Kept messages keep their expiry, so undoing the keep removes them
+	_, err = tx.Exec(
+		`DELETE FROM disappearing_messages WHERE expires_at <= ?
+		   AND NOT EXISTS (SELECT 1 FROM kept_messages k WHERE k.chat_jid = disappearing_messages.chat_jid AND k.message_id = disappearing_messages.message_id)`,
+		now,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear message expiries: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit expired messages: %v", err)
+	}
+
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
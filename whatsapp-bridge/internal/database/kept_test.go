@@ -0,0 +1,131 @@
+package database
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestKeptMessages(t *testing.T) {
+	tempDB := "test_kept.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+	chat := "15550000001@s.whatsapp.net"
+	if err := store.StoreChat(chat, "Ana", time.Now()); err != nil {
+		t.Fatalf("Failed to store chat: %v", err)
+	}
+	storeMessage := func() {
+		if err := store.StoreMessage("M1", chat, "15550000001", "Ana", "door code 4711", time.Now(), false, "", "", "", nil, nil, nil, 0, nil); err != nil {
+			t.Fatalf("Failed to store message: %v", err)
+		}
+	}
+	kept := func() bool {
+		msg, err := store.GetMessage(chat, "M1")
+		if err != nil || msg == nil {
+			t.Fatalf("GetMessage = %v, %v", msg, err)
+		}
+		return msg.Kept
+	}
+
+	// A keep may arrive before the message it keeps
+	if err := store.SetMessageKept(chat, "M1", chat, time.Now(), true); err != nil {
+		t.Fatalf("SetMessageKept: %v", err)
+	}
+	storeMessage()
+	if !kept() {
+		t.Error("message kept before it was stored is not flagged")
+	}
+
+	// Storing the message again keeps the flag
+	storeMessage()
+	if !kept() {
+		t.Error("kept flag lost when the message was stored again")
+	}
+
+	if err := store.SetMessageKept(chat, "M1", chat, time.Now(), false); err != nil {
+		t.Fatalf("SetMessageKept(undo): %v", err)
+	}
+	if kept() {
+		t.Error("message still flagged after the keep was undone")
+	}
+}
+
+func TestDeleteExpiredMessages(t *testing.T) {
+	tempDB := "test_expired.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+	chat := "15550000001@s.whatsapp.net"
+	if err := store.StoreChat(chat, "Ana", time.Now()); err != nil {
+		t.Fatalf("Failed to store chat: %v", err)
+	}
+	sent := time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC)
+	for _, id := range []string{"EXPIRED", "KEPT", "LATER", "PLAIN"} {
+		if err := store.StoreMessage(id, chat, "15550000001", "Ana", "text "+id, sent, false, "", "", "", nil, nil, nil, 0, nil); err != nil {
+			t.Fatalf("Failed to store message: %v", err)
+		}
+	}
+	now := sent.Add(48 * time.Hour)
+	for id, expiresAt := range map[string]time.Time{"EXPIRED": sent.Add(24 * time.Hour), "KEPT": sent.Add(24 * time.Hour), "LATER": sent.Add(7 * 24 * time.Hour)} {
+		if err := store.SetMessageExpiry(chat, id, expiresAt); err != nil {
+			t.Fatalf("SetMessageExpiry(%s): %v", id, err)
+		}
+	}
+	if err := store.SetMessageKept(chat, "KEPT", chat, sent, true); err != nil {
+		t.Fatalf("SetMessageKept: %v", err)
+	}
+
+	// Disappearing messages stay in the hot table until they expire
+	if _, err := store.ArchiveMessages(now.AddDate(0, 2, 0)); err != nil {
+		t.Fatalf("ArchiveMessages: %v", err)
+	}
+
+	removed, err := store.DeleteExpiredMessages(now)
+	if err != nil || removed != 1 {
+		t.Fatalf("DeleteExpiredMessages = %d, %v, want 1 removed", removed, err)
+	}
+	stored := func(id string) bool {
+		var n int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM messages WHERE chat_jid = ? AND id = ?`, chat, id).Scan(&n); err != nil {
+			t.Fatalf("count %s: %v", id, err)
+		}
+		return n == 1
+	}
+	if stored("EXPIRED") || !stored("KEPT") || !stored("LATER") {
+		t.Errorf("after cleanup: expired %v, kept %v, later %v; want only the expired message gone",
+			stored("EXPIRED"), stored("KEPT"), stored("LATER"))
+	}
+	if stored("PLAIN") {
+		t.Error("message without a timer was not archived")
+	}
+
+	// Undoing the keep lets the message disappear
+	if err := store.SetMessageKept(chat, "KEPT", chat, now, false); err != nil {
+		t.Fatalf("SetMessageKept(undo): %v", err)
+	}
+	if removed, err := store.DeleteExpiredMessages(now); err != nil || removed != 1 || stored("KEPT") {
+		t.Errorf("after undoing the keep: removed %d, %v; want KEPT removed", removed, err)
+	}
+}
@@ -0,0 +1,37 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// AcquireLease takes the named lease for holder, or extends it if holder
+// already has it. It fails without error while another holder's lease has
+// not expired. Expiry is kept in unix milliseconds.
+func (store *MessageStore) AcquireLease(name, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	result, err := store.db.Exec(
+		`INSERT INTO leader_leases (name, holder, expires_at) VALUES (?, ?, ?)
+		 ON CONFLICT(name) DO UPDATE SET holder = excluded.holder, expires_at = excluded.expires_at
+		 WHERE leader_leases.holder = excluded.holder OR leader_leases.expires_at < ?`,
+		name, holder, now.Add(ttl).UnixMilli(), now.UnixMilli(),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lease: %v", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %v", err)
+	}
+	return rows > 0, nil
+}
+
+// ReleaseLease gives up the named lease if holder has it
+func (store *MessageStore) ReleaseLease(name, holder string) error {
+	_, err := store.db.Exec("DELETE FROM leader_leases WHERE name = ? AND holder = ?", name, holder)
+	if err != nil {
+		return fmt.Errorf("failed to release lease: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package database
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestAcquireLease(t *testing.T) {
+	tempDB := "test_leases.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+
+	acquire := func(holder string, ttl time.Duration, want bool) {
+		t.Helper()
+		got, err := store.AcquireLease("bridge", holder, ttl)
+		if err != nil {
+			t.Fatalf("AcquireLease(%s) failed: %v", holder, err)
+		}
+		if got != want {
+			t.Errorf("AcquireLease(%s) = %v, want %v", holder, got, want)
+		}
+	}
+
+	acquire("primary", time.Minute, true)
+	acquire("standby", time.Minute, false)
+	acquire("primary", time.Minute, true) // renewal
+
+	// Releasing hands the lease over immediately; only the holder can release
+	if err := store.ReleaseLease("bridge", "standby"); err != nil {
+		t.Fatalf("ReleaseLease failed: %v", err)
+	}
+	acquire("standby", time.Minute, false)
+	if err := store.ReleaseLease("bridge", "primary"); err != nil {
+		t.Fatalf("ReleaseLease failed: %v", err)
+	}
+	acquire("standby", -time.Second, true) // already expired
+
+	// An expired lease can be taken over
+	acquire("primary", time.Minute, true)
+	acquire("standby", time.Minute, false)
+}
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"fmt"
+
+	"whatsapp-bridge/internal/types"
+)
+
+// Fields each list can be sorted by, by JSON name, and the column that sorts
+// them. The API offers exactly these.
+var (
+	MessageSortFields = map[string]string{
+		"timestamp":   "timestamp",
+		"chat_jid":    "chat_jid",
+		"sender":      "sender",
+		"sender_name": "sender_name",
+		"media_type":  "media_type",
+	}
+	ChatSortFields = map[string]string{
+		"last_message_time": "c.last_message_time",
+		"name":              "c.name",
+		"jid":               "c.jid",
+		"message_count":     "message_count",
+	}
+	WebhookLogSortFields = map[string]string{
+		"created_at":        "created_at",
+		"webhook_config_id": "webhook_config_id",
+		"chat_jid":          "chat_jid",
+		"response_status":   "response_status",
+		"attempt_count":     "attempt_count",
+	}
+)
+
+// orderBy builds the ORDER BY clause for a list. Without a field the list's
+// default order is used; either way tiebreak follows, so pages do not shift
+// between rows that sort equal.
+func orderBy(order types.ListOrder, fields map[string]string, defaultOrder, tiebreak string) (string, error) {
+	if order.Field == "" {
+		return " ORDER BY " + defaultOrder + ", " + tiebreak, nil
+	}
+	column, ok := fields[order.Field]
+	if !ok {
+		return "", fmt.Errorf("cannot sort by %s", order.Field)
+	}
+	direction := "ASC"
+	if order.Desc {
+		direction = "DESC"
+	}
+	return " ORDER BY " + column + " " + direction + ", " + tiebreak, nil
+}
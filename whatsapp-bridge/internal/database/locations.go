@@ -0,0 +1,73 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"whatsapp-bridge/internal/types"
+)
+
+// StoreLocationPoint stores a live location update. Repeated updates with the
+// same sequence number are ignored.
+func (store *MessageStore) StoreLocationPoint(p *types.LocationPoint) error {
+	_, err := store.db.Exec(
+		`INSERT OR IGNORE INTO location_points
+		 (chat_jid, sender_jid, message_id, latitude, longitude, accuracy_meters, speed_mps, heading, caption, sequence, timestamp)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		p.ChatJID, p.SenderJID, p.MessageID, p.Latitude, p.Longitude,
+		p.AccuracyMeters, p.SpeedMps, p.Heading, p.Caption, p.Sequence, p.Timestamp.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store location point: %v", err)
+	}
+	return nil
+}
+
+// GetLocationPoints returns a sender's live location points, newest first.
+// chatJID (optional) restricts the track to one chat; since (optional)
+// excludes older points.
+func (store *MessageStore) GetLocationPoints(senderJID, chatJID string, since time.Time, limit int) ([]types.LocationPoint, error) {
+	query := `SELECT chat_jid, sender_jid, message_id, latitude, longitude, accuracy_meters, speed_mps,
+		 heading, caption, sequence, timestamp FROM location_points WHERE sender_jid = ?`
+	args := []interface{}{senderJID}
+
+	if chatJID != "" {
+		query += " AND chat_jid = ?"
+		args = append(args, chatJID)
+	}
+	if !since.IsZero() {
+		query += " AND timestamp >= ?"
+		args = append(args, since.UTC())
+	}
+	query += " ORDER BY timestamp DESC, sequence DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := store.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query location points: %v", err)
+	}
+	defer rows.Close()
+
+	points := []types.LocationPoint{}
+	for rows.Next() {
+		var p types.LocationPoint
+		var accuracy, heading, sequence sql.NullInt64
+		var speed sql.NullFloat64
+		var caption sql.NullString
+
+		if err := rows.Scan(&p.ChatJID, &p.SenderJID, &p.MessageID, &p.Latitude, &p.Longitude,
+			&accuracy, &speed, &heading, &caption, &sequence, &p.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan location point: %v", err)
+		}
+
+		p.AccuracyMeters = int(accuracy.Int64)
+		p.SpeedMps = speed.Float64
+		p.Heading = int(heading.Int64)
+		p.Caption = caption.String
+		p.Sequence = sequence.Int64
+		points = append(points, p)
+	}
+
+	return points, rows.Err()
+}
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"whatsapp-bridge/internal/types"
+)
+
+func TestLocationPoints(t *testing.T) {
+	tempDB := "test_locations.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+	sender := "111@s.whatsapp.net"
+	start := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	points := []types.LocationPoint{
+		{ChatJID: "fleet@g.us", SenderJID: sender, MessageID: "LIVE1", Latitude: 52.50, Longitude: 13.40, Sequence: 1, Timestamp: start},
+		{ChatJID: "fleet@g.us", SenderJID: sender, MessageID: "LIVE1", Latitude: 52.51, Longitude: 13.41, Sequence: 2, Timestamp: start.Add(time.Minute)},
+		{ChatJID: "fleet@g.us", SenderJID: sender, MessageID: "LIVE1", Latitude: 52.51, Longitude: 13.41, Sequence: 2, Timestamp: start.Add(time.Minute)}, // redelivery
+		{ChatJID: "other@g.us", SenderJID: sender, MessageID: "LIVE2", Latitude: 48.13, Longitude: 11.58, Sequence: 1, Timestamp: start.Add(2 * time.Minute)},
+	}
+	for i := range points {
+		if err := store.StoreLocationPoint(&points[i]); err != nil {
+			t.Fatalf("StoreLocationPoint failed: %v", err)
+		}
+	}
+
+	all, err := store.GetLocationPoints(sender, "", time.Time{}, 10)
+	if err != nil {
+		t.Fatalf("GetLocationPoints failed: %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("Expected 3 points after dedupe, got %d", len(all))
+	}
+	if all[0].MessageID != "LIVE2" {
+		t.Errorf("Expected newest point first, got %+v", all[0])
+	}
+
+	fleet, err := store.GetLocationPoints(sender, "fleet@g.us", start.Add(30*time.Second), 10)
+	if err != nil {
+		t.Fatalf("GetLocationPoints failed: %v", err)
+	}
+	if len(fleet) != 1 || fleet[0].Sequence != 2 || fleet[0].Latitude != 52.51 {
+		t.Errorf("Expected only the second fleet point, got %+v", fleet)
+	}
+}
@@ -2,6 +2,9 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 
 	"whatsapp-bridge/internal/types"
@@ -11,14 +14,30 @@ import (
 func (store *MessageStore) StoreChat(jid, name string, lastMessageTime time.Time) error {
 	_, err := store.db.Exec(
 		"INSERT OR REPLACE INTO chats (jid, name, last_message_time) VALUES (?, ?, ?)",
-		jid, name, lastMessageTime,
+		jid, name, lastMessageTime.UTC(),
 	)
 	return err
 }
 
-// StoreMessage stores a message in the database
+// StoreMessage stores a message in the database. msgContext, kept as JSON,
+// may be nil.
 func (store *MessageStore) StoreMessage(id, chatJID, sender, senderName, content string, timestamp time.Time, isFromMe bool,
-	mediaType, filename, url string, mediaKey, fileSHA256, fileEncSHA256 []byte, fileLength uint64) error {
+	mediaType, filename, url string, mediaKey, fileSHA256, fileEncSHA256 []byte, fileLength uint64, msgContext *types.MessageContext) error {
+	if err := storeMessage(store.db, id, chatJID, sender, senderName, content, timestamp, isFromMe,
+		mediaType, filename, url, mediaKey, fileSHA256, fileEncSHA256, fileLength, msgContext); err != nil {
+		return err
+	}
+	store.notifyMessageStored()
+	return nil
+}
+
+// execer runs a statement on the database or within a transaction
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func storeMessage(db execer, id, chatJID, sender, senderName, content string, timestamp time.Time, isFromMe bool,
+	mediaType, filename, url string, mediaKey, fileSHA256, fileEncSHA256 []byte, fileLength uint64, msgContext *types.MessageContext) error {
 	// Only store if there's actual content or media
 	if content == "" && mediaType == "" {
 		return nil
@@ -29,11 +48,58 @@ func (store *MessageStore) StoreMessage(id, chatJID, sender, senderName, content
 		senderName = sender
 	}
 
-	_, err := store.db.Exec(
-		`INSERT OR REPLACE INTO messages
-		(id, chat_jid, sender, sender_name, content, timestamp, is_from_me, media_type, filename, url, media_key, file_sha256, file_enc_sha256, file_length)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		id, chatJID, sender, senderName, content, timestamp, isFromMe, mediaType, filename, url, mediaKey, fileSHA256, fileEncSHA256, fileLength,
+	var contextJSON sql.NullString
+	if msgContext != nil {
+		data, err := json.Marshal(msgContext)
+		if err != nil {
+			return fmt.Errorf("failed to encode message context: %v", err)
+		}
+		contextJSON = sql.NullString{String: string(data), Valid: true}
+	}
+
+	// Storing a message again, as history sync does, updates it in place:
+	// its row keeps its place in paging and its downloaded file
+	_, err := db.Exec(
+		`INSERT INTO messages
+		(id, chat_jid, sender, sender_name, content, timestamp, is_from_me, media_type, filename, url, media_key, file_sha256, file_enc_sha256, file_length, context)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id, chat_jid) DO UPDATE SET
+			sender = excluded.sender, sender_name = excluded.sender_name, content = excluded.content,
+			timestamp = excluded.timestamp, is_from_me = excluded.is_from_me, media_type = excluded.media_type,
+			filename = excluded.filename, url = excluded.url, media_key = excluded.media_key,
+			file_sha256 = excluded.file_sha256, file_enc_sha256 = excluded.file_enc_sha256,
+			file_length = excluded.file_length, metadata_only = 0, context = excluded.context`,
+		id, chatJID, sender, senderName, content, timestamp.UTC(), isFromMe, mediaType, filename, url, mediaKey, fileSHA256, fileEncSHA256, fileLength, contextJSON,
+	)
+	return err
+}
+
+// StoreMessageMetadata stores a message without its content: no text,
+// filename, URL or media keys are kept, only who sent what kind of message
+// where and when. mediaType is empty for text messages.
+func (store *MessageStore) StoreMessageMetadata(id, chatJID, sender, senderName string, timestamp time.Time, isFromMe bool, mediaType string, fileLength uint64) error {
+	if err := storeMessageMetadata(store.db, id, chatJID, sender, senderName, timestamp, isFromMe, mediaType, fileLength); err != nil {
+		return err
+	}
+	store.notifyMessageStored()
+	return nil
+}
+
+func storeMessageMetadata(db execer, id, chatJID, sender, senderName string, timestamp time.Time, isFromMe bool, mediaType string, fileLength uint64) error {
+	if senderName == "" {
+		senderName = sender
+	}
+
+	_, err := db.Exec(
+		`INSERT INTO messages
+		(id, chat_jid, sender, sender_name, content, timestamp, is_from_me, media_type, filename, url, file_length, metadata_only)
+		VALUES (?, ?, ?, ?, '', ?, ?, ?, '', '', ?, 1)
+		ON CONFLICT(id, chat_jid) DO UPDATE SET
+			sender = excluded.sender, sender_name = excluded.sender_name, content = '',
+			timestamp = excluded.timestamp, is_from_me = excluded.is_from_me, media_type = excluded.media_type,
+			filename = '', url = '', media_key = NULL, file_sha256 = NULL, file_enc_sha256 = NULL,
+			file_length = excluded.file_length, metadata_only = 1, context = NULL`,
+		id, chatJID, sender, senderName, timestamp.UTC(), isFromMe, mediaType, fileLength,
 	)
 	return err
 }
@@ -70,6 +136,284 @@ func (store *MessageStore) GetMessages(chatJID string, limit int) ([]types.Messa
 	return messages, nil
 }
 
+// storedMessageColumns are the columns scanStoredMessage reads
+const storedMessageColumns = `id, chat_jid, sender, sender_name, content, timestamp, is_from_me, media_type, filename, metadata_only, context,
+	EXISTS (SELECT 1 FROM kept_messages k WHERE k.chat_jid = messages.chat_jid AND k.message_id = messages.id),
+	(SELECT e.edited_at FROM message_edits e WHERE e.chat_jid = messages.chat_jid AND e.message_id = messages.id ORDER BY e.edited_at DESC LIMIT 1),
+	(SELECT r.revoked_at FROM revoked_messages r WHERE r.chat_jid = messages.chat_jid AND r.message_id = messages.id)`
+
+// scanStoredMessage reads a row of storedMessageColumns
+func scanStoredMessage(row interface{ Scan(...interface{}) error }) (*types.StoredMessage, error) {
+	msg := &types.StoredMessage{}
+	var senderName, mediaType, filename, contextJSON sql.NullString
+	var editedAt, revokedAt sql.NullTime
+	err := row.Scan(&msg.ID, &msg.ChatJID, &msg.Sender, &senderName, &msg.Content, &msg.Timestamp, &msg.IsFromMe,
+		&mediaType, &filename, &msg.MetadataOnly, &contextJSON, &msg.Kept, &editedAt, &revokedAt)
+	if err != nil {
+		return nil, err
+	}
+	if editedAt.Valid {
+		msg.Edited, msg.EditedAt = true, &editedAt.Time
+	}
+	if revokedAt.Valid {
+		msg.Revoked, msg.RevokedAt = true, &revokedAt.Time
+	}
+
+	msg.SenderName = senderName.String
+	if msg.SenderName == "" {
+		msg.SenderName = msg.Sender
+	}
+	msg.MediaType = mediaType.String
+	msg.Filename = filename.String
+	if contextJSON.String != "" {
+		msg.Context = &types.MessageContext{}
+		if err := json.Unmarshal([]byte(contextJSON.String), msg.Context); err != nil {
+			return nil, fmt.Errorf("failed to decode message context: %v", err)
+		}
+	}
+	return msg, nil
+}
+
+// GetMessage returns one message by chat and ID, or nil if it is not
+// stored. Archived messages are found too.
+func (store *MessageStore) GetMessage(chatJID, id string) (*types.StoredMessage, error) {
+	msg, err := store.getMessageFrom("messages", chatJID, id)
+	if msg != nil || err != nil {
+		return msg, err
+	}
+	table, err := store.archivedMessageTable(chatJID, id)
+	if table == "" || err != nil {
+		return nil, err
+	}
+	return store.getMessageFrom(table, chatJID, id)
+}
+
+func (store *MessageStore) getMessageFrom(table, chatJID, id string) (*types.StoredMessage, error) {
+	msg, err := scanStoredMessage(store.db.QueryRow(
+		`SELECT `+storedMessageColumns+fromSource(table)+` WHERE chat_jid = ? AND id = ?`,
+		chatJID, id,
+	))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message: %v", err)
+	}
+	return msg, nil
+}
+
+// messageQueryWhere builds the WHERE clause selecting the messages q matches
+func messageQueryWhere(q types.MessageQuery) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+
+	if q.ChatJID != "" {
+		conds = append(conds, "chat_jid = ?")
+		args = append(args, q.ChatJID)
+	}
+	if q.Sender != "" {
+		// Senders are stored by user, without the server
+		sender, _, _ := strings.Cut(q.Sender, "@")
+		conds = append(conds, "sender = ?")
+		args = append(args, sender)
+	}
+	switch q.MediaType {
+	case "":
+	case "text":
+		conds = append(conds, "COALESCE(media_type, '') = ''")
+	default:
+		conds = append(conds, "media_type = ?")
+		args = append(args, q.MediaType)
+	}
+	if q.IsFromMe != nil {
+		conds = append(conds, "is_from_me = ?")
+		args = append(args, *q.IsFromMe)
+	}
+	if !q.Since.IsZero() {
+		conds = append(conds, "timestamp >= ?")
+		args = append(args, q.Since.UTC())
+	}
+	if !q.Until.IsZero() {
+		conds = append(conds, "timestamp < ?")
+		args = append(args, q.Until.UTC())
+	}
+	if q.AfterSeq > 0 {
+		// Insertion order rather than timestamps, so a message delivered
+		// late with an older timestamp is still read
+		conds = append(conds, "rowid > ?")
+		args = append(args, q.AfterSeq)
+	}
+
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// MessageSeq returns the sequence a message was stored in, for
+// MessageQuery.AfterSeq, or 0 if it is not in the live table
+func (store *MessageStore) MessageSeq(chatJID, id string) (int64, error) {
+	var seq int64
+	err := store.db.QueryRow(`SELECT rowid FROM messages WHERE chat_jid = ? AND id = ?`, chatJID, id).Scan(&seq)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get message: %v", err)
+	}
+	return seq, nil
+}
+
+// QueryMessages returns the stored messages q selects, newest first unless
+// q.Order is set. Messages after q.AfterSeq come in the order they were
+// stored, so a client reading new messages can continue from the last one
+// it got without missing any delivered late.
+func (store *MessageStore) QueryMessages(q types.MessageQuery) ([]*types.StoredMessage, error) {
+	defaultOrder, tiebreak := "timestamp DESC", "id DESC"
+	if q.AfterSeq > 0 {
+		defaultOrder, tiebreak = "rowid ASC", "id ASC"
+	}
+	order, err := orderBy(q.Order, MessageSortFields, defaultOrder, tiebreak)
+	if err != nil {
+		return nil, err
+	}
+	sources, err := store.messageSources(q)
+	if err != nil {
+		return nil, err
+	}
+	where, whereArgs := messageQueryWhere(q)
+	parts := make([]string, len(sources))
+	var args []interface{}
+	for i, table := range sources {
+		parts[i] = `SELECT ` + storedMessageColumns + fromSource(table) + where
+		args = append(args, whereArgs...)
+	}
+	query := strings.Join(parts, " UNION ALL ") + order
+	if q.Limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, q.Limit, q.Offset)
+	}
+
+	rows, err := store.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query messages: %v", err)
+	}
+	defer rows.Close()
+
+	messages := []*types.StoredMessage{}
+	for rows.Next() {
+		msg, err := scanStoredMessage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan message: %v", err)
+		}
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
+
+// CountMessages counts all the stored messages q selects, ignoring its
+// limit and offset
+func (store *MessageStore) CountMessages(q types.MessageQuery) (int, error) {
+	sources, err := store.messageSources(q)
+	if err != nil {
+		return 0, err
+	}
+	where, whereArgs := messageQueryWhere(q)
+	parts := make([]string, len(sources))
+	var args []interface{}
+	for i, table := range sources {
+		parts[i] = `SELECT COUNT(*) AS n` + fromSource(table) + where
+		args = append(args, whereArgs...)
+	}
+	var count int
+	if err := store.db.QueryRow(`SELECT SUM(n) FROM (`+strings.Join(parts, " UNION ALL ")+`)`, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count messages: %v", err)
+	}
+	return count, nil
+}
+
+// GetMessageMedia returns what is needed to download a stored message's
+// media, or nil if the message is not stored or has no downloadable media.
+// Archived messages are found too.
+func (store *MessageStore) GetMessageMedia(chatJID, id string) (*types.MessageMedia, error) {
+	media, found, err := store.getMessageMediaFrom("messages", chatJID, id)
+	if found || err != nil {
+		return media, err
+	}
+	table, err := store.archivedMessageTable(chatJID, id)
+	if table == "" || err != nil {
+		return nil, err
+	}
+	media, _, err = store.getMessageMediaFrom(table, chatJID, id)
+	return media, err
+}
+
+func (store *MessageStore) getMessageMediaFrom(table, chatJID, id string) (*types.MessageMedia, bool, error) {
+	media := &types.MessageMedia{ChatJID: chatJID, MessageID: id}
+	var mediaType, filename, url, localPath sql.NullString
+	var fileLength sql.NullInt64
+	err := store.db.QueryRow(
+		`SELECT media_type, filename, url, media_key, file_sha256, file_enc_sha256, file_length, local_path
+		 FROM `+table+` WHERE chat_jid = ? AND id = ?`,
+		chatJID, id,
+	).Scan(&mediaType, &filename, &url, &media.MediaKey, &media.FileSHA256, &media.FileEncSHA256, &fileLength, &localPath)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get message media: %v", err)
+	}
+	if mediaType.String == "" || url.String == "" || len(media.MediaKey) == 0 {
+		return nil, true, nil
+	}
+
+	media.MediaType = mediaType.String
+	media.Filename = filename.String
+	media.URL = url.String
+	media.FileLength = uint64(fileLength.Int64)
+	media.LocalPath = localPath.String
+	return media, true, nil
+}
+
+// FindDownloadedMedia returns the media of the newest message with this ID
+// whose media has been downloaded, for callers that do not know its chat.
+// It returns nil if there is none.
+func (store *MessageStore) FindDownloadedMedia(id string) (*types.MessageMedia, error) {
+	var chatJID string
+	err := store.db.QueryRow(
+		`SELECT chat_jid FROM messages WHERE id = ? AND local_path IS NOT NULL AND local_path != ''
+		 ORDER BY timestamp DESC LIMIT 1`,
+		id,
+	).Scan(&chatJID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find message media: %v", err)
+	}
+	return store.GetMessageMedia(chatJID, id)
+}
+
+// SetMediaPath records where a message's media was downloaded to, whether
+// the message is archived or not
+func (store *MessageStore) SetMediaPath(chatJID, id, path string) error {
+	res, err := store.db.Exec(`UPDATE messages SET local_path = ? WHERE chat_jid = ? AND id = ?`, path, chatJID, id)
+	if err != nil {
+		return fmt.Errorf("failed to set media path: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return nil
+	}
+	table, err := store.archivedMessageTable(chatJID, id)
+	if table == "" || err != nil {
+		return err
+	}
+	if _, err := store.db.Exec(`UPDATE `+table+` SET local_path = ? WHERE chat_jid = ? AND id = ?`, path, chatJID, id); err != nil {
+		return fmt.Errorf("failed to set media path: %v", err)
+	}
+	return nil
+}
+
 // GetMessageCount returns total message count.
 func (store *MessageStore) GetMessageCount() (int, error) {
 	var count int
@@ -77,6 +421,17 @@ func (store *MessageStore) GetMessageCount() (int, error) {
 	return count, err
 }
 
+// GetLastMessageTime returns the timestamp of the newest stored message, or
+// the zero time when there are none.
+func (store *MessageStore) GetLastMessageTime() (time.Time, error) {
+	var last time.Time
+	err := store.db.QueryRow("SELECT timestamp FROM messages ORDER BY timestamp DESC LIMIT 1").Scan(&last)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	return last, err
+}
+
 // GetChatCount returns total chat count.
 func (store *MessageStore) GetChatCount() (int, error) {
 	var count int
@@ -84,6 +439,67 @@ func (store *MessageStore) GetChatCount() (int, error) {
 	return count, err
 }
 
+// ChatPreviewLength is how many characters of a chat's last message
+// ListChats keeps
+const ChatPreviewLength = 100
+
+// ListChats returns a page of the chat list, most recently active first
+// unless order is set, with each chat's stored message count and newest
+// message. limit <= 0 returns every chat.
+func (store *MessageStore) ListChats(limit, offset int, order types.ListOrder) ([]types.ChatSummary, error) {
+	orderClause, err := orderBy(order, ChatSortFields, "c.last_message_time DESC", "c.jid")
+	if err != nil {
+		return nil, err
+	}
+	query := `SELECT c.jid, c.name, c.last_message_time,
+			(SELECT COUNT(*) FROM messages WHERE chat_jid = c.jid) AS message_count,
+			m.id, m.sender, m.sender_name, substr(m.content, 1, ?), m.timestamp, m.is_from_me, m.media_type
+		FROM chats c
+		LEFT JOIN messages m ON m.rowid = (
+			SELECT rowid FROM messages WHERE chat_jid = c.jid ORDER BY timestamp DESC, id DESC LIMIT 1)` + orderClause
+	args := []interface{}{ChatPreviewLength}
+	if limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, limit, offset)
+	}
+
+	rows, err := store.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chats: %v", err)
+	}
+	defer rows.Close()
+
+	chats := []types.ChatSummary{}
+	for rows.Next() {
+		var chat types.ChatSummary
+		var name, id, sender, senderName, content, mediaType sql.NullString
+		var lastMessageTime, timestamp sql.NullTime
+		var isFromMe sql.NullBool
+		if err := rows.Scan(&chat.JID, &name, &lastMessageTime, &chat.MessageCount,
+			&id, &sender, &senderName, &content, &timestamp, &isFromMe, &mediaType); err != nil {
+			return nil, fmt.Errorf("failed to scan chat: %v", err)
+		}
+		chat.Name = name.String
+		chat.IsGroup = strings.HasSuffix(chat.JID, "@g.us")
+		if lastMessageTime.Valid {
+			chat.LastMessageTime = &lastMessageTime.Time
+		}
+		if id.Valid {
+			chat.LastMessage = &types.ChatPreview{
+				ID:         id.String,
+				Sender:     sender.String,
+				SenderName: senderName.String,
+				Content:    content.String,
+				Timestamp:  timestamp.Time,
+				IsFromMe:   isFromMe.Bool,
+				MediaType:  mediaType.String,
+			}
+		}
+		chats = append(chats, chat)
+	}
+	return chats, rows.Err()
+}
+
 // GetChats gets all chats
 func (store *MessageStore) GetChats() (map[string]time.Time, error) {
 	rows, err := store.db.Query("SELECT jid, last_message_time FROM chats ORDER BY last_message_time DESC")
@@ -0,0 +1,387 @@
+package database
+
+import (
+	"database/sql"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"whatsapp-bridge/internal/types"
+)
+
+func TestStoreMessageMetadata(t *testing.T) {
+	tempDB := "test_messages.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+	chat := "123@s.whatsapp.net"
+	if err := store.StoreChat(chat, "Alice", time.Now()); err != nil {
+		t.Fatalf("Failed to store chat: %v", err)
+	}
+
+	if err := store.StoreMessageMetadata("MSG1", chat, "123", "Alice", time.Now(), false, "image", 2048); err != nil {
+		t.Fatalf("Failed to store message metadata: %v", err)
+	}
+
+	var content, filename, url string
+	var mediaType string
+	var mediaKey []byte
+	var fileLength uint64
+	var metadataOnly bool
+	err = db.QueryRow(`SELECT content, media_type, filename, url, media_key, file_length, metadata_only
+		FROM messages WHERE id = 'MSG1'`).Scan(&content, &mediaType, &filename, &url, &mediaKey, &fileLength, &metadataOnly)
+	if err != nil {
+		t.Fatalf("Failed to read message: %v", err)
+	}
+
+	if content != "" || filename != "" || url != "" || mediaKey != nil {
+		t.Errorf("content kept: content=%q filename=%q url=%q media_key=%v", content, filename, url, mediaKey)
+	}
+	if mediaType != "image" || fileLength != 2048 || !metadataOnly {
+		t.Errorf("metadata = (%q, %d, %v), want (image, 2048, true)", mediaType, fileLength, metadataOnly)
+	}
+
+	got, err := store.GetMessage(chat, "MSG1")
+	if err != nil {
+		t.Fatalf("GetMessage: %v", err)
+	}
+	if got == nil || !got.MetadataOnly || got.MediaType != "image" || got.Content != "" {
+		t.Errorf("GetMessage = %+v, want metadata-only image without content", got)
+	}
+	if missing, err := store.GetMessage(chat, "MISSING"); err != nil || missing != nil {
+		t.Errorf("GetMessage(missing) = %+v, %v, want nil, nil", missing, err)
+	}
+
+	// Metadata-only rows still show up in the chat history
+	messages, err := store.GetMessages(chat, 10)
+	if err != nil {
+		t.Fatalf("GetMessages: %v", err)
+	}
+	if len(messages) != 1 || messages[0].SenderName != "Alice" {
+		t.Errorf("GetMessages = %+v, want one message from Alice", messages)
+	}
+
+	// Without its keys the image cannot be downloaded
+	if media, err := store.GetMessageMedia(chat, "MSG1"); err != nil || media != nil {
+		t.Errorf("GetMessageMedia(metadata only) = %+v, %v, want nil", media, err)
+	}
+	if err := store.StoreMessage("MSG2", chat, "123", "Alice", "", time.Now(), false, "document", "q3.pdf",
+		"https://mmg.whatsapp.net/d", []byte{1, 2}, []byte{3}, []byte{4}, 4096, nil); err != nil {
+		t.Fatalf("Failed to store message: %v", err)
+	}
+	media, err := store.GetMessageMedia(chat, "MSG2")
+	if err != nil || media == nil || media.Filename != "q3.pdf" || len(media.MediaKey) != 2 || media.FileLength != 4096 {
+		t.Errorf("GetMessageMedia = %+v, %v, want the stored document", media, err)
+	}
+
+	if err := store.SetMediaPath(chat, "MSG2", "store/media/chat/MSG2_q3.pdf"); err != nil {
+		t.Fatalf("SetMediaPath: %v", err)
+	}
+	if media, _ := store.GetMessageMedia(chat, "MSG2"); media == nil || media.LocalPath != "store/media/chat/MSG2_q3.pdf" {
+		t.Errorf("GetMessageMedia after download = %+v, want the local path", media)
+	}
+	if media, err := store.FindDownloadedMedia("MSG2"); err != nil || media == nil || media.ChatJID != chat {
+		t.Errorf("FindDownloadedMedia(MSG2) = %+v, %v, want the document in %s", media, err, chat)
+	}
+	if media, err := store.FindDownloadedMedia("MSG1"); err != nil || media != nil {
+		t.Errorf("FindDownloadedMedia(MSG1) = %+v, %v, want nil for media never downloaded", media, err)
+	}
+
+	// History sync stores the message again: it keeps its file and its place
+	seq, err := store.MessageSeq(chat, "MSG2")
+	if err != nil || seq == 0 {
+		t.Fatalf("MessageSeq = %d, %v", seq, err)
+	}
+	if err := store.StoreMessage("MSG2", chat, "123", "Alice", "", time.Now(), false, "document", "q3.pdf",
+		"https://mmg.whatsapp.net/d", []byte{1, 2}, []byte{3}, []byte{4}, 4096, nil); err != nil {
+		t.Fatalf("Failed to store message again: %v", err)
+	}
+	if media, _ := store.GetMessageMedia(chat, "MSG2"); media == nil || media.LocalPath != "store/media/chat/MSG2_q3.pdf" {
+		t.Errorf("GetMessageMedia after storing again = %+v, want the local path kept", media)
+	}
+	if again, err := store.MessageSeq(chat, "MSG2"); err != nil || again != seq {
+		t.Errorf("MessageSeq after storing again = %d, %v, want %d", again, err, seq)
+	}
+}
+
+func TestStoreMessageContext(t *testing.T) {
+	tempDB := "test_message_context.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+	chat := "123@s.whatsapp.net"
+	if err := store.StoreChat(chat, "Alice", time.Now()); err != nil {
+		t.Fatalf("Failed to store chat: %v", err)
+	}
+
+	mc := &types.MessageContext{
+		Mentions:   []string{"456@s.whatsapp.net"},
+		Formatting: []types.TextSpan{{Style: types.StyleBold, Start: 1, End: 4}},
+		Quoted:     &types.QuotedMessage{ID: "Q1", Sender: "456@s.whatsapp.net", ChatJID: chat},
+	}
+	if err := store.StoreMessage("MSG1", chat, "123", "Alice", "*hey* @456", time.Now(), false, "", "", "", nil, nil, nil, 0, mc); err != nil {
+		t.Fatalf("StoreMessage: %v", err)
+	}
+	if err := store.StoreMessage("MSG2", chat, "123", "Alice", "plain", time.Now(), false, "", "", "", nil, nil, nil, 0, nil); err != nil {
+		t.Fatalf("StoreMessage: %v", err)
+	}
+
+	got, err := store.GetMessage(chat, "MSG1")
+	if err != nil {
+		t.Fatalf("GetMessage: %v", err)
+	}
+	if !reflect.DeepEqual(got.Context, mc) {
+		t.Errorf("context = %+v, want %+v", got.Context, mc)
+	}
+
+	plain, err := store.GetMessage(chat, "MSG2")
+	if err != nil {
+		t.Fatalf("GetMessage: %v", err)
+	}
+	if plain.Context != nil {
+		t.Errorf("plain message context = %+v, want nil", plain.Context)
+	}
+}
+
+func TestNormalizeTimestamps(t *testing.T) {
+	tempDB := "test_timestamps.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	// Rows written in local zones: as strings the later-looking one is older
+	chat := "123@s.whatsapp.net"
+	if _, err := db.Exec(`INSERT INTO chats (jid, name, last_message_time) VALUES (?, 'Alice', '2024-03-10 01:30:00-05:00')`, chat); err != nil {
+		t.Fatalf("Failed to insert chat: %v", err)
+	}
+	for id, ts := range map[string]string{"NEWER": "2024-03-10 01:30:00-05:00", "OLDER": "2024-03-10 03:00:00+01:00"} {
+		if _, err := db.Exec(`INSERT INTO messages (id, chat_jid, sender, content, timestamp) VALUES (?, ?, '123', 'hi', ?)`, id, chat, ts); err != nil {
+			t.Fatalf("Failed to insert message: %v", err)
+		}
+	}
+
+	if err := normalizeTimestamps(db); err != nil {
+		t.Fatalf("normalizeTimestamps: %v", err)
+	}
+
+	var raw string
+	if err := db.QueryRow(`SELECT CAST(timestamp AS TEXT) FROM messages WHERE id = 'NEWER'`).Scan(&raw); err != nil {
+		t.Fatalf("Failed to read message: %v", err)
+	}
+	if raw != "2024-03-10 06:30:00+00:00" {
+		t.Errorf("normalized timestamp = %q, want 2024-03-10 06:30:00+00:00", raw)
+	}
+
+	store := &MessageStore{db: db}
+	last, err := store.GetLastMessageTime()
+	if err != nil {
+		t.Fatalf("GetLastMessageTime: %v", err)
+	}
+	if want := time.Date(2024, 3, 10, 6, 30, 0, 0, time.UTC); !last.Equal(want) {
+		t.Errorf("GetLastMessageTime = %v, want %v", last, want)
+	}
+
+	// Already normalized rows are left alone
+	if err := normalizeTimestamps(db); err != nil {
+		t.Fatalf("normalizeTimestamps (second run): %v", err)
+	}
+	if err := db.QueryRow(`SELECT CAST(timestamp AS TEXT) FROM messages WHERE id = 'NEWER'`).Scan(&raw); err != nil || raw != "2024-03-10 06:30:00+00:00" {
+		t.Errorf("second run changed timestamp to %q (err %v)", raw, err)
+	}
+}
+
+func TestQueryMessages(t *testing.T) {
+	tempDB := "test_query_messages.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+	group, direct := "120363000000000000@g.us", "111@s.whatsapp.net"
+	for _, chat := range []string{group, direct} {
+		if err := store.StoreChat(chat, chat, time.Now()); err != nil {
+			t.Fatalf("Failed to store chat: %v", err)
+		}
+	}
+
+	day := func(d int) time.Time { return time.Date(2024, 6, d, 9, 0, 0, 0, time.UTC) }
+	messages := []struct {
+		id, chat, sender string
+		fromMe           bool
+		mediaType        string
+		at               time.Time
+	}{
+		{"M1", group, "111", false, "", day(1)},
+		{"M2", group, "222", false, "image", day(2)},
+		{"M3", direct, "111", false, "", day(3)},
+		{"M4", direct, "999", true, "", day(4)},
+		{"M5", group, "111", false, "image", day(5)},
+	}
+	for _, m := range messages {
+		if err := store.StoreMessage(m.id, m.chat, m.sender, "", "text "+m.id, m.at, m.fromMe, m.mediaType, "", "", nil, nil, nil, 0, nil); err != nil {
+			t.Fatalf("Failed to store message: %v", err)
+		}
+	}
+
+	// Delivered late, stored last with the oldest timestamp
+	if err := store.StoreMessage("M0", direct, "111", "", "text M0", day(1).Add(-time.Hour), false, "", "", "", nil, nil, nil, 0, nil); err != nil {
+		t.Fatalf("Failed to store message: %v", err)
+	}
+	seq := func(id, chat string) int64 {
+		n, err := store.MessageSeq(chat, id)
+		if err != nil || n == 0 {
+			t.Fatalf("MessageSeq(%s) = %d, %v", id, n, err)
+		}
+		return n
+	}
+
+	fromMe := true
+	tests := []struct {
+		name    string
+		q       types.MessageQuery
+		wantIDs []string
+	}{
+		{"all newest first", types.MessageQuery{}, []string{"M5", "M4", "M3", "M2", "M1", "M0"}},
+		{"chat", types.MessageQuery{ChatJID: group}, []string{"M5", "M2", "M1"}},
+		{"sender by JID", types.MessageQuery{Sender: "111@s.whatsapp.net"}, []string{"M5", "M3", "M1", "M0"}},
+		{"media", types.MessageQuery{MediaType: "image"}, []string{"M5", "M2"}},
+		{"text only", types.MessageQuery{ChatJID: group, MediaType: "text"}, []string{"M1"}},
+		{"from me", types.MessageQuery{IsFromMe: &fromMe}, []string{"M4"}},
+		{"date range", types.MessageQuery{Since: day(2), Until: day(4)}, []string{"M3", "M2"}},
+		{"page", types.MessageQuery{Limit: 2, Offset: 1}, []string{"M4", "M3"}},
+		{"after in stored order", types.MessageQuery{AfterSeq: seq("M2", group)}, []string{"M3", "M4", "M5", "M0"}},
+		{"after the newest", types.MessageQuery{AfterSeq: seq("M5", group), Limit: 2}, []string{"M0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.QueryMessages(tt.q)
+			if err != nil {
+				t.Fatalf("QueryMessages: %v", err)
+			}
+			var ids []string
+			for _, msg := range got {
+				ids = append(ids, msg.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("QueryMessages = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+
+	if n, err := store.CountMessages(types.MessageQuery{ChatJID: group, Limit: 1}); err != nil || n != 3 {
+		t.Errorf("CountMessages = %d, %v, want 3 regardless of the limit", n, err)
+	}
+}
+
+func TestListChats(t *testing.T) {
+	tempDB := "test_list_chats.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+	day := func(d int) time.Time { return time.Date(2024, 6, d, 9, 0, 0, 0, time.UTC) }
+	group, direct, quiet := "120363000000000000@g.us", "111@s.whatsapp.net", "333@s.whatsapp.net"
+	if err := store.StoreChat(group, "Team", day(5)); err != nil {
+		t.Fatalf("Failed to store chat: %v", err)
+	}
+	if err := store.StoreChat(direct, "Ana", day(3)); err != nil {
+		t.Fatalf("Failed to store chat: %v", err)
+	}
+	if err := store.StoreChat(quiet, "", day(1)); err != nil {
+		t.Fatalf("Failed to store chat: %v", err)
+	}
+
+	long := strings.Repeat("é", ChatPreviewLength+20)
+	for _, m := range []struct {
+		id, chat, content string
+		at                time.Time
+	}{
+		{"M1", group, "first", day(4)},
+		{"M2", group, long, day(5)},
+		{"M3", direct, "hi", day(3)},
+	} {
+		if err := store.StoreMessage(m.id, m.chat, "111", "Ana", m.content, m.at, false, "", "", "", nil, nil, nil, 0, nil); err != nil {
+			t.Fatalf("Failed to store message: %v", err)
+		}
+	}
+
+	chats, err := store.ListChats(0, 0, types.ListOrder{})
+	if err != nil {
+		t.Fatalf("ListChats: %v", err)
+	}
+	if len(chats) != 3 || chats[0].JID != group || chats[1].JID != direct || chats[2].JID != quiet {
+		t.Fatalf("ListChats = %+v, want Team, Ana, then the quiet chat", chats)
+	}
+
+	team := chats[0]
+	if !team.IsGroup || team.Name != "Team" || team.MessageCount != 2 || !team.LastMessageTime.Equal(day(5)) {
+		t.Errorf("Team = %+v", team)
+	}
+	if team.LastMessage == nil || team.LastMessage.ID != "M2" || team.LastMessage.Content != long[:2*ChatPreviewLength] {
+		t.Errorf("Team last message = %+v, want M2 cut to %d characters", team.LastMessage, ChatPreviewLength)
+	}
+	if quiet := chats[2]; quiet.MessageCount != 0 || quiet.LastMessage != nil {
+		t.Errorf("quiet chat = %+v, want no messages", quiet)
+	}
+
+	page, _ := store.ListChats(1, 1, types.ListOrder{})
+	if len(page) != 1 || page[0].JID != direct || page[0].LastMessage.Content != "hi" {
+		t.Errorf("second page = %+v, want Ana", page)
+	}
+
+	byCount, err := store.ListChats(0, 0, types.ListOrder{Field: "message_count", Desc: true})
+	if err != nil || len(byCount) != 3 || byCount[0].JID != group || byCount[2].JID != quiet {
+		t.Errorf("ListChats by message count = %+v, %v", byCount, err)
+	}
+	if _, err := store.ListChats(0, 0, types.ListOrder{Field: "content"}); err == nil {
+		t.Error("sorting by an unsortable field succeeded")
+	}
+}
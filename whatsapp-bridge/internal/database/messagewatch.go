@@ -0,0 +1,38 @@
+package database
+
+// SubscribeMessages registers a listener that is signalled whenever this
+// store saves a message. Signals coalesce, so a listener that is busy gets
+// one for any number of messages. The returned cancel function must be
+// called to release the subscription.
+//
+// Only writes through this store signal; a read replica sees the writer's
+// messages without being told, so listeners there must also re-check.
+func (store *MessageStore) SubscribeMessages() (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	store.subsMutex.Lock()
+	if store.messageSubs == nil {
+		store.messageSubs = make(map[chan struct{}]struct{})
+	}
+	store.messageSubs[ch] = struct{}{}
+	store.subsMutex.Unlock()
+
+	cancel := func() {
+		store.subsMutex.Lock()
+		defer store.subsMutex.Unlock()
+		delete(store.messageSubs, ch)
+	}
+	return ch, cancel
+}
+
+// notifyMessageStored signals every listener without blocking
+func (store *MessageStore) notifyMessageStored() {
+	store.subsMutex.Lock()
+	defer store.subsMutex.Unlock()
+	for ch := range store.messageSubs {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
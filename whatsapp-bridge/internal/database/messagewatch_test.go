@@ -0,0 +1,69 @@
+package database
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSubscribeMessages(t *testing.T) {
+	tempDB := "test_message_watch.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+	stored, cancel := store.SubscribeMessages()
+
+	// Several messages coalesce into one signal
+	for _, id := range []string{"M1", "M2"} {
+		if err := store.StoreMessage(id, "111@s.whatsapp.net", "111", "", "hi", time.Now(), false, "", "", "", nil, nil, nil, 0, nil); err != nil {
+			t.Fatalf("Failed to store message: %v", err)
+		}
+	}
+	select {
+	case <-stored:
+	default:
+		t.Fatal("no signal after storing messages")
+	}
+	select {
+	case <-stored:
+		t.Error("second signal for messages already signalled")
+	default:
+	}
+
+	// A committed ingest signals too
+	in, err := store.BeginIngest()
+	if err != nil {
+		t.Fatalf("BeginIngest: %v", err)
+	}
+	if err := in.StoreMessage("M3", "111@s.whatsapp.net", "111", "", "hi", time.Now(), false, "", "", "", nil, nil, nil, 0, nil); err != nil {
+		t.Fatalf("Failed to store message: %v", err)
+	}
+	if err := in.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	select {
+	case <-stored:
+	default:
+		t.Error("no signal after an ingest")
+	}
+
+	// A cancelled listener is no longer signalled
+	cancel()
+	store.StoreMessageMetadata("M4", "111@s.whatsapp.net", "111", "", time.Now(), false, "image", 10)
+	select {
+	case <-stored:
+		t.Error("signal after cancel")
+	default:
+	}
+}
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"whatsapp-bridge/internal/tenant"
+	"whatsapp-bridge/internal/types"
+)
+
+// QueueOutboxJob stores a send entering the outbox and sets its ID
+func (store *MessageStore) QueueOutboxJob(job *types.OutboxJob) error {
+	if job.CreatedAt.IsZero() {
+		job.CreatedAt = time.Now()
+	}
+	job.CreatedAt = job.CreatedAt.UTC()
+	job.Tenant = tenant.Owner(job.Tenant)
+
+	result, err := store.db.Exec(
+		`INSERT INTO outbox_jobs (priority, kind, recipient, tenant, payload, attempts, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		job.Priority, job.Kind, job.Recipient, job.Tenant, job.Payload, job.Attempts, job.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to queue outbox job: %v", err)
+	}
+	if job.ID, err = result.LastInsertId(); err != nil {
+		return fmt.Errorf("failed to get outbox job ID: %v", err)
+	}
+	return nil
+}
+
+// ListOutboxJobs returns the sends still in the outbox, oldest first
+func (store *MessageStore) ListOutboxJobs() ([]types.OutboxJob, error) {
+	rows, err := store.db.Query(
+		`SELECT id, priority, kind, recipient, tenant, payload, attempts, created_at FROM outbox_jobs ORDER BY id`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list outbox jobs: %v", err)
+	}
+	defer rows.Close()
+
+	var jobs []types.OutboxJob
+	for rows.Next() {
+		var j types.OutboxJob
+		if err := rows.Scan(&j.ID, &j.Priority, &j.Kind, &j.Recipient, &j.Tenant, &j.Payload, &j.Attempts, &j.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox job: %v", err)
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
+
+// SetOutboxJobAttempts records how often a queued send has been tried
+func (store *MessageStore) SetOutboxJobAttempts(id int64, attempts int) error {
+	if _, err := store.db.Exec(`UPDATE outbox_jobs SET attempts = ? WHERE id = ?`, attempts, id); err != nil {
+		return fmt.Errorf("failed to update outbox job: %v", err)
+	}
+	return nil
+}
+
+// FinishOutboxJob removes a send once it was sent or failed for good
+func (store *MessageStore) FinishOutboxJob(id int64) error {
+	if _, err := store.db.Exec(`DELETE FROM outbox_jobs WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to finish outbox job: %v", err)
+	}
+	return nil
+}
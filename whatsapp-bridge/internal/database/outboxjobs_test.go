@@ -0,0 +1,59 @@
+package database
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	"whatsapp-bridge/internal/types"
+)
+
+func TestOutboxJobs(t *testing.T) {
+	tempDB := "test_outbox_jobs.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+
+	first := &types.OutboxJob{Priority: "high", Kind: "message", Recipient: "123", Payload: `{"message":"hi"}`}
+	second := &types.OutboxJob{Priority: "low", Kind: "contacts", Recipient: "456", Tenant: "sales", Payload: `{"contacts":[]}`}
+	for _, job := range []*types.OutboxJob{first, second} {
+		if err := store.QueueOutboxJob(job); err != nil {
+			t.Fatalf("QueueOutboxJob: %v", err)
+		}
+	}
+	if first.ID == 0 || second.ID <= first.ID {
+		t.Fatalf("IDs = %d, %d", first.ID, second.ID)
+	}
+
+	if err := store.SetOutboxJobAttempts(first.ID, 2); err != nil {
+		t.Fatalf("SetOutboxJobAttempts: %v", err)
+	}
+
+	jobs, err := store.ListOutboxJobs()
+	if err != nil {
+		t.Fatalf("ListOutboxJobs: %v", err)
+	}
+	if len(jobs) != 2 || jobs[0].ID != first.ID || jobs[0].Attempts != 2 || jobs[0].Tenant != "default" || jobs[0].Payload != first.Payload {
+		t.Fatalf("jobs = %+v", jobs)
+	}
+	if jobs[1].Kind != "contacts" || jobs[1].Tenant != "sales" || jobs[1].Priority != "low" {
+		t.Errorf("second job = %+v", jobs[1])
+	}
+
+	if err := store.FinishOutboxJob(first.ID); err != nil {
+		t.Fatalf("FinishOutboxJob: %v", err)
+	}
+	if jobs, _ := store.ListOutboxJobs(); len(jobs) != 1 || jobs[0].ID != second.ID {
+		t.Errorf("after finishing the first, jobs = %+v", jobs)
+	}
+}
@@ -0,0 +1,206 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"whatsapp-bridge/internal/tenant"
+	"whatsapp-bridge/internal/types"
+)
+
+// Outgoing message acknowledgment statuses
+const (
+	OutgoingPending   = "pending"
+	OutgoingServerAck = "server_ack"
+	OutgoingDelivered = "delivered"
+	OutgoingRead      = "read"
+	OutgoingFailed    = "failed"
+)
+
+// outgoingAdvancesFrom lists the statuses each status may replace. Receipts can
+// arrive out of order, so a status never moves backwards; an ack or receipt for
+// a message already marked failed means it did go through after all.
+var outgoingAdvancesFrom = map[string][]string{
+	OutgoingServerAck: {OutgoingPending, OutgoingFailed},
+	OutgoingDelivered: {OutgoingPending, OutgoingServerAck, OutgoingFailed},
+	OutgoingRead:      {OutgoingPending, OutgoingServerAck, OutgoingDelivered, OutgoingFailed},
+	OutgoingFailed:    {OutgoingPending},
+}
+
+// StoreOutgoingMessage records a message the bridge is about to send
+func (store *MessageStore) StoreOutgoingMessage(msg *types.OutgoingMessage) error {
+	now := time.Now().UTC()
+	msg.CreatedAt, msg.UpdatedAt = now, now
+
+	_, err := store.db.Exec(
+		`INSERT OR REPLACE INTO outgoing_messages (message_id, chat_jid, content, status, error, tenant, origin, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		msg.MessageID, msg.ChatJID, msg.Content, msg.Status, msg.Error, tenant.Owner(msg.Tenant), msg.Origin, now, now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store outgoing message: %v", err)
+	}
+	return nil
+}
+
+// UpdateOutgoingStatus moves an outgoing message to status if that is a step
+// forward. Returns false when the message is unknown or already further along.
+func (store *MessageStore) UpdateOutgoingStatus(messageID, status, errMsg string) (bool, error) {
+	from, ok := outgoingAdvancesFrom[status]
+	if !ok {
+		return false, fmt.Errorf("invalid outgoing status: %s", status)
+	}
+
+	args := []interface{}{status, errMsg, time.Now().UTC(), messageID}
+	for _, s := range from {
+		args = append(args, s)
+	}
+
+	result, err := store.db.Exec(
+		`UPDATE outgoing_messages SET status = ?, error = ?, updated_at = ?
+		 WHERE message_id = ? AND status IN (?`+strings.Repeat(", ?", len(from)-1)+`)`,
+		args...,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to update outgoing message: %v", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %v", err)
+	}
+	return rows > 0, nil
+}
+
+// GetOutgoingMessage retrieves an outgoing message by ID (nil if not found)
+func (store *MessageStore) GetOutgoingMessage(messageID string) (*types.OutgoingMessage, error) {
+	msg := &types.OutgoingMessage{}
+	var content, errMsg, origin sql.NullString
+
+	err := store.db.QueryRow(
+		`SELECT message_id, chat_jid, content, status, error, tenant, origin, created_at, updated_at
+		 FROM outgoing_messages WHERE message_id = ?`,
+		messageID,
+	).Scan(&msg.MessageID, &msg.ChatJID, &content, &msg.Status, &errMsg, &msg.Tenant, &origin, &msg.CreatedAt, &msg.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get outgoing message: %v", err)
+	}
+
+	msg.Content = content.String
+	msg.Error = errMsg.String
+	msg.Origin = origin.String
+	return msg, nil
+}
+
+// FailStalePendingMessages marks messages still pending since before cutoff as
+// failed and returns them.
+func (store *MessageStore) FailStalePendingMessages(cutoff time.Time, reason string) ([]*types.OutgoingMessage, error) {
+	rows, err := store.db.Query(
+		`SELECT message_id FROM outgoing_messages WHERE status = ? AND created_at < ?`,
+		OutgoingPending, cutoff.UTC(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pending messages: %v", err)
+	}
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan pending message: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	var failed []*types.OutgoingMessage
+	for _, id := range ids {
+		// Skip messages acked between the query and the update
+		updated, err := store.UpdateOutgoingStatus(id, OutgoingFailed, reason)
+		if err != nil {
+			return failed, err
+		}
+		if !updated {
+			continue
+		}
+
+		msg, err := store.GetOutgoingMessage(id)
+		if err != nil {
+			return failed, err
+		}
+		if msg != nil {
+			failed = append(failed, msg)
+		}
+	}
+
+	return failed, nil
+}
+
+// receiptWait is how long a send to a direct chat may go without a delivery
+// receipt before the recipient counts as unreached
+const receiptWait = time.Hour
+
+// GetSendStats summarises outgoing messages created since the given time. An
+// empty owner counts every tenant's sends. Cold recipients are direct chats
+// with no stored incoming message; unreached recipients are direct chats none
+// of whose sends were delivered within receiptWait, as when the recipient
+// has blocked the account.
+func (store *MessageStore) GetSendStats(owner string, since time.Time) (types.SendStats, error) {
+	var stats types.SendStats
+	since = since.UTC()
+
+	scope, args := "", []interface{}{since}
+	if owner != "" {
+		scope, args = " AND tenant = ?", append(args, owner)
+	}
+
+	err := store.db.QueryRow(
+		`SELECT COUNT(*), COALESCE(SUM(status = ?), 0), COUNT(DISTINCT chat_jid)
+		 FROM outgoing_messages WHERE created_at >= ?`+scope,
+		append([]interface{}{OutgoingFailed}, args...)...,
+	).Scan(&stats.Sent, &stats.Failed, &stats.Recipients)
+	if err != nil {
+		return stats, fmt.Errorf("failed to count outgoing messages: %v", err)
+	}
+
+	err = store.db.QueryRow(
+		`SELECT COUNT(DISTINCT o.chat_jid) FROM outgoing_messages o
+		 WHERE o.created_at >= ?`+scope+` AND o.chat_jid LIKE '%@s.whatsapp.net'
+		   AND NOT EXISTS (SELECT 1 FROM messages m WHERE m.chat_jid = o.chat_jid AND m.is_from_me = 0)`,
+		args...,
+	).Scan(&stats.ColdRecipients)
+	if err != nil {
+		return stats, fmt.Errorf("failed to count cold recipients: %v", err)
+	}
+
+	err = store.db.QueryRow(
+		`SELECT COUNT(*) FROM (
+		   SELECT chat_jid FROM outgoing_messages
+		   WHERE created_at >= ?`+scope+` AND created_at < ? AND chat_jid LIKE '%@s.whatsapp.net'
+		   GROUP BY chat_jid
+		   HAVING SUM(status = ?) > 0 AND SUM(status IN (?, ?)) = 0
+		 )`,
+		append(args, time.Now().UTC().Add(-receiptWait), OutgoingServerAck, OutgoingDelivered, OutgoingRead)...,
+	).Scan(&stats.UnreachedRecipients)
+	if err != nil {
+		return stats, fmt.Errorf("failed to count unreached recipients: %v", err)
+	}
+
+	err = store.db.QueryRow(
+		`SELECT COUNT(DISTINCT chat_jid) AS recipients FROM outgoing_messages
+		 WHERE created_at >= ?`+scope+` AND content IS NOT NULL AND content != ''
+		 GROUP BY content ORDER BY recipients DESC LIMIT 1`,
+		args...,
+	).Scan(&stats.MaxIdenticalRecipients)
+	if err != nil && err != sql.ErrNoRows {
+		return stats, fmt.Errorf("failed to count identical sends: %v", err)
+	}
+
+	return stats, nil
+}
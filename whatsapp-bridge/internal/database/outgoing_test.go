@@ -0,0 +1,201 @@
+package database
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"whatsapp-bridge/internal/types"
+)
+
+func TestOutgoingStatusProgression(t *testing.T) {
+	tempDB := "test_outgoing.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+
+	for _, id := range []string{"MSG1", "MSG2"} {
+		err := store.StoreOutgoingMessage(&types.OutgoingMessage{
+			MessageID: id,
+			ChatJID:   "123@s.whatsapp.net",
+			Content:   "hello",
+			Status:    OutgoingPending,
+		})
+		if err != nil {
+			t.Fatalf("Failed to store outgoing message: %v", err)
+		}
+	}
+
+	steps := []struct {
+		status  string
+		updated bool
+		want    string
+	}{
+		{OutgoingServerAck, true, OutgoingServerAck},
+		{OutgoingRead, true, OutgoingRead},
+		{OutgoingDelivered, false, OutgoingRead}, // late delivery receipt never downgrades
+		{OutgoingFailed, false, OutgoingRead},    // only pending messages can fail
+	}
+
+	for _, step := range steps {
+		updated, err := store.UpdateOutgoingStatus("MSG1", step.status, "")
+		if err != nil {
+			t.Fatalf("UpdateOutgoingStatus(%s) failed: %v", step.status, err)
+		}
+		if updated != step.updated {
+			t.Errorf("UpdateOutgoingStatus(%s) updated = %v, want %v", step.status, updated, step.updated)
+		}
+
+		msg, err := store.GetOutgoingMessage("MSG1")
+		if err != nil || msg == nil {
+			t.Fatalf("GetOutgoingMessage failed: %v", err)
+		}
+		if msg.Status != step.want {
+			t.Errorf("after %s status = %s, want %s", step.status, msg.Status, step.want)
+		}
+	}
+
+	// MSG2 is still pending and older than a cutoff in the future
+	failed, err := store.FailStalePendingMessages(time.Now().Add(time.Minute), "server ack timeout")
+	if err != nil {
+		t.Fatalf("FailStalePendingMessages failed: %v", err)
+	}
+	if len(failed) != 1 || failed[0].MessageID != "MSG2" {
+		t.Fatalf("Expected MSG2 to fail, got %+v", failed)
+	}
+	if failed[0].Status != OutgoingFailed || failed[0].Error != "server ack timeout" {
+		t.Errorf("Unexpected failed message: %+v", failed[0])
+	}
+
+	// A late ack still wins over the timeout
+	if updated, _ := store.UpdateOutgoingStatus("MSG2", OutgoingServerAck, ""); !updated {
+		t.Error("Expected server ack to replace failed status")
+	}
+}
+
+func TestGetSendStats(t *testing.T) {
+	tempDB := "test_send_stats.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+
+	sends := []struct {
+		id, chat, content, status, tenant string
+	}{
+		{"M1", "1@s.whatsapp.net", "sale today", OutgoingServerAck, ""},
+		{"M2", "2@s.whatsapp.net", "sale today", OutgoingServerAck, ""},
+		{"M3", "3@s.whatsapp.net", "sale today", OutgoingFailed, ""},
+		{"M4", "1@s.whatsapp.net", "thanks", OutgoingRead, ""},
+		{"M5", "team@g.us", "sale today", OutgoingServerAck, ""},
+		{"M6", "6@s.whatsapp.net", "crm follow-up", OutgoingServerAck, "crm"},
+	}
+	for _, s := range sends {
+		err := store.StoreOutgoingMessage(&types.OutgoingMessage{MessageID: s.id, ChatJID: s.chat, Content: s.content, Status: s.status, Tenant: s.tenant})
+		if err != nil {
+			t.Fatalf("Failed to store outgoing message: %v", err)
+		}
+	}
+
+	// Contact 2 never got a send delivered; the recent send to 6 may still be
+	if _, err := db.Exec(`UPDATE outgoing_messages SET created_at = ? WHERE message_id = 'M2'`, time.Now().UTC().Add(-2*time.Hour)); err != nil {
+		t.Fatalf("Failed to backdate send: %v", err)
+	}
+
+	// Contact 1 has written to the account, so only 2 and 3 are cold
+	if err := store.StoreChat("1@s.whatsapp.net", "One", time.Now()); err != nil {
+		t.Fatalf("Failed to store chat: %v", err)
+	}
+	err = store.StoreMessage("IN1", "1@s.whatsapp.net", "1", "One", "hi", time.Now(), false, "", "", "", nil, nil, nil, 0, nil)
+	if err != nil {
+		t.Fatalf("Failed to store message: %v", err)
+	}
+
+	since := time.Now().Add(-3 * time.Hour)
+	tests := []struct {
+		owner string
+		want  types.SendStats
+	}{
+		{"", types.SendStats{Sent: 6, Failed: 1, Recipients: 5, ColdRecipients: 3, UnreachedRecipients: 1, MaxIdenticalRecipients: 4}},
+		{"default", types.SendStats{Sent: 5, Failed: 1, Recipients: 4, ColdRecipients: 2, UnreachedRecipients: 1, MaxIdenticalRecipients: 4}},
+		{"crm", types.SendStats{Sent: 1, Recipients: 1, ColdRecipients: 1, MaxIdenticalRecipients: 1}},
+	}
+	for _, tt := range tests {
+		stats, err := store.GetSendStats(tt.owner, since)
+		if err != nil {
+			t.Fatalf("GetSendStats(%q) failed: %v", tt.owner, err)
+		}
+		if stats != tt.want {
+			t.Errorf("GetSendStats(%q) = %+v, want %+v", tt.owner, stats, tt.want)
+		}
+	}
+
+	stats, err := store.GetSendStats("", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GetSendStats failed: %v", err)
+	}
+	if stats != (types.SendStats{}) {
+		t.Errorf("GetSendStats() in the future = %+v, want zero", stats)
+	}
+}
+
+func TestAccountRestrictions(t *testing.T) {
+	tempDB := "test_account_restrictions.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	until := now.Add(15 * time.Minute)
+	restrictions := []types.AccountRestriction{
+		{Reason: "temporary_ban", Code: 104, Description: "old ban", Since: now.AddDate(0, 0, -10)},
+		{Reason: "rate_limited", Code: 429, Description: "rate limited", Since: now.Add(-time.Hour), Until: &until},
+		{Reason: "temporary_ban", Code: 101, Since: now},
+	}
+	for _, r := range restrictions {
+		if err := store.StoreAccountRestriction(r); err != nil {
+			t.Fatalf("StoreAccountRestriction failed: %v", err)
+		}
+	}
+
+	got, err := store.GetAccountRestrictions(now.AddDate(0, 0, -7))
+	if err != nil {
+		t.Fatalf("GetAccountRestrictions failed: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Expected the 2 restrictions of the last week, got %+v", got)
+	}
+	if got[0].Code != 429 || got[0].Until == nil || !got[0].Until.Equal(until) || got[1].Code != 101 || got[1].Until != nil {
+		t.Errorf("GetAccountRestrictions() = %+v", got)
+	}
+}
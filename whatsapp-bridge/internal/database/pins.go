@@ -0,0 +1,102 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"whatsapp-bridge/internal/types"
+)
+
+// MaxPinnedMessages is how many messages WhatsApp keeps pinned in one chat;
+// pinning another replaces the oldest pin
+const MaxPinnedMessages = 3
+
+// StorePinnedMessage records a pin, replacing an earlier pin of the same
+// message. Expired pins, and the oldest when the chat has more than
+// MaxPinnedMessages, are dropped.
+func (store *MessageStore) StorePinnedMessage(pin *types.PinnedMessage) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to store pin: %v", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.Exec(
+		`INSERT OR REPLACE INTO pinned_messages (chat_jid, message_id, sender_jid, pinned_by, pinned_at, expires_at)
+		 VALUES (?, ?, ?, ?, ?, ?)`,
+		pin.ChatJID, pin.MessageID, pin.SenderJID, pin.PinnedBy, pin.PinnedAt.UTC(), pin.ExpiresAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store pin: %v", err)
+	}
+
+	_, err = tx.Exec(
+		`DELETE FROM pinned_messages WHERE chat_jid = ? AND (expires_at <= ? OR message_id NOT IN (
+			SELECT message_id FROM pinned_messages WHERE chat_jid = ? ORDER BY pinned_at DESC LIMIT ?))`,
+		pin.ChatJID, time.Now().UTC(), pin.ChatJID, MaxPinnedMessages,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to prune pins: %v", err)
+	}
+	return tx.Commit()
+}
+
+// DeletePinnedMessage removes a message's pin. It reports whether the
+// message was pinned.
+func (store *MessageStore) DeletePinnedMessage(chatJID, messageID string) (bool, error) {
+	res, err := store.db.Exec(`DELETE FROM pinned_messages WHERE chat_jid = ? AND message_id = ?`, chatJID, messageID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete pin: %v", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// GetPinnedMessages returns a chat's pins that have not expired, newest
+// first, with the text of those in the archive
+func (store *MessageStore) GetPinnedMessages(chatJID string) ([]types.PinnedMessage, error) {
+	rows, err := store.db.Query(
+		`SELECT p.chat_jid, p.message_id, p.sender_jid, p.pinned_by, p.pinned_at, p.expires_at, m.content
+		 FROM pinned_messages p
+		 LEFT JOIN messages m ON m.chat_jid = p.chat_jid AND m.id = p.message_id
+		 WHERE p.chat_jid = ? AND p.expires_at > ?
+		 ORDER BY p.pinned_at DESC`,
+		chatJID, time.Now().UTC(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pins: %v", err)
+	}
+	defer rows.Close()
+
+	pins := []types.PinnedMessage{}
+	for rows.Next() {
+		var pin types.PinnedMessage
+		var sender, content sql.NullString
+		if err := rows.Scan(&pin.ChatJID, &pin.MessageID, &sender, &pin.PinnedBy, &pin.PinnedAt, &pin.ExpiresAt, &content); err != nil {
+			return nil, fmt.Errorf("failed to scan pin: %v", err)
+		}
+		pin.SenderJID = sender.String
+		pin.Content = content.String
+		pins = append(pins, pin)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	// Pinned messages since archived
+	for i := range pins {
+		if pins[i].Content != "" {
+			continue
+		}
+		msg, err := store.GetMessage(pins[i].ChatJID, pins[i].MessageID)
+		if err != nil {
+			return nil, err
+		}
+		if msg != nil {
+			pins[i].Content = msg.Content
+		}
+	}
+	return pins, nil
+}
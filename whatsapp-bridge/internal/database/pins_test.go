@@ -0,0 +1,89 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"whatsapp-bridge/internal/types"
+)
+
+func TestPinnedMessages(t *testing.T) {
+	tempDB := "test_pins.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+	chat := "120363000000000000@g.us"
+	if err := store.StoreChat(chat, "Team", time.Now()); err != nil {
+		t.Fatalf("Failed to store chat: %v", err)
+	}
+	if err := store.StoreMessage("M1", chat, "111", "Ana", "release at 5", time.Now(), false, "", "", "", nil, nil, nil, 0, nil); err != nil {
+		t.Fatalf("Failed to store message: %v", err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	pin := func(id string, age time.Duration) *types.PinnedMessage {
+		return &types.PinnedMessage{
+			ChatJID:   chat,
+			MessageID: id,
+			SenderJID: "111@s.whatsapp.net",
+			PinnedBy:  "222@s.whatsapp.net",
+			PinnedAt:  now.Add(-age),
+			ExpiresAt: now.Add(-age).Add(7 * 24 * time.Hour),
+		}
+	}
+
+	// A fourth pin replaces the oldest
+	for i := 1; i <= MaxPinnedMessages+1; i++ {
+		if err := store.StorePinnedMessage(pin(fmt.Sprintf("M%d", i), time.Duration(MaxPinnedMessages+1-i)*time.Minute)); err != nil {
+			t.Fatalf("StorePinnedMessage: %v", err)
+		}
+	}
+	pins, err := store.GetPinnedMessages(chat)
+	if err != nil {
+		t.Fatalf("GetPinnedMessages: %v", err)
+	}
+	if len(pins) != MaxPinnedMessages || pins[0].MessageID != "M4" || pins[len(pins)-1].MessageID != "M2" {
+		t.Fatalf("pins = %+v, want M4..M2 newest first", pins)
+	}
+
+	// Repinning the archived message brings its text along
+	if err := store.StorePinnedMessage(pin("M1", 0)); err != nil {
+		t.Fatalf("StorePinnedMessage: %v", err)
+	}
+	pins, _ = store.GetPinnedMessages(chat)
+	if pins[0].MessageID != "M1" || pins[0].Content != "release at 5" || !pins[0].PinnedAt.Equal(now) {
+		t.Errorf("newest pin = %+v, want M1 with its content", pins[0])
+	}
+
+	if ok, err := store.DeletePinnedMessage(chat, "M1"); err != nil || !ok {
+		t.Errorf("DeletePinnedMessage(M1) = %v, %v, want true", ok, err)
+	}
+	if ok, _ := store.DeletePinnedMessage(chat, "M1"); ok {
+		t.Error("DeletePinnedMessage(M1) again reported a pin")
+	}
+
+	// Expired pins are not listed
+	expired := pin("M9", 8*24*time.Hour)
+	if err := store.StorePinnedMessage(expired); err != nil {
+		t.Fatalf("StorePinnedMessage: %v", err)
+	}
+	pins, _ = store.GetPinnedMessages(chat)
+	for _, p := range pins {
+		if p.MessageID == "M9" {
+			t.Errorf("expired pin listed: %+v", p)
+		}
+	}
+}
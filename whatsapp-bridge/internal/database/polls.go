@@ -0,0 +1,123 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"whatsapp-bridge/internal/types"
+)
+
+// StorePoll records a poll created in a chat. A poll seen again, e.g. in a
+// history sync, is left as first stored.
+func (store *MessageStore) StorePoll(poll *types.Poll) error {
+	options, err := json.Marshal(poll.Options)
+	if err != nil {
+		return fmt.Errorf("failed to encode poll options: %v", err)
+	}
+
+	_, err = store.db.Exec(
+		`INSERT INTO polls (chat_jid, message_id, creator_jid, question, options, selectable_count, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT (chat_jid, message_id) DO NOTHING`,
+		poll.ChatJID, poll.MessageID, poll.CreatorJID, poll.Question, string(options), poll.SelectableCount, poll.CreatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store poll: %v", err)
+	}
+	return nil
+}
+
+// GetPoll returns the poll created by a message, or nil if it is not known.
+// An empty chatJID matches the poll in any chat.
+func (store *MessageStore) GetPoll(chatJID, messageID string) (*types.Poll, error) {
+	var poll types.Poll
+	var options string
+	err := store.db.QueryRow(
+		`SELECT chat_jid, message_id, creator_jid, question, options, selectable_count, created_at
+		 FROM polls WHERE message_id = ? AND (? = '' OR chat_jid = ?)
+		 ORDER BY created_at DESC LIMIT 1`,
+		messageID, chatJID, chatJID,
+	).Scan(&poll.ChatJID, &poll.MessageID, &poll.CreatorJID, &poll.Question, &options, &poll.SelectableCount, &poll.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get poll: %v", err)
+	}
+	if err := json.Unmarshal([]byte(options), &poll.Options); err != nil {
+		return nil, fmt.Errorf("failed to decode poll options: %v", err)
+	}
+	return &poll, nil
+}
+
+// StorePollVote records a voter's choice in a poll, replacing their earlier
+// one. Each vote carries the voter's whole selection, so only the latest
+// counts; an older vote arriving late, e.g. from a history sync, is ignored.
+func (store *MessageStore) StorePollVote(vote *types.PollVote) error {
+	options, err := json.Marshal(vote.Options)
+	if err != nil {
+		return fmt.Errorf("failed to encode poll vote: %v", err)
+	}
+
+	_, err = store.db.Exec(
+		`INSERT INTO poll_votes (chat_jid, poll_id, voter_jid, options, voted_at)
+		 VALUES (?, ?, ?, ?, ?)
+		 ON CONFLICT (chat_jid, poll_id, voter_jid) DO UPDATE SET
+			options = excluded.options,
+			voted_at = excluded.voted_at
+		 WHERE excluded.voted_at >= poll_votes.voted_at`,
+		vote.ChatJID, vote.PollID, vote.VoterJID, string(options), vote.VotedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store poll vote: %v", err)
+	}
+	return nil
+}
+
+// GetPollResults tallies the votes of a poll, in the order of its options
+// with voters in the order they voted. Votes for options the poll does not
+// have are left out.
+func (store *MessageStore) GetPollResults(poll *types.Poll) (*types.PollResults, error) {
+	rows, err := store.db.Query(
+		`SELECT voter_jid, options FROM poll_votes
+		 WHERE chat_jid = ? AND poll_id = ?
+		 ORDER BY voted_at, voter_jid`,
+		poll.ChatJID, poll.MessageID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query poll votes: %v", err)
+	}
+	defer rows.Close()
+
+	results := &types.PollResults{Poll: *poll, Results: make([]types.PollOptionResult, len(poll.Options))}
+	index := make(map[string]int, len(poll.Options))
+	for i, option := range poll.Options {
+		results.Results[i] = types.PollOptionResult{Option: option, Voters: []string{}}
+		index[option] = i
+	}
+
+	for rows.Next() {
+		var voter, encoded string
+		if err := rows.Scan(&voter, &encoded); err != nil {
+			return nil, fmt.Errorf("failed to scan poll vote: %v", err)
+		}
+		var options []string
+		if err := json.Unmarshal([]byte(encoded), &options); err != nil {
+			return nil, fmt.Errorf("failed to decode poll vote: %v", err)
+		}
+
+		counted := false
+		for _, option := range options {
+			if i, ok := index[option]; ok {
+				results.Results[i].Count++
+				results.Results[i].Voters = append(results.Results[i].Voters, voter)
+				counted = true
+			}
+		}
+		if counted {
+			results.Voters++
+		}
+	}
+	return results, rows.Err()
+}
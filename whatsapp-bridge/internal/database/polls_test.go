@@ -0,0 +1,101 @@
+package database
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"whatsapp-bridge/internal/types"
+)
+
+func TestPollVotes(t *testing.T) {
+	tempDB := "test_polls.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+	chat := "120363000000000000@g.us"
+	now := time.Now().UTC().Truncate(time.Second)
+
+	if poll, err := store.GetPoll(chat, "P1"); err != nil || poll != nil {
+		t.Fatalf("GetPoll before storing = %+v, %v", poll, err)
+	}
+
+	poll := &types.Poll{
+		ChatJID:         chat,
+		MessageID:       "P1",
+		CreatorJID:      "111@s.whatsapp.net",
+		Question:        "Lunch?",
+		Options:         []string{"Pizza", "Sushi", "Tacos"},
+		SelectableCount: 0,
+		CreatedAt:       now,
+	}
+	if err := store.StorePoll(poll); err != nil {
+		t.Fatalf("StorePoll: %v", err)
+	}
+
+	// A poll seen again keeps what was first stored
+	replay := *poll
+	replay.Question = "changed"
+	if err := store.StorePoll(&replay); err != nil {
+		t.Fatalf("StorePoll again: %v", err)
+	}
+	got, err := store.GetPoll("", "P1")
+	if err != nil || got == nil || got.Question != "Lunch?" || len(got.Options) != 3 || got.ChatJID != chat {
+		t.Fatalf("GetPoll = %+v, %v", got, err)
+	}
+
+	vote := func(voter string, at time.Duration, options ...string) {
+		t.Helper()
+		if err := store.StorePollVote(&types.PollVote{ChatJID: chat, PollID: "P1", VoterJID: voter, Options: options, VotedAt: now.Add(at)}); err != nil {
+			t.Fatalf("StorePollVote: %v", err)
+		}
+	}
+	vote("222@s.whatsapp.net", time.Minute, "Pizza", "Tacos")
+	vote("333@s.whatsapp.net", 2*time.Minute, "Pizza")
+	vote("444@s.whatsapp.net", 3*time.Minute, "Sushi")
+	// 444 changes their mind; a stale vote from 333 arriving late is ignored
+	vote("444@s.whatsapp.net", 4*time.Minute, "Tacos")
+	vote("333@s.whatsapp.net", time.Second, "Sushi")
+	// 555 votes, then takes it back
+	vote("555@s.whatsapp.net", time.Minute, "Sushi")
+	vote("555@s.whatsapp.net", 5*time.Minute)
+
+	results, err := store.GetPollResults(got)
+	if err != nil {
+		t.Fatalf("GetPollResults: %v", err)
+	}
+	if results.Voters != 3 {
+		t.Errorf("voters = %d, want 3", results.Voters)
+	}
+	want := map[string][]string{
+		"Pizza": {"222@s.whatsapp.net", "333@s.whatsapp.net"},
+		"Sushi": {},
+		"Tacos": {"222@s.whatsapp.net", "444@s.whatsapp.net"},
+	}
+	for i, r := range results.Results {
+		if r.Option != poll.Options[i] {
+			t.Errorf("result %d is %q, want %q", i, r.Option, poll.Options[i])
+		}
+		if r.Count != len(want[r.Option]) || len(r.Voters) != len(want[r.Option]) {
+			t.Errorf("%s = %+v, want voters %v", r.Option, r, want[r.Option])
+			continue
+		}
+		for j, voter := range want[r.Option] {
+			if r.Voters[j] != voter {
+				t.Errorf("%s voters = %v, want %v", r.Option, r.Voters, want[r.Option])
+				break
+			}
+		}
+	}
+}
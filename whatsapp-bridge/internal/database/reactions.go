@@ -0,0 +1,52 @@
+package database
+
+import (
+	"fmt"
+
+	"whatsapp-bridge/internal/types"
+)
+
+// StoreMessageReaction records someone's reaction to a message, replacing
+// their earlier one. A reaction taken back is kept with an empty emoji, so
+// an older reaction arriving late does not bring it back; only a newer
+// reaction replaces the stored one.
+func (store *MessageStore) StoreMessageReaction(r *types.MessageReaction) error {
+	_, err := store.db.Exec(
+		`INSERT INTO message_reactions (chat_jid, message_id, sender_jid, emoji, reacted_at)
+		 VALUES (?, ?, ?, ?, ?)
+		 ON CONFLICT (chat_jid, message_id, sender_jid) DO UPDATE SET
+			emoji = excluded.emoji,
+			reacted_at = excluded.reacted_at
+		 WHERE excluded.reacted_at >= message_reactions.reacted_at`,
+		r.ChatJID, r.MessageID, r.SenderJID, r.Emoji, r.ReactedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store reaction: %v", err)
+	}
+	return nil
+}
+
+// GetMessageReactions returns the reactions to a message, oldest first. An
+// empty chatJID matches the message in any chat.
+func (store *MessageStore) GetMessageReactions(chatJID, messageID string) ([]types.MessageReaction, error) {
+	rows, err := store.db.Query(
+		`SELECT chat_jid, message_id, sender_jid, emoji, reacted_at FROM message_reactions
+		 WHERE message_id = ? AND (? = '' OR chat_jid = ?) AND emoji != ''
+		 ORDER BY reacted_at, sender_jid`,
+		messageID, chatJID, chatJID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reactions: %v", err)
+	}
+	defer rows.Close()
+
+	reactions := []types.MessageReaction{}
+	for rows.Next() {
+		var r types.MessageReaction
+		if err := rows.Scan(&r.ChatJID, &r.MessageID, &r.SenderJID, &r.Emoji, &r.ReactedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan reaction: %v", err)
+		}
+		reactions = append(reactions, r)
+	}
+	return reactions, rows.Err()
+}
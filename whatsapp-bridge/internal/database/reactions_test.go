@@ -0,0 +1,67 @@
+package database
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"whatsapp-bridge/internal/types"
+)
+
+func TestMessageReactions(t *testing.T) {
+	tempDB := "test_reactions.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+	group := "120363000000000000@g.us"
+	ana, ben := "15550000001@s.whatsapp.net", "15550000002@s.whatsapp.net"
+	now := time.Now().UTC().Truncate(time.Second)
+
+	react := func(sender, emoji string, at time.Duration) {
+		t.Helper()
+		err := store.StoreMessageReaction(&types.MessageReaction{ChatJID: group, MessageID: "M1", SenderJID: sender, Emoji: emoji, ReactedAt: now.Add(at)})
+		if err != nil {
+			t.Fatalf("StoreMessageReaction: %v", err)
+		}
+	}
+	react(ana, "👍", 0)
+	react(ben, "😂", time.Second)
+	// Ana changes her reaction, then an older one arrives late
+	react(ana, "❤️", time.Minute)
+	react(ana, "😮", 30*time.Second)
+
+	reactions, err := store.GetMessageReactions(group, "M1")
+	if err != nil || len(reactions) != 2 {
+		t.Fatalf("GetMessageReactions = %+v, %v", reactions, err)
+	}
+	if reactions[0].SenderJID != ben || reactions[1].SenderJID != ana || reactions[1].Emoji != "❤️" || !reactions[1].ReactedAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("reactions = %+v", reactions)
+	}
+
+	// Any chat matches when none is given
+	if reactions, err := store.GetMessageReactions("", "M1"); err != nil || len(reactions) != 2 {
+		t.Errorf("any chat = %+v, %v", reactions, err)
+	}
+	if reactions, err := store.GetMessageReactions("15550000003@s.whatsapp.net", "M1"); err != nil || len(reactions) != 0 {
+		t.Errorf("other chat = %+v, %v", reactions, err)
+	}
+
+	// Ben takes his reaction back; his earlier one does not return
+	react(ben, "", 2*time.Minute)
+	react(ben, "😂", time.Second)
+	reactions, err = store.GetMessageReactions(group, "M1")
+	if err != nil || len(reactions) != 1 || reactions[0].SenderJID != ana {
+		t.Errorf("after removal = %+v, %v", reactions, err)
+	}
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"whatsapp-bridge/internal/types"
+)
+
+// SetChatReadState replaces a chat's read state, e.g. with the phone's state
+// from history sync
+func (store *MessageStore) SetChatReadState(state *types.ChatReadState) error {
+	var lastReadAt interface{}
+	if state.LastReadAt != nil {
+		lastReadAt = state.LastReadAt.UTC()
+	}
+
+	_, err := store.db.Exec(
+		`INSERT OR REPLACE INTO chat_read_state
+		(chat_jid, unread_count, unread_mentions, marked_unread, last_read_message_id, last_read_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		state.ChatJID, state.UnreadCount, state.UnreadMentions, state.MarkedUnread,
+		state.LastReadMessageID, lastReadAt, time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store read state: %v", err)
+	}
+	return nil
+}
+
+// IncrementUnread counts one more unread incoming message in a chat
+func (store *MessageStore) IncrementUnread(chatJID string, mention bool) error {
+	mentions := 0
+	if mention {
+		mentions = 1
+	}
+
+	_, err := store.db.Exec(
+		`INSERT INTO chat_read_state (chat_jid, unread_count, unread_mentions, updated_at) VALUES (?, 1, ?, ?)
+		ON CONFLICT(chat_jid) DO UPDATE SET
+			unread_count = unread_count + 1,
+			unread_mentions = unread_mentions + excluded.unread_mentions,
+			updated_at = excluded.updated_at`,
+		chatJID, mentions, time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update read state: %v", err)
+	}
+	return nil
+}
+
+// MarkChatRead clears a chat's unread state. messageID is the newest message
+// read, if known.
+func (store *MessageStore) MarkChatRead(chatJID, messageID string, readAt time.Time) error {
+	_, err := store.db.Exec(
+		`INSERT INTO chat_read_state (chat_jid, last_read_message_id, last_read_at, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(chat_jid) DO UPDATE SET
+			unread_count = 0,
+			unread_mentions = 0,
+			marked_unread = 0,
+			last_read_message_id = COALESCE(NULLIF(excluded.last_read_message_id, ''), last_read_message_id),
+			last_read_at = excluded.last_read_at,
+			updated_at = excluded.updated_at`,
+		chatJID, messageID, readAt.UTC(), time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update read state: %v", err)
+	}
+	return nil
+}
+
+// SetChatMarkedUnread records that a chat was manually marked unread
+func (store *MessageStore) SetChatMarkedUnread(chatJID string) error {
+	_, err := store.db.Exec(
+		`INSERT INTO chat_read_state (chat_jid, marked_unread, updated_at) VALUES (?, 1, ?)
+		ON CONFLICT(chat_jid) DO UPDATE SET marked_unread = 1, updated_at = excluded.updated_at`,
+		chatJID, time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update read state: %v", err)
+	}
+	return nil
+}
+
+// GetUnreadChats returns the chats with unread messages or marked unread,
+// most unread first
+func (store *MessageStore) GetUnreadChats() ([]types.ChatReadState, error) {
+	rows, err := store.db.Query(
+		`SELECT chat_jid, unread_count, unread_mentions, marked_unread, last_read_message_id, last_read_at, updated_at
+		FROM chat_read_state WHERE unread_count > 0 OR marked_unread
+		ORDER BY unread_count DESC, updated_at DESC`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unread chats: %v", err)
+	}
+	defer rows.Close()
+
+	states := []types.ChatReadState{}
+	for rows.Next() {
+		var state types.ChatReadState
+		var lastReadID sql.NullString
+		var lastReadAt sql.NullTime
+		if err := rows.Scan(&state.ChatJID, &state.UnreadCount, &state.UnreadMentions, &state.MarkedUnread,
+			&lastReadID, &lastReadAt, &state.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan read state: %v", err)
+		}
+		state.LastReadMessageID = lastReadID.String
+		if lastReadAt.Valid {
+			state.LastReadAt = &lastReadAt.Time
+		}
+		states = append(states, state)
+	}
+	return states, rows.Err()
+}
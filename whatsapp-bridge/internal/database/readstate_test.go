@@ -0,0 +1,71 @@
+package database
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"whatsapp-bridge/internal/types"
+)
+
+func TestChatReadState(t *testing.T) {
+	tempDB := "test_readstate.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+	chat := "123@s.whatsapp.net"
+
+	// Seeded from history sync, then two more incoming messages, one mentioning us
+	lastRead := time.Date(2024, 3, 10, 6, 30, 0, 0, time.UTC)
+	if err := store.SetChatReadState(&types.ChatReadState{ChatJID: chat, UnreadCount: 3, LastReadMessageID: "OLD", LastReadAt: &lastRead}); err != nil {
+		t.Fatalf("SetChatReadState: %v", err)
+	}
+	if err := store.IncrementUnread(chat, false); err != nil {
+		t.Fatalf("IncrementUnread: %v", err)
+	}
+	if err := store.IncrementUnread(chat, true); err != nil {
+		t.Fatalf("IncrementUnread: %v", err)
+	}
+
+	chats, err := store.GetUnreadChats()
+	if err != nil {
+		t.Fatalf("GetUnreadChats: %v", err)
+	}
+	if len(chats) != 1 || chats[0].UnreadCount != 5 || chats[0].UnreadMentions != 1 || chats[0].LastReadMessageID != "OLD" {
+		t.Fatalf("GetUnreadChats = %+v, want one chat with 5 unread, 1 mention, last read OLD", chats)
+	}
+	if chats[0].LastReadAt == nil || !chats[0].LastReadAt.Equal(lastRead) {
+		t.Errorf("LastReadAt = %v, want %v", chats[0].LastReadAt, lastRead)
+	}
+
+	// Reading without a known message keeps the previous marker
+	if err := store.MarkChatRead(chat, "", time.Now()); err != nil {
+		t.Fatalf("MarkChatRead: %v", err)
+	}
+	if chats, _ := store.GetUnreadChats(); len(chats) != 0 {
+		t.Errorf("GetUnreadChats after read = %+v, want none", chats)
+	}
+	var marker string
+	if err := db.QueryRow(`SELECT last_read_message_id FROM chat_read_state WHERE chat_jid = ?`, chat).Scan(&marker); err != nil || marker != "OLD" {
+		t.Errorf("last_read_message_id = %q (err %v), want OLD", marker, err)
+	}
+
+	// Marked unread with no unread messages still lists the chat
+	if err := store.SetChatMarkedUnread(chat); err != nil {
+		t.Fatalf("SetChatMarkedUnread: %v", err)
+	}
+	if chats, _ := store.GetUnreadChats(); len(chats) != 1 || !chats[0].MarkedUnread {
+		t.Errorf("GetUnreadChats after marking unread = %+v, want the chat marked unread", chats)
+	}
+}
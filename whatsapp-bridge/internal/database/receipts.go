@@ -0,0 +1,122 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"whatsapp-bridge/internal/types"
+)
+
+// Receipt statuses recipients send for messages we sent, in the order a
+// message goes through them
+const (
+	ReceiptDelivered = "delivered"
+	ReceiptRead      = "read"
+	ReceiptPlayed    = "played"
+)
+
+// receiptRank orders the receipt statuses
+var receiptRank = map[string]int{ReceiptDelivered: 1, ReceiptRead: 2, ReceiptPlayed: 3}
+
+// StoreMessageReceipts records that a recipient got, read or played messages
+// in a chat. Each status keeps the first time it was reported, so receipts
+// sent again, e.g. after a reconnect, do not move it.
+func (store *MessageStore) StoreMessageReceipts(chatJID, recipientJID, status string, at time.Time, messageIDs []string) error {
+	if _, ok := receiptRank[status]; !ok {
+		return fmt.Errorf("invalid receipt status: %s", status)
+	}
+
+	tx, err := store.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to store receipts: %v", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, id := range messageIDs {
+		_, err := tx.Exec(
+			`INSERT INTO message_receipts (chat_jid, message_id, recipient_jid, status, received_at)
+			 VALUES (?, ?, ?, ?, ?)
+			 ON CONFLICT (chat_jid, message_id, recipient_jid, status) DO UPDATE SET
+				received_at = excluded.received_at
+			 WHERE excluded.received_at < message_receipts.received_at`,
+			chatJID, id, recipientJID, status, at.UTC(),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to store receipt: %v", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetMessageStatus returns how far a message we sent got, or nil if it has
+// no receipts and was not sent through the API. A read or played message
+// counts as delivered even when its delivery receipt never came.
+func (store *MessageStore) GetMessageStatus(chatJID, messageID string) (*types.MessageStatus, error) {
+	rows, err := store.db.Query(
+		`SELECT recipient_jid, status, received_at FROM message_receipts
+		 WHERE chat_jid = ? AND message_id = ?
+		 ORDER BY recipient_jid`,
+		chatJID, messageID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query receipts: %v", err)
+	}
+	defer rows.Close()
+
+	status := &types.MessageStatus{ChatJID: chatJID, MessageID: messageID, Recipients: []types.MessageReceipt{}}
+	furthest := 0
+	for rows.Next() {
+		var recipient, receipt string
+		var at time.Time
+		if err := rows.Scan(&recipient, &receipt, &at); err != nil {
+			return nil, fmt.Errorf("failed to scan receipt: %v", err)
+		}
+
+		n := len(status.Recipients)
+		if n == 0 || status.Recipients[n-1].RecipientJID != recipient {
+			status.Recipients = append(status.Recipients, types.MessageReceipt{RecipientJID: recipient})
+			n++
+		}
+		r := &status.Recipients[n-1]
+		switch receipt {
+		case ReceiptDelivered:
+			r.DeliveredAt = &at
+		case ReceiptRead:
+			r.ReadAt = &at
+		case ReceiptPlayed:
+			r.PlayedAt = &at
+		}
+		if rank := receiptRank[receipt]; rank > furthest {
+			furthest, status.Status = rank, receipt
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range status.Recipients {
+		r := &status.Recipients[i]
+		if r.ReadAt == nil {
+			r.ReadAt = r.PlayedAt
+		}
+		if r.DeliveredAt == nil {
+			r.DeliveredAt = r.ReadAt
+		}
+	}
+
+	outgoing, err := store.GetOutgoingMessage(messageID)
+	if err != nil {
+		return nil, err
+	}
+	if outgoing != nil && strings.EqualFold(outgoing.ChatJID, chatJID) {
+		status.SendStatus = outgoing.Status
+		if status.Status == "" {
+			status.Status = outgoing.Status
+		}
+	}
+	if status.Status == "" {
+		return nil, nil
+	}
+	return status, nil
+}
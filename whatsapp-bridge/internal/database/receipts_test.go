@@ -0,0 +1,85 @@
+package database
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"whatsapp-bridge/internal/types"
+)
+
+func TestMessageReceipts(t *testing.T) {
+	tempDB := "test_receipts.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+	group := "120363000000000000@g.us"
+	ana, ben := "15550000001@s.whatsapp.net", "15550000002@s.whatsapp.net"
+	now := time.Now().UTC().Truncate(time.Second)
+
+	if status, err := store.GetMessageStatus(group, "M1"); err != nil || status != nil {
+		t.Fatalf("status of unknown message = %+v, %v", status, err)
+	}
+
+	// Sent through the API, no receipt yet
+	if err := store.StoreOutgoingMessage(&types.OutgoingMessage{MessageID: "M1", ChatJID: group, Status: OutgoingServerAck}); err != nil {
+		t.Fatalf("StoreOutgoingMessage: %v", err)
+	}
+	status, err := store.GetMessageStatus(group, "M1")
+	if err != nil || status == nil || status.Status != OutgoingServerAck || len(status.Recipients) != 0 {
+		t.Fatalf("status before receipts = %+v, %v", status, err)
+	}
+
+	receipt := func(recipient, kind string, at time.Duration, ids ...string) {
+		t.Helper()
+		if err := store.StoreMessageReceipts(group, recipient, kind, now.Add(at), ids); err != nil {
+			t.Fatalf("StoreMessageReceipts: %v", err)
+		}
+	}
+	receipt(ana, ReceiptDelivered, time.Second, "M1", "M2")
+	receipt(ben, ReceiptDelivered, 2*time.Second, "M1")
+	receipt(ana, ReceiptRead, time.Minute, "M1")
+	// A receipt sent again keeps the first time
+	receipt(ana, ReceiptDelivered, time.Hour, "M1")
+	// Ben's read receipt came without a delivery one in M2
+	receipt(ben, ReceiptPlayed, 2*time.Minute, "M2")
+
+	status, err = store.GetMessageStatus(group, "M1")
+	if err != nil || status == nil {
+		t.Fatalf("GetMessageStatus: %+v, %v", status, err)
+	}
+	if status.Status != ReceiptRead || status.SendStatus != OutgoingServerAck || len(status.Recipients) != 2 {
+		t.Fatalf("status = %+v", status)
+	}
+	a, b := status.Recipients[0], status.Recipients[1]
+	if a.RecipientJID != ana || !a.DeliveredAt.Equal(now.Add(time.Second)) || a.ReadAt == nil || !a.ReadAt.Equal(now.Add(time.Minute)) || a.PlayedAt != nil {
+		t.Errorf("ana = %+v", a)
+	}
+	if b.RecipientJID != ben || b.DeliveredAt == nil || b.ReadAt != nil {
+		t.Errorf("ben = %+v", b)
+	}
+
+	// Not sent through the API: receipts alone, and played implies read and delivered
+	status, err = store.GetMessageStatus(group, "M2")
+	if err != nil || status == nil || status.Status != ReceiptPlayed || status.SendStatus != "" || len(status.Recipients) != 2 {
+		t.Fatalf("M2 status = %+v, %v", status, err)
+	}
+	if b := status.Recipients[1]; b.PlayedAt == nil || b.ReadAt == nil || b.DeliveredAt == nil || !b.DeliveredAt.Equal(*b.PlayedAt) {
+		t.Errorf("ben on M2 = %+v", b)
+	}
+
+	if err := store.StoreMessageReceipts(group, ana, "retry", now, []string{"M1"}); err == nil {
+		t.Error("unknown receipt status accepted")
+	}
+}
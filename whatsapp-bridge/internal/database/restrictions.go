@@ -0,0 +1,55 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"whatsapp-bridge/internal/types"
+)
+
+// StoreAccountRestriction records a temporary ban or rate limit when it begins
+func (store *MessageStore) StoreAccountRestriction(r types.AccountRestriction) error {
+	var until interface{}
+	if r.Until != nil {
+		until = r.Until.UTC()
+	}
+	_, err := store.db.Exec(
+		`INSERT INTO account_restrictions (reason, code, description, since, until) VALUES (?, ?, ?, ?, ?)`,
+		r.Reason, r.Code, r.Description, r.Since.UTC(), until,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store account restriction: %v", err)
+	}
+	return nil
+}
+
+// GetAccountRestrictions returns the restrictions that began at or after
+// since, oldest first
+func (store *MessageStore) GetAccountRestrictions(since time.Time) ([]types.AccountRestriction, error) {
+	rows, err := store.db.Query(
+		`SELECT reason, code, description, since, until FROM account_restrictions
+		 WHERE since >= ? ORDER BY since, id`,
+		since.UTC(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query account restrictions: %v", err)
+	}
+	defer rows.Close()
+
+	restrictions := []types.AccountRestriction{}
+	for rows.Next() {
+		var r types.AccountRestriction
+		var description sql.NullString
+		var until sql.NullTime
+		if err := rows.Scan(&r.Reason, &r.Code, &description, &r.Since, &until); err != nil {
+			return nil, fmt.Errorf("failed to scan account restriction: %v", err)
+		}
+		r.Description = description.String
+		if until.Valid {
+			r.Until = &until.Time
+		}
+		restrictions = append(restrictions, r)
+	}
+	return restrictions, rows.Err()
+}
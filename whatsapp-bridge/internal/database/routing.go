@@ -0,0 +1,295 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"whatsapp-bridge/internal/tenant"
+	"whatsapp-bridge/internal/types"
+)
+
+// Routing profile target types
+const (
+	RoutingTargetChat = "chat"
+	RoutingTargetTag  = "tag"
+)
+
+// StoreRoutingProfile stores a routing profile with its webhooks and targets
+func (store *MessageStore) StoreRoutingProfile(profile *types.RoutingProfile) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.Exec(
+		`INSERT INTO routing_profiles (name, description, exclusive, enabled, tenant) VALUES (?, ?, ?, ?, ?)`,
+		profile.Name, profile.Description, profile.Exclusive, profile.Enabled, tenant.Owner(profile.Tenant),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert routing profile: %v", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert ID: %v", err)
+	}
+	profile.ID = int(id)
+
+	if err := insertRoutingMembers(tx, profile); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateRoutingProfile replaces a routing profile's settings, webhooks and targets
+func (store *MessageStore) UpdateRoutingProfile(profile *types.RoutingProfile) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.Exec(
+		`UPDATE routing_profiles SET name = ?, description = ?, exclusive = ?, enabled = ?,
+		 updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
+		profile.Name, profile.Description, profile.Exclusive, profile.Enabled, profile.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update routing profile: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("routing profile with ID %d not found", profile.ID)
+	}
+
+	if _, err := tx.Exec("DELETE FROM routing_profile_webhooks WHERE profile_id = ?", profile.ID); err != nil {
+		return fmt.Errorf("failed to delete existing webhooks: %v", err)
+	}
+	if _, err := tx.Exec("DELETE FROM routing_profile_targets WHERE profile_id = ?", profile.ID); err != nil {
+		return fmt.Errorf("failed to delete existing targets: %v", err)
+	}
+
+	if err := insertRoutingMembers(tx, profile); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertRoutingMembers writes the webhook and target rows for a profile
+func insertRoutingMembers(tx *sql.Tx, profile *types.RoutingProfile) error {
+	for _, webhookID := range profile.WebhookIDs {
+		if _, err := tx.Exec(
+			`INSERT OR IGNORE INTO routing_profile_webhooks (profile_id, webhook_config_id) VALUES (?, ?)`,
+			profile.ID, webhookID,
+		); err != nil {
+			return fmt.Errorf("failed to attach webhook %d: %v", webhookID, err)
+		}
+	}
+
+	for _, chatJID := range profile.ChatJIDs {
+		if _, err := tx.Exec(
+			`INSERT OR IGNORE INTO routing_profile_targets (profile_id, target_type, target_value) VALUES (?, ?, ?)`,
+			profile.ID, RoutingTargetChat, chatJID,
+		); err != nil {
+			return fmt.Errorf("failed to attach chat %s: %v", chatJID, err)
+		}
+	}
+
+	for _, tag := range profile.Tags {
+		if _, err := tx.Exec(
+			`INSERT OR IGNORE INTO routing_profile_targets (profile_id, target_type, target_value) VALUES (?, ?, ?)`,
+			profile.ID, RoutingTargetTag, tag,
+		); err != nil {
+			return fmt.Errorf("failed to attach tag %s: %v", tag, err)
+		}
+	}
+
+	return nil
+}
+
+// GetRoutingProfile retrieves a routing profile by ID
+func (store *MessageStore) GetRoutingProfile(id int) (*types.RoutingProfile, error) {
+	profile := &types.RoutingProfile{}
+	var description sql.NullString
+	err := store.db.QueryRow(
+		`SELECT id, name, description, exclusive, enabled, tenant, created_at, updated_at
+		 FROM routing_profiles WHERE id = ?`, id,
+	).Scan(&profile.ID, &profile.Name, &description, &profile.Exclusive,
+		&profile.Enabled, &profile.Tenant, &profile.CreatedAt, &profile.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	profile.Description = description.String
+
+	if err := store.loadRoutingMembers(profile); err != nil {
+		return nil, err
+	}
+
+	return profile, nil
+}
+
+// GetAllRoutingProfiles retrieves all routing profiles
+func (store *MessageStore) GetAllRoutingProfiles() ([]*types.RoutingProfile, error) {
+	rows, err := store.db.Query(
+		`SELECT id, name, description, exclusive, enabled, tenant, created_at, updated_at
+		 FROM routing_profiles ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var profiles []*types.RoutingProfile
+	for rows.Next() {
+		profile := &types.RoutingProfile{}
+		var description sql.NullString
+		if err := rows.Scan(&profile.ID, &profile.Name, &description, &profile.Exclusive,
+			&profile.Enabled, &profile.Tenant, &profile.CreatedAt, &profile.UpdatedAt); err != nil {
+			return nil, err
+		}
+		profile.Description = description.String
+		profiles = append(profiles, profile)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Load members after the profile cursor is exhausted
+	for _, profile := range profiles {
+		if err := store.loadRoutingMembers(profile); err != nil {
+			return nil, err
+		}
+	}
+
+	return profiles, nil
+}
+
+// loadRoutingMembers fills in a profile's webhook IDs, chats and tags
+func (store *MessageStore) loadRoutingMembers(profile *types.RoutingProfile) error {
+	profile.WebhookIDs = []int{}
+	profile.ChatJIDs = []string{}
+	profile.Tags = []string{}
+
+	rows, err := store.db.Query(
+		"SELECT webhook_config_id FROM routing_profile_webhooks WHERE profile_id = ? ORDER BY webhook_config_id", profile.ID)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		profile.WebhookIDs = append(profile.WebhookIDs, id)
+	}
+	rows.Close()
+
+	rows, err = store.db.Query(
+		"SELECT target_type, target_value FROM routing_profile_targets WHERE profile_id = ? ORDER BY target_value", profile.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var targetType, value string
+		if err := rows.Scan(&targetType, &value); err != nil {
+			return err
+		}
+		switch targetType {
+		case RoutingTargetChat:
+			profile.ChatJIDs = append(profile.ChatJIDs, value)
+		case RoutingTargetTag:
+			profile.Tags = append(profile.Tags, value)
+		}
+	}
+
+	return rows.Err()
+}
+
+// DeleteRoutingProfile deletes a routing profile and its webhook/target links
+func (store *MessageStore) DeleteRoutingProfile(id int) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec("DELETE FROM routing_profile_webhooks WHERE profile_id = ?", id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM routing_profile_targets WHERE profile_id = ?", id); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec("DELETE FROM routing_profiles WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("routing profile with ID %d not found", id)
+	}
+
+	return tx.Commit()
+}
+
+// SetChatTags replaces the tags a tenant has attached to a chat
+func (store *MessageStore) SetChatTags(tenant, chatJID string, tags []string) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec("DELETE FROM chat_tags WHERE tenant = ? AND chat_jid = ?", tenant, chatJID); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO chat_tags (tenant, chat_jid, tag) VALUES (?, ?, ?)", tenant, chatJID, tag); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetChatTags returns a tenant's chat tags keyed by chat JID
+func (store *MessageStore) GetChatTags(tenant string) (map[string][]string, error) {
+	all, err := store.GetAllChatTags()
+	if err != nil {
+		return nil, err
+	}
+	if tags, ok := all[tenant]; ok {
+		return tags, nil
+	}
+	return make(map[string][]string), nil
+}
+
+// GetAllChatTags returns every tenant's chat tags keyed by tenant, then chat JID
+func (store *MessageStore) GetAllChatTags() (map[string]map[string][]string, error) {
+	rows, err := store.db.Query("SELECT tenant, chat_jid, tag FROM chat_tags ORDER BY tenant, chat_jid, tag")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make(map[string]map[string][]string)
+	for rows.Next() {
+		var tenant, chatJID, tag string
+		if err := rows.Scan(&tenant, &chatJID, &tag); err != nil {
+			return nil, err
+		}
+		if tags[tenant] == nil {
+			tags[tenant] = make(map[string][]string)
+		}
+		tags[tenant][chatJID] = append(tags[tenant][chatJID], tag)
+	}
+
+	return tags, rows.Err()
+}
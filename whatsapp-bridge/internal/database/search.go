@@ -0,0 +1,257 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"whatsapp-bridge/internal/types"
+)
+
+// Search result orders
+const (
+	SearchOrderRelevance = "relevance"
+	SearchOrderNewest    = "newest"
+)
+
+// indexMessageText fills the full-text index from messages stored before
+// it existed. It does nothing once the index has any rows.
+func indexMessageText(db *sql.DB) error {
+	var indexed bool
+	if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM messages_fts)`).Scan(&indexed); err != nil {
+		return err
+	}
+	if indexed {
+		return nil
+	}
+	_, err := db.Exec(`INSERT INTO messages_fts (docid, text) SELECT rowid, content FROM messages WHERE content != ''`)
+	return err
+}
+
+// indexedText is the SQL for the text the full-text index holds for the
+// messages row named row: its content followed by its annotations, so a
+// voice note is found by its transcript
+func indexedText(row string) string {
+	return `trim(` + row + `.content || coalesce((SELECT ' ' || group_concat(a.text, ' ') FROM message_annotations a
+		WHERE a.chat_jid = ` + row + `.chat_jid AND a.message_id = ` + row + `.id), ''))`
+}
+
+// reindexMessage refreshes the full-text index of a stored message
+func reindexMessage(tx *sql.Tx, chatJID, messageID string) error {
+	_, err := tx.Exec(`DELETE FROM messages_fts WHERE docid = (SELECT rowid FROM messages WHERE chat_jid = ? AND id = ?)`, chatJID, messageID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(
+		`INSERT INTO messages_fts (docid, text) SELECT rowid, text FROM (
+			SELECT messages.rowid AS rowid, `+indexedText("messages")+` AS text FROM messages WHERE chat_jid = ? AND id = ?
+		 ) WHERE text != ''`,
+		chatJID, messageID,
+	)
+	return err
+}
+
+// indexAnnotationText adds the annotations stored before they were indexed
+// with their messages. It runs once.
+func indexAnnotationText(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var done int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM bridge_settings WHERE key = ?`, SettingAnnotationsIndexed).Scan(&done); err != nil {
+		return err
+	}
+	if done > 0 {
+		return nil
+	}
+
+	rows, err := tx.Query(`SELECT DISTINCT chat_jid, message_id FROM message_annotations`)
+	if err != nil {
+		return err
+	}
+	var annotated [][2]string
+	for rows.Next() {
+		var chatJID, messageID string
+		if err := rows.Scan(&chatJID, &messageID); err != nil {
+			rows.Close()
+			return err
+		}
+		annotated = append(annotated, [2]string{chatJID, messageID})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, m := range annotated {
+		if err := reindexMessage(tx, m[0], m[1]); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(
+		`INSERT INTO bridge_settings (key, value, updated_at) VALUES (?, 'true', CURRENT_TIMESTAMP)`,
+		SettingAnnotationsIndexed,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// maxRankedMatches bounds how many matches are scored for a relevance
+// search: the newest ones, so a common term does not score the whole archive
+const maxRankedMatches = 1000
+
+// SearchMessages runs a full-text query over stored message text and
+// returns a page of matches, best or newest first, with the number of
+// matches in all. Relevance ranks the newest maxRankedMatches matches only.
+func (store *MessageStore) SearchMessages(q types.MessageSearch) ([]types.MessageSearchResult, int, error) {
+	where, args := messageQueryWhere(types.MessageQuery{ChatJID: q.ChatJID, Sender: q.Sender, Since: q.Since, Until: q.Until})
+	if where == "" {
+		where = " WHERE messages_fts MATCH ?"
+	} else {
+		where += " AND messages_fts MATCH ?"
+	}
+	args = append(args, q.Query)
+	from := ` FROM messages_fts JOIN messages ON messages.rowid = messages_fts.docid`
+
+	var total int
+	if err := store.db.QueryRow(`SELECT COUNT(*)`+from+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to search messages: %v", err)
+	}
+	if q.Offset >= total {
+		return []types.MessageSearchResult{}, total, nil
+	}
+
+	// The newest search pages in SQL; a relevance search takes the newest
+	// candidates and scores them here, as FTS4 has no built-in ranking
+	limit, offset := q.Limit, q.Offset
+	if limit <= 0 {
+		limit = -1
+	}
+	if q.Order != SearchOrderNewest {
+		limit, offset = maxRankedMatches, 0
+	}
+	rows, err := store.db.Query(
+		`SELECT messages_fts.docid, messages.timestamp, matchinfo(messages_fts, 'pcnx')`+from+where+
+			` ORDER BY messages.timestamp DESC LIMIT ? OFFSET ?`,
+		append(args, limit, offset)...,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search messages: %v", err)
+	}
+	type hit struct {
+		docid int64
+		at    time.Time
+		score float64
+	}
+	var hits []hit
+	for rows.Next() {
+		var h hit
+		var info []byte
+		if err := rows.Scan(&h.docid, &h.at, &info); err != nil {
+			rows.Close()
+			return nil, 0, fmt.Errorf("failed to scan match: %v", err)
+		}
+		h.score = matchScore(info)
+		hits = append(hits, h)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to search messages: %v", err)
+	}
+
+	if q.Order != SearchOrderNewest {
+		sort.SliceStable(hits, func(i, j int) bool {
+			return hits[i].score > hits[j].score
+		})
+		if q.Offset >= len(hits) {
+			return []types.MessageSearchResult{}, total, nil
+		}
+		hits = hits[q.Offset:]
+		if q.Limit > 0 && len(hits) > q.Limit {
+			hits = hits[:q.Limit]
+		}
+	}
+
+	placeholders := make([]string, len(hits))
+	pageArgs := []interface{}{q.Query}
+	position := make(map[int64]int, len(hits))
+	for i, h := range hits {
+		placeholders[i] = "?"
+		pageArgs = append(pageArgs, h.docid)
+		position[h.docid] = i
+	}
+	rows, err = store.db.Query(
+		`SELECT messages_fts.docid, snippet(messages_fts, '[', ']', '…'), `+storedMessageColumns+from+
+			` WHERE messages_fts MATCH ? AND messages_fts.docid IN (`+strings.Join(placeholders, ", ")+`)`,
+		pageArgs...,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to load matches: %v", err)
+	}
+	defer rows.Close()
+
+	results := make([]types.MessageSearchResult, len(hits))
+	for rows.Next() {
+		var docid int64
+		var snippet string
+		msg, err := scanStoredMessage(prefixScanner{rows, []interface{}{&docid, &snippet}})
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan match: %v", err)
+		}
+		i := position[docid]
+		results[i] = types.MessageSearchResult{StoredMessage: msg, Snippet: snippet, Score: hits[i].score}
+	}
+	return results, total, rows.Err()
+}
+
+// prefixScanner scans the leading columns of a row into its own
+// destinations and passes the rest on, so scanStoredMessage can read a row
+// with extra columns in front
+type prefixScanner struct {
+	row  interface{ Scan(...interface{}) error }
+	dest []interface{}
+}
+
+func (p prefixScanner) Scan(dest ...interface{}) error {
+	return p.row.Scan(append(append([]interface{}{}, p.dest...), dest...)...)
+}
+
+// matchScore rates a match from FTS4 matchinfo 'pcnx' with BM25 term
+// weighting, leaving out document length: rare terms count for more, and
+// repeats of a term for less each time
+func matchScore(info []byte) float64 {
+	const k1 = 1.2
+	values := make([]uint32, len(info)/4)
+	for i := range values {
+		values[i] = binary.NativeEndian.Uint32(info[i*4:])
+	}
+	if len(values) < 3 {
+		return 0
+	}
+
+	phrases, columns, docs := int(values[0]), int(values[1]), float64(values[2])
+	score := 0.0
+	for p := 0; p < phrases; p++ {
+		for c := 0; c < columns; c++ {
+			i := 3 + 3*(p*columns+c)
+			if i+2 >= len(values) {
+				return score
+			}
+			tf, df := float64(values[i]), float64(values[i+2])
+			if tf == 0 {
+				continue
+			}
+			idf := math.Log(1 + (docs-df+0.5)/(df+0.5))
+			score += idf * tf * (k1 + 1) / (tf + k1)
+		}
+	}
+	return score
+}
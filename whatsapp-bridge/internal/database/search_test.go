@@ -0,0 +1,155 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"whatsapp-bridge/internal/types"
+)
+
+func TestSearchMessages(t *testing.T) {
+	tempDB := "test_search.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+	group, direct := "120363000000000000@g.us", "111@s.whatsapp.net"
+	for _, chat := range []string{group, direct} {
+		if err := store.StoreChat(chat, chat, time.Now()); err != nil {
+			t.Fatalf("Failed to store chat: %v", err)
+		}
+	}
+
+	day := func(d int) time.Time { return time.Date(2024, 6, d, 9, 0, 0, 0, time.UTC) }
+	messages := []struct {
+		id, chat, sender, content string
+		at                        time.Time
+	}{
+		{"M1", group, "111", "invoice attached", day(1)},
+		{"M2", group, "222", "invoice invoice invoice, please pay the invoice", day(2)},
+		{"M3", direct, "111", "lunch tomorrow?", day(3)},
+		{"M4", direct, "111", "the invoice is paid", day(4)},
+	}
+	for _, m := range messages {
+		if err := store.StoreMessage(m.id, m.chat, m.sender, "", m.content, m.at, false, "", "", "", nil, nil, nil, 0, nil); err != nil {
+			t.Fatalf("Failed to store message: %v", err)
+		}
+	}
+
+	ids := func(results []types.MessageSearchResult) []string {
+		var out []string
+		for _, r := range results {
+			out = append(out, r.ID)
+		}
+		return out
+	}
+
+	results, total, err := store.SearchMessages(types.MessageSearch{Query: "invoice"})
+	if err != nil {
+		t.Fatalf("SearchMessages: %v", err)
+	}
+	if total != 3 || len(results) != 3 || results[0].ID != "M2" {
+		t.Fatalf("relevance search = %v (total %d), want M2 first of 3", ids(results), total)
+	}
+	if results[0].Snippet == "" || results[0].Score <= results[2].Score {
+		t.Errorf("best match = %+v, want a snippet and the highest score", results[0])
+	}
+
+	results, _, _ = store.SearchMessages(types.MessageSearch{Query: "invoice", Order: SearchOrderNewest, Limit: 2})
+	if got := ids(results); len(got) != 2 || got[0] != "M4" || got[1] != "M2" {
+		t.Errorf("newest search = %v, want M4, M2", got)
+	}
+
+	results, total, _ = store.SearchMessages(types.MessageSearch{Query: "invoice", ChatJID: group, Sender: "111@s.whatsapp.net"})
+	if got := ids(results); total != 1 || len(got) != 1 || got[0] != "M1" {
+		t.Errorf("filtered search = %v (total %d), want M1", got, total)
+	}
+
+	// Replacing a message reindexes it
+	if err := store.StoreMessage("M3", direct, "111", "", "invoice for lunch", day(3), false, "", "", "", nil, nil, nil, 0, nil); err != nil {
+		t.Fatalf("Failed to store message: %v", err)
+	}
+	if results, _, _ := store.SearchMessages(types.MessageSearch{Query: "lunch"}); len(results) != 1 || results[0].Content != "invoice for lunch" {
+		t.Errorf("search after replace = %+v, want the new text once", results)
+	}
+	if _, total, _ := store.SearchMessages(types.MessageSearch{Query: "tomorrow"}); total != 0 {
+		t.Errorf("old text still matches %d messages", total)
+	}
+
+	// An index dropped and rebuilt picks up every stored message
+	if _, err := db.Exec(`DELETE FROM messages_fts`); err != nil {
+		t.Fatalf("Failed to clear index: %v", err)
+	}
+	if err := indexMessageText(db); err != nil {
+		t.Fatalf("indexMessageText: %v", err)
+	}
+	if _, total, _ := store.SearchMessages(types.MessageSearch{Query: "invoice"}); total != 4 {
+		t.Errorf("search after reindex = %d matches, want 4", total)
+	}
+}
+
+func TestSearchMessagesPaging(t *testing.T) {
+	tempDB := "test_search_paging.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+	chat := "111@s.whatsapp.net"
+	if err := store.StoreChat(chat, chat, time.Now()); err != nil {
+		t.Fatalf("Failed to store chat: %v", err)
+	}
+	start := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < maxRankedMatches+5; i++ {
+		content := "status update"
+		if i == 0 {
+			// The oldest message is the best match, but beyond the ranked ones
+			content = "update update update update"
+		}
+		if err := store.StoreMessage(fmt.Sprintf("M%d", i), chat, "111", "", content, start.Add(time.Duration(i)*time.Minute), false, "", "", "", nil, nil, nil, 0, nil); err != nil {
+			t.Fatalf("Failed to store message: %v", err)
+		}
+	}
+
+	results, total, err := store.SearchMessages(types.MessageSearch{Query: "update", Order: SearchOrderNewest, Limit: 2, Offset: 3})
+	if err != nil || total != maxRankedMatches+5 || len(results) != 2 {
+		t.Fatalf("newest page = %d results of %d, %v", len(results), total, err)
+	}
+	if want := fmt.Sprintf("M%d", maxRankedMatches+1); results[0].ID != want || results[0].Snippet == "" {
+		t.Errorf("newest page starts at %s, want %s", results[0].ID, want)
+	}
+
+	results, total, err = store.SearchMessages(types.MessageSearch{Query: "update", Limit: 10})
+	if err != nil || total != maxRankedMatches+5 || len(results) != 10 {
+		t.Fatalf("relevance page = %d results of %d, %v", len(results), total, err)
+	}
+	for _, r := range results {
+		if r.ID == "M0" {
+			t.Error("relevance search ranked a match older than the ranked ones")
+		}
+	}
+
+	if results, _, _ := store.SearchMessages(types.MessageSearch{Query: "update", Offset: maxRankedMatches}); len(results) != 0 {
+		t.Errorf("relevance page past the ranked matches = %d results", len(results))
+	}
+}
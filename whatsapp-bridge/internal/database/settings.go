@@ -0,0 +1,100 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// Setting keys stored in bridge_settings
+const (
+	SettingReceiptPolicy = "receipt_policy"
+	SettingAutoRead      = "auto_read"
+	SettingNewsletters   = "newsletters"
+	SettingMaintenance   = "maintenance"
+	SettingBusinessHours = "business_hours"
+	SettingUsageQuotas   = "usage_quotas"
+	SettingChatScope     = "chat_scope"
+	SettingStoragePolicy = "storage_policy"
+	SettingDuplicateSend = "duplicate_send"
+	SettingLoopBreaker   = "loop_breaker"
+	SettingSendRate      = "send_rate"
+	SettingRelay         = "relay"
+	SettingCORS          = "cors"
+	SettingCommands      = "commands"
+	SettingApprovals     = "approvals"
+
+	// SettingBlocklistSeeded is set once the blocklist mirror was first
+	// filled from the server, see SeedBlocklist
+	SettingBlocklistSeeded = "blocklist_seeded"
+
+	// SettingAnnotationsIndexed is set once annotations stored earlier were
+	// added to the messages' full-text index, see indexAnnotationText
+	SettingAnnotationsIndexed = "annotations_indexed"
+)
+
+// GetSetting retrieves a raw setting value. ok is false if the key is unset.
+func (store *MessageStore) GetSetting(key string) (value string, ok bool, err error) {
+	err = store.db.QueryRow("SELECT value FROM bridge_settings WHERE key = ?", key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+// SetSetting stores a raw setting value
+func (store *MessageStore) SetSetting(key, value string) error {
+	_, err := store.db.Exec(
+		`INSERT INTO bridge_settings (key, value, updated_at) VALUES (?, ?, CURRENT_TIMESTAMP)
+		 ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = CURRENT_TIMESTAMP`,
+		key, value,
+	)
+	return err
+}
+
+// GetJSONSetting decodes a JSON setting into dst. ok is false if the key is unset,
+// in which case dst is left untouched.
+func (store *MessageStore) GetJSONSetting(key string, dst interface{}) (ok bool, err error) {
+	value, ok, err := store.GetSetting(key)
+	if err != nil || !ok {
+		return false, err
+	}
+	if err := json.Unmarshal([]byte(value), dst); err != nil {
+		return false, fmt.Errorf("failed to decode setting %s: %v", key, err)
+	}
+	return true, nil
+}
+
+// SetJSONSetting encodes v as JSON and stores it under key
+func (store *MessageStore) SetJSONSetting(key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode setting %s: %v", key, err)
+	}
+	return store.SetSetting(key, string(data))
+}
+
+// ClaimAutoReply records that contactJID is being sent the kind auto-reply
+// for window (e.g. a day or a closed period). It returns false if the contact
+// was already answered in that window, so concurrent messages produce a
+// single reply.
+func (store *MessageStore) ClaimAutoReply(kind, contactJID, window string) (bool, error) {
+	result, err := store.db.Exec(
+		`INSERT INTO auto_replies (kind, contact_jid, reply_window) VALUES (?, ?, ?)
+		 ON CONFLICT(kind, contact_jid) DO UPDATE SET reply_window = excluded.reply_window
+		 WHERE reply_window != excluded.reply_window`,
+		kind, contactJID, window,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to record auto-reply: %v", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %v", err)
+	}
+	return rows > 0, nil
+}
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+)
+
+func TestClaimAutoReply(t *testing.T) {
+	tempDB := "test_settings.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+
+	claims := []struct {
+		kind    string
+		contact string
+		window  string
+		want    bool
+	}{
+		{"maintenance", "111@s.whatsapp.net", "2025-03-01", true},
+		{"maintenance", "111@s.whatsapp.net", "2025-03-01", false},   // same window
+		{"maintenance", "222@s.whatsapp.net", "2025-03-01", true},    // other contact
+		{"business_hours", "111@s.whatsapp.net", "2025-03-01", true}, // other kind
+		{"maintenance", "111@s.whatsapp.net", "2025-03-02", true},    // next window
+	}
+
+	for _, c := range claims {
+		got, err := store.ClaimAutoReply(c.kind, c.contact, c.window)
+		if err != nil {
+			t.Fatalf("ClaimAutoReply failed: %v", err)
+		}
+		if got != c.want {
+			t.Errorf("ClaimAutoReply(%s, %s, %s) = %v, want %v", c.kind, c.contact, c.window, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"whatsapp-bridge/internal/types"
+)
+
+// StoreStickerPack records a sticker pack seen in a chat. A pack seen again
+// keeps its first sighting but picks up the latest download location, since
+// older media URLs expire.
+func (store *MessageStore) StoreStickerPack(pack *types.StickerPack) error {
+	stickers, err := json.Marshal(pack.Stickers)
+	if err != nil {
+		return fmt.Errorf("failed to encode stickers: %v", err)
+	}
+
+	_, err = store.db.Exec(
+		`INSERT INTO sticker_packs
+		 (pack_id, name, publisher, description, stickers, chat_jid, message_id, sender_jid,
+		  direct_path, media_key, file_sha256, file_enc_sha256, file_length, seen_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(pack_id) DO UPDATE SET
+		  name = excluded.name, publisher = excluded.publisher, description = excluded.description,
+		  stickers = excluded.stickers, direct_path = excluded.direct_path, media_key = excluded.media_key,
+		  file_sha256 = excluded.file_sha256, file_enc_sha256 = excluded.file_enc_sha256,
+		  file_length = excluded.file_length`,
+		pack.ID, pack.Name, pack.Publisher, pack.Description, string(stickers), pack.ChatJID, pack.MessageID, pack.SenderJID,
+		pack.DirectPath, pack.MediaKey, pack.FileSHA256, pack.FileEncSHA256, pack.FileLength, pack.SeenAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store sticker pack: %v", err)
+	}
+	return nil
+}
+
+const stickerPackColumns = `pack_id, name, publisher, description, stickers, chat_jid, message_id, sender_jid,
+	direct_path, media_key, file_sha256, file_enc_sha256, file_length, seen_at`
+
+// GetStickerPacks returns every sticker pack seen, newest first
+func (store *MessageStore) GetStickerPacks() ([]types.StickerPack, error) {
+	rows, err := store.db.Query(`SELECT ` + stickerPackColumns + ` FROM sticker_packs ORDER BY seen_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sticker packs: %v", err)
+	}
+	defer rows.Close()
+
+	packs := []types.StickerPack{}
+	for rows.Next() {
+		pack, err := scanStickerPack(rows)
+		if err != nil {
+			return nil, err
+		}
+		packs = append(packs, *pack)
+	}
+	return packs, rows.Err()
+}
+
+// GetStickerPack returns one sticker pack, or nil if it was never seen
+func (store *MessageStore) GetStickerPack(id string) (*types.StickerPack, error) {
+	pack, err := scanStickerPack(store.db.QueryRow(`SELECT `+stickerPackColumns+` FROM sticker_packs WHERE pack_id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return pack, err
+}
+
+// scanStickerPack reads one sticker_packs row from a *sql.Row or *sql.Rows
+func scanStickerPack(row interface{ Scan(dest ...any) error }) (*types.StickerPack, error) {
+	var pack types.StickerPack
+	var name, publisher, description, stickers, directPath sql.NullString
+
+	err := row.Scan(&pack.ID, &name, &publisher, &description, &stickers, &pack.ChatJID, &pack.MessageID, &pack.SenderJID,
+		&directPath, &pack.MediaKey, &pack.FileSHA256, &pack.FileEncSHA256, &pack.FileLength, &pack.SeenAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan sticker pack: %v", err)
+	}
+
+	pack.Name = name.String
+	pack.Publisher = publisher.String
+	pack.Description = description.String
+	pack.DirectPath = directPath.String
+	if stickers.String != "" {
+		if err := json.Unmarshal([]byte(stickers.String), &pack.Stickers); err != nil {
+			return nil, fmt.Errorf("failed to decode stickers: %v", err)
+		}
+	}
+	return &pack, nil
+}
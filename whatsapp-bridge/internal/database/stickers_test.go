@@ -0,0 +1,79 @@
+package database
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"whatsapp-bridge/internal/types"
+)
+
+func TestStickerPacks(t *testing.T) {
+	tempDB := "test_stickers.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+
+	seen := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	pack := &types.StickerPack{
+		ID:         "PACK1",
+		Name:       "Cats",
+		Publisher:  "Someone",
+		Stickers:   []types.StickerPackItem{{FileName: "a.webp", Emojis: []string{"🐱"}}, {FileName: "b.webp", IsAnimated: true}},
+		ChatJID:    "123@s.whatsapp.net",
+		MessageID:  "MSG1",
+		SenderJID:  "123@s.whatsapp.net",
+		DirectPath: "/v/t62/old",
+		MediaKey:   []byte{1, 2, 3},
+		FileLength: 2048,
+		SeenAt:     seen,
+	}
+	if err := store.StoreStickerPack(pack); err != nil {
+		t.Fatalf("StoreStickerPack: %v", err)
+	}
+
+	// Seen again in another chat: keeps the first sighting, takes the new media location
+	again := *pack
+	again.ChatJID = "456@s.whatsapp.net"
+	again.MessageID = "MSG2"
+	again.DirectPath = "/v/t62/new"
+	again.SeenAt = seen.Add(time.Hour)
+	if err := store.StoreStickerPack(&again); err != nil {
+		t.Fatalf("StoreStickerPack again: %v", err)
+	}
+
+	got, err := store.GetStickerPack("PACK1")
+	if err != nil {
+		t.Fatalf("GetStickerPack: %v", err)
+	}
+	if got == nil || got.ChatJID != "123@s.whatsapp.net" || got.MessageID != "MSG1" || got.DirectPath != "/v/t62/new" {
+		t.Fatalf("Unexpected pack: %+v", got)
+	}
+	if len(got.Stickers) != 2 || !got.Stickers[1].IsAnimated || got.Stickers[0].Emojis[0] != "🐱" {
+		t.Errorf("Unexpected stickers: %+v", got.Stickers)
+	}
+	if string(got.MediaKey) != string([]byte{1, 2, 3}) || !got.SeenAt.Equal(seen) {
+		t.Errorf("Unexpected media key or seen time: %+v", got)
+	}
+
+	packs, err := store.GetStickerPacks()
+	if err != nil || len(packs) != 1 {
+		t.Fatalf("GetStickerPacks = %d packs, %v", len(packs), err)
+	}
+
+	missing, err := store.GetStickerPack("NOPE")
+	if err != nil || missing != nil {
+		t.Errorf("Expected nil for unknown pack, got %+v, %v", missing, err)
+	}
+}
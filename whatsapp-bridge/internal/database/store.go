@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	"os"
+	"sync"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -11,6 +12,10 @@ import (
 // MessageStore handles database operations for storing message history and webhook configurations
 type MessageStore struct {
 	db *sql.DB
+
+	// Listeners for newly stored messages
+	subsMutex   sync.Mutex
+	messageSubs map[chan struct{}]struct{}
 }
 
 // NewMessageStore initializes a new message store with SQLite database
@@ -50,9 +55,160 @@ func runMigrations(db *sql.DB) error {
 		// Unexpected migration error - log but don't fail
 		fmt.Printf("Warning: migration error (sender_name column): %v\n", err)
 	}
+
+	// Add filter_expression column to webhook_configs
+	_, err = db.Exec(`ALTER TABLE webhook_configs ADD COLUMN filter_expression TEXT`)
+	if err != nil && err.Error() != "duplicate column name: filter_expression" {
+		fmt.Printf("Warning: migration error (filter_expression column): %v\n", err)
+	}
+
+	// Mark rows stored without their content
+	_, err = db.Exec(`ALTER TABLE messages ADD COLUMN metadata_only BOOLEAN NOT NULL DEFAULT 0`)
+	if err != nil && err.Error() != "duplicate column name: metadata_only" {
+		fmt.Printf("Warning: migration error (metadata_only column): %v\n", err)
+	}
+
+	// Keep mentions, formatting and quotes alongside message text
+	_, err = db.Exec(`ALTER TABLE messages ADD COLUMN context TEXT`)
+	if err != nil && err.Error() != "duplicate column name: context" {
+		fmt.Printf("Warning: migration error (context column): %v\n", err)
+	}
+
+	// Message and chat times used to be written in the server's local zone
+	if err := normalizeTimestamps(db); err != nil {
+		fmt.Printf("Warning: migration error (UTC timestamps): %v\n", err)
+	}
+
+	// Add tenant ownership to webhooks, routing profiles and sent messages
+	for _, table := range []string{"webhook_configs", "routing_profiles", "outgoing_messages"} {
+		_, err = db.Exec(`ALTER TABLE ` + table + ` ADD COLUMN tenant TEXT NOT NULL DEFAULT 'default'`)
+		if err != nil && err.Error() != "duplicate column name: tenant" {
+			fmt.Printf("Warning: migration error (%s tenant column): %v\n", table, err)
+		}
+	}
+
+	// Tag sends made by automations
+	_, err = db.Exec(`ALTER TABLE outgoing_messages ADD COLUMN origin TEXT`)
+	if err != nil && err.Error() != "duplicate column name: origin" {
+		fmt.Printf("Warning: migration error (origin column): %v\n", err)
+	}
+
+	// Let each webhook choose its payload schema; existing ones keep version 1
+	_, err = db.Exec(`ALTER TABLE webhook_configs ADD COLUMN payload_version INTEGER NOT NULL DEFAULT 1`)
+	if err != nil && err.Error() != "duplicate column name: payload_version" {
+		fmt.Printf("Warning: migration error (payload_version column): %v\n", err)
+	}
+
+	// Let each webhook choose how its payload is encoded
+	for _, column := range []string{"content_type", "body_template"} {
+		_, err = db.Exec(`ALTER TABLE webhook_configs ADD COLUMN ` + column + ` TEXT`)
+		if err != nil && err.Error() != "duplicate column name: "+column {
+			fmt.Printf("Warning: migration error (%s column): %v\n", column, err)
+		}
+	}
+
+	// Let each webhook limit the size of what it receives
+	for _, column := range []string{"max_content_length", "max_payload_bytes"} {
+		_, err = db.Exec(`ALTER TABLE webhook_configs ADD COLUMN ` + column + ` INTEGER NOT NULL DEFAULT 0`)
+		if err != nil && err.Error() != "duplicate column name: "+column {
+			fmt.Printf("Warning: migration error (%s column): %v\n", column, err)
+		}
+	}
+	_, err = db.Exec(`ALTER TABLE webhook_configs ADD COLUMN oversize_action TEXT`)
+	if err != nil && err.Error() != "duplicate column name: oversize_action" {
+		fmt.Printf("Warning: migration error (oversize_action column): %v\n", err)
+	}
+
+	// Count how often each trigger fires
+	_, err = db.Exec(`ALTER TABLE webhook_triggers ADD COLUMN match_count INTEGER NOT NULL DEFAULT 0`)
+	if err != nil && err.Error() != "duplicate column name: match_count" {
+		fmt.Printf("Warning: migration error (match_count column): %v\n", err)
+	}
+	_, err = db.Exec(`ALTER TABLE webhook_triggers ADD COLUMN last_matched_at TIMESTAMP`)
+	if err != nil && err.Error() != "duplicate column name: last_matched_at" {
+		fmt.Printf("Warning: migration error (last_matched_at column): %v\n", err)
+	}
+
+	// Where a message's media was downloaded to
+	_, err = db.Exec(`ALTER TABLE messages ADD COLUMN local_path TEXT`)
+	if err != nil && err.Error() != "duplicate column name: local_path" {
+		fmt.Printf("Warning: migration error (local_path column): %v\n", err)
+	}
+
+	// Hold sends to every send endpoint for approval, not just /api/send
+	_, err = db.Exec(`ALTER TABLE pending_approvals ADD COLUMN route TEXT NOT NULL DEFAULT '/api/send'`)
+	if err != nil && err.Error() != "duplicate column name: route" {
+		fmt.Printf("Warning: migration error (pending_approvals route column): %v\n", err)
+	}
+	_, err = db.Exec(`ALTER TABLE pending_approvals ADD COLUMN body TEXT NOT NULL DEFAULT ''`)
+	if err != nil && err.Error() != "duplicate column name: body" {
+		fmt.Printf("Warning: migration error (pending_approvals body column): %v\n", err)
+	}
+
+	// Index messages stored before full-text search existed, and the
+	// annotations stored before they were indexed with their messages
+	if err := indexMessageText(db); err != nil {
+		fmt.Printf("Warning: migration error (messages_fts): %v\n", err)
+	}
+	if err := indexAnnotationText(db); err != nil {
+		fmt.Printf("Warning: migration error (messages_fts annotations): %v\n", err)
+	}
+
+	// Chat tags are namespaced per tenant, which changes their primary key
+	if err := migrateChatTagsTenant(db); err != nil {
+		fmt.Printf("Warning: migration error (chat_tags tenant): %v\n", err)
+	}
 	return nil
 }
 
+// normalizeTimestamps rewrites message and chat times stored with a local
+// offset in UTC, so they sort and compare correctly across zones and DST
+// changes. Rows already in UTC are left alone.
+func normalizeTimestamps(db *sql.DB) error {
+	for _, col := range [][2]string{{"messages", "timestamp"}, {"chats", "last_message_time"}} {
+		table, column := col[0], col[1]
+		_, err := db.Exec(`UPDATE ` + table + ` SET ` + column + ` = strftime('%Y-%m-%d %H:%M:%S+00:00', ` + column + `)
+			WHERE ` + column + ` NOT LIKE '%+00:00' AND strftime('%s', ` + column + `) IS NOT NULL`)
+		if err != nil {
+			return fmt.Errorf("failed to normalize %s.%s: %v", table, column, err)
+		}
+	}
+	return nil
+}
+
+// migrateChatTagsTenant rebuilds a pre-tenant chat_tags table, keeping
+// existing tags in the default tenant
+func migrateChatTagsTenant(db *sql.DB) error {
+	var hasTenant bool
+	if err := db.QueryRow(`SELECT COUNT(*) > 0 FROM pragma_table_info('chat_tags') WHERE name = 'tenant'`).Scan(&hasTenant); err != nil {
+		return err
+	}
+	if hasTenant {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(`
+		CREATE TABLE chat_tags_new (
+			tenant TEXT NOT NULL DEFAULT 'default',
+			chat_jid TEXT NOT NULL,
+			tag TEXT NOT NULL,
+			PRIMARY KEY (tenant, chat_jid, tag)
+		);
+		INSERT INTO chat_tags_new (chat_jid, tag) SELECT chat_jid, tag FROM chat_tags;
+		DROP TABLE chat_tags;
+		ALTER TABLE chat_tags_new RENAME TO chat_tags;
+	`); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 // createTables creates all necessary database tables
 func createTables(db *sql.DB) error {
 	_, err := db.Exec(`
@@ -62,6 +218,16 @@ func createTables(db *sql.DB) error {
 			last_message_time TIMESTAMP
 		);
 
+		CREATE TABLE IF NOT EXISTS chat_read_state (
+			chat_jid TEXT PRIMARY KEY,
+			unread_count INTEGER NOT NULL DEFAULT 0,
+			unread_mentions INTEGER NOT NULL DEFAULT 0,
+			marked_unread BOOLEAN NOT NULL DEFAULT 0,
+			last_read_message_id TEXT,
+			last_read_at TIMESTAMP,
+			updated_at TIMESTAMP
+		);
+
 		CREATE TABLE IF NOT EXISTS messages (
 			id TEXT,
 			chat_jid TEXT,
@@ -77,10 +243,126 @@ func createTables(db *sql.DB) error {
 			file_sha256 BLOB,
 			file_enc_sha256 BLOB,
 			file_length INTEGER,
+			metadata_only BOOLEAN NOT NULL DEFAULT 0,
+			context TEXT,
+			local_path TEXT,
 			PRIMARY KEY (id, chat_jid),
 			FOREIGN KEY (chat_jid) REFERENCES chats(jid)
 		);
 
+		CREATE INDEX IF NOT EXISTS idx_messages_chat ON messages(chat_jid, timestamp);
+		CREATE INDEX IF NOT EXISTS idx_messages_timestamp ON messages(timestamp);
+
+		CREATE TABLE IF NOT EXISTS message_annotations (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			chat_jid TEXT NOT NULL,
+			message_id TEXT NOT NULL,
+			kind TEXT NOT NULL,
+			text TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			UNIQUE (chat_jid, message_id, kind)
+		);
+
+		-- Full-text index of messages.content and the message's annotations
+		-- (see indexedText), keyed by docid = messages.rowid. FTS4 rather than
+		-- FTS5, which the SQLite driver only builds with a tag. A replaced
+		-- message is dropped from the index before the insert that replaces
+		-- it, as REPLACE does not fire delete triggers. The insert and update
+		-- triggers are created afresh so databases that indexed only the
+		-- content pick up the annotations.
+		CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts USING fts4(text);
+
+		CREATE TRIGGER IF NOT EXISTS messages_fts_replace BEFORE INSERT ON messages BEGIN
+			DELETE FROM messages_fts WHERE docid = (SELECT rowid FROM messages WHERE id = new.id AND chat_jid = new.chat_jid);
+		END;
+		DROP TRIGGER IF EXISTS messages_fts_insert;
+		CREATE TRIGGER messages_fts_insert AFTER INSERT ON messages BEGIN
+			INSERT INTO messages_fts (docid, text) SELECT new.rowid, text FROM (SELECT ` + indexedText("new") + ` AS text) WHERE text != '';
+		END;
+		DROP TRIGGER IF EXISTS messages_fts_update;
+		CREATE TRIGGER messages_fts_update AFTER UPDATE OF content ON messages BEGIN
+			DELETE FROM messages_fts WHERE docid = old.rowid;
+			INSERT INTO messages_fts (docid, text) SELECT new.rowid, text FROM (SELECT ` + indexedText("new") + ` AS text) WHERE text != '';
+		END;
+		CREATE TRIGGER IF NOT EXISTS messages_fts_delete AFTER DELETE ON messages BEGIN
+			DELETE FROM messages_fts WHERE docid = old.rowid;
+		END;
+
+		-- Full-text index of message_annotations.text, keyed by docid = id
+		CREATE VIRTUAL TABLE IF NOT EXISTS message_annotations_fts USING fts4(text);
+
+		CREATE TABLE IF NOT EXISTS pinned_messages (
+			chat_jid TEXT NOT NULL,
+			message_id TEXT NOT NULL,
+			sender_jid TEXT,
+			pinned_by TEXT NOT NULL,
+			pinned_at TIMESTAMP NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (chat_jid, message_id)
+		);
+
+		CREATE TABLE IF NOT EXISTS polls (
+			chat_jid TEXT NOT NULL,
+			message_id TEXT NOT NULL,
+			creator_jid TEXT NOT NULL,
+			question TEXT NOT NULL,
+			options TEXT NOT NULL,
+			selectable_count INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (chat_jid, message_id)
+		);
+
+		CREATE TABLE IF NOT EXISTS poll_votes (
+			chat_jid TEXT NOT NULL,
+			poll_id TEXT NOT NULL,
+			voter_jid TEXT NOT NULL,
+			options TEXT NOT NULL,
+			voted_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (chat_jid, poll_id, voter_jid)
+		);
+
+		CREATE TABLE IF NOT EXISTS message_reactions (
+			chat_jid TEXT NOT NULL,
+			message_id TEXT NOT NULL,
+			sender_jid TEXT NOT NULL,
+			emoji TEXT NOT NULL,
+			reacted_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (chat_jid, message_id, sender_jid)
+		);
+
+		CREATE TABLE IF NOT EXISTS message_edits (
+			chat_jid TEXT NOT NULL,
+			message_id TEXT NOT NULL,
+			content TEXT NOT NULL,
+			edited_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (chat_jid, message_id, edited_at)
+		);
+
+		CREATE TABLE IF NOT EXISTS revoked_messages (
+			chat_jid TEXT NOT NULL,
+			message_id TEXT NOT NULL,
+			revoked_by TEXT NOT NULL,
+			revoked_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (chat_jid, message_id)
+		);
+
+		CREATE TABLE IF NOT EXISTS kept_messages (
+			chat_jid TEXT NOT NULL,
+			message_id TEXT NOT NULL,
+			kept_by TEXT NOT NULL,
+			kept_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (chat_jid, message_id)
+		);
+
+		CREATE TABLE IF NOT EXISTS disappearing_messages (
+			chat_jid TEXT NOT NULL,
+			message_id TEXT NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (chat_jid, message_id)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_disappearing_messages_expiry ON disappearing_messages(expires_at);
+
 		CREATE TABLE IF NOT EXISTS contact_nicknames (
 			jid TEXT PRIMARY KEY,
 			nickname TEXT NOT NULL,
@@ -94,6 +376,14 @@ func createTables(db *sql.DB) error {
 			webhook_url TEXT NOT NULL,
 			secret_token TEXT,
 			enabled BOOLEAN DEFAULT 1,
+			filter_expression TEXT,
+			tenant TEXT NOT NULL DEFAULT 'default',
+			payload_version INTEGER NOT NULL DEFAULT 1,
+			content_type TEXT,
+			body_template TEXT,
+			max_content_length INTEGER NOT NULL DEFAULT 0,
+			max_payload_bytes INTEGER NOT NULL DEFAULT 0,
+			oversize_action TEXT,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 		);
@@ -104,7 +394,9 @@ func createTables(db *sql.DB) error {
 			trigger_type TEXT NOT NULL,
 			trigger_value TEXT,
 			match_type TEXT DEFAULT 'exact',
-			enabled BOOLEAN DEFAULT 1
+			enabled BOOLEAN DEFAULT 1,
+			match_count INTEGER NOT NULL DEFAULT 0,
+			last_matched_at TIMESTAMP
 		);
 
 		CREATE TABLE IF NOT EXISTS webhook_logs (
@@ -121,6 +413,252 @@ func createTables(db *sql.DB) error {
 			delivered_at TIMESTAMP,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 		);
+
+		CREATE INDEX IF NOT EXISTS idx_webhook_logs_chat ON webhook_logs(chat_jid, created_at);
+
+		CREATE TABLE IF NOT EXISTS webhook_dispatches (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			chat_jid TEXT NOT NULL,
+			message_id TEXT NOT NULL,
+			chat_name TEXT NOT NULL DEFAULT '',
+			event BLOB NOT NULL,
+			created_at TIMESTAMP NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			webhook_config_id INTEGER NOT NULL,
+			chat_jid TEXT NOT NULL DEFAULT '',
+			message_id TEXT NOT NULL DEFAULT '',
+			trigger_json TEXT NOT NULL,
+			payload TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS routing_profiles (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE,
+			description TEXT,
+			exclusive BOOLEAN DEFAULT 0,
+			enabled BOOLEAN DEFAULT 1,
+			tenant TEXT NOT NULL DEFAULT 'default',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS routing_profile_webhooks (
+			profile_id INTEGER REFERENCES routing_profiles(id),
+			webhook_config_id INTEGER REFERENCES webhook_configs(id),
+			PRIMARY KEY (profile_id, webhook_config_id)
+		);
+
+		CREATE TABLE IF NOT EXISTS routing_profile_targets (
+			profile_id INTEGER REFERENCES routing_profiles(id),
+			target_type TEXT NOT NULL,
+			target_value TEXT NOT NULL,
+			PRIMARY KEY (profile_id, target_type, target_value)
+		);
+
+		CREATE TABLE IF NOT EXISTS bridge_settings (
+			key TEXT PRIMARY KEY,
+			value TEXT NOT NULL,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS chat_tags (
+			tenant TEXT NOT NULL DEFAULT 'default',
+			chat_jid TEXT NOT NULL,
+			tag TEXT NOT NULL,
+			PRIMARY KEY (tenant, chat_jid, tag)
+		);
+
+		CREATE TABLE IF NOT EXISTS outgoing_messages (
+			message_id TEXT PRIMARY KEY,
+			chat_jid TEXT NOT NULL,
+			content TEXT,
+			status TEXT NOT NULL,
+			error TEXT,
+			tenant TEXT NOT NULL DEFAULT 'default',
+			origin TEXT,
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_outgoing_messages_status ON outgoing_messages(status, created_at);
+
+		CREATE TABLE IF NOT EXISTS message_receipts (
+			chat_jid TEXT NOT NULL,
+			message_id TEXT NOT NULL,
+			recipient_jid TEXT NOT NULL,
+			status TEXT NOT NULL,
+			received_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (chat_jid, message_id, recipient_jid, status)
+		);
+
+		CREATE TABLE IF NOT EXISTS outbox_jobs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			priority TEXT NOT NULL,
+			kind TEXT NOT NULL,
+			recipient TEXT NOT NULL,
+			tenant TEXT NOT NULL DEFAULT 'default',
+			payload TEXT NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS location_points (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			chat_jid TEXT NOT NULL,
+			sender_jid TEXT NOT NULL,
+			message_id TEXT NOT NULL,
+			latitude REAL NOT NULL,
+			longitude REAL NOT NULL,
+			accuracy_meters INTEGER,
+			speed_mps REAL,
+			heading INTEGER,
+			caption TEXT,
+			sequence INTEGER,
+			timestamp TIMESTAMP NOT NULL,
+			UNIQUE (chat_jid, sender_jid, message_id, sequence)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_location_points_sender ON location_points(sender_jid, timestamp);
+
+		CREATE TABLE IF NOT EXISTS commerce_messages (
+			message_id TEXT NOT NULL,
+			chat_jid TEXT NOT NULL,
+			sender_jid TEXT NOT NULL,
+			is_from_me BOOLEAN NOT NULL,
+			kind TEXT NOT NULL,
+			reference_id TEXT,
+			title TEXT,
+			status TEXT,
+			note TEXT,
+			seller_jid TEXT,
+			item_count INTEGER NOT NULL DEFAULT 0,
+			items TEXT,
+			amount_1000 INTEGER NOT NULL DEFAULT 0,
+			currency TEXT,
+			timestamp TIMESTAMP NOT NULL,
+			PRIMARY KEY (message_id, chat_jid)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_commerce_messages_kind ON commerce_messages(kind, timestamp);
+
+		CREATE TABLE IF NOT EXISTS sticker_packs (
+			pack_id TEXT PRIMARY KEY,
+			name TEXT,
+			publisher TEXT,
+			description TEXT,
+			stickers TEXT,
+			chat_jid TEXT NOT NULL,
+			message_id TEXT NOT NULL,
+			sender_jid TEXT NOT NULL,
+			direct_path TEXT,
+			media_key BLOB,
+			file_sha256 BLOB,
+			file_enc_sha256 BLOB,
+			file_length INTEGER NOT NULL DEFAULT 0,
+			seen_at TIMESTAMP NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS calendar_events (
+			chat_jid TEXT NOT NULL,
+			message_id TEXT NOT NULL,
+			creator_jid TEXT NOT NULL,
+			name TEXT NOT NULL,
+			description TEXT,
+			location TEXT,
+			latitude REAL NOT NULL DEFAULT 0,
+			longitude REAL NOT NULL DEFAULT 0,
+			join_link TEXT,
+			start_time TIMESTAMP NOT NULL,
+			end_time TIMESTAMP,
+			canceled BOOLEAN NOT NULL DEFAULT 0,
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (chat_jid, message_id)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_calendar_events_start ON calendar_events(start_time);
+
+		CREATE TABLE IF NOT EXISTS pending_approvals (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			tenant TEXT NOT NULL,
+			recipient TEXT NOT NULL,
+			route TEXT NOT NULL DEFAULT '/api/send',
+			request TEXT NOT NULL,
+			body TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			decided_by TEXT NOT NULL DEFAULT '',
+			decided_at TIMESTAMP,
+			reason TEXT NOT NULL DEFAULT '',
+			message_id TEXT NOT NULL DEFAULT '',
+			error TEXT NOT NULL DEFAULT ''
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_pending_approvals_status ON pending_approvals(status, created_at);
+
+		CREATE TABLE IF NOT EXISTS approval_notifications (
+			message_id TEXT PRIMARY KEY,
+			approval_id INTEGER NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS api_usage (
+			key_name TEXT NOT NULL,
+			period TEXT NOT NULL,
+			category TEXT NOT NULL,
+			count INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (key_name, period, category)
+		);
+
+		CREATE TABLE IF NOT EXISTS leader_leases (
+			name TEXT PRIMARY KEY,
+			holder TEXT NOT NULL,
+			expires_at INTEGER NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS auto_replies (
+			kind TEXT NOT NULL,
+			contact_jid TEXT NOT NULL,
+			reply_window TEXT NOT NULL,
+			PRIMARY KEY (kind, contact_jid)
+		);
+
+		CREATE TABLE IF NOT EXISTS blocked_contacts (
+			jid TEXT PRIMARY KEY,
+			blocked_at TIMESTAMP NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS blocklist_changes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			jid TEXT NOT NULL,
+			action TEXT NOT NULL,
+			changed_at TIMESTAMP NOT NULL
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_blocklist_changes_time ON blocklist_changes(changed_at);
+
+		CREATE TABLE IF NOT EXISTS group_settings (
+			group_jid TEXT NOT NULL,
+			setting TEXT NOT NULL,
+			value TEXT NOT NULL,
+			updated_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (group_jid, setting)
+		);
+
+		CREATE TABLE IF NOT EXISTS account_restrictions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			reason TEXT NOT NULL,
+			code INTEGER NOT NULL DEFAULT 0,
+			description TEXT,
+			since TIMESTAMP NOT NULL,
+			until TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_account_restrictions_since ON account_restrictions(since);
 	`)
 	return err
 }
@@ -130,6 +668,28 @@ func (store *MessageStore) Close() error {
 	return store.db.Close()
 }
 
+// IntegrityCheck runs SQLite's quick_check and returns the problems it finds,
+// or nil if the database is intact
+func (store *MessageStore) IntegrityCheck() ([]string, error) {
+	rows, err := store.db.Query(`PRAGMA quick_check`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check database integrity: %v", err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return nil, fmt.Errorf("failed to read integrity check: %v", err)
+		}
+		if line != "ok" {
+			problems = append(problems, line)
+		}
+	}
+	return problems, rows.Err()
+}
+
 // GetDB returns the underlying database connection for direct access
 func (store *MessageStore) GetDB() *sql.DB {
 	return store.db
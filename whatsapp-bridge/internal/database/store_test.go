@@ -0,0 +1,28 @@
+package database
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+)
+
+func TestIntegrityCheck(t *testing.T) {
+	tempDB := "test_store.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+	problems, err := store.IntegrityCheck()
+	if err != nil || len(problems) != 0 {
+		t.Errorf("IntegrityCheck() = %v, %v, want no problems", problems, err)
+	}
+}
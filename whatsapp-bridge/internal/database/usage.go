@@ -0,0 +1,59 @@
+package database
+
+import (
+	"fmt"
+
+	"whatsapp-bridge/internal/types"
+)
+
+// IncrementUsage counts one request for an API key in a usage category and
+// period. With a non-zero hardLimit the count is only incremented while it is
+// below the limit; allowed is false when the request would exceed it.
+func (store *MessageStore) IncrementUsage(keyName, period, category string, hardLimit int64) (count int64, allowed bool, err error) {
+	result, err := store.db.Exec(
+		`INSERT INTO api_usage (key_name, period, category, count) VALUES (?, ?, ?, 1)
+		 ON CONFLICT(key_name, period, category) DO UPDATE SET count = count + 1
+		 WHERE ? = 0 OR count < ?`,
+		keyName, period, category, hardLimit, hardLimit,
+	)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to record usage: %v", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get rows affected: %v", err)
+	}
+
+	err = store.db.QueryRow(
+		"SELECT count FROM api_usage WHERE key_name = ? AND period = ? AND category = ?",
+		keyName, period, category,
+	).Scan(&count)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read usage: %v", err)
+	}
+	return count, rows > 0, nil
+}
+
+// GetUsage returns all usage counters for a period, ordered by key and category
+func (store *MessageStore) GetUsage(period string) ([]types.UsageCounter, error) {
+	rows, err := store.db.Query(
+		`SELECT key_name, period, category, count FROM api_usage
+		 WHERE period = ? ORDER BY key_name, category`,
+		period,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query usage: %v", err)
+	}
+	defer rows.Close()
+
+	counters := []types.UsageCounter{}
+	for rows.Next() {
+		var c types.UsageCounter
+		if err := rows.Scan(&c.KeyName, &c.Period, &c.Category, &c.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan usage: %v", err)
+		}
+		counters = append(counters, c)
+	}
+	return counters, rows.Err()
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+)
+
+func TestIncrementUsage(t *testing.T) {
+	tempDB := "test_usage.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+
+	// Hard limit of 2: the third request is refused and not counted
+	for i, want := range []struct {
+		count   int64
+		allowed bool
+	}{{1, true}, {2, true}, {2, false}} {
+		count, allowed, err := store.IncrementUsage("team-a", "2025-03", "send", 2)
+		if err != nil {
+			t.Fatalf("IncrementUsage failed: %v", err)
+		}
+		if count != want.count || allowed != want.allowed {
+			t.Errorf("Request %d: got count=%d allowed=%v, want count=%d allowed=%v", i+1, count, allowed, want.count, want.allowed)
+		}
+	}
+
+	// Unlimited category and other periods are counted independently
+	for i := 0; i < 3; i++ {
+		if _, allowed, err := store.IncrementUsage("team-a", "2025-03", "read", 0); err != nil || !allowed {
+			t.Fatalf("Unlimited increment refused: allowed=%v err=%v", allowed, err)
+		}
+	}
+	if _, _, err := store.IncrementUsage("team-a", "2025-04", "send", 2); err != nil {
+		t.Fatalf("IncrementUsage failed: %v", err)
+	}
+
+	counters, err := store.GetUsage("2025-03")
+	if err != nil {
+		t.Fatalf("GetUsage failed: %v", err)
+	}
+	if len(counters) != 2 {
+		t.Fatalf("Expected 2 counters, got %d", len(counters))
+	}
+	if counters[0].Category != "read" || counters[0].Count != 3 || counters[1].Category != "send" || counters[1].Count != 2 {
+		t.Errorf("Unexpected counters: %+v", counters)
+	}
+}
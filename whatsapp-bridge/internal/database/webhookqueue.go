@@ -0,0 +1,192 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"whatsapp-bridge/internal/types"
+)
+
+// Ingest stores a received message and the record that its webhooks are
+// still to be sent in one transaction, so a crash cannot keep the message
+// and lose its webhooks. Commit or Rollback ends it.
+type Ingest struct {
+	store *MessageStore
+	tx    *sql.Tx
+}
+
+// BeginIngest starts storing a received message
+func (store *MessageStore) BeginIngest() (*Ingest, error) {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	return &Ingest{store: store, tx: tx}, nil
+}
+
+// StoreMessage stores the message like MessageStore.StoreMessage
+func (in *Ingest) StoreMessage(id, chatJID, sender, senderName, content string, timestamp time.Time, isFromMe bool,
+	mediaType, filename, url string, mediaKey, fileSHA256, fileEncSHA256 []byte, fileLength uint64, msgContext *types.MessageContext) error {
+	return storeMessage(in.tx, id, chatJID, sender, senderName, content, timestamp, isFromMe,
+		mediaType, filename, url, mediaKey, fileSHA256, fileEncSHA256, fileLength, msgContext)
+}
+
+// StoreMessageMetadata stores the message like MessageStore.StoreMessageMetadata
+func (in *Ingest) StoreMessageMetadata(id, chatJID, sender, senderName string, timestamp time.Time, isFromMe bool, mediaType string, fileLength uint64) error {
+	return storeMessageMetadata(in.tx, id, chatJID, sender, senderName, timestamp, isFromMe, mediaType, fileLength)
+}
+
+// QueueWebhookDispatch records that the message's webhooks are still to be
+// matched and sets the dispatch's ID
+func (in *Ingest) QueueWebhookDispatch(d *types.WebhookDispatch) error {
+	d.CreatedAt = d.CreatedAt.UTC()
+	result, err := in.tx.Exec(
+		`INSERT INTO webhook_dispatches (chat_jid, message_id, chat_name, event, created_at) VALUES (?, ?, ?, ?, ?)`,
+		d.ChatJID, d.MessageID, d.ChatName, d.Event, d.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store webhook dispatch: %v", err)
+	}
+	d.ID, err = result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get webhook dispatch ID: %v", err)
+	}
+	return nil
+}
+
+// Commit stores everything written
+func (in *Ingest) Commit() error {
+	if err := in.tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit message: %v", err)
+	}
+	in.store.notifyMessageStored()
+	return nil
+}
+
+// Rollback discards everything written; after Commit it does nothing
+func (in *Ingest) Rollback() {
+	in.tx.Rollback()
+}
+
+// ListWebhookDispatches returns the messages whose webhooks were never
+// matched, oldest first
+func (store *MessageStore) ListWebhookDispatches() ([]types.WebhookDispatch, error) {
+	rows, err := store.db.Query(
+		`SELECT id, chat_jid, message_id, chat_name, event, created_at FROM webhook_dispatches ORDER BY id`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook dispatches: %v", err)
+	}
+	defer rows.Close()
+
+	var dispatches []types.WebhookDispatch
+	for rows.Next() {
+		var d types.WebhookDispatch
+		if err := rows.Scan(&d.ID, &d.ChatJID, &d.MessageID, &d.ChatName, &d.Event, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook dispatch: %v", err)
+		}
+		dispatches = append(dispatches, d)
+	}
+	return dispatches, rows.Err()
+}
+
+// QueueWebhookDeliveries stores the deliveries a message or event matched,
+// setting their IDs, and in the same transaction counts the matches of their
+// triggers and removes the message's dispatch (0 when there is none). With
+// no deliveries it only removes the dispatch.
+func (store *MessageStore) QueueWebhookDeliveries(dispatchID int64, deliveries []*types.WebhookDelivery) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// The synthetic filter expression trigger is not stored, so not counted
+	matches := make(map[int]int)
+	var matchedAt time.Time
+	for _, d := range deliveries {
+		if d.Trigger.ID != 0 {
+			matches[d.Trigger.ID]++
+		}
+		if d.CreatedAt.After(matchedAt) {
+			matchedAt = d.CreatedAt
+		}
+
+		trigger, err := json.Marshal(d.Trigger)
+		if err != nil {
+			return fmt.Errorf("failed to encode webhook trigger: %v", err)
+		}
+		payload, err := json.Marshal(d.Payload)
+		if err != nil {
+			return fmt.Errorf("failed to encode webhook payload: %v", err)
+		}
+		d.CreatedAt = d.CreatedAt.UTC()
+
+		result, err := tx.Exec(
+			`INSERT INTO webhook_deliveries (webhook_config_id, chat_jid, message_id, trigger_json, payload, created_at)
+			 VALUES (?, ?, ?, ?, ?, ?)`,
+			d.WebhookConfigID, d.ChatJID, d.MessageID, string(trigger), string(payload), d.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to store webhook delivery: %v", err)
+		}
+		if d.ID, err = result.LastInsertId(); err != nil {
+			return fmt.Errorf("failed to get webhook delivery ID: %v", err)
+		}
+	}
+
+	if err := recordTriggerMatches(tx, matches, matchedAt); err != nil {
+		return err
+	}
+
+	if dispatchID != 0 {
+		if _, err := tx.Exec(`DELETE FROM webhook_dispatches WHERE id = ?`, dispatchID); err != nil {
+			return fmt.Errorf("failed to remove webhook dispatch: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit webhook deliveries: %v", err)
+	}
+	return nil
+}
+
+// ListWebhookDeliveries returns the deliveries not yet delivered or given up
+// on, oldest first
+func (store *MessageStore) ListWebhookDeliveries() ([]types.WebhookDelivery, error) {
+	rows, err := store.db.Query(
+		`SELECT id, webhook_config_id, chat_jid, message_id, trigger_json, payload, created_at
+		 FROM webhook_deliveries ORDER BY id`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %v", err)
+	}
+	defer rows.Close()
+
+	var deliveries []types.WebhookDelivery
+	for rows.Next() {
+		var d types.WebhookDelivery
+		var trigger, payload string
+		if err := rows.Scan(&d.ID, &d.WebhookConfigID, &d.ChatJID, &d.MessageID, &trigger, &payload, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %v", err)
+		}
+		if err := json.Unmarshal([]byte(trigger), &d.Trigger); err != nil {
+			return nil, fmt.Errorf("failed to decode trigger of webhook delivery %d: %v", d.ID, err)
+		}
+		if err := json.Unmarshal([]byte(payload), &d.Payload); err != nil {
+			return nil, fmt.Errorf("failed to decode payload of webhook delivery %d: %v", d.ID, err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// FinishWebhookDelivery removes a delivery once it was delivered or given up on
+func (store *MessageStore) FinishWebhookDelivery(id int64) error {
+	if _, err := store.db.Exec(`DELETE FROM webhook_deliveries WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to finish webhook delivery: %v", err)
+	}
+	return nil
+}
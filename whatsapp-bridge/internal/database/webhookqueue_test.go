@@ -0,0 +1,100 @@
+package database
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"whatsapp-bridge/internal/types"
+)
+
+func TestWebhookHandoff(t *testing.T) {
+	tempDB := "test_webhook_handoff.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+	now := time.Now().UTC()
+
+	// A rolled back ingest leaves neither the message nor its dispatch
+	in, err := store.BeginIngest()
+	if err != nil {
+		t.Fatalf("BeginIngest: %v", err)
+	}
+	if err := in.StoreMessage("m0", "chat@s.whatsapp.net", "1555", "Ann", "lost", now, false, "", "", "", nil, nil, nil, 0, nil); err != nil {
+		t.Fatalf("StoreMessage: %v", err)
+	}
+	if err := in.QueueWebhookDispatch(&types.WebhookDispatch{ChatJID: "chat@s.whatsapp.net", MessageID: "m0", Event: []byte("e0"), CreatedAt: now}); err != nil {
+		t.Fatalf("QueueWebhookDispatch: %v", err)
+	}
+	in.Rollback()
+
+	// A committed one keeps both
+	in, err = store.BeginIngest()
+	if err != nil {
+		t.Fatalf("BeginIngest: %v", err)
+	}
+	defer in.Rollback()
+	if err := in.StoreMessage("m1", "chat@s.whatsapp.net", "1555", "Ann", "hello", now, false, "", "", "", nil, nil, nil, 0, nil); err != nil {
+		t.Fatalf("StoreMessage: %v", err)
+	}
+	dispatch := types.WebhookDispatch{ChatJID: "chat@s.whatsapp.net", MessageID: "m1", ChatName: "Ann", Event: []byte("e1"), CreatedAt: now}
+	if err := in.QueueWebhookDispatch(&dispatch); err != nil || dispatch.ID == 0 {
+		t.Fatalf("QueueWebhookDispatch = %d, %v", dispatch.ID, err)
+	}
+	if err := in.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	if msgs, err := store.GetMessages("chat@s.whatsapp.net", 10); err != nil || len(msgs) != 1 || msgs[0].Content != "hello" {
+		t.Fatalf("GetMessages = %+v, %v", msgs, err)
+	}
+	dispatches, err := store.ListWebhookDispatches()
+	if err != nil || len(dispatches) != 1 || dispatches[0].ID != dispatch.ID || string(dispatches[0].Event) != "e1" || dispatches[0].ChatName != "Ann" {
+		t.Fatalf("ListWebhookDispatches = %+v, %v", dispatches, err)
+	}
+
+	// Queueing the deliveries takes the dispatch's place
+	deliveries := []*types.WebhookDelivery{
+		{WebhookConfigID: 1, ChatJID: "chat@s.whatsapp.net", MessageID: "m1", CreatedAt: now,
+			Trigger: types.WebhookTrigger{ID: 4, TriggerType: "keyword", TriggerValue: "hello"},
+			Payload: types.WebhookPayload{EventType: "message_received", Message: types.WebhookMessageInfo{ID: "m1", Content: "hello"}}},
+		{WebhookConfigID: 2, ChatJID: "chat@s.whatsapp.net", MessageID: "m1", CreatedAt: now,
+			Trigger: types.WebhookTrigger{TriggerType: "all"},
+			Payload: types.WebhookPayload{EventType: "message_received", Message: types.WebhookMessageInfo{ID: "m1"}}},
+	}
+	if err := store.QueueWebhookDeliveries(dispatch.ID, deliveries); err != nil {
+		t.Fatalf("QueueWebhookDeliveries: %v", err)
+	}
+	if deliveries[0].ID == 0 || deliveries[1].ID <= deliveries[0].ID {
+		t.Errorf("delivery IDs = %d, %d", deliveries[0].ID, deliveries[1].ID)
+	}
+	if dispatches, _ := store.ListWebhookDispatches(); len(dispatches) != 0 {
+		t.Errorf("dispatch still listed after queueing: %+v", dispatches)
+	}
+
+	queued, err := store.ListWebhookDeliveries()
+	if err != nil || len(queued) != 2 {
+		t.Fatalf("ListWebhookDeliveries = %+v, %v", queued, err)
+	}
+	if queued[0].Trigger.ID != 4 || queued[0].Trigger.TriggerValue != "hello" || queued[0].Payload.Message.Content != "hello" {
+		t.Errorf("first delivery = %+v", queued[0])
+	}
+
+	if err := store.FinishWebhookDelivery(deliveries[0].ID); err != nil {
+		t.Fatalf("FinishWebhookDelivery: %v", err)
+	}
+	if queued, _ := store.ListWebhookDeliveries(); len(queued) != 1 || queued[0].WebhookConfigID != 2 {
+		t.Errorf("after finishing one = %+v", queued)
+	}
+}
@@ -1,16 +1,22 @@
 package database
 
 import (
+	"database/sql"
 	"fmt"
+	"strings"
+	"time"
+	"whatsapp-bridge/internal/tenant"
 	"whatsapp-bridge/internal/types"
 )
 
 // StoreWebhookConfig stores a webhook configuration in the database
 func (store *MessageStore) StoreWebhookConfig(config *types.WebhookConfig) error {
 	result, err := store.db.Exec(
-		`INSERT INTO webhook_configs (name, webhook_url, secret_token, enabled) 
-		 VALUES (?, ?, ?, ?)`,
-		config.Name, config.WebhookURL, config.SecretToken, config.Enabled,
+		`INSERT INTO webhook_configs (name, webhook_url, secret_token, enabled, filter_expression, tenant, payload_version, content_type, body_template,
+		 max_content_length, max_payload_bytes, oversize_action) 
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		config.Name, config.WebhookURL, config.SecretToken, config.Enabled, config.FilterExpression, tenant.Owner(config.Tenant), config.PayloadVersion,
+		config.ContentType, config.BodyTemplate, config.MaxContentLength, config.MaxPayloadBytes, config.OversizeAction,
 	)
 	if err != nil {
 		return err
@@ -38,10 +44,14 @@ func (store *MessageStore) StoreWebhookConfig(config *types.WebhookConfig) error
 func (store *MessageStore) GetWebhookConfig(id int) (*types.WebhookConfig, error) {
 	config := &types.WebhookConfig{}
 	err := store.db.QueryRow(
-		`SELECT id, name, webhook_url, secret_token, enabled, created_at, updated_at 
+		`SELECT id, name, webhook_url, secret_token, enabled, COALESCE(filter_expression, ''), tenant, payload_version,
+		 COALESCE(content_type, ''), COALESCE(body_template, ''), max_content_length, max_payload_bytes, COALESCE(oversize_action, ''),
+		 created_at, updated_at 
 		 FROM webhook_configs WHERE id = ?`, id,
 	).Scan(&config.ID, &config.Name, &config.WebhookURL, &config.SecretToken,
-		&config.Enabled, &config.CreatedAt, &config.UpdatedAt)
+		&config.Enabled, &config.FilterExpression, &config.Tenant, &config.PayloadVersion,
+		&config.ContentType, &config.BodyTemplate, &config.MaxContentLength, &config.MaxPayloadBytes, &config.OversizeAction,
+		&config.CreatedAt, &config.UpdatedAt)
 
 	if err != nil {
 		return nil, err
@@ -59,7 +69,9 @@ func (store *MessageStore) GetWebhookConfig(id int) (*types.WebhookConfig, error
 // GetAllWebhookConfigs retrieves all webhook configurations
 func (store *MessageStore) GetAllWebhookConfigs() ([]*types.WebhookConfig, error) {
 	rows, err := store.db.Query(
-		`SELECT id, name, webhook_url, secret_token, enabled, created_at, updated_at 
+		`SELECT id, name, webhook_url, secret_token, enabled, COALESCE(filter_expression, ''), tenant, payload_version,
+		 COALESCE(content_type, ''), COALESCE(body_template, ''), max_content_length, max_payload_bytes, COALESCE(oversize_action, ''),
+		 created_at, updated_at 
 		 FROM webhook_configs ORDER BY created_at DESC`)
 	if err != nil {
 		return nil, err
@@ -70,7 +82,9 @@ func (store *MessageStore) GetAllWebhookConfigs() ([]*types.WebhookConfig, error
 	for rows.Next() {
 		config := &types.WebhookConfig{}
 		err := rows.Scan(&config.ID, &config.Name, &config.WebhookURL, &config.SecretToken,
-			&config.Enabled, &config.CreatedAt, &config.UpdatedAt)
+			&config.Enabled, &config.FilterExpression, &config.Tenant, &config.PayloadVersion,
+			&config.ContentType, &config.BodyTemplate, &config.MaxContentLength, &config.MaxPayloadBytes, &config.OversizeAction,
+			&config.CreatedAt, &config.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -101,8 +115,11 @@ func (store *MessageStore) UpdateWebhookConfig(config *types.WebhookConfig) erro
 	// Update the main webhook configuration
 	result, err := tx.Exec(
 		`UPDATE webhook_configs SET name = ?, webhook_url = ?, secret_token = ?, 
-		 enabled = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
-		config.Name, config.WebhookURL, config.SecretToken, config.Enabled, config.ID,
+		 enabled = ?, filter_expression = ?, payload_version = ?,
+		 content_type = ?, body_template = ?, max_content_length = ?, max_payload_bytes = ?, oversize_action = ?,
+		 updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
+		config.Name, config.WebhookURL, config.SecretToken, config.Enabled, config.FilterExpression, config.PayloadVersion,
+		config.ContentType, config.BodyTemplate, config.MaxContentLength, config.MaxPayloadBytes, config.OversizeAction, config.ID,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to update webhook config: %v", err)
@@ -117,6 +134,12 @@ func (store *MessageStore) UpdateWebhookConfig(config *types.WebhookConfig) erro
 		return fmt.Errorf("webhook with ID %d not found", config.ID)
 	}
 
+	// Triggers that are kept as they were keep their hit counters
+	hits, err := triggerHits(tx, config.ID)
+	if err != nil {
+		return fmt.Errorf("failed to read trigger hit counts: %v", err)
+	}
+
 	// Delete existing triggers
 	_, err = tx.Exec("DELETE FROM webhook_triggers WHERE webhook_config_id = ?", config.ID)
 	if err != nil {
@@ -126,11 +149,14 @@ func (store *MessageStore) UpdateWebhookConfig(config *types.WebhookConfig) erro
 	// Insert new triggers
 	for i := range config.Triggers {
 		config.Triggers[i].WebhookConfigID = config.ID
+		hit := hits[triggerKey(&config.Triggers[i])]
+		config.Triggers[i].MatchCount, config.Triggers[i].LastMatchedAt = hit.MatchCount, hit.LastMatchedAt
 		result, err := tx.Exec(
-			`INSERT INTO webhook_triggers (webhook_config_id, trigger_type, trigger_value, match_type, enabled) 
-			 VALUES (?, ?, ?, ?, ?)`,
+			`INSERT INTO webhook_triggers (webhook_config_id, trigger_type, trigger_value, match_type, enabled, match_count, last_matched_at) 
+			 VALUES (?, ?, ?, ?, ?, ?, ?)`,
 			config.Triggers[i].WebhookConfigID, config.Triggers[i].TriggerType,
 			config.Triggers[i].TriggerValue, config.Triggers[i].MatchType, config.Triggers[i].Enabled,
+			hit.MatchCount, hit.LastMatchedAt,
 		)
 		if err != nil {
 			return fmt.Errorf("failed to insert trigger %d: %v", i, err)
@@ -152,6 +178,48 @@ func (store *MessageStore) UpdateWebhookConfig(config *types.WebhookConfig) erro
 	return nil
 }
 
+// triggerKey identifies a trigger by what it matches
+func triggerKey(trigger *types.WebhookTrigger) [3]string {
+	return [3]string{trigger.TriggerType, trigger.TriggerValue, trigger.MatchType}
+}
+
+// triggerHits returns the hit counters of a webhook's triggers by triggerKey
+func triggerHits(tx *sql.Tx, webhookConfigID int) (map[[3]string]types.WebhookTrigger, error) {
+	rows, err := tx.Query(
+		`SELECT trigger_type, COALESCE(trigger_value, ''), COALESCE(match_type, ''), match_count, last_matched_at
+		 FROM webhook_triggers WHERE webhook_config_id = ?`, webhookConfigID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hits := make(map[[3]string]types.WebhookTrigger)
+	for rows.Next() {
+		var t types.WebhookTrigger
+		if err := rows.Scan(&t.TriggerType, &t.TriggerValue, &t.MatchType, &t.MatchCount, &t.LastMatchedAt); err != nil {
+			return nil, err
+		}
+		hits[triggerKey(&t)] = t
+	}
+	return hits, rows.Err()
+}
+
+// recordTriggerMatches adds to the times each trigger made its webhook fire,
+// keyed by trigger ID, the last of them at the given time
+func recordTriggerMatches(tx *sql.Tx, counts map[int]int, at time.Time) error {
+	for triggerID, n := range counts {
+		_, err := tx.Exec(
+			`UPDATE webhook_triggers SET match_count = match_count + ?, last_matched_at = ? WHERE id = ?`,
+			n, at.UTC(), triggerID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to record trigger match: %v", err)
+		}
+	}
+	return nil
+}
+
 // DeleteWebhookConfig deletes a webhook configuration and its triggers and logs
 func (store *MessageStore) DeleteWebhookConfig(id int) error {
 	// First check if the webhook exists
@@ -170,6 +238,12 @@ func (store *MessageStore) DeleteWebhookConfig(id int) error {
 		return err
 	}
 
+	// Detach from routing profiles (foreign key constraint)
+	_, err = store.db.Exec("DELETE FROM routing_profile_webhooks WHERE webhook_config_id = ?", id)
+	if err != nil {
+		return err
+	}
+
 	// Delete triggers second (foreign key constraint)
 	_, err = store.db.Exec("DELETE FROM webhook_triggers WHERE webhook_config_id = ?", id)
 	if err != nil {
@@ -204,7 +278,7 @@ func (store *MessageStore) StoreWebhookTrigger(trigger *types.WebhookTrigger) er
 // GetWebhookTriggers retrieves all triggers for a webhook config
 func (store *MessageStore) GetWebhookTriggers(webhookConfigID int) ([]types.WebhookTrigger, error) {
 	rows, err := store.db.Query(
-		`SELECT id, webhook_config_id, trigger_type, trigger_value, match_type, enabled 
+		`SELECT id, webhook_config_id, trigger_type, trigger_value, match_type, enabled, match_count, last_matched_at 
 		 FROM webhook_triggers WHERE webhook_config_id = ?`, webhookConfigID,
 	)
 	if err != nil {
@@ -216,7 +290,7 @@ func (store *MessageStore) GetWebhookTriggers(webhookConfigID int) ([]types.Webh
 	for rows.Next() {
 		trigger := types.WebhookTrigger{}
 		err := rows.Scan(&trigger.ID, &trigger.WebhookConfigID, &trigger.TriggerType,
-			&trigger.TriggerValue, &trigger.MatchType, &trigger.Enabled)
+			&trigger.TriggerValue, &trigger.MatchType, &trigger.Enabled, &trigger.MatchCount, &trigger.LastMatchedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -246,20 +320,69 @@ func (store *MessageStore) StoreWebhookLog(log *types.WebhookLog) error {
 
 // GetWebhookLogs retrieves webhook logs with optional filtering
 func (store *MessageStore) GetWebhookLogs(webhookConfigID int, limit int) ([]*types.WebhookLog, error) {
-	query := `SELECT id, webhook_config_id, message_id, chat_jid, trigger_type, trigger_value, 
-		 payload, response_status, response_body, attempt_count, delivered_at, created_at 
-		 FROM webhook_logs`
+	return store.SearchWebhookLogs(types.WebhookLogFilter{WebhookConfigID: webhookConfigID, Limit: limit})
+}
+
+// webhookLogTime is how webhook_logs.created_at is written by SQLite
+const webhookLogTime = "2006-01-02 15:04:05"
 
+// webhookLogWhere builds the WHERE clause selecting the logs filter matches
+func webhookLogWhere(filter types.WebhookLogFilter) (string, []interface{}) {
+	var conds []string
 	var args []interface{}
-	if webhookConfigID > 0 {
-		query += " WHERE webhook_config_id = ?"
-		args = append(args, webhookConfigID)
+
+	if filter.WebhookConfigID > 0 {
+		conds = append(conds, "webhook_config_id = ?")
+		args = append(args, filter.WebhookConfigID)
+	}
+	if filter.Tenant != "" {
+		conds = append(conds, "webhook_config_id IN (SELECT id FROM webhook_configs WHERE tenant = ?)")
+		args = append(args, filter.Tenant)
+	}
+	if filter.ChatJID != "" {
+		conds = append(conds, "chat_jid = ?")
+		args = append(args, filter.ChatJID)
+	}
+	switch filter.StatusClass {
+	case types.WebhookStatus2xx, types.WebhookStatus3xx, types.WebhookStatus4xx, types.WebhookStatus5xx:
+		low := int(filter.StatusClass[0]-'0') * 100
+		conds = append(conds, "response_status >= ? AND response_status < ?")
+		args = append(args, low, low+100)
+	case types.WebhookStatusNoReply:
+		conds = append(conds, "COALESCE(response_status, 0) = 0")
+	}
+	if filter.FailedOnly {
+		conds = append(conds, "delivered_at IS NULL")
+	}
+	if !filter.Since.IsZero() {
+		conds = append(conds, "created_at >= ?")
+		args = append(args, filter.Since.UTC().Format(webhookLogTime))
+	}
+	if !filter.Until.IsZero() {
+		conds = append(conds, "created_at < ?")
+		args = append(args, filter.Until.UTC().Format(webhookLogTime))
 	}
 
-	query += " ORDER BY created_at DESC"
-	if limit > 0 {
-		query += " LIMIT ?"
-		args = append(args, limit)
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// SearchWebhookLogs returns the webhook logs filter selects, newest first
+// unless filter.Order is set
+func (store *MessageStore) SearchWebhookLogs(filter types.WebhookLogFilter) ([]*types.WebhookLog, error) {
+	order, err := orderBy(filter.Order, WebhookLogSortFields, "created_at DESC", "id DESC")
+	if err != nil {
+		return nil, err
+	}
+	where, args := webhookLogWhere(filter)
+	query := `SELECT id, webhook_config_id, message_id, chat_jid, trigger_type, trigger_value, 
+		 payload, response_status, response_body, attempt_count, delivered_at, created_at 
+		 FROM webhook_logs` + where + order
+	if filter.Limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, filter.Limit, filter.Offset)
 	}
 
 	rows, err := store.db.Query(query, args...)
@@ -282,3 +405,18 @@ func (store *MessageStore) GetWebhookLogs(webhookConfigID int, limit int) ([]*ty
 
 	return logs, nil
 }
+
+// SummarizeWebhookLogs counts all the webhook logs filter selects,
+// ignoring its limit and offset
+func (store *MessageStore) SummarizeWebhookLogs(filter types.WebhookLogFilter) (types.WebhookLogSummary, error) {
+	where, args := webhookLogWhere(filter)
+	var summary types.WebhookLogSummary
+	err := store.db.QueryRow(
+		`SELECT COUNT(*), COALESCE(SUM(delivered_at IS NOT NULL), 0), COALESCE(SUM(delivered_at IS NULL), 0)
+		 FROM webhook_logs`+where, args...,
+	).Scan(&summary.Total, &summary.Delivered, &summary.Failed)
+	if err != nil {
+		return summary, fmt.Errorf("failed to summarize webhook logs: %v", err)
+	}
+	return summary, nil
+}
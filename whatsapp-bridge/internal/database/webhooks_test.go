@@ -2,8 +2,10 @@ package database
 
 import (
 	"database/sql"
+	"fmt"
 	"os"
 	"testing"
+	"time"
 	"whatsapp-bridge/internal/types"
 )
 
@@ -57,6 +59,9 @@ func TestUpdateWebhookConfig(t *testing.T) {
 	config.Name = "Updated Test Webhook"
 	config.WebhookURL = "https://example.com/updated"
 	config.SecretToken = "newsecret456"
+	config.PayloadVersion = 2
+	config.MaxPayloadBytes = 65536
+	config.OversizeAction = "drop"
 	config.Triggers = []types.WebhookTrigger{
 		{
 			TriggerType:  "keyword",
@@ -94,6 +99,12 @@ func TestUpdateWebhookConfig(t *testing.T) {
 	if updatedConfig.SecretToken != "newsecret456" {
 		t.Errorf("Expected secret 'newsecret456', got '%s'", updatedConfig.SecretToken)
 	}
+	if updatedConfig.PayloadVersion != 2 {
+		t.Errorf("Expected payload version 2, got %d", updatedConfig.PayloadVersion)
+	}
+	if updatedConfig.MaxPayloadBytes != 65536 || updatedConfig.OversizeAction != "drop" {
+		t.Errorf("Expected a 65536 byte limit that drops, got %d %q", updatedConfig.MaxPayloadBytes, updatedConfig.OversizeAction)
+	}
 
 	// Verify the triggers were updated
 	if len(updatedConfig.Triggers) != 2 {
@@ -125,3 +136,157 @@ func TestUpdateWebhookConfig(t *testing.T) {
 
 	t.Log("✓ Webhook update test passed successfully")
 }
+
+func TestSearchWebhookLogs(t *testing.T) {
+	tempDB := "test_webhook_logs.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+	for _, config := range []*types.WebhookConfig{
+		{Name: "crm", WebhookURL: "https://example.com/crm"},
+		{Name: "support", WebhookURL: "https://example.com/support", Tenant: "support"},
+	} {
+		if err := store.StoreWebhookConfig(config); err != nil {
+			t.Fatalf("Failed to store webhook config: %v", err)
+		}
+	}
+
+	delivered := time.Now()
+	logs := []struct {
+		webhook int
+		chat    string
+		status  int
+		day     int
+	}{
+		{1, "a@s.whatsapp.net", 200, 1},
+		{1, "a@s.whatsapp.net", 503, 2},
+		{1, "b@s.whatsapp.net", 0, 2},
+		{1, "a@s.whatsapp.net", 404, 3},
+		{2, "a@s.whatsapp.net", 200, 3},
+	}
+	for i, l := range logs {
+		log := &types.WebhookLog{WebhookConfigID: l.webhook, ChatJID: l.chat, ResponseStatus: l.status, AttemptCount: 1}
+		if l.status == 200 {
+			log.DeliveredAt = &delivered
+		}
+		if err := store.StoreWebhookLog(log); err != nil {
+			t.Fatalf("Failed to store webhook log: %v", err)
+		}
+		if _, err := db.Exec(`UPDATE webhook_logs SET created_at = ? WHERE id = ?`, fmt.Sprintf("2024-06-%02d 12:00:00", l.day), i+1); err != nil {
+			t.Fatalf("Failed to date webhook log: %v", err)
+		}
+	}
+
+	june := func(day int) time.Time { return time.Date(2024, 6, day, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name    string
+		filter  types.WebhookLogFilter
+		wantIDs []int
+		want    types.WebhookLogSummary
+	}{
+		{"chat", types.WebhookLogFilter{ChatJID: "a@s.whatsapp.net"}, []int{5, 4, 2, 1}, types.WebhookLogSummary{Total: 4, Delivered: 2, Failed: 2}},
+		{"failed only", types.WebhookLogFilter{FailedOnly: true}, []int{4, 3, 2}, types.WebhookLogSummary{Total: 3, Failed: 3}},
+		{"server errors", types.WebhookLogFilter{StatusClass: types.WebhookStatus5xx}, []int{2}, types.WebhookLogSummary{Total: 1, Failed: 1}},
+		{"no response", types.WebhookLogFilter{StatusClass: types.WebhookStatusNoReply}, []int{3}, types.WebhookLogSummary{Total: 1, Failed: 1}},
+		{"date range", types.WebhookLogFilter{Since: june(2), Until: june(3)}, []int{3, 2}, types.WebhookLogSummary{Total: 2, Failed: 2}},
+		{"tenant", types.WebhookLogFilter{Tenant: "support"}, []int{5}, types.WebhookLogSummary{Total: 1, Delivered: 1}},
+		{"second page", types.WebhookLogFilter{WebhookConfigID: 1, Limit: 2, Offset: 2}, []int{2, 1}, types.WebhookLogSummary{Total: 4, Delivered: 1, Failed: 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.SearchWebhookLogs(tt.filter)
+			if err != nil {
+				t.Fatalf("SearchWebhookLogs: %v", err)
+			}
+			var ids []int
+			for _, log := range got {
+				ids = append(ids, log.ID)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.wantIDs) {
+				t.Errorf("SearchWebhookLogs = %v, want %v", ids, tt.wantIDs)
+			}
+
+			summary, err := store.SummarizeWebhookLogs(tt.filter)
+			if err != nil || summary != tt.want {
+				t.Errorf("SummarizeWebhookLogs = %+v, %v, want %+v", summary, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTriggerHitCounts(t *testing.T) {
+	tempDB := "test_trigger_hits.db"
+	defer os.Remove(tempDB)
+
+	db, err := sql.Open("sqlite3", tempDB)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := createTables(db); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	store := &MessageStore{db: db}
+	config := &types.WebhookConfig{
+		Name:       "crm",
+		WebhookURL: "https://example.com/crm",
+		Triggers: []types.WebhookTrigger{
+			{TriggerType: "keyword", TriggerValue: "invoice", MatchType: "contains", Enabled: true},
+			{TriggerType: "keyword", TriggerValue: "refund", MatchType: "contains", Enabled: true},
+		},
+	}
+	if err := store.StoreWebhookConfig(config); err != nil {
+		t.Fatalf("Failed to store webhook config: %v", err)
+	}
+
+	// Matches are counted as their deliveries are queued, several at once
+	at := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	var deliveries []*types.WebhookDelivery
+	for i, trigger := range []types.WebhookTrigger{config.Triggers[0], config.Triggers[0], config.Triggers[1], {TriggerType: "expression"}} {
+		deliveries = append(deliveries, &types.WebhookDelivery{
+			WebhookConfigID: config.ID,
+			ChatJID:         "111@s.whatsapp.net",
+			MessageID:       fmt.Sprintf("M%d", i),
+			Trigger:         trigger,
+			CreatedAt:       at,
+		})
+	}
+	if err := store.QueueWebhookDeliveries(0, deliveries); err != nil {
+		t.Fatalf("QueueWebhookDeliveries: %v", err)
+	}
+	if err := store.QueueWebhookDeliveries(0, deliveries[:1]); err != nil {
+		t.Fatalf("QueueWebhookDeliveries: %v", err)
+	}
+
+	// Renaming the webhook and changing one trigger keeps the other's counts
+	config.Name = "crm v2"
+	config.Triggers[1].TriggerValue = "chargeback"
+	if err := store.UpdateWebhookConfig(config); err != nil {
+		t.Fatalf("UpdateWebhookConfig: %v", err)
+	}
+
+	got, err := store.GetWebhookConfig(config.ID)
+	if err != nil {
+		t.Fatalf("GetWebhookConfig: %v", err)
+	}
+	invoice, chargeback := got.Triggers[0], got.Triggers[1]
+	if invoice.MatchCount != 3 || invoice.LastMatchedAt == nil || !invoice.LastMatchedAt.Equal(at) {
+		t.Errorf("invoice trigger = %+v, want 3 matches last at %v", invoice, at)
+	}
+	if chargeback.MatchCount != 0 || chargeback.LastMatchedAt != nil {
+		t.Errorf("changed trigger = %+v, want its counts reset", chargeback)
+	}
+}
@@ -0,0 +1,145 @@
+// Package diskspace watches the free space on the volume holding the store
+// directory, so the bridge can stop bulky writes while there is still room
+// instead of SQLite writes starting to fail mid-transaction.
+package diskspace
+
+import (
+	"sync"
+	"syscall"
+	"time"
+
+	waLog "go.mau.fi/whatsmeow/util/log"
+
+	"whatsapp-bridge/internal/metrics"
+	"whatsapp-bridge/internal/types"
+)
+
+// recoveryMargin is how far above the threshold free space must climb before
+// the volume stops counting as low, so it does not flap around the threshold
+const recoveryMargin = 0.1
+
+var (
+	freeBytes = metrics.NewGauge("bridge_store_free_bytes", "Free space on the volume holding the store directory")
+	lowAlerts = metrics.NewCounter("bridge_store_low_space_total", "Times free space on the store volume fell below the threshold")
+)
+
+// Usage returns the space available to the bridge and the size of the
+// volume holding path
+func Usage(path string) (free, total uint64, err error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, 0, err
+	}
+	return st.Bavail * uint64(st.Bsize), st.Blocks * uint64(st.Bsize), nil
+}
+
+// Monitor checks the free space under a path against a threshold
+type Monitor struct {
+	path      string
+	threshold uint64
+	logger    waLog.Logger
+	usage     func(path string) (free, total uint64, err error)
+	now       func() time.Time
+
+	mu            sync.RWMutex
+	status        *types.DiskStatus
+	lowHook       func(status types.DiskStatus)
+	recoveredHook func(status types.DiskStatus)
+}
+
+// New creates a monitor for the volume holding path. A threshold of 0 never
+// reports the volume as low.
+func New(path string, threshold uint64, logger waLog.Logger) *Monitor {
+	return &Monitor{
+		path:      path,
+		threshold: threshold,
+		logger:    logger,
+		usage:     Usage,
+		now:       time.Now,
+	}
+}
+
+// SetLowHook registers fn to be called when free space falls below the
+// threshold. It is called once each time, not on every check while it stays
+// low.
+func (m *Monitor) SetLowHook(fn func(status types.DiskStatus)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lowHook = fn
+}
+
+// SetRecoveredHook registers fn to be called when free space climbs back
+// above the threshold after being low
+func (m *Monitor) SetRecoveredHook(fn func(status types.DiskStatus)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.recoveredHook = fn
+}
+
+// Check reads the free space and updates the status. A failed read keeps the
+// volume's previous low state.
+func (m *Monitor) Check() types.DiskStatus {
+	free, total, err := m.usage(m.path)
+
+	m.mu.Lock()
+	wasLow := m.status != nil && m.status.Low
+	status := types.DiskStatus{
+		Path:           m.path,
+		FreeBytes:      free,
+		TotalBytes:     total,
+		ThresholdBytes: m.threshold,
+		CheckedAt:      m.now().UTC(),
+	}
+	switch {
+	case err != nil:
+		status.Error = err.Error()
+		status.Low = wasLow
+	case m.threshold == 0:
+	case wasLow:
+		status.Low = float64(free) < float64(m.threshold)*(1+recoveryMargin)
+	default:
+		status.Low = free < m.threshold
+	}
+	m.status = &status
+	hook, recovered := m.lowHook, m.recoveredHook
+	m.mu.Unlock()
+
+	if err != nil {
+		m.logger.Warnf("Failed to read free space under %s: %v", m.path, err)
+		return status
+	}
+	freeBytes.Set(float64(free))
+
+	switch {
+	case status.Low && !wasLow:
+		lowAlerts.Inc()
+		m.logger.Errorf("✗ Only %d MB free under %s (threshold %d MB); pausing media downloads and history sync",
+			free>>20, m.path, m.threshold>>20)
+		if hook != nil {
+			hook(status)
+		}
+	case !status.Low && wasLow:
+		m.logger.Infof("✓ %d MB free under %s; resuming media downloads and history sync", free>>20, m.path)
+		if recovered != nil {
+			recovered(status)
+		}
+	}
+	return status
+}
+
+// Status returns the latest check, if one has been made
+func (m *Monitor) Status() (types.DiskStatus, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.status == nil {
+		return types.DiskStatus{}, false
+	}
+	return *m.status, true
+}
+
+// Low reports whether the latest check found the volume low on space
+func (m *Monitor) Low() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status != nil && m.status.Low
+}
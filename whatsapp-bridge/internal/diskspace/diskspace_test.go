@@ -0,0 +1,86 @@
+package diskspace
+
+import (
+	"errors"
+	"testing"
+
+	waLog "go.mau.fi/whatsmeow/util/log"
+
+	"whatsapp-bridge/internal/types"
+)
+
+func TestUsage(t *testing.T) {
+	free, total, err := Usage(t.TempDir())
+	if err != nil || total == 0 || free > total {
+		t.Errorf("Usage = %d, %d, %v", free, total, err)
+	}
+	if _, _, err := Usage("/does/not/exist"); err == nil {
+		t.Error("missing path read without error")
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	const mb = 1 << 20
+	m := New("store", 100*mb, waLog.Noop)
+
+	var free uint64
+	var readErr error
+	m.usage = func(string) (uint64, uint64, error) { return free, 1000 * mb, readErr }
+
+	var alerts []types.DiskStatus
+	m.SetLowHook(func(s types.DiskStatus) { alerts = append(alerts, s) })
+	recovered := 0
+	m.SetRecoveredHook(func(types.DiskStatus) { recovered++ })
+
+	if _, ok := m.Status(); ok || m.Low() {
+		t.Fatal("status reported before any check")
+	}
+
+	free = 500 * mb
+	if s := m.Check(); s.Low || s.FreeBytes != 500*mb || s.ThresholdBytes != 100*mb || len(alerts) != 0 {
+		t.Fatalf("plenty of space: %+v, alerts %d", s, len(alerts))
+	}
+
+	// Falling below the threshold alerts once
+	free = 90 * mb
+	m.Check()
+	free = 80 * mb
+	if s := m.Check(); !s.Low || !m.Low() || len(alerts) != 1 || alerts[0].FreeBytes != 90*mb {
+		t.Fatalf("low: %+v, alerts %+v", s, alerts)
+	}
+
+	// A failed read keeps the volume low
+	readErr = errors.New("statfs failed")
+	if s := m.Check(); !s.Low || s.Error == "" {
+		t.Errorf("failed read: %+v", s)
+	}
+	readErr = nil
+
+	// Just above the threshold is not enough to recover
+	free = 105 * mb
+	if !m.Check().Low || recovered != 0 {
+		t.Error("recovered within the margin")
+	}
+	free = 120 * mb
+	if m.Check().Low {
+		t.Error("still low well above the threshold")
+	}
+	m.Check()
+	if recovered != 1 {
+		t.Errorf("recovered hook called %d times, want 1", recovered)
+	}
+
+	// Dropping again alerts again
+	free = 10 * mb
+	m.Check()
+	if len(alerts) != 2 {
+		t.Errorf("alerts = %d, want 2", len(alerts))
+	}
+
+	// No threshold, never low
+	off := New("store", 0, waLog.Noop)
+	off.usage = m.usage
+	if off.Check().Low {
+		t.Error("low without a threshold")
+	}
+}
@@ -0,0 +1,417 @@
+// Package doctor checks that the bridge's configuration and environment are
+// coherent, so problems are reported with a fix at startup instead of
+// surfacing later as runtime failures.
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"whatsapp-bridge/internal/config"
+	"whatsapp-bridge/internal/diskspace"
+	"whatsapp-bridge/internal/secrets"
+	"whatsapp-bridge/internal/tenant"
+	"whatsapp-bridge/internal/types"
+)
+
+// Check statuses
+const (
+	StatusPass = "pass"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+)
+
+const (
+	// clockReference is asked for the time; WhatsApp rejects sessions whose
+	// clock is too far off
+	clockReference = "https://web.whatsapp.com"
+
+	// Clock skew above these is a warning and a failure
+	skewWarn = 10 * time.Second
+	skewFail = time.Minute
+
+	// dialTimeout bounds each webhook reachability probe
+	dialTimeout = 5 * time.Second
+)
+
+// Store is the message database being checked
+type Store interface {
+	IntegrityCheck() ([]string, error)
+	GetAllWebhookConfigs() ([]*types.WebhookConfig, error)
+}
+
+// Pinger is a shared store that can be checked for reachability
+type Pinger interface {
+	Ping() error
+}
+
+// Doctor runs the checks
+type Doctor struct {
+	store    Store
+	cfg      *config.Config
+	storeDir string
+	redis    Pinger
+
+	http     *http.Client
+	dial     func(ctx context.Context, network, address string) (net.Conn, error)
+	clockURL string
+	getenv   func(string) string
+	lookPath func(string) (string, error)
+	now      func() time.Time
+	usage    func(path string) (free, total uint64, err error)
+}
+
+// New creates a doctor for the message store and configuration
+func New(store Store, cfg *config.Config) *Doctor {
+	return &Doctor{
+		store:    store,
+		cfg:      cfg,
+		storeDir: "store",
+		http:     &http.Client{Timeout: 10 * time.Second},
+		dial:     (&net.Dialer{Timeout: dialTimeout}).DialContext,
+		clockURL: clockReference,
+		getenv:   os.Getenv,
+		lookPath: exec.LookPath,
+		now:      time.Now,
+		usage:    diskspace.Usage,
+	}
+}
+
+// SetRedis includes the shared Redis server in the checks
+func (d *Doctor) SetRedis(p Pinger) {
+	d.redis = p
+}
+
+// Run performs every check and summarises the results
+func (d *Doctor) Run(ctx context.Context) types.DoctorReport {
+	var checks []types.DoctorCheck
+	checks = append(checks, d.checkStoreDir())
+	checks = append(checks, d.checkDiskSpace())
+	checks = append(checks, d.checkDatabase())
+	checks = append(checks, d.checkClock(ctx))
+	checks = append(checks, d.checkAuth())
+	checks = append(checks, d.checkSecrets()...)
+	checks = append(checks, d.checkConflicts()...)
+	if d.redis != nil {
+		checks = append(checks, d.checkRedis())
+	}
+	if d.cfg.VoiceNoteTranscode {
+		checks = append(checks, d.checkFFmpeg())
+	}
+	checks = append(checks, d.checkWebhooks(ctx)...)
+
+	report := types.DoctorReport{CheckedAt: d.now().UTC(), Checks: checks}
+	for _, c := range checks {
+		switch c.Status {
+		case StatusFail:
+			report.Failures++
+		case StatusWarn:
+			report.Warnings++
+		}
+	}
+	report.Healthy = report.Failures == 0
+	return report
+}
+
+func pass(name, format string, args ...interface{}) types.DoctorCheck {
+	return types.DoctorCheck{Name: name, Status: StatusPass, Message: fmt.Sprintf(format, args...)}
+}
+
+func problem(name, status, remedy, format string, args ...interface{}) types.DoctorCheck {
+	return types.DoctorCheck{Name: name, Status: status, Message: fmt.Sprintf(format, args...), Remedy: remedy}
+}
+
+// checkStoreDir verifies the session and message databases' directory is writable
+func (d *Doctor) checkStoreDir() types.DoctorCheck {
+	const name = "store_directory"
+	f, err := os.CreateTemp(d.storeDir, ".doctor-*")
+	if err != nil {
+		abs, _ := filepath.Abs(d.storeDir)
+		return problem(name, StatusFail,
+			"Make the directory writable by the bridge's user, or mount a writable volume there",
+			"Cannot write to %s: %v", abs, err)
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return pass(name, "%s is writable", d.storeDir)
+}
+
+// checkDiskSpace compares the store volume's free space with the threshold
+// below which media downloads and history sync pause, warning when it is
+// within twice that
+func (d *Doctor) checkDiskSpace() types.DoctorCheck {
+	const name = "disk_space"
+	free, total, err := d.usage(d.storeDir)
+	if err != nil {
+		return problem(name, StatusWarn, "Check that the store directory exists and its volume is mounted",
+			"Cannot read free space under %s: %v", d.storeDir, err)
+	}
+
+	threshold := d.cfg.DiskFreeThresholdMB << 20
+	switch {
+	case threshold > 0 && free < threshold:
+		return problem(name, StatusFail,
+			"Free space on the volume or grow it; media downloads and history sync stay paused until then",
+			"%d MB of %d MB free under %s, below DISK_FREE_THRESHOLD_MB=%d", free>>20, total>>20, d.storeDir, d.cfg.DiskFreeThresholdMB)
+	case threshold > 0 && free < 2*threshold:
+		return problem(name, StatusWarn,
+			"Free space on the volume or grow it before media downloads and history sync pause",
+			"%d MB of %d MB free under %s, close to DISK_FREE_THRESHOLD_MB=%d", free>>20, total>>20, d.storeDir, d.cfg.DiskFreeThresholdMB)
+	}
+	return pass(name, "%d MB of %d MB free under %s", free>>20, total>>20, d.storeDir)
+}
+
+// checkDatabase runs SQLite's integrity check on the message database
+func (d *Doctor) checkDatabase() types.DoctorCheck {
+	const name = "database_integrity"
+	problems, err := d.store.IntegrityCheck()
+	if err != nil {
+		return problem(name, StatusFail, "Check that store/messages.db is a readable SQLite database", "%v", err)
+	}
+	if len(problems) > 0 {
+		if len(problems) > 5 {
+			problems = append(problems[:5], fmt.Sprintf("and %d more", len(problems)-5))
+		}
+		return problem(name, StatusFail,
+			"Stop the bridge and restore store/messages.db from a backup, or rebuild it with sqlite3 .recover",
+			"Message database is corrupt: %s", strings.Join(problems, "; "))
+	}
+	return pass(name, "Message database passed the integrity check")
+}
+
+// checkClock compares the local clock with the Date header of a WhatsApp
+// server, allowing for the round trip
+func (d *Doctor) checkClock(ctx context.Context) types.DoctorCheck {
+	const name = "clock_skew"
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, d.clockURL, nil)
+	if err != nil {
+		return problem(name, StatusWarn, "", "Could not check the clock: %v", err)
+	}
+
+	sent := d.now()
+	resp, err := d.http.Do(req)
+	if err != nil {
+		return problem(name, StatusWarn, "Check outbound HTTPS access to WhatsApp", "Could not reach %s to check the clock: %v", d.clockURL, err)
+	}
+	resp.Body.Close()
+	received := d.now()
+
+	serverTime, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return problem(name, StatusWarn, "", "%s sent no usable Date header", d.clockURL)
+	}
+
+	// The header has one second resolution and was stamped mid-flight
+	local := sent.Add(received.Sub(sent) / 2)
+	skew := local.Sub(serverTime).Round(time.Second)
+	abs := skew
+	if abs < 0 {
+		abs = -abs
+	}
+
+	remedy := "Enable NTP time synchronisation on the host (e.g. systemd-timesyncd or chrony)"
+	switch {
+	case abs > skewFail:
+		return problem(name, StatusFail, remedy, "Local clock is off by %v; WhatsApp may reject the session", skew)
+	case abs > skewWarn:
+		return problem(name, StatusWarn, remedy, "Local clock is off by %v", skew)
+	}
+	return pass(name, "Local clock is within %v of WhatsApp's", skewWarn)
+}
+
+// checkAuth flags an unauthenticated API. It is a failure when the API is
+// reachable from other hosts, a warning when only local clients can reach it.
+func (d *Doctor) checkAuth() types.DoctorCheck {
+	const name = "api_authentication"
+	if d.getenv("API_KEY") != "" {
+		return pass(name, "API key authentication is enabled")
+	}
+	if d.getenv("DISABLE_AUTH_CHECK") == "true" {
+		where, local := d.listenScope()
+		if local {
+			return problem(name, StatusWarn,
+				"Set API_KEY so local processes other than the proxy cannot use the API",
+				"DISABLE_AUTH_CHECK=true leaves the API unauthenticated on %s", where)
+		}
+		return problem(name, StatusFail,
+			"Set API_KEY, or set API_BIND=127.0.0.1 or API_SOCKET to keep the API local",
+			"DISABLE_AUTH_CHECK=true leaves the API unauthenticated on %s; anyone who can reach it can send as the account",
+			where)
+	}
+	return problem(name, StatusFail, "Set API_KEY", "API_KEY is not set")
+}
+
+// checkSecrets verifies the API keys given as secret store references can be
+// read. There is no check when no key is one.
+func (d *Doctor) checkSecrets() []types.DoctorCheck {
+	const name = "secrets"
+	var refs []string
+	if key := d.getenv("API_KEY"); secrets.IsReference(key) {
+		refs = append(refs, key)
+	}
+	for _, pair := range strings.Split(d.getenv("API_KEYS"), ",") {
+		if _, key, ok := strings.Cut(strings.TrimSpace(pair), ":"); ok && secrets.IsReference(key) {
+			refs = append(refs, key)
+		}
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+
+	for _, ref := range refs {
+		if _, err := secrets.Resolve(ref); err != nil {
+			return []types.DoctorCheck{problem(name, StatusFail,
+				"Check the secret store's address and credentials and that the referenced secret exists",
+				"%v", err)}
+		}
+	}
+	return []types.DoctorCheck{pass(name, "%d API key(s) read from a secret store", len(refs))}
+}
+
+// listenScope describes where the API listens and whether only local
+// clients can reach it
+func (d *Doctor) listenScope() (where string, local bool) {
+	if d.cfg.APISocket != "" {
+		return "Unix socket " + d.cfg.APISocket, true
+	}
+	if d.cfg.APIBind == "" {
+		return fmt.Sprintf("all interfaces (port %d)", d.cfg.APIPort), false
+	}
+	where = net.JoinHostPort(d.cfg.APIBind, strconv.Itoa(d.cfg.APIPort))
+	if d.cfg.APIBind == "localhost" {
+		return where, true
+	}
+	ip := net.ParseIP(d.cfg.APIBind)
+	return where, ip != nil && ip.IsLoopback()
+}
+
+// checkConflicts flags combinations of settings that cannot work together
+func (d *Doctor) checkConflicts() []types.DoctorCheck {
+	const name = "config_conflicts"
+	var checks []types.DoctorCheck
+
+	if d.cfg.ReadReplica && d.cfg.LeaderElection {
+		checks = append(checks, problem(name, StatusWarn,
+			"Unset LEADER_ELECTION on read replicas",
+			"READ_REPLICA and LEADER_ELECTION are both set; a read replica never connects to WhatsApp, so it is never elected"))
+	}
+	if d.cfg.DevMode && d.getenv("API_KEY") == "" {
+		checks = append(checks, problem(name, StatusFail,
+			"Set API_KEY or turn DEV_MODE off",
+			"DEV_MODE serves fault injection endpoints, and without API_KEY anyone can disconnect the bridge"))
+	}
+	if _, err := tenant.ParseRoles(d.getenv("API_KEY_ROLES")); err != nil {
+		checks = append(checks, problem(name, StatusWarn,
+			"Fix API_KEY_ROLES; until then those keys only get the reader role",
+			"%v", err))
+	}
+	if d.getenv("API_KEY") == "" && d.getenv("API_KEYS") != "" {
+		checks = append(checks, problem(name, StatusWarn,
+			"Set API_KEY for the operator; API_KEYS only adds tenant keys",
+			"API_KEYS is set without API_KEY, so no key can use the admin endpoints"))
+	}
+	if d.cfg.TranscriptionURL == "" && d.cfg.TranscriptionAPIKey != "" {
+		checks = append(checks, problem(name, StatusWarn,
+			"Set TRANSCRIPTION_URL or remove TRANSCRIPTION_API_KEY",
+			"TRANSCRIPTION_API_KEY is set without TRANSCRIPTION_URL, so voice notes are not transcribed"))
+	}
+	if d.cfg.OCRURL == "" && d.cfg.OCRAPIKey != "" {
+		checks = append(checks, problem(name, StatusWarn,
+			"Set OCR_URL or remove OCR_API_KEY",
+			"OCR_API_KEY is set without OCR_URL, so images are not read"))
+	}
+
+	if len(checks) == 0 {
+		checks = append(checks, pass(name, "No conflicting settings"))
+	}
+	return checks
+}
+
+// checkRedis pings the shared Redis server
+func (d *Doctor) checkRedis() types.DoctorCheck {
+	const name = "redis"
+	if err := d.redis.Ping(); err != nil {
+		return problem(name, StatusWarn,
+			"Check REDIS_URL and that the server is up; until then each instance rate limits on its own",
+			"Redis is unreachable: %v", err)
+	}
+	return pass(name, "Redis is reachable")
+}
+
+// checkFFmpeg verifies ffmpeg is installed for voice note transcoding
+func (d *Doctor) checkFFmpeg() types.DoctorCheck {
+	const name = "ffmpeg"
+	path, err := d.lookPath("ffmpeg")
+	if err != nil {
+		return problem(name, StatusWarn,
+			"Install ffmpeg, or unset VOICE_NOTE_TRANSCODE to send such audio as documents",
+			"VOICE_NOTE_TRANSCODE is on but ffmpeg is not installed; mp3, m4a and wav sends will fail")
+	}
+	return pass(name, "ffmpeg found at %s", path)
+}
+
+// checkWebhooks opens a connection to each enabled webhook's host. Nothing
+// is sent, so consumers see no traffic.
+func (d *Doctor) checkWebhooks(ctx context.Context) []types.DoctorCheck {
+	configs, err := d.store.GetAllWebhookConfigs()
+	if err != nil {
+		return []types.DoctorCheck{problem("webhooks", StatusWarn, "", "Could not load webhooks: %v", err)}
+	}
+
+	var enabled []*types.WebhookConfig
+	for _, config := range configs {
+		if config.Enabled {
+			enabled = append(enabled, config)
+		}
+	}
+	if len(enabled) == 0 {
+		return []types.DoctorCheck{pass("webhooks", "No webhooks are enabled")}
+	}
+
+	checks := make([]types.DoctorCheck, len(enabled))
+	var wg sync.WaitGroup
+	for i, config := range enabled {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checks[i] = d.checkWebhook(ctx, config)
+		}()
+	}
+	wg.Wait()
+	return checks
+}
+
+func (d *Doctor) checkWebhook(ctx context.Context, config *types.WebhookConfig) types.DoctorCheck {
+	name := fmt.Sprintf("webhook_%d", config.ID)
+
+	u, err := url.Parse(config.WebhookURL)
+	if err != nil || u.Host == "" {
+		return problem(name, StatusFail, "Fix the webhook's URL", "Webhook %q has an invalid URL %q", config.Name, config.WebhookURL)
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	conn, err := d.dial(ctx, "tcp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		return problem(name, StatusFail,
+			"Check the webhook's URL, DNS and firewall, or disable the webhook",
+			"Webhook %q (%s) is unreachable: %v", config.Name, u.Host, err)
+	}
+	conn.Close()
+	return pass(name, "Webhook %q (%s) is reachable", config.Name, u.Host)
+}
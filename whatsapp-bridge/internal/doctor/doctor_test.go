@@ -0,0 +1,180 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"whatsapp-bridge/internal/config"
+	"whatsapp-bridge/internal/types"
+)
+
+type fakeStore struct {
+	problems []string
+	webhooks []*types.WebhookConfig
+}
+
+func (s *fakeStore) IntegrityCheck() ([]string, error) { return s.problems, nil }
+
+func (s *fakeStore) GetAllWebhookConfigs() ([]*types.WebhookConfig, error) {
+	return s.webhooks, nil
+}
+
+type fakePinger struct{ err error }
+
+func (p fakePinger) Ping() error { return p.err }
+
+func newTestDoctor(t *testing.T, env map[string]string, serverTime time.Time) *Doctor {
+	t.Helper()
+	dir := t.TempDir()
+
+	clock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", serverTime.UTC().Format(http.TimeFormat))
+	}))
+	t.Cleanup(clock.Close)
+
+	d := New(&fakeStore{}, &config.Config{APIPort: 8080})
+	d.storeDir = dir
+	d.clockURL = clock.URL
+	d.getenv = func(key string) string { return env[key] }
+	return d
+}
+
+func find(t *testing.T, report types.DoctorReport, name string) types.DoctorCheck {
+	t.Helper()
+	for _, c := range report.Checks {
+		if c.Name == name {
+			return c
+		}
+	}
+	t.Fatalf("no %s check in %+v", name, report.Checks)
+	return types.DoctorCheck{}
+}
+
+func TestRunHealthy(t *testing.T) {
+	d := newTestDoctor(t, map[string]string{"API_KEY": "secret"}, time.Now())
+	d.SetRedis(fakePinger{})
+
+	report := d.Run(context.Background())
+	if !report.Healthy || report.Failures != 0 {
+		t.Errorf("report = %+v, want healthy", report)
+	}
+	for _, name := range []string{"store_directory", "disk_space", "database_integrity", "clock_skew", "api_authentication", "config_conflicts", "redis", "webhooks"} {
+		if c := find(t, report, name); c.Status != StatusPass {
+			t.Errorf("%s = %+v, want pass", name, c)
+		}
+	}
+}
+
+func TestRunProblems(t *testing.T) {
+	d := newTestDoctor(t, map[string]string{"DISABLE_AUTH_CHECK": "true"}, time.Now().Add(-5*time.Minute))
+	d.cfg.DevMode = true
+	d.cfg.VoiceNoteTranscode = true
+	d.lookPath = func(string) (string, error) { return "", errors.New("not found") }
+	d.storeDir = filepath.Join(d.storeDir, "missing")
+	d.cfg.DiskFreeThresholdMB = 500
+	d.usage = func(string) (uint64, uint64, error) { return 100 << 20, 10000 << 20, nil }
+	d.SetRedis(fakePinger{err: errors.New("connection refused")})
+	d.store = &fakeStore{
+		problems: []string{"row 3 missing from index idx_messages_chat"},
+		webhooks: []*types.WebhookConfig{{ID: 7, Name: "crm", Enabled: true, WebhookURL: "http://[::1"}},
+	}
+
+	report := d.Run(context.Background())
+	if report.Healthy {
+		t.Fatal("report healthy despite problems")
+	}
+
+	tests := map[string]string{
+		"store_directory":    StatusFail,
+		"disk_space":         StatusFail,
+		"clock_skew":         StatusFail,
+		"api_authentication": StatusFail,
+		"config_conflicts":   StatusFail,
+		"redis":              StatusWarn,
+		"ffmpeg":             StatusWarn,
+		"database_integrity": StatusFail,
+		"webhook_7":          StatusFail,
+	}
+	for name, want := range tests {
+		if c := find(t, report, name); c.Status != want || c.Remedy == "" {
+			t.Errorf("%s = %+v, want %s with a remedy", name, c, want)
+		}
+	}
+}
+
+func TestCheckWebhook(t *testing.T) {
+	d := newTestDoctor(t, nil, time.Now())
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+
+	up := d.checkWebhook(context.Background(), &types.WebhookConfig{ID: 1, Name: "up", WebhookURL: "http://" + ln.Addr().String() + "/hook"})
+	if up.Status != StatusPass || up.Name != "webhook_1" {
+		t.Errorf("reachable webhook = %+v", up)
+	}
+
+	d.dial = func(ctx context.Context, network, address string) (net.Conn, error) {
+		if address != "hooks.example.com:443" {
+			t.Errorf("dialled %s, want the default https port", address)
+		}
+		return nil, errors.New("no such host")
+	}
+	down := d.checkWebhook(context.Background(), &types.WebhookConfig{ID: 2, Name: "down", WebhookURL: "https://hooks.example.com/hook"})
+	if down.Status != StatusFail {
+		t.Errorf("unreachable webhook = %+v", down)
+	}
+}
+
+func TestCheckAuthListenScope(t *testing.T) {
+	tests := []struct {
+		bind, socket string
+		want         string
+	}{
+		{"", "", StatusFail},
+		{"0.0.0.0", "", StatusFail},
+		{"10.0.0.5", "", StatusFail},
+		{"127.0.0.1", "", StatusWarn},
+		{"::1", "", StatusWarn},
+		{"localhost", "", StatusWarn},
+		{"", "/run/bridge/api.sock", StatusWarn},
+	}
+	for _, tt := range tests {
+		d := newTestDoctor(t, map[string]string{"DISABLE_AUTH_CHECK": "true"}, time.Now())
+		d.cfg.APIBind, d.cfg.APISocket = tt.bind, tt.socket
+		if c := d.checkAuth(); c.Status != tt.want {
+			t.Errorf("bind %q socket %q: %+v, want %s", tt.bind, tt.socket, c, tt.want)
+		}
+	}
+}
+
+func TestCheckSecrets(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "api_key")
+	if err := os.WriteFile(keyFile, []byte("from-a-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	d := newTestDoctor(t, map[string]string{"API_KEY": "plain"}, time.Now())
+	if checks := d.checkSecrets(); len(checks) != 0 {
+		t.Errorf("plain keys: %+v, want no check", checks)
+	}
+
+	d = newTestDoctor(t, map[string]string{"API_KEY": "file://" + keyFile, "API_KEYS": "acme:plain"}, time.Now())
+	if checks := d.checkSecrets(); len(checks) != 1 || checks[0].Status != StatusPass {
+		t.Errorf("readable reference: %+v", checks)
+	}
+
+	d = newTestDoctor(t, map[string]string{"API_KEY": "plain", "API_KEYS": "acme:file://" + keyFile + ".missing"}, time.Now())
+	if checks := d.checkSecrets(); len(checks) != 1 || checks[0].Status != StatusFail {
+		t.Errorf("unreadable reference: %+v", checks)
+	}
+}
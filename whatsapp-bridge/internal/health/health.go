@@ -0,0 +1,134 @@
+// Package health scores how close the account's recent behaviour is to
+// getting it restricted by WhatsApp, and recommends how to back off.
+package health
+
+import (
+	"fmt"
+
+	"whatsapp-bridge/internal/types"
+	"whatsapp-bridge/internal/whatsapp"
+)
+
+// Health statuses by score
+const (
+	StatusGood     = "good"     // 80 and up
+	StatusFair     = "fair"     // 50 and up
+	StatusPoor     = "poor"     // 25 and up
+	StatusCritical = "critical" // below 25, or currently banned
+)
+
+const (
+	// failureRateLimit is the share of failed sends above which sending is
+	// penalised, once there are at least minSample sends
+	failureRateLimit = 0.05
+	minSample        = 20
+
+	// bulkIdentical is how many chats may get one identical text before it
+	// looks like a broadcast; heavyBulkIdentical is a mass broadcast
+	bulkIdentical      = 20
+	heavyBulkIdentical = 100
+
+	// coldShareLimit is the share of recipients who never messaged the account
+	// above which outreach looks unsolicited
+	coldShareLimit = 0.5
+
+	// unreachedShareLimit is the share of recipients whose sends are never
+	// delivered above which the account looks blocked by them
+	unreachedShareLimit = 0.1
+)
+
+// Score turns the signals into a 0-100 score, a status and recommendations.
+// current is the restriction in effect, if any.
+func Score(signals types.AccountHealthSignals, current *types.AccountRestriction) types.AccountHealth {
+	score := 100
+	recommendations := []string{}
+	recommend := func(penalty int, format string, args ...interface{}) {
+		score -= penalty
+		recommendations = append(recommendations, fmt.Sprintf(format, args...))
+	}
+
+	sends := signals.Sends
+
+	if signals.TemporaryBans > 0 {
+		recommend(min(25*signals.TemporaryBans, 50),
+			"The account was temporarily banned %d time(s) this week; keep volume well below the level that led to the ban and favour contacts who message you first",
+			signals.TemporaryBans)
+	}
+	if signals.RateLimits > 0 {
+		recommend(min(10*signals.RateLimits, 30),
+			"WhatsApp rate limited the account %d time(s) this week; spread sends out with the low priority lane and pauses between batches",
+			signals.RateLimits)
+	}
+
+	if sends.Sent >= minSample {
+		rate := float64(sends.Failed) / float64(sends.Sent)
+		if rate > failureRateLimit {
+			recommend(min(int(rate*100), 30),
+				"%.0f%% of sends in the last day failed; clean the recipient list of invalid and unreachable numbers",
+				rate*100)
+		}
+	}
+
+	switch {
+	case sends.MaxIdenticalRecipients >= heavyBulkIdentical:
+		recommend(25,
+			"One identical text went to %d chats in the last day; personalise campaign messages instead of broadcasting the same text",
+			sends.MaxIdenticalRecipients)
+	case sends.MaxIdenticalRecipients >= bulkIdentical:
+		recommend(15,
+			"One identical text went to %d chats in the last day; vary the content of bulk messages",
+			sends.MaxIdenticalRecipients)
+	}
+
+	if sends.Recipients >= minSample && sends.ColdRecipients > 0 {
+		share := float64(sends.ColdRecipients) / float64(sends.Recipients)
+		if share > coldShareLimit {
+			recommend(15,
+				"%d of %d recipients in the last day never messaged the account; contacts who did not opt in are the most likely to report or block",
+				sends.ColdRecipients, sends.Recipients)
+		}
+	}
+
+	if sends.Recipients >= minSample && sends.UnreachedRecipients > 0 {
+		share := float64(sends.UnreachedRecipients) / float64(sends.Recipients)
+		if share > unreachedShareLimit {
+			recommend(20,
+				"%d of %d recipients in the last day got no send delivered, as happens when they block the account; stop messaging them and review how they were added",
+				sends.UnreachedRecipients, sends.Recipients)
+		}
+	}
+
+	if current != nil {
+		if current.Reason == whatsapp.RestrictionTemporaryBan {
+			score = 0
+			recommendations = append([]string{"The account is temporarily banned; sending is paused until the ban lifts"}, recommendations...)
+		} else {
+			score -= 30
+			recommendations = append([]string{"The account is rate limited; sending is paused until the limit expires"}, recommendations...)
+		}
+	}
+
+	if score < 0 {
+		score = 0
+	}
+	return types.AccountHealth{
+		Score:           score,
+		Status:          status(score),
+		Restriction:     current,
+		Signals:         signals,
+		Recommendations: recommendations,
+	}
+}
+
+func status(score int) string {
+	switch {
+	case score >= 80:
+		return StatusGood
+	case score >= 50:
+		return StatusFair
+	case score >= 25:
+		return StatusPoor
+	default:
+		return StatusCritical
+	}
+}
@@ -0,0 +1,93 @@
+package health
+
+import (
+	"testing"
+
+	"whatsapp-bridge/internal/types"
+	"whatsapp-bridge/internal/whatsapp"
+)
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name            string
+		signals         types.AccountHealthSignals
+		current         *types.AccountRestriction
+		wantScore       int
+		wantStatus      string
+		recommendations int
+	}{
+		{
+			name:       "quiet account",
+			wantScore:  100,
+			wantStatus: StatusGood,
+		},
+		{
+			name:       "small sample ignores failures",
+			signals:    types.AccountHealthSignals{Sends: types.SendStats{Sent: 5, Failed: 5, Recipients: 5, ColdRecipients: 5}},
+			wantScore:  100,
+			wantStatus: StatusGood,
+		},
+		{
+			name:            "failing sends",
+			signals:         types.AccountHealthSignals{Sends: types.SendStats{Sent: 100, Failed: 20, Recipients: 50}},
+			wantScore:       80,
+			wantStatus:      StatusGood,
+			recommendations: 1,
+		},
+		{
+			name: "cold mass broadcast",
+			signals: types.AccountHealthSignals{
+				RateLimits: 1,
+				Sends:      types.SendStats{Sent: 200, Recipients: 200, ColdRecipients: 180, MaxIdenticalRecipients: 150},
+			},
+			wantScore:       50,
+			wantStatus:      StatusFair,
+			recommendations: 3,
+		},
+		{
+			name:            "blocked by recipients",
+			signals:         types.AccountHealthSignals{Sends: types.SendStats{Sent: 60, Recipients: 50, UnreachedRecipients: 10}},
+			wantScore:       80,
+			wantStatus:      StatusGood,
+			recommendations: 1,
+		},
+		{
+			name:            "repeated bans",
+			signals:         types.AccountHealthSignals{TemporaryBans: 3, RateLimits: 5},
+			wantScore:       20,
+			wantStatus:      StatusCritical,
+			recommendations: 2,
+		},
+		{
+			name:            "banned now",
+			signals:         types.AccountHealthSignals{TemporaryBans: 1},
+			current:         &types.AccountRestriction{Reason: whatsapp.RestrictionTemporaryBan},
+			wantScore:       0,
+			wantStatus:      StatusCritical,
+			recommendations: 2,
+		},
+		{
+			name:            "rate limited now",
+			signals:         types.AccountHealthSignals{RateLimits: 1},
+			current:         &types.AccountRestriction{Reason: whatsapp.RestrictionRateLimited},
+			wantScore:       60,
+			wantStatus:      StatusFair,
+			recommendations: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Score(tt.signals, tt.current)
+			if got.Score != tt.wantScore || got.Status != tt.wantStatus {
+				t.Errorf("Score() = %d (%s), want %d (%s)", got.Score, got.Status, tt.wantScore, tt.wantStatus)
+			}
+			if len(got.Recommendations) != tt.recommendations {
+				t.Errorf("got %d recommendations, want %d: %v", len(got.Recommendations), tt.recommendations, got.Recommendations)
+			}
+			if got.Restriction != tt.current {
+				t.Errorf("Restriction = %v, want %v", got.Restriction, tt.current)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+// Package ical writes events planned in WhatsApp chats as an iCalendar
+// (RFC 5545) feed that calendar apps can subscribe to.
+package ical
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"whatsapp-bridge/internal/types"
+)
+
+// ContentType is the media type of a feed
+const ContentType = "text/calendar; charset=utf-8"
+
+// maxLineOctets is the longest content line before it must be folded
+const maxLineOctets = 75
+
+// Write writes events as a calendar named name. Canceled events are kept
+// with a cancelled status so subscribed calendars drop them.
+func Write(w io.Writer, name string, events []types.CalendarEvent) error {
+	var b strings.Builder
+	line := func(property, value string) {
+		fold(&b, property+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//whatsapp-bridge//WhatsApp events//EN")
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	line("X-WR-CALNAME", escape(name))
+
+	for _, ev := range events {
+		line("BEGIN", "VEVENT")
+		line("UID", uid(ev))
+		line("DTSTAMP", stamp(ev.UpdatedAt))
+		line("CREATED", stamp(ev.CreatedAt))
+		line("LAST-MODIFIED", stamp(ev.UpdatedAt))
+		line("DTSTART", stamp(ev.StartTime))
+		if ev.EndTime != nil {
+			line("DTEND", stamp(*ev.EndTime))
+		}
+		line("SUMMARY", escape(ev.Name))
+
+		description := ev.Description
+		if ev.ChatName != "" {
+			description = strings.TrimSpace(description + "\n\nPlanned in " + ev.ChatName)
+		}
+		if description != "" {
+			line("DESCRIPTION", escape(description))
+		}
+		if ev.Location != "" {
+			line("LOCATION", escape(ev.Location))
+		}
+		if ev.Latitude != 0 || ev.Longitude != 0 {
+			line("GEO", fmt.Sprintf("%.6f;%.6f", ev.Latitude, ev.Longitude))
+		}
+		if ev.JoinLink != "" {
+			line("URL", ev.JoinLink)
+		}
+		if ev.Canceled {
+			line("STATUS", "CANCELLED")
+		} else {
+			line("STATUS", "CONFIRMED")
+		}
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// uid identifies an event across feed refreshes: the message that created
+// it and the chat it was planned in
+func uid(ev types.CalendarEvent) string {
+	chat, _, _ := strings.Cut(ev.ChatJID, "@")
+	return ev.MessageID + "." + chat + "@whatsapp-bridge"
+}
+
+func stamp(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// escape escapes a TEXT value
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`).Replace(s)
+}
+
+// fold writes a content line, folding it onto continuation lines of at most
+// maxLineOctets without splitting a UTF-8 sequence
+func fold(b *strings.Builder, line string) {
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space, which counts
+		limit = maxLineOctets - 1
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
@@ -0,0 +1,87 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"whatsapp-bridge/internal/types"
+)
+
+func TestWrite(t *testing.T) {
+	start := time.Date(2024, 6, 1, 18, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Hour)
+	events := []types.CalendarEvent{
+		{
+			ChatJID:     "120363000000000000@g.us",
+			ChatName:    "Climbing club",
+			MessageID:   "3EB0A1",
+			Name:        "Bouldering, then dinner; bring shoes",
+			Description: "Meet at the entrance.\nBeginners welcome.",
+			Location:    "Boulderhalle, Hauptstraße 1, Berlin",
+			Latitude:    52.52,
+			Longitude:   13.405,
+			StartTime:   start,
+			EndTime:     &end,
+			CreatedAt:   start.Add(-48 * time.Hour),
+			UpdatedAt:   start.Add(-24 * time.Hour),
+		},
+		{
+			ChatJID:   "120363000000000000@g.us",
+			MessageID: "3EB0A2",
+			Name:      "Gear swap",
+			StartTime: start.Add(24 * time.Hour),
+			Canceled:  true,
+			CreatedAt: start,
+			UpdatedAt: start,
+		},
+	}
+
+	var b strings.Builder
+	if err := Write(&b, "Climbing club", events); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	feed := b.String()
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"X-WR-CALNAME:Climbing club\r\n",
+		"UID:3EB0A1.120363000000000000@whatsapp-bridge\r\n",
+		"DTSTART:20240601T180000Z\r\n",
+		"DTEND:20240601T200000Z\r\n",
+		"SUMMARY:Bouldering\\, then dinner\\; bring shoes\r\n",
+		"GEO:52.520000;13.405000\r\n",
+		"STATUS:CONFIRMED\r\n",
+		"STATUS:CANCELLED\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(feed, want) {
+			t.Errorf("feed is missing %q:\n%s", want, feed)
+		}
+	}
+
+	// Long lines are folded; unfolding restores them
+	for _, line := range strings.Split(strings.TrimSuffix(feed, "\r\n"), "\r\n") {
+		if len(line) > maxLineOctets {
+			t.Errorf("line of %d octets: %q", len(line), line)
+		}
+	}
+	unfolded := strings.ReplaceAll(feed, "\r\n ", "")
+	if !strings.Contains(unfolded, "DESCRIPTION:Meet at the entrance.\\nBeginners welcome.\\n\\nPlanned in Climbing club\r\n") {
+		t.Errorf("description not folded back intact:\n%s", unfolded)
+	}
+}
+
+func TestFoldKeepsRunes(t *testing.T) {
+	var b strings.Builder
+	fold(&b, "SUMMARY:"+strings.Repeat("ü", 80))
+	for _, line := range strings.Split(b.String(), "\r\n") {
+		if !strings.HasPrefix(line, "SUMMARY") && line != "" && !strings.HasPrefix(line, " ") {
+			t.Errorf("continuation line without leading space: %q", line)
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("fold split a character: %q", line)
+		}
+	}
+}
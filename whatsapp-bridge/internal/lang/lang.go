@@ -0,0 +1,247 @@
+// Package lang guesses the language of a short message, so auto-replies can
+// answer in the language they were written to. Scripts used by one language
+// (Greek, Thai, Hangul...) decide on their own; Latin text is told apart by
+// letter trigrams against small built-in samples.
+package lang
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// minTrigrams is the least text worth guessing at; "ok" or "👍" is
+	// no language
+	minTrigrams = 5
+
+	// minMargin is how much more likely per trigram the best language must
+	// be than the runner-up; closer calls are no guess
+	minMargin = 0.15
+)
+
+// samples are typical support messages in each Latin-script language the
+// detector knows
+var samples = map[string]string{
+	"en": `Hello, thank you for your message. I would like to know when the order will arrive and what time you open tomorrow.
+		Could you please help me with this? We are closed right now but we will answer as soon as possible. Is there anything
+		else that I can do for you today? The price of the product is not the same as on the website, and I have a question
+		about my account. Please send me the details and the invoice.`,
+	"es": `Hola, gracias por tu mensaje. Quisiera saber cuándo llega el pedido y a qué hora abren mañana. ¿Me podrías ayudar
+		con esto, por favor? Ahora estamos cerrados pero responderemos lo antes posible. ¿Hay algo más que pueda hacer por
+		usted hoy? El precio del producto no es el mismo que en la página web y tengo una pregunta sobre mi cuenta. Por favor
+		envíame los detalles y la factura.`,
+	"pt": `Olá, obrigado pela sua mensagem. Gostaria de saber quando o pedido vai chegar e a que horas vocês abrem amanhã.
+		Você poderia me ajudar com isso, por favor? Agora estamos fechados, mas vamos responder o mais rápido possível. Há
+		mais alguma coisa que eu possa fazer por você hoje? O preço do produto não é o mesmo que no site e tenho uma dúvida
+		sobre a minha conta. Por favor, me envie os detalhes e a fatura.`,
+	"fr": `Bonjour, merci pour votre message. Je voudrais savoir quand la commande va arriver et à quelle heure vous ouvrez
+		demain. Pourriez-vous m'aider avec cela, s'il vous plaît ? Nous sommes fermés pour le moment mais nous répondrons dès
+		que possible. Est-ce que je peux faire autre chose pour vous aujourd'hui ? Le prix du produit n'est pas le même que
+		sur le site et j'ai une question sur mon compte. Merci de m'envoyer les détails et la facture.`,
+	"de": `Hallo, danke für Ihre Nachricht. Ich möchte wissen, wann die Bestellung ankommt und wann Sie morgen öffnen.
+		Könnten Sie mir bitte dabei helfen? Wir haben gerade geschlossen, aber wir antworten so schnell wie möglich. Kann
+		ich heute noch etwas für Sie tun? Der Preis des Produkts ist nicht derselbe wie auf der Webseite und ich habe eine
+		Frage zu meinem Konto. Bitte schicken Sie mir die Details und die Rechnung.`,
+	"it": `Ciao, grazie per il tuo messaggio. Vorrei sapere quando arriva l'ordine e a che ora aprite domani. Potresti
+		aiutarmi con questo, per favore? Adesso siamo chiusi ma risponderemo il prima possibile. C'è qualcos'altro che
+		posso fare per te oggi? Il prezzo del prodotto non è lo stesso del sito e ho una domanda sul mio conto. Per favore
+		mandami i dettagli e la fattura.`,
+	"nl": `Hallo, bedankt voor je bericht. Ik wil graag weten wanneer de bestelling aankomt en hoe laat jullie morgen
+		opengaan. Kun je me hiermee helpen, alsjeblieft? We zijn nu gesloten maar we antwoorden zo snel mogelijk. Kan ik
+		vandaag nog iets anders voor je doen? De prijs van het product is niet hetzelfde als op de website en ik heb een
+		vraag over mijn account. Stuur me alsjeblieft de details en de factuur.`,
+	"id": `Halo, terima kasih atas pesan Anda. Saya ingin tahu kapan pesanan akan sampai dan jam berapa toko buka besok.
+		Bisakah Anda membantu saya dengan ini? Kami sedang tutup sekarang tetapi kami akan menjawab secepat mungkin. Apakah
+		ada hal lain yang bisa saya bantu hari ini? Harga produk tidak sama dengan yang ada di situs web dan saya punya
+		pertanyaan tentang akun saya. Tolong kirimkan detail dan tagihannya kepada saya.`,
+	"tr": `Merhaba, mesajınız için teşekkür ederim. Siparişin ne zaman geleceğini ve yarın saat kaçta açtığınızı öğrenmek
+		istiyorum. Bu konuda bana yardım edebilir misiniz lütfen? Şu anda kapalıyız ama en kısa sürede cevap vereceğiz.
+		Bugün sizin için yapabileceğim başka bir şey var mı? Ürünün fiyatı web sitesindekiyle aynı değil ve hesabım
+		hakkında bir sorum var. Lütfen bana ayrıntıları ve faturayı gönderin.`,
+}
+
+// scripts are the writing systems that settle the language by themselves
+var scripts = []struct {
+	table *unicode.RangeTable
+	lang  string
+}{
+	{unicode.Cyrillic, "ru"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Greek, "el"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Thai, "th"},
+	{unicode.Hangul, "ko"},
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Han, "zh"},
+}
+
+// profile is the trigram counts of one language's sample
+type profile struct {
+	counts map[string]int
+	total  int
+}
+
+var (
+	profiles   = make(map[string]profile)
+	vocabulary int // distinct trigrams over all samples, for smoothing
+)
+
+func init() {
+	seen := make(map[string]bool)
+	for code, sample := range samples {
+		p := profile{counts: trigrams(sample)}
+		for t, n := range p.counts {
+			p.total += n
+			seen[t] = true
+		}
+		profiles[code] = p
+	}
+	vocabulary = len(seen)
+}
+
+// Languages returns the codes Detect can return, sorted
+func Languages() []string {
+	codes := make([]string, 0, len(samples)+len(scripts))
+	seen := make(map[string]bool)
+	for code := range samples {
+		codes, seen[code] = append(codes, code), true
+	}
+	for _, s := range scripts {
+		if !seen[s.lang] {
+			codes, seen[s.lang] = append(codes, s.lang), true
+		}
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Supported reports whether Detect can return code
+func Supported(code string) bool {
+	if _, ok := samples[code]; ok {
+		return true
+	}
+	for _, s := range scripts {
+		if s.lang == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Detect returns the ISO 639-1 code of the language text is written in, or
+// "" when it is too short or too close a call to tell
+func Detect(text string) string {
+	if code := detectScript(text); code != "" {
+		return code
+	}
+
+	grams := trigrams(text)
+	n := 0
+	for _, count := range grams {
+		n += count
+	}
+	if n < minTrigrams {
+		return ""
+	}
+
+	best, bestScore, runnerUp := "", math.Inf(-1), math.Inf(-1)
+	for code, p := range profiles {
+		score := 0.0
+		for t, count := range grams {
+			score += float64(count) * math.Log((float64(p.counts[t])+0.5)/(float64(p.total)+0.5*float64(vocabulary)))
+		}
+		if score > bestScore {
+			best, bestScore, runnerUp = code, score, bestScore
+		} else if score > runnerUp {
+			runnerUp = score
+		}
+	}
+	if (bestScore-runnerUp)/float64(n) < minMargin {
+		return ""
+	}
+	return best
+}
+
+// detectScript returns the language of the script most of text's letters are
+// in, when that script is not Latin
+func detectScript(text string) string {
+	letters, kana := 0, 0
+	counts := make(map[string]int)
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for _, s := range scripts {
+			if unicode.Is(s.table, r) {
+				counts[s.lang]++
+				if s.lang == "ja" {
+					kana++
+				}
+				break
+			}
+		}
+	}
+
+	// Japanese mixes kana into Han, which alone is read as Chinese
+	if kana > 0 {
+		counts["ja"] += counts["zh"]
+		delete(counts, "zh")
+	}
+	for code, count := range counts {
+		if count*2 > letters {
+			return code
+		}
+	}
+	return ""
+}
+
+// trigrams counts the letter trigrams of text's words, lowercased and padded
+// with a space on either side so word starts and ends count too
+func trigrams(text string) map[string]int {
+	counts := make(map[string]int)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, word := range words {
+		runes := []rune(" " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			counts[string(runes[i:i+3])]++
+		}
+	}
+	return counts
+}
+
+// Pick returns the variant of a reply in the language text is written in,
+// or fallback when the language is unknown or has no variant
+func Pick(text string, variants map[string]string, fallback string) string {
+	if len(variants) == 0 {
+		return fallback
+	}
+	if variant, ok := variants[Detect(text)]; ok {
+		return variant
+	}
+	return fallback
+}
+
+// ValidateVariants checks reply variants keyed by language: every language
+// must be one Detect knows and every variant non-empty and at most maxLength
+func ValidateVariants(variants map[string]string, maxLength int) error {
+	for code, text := range variants {
+		if !Supported(code) {
+			return fmt.Errorf("unsupported language %q; supported: %s", code, strings.Join(Languages(), ", "))
+		}
+		if text == "" {
+			return fmt.Errorf("translation for %s is empty", code)
+		}
+		if len(text) > maxLength {
+			return fmt.Errorf("translation for %s must be at most %d characters", code, maxLength)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package lang
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Hi, when do you open tomorrow?", "en"},
+		{"Hola, ¿a qué hora abren mañana?", "es"},
+		{"Olá, gostaria de saber o status do meu pedido", "pt"},
+		{"Bonjour, je voudrais savoir si ma commande est partie", "fr"},
+		{"Hallo, wann habt ihr morgen geöffnet?", "de"},
+		{"Buongiorno, vorrei sapere quando arriva il pacco", "it"},
+		{"Goedemiddag, waar blijft mijn pakketje?", "nl"},
+		{"Selamat pagi, apakah toko buka hari minggu?", "id"},
+		{"Merhaba, siparişim nerede acaba?", "tr"},
+		{"Привет, когда вы откроетесь?", "ru"},
+		{"こんにちは、明日は何時に開きますか", "ja"},
+		{"你好，明天几点开门？", "zh"},
+		{"안녕하세요", "ko"},
+
+		// Too little to tell
+		{"ok", ""},
+		{"Hola", ""},
+		{"👍", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Detect(tt.text); got != tt.want {
+			t.Errorf("Detect(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+
+	for _, code := range Languages() {
+		if !Supported(code) {
+			t.Errorf("Languages lists %s but Supported rejects it", code)
+		}
+	}
+}
+
+func TestPick(t *testing.T) {
+	variants := map[string]string{"es": "Estamos cerrados", "fr": "Nous sommes fermés"}
+
+	if got := Pick("Hola, ¿a qué hora abren mañana?", variants, "We are closed"); got != "Estamos cerrados" {
+		t.Errorf("Spanish message got %q", got)
+	}
+	if got := Pick("Hallo, wann habt ihr morgen geöffnet?", variants, "We are closed"); got != "We are closed" {
+		t.Errorf("German message without a variant got %q", got)
+	}
+	if got := Pick("ok", variants, "We are closed"); got != "We are closed" {
+		t.Errorf("undetected message got %q", got)
+	}
+}
+
+func TestValidateVariants(t *testing.T) {
+	if err := ValidateVariants(map[string]string{"es": "Hola", "ja": "はい"}, 10); err != nil {
+		t.Errorf("valid variants rejected: %v", err)
+	}
+	for _, bad := range []map[string]string{{"xx": "?"}, {"ES": "Hola"}, {"es": ""}, {"es": "demasiado largo"}} {
+		if ValidateVariants(bad, 10) == nil {
+			t.Errorf("%v accepted", bad)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+// Package leader elects one of several bridge instances to own the WhatsApp
+// session. Instances compete for a lease; the holder connects and keeps
+// renewing it, and a standby takes over once the lease expires.
+package leader
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	waLog "go.mau.fi/whatsmeow/util/log"
+
+	"whatsapp-bridge/internal/database"
+	"whatsapp-bridge/internal/redis"
+)
+
+// Lease timing: the holder renews every LeaseTTL/3. When renewals fail it
+// steps down after stepDownAfter, a renewal interval before the lease can
+// expire, so it has disconnected before a standby can take over.
+const (
+	LeaseName     = "whatsapp-session"
+	LeaseTTL      = 15 * time.Second
+	stepDownAfter = LeaseTTL - LeaseTTL/3
+)
+
+// Lease is a lock with an expiry shared by all instances
+type Lease interface {
+	// Acquire takes or renews the lease for holder and reports whether holder has it
+	Acquire(holder string, ttl time.Duration) (bool, error)
+	// Release gives the lease up if holder has it
+	Release(holder string) error
+}
+
+// storeLease keeps the lease in the shared message database
+type storeLease struct {
+	messageStore *database.MessageStore
+}
+
+// NewStoreLease returns a lease kept in the message database, for instances
+// sharing one database file
+func NewStoreLease(messageStore *database.MessageStore) Lease {
+	return storeLease{messageStore: messageStore}
+}
+
+func (l storeLease) Acquire(holder string, ttl time.Duration) (bool, error) {
+	return l.messageStore.AcquireLease(LeaseName, holder, ttl)
+}
+
+func (l storeLease) Release(holder string) error {
+	return l.messageStore.ReleaseLease(LeaseName, holder)
+}
+
+// Scripts run atomically on the Redis server
+const (
+	acquireScript = `local v = redis.call('GET', KEYS[1])
+if v == ARGV[1] then redis.call('PEXPIRE', KEYS[1], ARGV[2]) return 1 end
+if v then return 0 end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+return 1`
+	releaseScript = `if redis.call('GET', KEYS[1]) == ARGV[1] then return redis.call('DEL', KEYS[1]) end
+return 0`
+)
+
+// redisLease keeps the lease in a Redis key
+type redisLease struct {
+	client *redis.Client
+	key    string
+}
+
+// NewRedisLease returns a lease kept in Redis
+func NewRedisLease(client *redis.Client) Lease {
+	return redisLease{client: client, key: "leader:" + LeaseName}
+}
+
+func (l redisLease) Acquire(holder string, ttl time.Duration) (bool, error) {
+	reply, err := l.client.Do("EVAL", acquireScript, "1", l.key, holder, strconv.FormatInt(ttl.Milliseconds(), 10))
+	if err != nil {
+		return false, err
+	}
+	return reply == int64(1), nil
+}
+
+func (l redisLease) Release(holder string) error {
+	_, err := l.client.Do("EVAL", releaseScript, "1", l.key, holder)
+	return err
+}
+
+// Elector competes for the lease on behalf of one instance
+type Elector struct {
+	lease  Lease
+	holder string
+	logger waLog.Logger
+
+	mu        sync.Mutex
+	leader    bool
+	renewedAt time.Time
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// NewElector creates an elector for the instance named holder
+func NewElector(lease Lease, holder string, logger waLog.Logger) *Elector {
+	return &Elector{
+		lease:  lease,
+		holder: holder,
+		logger: logger,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// IsLeader reports whether this instance currently holds the lease
+func (e *Elector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leader
+}
+
+// Run competes for the lease until Stop is called. onElected runs when this
+// instance takes the lease; onDemoted runs when it loses it, including when
+// renewals keep failing until the lease is close to expiring.
+func (e *Elector) Run(onElected, onDemoted func()) {
+	defer close(e.done)
+
+	ticker := time.NewTicker(LeaseTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		e.tick(time.Now(), onElected, onDemoted)
+
+		select {
+		case <-e.stop:
+			if e.IsLeader() {
+				if err := e.lease.Release(e.holder); err != nil {
+					e.logger.Warnf("Failed to release leader lease: %v", err)
+				}
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick makes one acquire or renew attempt and fires the role change callbacks
+func (e *Elector) tick(now time.Time, onElected, onDemoted func()) {
+	ok, err := e.lease.Acquire(e.holder, LeaseTTL)
+
+	e.mu.Lock()
+	wasLeader := e.leader
+	switch {
+	case err != nil:
+		e.logger.Warnf("Leader lease check failed: %v", err)
+		// Without a confirmed renewal the lease may soon pass to a standby
+		if wasLeader && now.Sub(e.renewedAt) >= stepDownAfter {
+			e.leader = false
+		}
+	case ok:
+		e.leader = true
+		e.renewedAt = now
+	default:
+		e.leader = false
+	}
+	isLeader := e.leader
+	e.mu.Unlock()
+
+	switch {
+	case isLeader && !wasLeader:
+		e.logger.Infof("Instance %s acquired the leader lease", e.holder)
+		onElected()
+	case !isLeader && wasLeader:
+		e.logger.Warnf("Instance %s lost the leader lease", e.holder)
+		onDemoted()
+	}
+}
+
+// Stop ends Run, releasing the lease if this instance holds it
+func (e *Elector) Stop() {
+	close(e.stop)
+	<-e.done
+}
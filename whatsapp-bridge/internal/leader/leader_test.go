@@ -0,0 +1,87 @@
+package leader
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+// fakeLease replays scripted Acquire results
+type fakeLease struct {
+	results []error // nil grants the lease, errNotHeld refuses it, anything else fails
+}
+
+var errNotHeld = errors.New("held by another instance")
+
+func (l *fakeLease) Acquire(holder string, ttl time.Duration) (bool, error) {
+	result := l.results[0]
+	l.results = l.results[1:]
+	if result == errNotHeld {
+		return false, nil
+	}
+	return result == nil, result
+}
+
+func (l *fakeLease) Release(holder string) error { return nil }
+
+func TestElectorTransitions(t *testing.T) {
+	failure := errors.New("database locked")
+	lease := &fakeLease{results: []error{
+		errNotHeld, // standby
+		nil,        // elected
+		failure,    // renewal failed, lease still valid
+		nil,        // renewed
+		failure,    // renewal failed
+		failure,    // still failing past the TTL: demoted
+		errNotHeld, // standby
+	}}
+	e := NewElector(lease, "a", waLog.Noop)
+
+	var events []string
+	onElected := func() { events = append(events, "elected") }
+	onDemoted := func() { events = append(events, "demoted") }
+
+	start := time.Now()
+	steps := []struct {
+		at     time.Duration
+		leader bool
+	}{
+		{0, false},
+		{5 * time.Second, true},
+		{10 * time.Second, true},
+		{15 * time.Second, true},
+		{20 * time.Second, true},
+		{30 * time.Second, false},
+		{35 * time.Second, false},
+	}
+	for i, step := range steps {
+		e.tick(start.Add(step.at), onElected, onDemoted)
+		if e.IsLeader() != step.leader {
+			t.Errorf("step %d: IsLeader() = %v, want %v", i, e.IsLeader(), step.leader)
+		}
+	}
+
+	if len(events) != 2 || events[0] != "elected" || events[1] != "demoted" {
+		t.Errorf("events = %v, want [elected demoted]", events)
+	}
+}
+
+func TestElectorStepsDownBeforeExpiry(t *testing.T) {
+	failure := errors.New("database locked")
+	lease := &fakeLease{results: []error{nil, failure, failure}}
+	e := NewElector(lease, "a", waLog.Noop)
+	demotedAt := time.Duration(-1)
+
+	start := time.Now()
+	for _, at := range []time.Duration{0, LeaseTTL / 3, 2 * LeaseTTL / 3} {
+		e.tick(start.Add(at), func() {}, func() { demotedAt = at })
+	}
+
+	// A standby can take the lease once it expires, so the leader must have
+	// let go of the session by then
+	if e.IsLeader() || demotedAt < 0 || demotedAt >= LeaseTTL {
+		t.Errorf("leader %v, demoted at %v; want a step-down before the %v lease expires", e.IsLeader(), demotedAt, LeaseTTL)
+	}
+}
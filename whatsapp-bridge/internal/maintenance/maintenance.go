@@ -0,0 +1,118 @@
+// Package maintenance answers incoming direct messages with a fixed notice,
+// in the sender's language when it has a translation, while the systems
+// behind the bridge are down for planned maintenance.
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	waLog "go.mau.fi/whatsmeow/util/log"
+
+	"whatsapp-bridge/internal/automation"
+	"whatsapp-bridge/internal/database"
+	"whatsapp-bridge/internal/lang"
+	"whatsapp-bridge/internal/outbox"
+	localTypes "whatsapp-bridge/internal/types"
+	"whatsapp-bridge/internal/whatsapp"
+)
+
+// MaxMessageLength caps the auto-reply text
+const MaxMessageLength = 4096
+
+// Responder sends the maintenance auto-reply, at most once per contact per day
+type Responder struct {
+	messageStore *database.MessageStore
+	outbox       *outbox.Dispatcher
+	logger       waLog.Logger
+
+	mu     sync.RWMutex
+	config localTypes.MaintenanceConfig
+}
+
+// NewResponder creates a responder with maintenance mode off
+func NewResponder(messageStore *database.MessageStore, dispatcher *outbox.Dispatcher, logger waLog.Logger) *Responder {
+	return &Responder{
+		messageStore: messageStore,
+		outbox:       dispatcher,
+		logger:       logger,
+	}
+}
+
+// ValidateConfig checks that an enabled configuration has a usable message
+func ValidateConfig(cfg localTypes.MaintenanceConfig) error {
+	if cfg.Enabled && cfg.Message == "" {
+		return fmt.Errorf("message is required when maintenance mode is enabled")
+	}
+	if len(cfg.Message) > MaxMessageLength {
+		return fmt.Errorf("message must be at most %d characters", MaxMessageLength)
+	}
+	return lang.ValidateVariants(cfg.Translations, MaxMessageLength)
+}
+
+// SetConfig validates and applies a new configuration
+func (r *Responder) SetConfig(cfg localTypes.MaintenanceConfig) error {
+	if err := ValidateConfig(cfg); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.config = cfg
+	return nil
+}
+
+// Config returns the current configuration
+func (r *Responder) Config() localTypes.MaintenanceConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.config
+}
+
+// Active reports whether maintenance mode is on. While active, incoming
+// messages are still stored but webhooks and auto-read rules are skipped.
+func (r *Responder) Active() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.config.Enabled
+}
+
+// HandleMessage replies to an incoming direct message unless the contact was
+// already answered today.
+func (r *Responder) HandleMessage(msg *events.Message) {
+	if msg.Info.IsFromMe || msg.Info.IsGroup {
+		return
+	}
+	if server := msg.Info.Chat.Server; server != types.DefaultUserServer && server != types.HiddenUserServer {
+		return
+	}
+
+	cfg := r.Config()
+	if !cfg.Enabled {
+		return
+	}
+
+	chatJID := msg.Info.Chat.ToNonAD().String()
+	claimed, err := r.messageStore.ClaimAutoReply("maintenance", chatJID, time.Now().UTC().Format("2006-01-02"))
+	if err != nil {
+		r.logger.Warnf("Failed to check maintenance reply for %s: %v", chatJID, err)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	reply := lang.Pick(whatsapp.ExtractTextContent(msg.Message), cfg.Translations, cfg.Message)
+	go func() {
+		result, err := r.outbox.Send(automation.WithOrigin(context.Background(), automation.OriginMaintenance), outbox.PriorityHigh, chatJID, reply, "")
+		if err != nil {
+			r.logger.Warnf("Failed to queue maintenance reply to %s: %v", chatJID, err)
+		} else if !result.Success {
+			r.logger.Warnf("Failed to send maintenance reply to %s: %s", chatJID, result.Error)
+		}
+	}()
+}
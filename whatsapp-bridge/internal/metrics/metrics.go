@@ -0,0 +1,158 @@
+// Package metrics keeps process-wide counters and gauges and serves them in
+// the Prometheus text exposition format, optionally also pushing them to a
+// StatsD server.
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Counter is a monotonically increasing value
+type Counter struct {
+	v atomic.Uint64
+}
+
+// Inc adds one to the counter
+func (c *Counter) Inc() { c.v.Add(1) }
+
+// Add adds n to the counter
+func (c *Counter) Add(n uint64) { c.v.Add(n) }
+
+// Value returns the current count
+func (c *Counter) Value() uint64 { return c.v.Load() }
+
+// Gauge is a value that can go up and down
+type Gauge struct {
+	bits atomic.Uint64
+}
+
+// Set replaces the gauge value
+func (g *Gauge) Set(v float64) { g.bits.Store(math.Float64bits(v)) }
+
+// Value returns the current gauge value
+func (g *Gauge) Value() float64 { return math.Float64frombits(g.bits.Load()) }
+
+// series is one labelled time series of a metric family
+type series struct {
+	labels string // Prometheus form, e.g. lane="high"
+	path   string // dotted StatsD form, e.g. name.lane.high
+	value  func() float64
+}
+
+// family groups the series sharing a metric name
+type family struct {
+	name   string
+	help   string
+	kind   string // "counter" or "gauge"
+	series []series
+}
+
+var (
+	mu       sync.Mutex
+	families = make(map[string]*family)
+)
+
+// NewCounter registers a counter. labels are alternating key/value pairs.
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{}
+	register(name, help, "counter", labels, func() float64 { return float64(c.Value()) })
+	return c
+}
+
+// NewGauge registers a gauge. labels are alternating key/value pairs.
+func NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{}
+	register(name, help, "gauge", labels, g.Value)
+	return g
+}
+
+// NewGaugeFunc registers a gauge whose value is read from fn at scrape time
+func NewGaugeFunc(name, help string, fn func() float64, labels ...string) {
+	register(name, help, "gauge", labels, fn)
+}
+
+func register(name, help, kind string, labels []string, value func() float64) {
+	if len(labels)%2 != 0 {
+		panic(fmt.Sprintf("metrics: odd label list for %s", name))
+	}
+
+	pairs := make([]string, 0, len(labels)/2)
+	path := name
+	for i := 0; i < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, labels[i], escapeLabel(labels[i+1])))
+		path += "." + pathSegment(labels[i]) + "." + pathSegment(labels[i+1])
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	f, ok := families[name]
+	if !ok {
+		f = &family{name: name, help: help, kind: kind}
+		families[name] = f
+	}
+	f.series = append(f.series, series{labels: strings.Join(pairs, ","), path: path, value: value})
+}
+
+func escapeLabel(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `"`, `\"`)
+	return strings.ReplaceAll(v, "\n", `\n`)
+}
+
+// pathSegment makes a label key or value safe as one part of a dotted
+// StatsD name
+func pathSegment(v string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', ':', '|', '@', '#', ' ', '\n':
+			return '_'
+		}
+		return r
+	}, v)
+}
+
+// snapshot returns the registered families sorted by name
+func snapshot() []family {
+	mu.Lock()
+	defer mu.Unlock()
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fams := make([]family, len(names))
+	for i, name := range names {
+		fams[i] = *families[name]
+	}
+	return fams
+}
+
+// Write renders every registered metric in the Prometheus text format
+func Write(sb *strings.Builder) {
+	for _, f := range snapshot() {
+		fmt.Fprintf(sb, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind)
+		for _, s := range f.series {
+			if s.labels == "" {
+				fmt.Fprintf(sb, "%s %g\n", f.name, s.value())
+			} else {
+				fmt.Fprintf(sb, "%s{%s} %g\n", f.name, s.labels, s.value())
+			}
+		}
+	}
+}
+
+// Handler serves the metrics for scraping
+func Handler(w http.ResponseWriter, r *http.Request) {
+	var sb strings.Builder
+	Write(&sb)
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_, _ = w.Write([]byte(sb.String()))
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	sent := NewCounter("test_sent_total", "Messages sent", "lane", "high")
+	NewCounter("test_sent_total", "Messages sent", "lane", "low")
+	depth := NewGauge("test_depth", "Queue depth")
+	NewGaugeFunc("test_func", "Computed value", func() float64 { return 2.5 }, "name", `a"b`)
+
+	sent.Add(3)
+	depth.Set(7)
+
+	var sb strings.Builder
+	Write(&sb)
+	out := sb.String()
+
+	for _, want := range []string{
+		"# TYPE test_sent_total counter\n",
+		`test_sent_total{lane="high"} 3` + "\n",
+		`test_sent_total{lane="low"} 0` + "\n",
+		"# TYPE test_depth gauge\ntest_depth 7\n",
+		`test_func{name="a\"b"} 2.5` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	// Families are emitted once, sorted by name
+	if strings.Count(out, "# HELP test_sent_total") != 1 {
+		t.Errorf("expected a single HELP line for test_sent_total:\n%s", out)
+	}
+	if strings.Index(out, "test_depth") > strings.Index(out, "test_sent_total") {
+		t.Errorf("expected families sorted by name:\n%s", out)
+	}
+}
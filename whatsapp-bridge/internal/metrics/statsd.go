@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	// StatsDInterval is how often metrics are pushed to StatsD
+	StatsDInterval = 10 * time.Second
+
+	// statsdPacketSize keeps each UDP packet within a typical MTU
+	statsdPacketSize = 1432
+)
+
+// StatsD pushes the registered metrics to a StatsD server over UDP.
+// Counters are sent as the increase since the last push, gauges as their
+// current value.
+type StatsD struct {
+	conn   net.Conn
+	prefix string
+	last   map[string]float64 // counter path -> value at the last push
+}
+
+// NewStatsD connects to the StatsD server at addr (host:port). prefix, if
+// set, is prepended to every metric name, e.g. "whatsapp" gives
+// whatsapp.bridge_messages_total.direction.received.
+func NewStatsD(addr, prefix string) (*StatsD, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach StatsD at %s: %v", addr, err)
+	}
+	prefix = strings.Trim(prefix, ".")
+	if prefix != "" {
+		prefix += "."
+	}
+	return &StatsD{conn: conn, prefix: prefix, last: make(map[string]float64)}, nil
+}
+
+// Start pushes the metrics every interval until the process exits
+func (s *StatsD) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			_ = s.Push()
+		}
+	}()
+}
+
+// Push sends the metrics once. Counters that have not moved are skipped.
+func (s *StatsD) Push() error {
+	var packet strings.Builder
+	flush := func() error {
+		if packet.Len() == 0 {
+			return nil
+		}
+		_, err := s.conn.Write([]byte(packet.String()))
+		packet.Reset()
+		return err
+	}
+
+	var firstErr error
+	for _, f := range snapshot() {
+		for _, sr := range f.series {
+			value := sr.value()
+			var line string
+			if f.kind == "counter" {
+				delta := value - s.last[sr.path]
+				s.last[sr.path] = value
+				if delta <= 0 {
+					continue
+				}
+				line = fmt.Sprintf("%s%s:%g|c", s.prefix, sr.path, delta)
+			} else {
+				line = fmt.Sprintf("%s%s:%g|g", s.prefix, sr.path, value)
+				// A signed gauge value is read as a change, so reset first
+				if value < 0 {
+					line = fmt.Sprintf("%s%s:0|g\n%s", s.prefix, sr.path, line)
+				}
+			}
+
+			if packet.Len() > 0 && packet.Len()+1+len(line) > statsdPacketSize {
+				if err := flush(); err != nil && firstErr == nil {
+					firstErr = err
+				}
+			}
+			if packet.Len() > 0 {
+				packet.WriteByte('\n')
+			}
+			packet.WriteString(line)
+		}
+	}
+	if err := flush(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return firstErr
+}
@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatsDPush(t *testing.T) {
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket: %v", err)
+	}
+	defer server.Close()
+
+	received := NewCounter("statsd_messages_total", "Messages", "direction", "received")
+	NewGauge("statsd_depth", "Depth", "lane", "a.b").Set(4)
+
+	s, err := NewStatsD(server.LocalAddr().String(), "wa.")
+	if err != nil {
+		t.Fatalf("NewStatsD: %v", err)
+	}
+	read := func() string {
+		buf := make([]byte, 65536)
+		_ = server.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := server.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("no packet: %v", err)
+		}
+		return string(buf[:n])
+	}
+
+	received.Add(3)
+	if err := s.Push(); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	out := read()
+	for _, want := range []string{"wa.statsd_messages_total.direction.received:3|c", "wa.statsd_depth.lane.a_b:4|g"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("packet missing %q:\n%s", want, out)
+		}
+	}
+
+	// Counters are sent as the increase since the last push
+	received.Inc()
+	_ = s.Push()
+	if out := read(); !strings.Contains(out, "wa.statsd_messages_total.direction.received:1|c") {
+		t.Errorf("second push = %q, want an increase of 1", out)
+	}
+	_ = s.Push()
+	if out := read(); strings.Contains(out, "statsd_messages_total") {
+		t.Errorf("unchanged counter pushed again: %q", out)
+	}
+}
@@ -0,0 +1,241 @@
+// Package msgrate keeps rolling per-chat and per-sender counts of incoming
+// messages, so floods can be spotted and alerted on without an external
+// stream processor.
+package msgrate
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+// Scopes counts are kept under
+const (
+	ScopeChat   = "chat"
+	ScopeSender = "sender"
+)
+
+const (
+	// bucketSize is the resolution of the counts; a window is a whole number
+	// of buckets, the newest of which is still filling
+	bucketSize = time.Minute
+
+	// MaxWindow is the longest window counts are kept for
+	MaxWindow = time.Hour
+
+	buckets = int64(MaxWindow / bucketSize)
+)
+
+// counter is one chat's or sender's messages per minute over MaxWindow
+type counter struct {
+	counts [buckets]uint32
+	newest int64 // minute of the newest bucket
+}
+
+// add counts a message in minute, dropping it if older than MaxWindow
+func (c *counter) add(minute int64) {
+	c.advance(minute)
+	if c.newest-minute >= buckets {
+		return
+	}
+	c.counts[minute%buckets]++
+}
+
+// advance moves the newest bucket up to minute, clearing the buckets passed
+func (c *counter) advance(minute int64) {
+	if minute <= c.newest {
+		return
+	}
+	steps := minute - c.newest
+	if steps > buckets {
+		steps = buckets
+	}
+	for i := int64(1); i <= steps; i++ {
+		c.counts[(c.newest+i)%buckets] = 0
+	}
+	c.newest = minute
+}
+
+// sum counts the messages in the n minutes up to and including minute
+func (c *counter) sum(minute, n int64) int {
+	total := 0
+	for m := minute - n + 1; m <= minute; m++ {
+		if m <= c.newest && c.newest-m < buckets {
+			total += int(c.counts[m%buckets])
+		}
+	}
+	return total
+}
+
+// Tracker counts incoming messages per chat and per sender. It is safe for
+// concurrent use.
+type Tracker struct {
+	mu     sync.Mutex
+	scopes map[string]map[string]*counter // scope -> JID -> counter
+	swept  int64                          // minute of the last sweep of idle counters
+	now    func() time.Time
+}
+
+// NewTracker creates an empty tracker
+func NewTracker() *Tracker {
+	return &Tracker{
+		scopes: map[string]map[string]*counter{
+			ScopeChat:   make(map[string]*counter),
+			ScopeSender: make(map[string]*counter),
+		},
+		now: time.Now,
+	}
+}
+
+func minuteOf(t time.Time) int64 {
+	return t.Unix() / int64(bucketSize/time.Second)
+}
+
+// Record counts a message sent at the given time. Messages from the future
+// count as now; those older than MaxWindow, e.g. from an offline backlog,
+// are ignored.
+func (t *Tracker) Record(chatJID, senderJID string, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := minuteOf(t.now())
+	minute := minuteOf(at)
+	if minute > now {
+		minute = now
+	}
+	if now-minute >= buckets {
+		return
+	}
+
+	for scope, key := range map[string]string{ScopeChat: chatJID, ScopeSender: senderJID} {
+		if key == "" {
+			continue
+		}
+		c := t.scopes[scope][key]
+		if c == nil {
+			c = &counter{newest: now}
+			t.scopes[scope][key] = c
+		}
+		c.advance(now)
+		c.add(minute)
+	}
+
+	// Forget chats and senders that have been quiet for the whole window
+	if now != t.swept {
+		t.swept = now
+		for _, counters := range t.scopes {
+			for key, c := range counters {
+				if now-c.newest >= buckets {
+					delete(counters, key)
+				}
+			}
+		}
+	}
+}
+
+// Count returns how many messages a chat or sender sent within window
+func (t *Tracker) Count(scope, jid string, window time.Duration) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c := t.scopes[scope][jid]
+	if c == nil {
+		return 0
+	}
+	return c.sum(minuteOf(t.now()), windowBuckets(window))
+}
+
+// Rate returns a chat's or sender's message rate within window
+func (t *Tracker) Rate(scope, jid string, window time.Duration) localTypes.MessageRate {
+	return newRate(scope, jid, t.Count(scope, jid, window), window)
+}
+
+func newRate(scope, jid string, count int, window time.Duration) localTypes.MessageRate {
+	return localTypes.MessageRate{
+		Scope:     scope,
+		JID:       jid,
+		Count:     count,
+		Window:    FormatWindow(window),
+		PerMinute: float64(count) / window.Minutes(),
+	}
+}
+
+// Top returns the busiest chats or senders within window, busiest first,
+// leaving out those with fewer than minCount messages
+func (t *Tracker) Top(scope string, window time.Duration, minCount, limit int) []localTypes.MessageRate {
+	t.mu.Lock()
+	now := minuteOf(t.now())
+	n := windowBuckets(window)
+	rates := []localTypes.MessageRate{}
+	for jid, c := range t.scopes[scope] {
+		count := c.sum(now, n)
+		if count == 0 || count < minCount {
+			continue
+		}
+		rates = append(rates, newRate(scope, jid, count, window))
+	}
+	t.mu.Unlock()
+
+	sort.Slice(rates, func(i, j int) bool {
+		if rates[i].Count != rates[j].Count {
+			return rates[i].Count > rates[j].Count
+		}
+		return rates[i].JID < rates[j].JID
+	})
+	if limit > 0 && len(rates) > limit {
+		rates = rates[:limit]
+	}
+	return rates
+}
+
+func windowBuckets(window time.Duration) int64 {
+	n := int64(window / bucketSize)
+	if n < 1 {
+		n = 1
+	}
+	if n > buckets {
+		n = buckets
+	}
+	return n
+}
+
+// ParseWindow reads a window such as "5m" or "1h": whole minutes, at most
+// MaxWindow
+func ParseWindow(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil || d < bucketSize || d > MaxWindow || d%bucketSize != 0 {
+		return 0, fmt.Errorf("invalid window: %s (must be whole minutes from 1m to %s)", s, FormatWindow(MaxWindow))
+	}
+	return d, nil
+}
+
+// FormatWindow writes a window the way ParseWindow reads it, e.g. "5m"
+func FormatWindow(d time.Duration) string {
+	if d%time.Hour == 0 {
+		return strconv.Itoa(int(d/time.Hour)) + "h"
+	}
+	return strconv.Itoa(int(d/time.Minute)) + "m"
+}
+
+// ParseThreshold reads a rate condition written as count/window, e.g.
+// "50/5m" for more than 50 messages in five minutes
+func ParseThreshold(s string) (count int, window time.Duration, err error) {
+	countStr, windowStr, ok := strings.Cut(s, "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid rate: %s (must be count/window, e.g. 50/5m)", s)
+	}
+	count, err = strconv.Atoi(strings.TrimSpace(countStr))
+	if err != nil || count < 1 {
+		return 0, 0, fmt.Errorf("invalid rate count: %s (must be a positive number)", countStr)
+	}
+	window, err = ParseWindow(strings.TrimSpace(windowStr))
+	if err != nil {
+		return 0, 0, err
+	}
+	return count, window, nil
+}
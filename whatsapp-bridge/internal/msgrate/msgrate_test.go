@@ -0,0 +1,61 @@
+package msgrate
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTracker(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 30, 0, time.UTC)
+	tr := NewTracker()
+	tr.now = func() time.Time { return now }
+
+	group, ana, ben := "120363000000000000@g.us", "15550000001@s.whatsapp.net", "15550000002@s.whatsapp.net"
+	for i := 0; i < 3; i++ {
+		tr.Record(group, ana, now.Add(-10*time.Minute))
+	}
+	for i := 0; i < 5; i++ {
+		tr.Record(group, ben, now)
+	}
+	tr.Record(ana, ana, now.Add(time.Hour))    // from the future: counts as now
+	tr.Record(ana, ana, now.Add(-2*time.Hour)) // backlog: ignored
+
+	if got := tr.Count(ScopeChat, group, 5*time.Minute); got != 5 {
+		t.Errorf("chat count over 5m = %d, want 5", got)
+	}
+	if got := tr.Count(ScopeChat, group, 15*time.Minute); got != 8 {
+		t.Errorf("chat count over 15m = %d, want 8", got)
+	}
+	if got := tr.Count(ScopeSender, ana, time.Hour); got != 4 {
+		t.Errorf("sender count over 1h = %d, want 4", got)
+	}
+
+	top := tr.Top(ScopeSender, 15*time.Minute, 0, 1)
+	if len(top) != 1 || top[0].JID != ben || top[0].Count != 5 || top[0].Window != "15m" {
+		t.Errorf("Top = %+v, want Ben with 5", top)
+	}
+	if top := tr.Top(ScopeChat, 5*time.Minute, 2, 10); len(top) != 1 || top[0].JID != group {
+		t.Errorf("Top(min 2) = %+v, want only the group", top)
+	}
+
+	// An hour later the counts have rolled off and idle senders are forgotten
+	now = now.Add(time.Hour)
+	tr.Record(group, ben, now)
+	if got := tr.Count(ScopeChat, group, time.Hour); got != 1 {
+		t.Errorf("chat count an hour later = %d, want 1", got)
+	}
+	if _, ok := tr.scopes[ScopeSender][ana]; ok {
+		t.Error("idle sender was not forgotten")
+	}
+}
+
+func TestParseThreshold(t *testing.T) {
+	if count, window, err := ParseThreshold("50/5m"); err != nil || count != 50 || window != 5*time.Minute {
+		t.Errorf("ParseThreshold(50/5m) = %d, %v, %v", count, window, err)
+	}
+	for _, bad := range []string{"50", "0/5m", "x/5m", "50/30s", "50/2h", "50/90s"} {
+		if _, _, err := ParseThreshold(bad); err == nil {
+			t.Errorf("ParseThreshold(%q) accepted", bad)
+		}
+	}
+}
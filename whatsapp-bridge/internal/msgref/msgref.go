@@ -0,0 +1,35 @@
+// Package msgref builds opaque, URL-safe references to stored messages. A
+// message is identified by its chat JID and message ID together; raw JIDs
+// contain '@' and message IDs may contain characters that break path routing,
+// so endpoints take a reference instead.
+package msgref
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// separator cannot occur in a chat JID, so the first one ends the JID
+const separator = "/"
+
+// ErrInvalid is returned for references that do not decode to a chat and message
+var ErrInvalid = errors.New("invalid message reference")
+
+// Encode returns the reference for message id in chat chatJID
+func Encode(chatJID, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(chatJID + separator + id))
+}
+
+// Decode splits a reference back into its chat JID and message ID
+func Decode(ref string) (chatJID, id string, err error) {
+	raw, err := base64.RawURLEncoding.DecodeString(ref)
+	if err != nil {
+		return "", "", ErrInvalid
+	}
+	chatJID, id, ok := strings.Cut(string(raw), separator)
+	if !ok || chatJID == "" || id == "" || !strings.Contains(chatJID, "@") {
+		return "", "", ErrInvalid
+	}
+	return chatJID, id, nil
+}
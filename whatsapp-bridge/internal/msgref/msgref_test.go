@@ -0,0 +1,39 @@
+package msgref
+
+import "testing"
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		chatJID string
+		id      string
+	}{
+		{"15551234567@s.whatsapp.net", "3EB0C767D71D8B1E2F4A"},
+		{"120363000000000001@g.us", "id/with/slashes?and=query#frag"},
+		{"120363000000000003@newsletter", "128"},
+	}
+
+	for _, tt := range tests {
+		ref := Encode(tt.chatJID, tt.id)
+		for _, c := range ref {
+			if !(c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				t.Errorf("Encode(%q, %q) = %q contains non URL-safe %q", tt.chatJID, tt.id, ref, c)
+			}
+		}
+
+		chatJID, id, err := Decode(ref)
+		if err != nil {
+			t.Fatalf("Decode(%q) error: %v", ref, err)
+		}
+		if chatJID != tt.chatJID || id != tt.id {
+			t.Errorf("Decode(Encode(%q, %q)) = (%q, %q)", tt.chatJID, tt.id, chatJID, id)
+		}
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	for _, ref := range []string{"", "not base64!", Encode("", "id"), Encode("nojid", "id"), Encode("1@s.whatsapp.net", "")} {
+		if _, _, err := Decode(ref); err != ErrInvalid {
+			t.Errorf("Decode(%q) error = %v, want ErrInvalid", ref, err)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Package ocr extracts text from incoming images through an OpenAI-compatible
+// vision endpoint (POST /v1/chat/completions with an image part), such as
+// the OpenAI API or a local vision model server.
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultModel is sent when no model is configured
+const DefaultModel = "gpt-4o-mini"
+
+// maxErrorBody bounds how much of a failed response is quoted in the error
+const maxErrorBody = 512
+
+// noText is what the model is asked to answer for images without text
+const noText = "NO_TEXT"
+
+// prompt asks for a transcription rather than a description, so the result
+// indexes well for search
+const prompt = "Transcribe all text visible in this image, such as a receipt, " +
+	"screenshot or document, preserving line breaks. Reply with the text only. " +
+	"If the image contains no text, reply with " + noText + "."
+
+// Client sends images to a vision endpoint
+type Client struct {
+	url        string
+	apiKey     string
+	model      string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for the chat completions endpoint at url.
+// apiKey may be empty for local servers without authentication.
+func NewClient(url, apiKey, model string) *Client {
+	if model == "" {
+		model = DefaultModel
+	}
+	return &Client{
+		url:    url,
+		apiKey: apiKey,
+		model:  model,
+		httpClient: &http.Client{
+			Timeout: 2 * time.Minute,
+		},
+	}
+}
+
+type contentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *imageURL `json:"image_url,omitempty"`
+}
+
+type imageURL struct {
+	URL string `json:"url"`
+}
+
+// Annotate returns the text found in an image, or "" if it has none
+func (c *Client) Annotate(ctx context.Context, data []byte, mimetype string) (string, error) {
+	if mimetype == "" {
+		mimetype = "image/jpeg"
+	}
+
+	reqBody, err := json.Marshal(map[string]interface{}{
+		"model":       c.model,
+		"temperature": 0,
+		"messages": []map[string]interface{}{{
+			"role": "user",
+			"content": []contentPart{
+				{Type: "text", Text: prompt},
+				{Type: "image_url", ImageURL: &imageURL{URL: "data:" + mimetype + ";base64," + base64.StdEncoding.EncodeToString(data)}},
+			},
+		}},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(reqBody))
+	if err != nil {
+		return "", fmt.Errorf("invalid OCR URL: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("OCR request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return "", fmt.Errorf("OCR failed: HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var result struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("invalid OCR response: %v", err)
+	}
+	if len(result.Choices) == 0 {
+		return "", fmt.Errorf("invalid OCR response: no choices")
+	}
+
+	text := strings.TrimSpace(result.Choices[0].Message.Content)
+	if text == noText {
+		return "", nil
+	}
+	return text, nil
+}
@@ -0,0 +1,72 @@
+package ocr
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func completion(content string) string {
+	body, _ := json.Marshal(map[string]interface{}{
+		"choices": []map[string]interface{}{{"message": map[string]string{"role": "assistant", "content": content}}},
+	})
+	return string(body)
+}
+
+func TestAnnotate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model    string `json:"model"`
+			Messages []struct {
+				Content []contentPart `json:"content"`
+			} `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("invalid request: %v", err)
+		}
+		if req.Model != "vision" {
+			t.Errorf("model = %q", req.Model)
+		}
+		parts := req.Messages[0].Content
+		if len(parts) != 2 || parts[1].ImageURL == nil || parts[1].ImageURL.URL != "data:image/png;base64,iVBO" {
+			t.Errorf("unexpected content parts: %+v", parts)
+		}
+		_, _ = w.Write([]byte(completion("TOTAL 12.50\n")))
+	}))
+	defer srv.Close()
+
+	text, err := NewClient(srv.URL, "", "vision").Annotate(context.Background(), []byte{0x89, 0x50, 0x4e}, "image/png")
+	if err != nil {
+		t.Fatalf("Annotate: %v", err)
+	}
+	if text != "TOTAL 12.50" {
+		t.Errorf("text = %q", text)
+	}
+}
+
+func TestAnnotateNoText(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(completion(noText)))
+	}))
+	defer srv.Close()
+
+	text, err := NewClient(srv.URL, "", "").Annotate(context.Background(), []byte("jpeg"), "image/jpeg")
+	if err != nil || text != "" {
+		t.Errorf("Annotate = %q, %v; want empty text", text, err)
+	}
+}
+
+func TestAnnotateError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "rate limited", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	_, err := NewClient(srv.URL, "", "").Annotate(context.Background(), []byte("jpeg"), "image/jpeg")
+	if err == nil || !strings.Contains(err.Error(), "429") {
+		t.Errorf("err = %v, want HTTP 429", err)
+	}
+}
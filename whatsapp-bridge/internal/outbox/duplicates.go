@@ -0,0 +1,131 @@
+package outbox
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+// Duplicate send actions
+const (
+	DuplicateReject = "reject" // fail the send with ErrDuplicate
+	DuplicateFlag   = "flag"   // send it and mark the result as a duplicate
+)
+
+// MaxDuplicateWindow caps the duplicate send window (one day)
+const MaxDuplicateWindow = 24 * 60 * 60
+
+// ErrDuplicate is returned when the same tenant sent the same message to the
+// same recipient within the duplicate window and the action is reject
+var ErrDuplicate = errors.New("identical message already sent to this recipient within the duplicate window")
+
+// SendErrDuplicate is the send error code reported for ErrDuplicate
+const SendErrDuplicate = "duplicate_send"
+
+// ValidateDuplicateConfig checks the window and action of a duplicate send configuration
+func ValidateDuplicateConfig(cfg localTypes.DuplicateSendConfig) error {
+	if cfg.WindowSeconds < 0 || cfg.WindowSeconds > MaxDuplicateWindow {
+		return fmt.Errorf("window_seconds must be between 0 and %d", MaxDuplicateWindow)
+	}
+	switch cfg.Action {
+	case "", DuplicateReject, DuplicateFlag:
+		return nil
+	default:
+		return fmt.Errorf("action must be %q or %q", DuplicateReject, DuplicateFlag)
+	}
+}
+
+// duplicateGuard remembers recent sends by (tenant, recipient, content) so
+// loops between auto-replies and external bots cannot repeat a message
+type duplicateGuard struct {
+	mu        sync.Mutex
+	config    localTypes.DuplicateSendConfig
+	seen      map[[sha256.Size]byte]time.Time
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+func newDuplicateGuard() *duplicateGuard {
+	return &duplicateGuard{
+		seen: make(map[[sha256.Size]byte]time.Time),
+		now:  time.Now,
+	}
+}
+
+// setConfig applies a validated configuration; an action of "" means reject
+func (g *duplicateGuard) setConfig(cfg localTypes.DuplicateSendConfig) {
+	if cfg.Action == "" {
+		cfg.Action = DuplicateReject
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.config = cfg
+}
+
+func (g *duplicateGuard) getConfig() localTypes.DuplicateSendConfig {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.config
+}
+
+// check records a send and reports whether the same send was already made
+// within the window, and if so the configured action. A zero window
+// disables the guard. Call undo when the send then fails, so that trying it
+// again is not taken for a repeat; undo is never nil.
+func (g *duplicateGuard) check(owner, recipient, message, mediaPath string) (duplicate bool, action string, undo func()) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.config.WindowSeconds == 0 {
+		return false, "", func() {}
+	}
+	window := time.Duration(g.config.WindowSeconds) * time.Second
+	now := g.now()
+
+	key := sha256.Sum256([]byte(owner + "\x00" + recipientKey(recipient) + "\x00" + message + "\x00" + mediaPath))
+
+	if now.Sub(g.lastPrune) > window {
+		for k, at := range g.seen {
+			if now.Sub(at) > window {
+				delete(g.seen, k)
+			}
+		}
+		g.lastPrune = now
+	}
+
+	last, ok := g.seen[key]
+	g.seen[key] = now
+	undo = func() { g.undo(key, now, last, ok) }
+	if ok && now.Sub(last) <= window {
+		return true, g.config.Action, undo
+	}
+	return false, "", undo
+}
+
+// undo takes back the record check made at, restoring the one before it,
+// unless a later send has recorded over it
+func (g *duplicateGuard) undo(key [sha256.Size]byte, at, previous time.Time, hadPrevious bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if current, ok := g.seen[key]; !ok || !current.Equal(at) {
+		return
+	}
+	if hadPrevious {
+		g.seen[key] = previous
+	} else {
+		delete(g.seen, key)
+	}
+}
+
+// recipientKey normalizes a recipient so a bare phone number and its personal
+// chat JID count as the same chat
+func recipientKey(recipient string) string {
+	return strings.TrimSuffix(recipient, "@s.whatsapp.net")
+}
@@ -0,0 +1,102 @@
+package outbox
+
+import (
+	"testing"
+	"time"
+
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+func TestDuplicateGuard(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	g := newDuplicateGuard()
+	g.now = func() time.Time { return now }
+
+	if dup, _, _ := g.check("default", "123", "hi", ""); dup {
+		t.Fatal("Expected the guard to be off with a zero window")
+	}
+	if dup, _, _ := g.check("default", "123", "hi", ""); dup {
+		t.Fatal("Expected the guard to be off with a zero window")
+	}
+
+	g.setConfig(localTypes.DuplicateSendConfig{WindowSeconds: 60})
+	if dup, _, _ := g.check("default", "123", "hello", ""); dup {
+		t.Fatal("First send flagged as duplicate")
+	}
+
+	now = now.Add(30 * time.Second)
+	if dup, action, _ := g.check("default", "123@s.whatsapp.net", "hello", ""); !dup || action != DuplicateReject {
+		t.Errorf("Expected a JID resend within the window to be rejected, got %v %q", dup, action)
+	}
+	if dup, _, _ := g.check("default", "123", "hello again", ""); dup {
+		t.Error("Different content flagged as duplicate")
+	}
+	if dup, _, _ := g.check("other", "123", "hello", ""); dup {
+		t.Error("Another tenant's send flagged as duplicate")
+	}
+
+	// The window is measured from the latest attempt, so a loop stays blocked
+	now = now.Add(50 * time.Second)
+	if dup, _, _ := g.check("default", "123", "hello", ""); !dup {
+		t.Error("Expected a resend 50s after the last attempt to be a duplicate")
+	}
+
+	now = now.Add(61 * time.Second)
+	if dup, _, _ := g.check("default", "123", "hello", ""); dup {
+		t.Error("Send after the window flagged as duplicate")
+	}
+	if len(g.seen) != 1 {
+		t.Errorf("Expected expired entries to be pruned, have %d", len(g.seen))
+	}
+
+	g.setConfig(localTypes.DuplicateSendConfig{WindowSeconds: 60, Action: DuplicateFlag})
+	if _, action, _ := g.check("default", "123", "hello", ""); action != DuplicateFlag {
+		t.Errorf("action = %q, want %q", action, DuplicateFlag)
+	}
+}
+
+func TestValidateDuplicateConfig(t *testing.T) {
+	for _, cfg := range []localTypes.DuplicateSendConfig{
+		{WindowSeconds: -1},
+		{WindowSeconds: MaxDuplicateWindow + 1},
+		{WindowSeconds: 10, Action: "drop"},
+	} {
+		if err := ValidateDuplicateConfig(cfg); err == nil {
+			t.Errorf("Expected %+v to be invalid", cfg)
+		}
+	}
+	if err := ValidateDuplicateConfig(localTypes.DuplicateSendConfig{WindowSeconds: 30, Action: DuplicateFlag}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestDuplicateGuardUndo(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	g := newDuplicateGuard()
+	g.now = func() time.Time { return now }
+	g.setConfig(localTypes.DuplicateSendConfig{WindowSeconds: 60})
+
+	// A send that failed does not block trying it again
+	_, _, undo := g.check("default", "123", "hello", "")
+	undo()
+	if dup, _, _ := g.check("default", "123", "hello", ""); dup {
+		t.Error("retry of a failed send flagged as duplicate")
+	}
+
+	// Undoing a failed repeat keeps the send that went through before it
+	now = now.Add(10 * time.Second)
+	_, _, undo = g.check("default", "123", "hello", "")
+	undo()
+	if dup, _, _ := g.check("default", "123", "hello", ""); !dup {
+		t.Error("repeat of a successful send not flagged after a failed one")
+	}
+
+	// A later send's record is not taken back by an earlier send's failure
+	_, _, undo = g.check("default", "456", "hi", "")
+	now = now.Add(time.Second)
+	g.check("default", "456", "hi", "")
+	undo()
+	if dup, _, _ := g.check("default", "456", "hi", ""); !dup {
+		t.Error("failure of an earlier send undid a later one")
+	}
+}
@@ -0,0 +1,128 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	localTypes "whatsapp-bridge/internal/types"
+	"whatsapp-bridge/internal/whatsapp"
+)
+
+// pollVote is a vote queued for a stored poll
+type pollVote struct {
+	ChatJID   string   `json:"chat_jid"`
+	MessageID string   `json:"message_id"`
+	Options   []string `json:"options"` // the option names picked; none takes the vote back
+}
+
+// pin is a pin or unpin queued for a message
+type pin struct {
+	ChatJID   string        `json:"chat_jid"`
+	MessageID string        `json:"message_id"`
+	SenderJID string        `json:"sender_jid,omitempty"`
+	Duration  time.Duration `json:"duration,omitempty"`
+	Unpin     bool          `json:"unpin,omitempty"`
+}
+
+// CreatePoll queues a poll like Send queues a message
+func (d *Dispatcher) CreatePoll(ctx context.Context, req localTypes.CreatePollRequest) (localTypes.SendResult, error) {
+	return d.enqueue(ctx, PriorityHigh, kindPoll, req.ChatJID, payload{Poll: &req}, true)
+}
+
+// VotePoll queues a vote in a stored poll like Send queues a message. On
+// success it returns the vote cast.
+func (d *Dispatcher) VotePoll(ctx context.Context, chatJID, messageID string, options []string) (localTypes.SendResult, *localTypes.PollVote, error) {
+	o, err := d.submit(ctx, PriorityHigh, kindPollVote, chatJID, payload{PollVote: &pollVote{ChatJID: chatJID, MessageID: messageID, Options: options}}, true)
+	vote, _ := o.data.(*localTypes.PollVote)
+	return o.result, vote, err
+}
+
+// SendReaction queues an emoji reaction like Send queues a message
+func (d *Dispatcher) SendReaction(ctx context.Context, req localTypes.ReactionRequest) (localTypes.SendResult, error) {
+	return d.enqueue(ctx, PriorityHigh, kindReaction, req.ChatJID, payload{Reaction: &req}, true)
+}
+
+// ReactToNewsletterMessage queues a reaction to a channel post like Send
+// queues a message
+func (d *Dispatcher) ReactToNewsletterMessage(ctx context.Context, req localTypes.NewsletterReactionRequest) (localTypes.SendResult, error) {
+	return d.enqueue(ctx, PriorityHigh, kindNewsletterReaction, req.JID, payload{NewsletterReaction: &req}, true)
+}
+
+// PinMessage queues a pin like Send queues a message. The pin is stored once
+// sent; on success it is returned.
+func (d *Dispatcher) PinMessage(ctx context.Context, chatJID, messageID, senderJID string, duration time.Duration) (localTypes.SendResult, *localTypes.PinnedMessage, error) {
+	o, err := d.submit(ctx, PriorityHigh, kindPin, chatJID, payload{Pin: &pin{ChatJID: chatJID, MessageID: messageID, SenderJID: senderJID, Duration: duration}}, true)
+	pinned, _ := o.data.(*localTypes.PinnedMessage)
+	return o.result, pinned, err
+}
+
+// UnpinMessage queues the removal of a pin like Send queues a message. The
+// stored pin is removed once it is sent.
+func (d *Dispatcher) UnpinMessage(ctx context.Context, chatJID, messageID, senderJID string) (localTypes.SendResult, error) {
+	return d.enqueue(ctx, PriorityHigh, kindPin, chatJID, payload{Pin: &pin{ChatJID: chatJID, MessageID: messageID, SenderJID: senderJID, Unpin: true}}, true)
+}
+
+// interact sends a job of one of the interaction kinds. What it stores on
+// success is stored here rather than by the caller, so a job resumed after a
+// restart is recorded too.
+func (d *Dispatcher) interact(ctx context.Context, j *job) localTypes.SendResult {
+	p := j.payload
+	switch j.kind {
+	case kindPoll:
+		result, err := d.client.CreatePoll(ctx, d.messageStore, p.Poll.ChatJID, p.Poll.Question, p.Poll.Options, p.Poll.MultiSelect)
+		if err != nil {
+			return whatsapp.SendError(err)
+		}
+		return result
+
+	case kindPollVote:
+		poll, err := d.messageStore.GetPoll(p.PollVote.ChatJID, p.PollVote.MessageID)
+		if err != nil {
+			return whatsapp.SendError(err)
+		}
+		if poll == nil {
+			return localTypes.SendResult{Error: fmt.Sprintf("poll %s not found", p.PollVote.MessageID), Code: whatsapp.SendErrUnknown}
+		}
+		vote, err := d.client.VotePoll(ctx, d.messageStore, poll, p.PollVote.Options)
+		if err != nil {
+			return whatsapp.SendError(err)
+		}
+		j.data = vote
+		return localTypes.SendResult{Success: true, Timestamp: vote.VotedAt}
+
+	case kindReaction:
+		if err := d.client.SendReaction(ctx, p.Reaction.ChatJID, p.Reaction.MessageID, p.Reaction.Emoji); err != nil {
+			return whatsapp.SendError(err)
+		}
+		return localTypes.SendResult{Success: true, Timestamp: time.Now()}
+
+	case kindNewsletterReaction:
+		r := p.NewsletterReaction
+		if err := d.client.ReactToNewsletterMessage(ctx, r.JID, r.ServerID, r.Reaction); err != nil {
+			return whatsapp.SendError(err)
+		}
+		return localTypes.SendResult{Success: true, Timestamp: time.Now()}
+
+	case kindPin:
+		if p.Pin.Unpin {
+			if err := d.client.UnpinMessage(ctx, p.Pin.ChatJID, p.Pin.MessageID, p.Pin.SenderJID); err != nil {
+				return whatsapp.SendError(err)
+			}
+			if _, err := d.messageStore.DeletePinnedMessage(p.Pin.ChatJID, p.Pin.MessageID); err != nil {
+				d.logger.Warnf("Failed to remove pin of %s: %v", p.Pin.MessageID, err)
+			}
+			return localTypes.SendResult{Success: true, Timestamp: time.Now()}
+		}
+		pinned, err := d.client.PinMessage(ctx, p.Pin.ChatJID, p.Pin.MessageID, p.Pin.SenderJID, p.Pin.Duration)
+		if err != nil {
+			return whatsapp.SendError(err)
+		}
+		if err := d.messageStore.StorePinnedMessage(pinned); err != nil {
+			d.logger.Warnf("Failed to store pin of %s: %v", p.Pin.MessageID, err)
+		}
+		j.data = pinned
+		return localTypes.SendResult{Success: true, Timestamp: pinned.PinnedAt}
+	}
+	return localTypes.SendResult{Error: fmt.Sprintf("unknown send kind %q", j.kind), Code: whatsapp.SendErrUnknown}
+}
@@ -0,0 +1,611 @@
+// Package outbox dispatches outgoing messages through two priority lanes so
+// interactive replies are not stuck behind bulk campaigns. Queued sends are
+// kept in the database until they are done, so a restart resends them, and
+// go out within the configured send rates, retried on transient failures.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	waLog "go.mau.fi/whatsmeow/util/log"
+
+	"whatsapp-bridge/internal/automation"
+	"whatsapp-bridge/internal/database"
+	"whatsapp-bridge/internal/metrics"
+	"whatsapp-bridge/internal/recovery"
+	"whatsapp-bridge/internal/tenant"
+	localTypes "whatsapp-bridge/internal/types"
+	"whatsapp-bridge/internal/whatsapp"
+)
+
+// Send priorities. Requests without a priority are high so existing callers
+// keep interactive latency; bulk senders opt into the low lane.
+const (
+	PriorityHigh = "high"
+	PriorityLow  = "low"
+)
+
+const (
+	// laneCapacity bounds each lane; further sends are rejected until it drains
+	laneCapacity = 1000
+
+	// workers is the number of concurrent sends to WhatsApp
+	workers = 2
+
+	// starvationLimit lets one waiting low priority send through after this
+	// many consecutive high priority sends on a worker
+	starvationLimit = 10
+
+	// sendTimeout bounds one send, including any media upload, so a hung
+	// call cannot pin a worker
+	sendTimeout = 2 * time.Minute
+
+	// maxAttempts is how often a send failing transiently is tried in all
+	maxAttempts = 4
+
+	// retryBackoff is the wait before the first retry; it doubles after each
+	retryBackoff = 5 * time.Second
+)
+
+// Kinds of send, by what a job sends
+const (
+	kindMessage     = "message"
+	kindContacts    = "contacts"
+	kindProduct     = "product"
+	kindCatalog     = "catalog"
+	kindStickerPack = "sticker_pack"
+
+	// Interactions with a chat's messages, see interactions.go
+	kindPoll               = "poll"
+	kindPollVote           = "poll_vote"
+	kindReaction           = "reaction"
+	kindNewsletterReaction = "newsletter_reaction"
+	kindPin                = "pin"
+)
+
+// ErrQueueFull is returned when the requested lane is at capacity
+var ErrQueueFull = errors.New("outbox queue is full")
+
+// ErrAutomationPaused is returned for sends by an automation the loop
+// breaker has paused
+var ErrAutomationPaused = errors.New("automation paused by the loop breaker")
+
+// ErrAccountRestricted is returned while WhatsApp has temporarily banned or
+// rate limited the account
+var ErrAccountRestricted = errors.New("account is restricted by WhatsApp; sending is paused")
+
+// Send error codes reported for ErrQueueFull, ErrAutomationPaused and
+// ErrAccountRestricted
+const (
+	SendErrQueueFull         = "queue_full"
+	SendErrAutomationPaused  = "automation_paused"
+	SendErrAccountRestricted = "account_restricted"
+)
+
+// payload is what a job sends, by kind. It is stored with the job as JSON.
+type payload struct {
+	Message     string                             `json:"message,omitempty"`
+	MediaPath   string                             `json:"media_path,omitempty"`
+	Mentions    []string                           `json:"mentions,omitempty"`
+	LinkPreview bool                               `json:"link_preview,omitempty"`
+	Contacts    []localTypes.ContactCard           `json:"contacts,omitempty"`
+	Product     *localTypes.SendProductRequest     `json:"product,omitempty"`
+	Catalog     *localTypes.SendCatalogRequest     `json:"catalog,omitempty"`
+	StickerPack *localTypes.SendStickerPackRequest `json:"sticker_pack,omitempty"`
+
+	Poll               *localTypes.CreatePollRequest         `json:"poll,omitempty"`
+	PollVote           *pollVote                             `json:"poll_vote,omitempty"`
+	Reaction           *localTypes.ReactionRequest           `json:"reaction,omitempty"`
+	NewsletterReaction *localTypes.NewsletterReactionRequest `json:"newsletter_reaction,omitempty"`
+	Pin                *pin                                  `json:"pin,omitempty"`
+
+	// Origin is the automation that made the send (see automation.WithOrigin),
+	// kept so a resent job still counts against its loop breaker
+	Origin string `json:"origin,omitempty"`
+}
+
+// outcome is what a finished job hands back to the caller waiting for it
+type outcome struct {
+	result localTypes.SendResult
+	data   interface{} // what the send produced, such as a pin or a poll vote
+}
+
+// job is one queued send awaiting a worker
+type job struct {
+	id        int64 // row in outbox_jobs; 0 if it could not be stored
+	priority  string
+	kind      string
+	recipient string
+	owner     string // tenant the send is recorded for
+	payload   payload
+	attempts  int
+	slotTaken bool         // its chat's rate limit slot has come
+	data      interface{}  // set by send for kinds that produce more than a result
+	undo      func()       // takes back its duplicate check record; nil for a job resumed after a restart
+	result    chan outcome // nil for a job resumed after a restart
+}
+
+// Dispatcher queues sends and hands them to WhatsApp, high priority first
+type Dispatcher struct {
+	client       *whatsapp.Client
+	messageStore *database.MessageStore
+	logger       waLog.Logger
+
+	high chan *job
+	low  chan *job
+
+	inFlight atomic.Int64
+	delayed  atomic.Int64                // jobs waiting off the lanes for a retry or a rate limit slot
+	sent     map[string]*metrics.Counter // priority -> successful sends
+	failed   map[string]*metrics.Counter // priority -> failed sends
+	retried  map[string]*metrics.Counter // priority -> retries after a transient failure
+
+	// Spaces sends out to the configured rates
+	rates *rateLimiter
+
+	// Rejects or flags repeats of a recent send (see duplicates.go)
+	duplicates *duplicateGuard
+
+	// Pauses automations caught in a reply loop
+	loops *automation.Breaker
+}
+
+// NewDispatcher creates a dispatcher; call Start to begin sending
+func NewDispatcher(client *whatsapp.Client, messageStore *database.MessageStore, logger waLog.Logger) *Dispatcher {
+	d := &Dispatcher{
+		client:       client,
+		messageStore: messageStore,
+		logger:       logger,
+		high:         make(chan *job, laneCapacity),
+		low:          make(chan *job, laneCapacity),
+		sent:         make(map[string]*metrics.Counter),
+		failed:       make(map[string]*metrics.Counter),
+		retried:      make(map[string]*metrics.Counter),
+		rates:        newRateLimiter(),
+		duplicates:   newDuplicateGuard(),
+		loops:        automation.NewBreaker(),
+	}
+
+	for _, p := range []string{PriorityHigh, PriorityLow} {
+		lane := d.lane(p)
+		metrics.NewGaugeFunc("bridge_outbox_depth", "Sends waiting in the outbox lane",
+			func() float64 { return float64(len(lane)) }, "priority", p)
+		d.sent[p] = metrics.NewCounter("bridge_outbox_sent_total", "Sends completed successfully", "priority", p)
+		d.failed[p] = metrics.NewCounter("bridge_outbox_failed_total", "Sends that failed", "priority", p)
+		d.retried[p] = metrics.NewCounter("bridge_outbox_retried_total", "Sends retried after a transient failure", "priority", p)
+	}
+	metrics.NewGaugeFunc("bridge_outbox_in_flight", "Sends currently being written to WhatsApp",
+		func() float64 { return float64(d.inFlight.Load()) })
+	metrics.NewGaugeFunc("bridge_outbox_delayed", "Sends waiting for a retry or a rate limit slot",
+		func() float64 { return float64(d.delayed.Load()) })
+
+	return d
+}
+
+// ValidPriority reports whether p is an accepted priority ("" means high)
+func ValidPriority(p string) bool {
+	return p == "" || p == PriorityHigh || p == PriorityLow
+}
+
+// Start queues the sends left over from the last run, then launches the
+// send workers
+func (d *Dispatcher) Start() {
+	d.resume()
+	for i := 0; i < workers; i++ {
+		go d.worker()
+	}
+}
+
+// resume queues the sends stored by an earlier run that never finished.
+// Nobody waits for them any more; their results are only logged.
+func (d *Dispatcher) resume() {
+	rows, err := d.messageStore.ListOutboxJobs()
+	if err != nil {
+		d.logger.Warnf("Failed to load queued sends: %v", err)
+		return
+	}
+	if len(rows) == 0 {
+		return
+	}
+
+	jobs := make([]*job, 0, len(rows))
+	for _, row := range rows {
+		j := &job{id: row.ID, priority: row.Priority, kind: row.Kind, recipient: row.Recipient, owner: row.Tenant, attempts: row.Attempts}
+		if err := json.Unmarshal([]byte(row.Payload), &j.payload); err != nil {
+			d.logger.Warnf("Dropping queued send %d to %s: %v", row.ID, row.Recipient, err)
+			d.messageStore.FinishOutboxJob(row.ID)
+			continue
+		}
+		jobs = append(jobs, j)
+	}
+	d.logger.Infof("Resending %d sends queued before the restart", len(jobs))
+
+	// More may be stored than the lanes hold, so they are fed in as the
+	// workers drain them
+	recovery.Go("outbox resume", func() {
+		for _, j := range jobs {
+			d.lane(j.priority) <- j
+		}
+	})
+}
+
+// Send queues a message in its priority lane and waits for the result. If ctx
+// ends first the send still happens; only the wait is abandoned. The message is
+// recorded as sent by the tenant carried by ctx. A repeat of a send made within
+// the duplicate window fails with ErrDuplicate or is flagged, as configured.
+// Sends by an automation (see automation.WithOrigin) fail with
+// ErrAutomationPaused once the loop breaker has paused it. While WhatsApp
+// restricts the account, sends fail with ErrAccountRestricted.
+//
+// Mentions and link previews set on ctx (see whatsapp.WithMentions and
+// whatsapp.WithLinkPreview) are queued with the message.
+func (d *Dispatcher) Send(ctx context.Context, priority, recipient, message, mediaPath string) (localTypes.SendResult, error) {
+	return d.enqueue(ctx, priority, kindMessage, recipient, messagePayload(ctx, message, mediaPath), false)
+}
+
+// SendForced is Send without the duplicate send check
+func (d *Dispatcher) SendForced(ctx context.Context, priority, recipient, message, mediaPath string) (localTypes.SendResult, error) {
+	return d.enqueue(ctx, priority, kindMessage, recipient, messagePayload(ctx, message, mediaPath), true)
+}
+
+// SendContacts queues contact cards like Send queues a message
+func (d *Dispatcher) SendContacts(ctx context.Context, recipient string, cards []localTypes.ContactCard) (localTypes.SendResult, error) {
+	return d.enqueue(ctx, PriorityHigh, kindContacts, recipient, payload{Contacts: cards}, true)
+}
+
+// SendProduct queues a catalog product like Send queues a message
+func (d *Dispatcher) SendProduct(ctx context.Context, req localTypes.SendProductRequest) (localTypes.SendResult, error) {
+	return d.enqueue(ctx, PriorityHigh, kindProduct, req.Recipient, payload{Product: &req}, true)
+}
+
+// SendCatalog queues a catalog link like Send queues a message
+func (d *Dispatcher) SendCatalog(ctx context.Context, req localTypes.SendCatalogRequest) (localTypes.SendResult, error) {
+	return d.enqueue(ctx, PriorityHigh, kindCatalog, req.Recipient, payload{Catalog: &req}, true)
+}
+
+// SendStickerPack queues a sticker pack like Send queues a message
+func (d *Dispatcher) SendStickerPack(ctx context.Context, req localTypes.SendStickerPackRequest) (localTypes.SendResult, error) {
+	return d.enqueue(ctx, PriorityHigh, kindStickerPack, req.Recipient, payload{StickerPack: &req}, true)
+}
+
+func messagePayload(ctx context.Context, message, mediaPath string) payload {
+	return payload{
+		Message:     message,
+		MediaPath:   mediaPath,
+		Mentions:    whatsapp.MentionsFrom(ctx),
+		LinkPreview: whatsapp.LinkPreviewFrom(ctx),
+	}
+}
+
+// SetRateConfig validates and applies the send rates
+func (d *Dispatcher) SetRateConfig(cfg localTypes.SendRateConfig) error {
+	if err := ValidateRateConfig(cfg); err != nil {
+		return err
+	}
+	d.rates.setConfig(cfg)
+	return nil
+}
+
+// RateConfig returns the send rates in effect
+func (d *Dispatcher) RateConfig() localTypes.SendRateConfig {
+	return d.rates.getConfig()
+}
+
+// SetDuplicateConfig validates and applies the duplicate send protection
+func (d *Dispatcher) SetDuplicateConfig(cfg localTypes.DuplicateSendConfig) error {
+	if err := ValidateDuplicateConfig(cfg); err != nil {
+		return err
+	}
+	d.duplicates.setConfig(cfg)
+	return nil
+}
+
+// DuplicateConfig returns the duplicate send protection in effect
+func (d *Dispatcher) DuplicateConfig() localTypes.DuplicateSendConfig {
+	return d.duplicates.getConfig()
+}
+
+// LoopBreaker returns the breaker that pauses automations caught in a loop
+func (d *Dispatcher) LoopBreaker() *automation.Breaker {
+	return d.loops
+}
+
+// alertLoop reports a tripped loop breaker to the log and the admin JID
+func (d *Dispatcher) alertLoop(origin, recipient string) {
+	d.logger.Errorf("Loop breaker paused automation %q after repeated sends to %s", origin, recipient)
+
+	cfg := d.loops.Config()
+	admin := cfg.AdminJID
+	if admin == "" {
+		return
+	}
+	text := fmt.Sprintf("⚠ Loop breaker: automation %q sent more than %d messages to %s within %ds and has been paused. Resume it with POST /api/automations/resume.",
+		origin, cfg.MaxMessages, recipient, cfg.WindowSeconds)
+	recovery.Go("loop breaker alert", func() {
+		result, err := d.SendForced(context.Background(), PriorityHigh, admin, text, "")
+		if err != nil {
+			d.logger.Warnf("Failed to queue loop breaker alert to %s: %v", admin, err)
+		} else if !result.Success {
+			d.logger.Warnf("Failed to send loop breaker alert to %s: %s", admin, result.Error)
+		}
+	})
+}
+
+func (d *Dispatcher) enqueue(ctx context.Context, priority, kind, recipient string, p payload, force bool) (localTypes.SendResult, error) {
+	o, err := d.submit(ctx, priority, kind, recipient, p, force)
+	return o.result, err
+}
+
+// submit queues a job and waits for its outcome, see Send
+func (d *Dispatcher) submit(ctx context.Context, priority, kind, recipient string, p payload, force bool) (outcome, error) {
+	if priority == "" {
+		priority = PriorityHigh
+	}
+
+	if _, restricted := d.client.Restriction(); restricted {
+		return outcome{}, ErrAccountRestricted
+	}
+
+	allowed, tripped := d.loops.Allow(automation.Origin(ctx), recipientKey(recipient))
+	if tripped {
+		d.alertLoop(automation.Origin(ctx), recipient)
+	}
+	if !allowed {
+		return outcome{}, ErrAutomationPaused
+	}
+
+	var flagged bool
+	undo := func() {}
+	if !force {
+		var duplicate bool
+		var action string
+		duplicate, action, undo = d.duplicates.check(tenant.FromContext(ctx), recipient, p.Message, p.MediaPath)
+		if duplicate && action == DuplicateReject {
+			// Kept as recorded, so a loop stays blocked while it repeats
+			d.logger.Warnf("Outbox rejected duplicate send to %s", recipient)
+			return outcome{}, ErrDuplicate
+		}
+		flagged = duplicate
+	}
+
+	p.Origin = automation.Origin(ctx)
+	j := &job{
+		priority:  priority,
+		kind:      kind,
+		recipient: recipient,
+		owner:     tenant.FromContext(ctx),
+		payload:   p,
+		undo:      undo,
+		result:    make(chan outcome, 1),
+	}
+
+	// A send that cannot be stored still goes out; it is only not resent
+	// after a restart
+	row := &localTypes.OutboxJob{Priority: priority, Kind: kind, Recipient: recipient, Tenant: j.owner}
+	if encoded, err := json.Marshal(p); err != nil {
+		d.logger.Warnf("Failed to encode send to %s for the outbox: %v", recipient, err)
+	} else {
+		row.Payload = string(encoded)
+		if err := d.messageStore.QueueOutboxJob(row); err != nil {
+			d.logger.Warnf("Failed to store send to %s in the outbox: %v", recipient, err)
+		} else {
+			j.id = row.ID
+		}
+	}
+
+	select {
+	case d.lane(priority) <- j:
+	default:
+		d.forget(j)
+		undo()
+		return outcome{}, ErrQueueFull
+	}
+
+	select {
+	case o := <-j.result:
+		o.result.Duplicate = flagged
+		return o, nil
+	case <-ctx.Done():
+		return outcome{}, ctx.Err()
+	}
+}
+
+// Stats reports lane depths and send counts
+func (d *Dispatcher) Stats() localTypes.OutboxStats {
+	return localTypes.OutboxStats{
+		Capacity: laneCapacity,
+		InFlight: int(d.inFlight.Load()),
+		Delayed:  int(d.delayed.Load()),
+		Lanes: map[string]localTypes.OutboxLaneStats{
+			PriorityHigh: d.laneStats(PriorityHigh),
+			PriorityLow:  d.laneStats(PriorityLow),
+		},
+		Rate: d.rates.getConfig(),
+	}
+}
+
+func (d *Dispatcher) laneStats(priority string) localTypes.OutboxLaneStats {
+	return localTypes.OutboxLaneStats{
+		Depth:   len(d.lane(priority)),
+		Sent:    d.sent[priority].Value(),
+		Failed:  d.failed[priority].Value(),
+		Retried: d.retried[priority].Value(),
+	}
+}
+
+func (d *Dispatcher) lane(priority string) chan *job {
+	if priority == PriorityLow {
+		return d.low
+	}
+	return d.high
+}
+
+// worker sends queued jobs, always preferring the high lane except when a
+// long run of high priority sends would starve the low lane
+func (d *Dispatcher) worker() {
+	highStreak := 0
+	for {
+		var j *job
+
+		if highStreak >= starvationLimit {
+			select {
+			case j = <-d.low:
+			default:
+			}
+		}
+		if j == nil {
+			select {
+			case j = <-d.high:
+			default:
+			}
+		}
+		if j == nil {
+			select {
+			case j = <-d.high:
+			case j = <-d.low:
+			}
+		}
+
+		if j.priority == PriorityHigh {
+			highStreak++
+		} else {
+			highStreak = 0
+		}
+
+		d.dispatch(j)
+	}
+}
+
+// dispatch sends one job once its rate limit slots come. A transient
+// failure puts it back for another try after a backoff; anything else
+// finishes it.
+func (d *Dispatcher) dispatch(j *job) {
+	// The chat's slot is taken when the job first reaches a worker. Waiting
+	// for it happens off the lanes, so sends to other chats go on meanwhile.
+	if !j.slotTaken {
+		j.slotTaken = true
+		if wait := d.rates.reserveChat(recipientKey(j.recipient)); wait > 0 {
+			d.later(j, wait)
+			return
+		}
+	}
+	if wait := d.rates.reserve(); wait > 0 {
+		time.Sleep(wait)
+	}
+
+	d.inFlight.Add(1)
+	start := time.Now()
+	result := d.send(j)
+	d.inFlight.Add(-1)
+	j.attempts++
+
+	if retryable(result) && j.attempts < maxAttempts {
+		wait := retryBackoff << (j.attempts - 1)
+		d.retried[j.priority].Inc()
+		d.logger.Warnf("Outbox %s send to %s failed (attempt %d of %d), retrying in %v: %s", j.priority, j.recipient, j.attempts, maxAttempts, wait, result.Error)
+		if j.id != 0 {
+			if err := d.messageStore.SetOutboxJobAttempts(j.id, j.attempts); err != nil {
+				d.logger.Warnf("Failed to record outbox send attempt: %v", err)
+			}
+		}
+		j.slotTaken = false
+		d.later(j, wait)
+		return
+	}
+
+	if result.Success {
+		d.sent[j.priority].Inc()
+	} else {
+		if j.undo != nil {
+			j.undo()
+		}
+		d.failed[j.priority].Inc()
+		d.logger.Warnf("Outbox %s send to %s failed after %v: %s", j.priority, j.recipient, time.Since(start).Round(time.Millisecond), result.Error)
+	}
+
+	d.forget(j)
+	if j.result != nil {
+		j.result <- outcome{result: result, data: j.data}
+	}
+}
+
+// retryable reports whether a failed send is worth another try. Timeouts
+// are not: the message may have gone through, and a retry would repeat it.
+// Neither is a restricted account, which lasts far longer than the backoff.
+func retryable(result localTypes.SendResult) bool {
+	return !result.Success && result.Retryable &&
+		result.Code != whatsapp.SendErrTimeout && result.Code != SendErrAccountRestricted
+}
+
+// later puts a job back in its lane after wait, without holding a worker
+func (d *Dispatcher) later(j *job, wait time.Duration) {
+	d.delayed.Add(1)
+	time.AfterFunc(wait, func() {
+		d.delayed.Add(-1)
+		d.lane(j.priority) <- j
+	})
+}
+
+// forget removes a finished job from the database
+func (d *Dispatcher) forget(j *job) {
+	if j.id == 0 {
+		return
+	}
+	if err := d.messageStore.FinishOutboxJob(j.id); err != nil {
+		d.logger.Warnf("Failed to remove finished send from the outbox: %v", err)
+	}
+}
+
+// send hands one job to WhatsApp. A panic becomes a failed result so neither
+// the worker nor the waiting caller is lost. Jobs still queued when the
+// account becomes restricted fail without being sent.
+func (d *Dispatcher) send(j *job) (result localTypes.SendResult) {
+	defer recovery.Handle(recovery.SourceJob, fmt.Sprintf("outbox send to %s", j.recipient), func() {
+		result = localTypes.SendResult{Error: "internal error while sending", Code: whatsapp.SendErrUnknown}
+	})
+
+	if _, restricted := d.client.Restriction(); restricted {
+		return localTypes.SendResult{Error: ErrAccountRestricted.Error(), Code: SendErrAccountRestricted, Retryable: true}
+	}
+
+	ctx, cancel := context.WithTimeout(j.context(), sendTimeout)
+	defer cancel()
+
+	p := j.payload
+	switch j.kind {
+	case kindPoll, kindPollVote, kindReaction, kindNewsletterReaction, kindPin:
+		return d.interact(ctx, j)
+	case kindContacts:
+		return d.client.SendContacts(ctx, d.messageStore, j.owner, j.recipient, p.Contacts)
+	case kindProduct:
+		return d.client.SendProduct(ctx, d.messageStore, j.owner, *p.Product)
+	case kindCatalog:
+		return d.client.SendCatalog(ctx, d.messageStore, j.owner, *p.Catalog)
+	case kindStickerPack:
+		return d.client.SendStickerPack(ctx, d.messageStore, j.owner, *p.StickerPack)
+	}
+
+	return d.client.SendMessageAs(ctx, d.messageStore, j.owner, j.recipient, p.Message, p.MediaPath)
+}
+
+// context rebuilds what the caller's context carried when the job was
+// queued: the tenant, the automation that sent it, and the message's
+// mentions and link preview
+func (j *job) context() context.Context {
+	ctx := tenant.WithName(context.Background(), j.owner)
+	if j.payload.Origin != "" {
+		ctx = automation.WithOrigin(ctx, j.payload.Origin)
+	}
+	if len(j.payload.Mentions) > 0 {
+		ctx = whatsapp.WithMentions(ctx, j.payload.Mentions)
+	}
+	if j.payload.LinkPreview {
+		ctx = whatsapp.WithLinkPreview(ctx)
+	}
+	return ctx
+}
@@ -0,0 +1,70 @@
+package outbox
+
+import (
+	"testing"
+	"time"
+
+	waLog "go.mau.fi/whatsmeow/util/log"
+
+	"whatsapp-bridge/internal/automation"
+	"whatsapp-bridge/internal/database"
+	"whatsapp-bridge/internal/tenant"
+	localTypes "whatsapp-bridge/internal/types"
+	"whatsapp-bridge/internal/whatsapp"
+)
+
+func TestResumedJobKeepsOrigin(t *testing.T) {
+	t.Chdir(t.TempDir())
+	store, err := database.NewMessageStore()
+	if err != nil {
+		t.Fatalf("NewMessageStore: %v", err)
+	}
+	defer store.Close()
+
+	// As stored by an automated reply before a restart
+	row := &localTypes.OutboxJob{Priority: PriorityHigh, Kind: kindMessage, Recipient: "15550102030", Tenant: "support",
+		Payload: `{"message":"We are closed","mentions":["15550102031@s.whatsapp.net"],"origin":"business_hours"}`}
+	if err := store.QueueOutboxJob(row); err != nil {
+		t.Fatalf("QueueOutboxJob: %v", err)
+	}
+
+	d := &Dispatcher{messageStore: store, logger: waLog.Noop, high: make(chan *job, 1), low: make(chan *job, 1)}
+	d.resume()
+
+	var j *job
+	select {
+	case j = <-d.high:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stored job not resumed")
+	}
+	ctx := j.context()
+	if origin := automation.Origin(ctx); origin != "business_hours" {
+		t.Errorf("resumed job sent with origin %q", origin)
+	}
+	if owner := tenant.FromContext(ctx); owner != "support" {
+		t.Errorf("resumed job sent for %q", owner)
+	}
+	if mentions := whatsapp.MentionsFrom(ctx); len(mentions) != 1 {
+		t.Errorf("resumed job mentions = %v", mentions)
+	}
+
+	// A job without an origin is sent as a plain API send
+	if origin := automation.Origin((&job{owner: "support"}).context()); origin != "" {
+		t.Errorf("job without an origin sent with %q", origin)
+	}
+}
+
+func TestPollVoteJobForUnknownPoll(t *testing.T) {
+	t.Chdir(t.TempDir())
+	store, err := database.NewMessageStore()
+	if err != nil {
+		t.Fatalf("NewMessageStore: %v", err)
+	}
+	defer store.Close()
+
+	d := &Dispatcher{messageStore: store, logger: waLog.Noop}
+	j := &job{kind: kindPollVote, payload: payload{PollVote: &pollVote{ChatJID: "15550102030@s.whatsapp.net", MessageID: "P1"}}}
+	if result := d.interact(j.context(), j); result.Success || result.Retryable || j.data != nil {
+		t.Errorf("vote in an unknown poll = %+v", result)
+	}
+}
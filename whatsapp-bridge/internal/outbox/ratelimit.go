@@ -0,0 +1,100 @@
+package outbox
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+// MaxSendRate caps both send rates; faster than one send a tenth of a second
+// is no limit at all
+const MaxSendRate = 600
+
+// ValidateRateConfig checks the rates of a send rate configuration
+func ValidateRateConfig(cfg localTypes.SendRateConfig) error {
+	if cfg.GlobalPerMinute < 0 || cfg.GlobalPerMinute > MaxSendRate {
+		return fmt.Errorf("global_per_minute must be between 0 and %d", MaxSendRate)
+	}
+	if cfg.ChatPerMinute < 0 || cfg.ChatPerMinute > MaxSendRate {
+		return fmt.Errorf("chat_per_minute must be between 0 and %d", MaxSendRate)
+	}
+	return nil
+}
+
+// rateLimiter spaces sends evenly: a limit of N per minute lets one send go
+// every minute/N, overall and to each chat. Callers take a slot and wait
+// until it comes, so sends go in the order their slots were taken.
+type rateLimiter struct {
+	mu        sync.Mutex
+	config    localTypes.SendRateConfig
+	next      time.Time            // earliest slot for any send
+	nextChat  map[string]time.Time // earliest slot per chat
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		nextChat: make(map[string]time.Time),
+		now:      time.Now,
+	}
+}
+
+func (l *rateLimiter) setConfig(cfg localTypes.SendRateConfig) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.config = cfg
+}
+
+func (l *rateLimiter) getConfig() localTypes.SendRateConfig {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.config
+}
+
+// reserveChat takes the next slot for a send to chat and returns how long
+// until it comes
+func (l *rateLimiter) reserveChat(chat string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastPrune) > time.Minute {
+		for c, at := range l.nextChat {
+			if at.Before(now) {
+				delete(l.nextChat, c)
+			}
+		}
+		l.lastPrune = now
+	}
+
+	if l.config.ChatPerMinute == 0 {
+		return 0
+	}
+	slot := l.nextChat[chat]
+	if slot.Before(now) {
+		slot = now
+	}
+	l.nextChat[chat] = slot.Add(time.Minute / time.Duration(l.config.ChatPerMinute))
+	return slot.Sub(now)
+}
+
+// reserve takes the next slot for any send and returns how long until it
+// comes
+func (l *rateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.config.GlobalPerMinute == 0 {
+		return 0
+	}
+	now := l.now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(time.Minute / time.Duration(l.config.GlobalPerMinute))
+	return slot.Sub(now)
+}
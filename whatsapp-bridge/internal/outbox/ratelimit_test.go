@@ -0,0 +1,72 @@
+package outbox
+
+import (
+	"testing"
+	"time"
+
+	localTypes "whatsapp-bridge/internal/types"
+	"whatsapp-bridge/internal/whatsapp"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	l := newRateLimiter()
+	l.now = func() time.Time { return now }
+
+	if l.reserve() != 0 || l.reserveChat("123") != 0 || l.reserveChat("123") != 0 {
+		t.Fatal("Expected no waits without limits")
+	}
+
+	l.setConfig(localTypes.SendRateConfig{GlobalPerMinute: 60, ChatPerMinute: 6})
+
+	// Sends to one chat are spaced ten seconds apart, in the order reserved
+	for i, want := range []time.Duration{0, 10 * time.Second, 20 * time.Second} {
+		if got := l.reserveChat("123"); got != want {
+			t.Errorf("send %d to 123 waits %v, want %v", i, got, want)
+		}
+	}
+	if got := l.reserveChat("456"); got != 0 {
+		t.Errorf("another chat waits %v, want none", got)
+	}
+
+	// All sends are spaced a second apart
+	if l.reserve() != 0 || l.reserve() != time.Second {
+		t.Error("Expected the second send to wait a second")
+	}
+
+	// Slots that have passed are not owed
+	now = now.Add(time.Minute)
+	if l.reserve() != 0 || l.reserveChat("123") != 0 {
+		t.Error("Expected no wait once the slots have passed")
+	}
+}
+
+func TestValidateRateConfig(t *testing.T) {
+	if err := ValidateRateConfig(localTypes.SendRateConfig{GlobalPerMinute: 30, ChatPerMinute: 10}); err != nil {
+		t.Errorf("valid config rejected: %v", err)
+	}
+	for _, cfg := range []localTypes.SendRateConfig{{GlobalPerMinute: -1}, {ChatPerMinute: MaxSendRate + 1}} {
+		if ValidateRateConfig(cfg) == nil {
+			t.Errorf("%+v accepted", cfg)
+		}
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		result localTypes.SendResult
+		want   bool
+	}{
+		{localTypes.SendResult{Success: true}, false},
+		{localTypes.SendResult{Code: whatsapp.SendErrNotConnected, Retryable: true}, true},
+		{localTypes.SendResult{Code: whatsapp.SendErrServerRejected, Retryable: true}, true},
+		{localTypes.SendResult{Code: whatsapp.SendErrTimeout, Retryable: true}, false},
+		{localTypes.SendResult{Code: SendErrAccountRestricted, Retryable: true}, false},
+		{localTypes.SendResult{Code: whatsapp.SendErrInvalidRecipient}, false},
+	}
+	for _, tt := range tests {
+		if got := retryable(tt.result); got != tt.want {
+			t.Errorf("retryable(%s) = %v, want %v", tt.result.Code, got, tt.want)
+		}
+	}
+}
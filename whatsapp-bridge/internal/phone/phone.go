@@ -0,0 +1,202 @@
+// Package phone normalizes free-form phone numbers, as people type or paste
+// them, into E.164 form and WhatsApp user JIDs. It follows libphonenumber's
+// approach for the common cases: formatting is ignored, digits from any
+// script are accepted, and national numbers are completed from a region's
+// calling code and trunk prefix.
+package phone
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const (
+	// E.164 numbers, calling code included, have at most 15 digits; the
+	// shortest in use have 7
+	minDigits = 7
+	maxDigits = 15
+
+	// userServer is the JID server of personal chats
+	userServer = "s.whatsapp.net"
+)
+
+// ErrEmpty is returned for input without digits
+var ErrEmpty = errors.New("no phone number given")
+
+// ErrExtension is returned for numbers with an extension, which WhatsApp
+// accounts cannot have
+var ErrExtension = errors.New("phone extensions cannot be reached on WhatsApp")
+
+// ErrUnknownRegion is returned for a region hint that is not listed
+var ErrUnknownRegion = errors.New("unknown region; use an ISO 3166-1 alpha-2 code such as US or GB")
+
+// Number is a normalized phone number
+type Number struct {
+	E164        string // e.g. +15550102030
+	Digits      string // E164 without the "+", the user part of the JID
+	CountryCode string // calling code, empty if not a listed one
+	Region      string // ISO region, empty if the calling code is shared or not listed
+}
+
+// JID returns the number's WhatsApp user JID
+func (n Number) JID() string {
+	return n.Digits + "@" + userServer
+}
+
+var defaultRegion struct {
+	sync.RWMutex
+	name string
+}
+
+// SetDefaultRegion sets the region national numbers are read in when the
+// caller gives none. Empty means numbers must be in international form.
+func SetDefaultRegion(name string) error {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if _, ok := regions[name]; name != "" && !ok {
+		return ErrUnknownRegion
+	}
+
+	defaultRegion.Lock()
+	defer defaultRegion.Unlock()
+	defaultRegion.name = name
+	return nil
+}
+
+// DefaultRegion returns the region set by SetDefaultRegion
+func DefaultRegion() string {
+	defaultRegion.RLock()
+	defer defaultRegion.RUnlock()
+	return defaultRegion.name
+}
+
+// Parse normalizes a phone number. A number starting with "+", "00" or the
+// region's international call prefix is read as international. Otherwise it
+// is read in regionHint (or the default region) as a national number, unless
+// it starts with that region's calling code and has the length of a full
+// number there: a national number merely starting with the calling code's
+// digits, such as 91234 56789 in India, is still national. Without a region
+// it must be international with the "+" left out, as the API has always
+// accepted.
+func Parse(input, regionHint string) (Number, error) {
+	name := strings.ToUpper(strings.TrimSpace(regionHint))
+	if name == "" {
+		name = DefaultRegion()
+	}
+	var rules region
+	if name != "" {
+		var ok bool
+		if rules, ok = regions[name]; !ok {
+			return Number{}, ErrUnknownRegion
+		}
+	}
+
+	plus, digits, err := scan(input)
+	if err != nil {
+		return Number{}, err
+	}
+
+	switch {
+	case plus:
+	case name == "":
+		digits = strings.TrimPrefix(digits, "00")
+	case strings.HasPrefix(digits, rules.intl):
+		digits = digits[len(rules.intl):]
+	case strings.HasPrefix(digits, "00"):
+		digits = digits[2:]
+	case rules.trunk != "" && strings.HasPrefix(digits, rules.trunk):
+		digits = rules.code + digits[len(rules.trunk):]
+	case !rules.international(digits):
+		digits = rules.code + digits
+	}
+
+	if len(digits) < minDigits || len(digits) > maxDigits {
+		return Number{}, fmt.Errorf("%q is not a valid phone number: expected %d to %d digits with the country code, got %d", input, minDigits, maxDigits, len(digits))
+	}
+	if digits[0] == '0' {
+		return Number{}, fmt.Errorf("%q is not a valid phone number: country codes do not start with 0; give a region for national numbers", input)
+	}
+
+	n := Number{E164: "+" + digits, Digits: digits, CountryCode: knownCode(digits)}
+	n.Region = regionsByCode[n.CountryCode]
+	if n.Region == "" && n.CountryCode != "" && n.CountryCode == rules.code {
+		n.Region = name
+	}
+	return n, nil
+}
+
+// scan extracts the digits of input, noting a leading "+". Separators are
+// skipped; any other character is an error.
+func scan(input string) (plus bool, digits string, err error) {
+	s := strings.TrimSpace(input)
+	if len(s) >= 4 && strings.EqualFold(s[:4], "tel:") {
+		s = s[4:]
+	}
+
+	var b strings.Builder
+	for i, r := range s {
+		if d, ok := digitValue(r); ok {
+			b.WriteByte(byte('0' + d))
+			continue
+		}
+		switch {
+		case r == '+' || r == '\uff0b': // fullwidth plus
+			if plus || b.Len() > 0 {
+				return false, "", fmt.Errorf("%q is not a valid phone number: misplaced \"+\"", input)
+			}
+			plus = true
+		case isSeparator(r):
+		case r == '#' || r == ';' || r == 'x' || r == 'X' || strings.HasPrefix(strings.ToLower(s[i:]), "ext"):
+			return false, "", ErrExtension
+		default:
+			return false, "", fmt.Errorf("%q is not a valid phone number: unexpected %q", input, r)
+		}
+	}
+
+	if b.Len() == 0 {
+		return false, "", ErrEmpty
+	}
+	return plus, b.String(), nil
+}
+
+// isSeparator reports whether r is formatting people put in phone numbers,
+// including the direction marks right-to-left text inserts
+func isSeparator(r rune) bool {
+	return unicode.IsSpace(r) || unicode.Is(unicode.Pd, r) || strings.ContainsRune("()./\u200e\u200f", r)
+}
+
+// digitZeros are the zeros of the decimal digit blocks phone numbers are
+// written in; each block runs from its zero to nine
+var digitZeros = []rune{
+	'0',      // ASCII
+	'\u0660', // Arabic-Indic
+	'\u06f0', // Extended Arabic-Indic (Persian, Urdu)
+	'\u0966', // Devanagari
+	'\u09e6', // Bengali
+	'\u0a66', // Gurmukhi
+	'\u0ae6', // Gujarati
+	'\u0b66', // Oriya
+	'\u0be6', // Tamil
+	'\u0c66', // Telugu
+	'\u0ce6', // Kannada
+	'\u0d66', // Malayalam
+	'\u0e50', // Thai
+	'\u0ed0', // Lao
+	'\u0f20', // Tibetan
+	'\u1040', // Myanmar
+	'\u17e0', // Khmer
+	'\u1810', // Mongolian
+	'\uff10', // Fullwidth
+}
+
+// digitValue returns the value of a decimal digit in any supported script
+func digitValue(r rune) (int, bool) {
+	for _, zero := range digitZeros {
+		if r >= zero && r <= zero+9 {
+			return int(r - zero), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,102 @@
+package phone
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input, region string
+		want          string
+		wantRegion    string
+	}{
+		{"+1 (555) 010-2030", "", "15550102030", ""},
+		{"+1 (555) 010-2030", "CA", "15550102030", "CA"},
+		{"15550102030", "", "15550102030", ""},
+		{"tel:+44-20-7946-0958", "", "442079460958", "GB"},
+		{"0044 20 7946 0958", "", "442079460958", "GB"},
+		{"(555) 010-2030", "US", "15550102030", "US"},
+		{"1 555 010 2030", "us", "15550102030", "US"},
+		{"011 44 20 7946 0958", "US", "442079460958", "GB"},
+		{"07911 123456", "GB", "447911123456", "GB"},
+		{"447911123456", "GB", "447911123456", "GB"},
+		{"06 12 34 56 78", "FR", "33612345678", "FR"},
+		{"06 1234 5678", "IT", "390612345678", "IT"},
+		{"090-1234-5678", "JP", "819012345678", "JP"},
+		{"8 (912) 345-67-89", "RU", "79123456789", "RU"},
+		{"98765 43210", "IN", "919876543210", "IN"},
+		{"91234 56789", "IN", "919123456789", "IN"}, // national, starting with the calling code
+		{"919123456789", "IN", "919123456789", "IN"},
+		{"0044 20 7946 0958", "IN", "442079460958", "GB"},
+		{"555 010 2030", "US", "15550102030", "US"},
+		{"15550102030", "US", "15550102030", "US"},
+		{"0044 20 7946 0958", "US", "442079460958", "GB"},
+		{"030 123456", "DE", "4930123456", "DE"},
+		{"4915123456789", "DE", "4915123456789", "DE"},
+		{"491 1234567", "DE", "494911234567", "DE"},          // national, starting with the calling code
+		{"०९८७६५ ४३२१०", "IN", "919876543210", "IN"},         // Devanagari digits
+		{"\u200f+٩٧١ ٥٠ ١٢٣ ٤٥٦٧", "", "971501234567", "AE"}, // Arabic-Indic digits, RTL mark
+		{"۰۹۱۲ ۳۴۵ ۶۷۸۹", "IR", "989123456789", "IR"},        // Persian digits
+		{"＋８１ ９０ １２３４ ５６７８", "", "819012345678", "JP"},       // fullwidth
+	}
+
+	for _, tt := range tests {
+		n, err := Parse(tt.input, tt.region)
+		if err != nil {
+			t.Errorf("Parse(%q, %q) error = %v", tt.input, tt.region, err)
+			continue
+		}
+		if n.Digits != tt.want || n.E164 != "+"+tt.want || n.JID() != tt.want+"@s.whatsapp.net" || n.Region != tt.wantRegion {
+			t.Errorf("Parse(%q, %q) = %+v, want %s in %q", tt.input, tt.region, n, tt.want, tt.wantRegion)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input, region string
+		want          error
+	}{
+		{"", "", ErrEmpty},
+		{" - ", "", ErrEmpty},
+		{"+1 555 010 2030 ext. 12", "", ErrExtension},
+		{"+1 555 010 2030 x12", "", ErrExtension},
+		{"5550102030", "ZZ", ErrUnknownRegion},
+		{"+1 555 abc", "", nil},
+		{"1+5550102030", "", nil},
+		{"12345", "", nil},
+		{"+1234567890123456", "", nil},
+		{"07911 123456", "", nil}, // national number without a region
+	}
+
+	for _, tt := range tests {
+		n, err := Parse(tt.input, tt.region)
+		if err == nil {
+			t.Errorf("Parse(%q, %q) = %+v, want an error", tt.input, tt.region, n)
+			continue
+		}
+		if tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("Parse(%q, %q) error = %v, want %v", tt.input, tt.region, err, tt.want)
+		}
+	}
+}
+
+func TestDefaultRegion(t *testing.T) {
+	if err := SetDefaultRegion("XX"); !errors.Is(err, ErrUnknownRegion) {
+		t.Errorf("SetDefaultRegion(XX) error = %v", err)
+	}
+	if err := SetDefaultRegion("gb"); err != nil {
+		t.Fatalf("SetDefaultRegion(gb) error = %v", err)
+	}
+	defer SetDefaultRegion("")
+
+	n, err := Parse("07911 123456", "")
+	if err != nil || n.Digits != "447911123456" {
+		t.Errorf("Parse in the default region = %+v, %v", n, err)
+	}
+	// An explicit hint wins
+	if n, err := Parse("06 12 34 56 78", "FR"); err != nil || n.Digits != "33612345678" {
+		t.Errorf("Parse with a hint = %+v, %v", n, err)
+	}
+}
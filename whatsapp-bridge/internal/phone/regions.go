@@ -0,0 +1,138 @@
+package phone
+
+import "strings"
+
+// region describes how phone numbers are dialled in one country
+type region struct {
+	code    string // country calling code
+	trunk   string // national trunk prefix, dropped in international form
+	intl    string // international call prefix, replaced by "+"
+	shared  bool   // the calling code is shared with other regions
+	lengths []int  // national number lengths, trunk prefix excluded
+}
+
+// international reports whether digits, given without "+", are a full
+// number of the region rather than a national number that happens to start
+// with the calling code's digits: it starts with the calling code and has
+// the length of one of the region's numbers after it
+func (r region) international(digits string) bool {
+	if !strings.HasPrefix(digits, r.code) {
+		return false
+	}
+	for _, n := range r.lengths {
+		if len(digits) == len(r.code)+n {
+			return true
+		}
+	}
+	return false
+}
+
+// regions maps ISO 3166-1 alpha-2 codes to their dialling rules. Countries
+// not listed can still be normalized from international form.
+var regions = map[string]region{
+	// North American Numbering Plan
+	"US": {code: "1", trunk: "1", intl: "011", shared: true, lengths: []int{10}},
+	"CA": {code: "1", trunk: "1", intl: "011", shared: true, lengths: []int{10}},
+
+	// Europe
+	"GB": {code: "44", trunk: "0", intl: "00", lengths: []int{9, 10}},
+	"IE": {code: "353", trunk: "0", intl: "00", lengths: []int{9}},
+	"FR": {code: "33", trunk: "0", intl: "00", lengths: []int{9}},
+	"DE": {code: "49", trunk: "0", intl: "00", lengths: []int{10, 11}},
+	"ES": {code: "34", intl: "00", lengths: []int{9}},
+	"IT": {code: "39", intl: "00", lengths: []int{9, 10}}, // the leading 0 of landlines is part of the number
+	"PT": {code: "351", intl: "00", lengths: []int{9}},
+	"NL": {code: "31", trunk: "0", intl: "00", lengths: []int{9}},
+	"BE": {code: "32", trunk: "0", intl: "00", lengths: []int{8, 9}},
+	"CH": {code: "41", trunk: "0", intl: "00", lengths: []int{9}},
+	"AT": {code: "43", trunk: "0", intl: "00", lengths: []int{10, 11, 12, 13}},
+	"SE": {code: "46", trunk: "0", intl: "00", lengths: []int{9}},
+	"NO": {code: "47", intl: "00", lengths: []int{8}},
+	"DK": {code: "45", intl: "00", lengths: []int{8}},
+	"FI": {code: "358", trunk: "0", intl: "00", lengths: []int{9, 10}},
+	"PL": {code: "48", intl: "00", lengths: []int{9}},
+	"CZ": {code: "420", intl: "00", lengths: []int{9}},
+	"GR": {code: "30", intl: "00", lengths: []int{10}},
+	"RO": {code: "40", trunk: "0", intl: "00", lengths: []int{9}},
+	"HU": {code: "36", trunk: "06", intl: "00", lengths: []int{8, 9}},
+	"UA": {code: "380", trunk: "0", intl: "00", lengths: []int{9}},
+	"RU": {code: "7", trunk: "8", intl: "810", shared: true, lengths: []int{10}},
+	"KZ": {code: "7", trunk: "8", intl: "810", shared: true, lengths: []int{10}},
+	"TR": {code: "90", trunk: "0", intl: "00", lengths: []int{10}},
+
+	// Middle East and Africa
+	"IL": {code: "972", trunk: "0", intl: "00", lengths: []int{9}},
+	"AE": {code: "971", trunk: "0", intl: "00", lengths: []int{9}},
+	"SA": {code: "966", trunk: "0", intl: "00", lengths: []int{9}},
+	"QA": {code: "974", intl: "00", lengths: []int{8}},
+	"KW": {code: "965", intl: "00", lengths: []int{8}},
+	"BH": {code: "973", intl: "00", lengths: []int{8}},
+	"OM": {code: "968", intl: "00", lengths: []int{8}},
+	"JO": {code: "962", trunk: "0", intl: "00", lengths: []int{9}},
+	"LB": {code: "961", trunk: "0", intl: "00", lengths: []int{7, 8}},
+	"IQ": {code: "964", trunk: "0", intl: "00", lengths: []int{10}},
+	"IR": {code: "98", trunk: "0", intl: "00", lengths: []int{10}},
+	"EG": {code: "20", trunk: "0", intl: "00", lengths: []int{10}},
+	"MA": {code: "212", trunk: "0", intl: "00", lengths: []int{9}},
+	"DZ": {code: "213", trunk: "0", intl: "00", lengths: []int{9}},
+	"TN": {code: "216", intl: "00", lengths: []int{8}},
+	"NG": {code: "234", trunk: "0", intl: "009", lengths: []int{10}},
+	"GH": {code: "233", trunk: "0", intl: "00", lengths: []int{9}},
+	"KE": {code: "254", trunk: "0", intl: "000", lengths: []int{9}},
+	"ET": {code: "251", trunk: "0", intl: "00", lengths: []int{9}},
+	"ZA": {code: "27", trunk: "0", intl: "00", lengths: []int{9}},
+
+	// Asia and Oceania
+	"IN": {code: "91", trunk: "0", intl: "00", lengths: []int{10}},
+	"PK": {code: "92", trunk: "0", intl: "00", lengths: []int{10}},
+	"BD": {code: "880", trunk: "0", intl: "00", lengths: []int{10}},
+	"LK": {code: "94", trunk: "0", intl: "00", lengths: []int{9}},
+	"NP": {code: "977", trunk: "0", intl: "00", lengths: []int{10}},
+	"CN": {code: "86", trunk: "0", intl: "00", lengths: []int{11}},
+	"HK": {code: "852", intl: "001", lengths: []int{8}},
+	"TW": {code: "886", trunk: "0", intl: "002", lengths: []int{9}},
+	"JP": {code: "81", trunk: "0", intl: "010", lengths: []int{10}},
+	"KR": {code: "82", trunk: "0", intl: "001", lengths: []int{9, 10}},
+	"SG": {code: "65", intl: "000", lengths: []int{8}},
+	"MY": {code: "60", trunk: "0", intl: "00", lengths: []int{9, 10}},
+	"ID": {code: "62", trunk: "0", intl: "001", lengths: []int{9, 10, 11, 12}},
+	"TH": {code: "66", trunk: "0", intl: "001", lengths: []int{9}},
+	"VN": {code: "84", trunk: "0", intl: "00", lengths: []int{9}},
+	"PH": {code: "63", trunk: "0", intl: "00", lengths: []int{10}},
+	"AU": {code: "61", trunk: "0", intl: "0011", lengths: []int{9}},
+	"NZ": {code: "64", trunk: "0", intl: "00", lengths: []int{8, 9, 10}},
+
+	// Latin America
+	"MX": {code: "52", intl: "00", lengths: []int{10}},
+	"BR": {code: "55", trunk: "0", intl: "00", lengths: []int{10, 11}},
+	"AR": {code: "54", trunk: "0", intl: "00", lengths: []int{10, 11}},
+	"CO": {code: "57", intl: "00", lengths: []int{10}},
+	"CL": {code: "56", intl: "00", lengths: []int{9}},
+	"PE": {code: "51", trunk: "0", intl: "00", lengths: []int{9}},
+	"VE": {code: "58", trunk: "0", intl: "00", lengths: []int{10}},
+}
+
+// callingCodes holds every listed calling code; regionsByCode maps those
+// used by a single listed region to it
+var callingCodes, regionsByCode = func() (map[string]bool, map[string]string) {
+	codes := make(map[string]bool)
+	byCode := make(map[string]string)
+	for name, r := range regions {
+		codes[r.code] = true
+		if !r.shared {
+			byCode[r.code] = name
+		}
+	}
+	return codes, byCode
+}()
+
+// knownCode returns the listed calling code that digits start with. Calling
+// codes are prefix-free, so at most one matches.
+func knownCode(digits string) string {
+	for n := 1; n <= 3 && n <= len(digits); n++ {
+		if callingCodes[digits[:n]] {
+			return digits[:n]
+		}
+	}
+	return ""
+}
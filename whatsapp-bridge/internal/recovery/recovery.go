@@ -0,0 +1,78 @@
+// Package recovery keeps a panic in one request, event or background job from
+// crashing the whole bridge and dropping the WhatsApp connection.
+package recovery
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"go.mau.fi/whatsmeow/types/events"
+	waLog "go.mau.fi/whatsmeow/util/log"
+
+	"whatsapp-bridge/internal/metrics"
+	"whatsapp-bridge/internal/redact"
+)
+
+// Panic sources, used as the metric label
+const (
+	SourceHTTP  = "http"
+	SourceEvent = "event"
+	SourceJob   = "job"
+)
+
+var (
+	logger = redact.Logger(waLog.Stdout("Recovery", "INFO", true))
+
+	panics = map[string]*metrics.Counter{
+		SourceHTTP:  metrics.NewCounter("bridge_panics_total", "Panics recovered without crashing the bridge", "source", SourceHTTP),
+		SourceEvent: metrics.NewCounter("bridge_panics_total", "Panics recovered without crashing the bridge", "source", SourceEvent),
+		SourceJob:   metrics.NewCounter("bridge_panics_total", "Panics recovered without crashing the bridge", "source", SourceJob),
+	}
+)
+
+// Handle recovers a panic, logging it with detail and the stack trace and
+// counting it under source. It must be deferred directly, e.g.
+//
+//	defer recovery.Handle(recovery.SourceJob, "nightly cleanup", nil)
+//
+// onPanic, if set, runs after a panic was recovered.
+func Handle(source, detail string, onPanic func()) {
+	err := recover()
+	if err == nil {
+		return
+	}
+
+	if counter := panics[source]; counter != nil {
+		counter.Inc()
+	}
+	logger.Errorf("Recovered panic in %s (%s): %v\n%s", source, detail, err, debug.Stack())
+
+	if onPanic != nil {
+		onPanic()
+	}
+}
+
+// Go runs fn in a new goroutine, recovering any panic it raises
+func Go(detail string, fn func()) {
+	go func() {
+		defer Handle(SourceJob, detail, nil)
+		fn()
+	}()
+}
+
+// EventHandler wraps a whatsmeow event handler so a panic while handling one
+// event is logged with the event's context and the next event still runs
+func EventHandler(handler func(evt interface{})) func(evt interface{}) {
+	return func(evt interface{}) {
+		defer Handle(SourceEvent, describeEvent(evt), nil)
+		handler(evt)
+	}
+}
+
+// describeEvent identifies an event for the panic log
+func describeEvent(evt interface{}) string {
+	if msg, ok := evt.(*events.Message); ok {
+		return fmt.Sprintf("message %s in %s from %s", msg.Info.ID, msg.Info.Chat, msg.Info.Sender)
+	}
+	return fmt.Sprintf("%T", evt)
+}
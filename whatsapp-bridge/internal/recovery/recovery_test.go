@@ -0,0 +1,52 @@
+package recovery
+
+import (
+	"testing"
+
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+func TestHandleRecoversAndCounts(t *testing.T) {
+	before := panics[SourceJob].Value()
+
+	called := false
+	func() {
+		defer Handle(SourceJob, "test job", func() { called = true })
+		panic("boom")
+	}()
+
+	if !called {
+		t.Error("onPanic was not called")
+	}
+	if got := panics[SourceJob].Value(); got != before+1 {
+		t.Errorf("panic counter = %d, want %d", got, before+1)
+	}
+}
+
+func TestHandleWithoutPanic(t *testing.T) {
+	func() {
+		defer Handle(SourceJob, "test job", func() { t.Error("onPanic called without a panic") })
+	}()
+}
+
+func TestEventHandlerContinues(t *testing.T) {
+	before := panics[SourceEvent].Value()
+
+	handled := 0
+	handler := EventHandler(func(evt interface{}) {
+		handled++
+		if _, ok := evt.(*events.Message); ok {
+			panic("malformed message")
+		}
+	})
+
+	handler(&events.Message{})
+	handler(&events.Connected{})
+
+	if handled != 2 {
+		t.Errorf("handled %d events, want 2", handled)
+	}
+	if got := panics[SourceEvent].Value(); got != before+1 {
+		t.Errorf("panic counter = %d, want %d", got, before+1)
+	}
+}
@@ -0,0 +1,117 @@
+// Package redact scrubs known secrets (API keys, webhook secrets, pairing
+// codes) from log output, so logs can be shipped to aggregation without
+// leaking credentials. Secrets are registered where they are loaded or
+// generated; every logger the bridge creates is wrapped with Logger.
+package redact
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+// Placeholder replaces each secret
+const Placeholder = "[REDACTED]"
+
+// minSecretLength keeps short values, which would scrub ordinary words and
+// numbers, out of the registry
+const minSecretLength = 6
+
+var registry = struct {
+	sync.RWMutex
+	secrets  map[string]bool
+	replacer *strings.Replacer
+}{secrets: make(map[string]bool)}
+
+// Register adds secrets to be scrubbed from log output. Empty and very
+// short values are ignored.
+func Register(secrets ...string) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	changed := false
+	for _, s := range secrets {
+		if len(s) >= minSecretLength && !registry.secrets[s] {
+			registry.secrets[s] = true
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+
+	// Longest first, so a secret containing another is replaced whole
+	sorted := make([]string, 0, len(registry.secrets))
+	for s := range registry.secrets {
+		sorted = append(sorted, s)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+
+	pairs := make([]string, 0, 2*len(sorted))
+	for _, s := range sorted {
+		pairs = append(pairs, s, Placeholder)
+	}
+	registry.replacer = strings.NewReplacer(pairs...)
+}
+
+// String returns s with every registered secret replaced by Placeholder
+func String(s string) string {
+	registry.RLock()
+	replacer := registry.replacer
+	registry.RUnlock()
+
+	if replacer == nil {
+		return s
+	}
+	return replacer.Replace(s)
+}
+
+// Logger wraps l so messages are scrubbed before they are written
+func Logger(l waLog.Logger) waLog.Logger {
+	return &logger{inner: l}
+}
+
+type logger struct {
+	inner waLog.Logger
+}
+
+func (l *logger) Errorf(msg string, args ...interface{}) {
+	l.inner.Errorf("%s", String(fmt.Sprintf(msg, args...)))
+}
+
+func (l *logger) Warnf(msg string, args ...interface{}) {
+	l.inner.Warnf("%s", String(fmt.Sprintf(msg, args...)))
+}
+
+func (l *logger) Infof(msg string, args ...interface{}) {
+	l.inner.Infof("%s", String(fmt.Sprintf(msg, args...)))
+}
+
+func (l *logger) Debugf(msg string, args ...interface{}) {
+	l.inner.Debugf("%s", String(fmt.Sprintf(msg, args...)))
+}
+
+func (l *logger) Sub(module string) waLog.Logger {
+	return &logger{inner: l.inner.Sub(module)}
+}
+
+// Writer wraps w so each write is scrubbed. A secret split across two
+// writes is not caught, so use it under line-based writers such as log.Logger.
+func Writer(w io.Writer) io.Writer {
+	return &writer{inner: w}
+}
+
+type writer struct {
+	inner io.Writer
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.inner, String(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,74 @@
+package redact
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+// recorder captures formatted log messages
+type recorder struct {
+	module string
+	lines  *[]string
+}
+
+func (r recorder) record(level, msg string, args ...interface{}) {
+	*r.lines = append(*r.lines, r.module+" "+level+" "+fmt.Sprintf(msg, args...))
+}
+
+func (r recorder) Errorf(msg string, args ...interface{}) { r.record("ERROR", msg, args...) }
+func (r recorder) Warnf(msg string, args ...interface{})  { r.record("WARN", msg, args...) }
+func (r recorder) Infof(msg string, args ...interface{})  { r.record("INFO", msg, args...) }
+func (r recorder) Debugf(msg string, args ...interface{}) { r.record("DEBUG", msg, args...) }
+
+func (r recorder) Sub(module string) waLog.Logger {
+	return recorder{module: r.module + "/" + module, lines: r.lines}
+}
+
+func TestString(t *testing.T) {
+	Register("", "abc", "sk-live-0123456789", "sk-live-0123456789-extended", "WXYZ-1234")
+
+	tests := map[string]string{
+		"key sk-live-0123456789 used":          "key " + Placeholder + " used",
+		"key sk-live-0123456789-extended used": "key " + Placeholder + " used",
+		"code WXYZ-1234 expires":               "code " + Placeholder + " expires",
+		"abc is too short to register":         "abc is too short to register",
+	}
+	for in, want := range tests {
+		if got := String(in); got != want {
+			t.Errorf("String(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestLogger(t *testing.T) {
+	Register("hook-secret-42")
+
+	var lines []string
+	l := Logger(recorder{module: "Main", lines: &lines})
+	l.Infof("Webhook secret is %s", "hook-secret-42")
+	l.Sub("Webhook").Warnf("Signing with hook-secret-42 and %d%%", 100)
+
+	want := []string{
+		"Main INFO Webhook secret is " + Placeholder,
+		"Main/Webhook WARN Signing with " + Placeholder + " and 100%",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("logged %q, want %q", lines, want)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	Register("audit-key-xyz")
+
+	var buf bytes.Buffer
+	l := log.New(Writer(&buf), "", 0)
+	l.Printf("auth with audit-key-xyz failed")
+	if got := buf.String(); got != "auth with "+Placeholder+" failed\n" {
+		t.Errorf("wrote %q", got)
+	}
+}
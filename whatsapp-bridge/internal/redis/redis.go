@@ -0,0 +1,282 @@
+// Package redis is a minimal Redis client used to share coordination state,
+// such as rate limit counters, between bridge instances. It speaks just
+// enough RESP for the commands the bridge needs.
+package redis
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+const dialTimeout = 5 * time.Second
+
+// Reconnect backoff: after a failed dial, commands fail fast until the next
+// attempt is due, waiting twice as long after each failure
+const (
+	minBackoff = time.Second
+	maxBackoff = 30 * time.Second
+)
+
+// ErrUnavailable is returned by commands while a reconnect is backing off
+var ErrUnavailable = errors.New("redis unavailable, waiting to reconnect")
+
+// Client is a single Redis connection, safe for concurrent use. Commands are
+// serialized; a broken connection is redialed by a later command, at most
+// once per backoff interval.
+type Client struct {
+	addr     string
+	username string
+	password string
+	db       int
+	timeout  time.Duration
+	logger   waLog.Logger
+
+	mu      sync.Mutex
+	conn    net.Conn
+	rw      *bufio.ReadWriter
+	backoff time.Duration // 0 while connected
+	retryAt time.Time
+	now     func() time.Time
+}
+
+// Dial connects to the server at a redis://[user:password@]host[:port][/db] URL
+func Dial(rawURL string, logger waLog.Logger) (*Client, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REDIS_URL: %v", err)
+	}
+	if u.Scheme != "redis" {
+		return nil, fmt.Errorf("invalid REDIS_URL: unsupported scheme %q", u.Scheme)
+	}
+
+	c := &Client{addr: u.Host, timeout: dialTimeout, logger: logger, now: time.Now}
+	if u.Port() == "" {
+		c.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		c.password, _ = u.User.Password()
+		if c.password != "" {
+			c.username = u.User.Username()
+		} else {
+			c.password = u.User.Username()
+		}
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if c.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid REDIS_URL: bad database %q", db)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.connect(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// connect dials the server and authenticates. Caller must hold c.mu.
+func (c *Client) connect() error {
+	conn, err := net.DialTimeout("tcp", c.addr, c.timeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to redis: %v", err)
+	}
+	c.conn = conn
+	c.rw = bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+	if c.password != "" {
+		args := []string{"AUTH", c.password}
+		if c.username != "" {
+			args = []string{"AUTH", c.username, c.password}
+		}
+		if _, err := c.roundTrip(args); err != nil {
+			c.close()
+			return fmt.Errorf("redis authentication failed: %v", err)
+		}
+	}
+	if c.db != 0 {
+		if _, err := c.roundTrip([]string{"SELECT", strconv.Itoa(c.db)}); err != nil {
+			c.close()
+			return fmt.Errorf("failed to select redis database: %v", err)
+		}
+	}
+	return nil
+}
+
+// close drops the connection. Caller must hold c.mu.
+func (c *Client) close() {
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.close()
+	return nil
+}
+
+// Do sends a command and returns its reply: string, int64, nil or []interface{}.
+// A Redis error reply is returned as an error.
+func (c *Client) Do(args ...string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		if err := c.reconnect(); err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := c.roundTrip(args)
+	if _, isReplyErr := err.(Error); err != nil && !isReplyErr {
+		// The stream may be out of sync; start over on the next command
+		c.logger.Warnf("Redis connection lost: %v", err)
+		c.close()
+	}
+	return reply, err
+}
+
+// reconnect dials again unless the last attempt failed less than a backoff
+// interval ago. Caller must hold c.mu.
+func (c *Client) reconnect() error {
+	now := c.now()
+	if now.Before(c.retryAt) {
+		return ErrUnavailable
+	}
+	if err := c.connect(); err != nil {
+		c.backoff = min(max(2*c.backoff, minBackoff), maxBackoff)
+		c.retryAt = now.Add(c.backoff)
+		c.logger.Warnf("%v; retrying in %v", err, c.backoff)
+		return err
+	}
+	if c.backoff > 0 {
+		c.logger.Infof("Reconnected to redis at %s", c.addr)
+	}
+	c.backoff, c.retryAt = 0, time.Time{}
+	return nil
+}
+
+// roundTrip writes one command and reads its reply. Caller must hold c.mu.
+func (c *Client) roundTrip(args []string) (interface{}, error) {
+	if err := c.conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(c.rw, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(c.rw, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if err := c.rw.Flush(); err != nil {
+		return nil, fmt.Errorf("redis write failed: %v", err)
+	}
+
+	return readReply(c.rw.Reader)
+}
+
+// Error is an error reply from the server
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// readReply parses one RESP reply
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("redis read failed: %v", err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("redis protocol error: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, Error(line[1:])
+	case ':':
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("redis protocol error: bad integer %q", line)
+		}
+		return n, nil
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis protocol error: bad bulk length %q", line)
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, fmt.Errorf("redis read failed: %v", err)
+		}
+		return string(buf[:size]), nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis protocol error: bad array length %q", line)
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, count)
+		for i := range items {
+			if items[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis protocol error: unexpected reply %q", line)
+	}
+}
+
+// Ping checks that the server is reachable
+func (c *Client) Ping() error {
+	_, err := c.Do("PING")
+	return err
+}
+
+// countWindowScript increments KEYS[1], starting its expiry of ARGV[1]
+// milliseconds on the first increment, and returns it with the count at
+// KEYS[2], all in one atomic step
+const countWindowScript = `local n = redis.call('INCR', KEYS[1])
+if n == 1 then redis.call('PEXPIRE', KEYS[1], ARGV[1]) end
+return {n, tonumber(redis.call('GET', KEYS[2]) or '0')}`
+
+// CountWindow increments the counter for key and returns its new value with
+// the counter at previousKey, or 0 if that does not exist, in one round
+// trip. key expires ttl after its first increment, so callers get
+// fixed-window counters shared by every instance using the same server.
+func (c *Client) CountWindow(key, previousKey string, ttl time.Duration) (current, previous int64, err error) {
+	reply, err := c.Do("EVAL", countWindowScript, "2", key, previousKey, strconv.FormatInt(ttl.Milliseconds(), 10))
+	if err != nil {
+		return 0, 0, err
+	}
+	counts, ok := reply.([]interface{})
+	if !ok || len(counts) != 2 {
+		return 0, 0, fmt.Errorf("redis protocol error: EVAL returned %v", reply)
+	}
+	current, ok1 := counts[0].(int64)
+	previous, ok2 := counts[1].(int64)
+	if !ok1 || !ok2 {
+		return 0, 0, fmt.Errorf("redis protocol error: EVAL returned %v", reply)
+	}
+	return current, previous, nil
+}
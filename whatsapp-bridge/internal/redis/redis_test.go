@@ -0,0 +1,160 @@
+package redis
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+func TestReadReply(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  interface{}
+	}{
+		{"simple string", "+OK\r\n", "OK"},
+		{"integer", ":42\r\n", int64(42)},
+		{"bulk string", "$5\r\nhello\r\n", "hello"},
+		{"nil bulk string", "$-1\r\n", nil},
+		{"array", "*2\r\n:1\r\n$2\r\nhi\r\n", []interface{}{int64(1), "hi"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readReply(bufio.NewReader(strings.NewReader(tt.input)))
+			if err != nil {
+				t.Fatalf("readReply(%q) error: %v", tt.input, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readReply(%q) = %#v, want %#v", tt.input, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := readReply(bufio.NewReader(strings.NewReader("-ERR wrong type\r\n"))); err != Error("ERR wrong type") {
+		t.Errorf("error reply = %v, want ERR wrong type", err)
+	}
+}
+
+// fakeServer answers EVAL of countWindowScript from in-memory counters and
+// records every command
+func fakeServer(t *testing.T) (addr string, commands chan []string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	commands = make(chan []string, 10)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		counters := make(map[string]int)
+		for {
+			reply, err := readReply(r)
+			if err != nil {
+				return
+			}
+			var args []string
+			for _, arg := range reply.([]interface{}) {
+				args = append(args, arg.(string))
+			}
+			commands <- args
+
+			if args[0] == "EVAL" && len(args) == 6 {
+				counters[args[3]]++
+				_, _ = fmt.Fprintf(conn, "*2\r\n:%d\r\n:%d\r\n", counters[args[3]], counters[args[4]])
+			} else {
+				_, _ = conn.Write([]byte(":1\r\n"))
+			}
+		}
+	}()
+
+	return ln.Addr().String(), commands
+}
+
+func TestCountWindow(t *testing.T) {
+	addr, commands := fakeServer(t)
+
+	c, err := Dial("redis://"+addr, waLog.Noop)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer c.Close()
+
+	for want := int64(1); want <= 2; want++ {
+		current, previous, err := c.CountWindow("ratelimit:1.2.3.4:120", "ratelimit:1.2.3.4:60", 2*time.Minute)
+		if err != nil {
+			t.Fatalf("CountWindow: %v", err)
+		}
+		if current != want || previous != 0 {
+			t.Errorf("CountWindow = %d, %d, want %d, 0", current, previous, want)
+		}
+	}
+	if current, previous, err := c.CountWindow("ratelimit:1.2.3.4:180", "ratelimit:1.2.3.4:120", 2*time.Minute); err != nil || current != 1 || previous != 2 {
+		t.Errorf("CountWindow(next window) = %d, %d, %v, want 1, 2", current, previous, err)
+	}
+
+	// One command per count
+	want := []string{"EVAL", countWindowScript, "2", "ratelimit:1.2.3.4:120", "ratelimit:1.2.3.4:60", "120000"}
+	if got := <-commands; !reflect.DeepEqual(got, want) {
+		t.Errorf("command = %v, want %v", got, want)
+	}
+	if got := <-commands; got[0] != "EVAL" {
+		t.Errorf("second command = %v, want EVAL", got)
+	}
+}
+
+func TestDialRejectsBadURL(t *testing.T) {
+	for _, rawURL := range []string{"http://localhost:6379", "redis://localhost:6379/x"} {
+		if _, err := Dial(rawURL, waLog.Noop); err == nil {
+			t.Errorf("Dial(%q) succeeded, want error", rawURL)
+		}
+	}
+}
+
+func TestReconnectBackoff(t *testing.T) {
+	// An address nothing listens on
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	now := time.Now()
+	c := &Client{addr: addr, timeout: time.Second, logger: waLog.Noop, now: func() time.Time { return now }}
+
+	if _, err := c.Do("PING"); err == nil || err == ErrUnavailable {
+		t.Fatalf("first command = %v, want a dial error", err)
+	}
+	// Commands fail fast until the backoff is over
+	if _, err := c.Do("PING"); err != ErrUnavailable {
+		t.Errorf("command during backoff = %v, want ErrUnavailable", err)
+	}
+	now = now.Add(minBackoff)
+	if _, err := c.Do("PING"); err == nil || err == ErrUnavailable {
+		t.Errorf("command after backoff = %v, want a dial error", err)
+	}
+	if c.backoff != 2*minBackoff {
+		t.Errorf("backoff = %v, want %v", c.backoff, 2*minBackoff)
+	}
+	for range 10 {
+		now = now.Add(time.Hour)
+		_, _ = c.Do("PING")
+	}
+	if c.backoff != maxBackoff {
+		t.Errorf("backoff = %v, want capped at %v", c.backoff, maxBackoff)
+	}
+}
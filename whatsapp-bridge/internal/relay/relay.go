@@ -0,0 +1,282 @@
+// Package relay mirrors selected chats between bridge instances running
+// different WhatsApp numbers. Incoming messages in a linked chat are posted
+// to the peer bridge's /api/relay, which sends them into the mapped chat on
+// its own number.
+package relay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	waLog "go.mau.fi/whatsmeow/util/log"
+
+	"whatsapp-bridge/internal/outbox"
+	"whatsapp-bridge/internal/recovery"
+	"whatsapp-bridge/internal/redact"
+	"whatsapp-bridge/internal/retry"
+	localTypes "whatsapp-bridge/internal/types"
+	"whatsapp-bridge/internal/whatsapp"
+)
+
+const (
+	// MaxLinks caps the configured outbound links
+	MaxLinks = 100
+
+	// postTimeout bounds one delivery to a peer bridge
+	postTimeout = 30 * time.Second
+)
+
+// postPolicy retries a delivery the peer could not take, e.g. while it
+// restarts; a refusal such as a wrong API key is not retried
+var postPolicy = retry.Policy{
+	Attempts:   6,
+	Backoff:    2 * time.Second,
+	MaxBackoff: time.Minute,
+	Retryable:  retryablePost,
+}
+
+// ErrNotAccepted is returned for relayed messages aimed at a chat that is not
+// in the inbound chat list
+var ErrNotAccepted = errors.New("chat does not accept relayed messages")
+
+// Relay forwards linked chats to peer bridges and delivers messages relayed
+// from them
+type Relay struct {
+	outbox *outbox.Dispatcher
+	logger waLog.Logger
+	http   *http.Client
+
+	// policy paces retries of failed deliveries
+	policy retry.Policy
+
+	mu     sync.RWMutex
+	config localTypes.RelayConfig
+}
+
+// New creates a relay with no links
+func New(dispatcher *outbox.Dispatcher, logger waLog.Logger) *Relay {
+	return &Relay{
+		outbox: dispatcher,
+		logger: logger,
+		http:   &http.Client{Timeout: postTimeout},
+		policy: postPolicy,
+	}
+}
+
+// ValidateConfig checks JIDs and peer URLs, and that no chat is linked twice
+func ValidateConfig(cfg localTypes.RelayConfig) error {
+	if len(cfg.Links) > MaxLinks {
+		return fmt.Errorf("at most %d links are allowed", MaxLinks)
+	}
+
+	seen := make(map[string]bool)
+	for i, link := range cfg.Links {
+		if _, err := types.ParseJID(link.ChatJID); err != nil || link.ChatJID == "" {
+			return fmt.Errorf("link %d: invalid chat_jid %q", i, link.ChatJID)
+		}
+		if seen[link.ChatJID] {
+			return fmt.Errorf("link %d: chat %s is linked more than once", i, link.ChatJID)
+		}
+		seen[link.ChatJID] = true
+
+		u, err := url.Parse(link.PeerURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("link %d: peer_url must be an http(s) URL", i)
+		}
+		if link.PeerAPIKey == "" {
+			return fmt.Errorf("link %d: peer_api_key is required", i)
+		}
+		if _, err := types.ParseJID(link.PeerChatJID); err != nil || link.PeerChatJID == "" {
+			return fmt.Errorf("link %d: invalid peer_chat_jid %q", i, link.PeerChatJID)
+		}
+	}
+
+	for _, jid := range cfg.InboundChats {
+		if _, err := types.ParseJID(jid); err != nil || jid == "" {
+			return fmt.Errorf("invalid inbound chat %q", jid)
+		}
+	}
+	return nil
+}
+
+// SetConfig validates and applies a new configuration
+func (r *Relay) SetConfig(cfg localTypes.RelayConfig) error {
+	if err := ValidateConfig(cfg); err != nil {
+		return err
+	}
+
+	for _, link := range cfg.Links {
+		redact.Register(link.PeerAPIKey)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.config = cfg
+	return nil
+}
+
+// Config returns the current configuration
+func (r *Relay) Config() localTypes.RelayConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.config
+}
+
+// link returns the enabled link for a chat, if any
+func (r *Relay) link(chatJID string) (localTypes.RelayLink, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, link := range r.config.Links {
+		if link.Enabled && link.ChatJID == chatJID {
+			return link, true
+		}
+	}
+	return localTypes.RelayLink{}, false
+}
+
+// HandleMessage posts an incoming message in a linked chat to its peer in the
+// background, retrying with backoff while the peer is unreachable or failing.
+// The bridge's own messages are never relayed, so two bridges linking the
+// same pair of chats both ways do not echo each other.
+func (r *Relay) HandleMessage(msg *events.Message) {
+	if msg.Info.IsFromMe {
+		return
+	}
+
+	chatJID := msg.Info.Chat.ToNonAD().String()
+	link, ok := r.link(chatJID)
+	if !ok {
+		return
+	}
+
+	content := whatsapp.ExtractTextContent(msg.Message)
+	mediaType, _, _, _, _, _, _ := whatsapp.ExtractMediaInfo(msg.Message)
+	if content == "" && mediaType == "" {
+		return
+	}
+
+	m := localTypes.RelayMessage{
+		ChatJID:       link.PeerChatJID,
+		SourceChatJID: chatJID,
+		Sender:        msg.Info.Sender.ToNonAD().String(),
+		SenderName:    msg.Info.PushName,
+		Content:       content,
+		MediaType:     mediaType,
+		Timestamp:     msg.Info.Timestamp.UTC(),
+	}
+
+	recovery.Go("relay to "+link.PeerURL, func() {
+		attempt := 0
+		err := retry.Do(context.Background(), r.policy, func() error {
+			attempt++
+			ctx, cancel := context.WithTimeout(context.Background(), postTimeout)
+			defer cancel()
+			err := r.post(ctx, link, m)
+			if err != nil && attempt < r.policy.Attempts && retryablePost(err) {
+				r.logger.Infof("Relay to %s failed (attempt %d of %d), retrying: %v", link.PeerURL, attempt, r.policy.Attempts, err)
+			}
+			return err
+		})
+		if err != nil {
+			r.logger.Warnf("Failed to relay message from %s to %s after %d attempts: %v", chatJID, link.PeerURL, attempt, err)
+		}
+	})
+}
+
+// peerError is a delivery the peer bridge answered with an error status
+type peerError struct {
+	status int
+	body   string
+}
+
+func (e *peerError) Error() string {
+	return fmt.Sprintf("peer returned %d: %s", e.status, e.body)
+}
+
+// retryablePost reports whether a failed delivery may succeed later: the
+// peer could not be reached, failed itself or asked to slow down
+func retryablePost(err error) bool {
+	var pe *peerError
+	if errors.As(err, &pe) {
+		return pe.status >= 500 || pe.status == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// post delivers a message to the peer bridge's /api/relay
+func (r *Relay) post(ctx context.Context, link localTypes.RelayLink, m localTypes.RelayMessage) error {
+	body, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(link.PeerURL, "/")+"/api/relay", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", link.PeerAPIKey)
+
+	resp, err := r.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &peerError{status: resp.StatusCode, body: strings.TrimSpace(string(snippet))}
+	}
+	return nil
+}
+
+// Deliver sends a message relayed from a peer into its chat, prefixed with
+// the original sender. People repeat themselves, so duplicate detection is
+// skipped. Relayed sends are not tagged as an automation either: they cannot
+// loop, and a busy mirrored chat would trip the loop breaker.
+func (r *Relay) Deliver(ctx context.Context, m localTypes.RelayMessage) (localTypes.SendResult, error) {
+	if !r.accepts(m.ChatJID) {
+		return localTypes.SendResult{}, ErrNotAccepted
+	}
+	return r.outbox.SendForced(ctx, outbox.PriorityHigh, m.ChatJID, Format(m), "")
+}
+
+func (r *Relay) accepts(chatJID string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, jid := range r.config.InboundChats {
+		if jid == chatJID {
+			return true
+		}
+	}
+	return false
+}
+
+// Format renders a relayed message as text: the sender in bold, then a media
+// placeholder and the text, e.g. "*Ana*: [image] see attached"
+func Format(m localTypes.RelayMessage) string {
+	sender := m.SenderName
+	if sender == "" {
+		sender = strings.SplitN(m.Sender, "@", 2)[0]
+	}
+
+	var body []string
+	if m.MediaType != "" {
+		body = append(body, "["+m.MediaType+"]")
+	}
+	if m.Content != "" {
+		body = append(body, m.Content)
+	}
+	return fmt.Sprintf("*%s*: %s", sender, strings.Join(body, " "))
+}
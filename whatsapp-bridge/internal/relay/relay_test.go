@@ -0,0 +1,180 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	waLog "go.mau.fi/whatsmeow/util/log"
+	"google.golang.org/protobuf/proto"
+
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+func TestValidateConfig(t *testing.T) {
+	valid := localTypes.RelayLink{
+		ChatJID:     "123@g.us",
+		PeerURL:     "https://peer.example.com",
+		PeerAPIKey:  "key",
+		PeerChatJID: "456@g.us",
+		Enabled:     true,
+	}
+	if err := ValidateConfig(localTypes.RelayConfig{Links: []localTypes.RelayLink{valid}, InboundChats: []string{"789@g.us"}}); err != nil {
+		t.Fatalf("valid config rejected: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(l *localTypes.RelayLink)
+	}{
+		{"missing chat", func(l *localTypes.RelayLink) { l.ChatJID = "" }},
+		{"ftp peer", func(l *localTypes.RelayLink) { l.PeerURL = "ftp://peer.example.com" }},
+		{"no host", func(l *localTypes.RelayLink) { l.PeerURL = "https://" }},
+		{"missing key", func(l *localTypes.RelayLink) { l.PeerAPIKey = "" }},
+		{"missing peer chat", func(l *localTypes.RelayLink) { l.PeerChatJID = "" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			link := valid
+			tt.modify(&link)
+			if err := ValidateConfig(localTypes.RelayConfig{Links: []localTypes.RelayLink{link}}); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+
+	if err := ValidateConfig(localTypes.RelayConfig{Links: []localTypes.RelayLink{valid, valid}}); err == nil {
+		t.Error("expected a chat linked twice to be rejected")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		msg  localTypes.RelayMessage
+		want string
+	}{
+		{localTypes.RelayMessage{Sender: "123@s.whatsapp.net", SenderName: "Ana", Content: "hi"}, "*Ana*: hi"},
+		{localTypes.RelayMessage{Sender: "123@s.whatsapp.net", Content: "hi"}, "*123*: hi"},
+		{localTypes.RelayMessage{Sender: "123@s.whatsapp.net", SenderName: "Ana", MediaType: "image", Content: "look"}, "*Ana*: [image] look"},
+		{localTypes.RelayMessage{Sender: "123@s.whatsapp.net", SenderName: "Ana", MediaType: "audio"}, "*Ana*: [audio]"},
+	}
+	for _, tt := range tests {
+		if got := Format(tt.msg); got != tt.want {
+			t.Errorf("Format(%+v) = %q, want %q", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestHandleMessage(t *testing.T) {
+	received := make(chan localTypes.RelayMessage, 1)
+	peer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/relay" || r.Header.Get("X-API-Key") != "key" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		var m localTypes.RelayMessage
+		_ = json.NewDecoder(r.Body).Decode(&m)
+		received <- m
+	}))
+	defer peer.Close()
+
+	r := New(nil, waLog.Noop)
+	if err := r.SetConfig(localTypes.RelayConfig{Links: []localTypes.RelayLink{{
+		ChatJID:     "123@g.us",
+		PeerURL:     peer.URL + "/",
+		PeerAPIKey:  "key",
+		PeerChatJID: "456@g.us",
+		Enabled:     true,
+	}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	message := func(chat string, fromMe bool) *events.Message {
+		evt := &events.Message{Message: &waE2E.Message{Conversation: proto.String("hello")}}
+		evt.Info.Chat = types.NewJID(chat, types.GroupServer)
+		evt.Info.Sender = types.NewJID("111", types.DefaultUserServer)
+		evt.Info.PushName = "Ana"
+		evt.Info.IsFromMe = fromMe
+		return evt
+	}
+
+	// Own messages and unlinked chats are not relayed
+	r.HandleMessage(message("123", true))
+	r.HandleMessage(message("999", false))
+
+	r.HandleMessage(message("123", false))
+	select {
+	case m := <-received:
+		if m.ChatJID != "456@g.us" || m.SourceChatJID != "123@g.us" || m.Content != "hello" || m.SenderName != "Ana" {
+			t.Errorf("relayed %+v", m)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("message was not relayed")
+	}
+
+	select {
+	case m := <-received:
+		t.Errorf("unexpected relay of %+v", m)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestHandleMessageRetries(t *testing.T) {
+	var calls atomic.Int32
+	received := make(chan localTypes.RelayMessage, 1)
+	peer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			http.Error(w, "restarting", http.StatusServiceUnavailable)
+			return
+		case 2:
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+			return
+		}
+		var m localTypes.RelayMessage
+		_ = json.NewDecoder(r.Body).Decode(&m)
+		received <- m
+	}))
+	defer peer.Close()
+
+	r := New(nil, waLog.Noop)
+	r.policy.Backoff, r.policy.MaxBackoff = time.Millisecond, time.Millisecond
+	link := localTypes.RelayLink{ChatJID: "123@g.us", PeerURL: peer.URL, PeerAPIKey: "key", PeerChatJID: "456@g.us", Enabled: true}
+	if err := r.SetConfig(localTypes.RelayConfig{Links: []localTypes.RelayLink{link}}); err != nil {
+		t.Fatal(err)
+	}
+
+	evt := &events.Message{Message: &waE2E.Message{Conversation: proto.String("hello")}}
+	evt.Info.Chat = types.NewJID("123", types.GroupServer)
+	evt.Info.Sender = types.NewJID("111", types.DefaultUserServer)
+	r.HandleMessage(evt)
+
+	select {
+	case m := <-received:
+		if m.Content != "hello" || calls.Load() != 3 {
+			t.Errorf("relayed %+v after %d calls, want hello on the third", m, calls.Load())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("message was not relayed after the peer recovered")
+	}
+
+	// A refusal is final
+	if retryablePost(&peerError{status: http.StatusUnauthorized}) || !retryablePost(&peerError{status: http.StatusBadGateway}) {
+		t.Error("retryablePost retries refusals or gives up on server errors")
+	}
+}
+
+func TestDeliverRefusesUnlistedChat(t *testing.T) {
+	r := New(nil, waLog.Noop)
+	_, err := r.Deliver(context.Background(), localTypes.RelayMessage{ChatJID: "123@g.us", Sender: "1@s.whatsapp.net", Content: "hi"})
+	if err != ErrNotAccepted {
+		t.Errorf("Deliver() error = %v, want ErrNotAccepted", err)
+	}
+}
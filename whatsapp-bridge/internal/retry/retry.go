@@ -0,0 +1,70 @@
+// Package retry re-runs WhatsApp operations that fail transiently, backing
+// off between attempts and waiting longer when the server says to slow down.
+package retry
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+)
+
+// Policy says how often and how patiently to retry one kind of operation
+type Policy struct {
+	// Attempts is the total number of tries, including the first
+	Attempts int
+	// Backoff is the wait before the second try; it doubles after each retry
+	Backoff time.Duration
+	// MaxBackoff caps the doubled backoff (0 means no cap)
+	MaxBackoff time.Duration
+	// RateLimitWait replaces the backoff after a rate limit error
+	RateLimitWait time.Duration
+	// Retryable reports whether an error is worth another try; nil retries all
+	Retryable func(err error) bool
+}
+
+// IsRateLimited reports whether err is WhatsApp asking the client to slow down
+func IsRateLimited(err error) bool {
+	var iqErr *whatsmeow.IQError
+	return errors.Is(err, whatsmeow.ErrIQRateOverLimit) || (errors.As(err, &iqErr) && iqErr.Code == 429)
+}
+
+// Do runs fn until it succeeds, returns an error the policy does not retry, or
+// runs out of attempts. It stops waiting when ctx ends, returning the last
+// error from fn.
+func Do(ctx context.Context, p Policy, fn func() error) error {
+	backoff := p.Backoff
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if attempt >= p.Attempts || (p.Retryable != nil && !p.Retryable(err)) {
+			return err
+		}
+
+		wait := backoff
+		if IsRateLimited(err) && p.RateLimitWait > wait {
+			wait = p.RateLimitWait
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			// The next try could not finish in time anyway
+			return err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+
+		backoff *= 2
+		if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+	}
+}
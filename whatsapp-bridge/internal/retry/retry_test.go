@@ -0,0 +1,91 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+)
+
+func TestDo(t *testing.T) {
+	errTransient := errors.New("transient")
+	errPermanent := errors.New("permanent")
+	policy := Policy{
+		Attempts:  3,
+		Backoff:   time.Millisecond,
+		Retryable: func(err error) bool { return err != errPermanent },
+	}
+
+	tests := []struct {
+		name      string
+		errs      []error // returned by successive calls; nil after the list ends
+		wantErr   error
+		wantCalls int
+	}{
+		{"succeeds first time", nil, nil, 1},
+		{"succeeds after retries", []error{errTransient, errTransient}, nil, 3},
+		{"gives up after attempts", []error{errTransient, errTransient, errTransient, errTransient}, errTransient, 3},
+		{"does not retry permanent errors", []error{errPermanent}, errPermanent, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := Do(context.Background(), policy, func() error {
+				calls++
+				if calls <= len(tt.errs) {
+					return tt.errs[calls-1]
+				}
+				return nil
+			})
+			if err != tt.wantErr {
+				t.Errorf("Do() error = %v, want %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("fn called %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestDoStopsAtDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// The rate limit wait cannot fit before the deadline, so there is no retry
+	calls := 0
+	start := time.Now()
+	err := Do(ctx, Policy{Attempts: 3, Backoff: time.Millisecond, RateLimitWait: time.Minute}, func() error {
+		calls++
+		return whatsmeow.ErrIQRateOverLimit
+	})
+	if !errors.Is(err, whatsmeow.ErrIQRateOverLimit) {
+		t.Errorf("Do() error = %v, want rate limit error", err)
+	}
+	if calls != 1 {
+		t.Errorf("fn called %d times, want 1", calls)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Do() took %v, want an immediate return", elapsed)
+	}
+}
+
+func TestIsRateLimited(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{whatsmeow.ErrIQRateOverLimit, true},
+		{fmt.Errorf("failed to get group info: %w", &whatsmeow.IQError{Code: 429}), true},
+		{&whatsmeow.IQError{Code: 500}, false},
+		{errors.New("rate of something"), false},
+	}
+	for _, tt := range tests {
+		if got := IsRateLimited(tt.err); got != tt.want {
+			t.Errorf("IsRateLimited(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
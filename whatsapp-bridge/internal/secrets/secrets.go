@@ -0,0 +1,146 @@
+// Package secrets lets credentials be given as references to a secret store
+// instead of as plaintext in the environment or the database. A value such
+// as "vault://secret/data/bridge#api_key" is fetched from the store on first
+// use, cached and refreshed periodically, so a rotated secret is picked up
+// without a restart. Values that are not references are used as they are.
+//
+// Supported references:
+//   - vault://<path>#<field>: HashiCorp Vault, read from VAULT_ADDR with
+//     VAULT_TOKEN (and VAULT_NAMESPACE if set). path is the API path, e.g.
+//     secret/data/bridge for a KV v2 mount; KV v1 paths work too.
+//   - awssm://<secret id or ARN>[#field]: AWS Secrets Manager, signed with
+//     the standard AWS_* credentials and region. With a field, the secret
+//     string is read as JSON and the field taken from it.
+//   - file://<path>: a file's contents without trailing newlines, e.g. a
+//     Docker or Kubernetes secret mount; only read from the environment,
+//     never accepted through the API.
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"whatsapp-bridge/internal/redact"
+)
+
+// fetchTimeout bounds one fetch from a secret store
+const fetchTimeout = 10 * time.Second
+
+// fetcher reads the secret a reference (without its scheme) points to
+type fetcher func(ctx context.Context, ref string) (string, error)
+
+var fetchers = map[string]fetcher{
+	"vault://": fetchVault,
+	"awssm://": fetchAWS,
+	"file://":  fetchFile,
+}
+
+var cache = struct {
+	sync.RWMutex
+	values map[string]string
+}{values: make(map[string]string)}
+
+// IsReference reports whether value points to a secret store rather than
+// being the secret itself
+func IsReference(value string) bool {
+	for scheme := range fetchers {
+		if strings.HasPrefix(value, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFile reports whether value refers to a file on the bridge's host
+func IsFile(value string) bool {
+	return strings.HasPrefix(value, "file://")
+}
+
+// Resolve returns the secret value refers to, or value itself when it is
+// not a reference. Secrets are cached after the first fetch; fetched values
+// are registered for redaction from logs.
+func Resolve(value string) (string, error) {
+	if !IsReference(value) {
+		return value, nil
+	}
+
+	cache.RLock()
+	secret, ok := cache.values[value]
+	cache.RUnlock()
+	if ok {
+		return secret, nil
+	}
+
+	secret, err := fetch(value)
+	if err != nil {
+		return "", err
+	}
+	cache.Lock()
+	cache.values[value] = secret
+	cache.Unlock()
+	return secret, nil
+}
+
+// Refresh fetches every cached secret again. A secret that cannot be
+// fetched keeps its previous value; the failures are returned together.
+func Refresh() error {
+	cache.RLock()
+	refs := make([]string, 0, len(cache.values))
+	for ref := range cache.values {
+		refs = append(refs, ref)
+	}
+	cache.RUnlock()
+
+	var errs []error
+	for _, ref := range refs {
+		secret, err := fetch(ref)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		cache.Lock()
+		cache.values[ref] = secret
+		cache.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+func fetch(ref string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+
+	for scheme, f := range fetchers {
+		if path, ok := strings.CutPrefix(ref, scheme); ok {
+			secret, err := f(ctx, path)
+			if err != nil {
+				return "", fmt.Errorf("failed to fetch secret %s: %w", ref, err)
+			}
+			if secret == "" {
+				return "", fmt.Errorf("secret %s is empty", ref)
+			}
+			redact.Register(secret)
+			return secret, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported secret reference %s", ref)
+}
+
+// fetchFile reads a secret mounted as a file
+func fetchFile(_ context.Context, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// splitField splits "path#field" into its parts
+func splitField(ref string) (string, string) {
+	path, field, _ := strings.Cut(ref, "#")
+	return path, field
+}
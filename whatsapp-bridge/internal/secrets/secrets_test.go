@@ -0,0 +1,137 @@
+package secrets
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolvePlainValue(t *testing.T) {
+	if got, err := Resolve("plain-key"); err != nil || got != "plain-key" {
+		t.Errorf("Resolve(plain) = %q, %v", got, err)
+	}
+	if IsReference("https://example.com") || !IsReference("vault://secret/data/x#k") {
+		t.Error("IsReference misread a value")
+	}
+}
+
+func TestResolveFileAndRefresh(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api_key")
+	if err := os.WriteFile(path, []byte("first-secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	ref := "file://" + path
+
+	if got, err := Resolve(ref); err != nil || got != "first-secret" {
+		t.Fatalf("Resolve = %q, %v", got, err)
+	}
+
+	// Cached until refreshed
+	os.WriteFile(path, []byte("rotated-secret"), 0600)
+	if got, _ := Resolve(ref); got != "first-secret" {
+		t.Errorf("Resolve before refresh = %q, want the cached value", got)
+	}
+	if err := Refresh(); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if got, _ := Resolve(ref); got != "rotated-secret" {
+		t.Errorf("Resolve after refresh = %q, want the rotated value", got)
+	}
+
+	// A failed refresh keeps the last value
+	os.Remove(path)
+	if err := Refresh(); err == nil {
+		t.Error("Refresh of a missing file succeeded")
+	}
+	if got, _ := Resolve(ref); got != "rotated-secret" {
+		t.Errorf("Resolve after failed refresh = %q", got)
+	}
+
+	if _, err := Resolve("file://" + filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Resolve of a missing file succeeded")
+	}
+}
+
+func TestFetchVault(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/bridge":
+			w.Write([]byte(`{"data":{"data":{"api_key":"kv2-secret"},"metadata":{"version":3}}}`))
+		case "/v1/kv/bridge":
+			w.Write([]byte(`{"data":{"api_key":"kv1-secret","port":8080}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("VAULT_ADDR", server.URL)
+	t.Setenv("VAULT_TOKEN", "root")
+
+	for ref, want := range map[string]string{"secret/data/bridge#api_key": "kv2-secret", "kv/bridge#api_key": "kv1-secret"} {
+		if got, err := fetchVault(t.Context(), ref); err != nil || got != want {
+			t.Errorf("fetchVault(%s) = %q, %v; want %q", ref, got, err, want)
+		}
+	}
+	for _, ref := range []string{"kv/bridge#missing", "kv/bridge#port", "kv/other#api_key", "kv/bridge"} {
+		if _, err := fetchVault(t.Context(), ref); err == nil {
+			t.Errorf("fetchVault(%s) succeeded", ref)
+		}
+	}
+}
+
+func TestFetchAWS(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(auth, "/eu-west-1/secretsmanager/aws4_request") ||
+			r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var req struct{ SecretId string }
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.SecretId != "bridge/prod" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"ResourceNotFoundException"}`))
+			return
+		}
+		w.Write([]byte(`{"SecretString":"{\"api_key\":\"aws-secret\"}"}`))
+	}))
+	defer server.Close()
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "SECRET")
+	t.Setenv("AWS_REGION", "eu-west-1")
+	t.Setenv("AWS_ENDPOINT_URL_SECRETS_MANAGER", server.URL)
+
+	if got, err := fetchAWS(t.Context(), "bridge/prod#api_key"); err != nil || got != "aws-secret" {
+		t.Errorf("fetchAWS field = %q, %v", got, err)
+	}
+	if got, err := fetchAWS(t.Context(), "bridge/prod"); err != nil || got != `{"api_key":"aws-secret"}` {
+		t.Errorf("fetchAWS whole = %q, %v", got, err)
+	}
+	if _, err := fetchAWS(t.Context(), "bridge/other"); err == nil || !strings.Contains(err.Error(), "ResourceNotFoundException") {
+		t.Errorf("fetchAWS missing = %v", err)
+	}
+}
+
+// TestSignAWS checks the signer against the get-vanilla case of the AWS
+// Signature Version 4 test suite
+func TestSignAWS(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	creds := awsCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	signAWS(req, nil, creds, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization = %s\nwant %s", got, want)
+	}
+}
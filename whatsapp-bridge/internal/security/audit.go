@@ -6,6 +6,8 @@ import (
 	"log"
 	"os"
 	"time"
+
+	"whatsapp-bridge/internal/redact"
 )
 
 // AuditLogger logs security-relevant events
@@ -34,7 +36,7 @@ func init() {
 // NewAuditLogger creates a new audit logger
 func NewAuditLogger() *AuditLogger {
 	return &AuditLogger{
-		logger: log.New(os.Stdout, "[AUDIT] ", log.LstdFlags),
+		logger: log.New(redact.Writer(os.Stdout), "[AUDIT] ", log.LstdFlags),
 	}
 }
 
@@ -133,3 +135,19 @@ func LogMessageSent(recipient, messageType string) {
 		Status:    "success",
 	})
 }
+
+// LogChatCommand logs a command an admin sent over WhatsApp. target is the
+// chat the command acted on, if any; reason explains a failure.
+func LogChatCommand(sender, command, target, status, reason string) {
+	details := "sender=" + sender
+	if reason != "" {
+		details += "; " + reason
+	}
+	defaultAuditLogger.Log(AuditEvent{
+		EventType: "chat_command",
+		Resource:  target,
+		Action:    command,
+		Status:    status,
+		Details:   details,
+	})
+}
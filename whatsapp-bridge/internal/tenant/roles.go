@@ -0,0 +1,71 @@
+package tenant
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Roles an API key can have. Each role may do everything the roles before it
+// may.
+const (
+	// RoleReader reads messages, chats, receipts and other stored data
+	RoleReader = "reader"
+	// RoleSender also sends messages, reactions, pins, polls and votes
+	RoleSender = "sender"
+	// RoleOperator also manages its own webhooks and routing, groups and
+	// channel subscriptions; it is what tenant keys could always do
+	RoleOperator = "operator"
+	// RoleAdmin also changes account-wide settings, pairs the device and uses
+	// the admin endpoints. Only the primary key, which acts as the operator
+	// over every tenant, is admin.
+	RoleAdmin = "admin"
+)
+
+var roleRank = map[string]int{RoleReader: 1, RoleSender: 2, RoleOperator: 3, RoleAdmin: 4}
+
+// Allows reports whether a key with role may do what required needs
+func Allows(role, required string) bool {
+	return roleRank[required] > 0 && roleRank[role] >= roleRank[required]
+}
+
+// ParseRoles reads comma-separated name=role pairs giving named API keys a
+// role. A pair naming an unknown role, or admin, is an error; the key gets
+// the reader role rather than more than it was meant to have.
+func ParseRoles(spec string) (map[string]string, error) {
+	roles := make(map[string]string)
+	var bad []string
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, role, _ := strings.Cut(pair, "=")
+		name, role = strings.TrimSpace(name), strings.ToLower(strings.TrimSpace(role))
+		if name == "" {
+			bad = append(bad, pair)
+			continue
+		}
+		if roleRank[role] == 0 || role == RoleAdmin {
+			bad = append(bad, pair)
+			role = RoleReader
+		}
+		roles[name] = role
+	}
+	if len(bad) > 0 {
+		return roles, fmt.Errorf("invalid role assignment(s) %s; roles are %s, %s and %s (admin is the primary key's alone)",
+			strings.Join(bad, ", "), RoleReader, RoleSender, RoleOperator)
+	}
+	return roles, nil
+}
+
+// RoleOf returns the role of the API key named name: admin for the primary
+// key, else the role given in roles, else operator
+func RoleOf(name string, roles map[string]string) string {
+	if IsOperator(name) {
+		return RoleAdmin
+	}
+	if role, ok := roles[name]; ok {
+		return role
+	}
+	return RoleOperator
+}
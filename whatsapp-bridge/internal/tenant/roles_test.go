@@ -0,0 +1,33 @@
+package tenant
+
+import "testing"
+
+func TestParseRoles(t *testing.T) {
+	roles, err := ParseRoles(" dashboard=reader, crm = Sender ,ops=operator,")
+	if err != nil || roles["dashboard"] != RoleReader || roles["crm"] != RoleSender || roles["ops"] != RoleOperator {
+		t.Errorf("ParseRoles = %v, %v", roles, err)
+	}
+
+	// Mistakes fall back to the least role, not the default
+	roles, err = ParseRoles("dashboard=viewer,crm=admin,=reader")
+	if err == nil || roles["dashboard"] != RoleReader || roles["crm"] != RoleReader {
+		t.Errorf("ParseRoles with mistakes = %v, %v", roles, err)
+	}
+}
+
+func TestRoleOf(t *testing.T) {
+	roles := map[string]string{"dashboard": RoleReader, Default: RoleReader}
+	if got := RoleOf(Default, roles); got != RoleAdmin {
+		t.Errorf("primary key role = %s, want admin", got)
+	}
+	if got := RoleOf("dashboard", roles); got != RoleReader {
+		t.Errorf("dashboard role = %s", got)
+	}
+	if got := RoleOf("crm", roles); got != RoleOperator {
+		t.Errorf("unlisted key role = %s, want operator", got)
+	}
+
+	if !Allows(RoleOperator, RoleSender) || Allows(RoleReader, RoleSender) || !Allows(RoleAdmin, RoleAdmin) || Allows(RoleAdmin, "bogus") {
+		t.Error("Allows misorders the roles")
+	}
+}
@@ -0,0 +1,44 @@
+// Package tenant identifies which API key (tenant) created a webhook, routing
+// profile or sent message, so departments sharing one WhatsApp account only
+// see their own configuration and sends.
+package tenant
+
+import "context"
+
+// Default is the tenant of the primary API_KEY. It acts as the operator and
+// can see every tenant's resources; it is also used when auth is disabled.
+const Default = "default"
+
+type contextKey struct{}
+
+// WithName returns a context carrying the tenant name
+func WithName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, contextKey{}, name)
+}
+
+// FromContext returns the tenant carried by ctx, or Default
+func FromContext(ctx context.Context) string {
+	if name, ok := ctx.Value(contextKey{}).(string); ok && name != "" {
+		return name
+	}
+	return Default
+}
+
+// IsOperator reports whether a tenant has operator rights
+func IsOperator(name string) bool {
+	return name == Default
+}
+
+// Owner returns the tenant owning a resource; resources created before
+// tenants existed belong to Default
+func Owner(name string) string {
+	if name == "" {
+		return Default
+	}
+	return name
+}
+
+// Visible reports whether viewer may see a resource owned by owner
+func Visible(viewer, owner string) bool {
+	return IsOperator(viewer) || viewer == Owner(owner)
+}
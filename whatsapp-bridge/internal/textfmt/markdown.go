@@ -0,0 +1,87 @@
+// Package textfmt handles WhatsApp text formatting. Outgoing basic Markdown is
+// rewritten with WhatsApp's formatting characters, text over the message
+// length limit is split into numbered parts, and the formatting of stored
+// messages is located as spans.
+package textfmt
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	heading    = regexp.MustCompile(`^#{1,6}\s+(.+?)\s*#*\s*$`)
+	bullet     = regexp.MustCompile(`^(\s*)[-*+]\s+`)
+	rule       = regexp.MustCompile(`^\s*(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+	link       = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	boldStars  = regexp.MustCompile(`\*\*([^*\s](?:[^*]*[^*\s])?)\*\*`)
+	boldUnders = regexp.MustCompile(`__([^_\s](?:[^_]*[^_\s])?)__`)
+	strike     = regexp.MustCompile(`~~([^~\s](?:[^~]*[^~\s])?)~~`)
+	italicStar = regexp.MustCompile(`\*([^*\s](?:[^*]*[^*\s])?)\*`)
+)
+
+// boldMark stands in for converted bold until italics are done, so the
+// single asterisk WhatsApp uses for bold is not read as Markdown italic
+const boldMark = "\x00"
+
+// MarkdownToWhatsApp rewrites basic Markdown with WhatsApp formatting:
+//
+//	**bold**, __bold__, # Heading  ->  *bold*
+//	*italic*, _italic_             ->  _italic_
+//	~~strike~~                     ->  ~strike~
+//	- item, * item, + item         ->  • item
+//	[text](url)                    ->  text (url)
+//
+// Inline `code` and ``` blocks are already WhatsApp syntax and are left
+// untouched, as is everything inside them.
+func MarkdownToWhatsApp(text string) string {
+	lines := strings.Split(text, "\n")
+	inBlock := false
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inBlock = !inBlock
+			continue
+		}
+		if inBlock {
+			continue
+		}
+		lines[i] = convertLine(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func convertLine(line string) string {
+	if rule.MatchString(line) {
+		return "──────────"
+	}
+	if m := heading.FindStringSubmatch(line); m != nil {
+		return "*" + strings.Trim(convertInline(m[1]), "*") + "*"
+	}
+	if m := bullet.FindStringSubmatch(line); m != nil {
+		return m[1] + "• " + convertInline(line[len(m[0]):])
+	}
+	return convertInline(line)
+}
+
+// convertInline converts the text outside `code` spans
+func convertInline(s string) string {
+	parts := strings.Split(s, "`")
+	// An odd number of backticks leaves the last one unmatched; treat the
+	// text after it as ordinary text
+	for i := 0; i < len(parts); i += 2 {
+		parts[i] = convertSpan(parts[i])
+	}
+	if len(parts)%2 == 0 {
+		parts[len(parts)-1] = convertSpan(parts[len(parts)-1])
+	}
+	return strings.Join(parts, "`")
+}
+
+func convertSpan(s string) string {
+	s = link.ReplaceAllString(s, "$1 ($2)")
+	s = boldStars.ReplaceAllString(s, boldMark+"$1"+boldMark)
+	s = boldUnders.ReplaceAllString(s, boldMark+"$1"+boldMark)
+	s = strike.ReplaceAllString(s, "~$1~")
+	s = italicStar.ReplaceAllString(s, "_${1}_")
+	return strings.ReplaceAll(s, boldMark, "*")
+}
@@ -0,0 +1,109 @@
+package textfmt
+
+import (
+	"sort"
+	"unicode"
+
+	"whatsapp-bridge/internal/types"
+)
+
+// inlineStyles are the single-character markers WhatsApp formats with
+var inlineStyles = []struct {
+	marker rune
+	style  string
+}{
+	{'*', types.StyleBold},
+	{'_', types.StyleItalic},
+	{'~', types.StyleStrikethrough},
+}
+
+// Spans finds the WhatsApp formatting in text, following the app's rules:
+// a marker opens at the start of a word and closes at the end of one on the
+// same line, nothing is formatted inside `code` or ```blocks```, and styles
+// may nest. Spans are ordered by where they start.
+func Spans(text string) []types.TextSpan {
+	runes := []rune(text)
+	code := make([]bool, len(runes)) // inside a code span or block, markers included
+	var spans []types.TextSpan
+
+	for i := 0; i+6 <= len(runes); i++ {
+		if !hasFence(runes, i) {
+			continue
+		}
+		for j := i + 4; j+3 <= len(runes); j++ {
+			if hasFence(runes, j) {
+				spans = append(spans, types.TextSpan{Style: types.StyleMonospace, Start: i + 3, End: j})
+				mark(code, i, j+3)
+				i = j + 2
+				break
+			}
+		}
+	}
+
+	inline := find(runes, code, '`', types.StyleCode, false)
+	for _, s := range inline {
+		mark(code, s.Start-1, s.End+1)
+	}
+	spans = append(spans, inline...)
+
+	for _, st := range inlineStyles {
+		spans = append(spans, find(runes, code, st.marker, st.style, true)...)
+	}
+
+	sort.SliceStable(spans, func(a, b int) bool {
+		if spans[a].Start != spans[b].Start {
+			return spans[a].Start < spans[b].Start
+		}
+		return spans[a].End > spans[b].End
+	})
+	return spans
+}
+
+// find returns the spans between pairs of marker on one line. Word
+// boundaries are only required of styles, not of `code`.
+func find(runes []rune, code []bool, marker rune, style string, words bool) []types.TextSpan {
+	var spans []types.TextSpan
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != marker || code[i] || !opens(runes, i, words) {
+			continue
+		}
+		for j := i + 1; j < len(runes) && runes[j] != '\n' && !code[j]; j++ {
+			if runes[j] == marker && closes(runes, j, words) {
+				if j > i+1 {
+					spans = append(spans, types.TextSpan{Style: style, Start: i + 1, End: j})
+					i = j
+				}
+				break
+			}
+		}
+	}
+	return spans
+}
+
+func opens(runes []rune, i int, words bool) bool {
+	if i+1 >= len(runes) || unicode.IsSpace(runes[i+1]) || runes[i+1] == runes[i] {
+		return false
+	}
+	return !words || i == 0 || !isWordRune(runes[i-1])
+}
+
+func closes(runes []rune, j int, words bool) bool {
+	if unicode.IsSpace(runes[j-1]) {
+		return false
+	}
+	return !words || j+1 == len(runes) || !isWordRune(runes[j+1])
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func hasFence(runes []rune, i int) bool {
+	return i+3 <= len(runes) && runes[i] == '`' && runes[i+1] == '`' && runes[i+2] == '`'
+}
+
+func mark(code []bool, from, to int) {
+	for k := from; k < to; k++ {
+		code[k] = true
+	}
+}
@@ -0,0 +1,72 @@
+package textfmt
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Split breaks text into parts of at most limit bytes, each prefixed with
+// its number, e.g. "(2/3) ". Parts end at a paragraph break, line break or
+// space where possible, and never inside a UTF-8 character. Text within the
+// limit is returned as the only part, without a number.
+func Split(text string, limit int) []string {
+	if len(text) <= limit {
+		return []string{text}
+	}
+
+	// The label's length depends on the number of parts, so settle that first
+	n := 1
+	for {
+		chunks := chunk(text, limit-len(label(n, n)))
+		if len(chunks) <= n {
+			parts := make([]string, len(chunks))
+			for i, c := range chunks {
+				parts[i] = label(i+1, len(chunks)) + c
+			}
+			return parts
+		}
+		n = len(chunks)
+	}
+}
+
+func label(i, n int) string {
+	return fmt.Sprintf("(%d/%d) ", i, n)
+}
+
+// chunk cuts text into pieces of at most size bytes
+func chunk(text string, size int) []string {
+	var chunks []string
+	for len(text) > size {
+		cut := breakPoint(text, size)
+		if c := strings.TrimRight(text[:cut], " \n"); c != "" {
+			chunks = append(chunks, c)
+		}
+		text = strings.TrimLeft(text[cut:], " \n")
+	}
+	if text != "" {
+		chunks = append(chunks, text)
+	}
+	return chunks
+}
+
+// breakPoint returns where to end a part of text that must fit in size
+// bytes: after the last paragraph break, line break or space in the second
+// half of the part, or failing that before the character that overflows
+func breakPoint(text string, size int) int {
+	window := text[:size]
+	for _, sep := range []string{"\n\n", "\n", " "} {
+		if i := strings.LastIndex(window, sep); i >= size/2 {
+			return i + len(sep)
+		}
+	}
+
+	cut := size
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	if cut == 0 {
+		return size
+	}
+	return cut
+}
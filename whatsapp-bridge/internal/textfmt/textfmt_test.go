@@ -0,0 +1,108 @@
+package textfmt
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"whatsapp-bridge/internal/types"
+)
+
+func TestMarkdownToWhatsApp(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"**bold** and __bold__", "*bold* and *bold*"},
+		{"*italic* and _italic_", "_italic_ and _italic_"},
+		{"***both***", "_*both*_"},
+		{"~~gone~~", "~gone~"},
+		{"use `**raw**` here", "use `**raw**` here"},
+		{"# Release notes", "*Release notes*"},
+		{"## **Already bold** ##", "*Already bold*"},
+		{"- one\n* two\n  + nested", "• one\n• two\n  • nested"},
+		{"1. first", "1. first"},
+		{"see [the docs](https://example.com/docs)", "see the docs (https://example.com/docs)"},
+		{"2 * 3 * 4", "2 * 3 * 4"},
+		{"snake_case_name", "snake_case_name"},
+		{"```\n**not converted**\n- nor this\n```\n**converted**", "```\n**not converted**\n- nor this\n```\n*converted*"},
+		{"---", "──────────"},
+	}
+
+	for _, tt := range tests {
+		if got := MarkdownToWhatsApp(tt.in); got != tt.want {
+			t.Errorf("MarkdownToWhatsApp(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSplitShort(t *testing.T) {
+	parts := Split("hello", 100)
+	if len(parts) != 1 || parts[0] != "hello" {
+		t.Errorf("Split = %q, want the text unchanged", parts)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	paragraph := strings.Repeat("word ", 30) // 150 bytes
+	text := strings.TrimSpace(strings.Repeat(paragraph+"\n\n", 8))
+
+	parts := Split(text, 200)
+	for i, part := range parts {
+		if len(part) > 200 {
+			t.Errorf("part %d is %d bytes", i+1, len(part))
+		}
+		if want := label(i+1, len(parts)); !strings.HasPrefix(part, want) {
+			t.Errorf("part %d = %q, want prefix %q", i+1, part[:10], want)
+		}
+		if strings.HasSuffix(part, " ") || strings.Contains(strings.TrimPrefix(part, label(i+1, len(parts))), "wor\n") {
+			t.Errorf("part %d was not cut at a boundary: %q", i+1, part)
+		}
+	}
+
+	var rebuilt []string
+	for i, part := range parts {
+		rebuilt = append(rebuilt, strings.TrimPrefix(part, label(i+1, len(parts))))
+	}
+	if got := strings.Join(strings.Fields(strings.Join(rebuilt, " ")), " "); got != strings.Join(strings.Fields(text), " ") {
+		t.Error("parts do not add up to the text")
+	}
+}
+
+func TestSplitUnbrokenText(t *testing.T) {
+	text := strings.Repeat("é", 100) // 200 bytes, no spaces
+
+	parts := Split(text, 50)
+	for i, part := range parts {
+		if len(part) > 50 || !utf8.ValidString(part) {
+			t.Errorf("part %d = %q (%d bytes)", i+1, part, len(part))
+		}
+	}
+	if len(parts) < 5 {
+		t.Errorf("got %d parts, want at least 5", len(parts))
+	}
+}
+
+func TestSpans(t *testing.T) {
+	span := func(style string, start, end int) types.TextSpan {
+		return types.TextSpan{Style: style, Start: start, End: end}
+	}
+	tests := []struct {
+		in   string
+		want []types.TextSpan
+	}{
+		{"plain text", nil},
+		{"*bold* _it_ ~no~", []types.TextSpan{span(types.StyleBold, 1, 5), span(types.StyleItalic, 8, 10), span(types.StyleStrikethrough, 13, 15)}},
+		{"*_both_*", []types.TextSpan{span(types.StyleBold, 1, 7), span(types.StyleItalic, 2, 6)}},
+		{"2 * 3 * 4", nil},
+		{"snake_case_name", nil},
+		{"*not\nclosed*", nil},
+		{"é *gras*", []types.TextSpan{span(types.StyleBold, 3, 7)}},
+		{"run `*x*` now", []types.TextSpan{span(types.StyleCode, 5, 8)}},
+		{"```\n*raw*\n``` *b*", []types.TextSpan{span(types.StyleMonospace, 3, 10), span(types.StyleBold, 15, 16)}},
+	}
+
+	for _, tt := range tests {
+		if got := Spans(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Spans(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
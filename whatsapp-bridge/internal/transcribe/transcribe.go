@@ -0,0 +1,105 @@
+// Package transcribe is a client for OpenAI-compatible speech-to-text
+// endpoints (POST /v1/audio/transcriptions), such as the OpenAI Whisper API
+// or a local whisper server, used to transcribe incoming voice notes.
+package transcribe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultModel is sent when no model is configured
+const DefaultModel = "whisper-1"
+
+// maxErrorBody bounds how much of a failed response is quoted in the error
+const maxErrorBody = 512
+
+// Client sends audio to a transcription endpoint
+type Client struct {
+	url        string
+	apiKey     string
+	model      string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for the transcription endpoint at url. apiKey
+// may be empty for local servers without authentication.
+func NewClient(url, apiKey, model string) *Client {
+	if model == "" {
+		model = DefaultModel
+	}
+	return &Client{
+		url:    url,
+		apiKey: apiKey,
+		model:  model,
+		httpClient: &http.Client{
+			Timeout: 2 * time.Minute,
+		},
+	}
+}
+
+// Annotate transcribes an audio file and returns the text
+func (c *Client) Annotate(ctx context.Context, data []byte, mimetype string) (string, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	_ = form.WriteField("model", c.model)
+	_ = form.WriteField("response_format", "json")
+	part, err := form.CreateFormFile("file", "voice"+extension(mimetype))
+	if err != nil {
+		return "", err
+	}
+	if _, err := part.Write(data); err != nil {
+		return "", err
+	}
+	if err := form.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, &body)
+	if err != nil {
+		return "", fmt.Errorf("invalid transcription URL: %v", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("transcription request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return "", fmt.Errorf("transcription failed: HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var result struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("invalid transcription response: %v", err)
+	}
+	return strings.TrimSpace(result.Text), nil
+}
+
+// extension picks a file name extension the server can use to detect the
+// audio format; WhatsApp voice notes are Ogg Opus
+func extension(mimetype string) string {
+	switch {
+	case strings.HasPrefix(mimetype, "audio/mpeg"):
+		return ".mp3"
+	case strings.HasPrefix(mimetype, "audio/mp4"), strings.HasPrefix(mimetype, "audio/aac"):
+		return ".m4a"
+	default:
+		return ".ogg"
+	}
+}
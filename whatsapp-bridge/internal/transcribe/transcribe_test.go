@@ -0,0 +1,51 @@
+package transcribe
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAnnotate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization = %q", got)
+		}
+		if got := r.FormValue("model"); got != DefaultModel {
+			t.Errorf("model = %q, want %q", got, DefaultModel)
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			t.Fatalf("missing file: %v", err)
+		}
+		data, _ := io.ReadAll(file)
+		if string(data) != "OggS" || header.Filename != "voice.ogg" {
+			t.Errorf("file = %q (%s)", data, header.Filename)
+		}
+		_, _ = w.Write([]byte(`{"text": " hello there \n"}`))
+	}))
+	defer srv.Close()
+
+	text, err := NewClient(srv.URL, "secret", "").Annotate(context.Background(), []byte("OggS"), "audio/ogg; codecs=opus")
+	if err != nil {
+		t.Fatalf("Annotate: %v", err)
+	}
+	if text != "hello there" {
+		t.Errorf("text = %q", text)
+	}
+}
+
+func TestAnnotateError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "model not loaded", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	_, err := NewClient(srv.URL, "", "base").Annotate(context.Background(), []byte("OggS"), "audio/ogg")
+	if err == nil || !strings.Contains(err.Error(), "503") || !strings.Contains(err.Error(), "model not loaded") {
+		t.Errorf("err = %v, want HTTP 503 with body", err)
+	}
+}
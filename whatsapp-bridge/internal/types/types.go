@@ -15,6 +15,163 @@ type Message struct {
 	Filename   string
 }
 
+// ChatReadState is the unread state of one chat, seeded from the phone's
+// history sync and kept current from incoming messages and read markers
+type ChatReadState struct {
+	ChatJID           string     `json:"chat_jid"`
+	UnreadCount       int        `json:"unread_count"`
+	UnreadMentions    int        `json:"unread_mentions"`
+	MarkedUnread      bool       `json:"marked_unread"` // manually marked unread on the phone
+	LastReadMessageID string     `json:"last_read_message_id,omitempty"`
+	LastReadAt        *time.Time `json:"last_read_at,omitempty"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// StoredMessage is one archived message as returned by /api/messages/{ref}
+type StoredMessage struct {
+	Ref          string    `json:"ref"` // opaque URL-safe reference, see msgref
+	ID           string    `json:"id"`
+	ChatJID      string    `json:"chat_jid"`
+	Sender       string    `json:"sender"`
+	SenderName   string    `json:"sender_name"`
+	Content      string    `json:"content"`
+	Timestamp    time.Time `json:"timestamp"`
+	IsFromMe     bool      `json:"is_from_me"`
+	MediaType    string    `json:"media_type,omitempty"`
+	Filename     string    `json:"filename,omitempty"`
+	MetadataOnly bool      `json:"metadata_only"` // content was not stored
+	Kept         bool      `json:"kept"`          // kept in a disappearing chat, so it does not disappear
+	Edited       bool      `json:"edited"`        // content is the latest edit; earlier versions are in the edit history
+	Revoked      bool      `json:"revoked"`       // deleted for everyone by its sender or a group admin
+
+	EditedAt  *time.Time `json:"edited_at,omitempty"` // time of the latest edit
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+
+	// Mentions, formatting and the quoted message, when the message had any
+	Context *MessageContext `json:"context,omitempty"`
+
+	// Text extracted from the media by enrichment hooks, keyed by kind
+	// ("transcript" for voice notes, "ocr" for images)
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// MessageQuery selects stored messages. Zero fields do not filter.
+type MessageQuery struct {
+	ChatJID        string
+	Sender         string // JID or user part
+	MediaType      string // "text" for messages without media
+	IsFromMe       *bool
+	Since          time.Time
+	Until          time.Time
+	IncludeArchive bool  // also read archived months overlapping Since..Until
+	AfterSeq       int64 // only messages stored after the one with this sequence; oldest stored first when Order is empty
+	Limit          int
+	Offset         int
+	Order          ListOrder // newest first when empty
+}
+
+// ListOrder sorts a list by one of the fields the list allows sorting by
+type ListOrder struct {
+	Field string // JSON name of the field; empty for the list's default order
+	Desc  bool
+}
+
+// MessageSearch is a full-text search over stored message text. Zero
+// filter fields do not filter.
+type MessageSearch struct {
+	Query   string // SQLite FTS syntax, e.g. "invoice OR receipt"
+	ChatJID string
+	Sender  string // JID or user part
+	Since   time.Time
+	Until   time.Time
+	Order   string // "relevance" (default) or "newest"
+	Limit   int
+	Offset  int
+}
+
+// MessageSearchResult is a stored message matching a search
+type MessageSearchResult struct {
+	*StoredMessage
+	Snippet string  `json:"snippet"` // matched terms wrapped in [ ]
+	Score   float64 `json:"score"`   // relevance, higher is better
+}
+
+// ChatSummary is one chat in the chat list, as returned by /api/chats
+type ChatSummary struct {
+	JID             string       `json:"jid"`
+	Name            string       `json:"name"`
+	IsGroup         bool         `json:"is_group"`
+	LastMessageTime *time.Time   `json:"last_message_time,omitempty"`
+	MessageCount    int          `json:"message_count"` // stored messages
+	LastMessage     *ChatPreview `json:"last_message,omitempty"`
+}
+
+// ChatPreview is a chat's newest stored message, its text cut short
+type ChatPreview struct {
+	Ref        string    `json:"ref"`
+	ID         string    `json:"id"`
+	Sender     string    `json:"sender"`
+	SenderName string    `json:"sender_name"`
+	Content    string    `json:"content"`
+	Timestamp  time.Time `json:"timestamp"`
+	IsFromMe   bool      `json:"is_from_me"`
+	MediaType  string    `json:"media_type,omitempty"`
+}
+
+// MessageContext is the structure WhatsApp sends alongside a message's text,
+// kept so the message can be shown as it was rather than as bare text
+type MessageContext struct {
+	Mentions   []string       `json:"mentions,omitempty"` // mentioned user JIDs
+	Formatting []TextSpan     `json:"formatting,omitempty"`
+	Quoted     *QuotedMessage `json:"quoted,omitempty"`
+}
+
+// Formatting styles of a TextSpan
+const (
+	StyleBold          = "bold"
+	StyleItalic        = "italic"
+	StyleStrikethrough = "strikethrough"
+	StyleCode          = "code"      // `inline`
+	StyleMonospace     = "monospace" // ```block```
+)
+
+// TextSpan is a formatted run of a message's content. Start and End are
+// offsets in Unicode code points and cover the text between the markers.
+type TextSpan struct {
+	Style string `json:"style"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// QuotedMessage identifies the message a reply quotes
+type QuotedMessage struct {
+	ID      string `json:"id"`
+	Sender  string `json:"sender,omitempty"` // JID of the quoted message's author
+	ChatJID string `json:"chat_jid"`
+}
+
+// AnnotationMatch is a message annotation found by /api/annotations/search
+type AnnotationMatch struct {
+	Ref       string    `json:"ref"` // for /api/messages/{ref}
+	ChatJID   string    `json:"chat_jid"`
+	MessageID string    `json:"message_id"`
+	Kind      string    `json:"kind"`
+	Text      string    `json:"text"`
+	Snippet   string    `json:"snippet"` // matched terms wrapped in [ ]
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MessageAnnotation is text extracted from a stored message's media, such
+// as a voice note transcript, raised as a message_annotated event
+type MessageAnnotation struct {
+	ChatJID   string
+	MessageID string
+	Sender    string
+	Timestamp time.Time // when the message was sent
+	Kind      string
+	Text      string
+}
+
 // WebhookConfig represents a webhook configuration
 type WebhookConfig struct {
 	ID          int              `json:"id"`
@@ -25,6 +182,29 @@ type WebhookConfig struct {
 	CreatedAt   time.Time        `json:"created_at"`
 	UpdatedAt   time.Time        `json:"updated_at"`
 	Triggers    []WebhookTrigger `json:"triggers"`
+	Tenant      string           `json:"tenant,omitempty"` // API key name that owns the webhook
+
+	// FilterExpression is an optional boolean expression that must hold for
+	// the webhook to fire, e.g. `chat.is_group && contains(content, "invoice")`
+	FilterExpression string `json:"filter_expression,omitempty"`
+
+	// PayloadVersion is the payload schema the webhook receives, so receivers
+	// can move to a new schema when they are ready. 0 means version 1.
+	PayloadVersion int `json:"payload_version,omitempty"`
+
+	// ContentType is how the payload is sent: "application/json" (default),
+	// "application/x-www-form-urlencoded" as flat fields, or "text/plain"
+	// rendered from BodyTemplate
+	ContentType  string `json:"content_type,omitempty"`
+	BodyTemplate string `json:"body_template,omitempty"`
+
+	// Size limits for receivers with strict body limits. Message content
+	// over MaxContentLength bytes is cut. A payload over MaxPayloadBytes is
+	// sent without its content, for the receiver to fetch by message ref,
+	// or dropped when OversizeAction is "drop". 0 means no limit.
+	MaxContentLength int    `json:"max_content_length,omitempty"`
+	MaxPayloadBytes  int    `json:"max_payload_bytes,omitempty"`
+	OversizeAction   string `json:"oversize_action,omitempty"` // "reference" (default) or "drop"
 }
 
 // WebhookConfigResponse is the API response format with masked secret
@@ -38,6 +218,15 @@ type WebhookConfigResponse struct {
 	CreatedAt  time.Time        `json:"created_at"`
 	UpdatedAt  time.Time        `json:"updated_at"`
 	Triggers   []WebhookTrigger `json:"triggers"`
+	Tenant     string           `json:"tenant,omitempty"`
+
+	FilterExpression string `json:"filter_expression,omitempty"`
+	PayloadVersion   int    `json:"payload_version"`
+	ContentType      string `json:"content_type,omitempty"`
+	BodyTemplate     string `json:"body_template,omitempty"`
+	MaxContentLength int    `json:"max_content_length,omitempty"`
+	MaxPayloadBytes  int    `json:"max_payload_bytes,omitempty"`
+	OversizeAction   string `json:"oversize_action,omitempty"`
 }
 
 // MaskSecret returns a masked version of a secret token
@@ -63,6 +252,15 @@ func (c *WebhookConfig) ToResponse() WebhookConfigResponse {
 		CreatedAt:  c.CreatedAt,
 		UpdatedAt:  c.UpdatedAt,
 		Triggers:   c.Triggers,
+		Tenant:     c.Tenant,
+
+		FilterExpression: c.FilterExpression,
+		PayloadVersion:   c.PayloadVersion,
+		ContentType:      c.ContentType,
+		BodyTemplate:     c.BodyTemplate,
+		MaxContentLength: c.MaxContentLength,
+		MaxPayloadBytes:  c.MaxPayloadBytes,
+		OversizeAction:   c.OversizeAction,
 	}
 }
 
@@ -70,14 +268,19 @@ func (c *WebhookConfig) ToResponse() WebhookConfigResponse {
 type WebhookTrigger struct {
 	ID              int    `json:"id"`
 	WebhookConfigID int    `json:"webhook_config_id"`
-	TriggerType     string `json:"trigger_type"` // chat_jid, sender, keyword, media_type, all
+	TriggerType     string `json:"trigger_type"` // chat_jid, sender, keyword, media_type, all (expression is synthetic)
 	TriggerValue    string `json:"trigger_value"`
 	MatchType       string `json:"match_type"` // exact, contains, regex
 	Enabled         bool   `json:"enabled"`
+
+	// How often the trigger made its webhook fire, and when it last did
+	MatchCount    int64      `json:"match_count"`
+	LastMatchedAt *time.Time `json:"last_matched_at,omitempty"`
 }
 
 // WebhookPayload represents the standardized payload structure for webhook notifications
 type WebhookPayload struct {
+	SchemaVersion int                `json:"schema_version"` // payload schema, see WebhookConfig.PayloadVersion
 	EventType     string             `json:"event_type"`
 	Timestamp     string             `json:"timestamp"`
 	WebhookConfig WebhookConfigInfo  `json:"webhook_config"`
@@ -86,6 +289,41 @@ type WebhookPayload struct {
 	Metadata      WebhookMetadata    `json:"metadata"`
 }
 
+// WebhookTestRequest is an optional sample message for a webhook test, so
+// the receiver gets a payload like the ones its triggers would send
+type WebhookTestRequest struct {
+	ChatJID    string `json:"chat_jid"`
+	ChatName   string `json:"chat_name,omitempty"`
+	Sender     string `json:"sender,omitempty"` // JID or phone number; defaults to the chat in direct chats
+	SenderName string `json:"sender_name,omitempty"`
+	Content    string `json:"content,omitempty"`
+	MediaType  string `json:"media_type,omitempty"` // e.g. "image", "audio", "document"
+}
+
+// WebhookDryRun explains whether a webhook would fire for a sample message
+type WebhookDryRun struct {
+	Fires      bool                      `json:"fires"` // enabled, routed, expression holds and a trigger matched
+	Enabled    bool                      `json:"enabled"`
+	Routed     bool                      `json:"routed"` // routing profiles let the webhook fire for the chat
+	Expression *WebhookExpressionResult  `json:"expression,omitempty"`
+	Trigger    *WebhookTrigger           `json:"trigger,omitempty"` // the trigger that fires
+	Triggers   []WebhookTriggerDiagnosis `json:"triggers"`
+}
+
+// WebhookExpressionResult is how a webhook's filter expression evaluated
+type WebhookExpressionResult struct {
+	Expression string `json:"expression"`
+	Result     bool   `json:"result"`
+	Error      string `json:"error,omitempty"`
+}
+
+// WebhookTriggerDiagnosis says whether one trigger matches a sample and why
+type WebhookTriggerDiagnosis struct {
+	Trigger WebhookTrigger `json:"trigger"`
+	Matched bool           `json:"matched"`
+	Reason  string         `json:"reason"`
+}
+
 type WebhookConfigInfo struct {
 	ID   int    `json:"id"`
 	Name string `json:"name"`
@@ -99,6 +337,7 @@ type WebhookTriggerInfo struct {
 
 type WebhookMessageInfo struct {
 	ID               string `json:"id"`
+	Ref              string `json:"ref"` // for /api/messages/{ref}
 	ChatJID          string `json:"chat_jid"`
 	ChatName         string `json:"chat_name"`
 	Sender           string `json:"sender"`
@@ -110,12 +349,52 @@ type WebhookMessageInfo struct {
 	MediaType        string `json:"media_type"`
 	Filename         string `json:"filename"`
 	MediaDownloadURL string `json:"media_download_url"`
+
+	// Set when the webhook's size limits cut the content or left it out;
+	// ContentLength is then the full content's length in bytes
+	ContentTruncated bool `json:"content_truncated,omitempty"`
+	ContentOmitted   bool `json:"content_omitted,omitempty"`
+	ContentLength    int  `json:"content_length,omitempty"`
+
+	// Enrichment results such as a voice note transcript or text read from
+	// an image, keyed by kind
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Schema version 2 and later only
+	Context  *MessageContext  `json:"context,omitempty"`  // mentions, formatting and the quoted message
+	Reaction *MessageReaction `json:"reaction,omitempty"` // the message is a reaction to another
+	Receipt  *WebhookReceipt  `json:"receipt,omitempty"`  // acknowledgment state of a message we sent
+}
+
+// WebhookReceipt is how far a message we sent has got, see OutgoingMessage
+type WebhookReceipt struct {
+	Status    string `json:"status"`
+	UpdatedAt string `json:"updated_at"`
 }
 
 type WebhookMetadata struct {
-	GroupInfo        *GroupInfo `json:"group_info,omitempty"`
-	DeliveryAttempt  int        `json:"delivery_attempt"`
-	ProcessingTimeMs int64      `json:"processing_time_ms"`
+	GroupInfo        *GroupInfo       `json:"group_info,omitempty"`
+	DeliveryAttempt  int              `json:"delivery_attempt"`
+	DeliveryID       int64            `json:"delivery_id,omitempty"` // the same for every attempt, and after a restart
+	ProcessingTimeMs int64            `json:"processing_time_ms"`
+	SendError        string           `json:"send_error,omitempty"` // send_failed events only
+	Tenant           string           `json:"tenant,omitempty"`     // message_sent and send_failed: API key name that sent the message
+	Origin           string           `json:"origin,omitempty"`     // message_sent and send_failed: automation that sent it
+	Commerce         *CommerceMessage `json:"commerce,omitempty"`   // order_received events only
+
+	Restriction *AccountRestriction `json:"restriction,omitempty"` // account_restricted events only
+
+	Quality *ConnectionQuality `json:"quality,omitempty"` // event_lag_high events only
+
+	Disk *DiskStatus `json:"disk,omitempty"` // disk_space_low events only
+
+	Pairing *PairingCode `json:"pairing,omitempty"` // pairing_code_generated events only
+
+	Pin *PinnedMessage `json:"pin,omitempty"` // message_pinned and message_unpinned events only
+
+	GroupChange *GroupChange `json:"group_change,omitempty"` // group_*_changed events only
+
+	Rate *MessageRate `json:"rate,omitempty"` // message_rate triggers only: the rate that was exceeded
 }
 
 type GroupInfo struct {
@@ -124,6 +403,43 @@ type GroupInfo struct {
 	ParticipantCount int    `json:"participant_count"`
 }
 
+// OutboxJob is a send waiting in the outbox, kept until it is sent or fails
+// for good so a restart does not lose it
+type OutboxJob struct {
+	ID        int64     `json:"id"`
+	Priority  string    `json:"priority"`
+	Kind      string    `json:"kind"` // what is sent: "message", "contacts", "product", "catalog", "sticker_pack", "poll", "poll_vote", "reaction", "newsletter_reaction" or "pin"
+	Recipient string    `json:"recipient"`
+	Tenant    string    `json:"tenant"`
+	Payload   string    `json:"-"` // JSON of what is sent, by kind
+	Attempts  int       `json:"attempts"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookDispatch is a received message whose webhooks have not been matched
+// yet. It is stored with the message and removed once its deliveries are
+// queued, so a restart in between still sends them.
+type WebhookDispatch struct {
+	ID        int64     `json:"id"`
+	ChatJID   string    `json:"chat_jid"`
+	MessageID string    `json:"message_id"`
+	ChatName  string    `json:"chat_name"`
+	Event     []byte    `json:"-"` // the encoded message event
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookDelivery is a payload queued for a webhook, kept until it is
+// delivered or given up on
+type WebhookDelivery struct {
+	ID              int64          `json:"id"`
+	WebhookConfigID int            `json:"webhook_config_id"`
+	ChatJID         string         `json:"chat_jid"`
+	MessageID       string         `json:"message_id"`
+	Trigger         WebhookTrigger `json:"trigger"`
+	Payload         WebhookPayload `json:"payload"`
+	CreatedAt       time.Time      `json:"created_at"`
+}
+
 // WebhookLog represents a webhook delivery log entry
 type WebhookLog struct {
 	ID              int        `json:"id"`
@@ -140,28 +456,370 @@ type WebhookLog struct {
 	CreatedAt       time.Time  `json:"created_at"`
 }
 
+// Webhook log status classes, by the receiver's HTTP response
+const (
+	WebhookStatus2xx     = "2xx"
+	WebhookStatus3xx     = "3xx"
+	WebhookStatus4xx     = "4xx"
+	WebhookStatus5xx     = "5xx"
+	WebhookStatusNoReply = "none" // the request failed before any response
+)
+
+// WebhookLogFilter selects webhook delivery logs. Zero fields do not filter.
+type WebhookLogFilter struct {
+	WebhookConfigID int
+	Tenant          string // only webhooks owned by this tenant
+	ChatJID         string
+	StatusClass     string // one of the WebhookStatus classes
+	FailedOnly      bool
+	Since           time.Time
+	Until           time.Time
+	Limit           int
+	Offset          int
+	Order           ListOrder // newest first when empty
+}
+
+// WebhookLogSummary counts every log a filter selects, not only one page
+type WebhookLogSummary struct {
+	Total     int `json:"total"`
+	Delivered int `json:"delivered"`
+	Failed    int `json:"failed"`
+}
+
+// RoutingProfile is a named bundle of webhooks attached to specific chats or
+// chat tags. Webhooks that belong to a profile only fire for chats the profile
+// is attached to; an exclusive profile additionally stops every webhook outside
+// it from firing for those chats.
+type RoutingProfile struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Exclusive   bool      `json:"exclusive"`
+	Enabled     bool      `json:"enabled"`
+	WebhookIDs  []int     `json:"webhook_ids"`
+	ChatJIDs    []string  `json:"chat_jids"`
+	Tags        []string  `json:"tags"`
+	Tenant      string    `json:"tenant,omitempty"` // API key name that owns the profile
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ChatTagsRequest represents the request body for tagging a chat
+type ChatTagsRequest struct {
+	ChatJID string   `json:"chat_jid"`
+	Tags    []string `json:"tags"` // replaces existing tags; empty clears them
+}
+
 // SendMessageRequest represents the request body for the send message API
 type SendMessageRequest struct {
-	Recipient string `json:"recipient"`
-	Message   string `json:"message"`
-	MediaPath string `json:"media_path,omitempty"`
+	Recipient string   `json:"recipient"`
+	Message   string   `json:"message"`
+	MediaPath string   `json:"media_path,omitempty"`
+	Priority  string   `json:"priority,omitempty"` // "high" (default) or "low" for bulk sends
+	Force     bool     `json:"force,omitempty"`    // skip the duplicate send check
+	Origin    string   `json:"origin,omitempty"`   // automation (bot or rule) making the send, for loop detection
+	Format    string   `json:"format,omitempty"`   // FormatMarkdown converts Markdown to WhatsApp formatting
+	Split     bool     `json:"split,omitempty"`    // send text over the length limit as numbered parts
+	Mentions  []string `json:"mentions,omitempty"` // users to @-mention, by JID or phone number
+
+	GeneratePreview bool `json:"generate_preview,omitempty"` // text only: preview the first link with the page's title, description and image
+}
+
+// FormatMarkdown marks message text written in Markdown
+const FormatMarkdown = "markdown"
+
+// NormalizeRequest asks for phone numbers to be converted to JIDs
+type NormalizeRequest struct {
+	Numbers []string `json:"numbers"`
+	Region  string   `json:"region,omitempty"` // ISO 3166-1 alpha-2 code national numbers are read in
+}
+
+// NormalizedNumber is the result for one input; Error is set instead of the
+// other fields when the input is not a usable phone number
+type NormalizedNumber struct {
+	Input       string `json:"input"`
+	Valid       bool   `json:"valid"`
+	E164        string `json:"e164,omitempty"`
+	JID         string `json:"jid,omitempty"`
+	CountryCode string `json:"country_code,omitempty"`
+	Region      string `json:"region,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// OutboxStats reports the state of the outgoing send lanes
+type OutboxStats struct {
+	Capacity int                        `json:"capacity"` // per lane
+	InFlight int                        `json:"in_flight"`
+	Delayed  int                        `json:"delayed"` // waiting for a retry or a rate limit
+	Lanes    map[string]OutboxLaneStats `json:"lanes"`
+	Rate     SendRateConfig             `json:"rate"`
+}
+
+// OutboxLaneStats reports one priority lane of the outbox
+type OutboxLaneStats struct {
+	Depth   int    `json:"depth"`
+	Sent    uint64 `json:"sent"`
+	Failed  uint64 `json:"failed"`
+	Retried uint64 `json:"retried"`
 }
 
 // SendMessageResponse represents the response for the send message API
 type SendMessageResponse struct {
-	Success   bool      `json:"success"`
-	Message   string    `json:"message,omitempty"`
-	MessageID string    `json:"message_id,omitempty"`
-	Timestamp time.Time `json:"timestamp,omitempty"`
-	Recipient string    `json:"recipient,omitempty"`
+	Success    bool      `json:"success"`
+	Message    string    `json:"message,omitempty"`
+	MessageID  string    `json:"message_id,omitempty"`
+	MessageRef string    `json:"message_ref,omitempty"` // for /api/messages/{ref}
+	Timestamp  time.Time `json:"timestamp,omitempty"`
+	Recipient  string    `json:"recipient,omitempty"`
+	Status     string    `json:"status,omitempty"`      // acknowledgment status, see OutgoingMessage
+	ErrorCode  string    `json:"error_code,omitempty"`  // e.g. "not_on_whatsapp", "timeout"
+	Retryable  bool      `json:"retryable,omitempty"`   // true if the same request may succeed later
+	Duplicate  bool      `json:"duplicate,omitempty"`   // repeat of a recent send, sent because the duplicate action is flag
+	MessageIDs []string  `json:"message_ids,omitempty"` // every part sent, in order, when the message was split
 }
 
 // SendResult contains the result of sending a message (internal use)
 type SendResult struct {
-	Success   bool
-	Error     string
-	MessageID string
-	Timestamp time.Time
+	Success    bool
+	Error      string
+	Code       string // classified failure, see whatsapp.SendErr* constants
+	Retryable  bool
+	MessageID  string
+	MessageRef string // opaque reference to the stored message, see msgref
+	Status     string // acknowledgment status once the message was handed to WhatsApp
+	Timestamp  time.Time
+	Duplicate  bool     // repeat of a send made within the duplicate window
+	PartIDs    []string // message IDs of the parts sent when /api/send split the text
+}
+
+// OutgoingMessage tracks the acknowledgment status of a message sent by the bridge.
+// Status moves forward through pending, server_ack, delivered and read, or
+// becomes failed when sending errors or the server ack never arrives.
+type OutgoingMessage struct {
+	MessageID string    `json:"message_id"`
+	ChatJID   string    `json:"chat_jid"`
+	Content   string    `json:"content,omitempty"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Tenant    string    `json:"tenant,omitempty"` // API key name that sent the message
+	Origin    string    `json:"origin,omitempty"` // automation that sent it; empty for manual sends
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MessageReceipt is when one recipient of a message we sent got it, read it
+// and, for voice notes and videos, played it
+type MessageReceipt struct {
+	RecipientJID string     `json:"recipient_jid"`
+	DeliveredAt  *time.Time `json:"delivered_at,omitempty"`
+	ReadAt       *time.Time `json:"read_at,omitempty"`
+	PlayedAt     *time.Time `json:"played_at,omitempty"`
+}
+
+// MessageStatus is how far a message we sent got: status is the furthest any
+// recipient took it (delivered, read or played), or the send status while no
+// receipt has come
+type MessageStatus struct {
+	ChatJID    string           `json:"chat_jid"`
+	MessageID  string           `json:"message_id"`
+	Status     string           `json:"status"`
+	SendStatus string           `json:"send_status,omitempty"` // for messages sent through the API
+	Recipients []MessageReceipt `json:"recipients"`
+}
+
+// MessageRate is how many messages a chat or sender sent within a recent window
+type MessageRate struct {
+	Scope     string  `json:"scope"` // "chat" or "sender"
+	JID       string  `json:"jid"`
+	Count     int     `json:"count"`
+	Window    string  `json:"window"` // e.g. "5m"
+	PerMinute float64 `json:"per_minute"`
+}
+
+// LocationPoint is one position from a live location share
+type LocationPoint struct {
+	ChatJID        string    `json:"chat_jid"`
+	SenderJID      string    `json:"sender_jid"`
+	MessageID      string    `json:"message_id"`
+	Latitude       float64   `json:"latitude"`
+	Longitude      float64   `json:"longitude"`
+	AccuracyMeters int       `json:"accuracy_meters,omitempty"`
+	SpeedMps       float64   `json:"speed_mps,omitempty"`
+	Heading        int       `json:"heading,omitempty"` // degrees clockwise from magnetic north
+	Caption        string    `json:"caption,omitempty"`
+	Sequence       int64     `json:"sequence"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// Commerce message kinds
+const (
+	CommerceOrder           = "order"
+	CommerceProduct         = "product"
+	CommercePaymentRequest  = "payment_request"
+	CommercePaymentSent     = "payment_sent"
+	CommercePaymentInvite   = "payment_invite"
+	CommercePaymentDeclined = "payment_declined"
+	CommercePaymentCanceled = "payment_canceled"
+)
+
+// CommerceMessage is an order, catalog or payment message captured from a chat.
+// Amounts are in thousandths of the currency unit, as WhatsApp sends them.
+type CommerceMessage struct {
+	MessageID   string         `json:"message_id"`
+	ChatJID     string         `json:"chat_jid"`
+	SenderJID   string         `json:"sender_jid"`
+	IsFromMe    bool           `json:"is_from_me"`
+	Kind        string         `json:"kind"`
+	ReferenceID string         `json:"reference_id,omitempty"` // order ID, product ID or referenced request message ID
+	Title       string         `json:"title,omitempty"`
+	Status      string         `json:"status,omitempty"`
+	Note        string         `json:"note,omitempty"`
+	SellerJID   string         `json:"seller_jid,omitempty"`
+	ItemCount   int            `json:"item_count,omitempty"`
+	Items       []CommerceItem `json:"items,omitempty"`
+	Amount1000  int64          `json:"amount_1000,omitempty"`
+	Currency    string         `json:"currency,omitempty"`
+	Timestamp   time.Time      `json:"timestamp"`
+}
+
+// CommerceItem is one product line in a commerce message
+type CommerceItem struct {
+	ProductID  string `json:"product_id,omitempty"`
+	RetailerID string `json:"retailer_id,omitempty"`
+	Title      string `json:"title,omitempty"`
+	Quantity   int    `json:"quantity"`
+	Price1000  int64  `json:"price_1000,omitempty"`
+	Currency   string `json:"currency,omitempty"`
+}
+
+// CatalogProduct is a product listed in a business catalog.
+// Prices are in thousandths of the currency unit.
+type CatalogProduct struct {
+	ID           string `json:"id"`
+	RetailerID   string `json:"retailer_id,omitempty"`
+	Name         string `json:"name"`
+	Description  string `json:"description,omitempty"`
+	URL          string `json:"url,omitempty"`
+	Price1000    int64  `json:"price_1000"`
+	Currency     string `json:"currency"`
+	ImageURL     string `json:"image_url,omitempty"`
+	Hidden       bool   `json:"hidden"`
+	ReviewStatus string `json:"review_status,omitempty"`
+}
+
+// Catalog is one page of a business catalog
+type Catalog struct {
+	OwnerJID   string           `json:"owner_jid"`
+	Products   []CatalogProduct `json:"products"`
+	NextCursor string           `json:"next_cursor,omitempty"` // pass as cursor for the next page
+}
+
+// SendProductRequest represents the request body for sending a product message
+type SendProductRequest struct {
+	Recipient string `json:"recipient"`
+	ProductID string `json:"product_id"`
+	Body      string `json:"body,omitempty"`
+	Footer    string `json:"footer,omitempty"`
+}
+
+// SendContactRequest represents the request body for sending contact cards:
+// one card given inline, or several in Contacts
+type SendContactRequest struct {
+	Recipient string `json:"recipient"`
+	ContactCard
+	Contacts []ContactCard `json:"contacts,omitempty"`
+}
+
+// ContactCard is a contact to share: a name and phone number, or a raw vCard
+type ContactCard struct {
+	Name  string `json:"name,omitempty"`  // display name; read from the vCard's FN when omitted
+	Phone string `json:"phone,omitempty"` // phone number in any format /api/normalize reads
+	VCard string `json:"vcard,omitempty"` // sent as is instead of one built from name and phone
+}
+
+// SendCatalogRequest represents the request body for sending a catalog message
+type SendCatalogRequest struct {
+	Recipient string `json:"recipient"`
+	Message   string `json:"message,omitempty"` // text shown above the catalog link
+}
+
+// StickerPack is a sticker pack seen in a chat. The download fields locate
+// the encrypted pack archive on WhatsApp's media servers.
+type StickerPack struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Publisher   string            `json:"publisher"`
+	Description string            `json:"description,omitempty"`
+	Stickers    []StickerPackItem `json:"stickers"`
+	ChatJID     string            `json:"chat_jid"`   // chat it was first seen in
+	MessageID   string            `json:"message_id"` // message it was first seen in
+	SenderJID   string            `json:"sender_jid"`
+	FileLength  uint64            `json:"file_length"`
+	SeenAt      time.Time         `json:"seen_at"`
+
+	DirectPath    string `json:"-"`
+	MediaKey      []byte `json:"-"`
+	FileSHA256    []byte `json:"-"`
+	FileEncSHA256 []byte `json:"-"`
+}
+
+// CalendarEvent is an event planned in a chat with WhatsApp's events
+// feature. Edits replace its details; a canceled event is kept with
+// Canceled set.
+type CalendarEvent struct {
+	ChatJID     string     `json:"chat_jid"`
+	ChatName    string     `json:"chat_name,omitempty"`
+	MessageID   string     `json:"message_id"` // message that created the event
+	CreatorJID  string     `json:"creator_jid"`
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Location    string     `json:"location,omitempty"` // place name and address
+	Latitude    float64    `json:"latitude,omitempty"`
+	Longitude   float64    `json:"longitude,omitempty"`
+	JoinLink    string     `json:"join_link,omitempty"` // call link for online events
+	StartTime   time.Time  `json:"start_time"`
+	EndTime     *time.Time `json:"end_time,omitempty"`
+	Canceled    bool       `json:"canceled"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"` // time of the last edit
+}
+
+// StickerPackItem is one sticker in a pack archive
+type StickerPackItem struct {
+	FileName   string   `json:"file_name"`
+	Emojis     []string `json:"emojis,omitempty"`
+	IsAnimated bool     `json:"is_animated"`
+	Mimetype   string   `json:"mimetype,omitempty"`
+}
+
+// SendStickerPackRequest represents the request body for sending a sticker
+// pack built from a directory of images
+type SendStickerPackRequest struct {
+	Recipient   string `json:"recipient"`
+	Directory   string `json:"directory"` // images to convert; must be under an allowed media directory
+	Name        string `json:"name"`
+	Publisher   string `json:"publisher,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// UsageQuota is a monthly request allowance for one API key and usage
+// category. Past Soft requests still succeed with a warning; past Hard they
+// are rejected. Zero means unlimited.
+type UsageQuota struct {
+	Soft int64 `json:"soft,omitempty"`
+	Hard int64 `json:"hard,omitempty"`
+}
+
+// UsageQuotas maps API key name to category (send, read, webhook_test) to quota
+type UsageQuotas map[string]map[string]UsageQuota
+
+// UsageCounter is one API key's request count for a category in a month
+type UsageCounter struct {
+	KeyName  string     `json:"key_name"`
+	Period   string     `json:"period"` // YYYY-MM, UTC
+	Category string     `json:"category"`
+	Count    int64      `json:"count"`
+	Quota    UsageQuota `json:"quota"`
 }
 
 // ReactionRequest represents the request body for sending reactions
@@ -198,12 +856,31 @@ type MarkReadRequest struct {
 type CreateGroupRequest struct {
 	Name         string   `json:"name"`
 	Participants []string `json:"participants"` // JIDs of participants to add
+
+	// Initial settings, applied as part of creation
+	Disappearing     string `json:"disappearing,omitempty"`      // "off", "24h", "7d", "90d"
+	AnnounceOnly     bool   `json:"announce_only,omitempty"`     // only admins can send messages
+	ApprovalRequired bool   `json:"approval_required,omitempty"` // admins must approve new members
+	Description      string `json:"description,omitempty"`
 }
 
 // GroupParticipantsRequest represents the request body for adding/removing group members
 type GroupParticipantsRequest struct {
 	GroupJID     string   `json:"group_jid"`
 	Participants []string `json:"participants"` // JIDs of participants
+
+	// InviteFallback DMs the group invite link to users whose privacy settings block adding them (add only)
+	InviteFallback bool `json:"invite_fallback,omitempty"`
+}
+
+// ParticipantAddResult reports the outcome of adding one participant to a group
+type ParticipantAddResult struct {
+	JID         string `json:"jid"`
+	Added       bool   `json:"added"`
+	ErrorCode   int    `json:"error_code,omitempty"` // WhatsApp participant error code
+	Reason      string `json:"reason,omitempty"`     // e.g. "privacy_restricted", "already_member"
+	Resolution  string `json:"resolution"`           // "added", "invite_sent", "invite_failed", "none"
+	InviteError string `json:"invite_error,omitempty"`
 }
 
 // GroupAdminRequest represents the request body for promoting/demoting admins
@@ -234,6 +911,48 @@ type CreatePollRequest struct {
 	MultiSelect bool     `json:"multi_select"`
 }
 
+// PollVoteRequest represents the request body for voting in a poll
+type PollVoteRequest struct {
+	ChatJID   string `json:"chat_jid"`
+	MessageID string `json:"message_id"`
+	Options   []int  `json:"options"` // indices into the poll's options; empty takes the vote back
+}
+
+// Poll is a poll created in a chat, by us or anyone in it
+type Poll struct {
+	ChatJID         string    `json:"chat_jid"`
+	MessageID       string    `json:"message_id"`
+	CreatorJID      string    `json:"creator_jid"`
+	Question        string    `json:"question"`
+	Options         []string  `json:"options"`
+	SelectableCount int       `json:"selectable_count"` // 0 means any number of options
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// PollVote is a voter's latest choice in a poll. Options is empty once the
+// vote is taken back.
+type PollVote struct {
+	ChatJID  string    `json:"chat_jid"`
+	PollID   string    `json:"poll_id"`
+	VoterJID string    `json:"voter_jid"`
+	Options  []string  `json:"options"`
+	VotedAt  time.Time `json:"voted_at"`
+}
+
+// PollResults tallies a poll's votes option by option, in the poll's order
+type PollResults struct {
+	Poll
+	Voters  int                `json:"voters"` // everyone with a vote standing
+	Results []PollOptionResult `json:"results"`
+}
+
+// PollOptionResult is the votes for one poll option
+type PollOptionResult struct {
+	Option string   `json:"option"`
+	Count  int      `json:"count"`
+	Voters []string `json:"voters"`
+}
+
 // Phase 4: History Sync
 
 // RequestHistoryRequest represents the request body for on-demand history request
@@ -314,6 +1033,47 @@ type NewsletterInfo struct {
 	Description string `json:"description,omitempty"`
 }
 
+// NewsletterSettings controls how posts from a followed newsletter are handled.
+// Newsletters without an entry are persisted and webhooked.
+type NewsletterSettings struct {
+	Muted    bool `json:"muted"`
+	Persist  bool `json:"persist"`  // store posts in the message archive
+	Webhooks bool `json:"webhooks"` // deliver posts to webhooks
+}
+
+// NewsletterMuteRequest represents request to mute/unmute a newsletter
+type NewsletterMuteRequest struct {
+	JID  string `json:"jid"`
+	Mute bool   `json:"mute"`
+}
+
+// UpdateNewsletterSettingsRequest represents a partial update of a newsletter's settings
+type UpdateNewsletterSettingsRequest struct {
+	JID      string `json:"jid"`
+	Persist  *bool  `json:"persist,omitempty"`
+	Webhooks *bool  `json:"webhooks,omitempty"`
+}
+
+// NewsletterReactionRequest represents request to react to a channel post
+type NewsletterReactionRequest struct {
+	JID      string `json:"jid"`
+	ServerID int    `json:"server_id"` // channel post server ID
+	Reaction string `json:"reaction"`  // empty string to remove reaction
+}
+
+// NewsletterMessageStats represents engagement metrics for a channel post
+type NewsletterMessageStats struct {
+	JID            string         `json:"jid"`
+	ServerID       int            `json:"server_id"`
+	MessageID      string         `json:"message_id"`
+	Type           string         `json:"type"`
+	Timestamp      time.Time      `json:"timestamp"`
+	Content        string         `json:"content,omitempty"`
+	ViewsCount     int            `json:"views_count"`
+	ReactionCounts map[string]int `json:"reaction_counts"`
+	TotalReactions int            `json:"total_reactions"`
+}
+
 // Phase 6: Chat Features
 
 // SendTypingRequest represents request to send typing indicator
@@ -322,11 +1082,30 @@ type SendTypingRequest struct {
 	State   string `json:"state"` // "typing", "paused", or "recording"
 }
 
+// SetProfilePhotoRequest sets the account's profile photo from exactly one
+// image source
+type SetProfilePhotoRequest struct {
+	ImagePath   string `json:"image_path,omitempty"`   // file on the bridge host
+	ImageURL    string `json:"image_url,omitempty"`    // http(s) URL to download
+	ImageBase64 string `json:"image_base64,omitempty"` // standard base64 image data
+}
+
+// SetPushNameRequest represents request to change the account's display name
+type SetPushNameRequest struct {
+	Name string `json:"name"`
+}
+
 // SetAboutRequest represents request to set profile about/status text
 type SetAboutRequest struct {
 	Text string `json:"text"`
 }
 
+// SetDefaultDisappearingRequest represents request to set the disappearing
+// messages timer for new chats
+type SetDefaultDisappearingRequest struct {
+	Duration string `json:"duration"` // "off", "24h", "7d", "90d"
+}
+
 // SetDisappearingTimerRequest represents request to set disappearing messages timer
 type SetDisappearingTimerRequest struct {
 	ChatJID  string `json:"chat_jid"`
@@ -339,6 +1118,77 @@ type PinChatRequest struct {
 	Pin     bool   `json:"pin"` // true to pin, false to unpin
 }
 
+// DownloadMediaRequest represents request to download a stored message's
+// media, named by message_ref or by chat_jid and message_id
+type DownloadMediaRequest struct {
+	MessageRef string `json:"message_ref,omitempty"`
+	ChatJID    string `json:"chat_jid,omitempty"`
+	MessageID  string `json:"message_id,omitempty"`
+	Stream     bool   `json:"stream,omitempty"` // answer with the file itself rather than its path
+}
+
+// MessageMedia is a stored message's media and the keys to download it
+type MessageMedia struct {
+	ChatJID       string
+	MessageID     string
+	MediaType     string
+	Filename      string
+	URL           string
+	MediaKey      []byte
+	FileSHA256    []byte
+	FileEncSHA256 []byte
+	FileLength    uint64
+	LocalPath     string // where the media was downloaded to, if it was
+}
+
+// DownloadedMedia is a message's media saved on the bridge
+type DownloadedMedia struct {
+	Path      string `json:"path"`
+	MediaType string `json:"media_type"`
+	Filename  string `json:"filename"`
+	Size      int64  `json:"size"`
+}
+
+// PinMessageRequest represents request to pin or unpin a message in a chat
+type PinMessageRequest struct {
+	ChatJID   string `json:"chat_jid"`
+	MessageID string `json:"message_id"`
+	SenderJID string `json:"sender_jid,omitempty"` // author of the message, when not known from the archive
+	Duration  string `json:"duration,omitempty"`   // "24h", "7d" (default) or "30d"
+	Unpin     bool   `json:"unpin,omitempty"`
+}
+
+// PinnedMessage is a message pinned in a chat, by anyone in it. Pins lapse
+// at ExpiresAt.
+type PinnedMessage struct {
+	ChatJID   string    `json:"chat_jid"`
+	MessageID string    `json:"message_id"`
+	SenderJID string    `json:"sender_jid,omitempty"` // author of the pinned message
+	PinnedBy  string    `json:"pinned_by"`
+	PinnedAt  time.Time `json:"pinned_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Content   string    `json:"content,omitempty"` // the message's text, when archived
+}
+
+// MessageEdit is a version of a message replaced by an edit: Content is the
+// text before the edit made at EditedAt
+type MessageEdit struct {
+	ChatJID   string    `json:"chat_jid"`
+	MessageID string    `json:"message_id"`
+	Content   string    `json:"content"`
+	EditedAt  time.Time `json:"edited_at"`
+}
+
+// MessageReaction is an emoji reaction to a message. Each person has at most
+// one reaction per message; reacting again replaces it.
+type MessageReaction struct {
+	ChatJID   string    `json:"chat_jid"`
+	MessageID string    `json:"message_id"` // message reacted to
+	SenderJID string    `json:"sender_jid"` // who reacted
+	Emoji     string    `json:"emoji"`      // empty when the reaction was taken back
+	ReactedAt time.Time `json:"reacted_at"`
+}
+
 // MuteChatRequest represents request to mute or unmute a chat
 type MuteChatRequest struct {
 	ChatJID  string `json:"chat_jid"`
@@ -352,6 +1202,207 @@ type ArchiveChatRequest struct {
 	Archive bool   `json:"archive"` // true to archive, false to unarchive
 }
 
+// ReceiptPolicy controls which receipts the bridge sends on its own.
+// Read receipts can be overridden per chat; delivery receipts are account-wide
+// because whatsmeow acknowledges every incoming message automatically.
+type ReceiptPolicy struct {
+	ReadReceipts     bool            `json:"read_receipts"`
+	DeliveryReceipts bool            `json:"delivery_receipts"`
+	ChatReadReceipts map[string]bool `json:"chat_read_receipts,omitempty"` // per-chat read receipt overrides
+}
+
+// UpdateReceiptPolicyRequest represents a partial update of the receipt policy
+type UpdateReceiptPolicyRequest struct {
+	ReadReceipts     *bool  `json:"read_receipts,omitempty"`
+	DeliveryReceipts *bool  `json:"delivery_receipts,omitempty"`
+	ChatJID          string `json:"chat_jid,omitempty"`       // when set, read_receipts applies to this chat only
+	ClearOverride    bool   `json:"clear_override,omitempty"` // remove the chat_jid override
+}
+
+// StoragePolicy selects metadata-only storage, which keeps sender, chat,
+// timestamp and message type but never content, filenames or media keys.
+// Webhooks still receive the full message.
+type StoragePolicy struct {
+	MetadataOnly     bool            `json:"metadata_only"`
+	ChatMetadataOnly map[string]bool `json:"chat_metadata_only,omitempty"` // per-chat overrides
+}
+
+// UpdateStoragePolicyRequest represents a partial update of the storage policy
+type UpdateStoragePolicyRequest struct {
+	MetadataOnly  *bool  `json:"metadata_only,omitempty"`
+	ChatJID       string `json:"chat_jid,omitempty"`       // when set, metadata_only applies to this chat only
+	ClearOverride bool   `json:"clear_override,omitempty"` // remove the chat_jid override
+}
+
+// ChatScope limits which chats are stored and forwarded to webhooks, both for
+// history sync and real-time messages. Chats outside the scope are dropped:
+// they are not auto-replied to, auto-read or relayed either.
+type ChatScope struct {
+	AllowChats         []string `json:"allow_chats,omitempty"` // when set, only these chat JIDs are in scope
+	DenyChats          []string `json:"deny_chats,omitempty"`  // never in scope, even if allowed
+	ExcludeGroups      bool     `json:"exclude_groups"`        // drop groups not listed in allow_chats
+	ExcludeNewsletters bool     `json:"exclude_newsletters"`   // drop newsletters not listed in allow_chats
+}
+
+// AutoReadConfig controls automatic read receipts for processed messages.
+// A message is marked read when any rule (a filter expression, see
+// WebhookConfig.FilterExpression) matches it, or, with MarkOnWebhookDelivery,
+// once a webhook for it has been delivered. Receipts are batched per chat and
+// sent DelaySeconds after the first pending message, subject to ReceiptPolicy.
+type AutoReadConfig struct {
+	Enabled               bool     `json:"enabled"`
+	DelaySeconds          int      `json:"delay_seconds"`
+	Rules                 []string `json:"rules"`
+	MarkOnWebhookDelivery bool     `json:"mark_on_webhook_delivery"`
+}
+
+// MaintenanceConfig controls maintenance mode. While enabled, incoming direct
+// messages get Message as an auto-reply (once per contact per day) and
+// webhooks and auto-read rules are suppressed; messages are still stored.
+// A message written in a language with a translation gets that instead.
+type MaintenanceConfig struct {
+	Enabled      bool              `json:"enabled"`
+	Message      string            `json:"message"`
+	Translations map[string]string `json:"translations,omitempty"` // by ISO 639-1 code, e.g. "es"
+}
+
+// DuplicateSendConfig controls duplicate send protection. A send with the
+// same recipient, content and media as one the same tenant made within the
+// last WindowSeconds is rejected, or sent and flagged, unless forced.
+type DuplicateSendConfig struct {
+	WindowSeconds int    `json:"window_seconds"` // 0 disables the check
+	Action        string `json:"action"`         // "reject" (default) or "flag"
+}
+
+// SendRateConfig spaces out sends through the outbox so the account stays
+// clear of WhatsApp's spam limits. Sends wait for their turn rather than fail.
+type SendRateConfig struct {
+	GlobalPerMinute int `json:"global_per_minute"` // all chats together; 0 is no limit
+	ChatPerMinute   int `json:"chat_per_minute"`   // any one chat; 0 is no limit
+}
+
+// CORSConfig controls which browser origins may call the API. Disabled sends
+// no CORS headers at all, for API-only deployments.
+type CORSConfig struct {
+	Disabled bool         `json:"disabled"`
+	Origins  []CORSOrigin `json:"origins"`
+}
+
+// CORSOrigin allows one origin, e.g. "https://app.example.com", or any
+// subdomain with "https://*.example.com", or any origin with "*"
+type CORSOrigin struct {
+	Origin           string `json:"origin"`
+	AllowCredentials bool   `json:"allow_credentials"` // not allowed with "*"
+}
+
+// LoopBreakerConfig controls loop detection for automated sends. An
+// automation that sends more than MaxMessages to one chat within
+// WindowSeconds is paused and AdminJID, if set, is alerted.
+type LoopBreakerConfig struct {
+	MaxMessages   int    `json:"max_messages"` // 0 disables loop detection
+	WindowSeconds int    `json:"window_seconds"`
+	AdminJID      string `json:"admin_jid,omitempty"`
+}
+
+// PausedAutomation is an automation stopped by the loop breaker
+type PausedAutomation struct {
+	Origin   string    `json:"origin"`
+	ChatJID  string    `json:"chat_jid"` // chat where the loop was detected
+	Messages int       `json:"messages"` // sends within the window before it tripped
+	PausedAt time.Time `json:"paused_at"`
+}
+
+// RelayConfig links chats on this bridge to chats on peer bridges running
+// other WhatsApp numbers
+type RelayConfig struct {
+	Links        []RelayLink `json:"links"`         // chats mirrored out to a peer
+	InboundChats []string    `json:"inbound_chats"` // chats peers may relay into
+}
+
+// RelayLink mirrors incoming messages of one local chat into a chat on a peer
+// bridge
+type RelayLink struct {
+	ChatJID     string `json:"chat_jid"`
+	PeerURL     string `json:"peer_url"`      // base URL of the peer bridge's API
+	PeerAPIKey  string `json:"peer_api_key"`  // API key accepted by the peer bridge
+	PeerChatJID string `json:"peer_chat_jid"` // chat on the peer's number to deliver to
+	Enabled     bool   `json:"enabled"`
+}
+
+// RelayMessage is one message relayed from a peer bridge
+type RelayMessage struct {
+	ChatJID       string    `json:"chat_jid"`        // chat on the receiving bridge
+	SourceChatJID string    `json:"source_chat_jid"` // chat on the sending bridge
+	Sender        string    `json:"sender"`
+	SenderName    string    `json:"sender_name,omitempty"`
+	Content       string    `json:"content,omitempty"`
+	MediaType     string    `json:"media_type,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// CommandConfig lets admins run bridge commands, such as "!status", by
+// sending them to the bridge's number in a direct chat
+type CommandConfig struct {
+	Enabled bool           `json:"enabled"`
+	Prefix  string         `json:"prefix"` // marks a message as a command (default "!")
+	Admins  []CommandAdmin `json:"admins"`
+}
+
+// CommandAdmin is a user allowed to send commands, and the commands they
+// may run. help is always allowed.
+type CommandAdmin struct {
+	JID      string   `json:"jid"`      // user JID, e.g. 15551234567@s.whatsapp.net
+	Commands []string `json:"commands"` // command names, or "*" for all
+}
+
+// ApprovalConfig holds sends made with the listed API keys until an
+// approver accepts them
+type ApprovalConfig struct {
+	Enabled     bool     `json:"enabled"`
+	Keys        []string `json:"keys"`         // API key names whose sends need approval, or "*" for all
+	Approvers   []string `json:"approvers"`    // user JIDs asked over WhatsApp; they approve by reacting
+	ExpireHours int      `json:"expire_hours"` // pending sends expire after this long (default 24)
+}
+
+// PendingApproval is a send held for approval. Status is pending until an
+// approver decides, then rejected, or sent or failed once it was approved
+// and run; a send nobody decides on in time is expired.
+type PendingApproval struct {
+	ID        int64              `json:"id"`
+	Tenant    string             `json:"tenant"` // API key that made the send
+	Recipient string             `json:"recipient"`
+	Route     string             `json:"route"`          // send endpoint the request was made to
+	Request   SendMessageRequest `json:"request"`        // the request to /api/send; only the recipient for other routes
+	Body      string             `json:"body,omitempty"` // request body as sent, replayed on approval
+	Status    string             `json:"status"`
+	CreatedAt time.Time          `json:"created_at"`
+	ExpiresAt time.Time          `json:"expires_at"`
+	DecidedBy string             `json:"decided_by,omitempty"` // approver JID, or "api:<key name>"
+	DecidedAt *time.Time         `json:"decided_at,omitempty"`
+	Reason    string             `json:"reason,omitempty"` // given on rejection
+	MessageID string             `json:"message_id,omitempty"`
+	Error     string             `json:"error,omitempty"`
+}
+
+// BusinessHoursConfig controls the out-of-hours auto-reply. Outside the
+// opening periods, the first direct message from each contact gets Message,
+// or its translation into the language the contact wrote in, with
+// {next_open}, {next_open_date} and {name} substituted.
+type BusinessHoursConfig struct {
+	Enabled      bool                  `json:"enabled"`
+	Timezone     string                `json:"timezone"` // IANA zone, e.g. "Europe/Berlin" (default UTC)
+	Hours        []BusinessHoursPeriod `json:"hours"`
+	Message      string                `json:"message"`
+	Translations map[string]string     `json:"translations,omitempty"` // by ISO 639-1 code, e.g. "es"
+}
+
+// BusinessHoursPeriod is one weekly opening period
+type BusinessHoursPeriod struct {
+	Day   string `json:"day"`   // "mon".."sun"
+	Open  string `json:"open"`  // "HH:MM"
+	Close string `json:"close"` // "HH:MM", after open; "24:00" for midnight
+}
+
 // Phase 7: Phone Number Pairing
 
 // PairPhoneRequest initiates phone number pairing
@@ -377,6 +1428,27 @@ type PairingStatusResponse struct {
 	Error      string `json:"error,omitempty"`
 }
 
+// PairingEvent is a pairing status update streamed to the /ui/pair page.
+// Event is one of "code" (new QR available), "pair_code", "success", "error",
+// "cancelled", or a whatsmeow QR channel event such as "timeout".
+type PairingEvent struct {
+	Event     string `json:"event"`
+	PairCode  string `json:"pair_code,omitempty"`
+	ExpiresIn int    `json:"expires_in,omitempty"`
+	Attempt   int    `json:"attempt,omitempty"` // pair_code events: 1 for the first code, higher for renewals
+	JID       string `json:"jid,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// PairingCode is a phone pairing code issued by WhatsApp. Codes that expire
+// before the user enters them are renewed until the session ends.
+type PairingCode struct {
+	Code        string    `json:"code"`
+	PhoneNumber string    `json:"phone_number"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	Attempt     int       `json:"attempt"` // 1 for the first code, higher for renewals
+}
+
 // ConnectionStatusResponse returns WhatsApp connection state
 type ConnectionStatusResponse struct {
 	Success             bool   `json:"success"`
@@ -387,13 +1459,170 @@ type ConnectionStatusResponse struct {
 	LastConnected       string `json:"last_connected,omitempty"`       // ISO-8601 timestamp
 	DisconnectedFor     string `json:"disconnected_for,omitempty"`     // Duration string
 	AutoReconnectErrors int    `json:"auto_reconnect_errors,omitempty"`
+
+	Restriction *AccountRestriction `json:"restriction,omitempty"` // Set while WhatsApp restricts the account
+
+	Quality *ConnectionQuality `json:"quality,omitempty"` // Unset until the first sample is taken
+}
+
+// ConnectionQuality is the latest sample of how quickly WhatsApp answers and
+// how quickly the bridge keeps up with incoming events. A slow ping with a
+// low event lag points at WhatsApp or the network; a high event lag points at
+// the bridge's own handlers, which hold up every event queued behind them.
+type ConnectionQuality struct {
+	PingRTTMs           int64     `json:"ping_rtt_ms"`            // round trip of a keepalive ping, 0 if it failed
+	PingError           string    `json:"ping_error,omitempty"`   // set when the ping got no answer or the bridge was offline
+	EventLagMs          int64     `json:"event_lag_ms"`           // longest an event took to handle since the previous sample
+	EventLagThresholdMs int64     `json:"event_lag_threshold_ms"` // lag above which event_lag_high is raised
+	Lagging             bool      `json:"lagging"`                // event lag is above the threshold
+	SampledAt           time.Time `json:"sampled_at"`
+}
+
+// DiskStatus is the free space on the volume holding the store directory.
+// While Low, media downloads and history sync ingestion are paused so the
+// databases keep room to write.
+type DiskStatus struct {
+	Path           string    `json:"path"`
+	FreeBytes      uint64    `json:"free_bytes"`
+	TotalBytes     uint64    `json:"total_bytes"`
+	ThresholdBytes uint64    `json:"threshold_bytes"` // free space below which the volume is low
+	Low            bool      `json:"low"`
+	Error          string    `json:"error,omitempty"` // set when free space could not be read
+	CheckedAt      time.Time `json:"checked_at"`
+}
+
+// AccountRestriction describes a temporary ban or send rate limit WhatsApp has
+// placed on the account. The outbox does not send while one is active.
+type AccountRestriction struct {
+	Reason      string     `json:"reason"`         // temporary_ban, rate_limited
+	Code        int        `json:"code,omitempty"` // WhatsApp ban reason or error code
+	Description string     `json:"description"`
+	Since       time.Time  `json:"since"`
+	Until       *time.Time `json:"until,omitempty"` // unset when WhatsApp gave no expiry
+}
+
+// BlockedContact is a contact on the account's blocklist
+type BlockedContact struct {
+	JID       string    `json:"jid"`
+	BlockedAt time.Time `json:"blocked_at"` // when the bridge first saw the block
+}
+
+// BlocklistChange is one block or unblock of a contact
+type BlocklistChange struct {
+	JID       string    `json:"jid"`
+	Action    string    `json:"action"` // block, unblock
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// GroupChange is one change to a group's subject, description, picture or
+// settings. Values are strings: settings read "true" or "false", the
+// disappearing timer is in seconds and the picture is WhatsApp's picture ID.
+// OldValue is empty when the bridge had not seen the group's value before.
+type GroupChange struct {
+	GroupJID  string    `json:"group_jid"`
+	Field     string    `json:"field"` // subject, description, picture, announce, locked, ephemeral, join_approval
+	OldValue  string    `json:"old_value"`
+	NewValue  string    `json:"new_value"`
+	ChangedBy string    `json:"changed_by,omitempty"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// SendStats summarises the bridge's recent outgoing messages
+type SendStats struct {
+	Sent                   int `json:"sent"`
+	Failed                 int `json:"failed"`
+	Recipients             int `json:"recipients"`               // distinct chats sent to
+	ColdRecipients         int `json:"cold_recipients"`          // direct chats that never messaged the account
+	UnreachedRecipients    int `json:"unreached_recipients"`     // direct chats with no send delivered, as when blocked
+	MaxIdenticalRecipients int `json:"max_identical_recipients"` // most chats sent one identical text
+}
+
+// AccountHealthSignals are the inputs to the account health score
+type AccountHealthSignals struct {
+	TemporaryBans int       `json:"temporary_bans"` // within the last week
+	RateLimits    int       `json:"rate_limits"`    // within the last week
+	Sends         SendStats `json:"sends"`          // within the last day
+}
+
+// AccountHealth is a heuristic estimate of how close the account is to being
+// restricted by WhatsApp. Score runs from 0 (restricted) to 100.
+type AccountHealth struct {
+	Score           int                  `json:"score"`
+	Status          string               `json:"status"` // good, fair, poor, critical
+	Restriction     *AccountRestriction  `json:"restriction,omitempty"`
+	Signals         AccountHealthSignals `json:"signals"`
+	Recommendations []string             `json:"recommendations"`
+}
+
+// SelfTestRequest configures a POST /api/selftest run
+type SelfTestRequest struct {
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"` // overall limit (default 30, max 120)
+}
+
+// SelfTestReport is the outcome of a canary message sent to the account's own
+// chat and followed through the pipeline
+type SelfTestReport struct {
+	Success   bool            `json:"success"`
+	MessageID string          `json:"message_id,omitempty"`
+	ChatJID   string          `json:"chat_jid,omitempty"`
+	TotalMs   int64           `json:"total_ms"`
+	Stages    []SelfTestStage `json:"stages"`
+}
+
+// SelfTestStage is one step of a self-test. LatencyMs is measured from the
+// start of the stage.
+type SelfTestStage struct {
+	Name      string `json:"name"` // send, echo, storage, webhook
+	Success   bool   `json:"success"`
+	Skipped   bool   `json:"skipped,omitempty"`
+	LatencyMs int64  `json:"latency_ms"`
+	Detail    string `json:"detail,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// SelfTestDelivery is the result of delivering a selftest event to one webhook
+type SelfTestDelivery struct {
+	WebhookID  int    `json:"webhook_id"`
+	Name       string `json:"name"`
+	Success    bool   `json:"success"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ChaosRequest parameterises a fault injected via /api/admin/chaos/ (dev mode only)
+type ChaosRequest struct {
+	OfflineSeconds int    `json:"offline_seconds,omitempty"` // disconnect
+	Failures       int    `json:"failures,omitempty"`        // keepalive-timeout
+	Code           string `json:"code,omitempty"`            // stream-error
+	SyncType       string `json:"sync_type,omitempty"`       // history-sync
+	ChatJID        string `json:"chat_jid,omitempty"`        // history-sync
+	Messages       int    `json:"messages,omitempty"`        // history-sync
+}
+
+// DoctorReport is the outcome of the startup configuration checks
+type DoctorReport struct {
+	Healthy   bool          `json:"healthy"` // no check failed
+	Failures  int           `json:"failures"`
+	Warnings  int           `json:"warnings"`
+	CheckedAt time.Time     `json:"checked_at"`
+	Checks    []DoctorCheck `json:"checks"`
+}
+
+// DoctorCheck is one configuration check, with what to do when it does not pass
+type DoctorCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"` // pass, warn, fail
+	Message string `json:"message"`
+	Remedy  string `json:"remedy,omitempty"`
 }
 
 // SyncStatusResponse returns current message sync state
 type SyncStatusResponse struct {
 	Success       bool   `json:"success"`
 	Syncing       bool   `json:"syncing"`
-	LastSync      string `json:"last_sync,omitempty"`
+	LastSync      string `json:"last_sync,omitempty"`       // RFC3339, UTC
+	LastSyncLocal string `json:"last_sync_local,omitempty"` // RFC3339, in the display time zone
+	DisplayTimezone string `json:"display_timezone,omitempty"`
 	SyncProgress  int    `json:"sync_progress"`        // 0-100 percent
 	MessageCount  int    `json:"message_count"`
 	ConversationCount int `json:"conversation_count"`
@@ -0,0 +1,173 @@
+// Package usage counts API requests per API key and enforces monthly quotas,
+// so teams sharing one bridge can be billed for what they use.
+package usage
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	waLog "go.mau.fi/whatsmeow/util/log"
+
+	"whatsapp-bridge/internal/database"
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+// Usage categories
+const (
+	CategorySend        = "send"
+	CategoryRead        = "read"
+	CategoryWebhookTest = "webhook_test"
+)
+
+// Categories lists the valid usage categories
+var Categories = []string{CategorySend, CategoryRead, CategoryWebhookTest}
+
+// sendPaths are the endpoints that send WhatsApp messages
+var sendPaths = map[string]bool{
+	"/api/send":         true,
+	"/api/send/product": true,
+	"/api/send/catalog": true,
+	"/api/send/contact": true,
+}
+
+// Classify returns the usage category of a request, or "" if it is not metered.
+// Message sends and webhook tests are counted separately; every other GET is a read.
+func Classify(r *http.Request) string {
+	switch {
+	case r.Method == http.MethodPost && sendPaths[r.URL.Path]:
+		return CategorySend
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/api/webhooks/") && strings.HasSuffix(r.URL.Path, "/test"):
+		return CategoryWebhookTest
+	case r.Method == http.MethodGet:
+		return CategoryRead
+	default:
+		return ""
+	}
+}
+
+// Period returns the monthly accounting period containing t
+func Period(t time.Time) string {
+	return t.UTC().Format("2006-01")
+}
+
+// Decision is the outcome of metering one request
+type Decision struct {
+	Allowed      bool
+	SoftExceeded bool
+	Count        int64
+	Quota        localTypes.UsageQuota
+}
+
+// Meter counts requests and applies the configured quotas
+type Meter struct {
+	messageStore *database.MessageStore
+	logger       waLog.Logger
+
+	mu     sync.RWMutex
+	quotas localTypes.UsageQuotas
+}
+
+// NewMeter creates a meter with no quotas
+func NewMeter(messageStore *database.MessageStore, logger waLog.Logger) *Meter {
+	return &Meter{
+		messageStore: messageStore,
+		logger:       logger,
+		quotas:       localTypes.UsageQuotas{},
+	}
+}
+
+// ValidateQuotas checks categories and that soft quotas sit below hard ones
+func ValidateQuotas(quotas localTypes.UsageQuotas) error {
+	for keyName, categories := range quotas {
+		if keyName == "" {
+			return fmt.Errorf("key name must not be empty")
+		}
+		for category, quota := range categories {
+			if !validCategory(category) {
+				return fmt.Errorf("%s: unknown category %q (must be one of %s)", keyName, category, strings.Join(Categories, ", "))
+			}
+			if quota.Soft < 0 || quota.Hard < 0 {
+				return fmt.Errorf("%s/%s: quotas must not be negative", keyName, category)
+			}
+			if quota.Soft > 0 && quota.Hard > 0 && quota.Soft > quota.Hard {
+				return fmt.Errorf("%s/%s: soft quota must not exceed hard quota", keyName, category)
+			}
+		}
+	}
+	return nil
+}
+
+func validCategory(category string) bool {
+	for _, c := range Categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// SetQuotas validates and applies new quotas
+func (m *Meter) SetQuotas(quotas localTypes.UsageQuotas) error {
+	if err := ValidateQuotas(quotas); err != nil {
+		return err
+	}
+	if quotas == nil {
+		quotas = localTypes.UsageQuotas{}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.quotas = quotas
+	return nil
+}
+
+// Quotas returns the current quotas
+func (m *Meter) Quotas() localTypes.UsageQuotas {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.quotas
+}
+
+// Quota returns the quota for a key and category
+func (m *Meter) Quota(keyName, category string) localTypes.UsageQuota {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.quotas[keyName][category]
+}
+
+// Record counts one request for keyName in category. The request is refused
+// without being counted once the hard quota is used up.
+func (m *Meter) Record(keyName, category string, now time.Time) (Decision, error) {
+	quota := m.Quota(keyName, category)
+
+	count, allowed, err := m.messageStore.IncrementUsage(keyName, Period(now), category, quota.Hard)
+	if err != nil {
+		return Decision{Allowed: true, Quota: quota}, err
+	}
+
+	decision := Decision{
+		Allowed:      allowed,
+		SoftExceeded: quota.Soft > 0 && count > quota.Soft,
+		Count:        count,
+		Quota:        quota,
+	}
+	if decision.SoftExceeded && count == quota.Soft+1 {
+		m.logger.Warnf("API key %s passed its soft %s quota (%d) for %s", keyName, category, quota.Soft, Period(now))
+	}
+	return decision, nil
+}
+
+// Report returns a period's counters with their quotas attached
+func (m *Meter) Report(period string) ([]localTypes.UsageCounter, error) {
+	counters, err := m.messageStore.GetUsage(period)
+	if err != nil {
+		return nil, err
+	}
+	for i := range counters {
+		counters[i].Quota = m.Quota(counters[i].KeyName, counters[i].Category)
+	}
+	return counters, nil
+}
@@ -0,0 +1,48 @@
+package usage
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		method, path, want string
+	}{
+		{"POST", "/api/send", CategorySend},
+		{"POST", "/api/send/product", CategorySend},
+		{"POST", "/api/send/contact", CategorySend},
+		{"GET", "/api/send/status", CategoryRead},
+		{"POST", "/api/webhooks/3/test", CategoryWebhookTest},
+		{"POST", "/api/webhooks", ""},
+		{"PUT", "/api/settings/receipts", ""},
+		{"GET", "/api/commerce", CategoryRead},
+	}
+
+	for _, tt := range tests {
+		if got := Classify(httptest.NewRequest(tt.method, tt.path, nil)); got != tt.want {
+			t.Errorf("Classify(%s %s) = %q, want %q", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestValidateQuotas(t *testing.T) {
+	valid := localTypes.UsageQuotas{"team-a": {CategorySend: {Soft: 90, Hard: 100}, CategoryRead: {Hard: 10}}}
+	if err := ValidateQuotas(valid); err != nil {
+		t.Errorf("Expected valid quotas, got %v", err)
+	}
+
+	invalid := []localTypes.UsageQuotas{
+		{"team-a": {"messages": {Hard: 1}}},
+		{"team-a": {CategorySend: {Soft: 200, Hard: 100}}},
+		{"team-a": {CategorySend: {Hard: -1}}},
+		{"": {CategorySend: {Hard: 1}}},
+	}
+	for _, q := range invalid {
+		if err := ValidateQuotas(q); err == nil {
+			t.Errorf("Expected error for %+v", q)
+		}
+	}
+}
@@ -5,11 +5,13 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
-	"encoding/json"
 	"net/http"
 	"time"
 
 	"whatsapp-bridge/internal/database"
+	"whatsapp-bridge/internal/metrics"
+	"whatsapp-bridge/internal/redact"
+	"whatsapp-bridge/internal/secrets"
 	"whatsapp-bridge/internal/types"
 
 	waLog "go.mau.fi/whatsmeow/util/log"
@@ -20,6 +22,13 @@ type DeliveryService struct {
 	messageStore *database.MessageStore
 	logger       waLog.Logger
 	httpClient   *http.Client
+
+	// onDelivered is called after a successful delivery (optional)
+	onDelivered func(chatJID, messageID string)
+
+	// metadataOnly reports whether a chat is stored without message content
+	// (optional); payloads for such chats are logged without it
+	metadataOnly func(chatJID string) bool
 }
 
 // NewDeliveryService creates a new delivery service
@@ -33,13 +42,38 @@ func NewDeliveryService(messageStore *database.MessageStore, logger waLog.Logger
 	}
 }
 
+// Delivery outcomes, served at /api/metrics and pushed to StatsD when configured
+var (
+	deliveriesSucceeded = metrics.NewCounter("bridge_webhook_deliveries_total", "Webhook deliveries by outcome", "result", "delivered")
+	deliveriesFailed    = metrics.NewCounter("bridge_webhook_deliveries_total", "Webhook deliveries by outcome", "result", "failed")
+)
+
 // DeliverWebhook delivers a webhook with retry logic
 func (ds *DeliveryService) DeliverWebhook(config *types.WebhookConfig, payload *types.WebhookPayload, messageID, chatJID string, trigger *types.WebhookTrigger) {
 	maxRetries := 5
 	backoffIntervals := []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second}
 
-	if _, err := json.Marshal(payload); err != nil {
-		ds.logger.Errorf("Failed to marshal webhook payload: %v", err)
+	rendered := renderPayload(*payload, config.PayloadVersion)
+	payload = &rendered
+	if _, _, err := encodePayload(config, payload); err != nil {
+		ds.logger.Errorf("Failed to encode webhook payload: %v", err)
+		return
+	}
+	if err := fitPayload(config, payload); err != nil {
+		// Logged as a failed delivery so the drop shows up in the webhook's logs
+		ds.logger.Warnf("Webhook payload for %s not sent: %v", config.WebhookURL, err)
+		log := &types.WebhookLog{
+			WebhookConfigID: config.ID,
+			MessageID:       messageID,
+			ChatJID:         chatJID,
+			TriggerType:     trigger.TriggerType,
+			TriggerValue:    trigger.TriggerValue,
+			ResponseBody:    err.Error(),
+		}
+		if err := ds.messageStore.StoreWebhookLog(log); err != nil {
+			ds.logger.Errorf("Failed to store webhook log: %v", err)
+		}
+		deliveriesFailed.Inc()
 		return
 	}
 
@@ -47,18 +81,27 @@ func (ds *DeliveryService) DeliverWebhook(config *types.WebhookConfig, payload *
 		payload.Metadata.DeliveryAttempt = attempt
 
 		// Update payload with current attempt
-		payloadBytes, _ := json.Marshal(payload)
+		payloadBytes, contentType, _ := encodePayload(config, payload)
 
-		success, statusCode, responseBody := ds.sendHTTPRequest(config, payloadBytes)
+		success, statusCode, responseBody := ds.sendHTTPRequest(config, payloadBytes, contentType)
 
 		// Log the delivery attempt
+		logged := payloadBytes
+		if withoutContent := ds.withoutContent(chatJID); withoutContent || payload.Metadata.Pairing != nil {
+			stored := *payload
+			if withoutContent {
+				stored = storedPayload(stored)
+			}
+			stored.Metadata.Pairing = keptPairing(stored.Metadata.Pairing)
+			logged, _, _ = encodePayload(config, &stored)
+		}
 		log := &types.WebhookLog{
 			WebhookConfigID: config.ID,
 			MessageID:       messageID,
 			ChatJID:         chatJID,
 			TriggerType:     trigger.TriggerType,
 			TriggerValue:    trigger.TriggerValue,
-			Payload:         string(payloadBytes),
+			Payload:         string(logged),
 			ResponseStatus:  statusCode,
 			ResponseBody:    responseBody,
 			AttemptCount:    attempt,
@@ -78,6 +121,10 @@ func (ds *DeliveryService) DeliverWebhook(config *types.WebhookConfig, payload *
 		}
 
 		if success {
+			deliveriesSucceeded.Inc()
+			if ds.onDelivered != nil {
+				ds.onDelivered(chatJID, messageID)
+			}
 			return // Success, no need to retry
 		}
 
@@ -88,10 +135,42 @@ func (ds *DeliveryService) DeliverWebhook(config *types.WebhookConfig, payload *
 	}
 
 	ds.logger.Errorf("Webhook delivery failed permanently to %s after %d attempts", config.WebhookURL, maxRetries)
+	deliveriesFailed.Inc()
+}
+
+// withoutContent reports whether payloads for chatJID are kept in the store
+// without the message's content
+func (ds *DeliveryService) withoutContent(chatJID string) bool {
+	return ds.metadataOnly != nil && ds.metadataOnly(chatJID)
+}
+
+// storedPayload returns payload with what a chat stored without content
+// must not keep left out: the message's text, file name, context and
+// annotations, and the items of an order
+func storedPayload(payload types.WebhookPayload) types.WebhookPayload {
+	payload.Message.Content = ""
+	payload.Message.Filename = ""
+	payload.Message.Context = nil
+	payload.Message.Annotations = nil
+	payload.Metadata.Commerce = nil
+	return payload
+}
+
+// keptPairing returns a copy of a pairing_code_generated event's code with
+// the code itself redacted: it links a device to the account, so it is sent
+// to the webhook but never kept in the store, whether in its logs or in the
+// delivery queue
+func keptPairing(code *types.PairingCode) *types.PairingCode {
+	if code == nil {
+		return nil
+	}
+	logged := *code
+	logged.Code = redact.Placeholder
+	return &logged
 }
 
 // sendHTTPRequest sends the actual HTTP request
-func (ds *DeliveryService) sendHTTPRequest(config *types.WebhookConfig, payload []byte) (success bool, statusCode int, responseBody string) {
+func (ds *DeliveryService) sendHTTPRequest(config *types.WebhookConfig, payload []byte, contentType string) (success bool, statusCode int, responseBody string) {
 	req, err := http.NewRequest("POST", config.WebhookURL, bytes.NewBuffer(payload))
 	if err != nil {
 		ds.logger.Errorf("Failed to create HTTP request: %v", err)
@@ -99,12 +178,18 @@ func (ds *DeliveryService) sendHTTPRequest(config *types.WebhookConfig, payload
 	}
 
 	// Set headers
-	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Type", contentType)
 	req.Header.Set("User-Agent", "WhatsApp-Bridge-Webhook/1.0")
 
-	// Add HMAC signature if secret token is provided
+	// Add HMAC signature if secret token is provided. The token may refer
+	// to a secret store; unsigned deliveries are never sent in its place.
 	if config.SecretToken != "" {
-		signature := ds.generateHMACSignature(payload, config.SecretToken)
+		secret, err := secrets.Resolve(config.SecretToken)
+		if err != nil {
+			ds.logger.Errorf("Failed to read webhook secret: %v", err)
+			return false, 0, err.Error()
+		}
+		signature := ds.generateHMACSignature(payload, secret)
 		req.Header.Set("X-Webhook-Signature", signature)
 	}
 
@@ -0,0 +1,133 @@
+package webhook
+
+import (
+	"fmt"
+	"regexp"
+
+	"whatsapp-bridge/internal/types"
+
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// DryRun reports whether config would fire for a sample message and why,
+// trigger by trigger, without delivering anything
+func (wm *Manager) DryRun(config *types.WebhookConfig, sample *types.WebhookTestRequest) (*types.WebhookDryRun, error) {
+	msg, err := sampleMessage(sample)
+	if err != nil {
+		return nil, err
+	}
+
+	wm.mutex.RLock()
+	defer wm.mutex.RUnlock()
+
+	result := &types.WebhookDryRun{
+		Enabled:  config.Enabled,
+		Routed:   wm.routeFilter(msg.Info.Chat.String())(config),
+		Triggers: []types.WebhookTriggerDiagnosis{},
+	}
+
+	// The expression is compiled here rather than taken from the cache so a
+	// config that has not been loaded yet is judged the same way
+	var expr *Expression
+	if config.FilterExpression != "" {
+		result.Expression = &types.WebhookExpressionResult{Expression: config.FilterExpression}
+		expr, err = CompileExpression(config.FilterExpression)
+		if err == nil {
+			result.Expression.Result, err = expr.Eval(MessageExpressionEnv(msg, sample.Content, sample.MediaType, sample.ChatName))
+		}
+		if err != nil {
+			result.Expression.Error = err.Error()
+		}
+	}
+
+	for _, trigger := range config.Triggers {
+		matched, reason := wm.explainTrigger(trigger, msg, sample.Content, sample.MediaType)
+		result.Triggers = append(result.Triggers, types.WebhookTriggerDiagnosis{Trigger: trigger, Matched: matched, Reason: reason})
+	}
+
+	// A failing expression holds the webhook back whatever the triggers say
+	if result.Expression == nil || result.Expression.Error == "" {
+		result.Trigger = wm.matchConfigWith(config, expr, msg, sample.Content, sample.MediaType, sample.ChatName)
+	}
+	result.Fires = result.Enabled && result.Routed && result.Trigger != nil
+	return result, nil
+}
+
+// explainTrigger reports whether a trigger matches a message, as
+// matchesTrigger decides it, and says why
+func (wm *Manager) explainTrigger(trigger types.WebhookTrigger, msg *events.Message, content, mediaType string) (bool, string) {
+	if !trigger.Enabled {
+		return false, "trigger is disabled"
+	}
+	if isEventTrigger(trigger.TriggerType) {
+		return false, fmt.Sprintf("fires on %s events, not on messages", trigger.TriggerType)
+	}
+
+	switch trigger.TriggerType {
+	case "all":
+		return true, "matches every message"
+	case "chat_jid":
+		return wm.explainString("chat_jid", msg.Info.Chat.String(), trigger)
+	case "sender":
+		ok, byJID := wm.explainString("sender", msg.Info.Sender.String(), trigger)
+		if ok {
+			return true, byJID
+		}
+		ok, byUser := wm.explainString("sender", msg.Info.Sender.User, trigger)
+		if ok {
+			return true, byUser
+		}
+		return false, byJID + "; " + byUser
+	case "keyword":
+		return wm.explainString("content", content, trigger)
+	case "media_type":
+		return wm.explainString("media_type", mediaType, trigger)
+	case "message_rate":
+		return wm.explainRate(trigger, msg)
+	}
+	return false, fmt.Sprintf("unknown trigger type %s", trigger.TriggerType)
+}
+
+// explainRate describes the rate a message_rate trigger watches against
+// its threshold. Whether a live message fires it also depends on whether
+// the rate has just crossed the threshold, see messageRate.
+func (wm *Manager) explainRate(trigger types.WebhookTrigger, msg *events.Message) (bool, string) {
+	if msg.Info.IsFromMe {
+		return false, "our own messages are not counted"
+	}
+	if wm.rates == nil {
+		return false, "message rates are not tracked"
+	}
+	rate, limit, _, err := wm.currentRate(trigger, msg)
+	if err != nil {
+		return false, fmt.Sprintf("invalid threshold %q: %v", trigger.TriggerValue, err)
+	}
+	if rate.Count > limit {
+		return true, fmt.Sprintf("%s %s sent %d messages in the last %s, over %d; fires once as the rate goes over", rate.Scope, rate.JID, rate.Count, rate.Window, limit)
+	}
+	return false, fmt.Sprintf("%s %s sent %d messages in the last %s, not over %d", rate.Scope, rate.JID, rate.Count, rate.Window, limit)
+}
+
+// explainString describes the outcome of matchesString for a trigger
+func (wm *Manager) explainString(field, text string, trigger types.WebhookTrigger) (bool, string) {
+	if trigger.MatchType == "regex" {
+		if _, err := regexp.Compile(trigger.TriggerValue); err != nil {
+			return false, fmt.Sprintf("invalid regex %q: %v", trigger.TriggerValue, err)
+		}
+	}
+
+	verbs := map[string][2]string{
+		"exact":    {"equals", "does not equal"},
+		"contains": {"contains", "does not contain"},
+		"regex":    {"matches", "does not match"},
+	}
+	verb, ok := verbs[trigger.MatchType]
+	if !ok {
+		return false, fmt.Sprintf("unknown match type %s", trigger.MatchType)
+	}
+
+	if wm.matchesString(text, trigger.TriggerValue, trigger.MatchType) {
+		return true, fmt.Sprintf("%s %q %s %q", field, text, verb[0], trigger.TriggerValue)
+	}
+	return false, fmt.Sprintf("%s %q %s %q", field, text, verb[1], trigger.TriggerValue)
+}
@@ -0,0 +1,59 @@
+package webhook
+
+import (
+	"strings"
+	"testing"
+
+	waLog "go.mau.fi/whatsmeow/util/log"
+
+	"whatsapp-bridge/internal/types"
+)
+
+func TestDryRun(t *testing.T) {
+	wm := &Manager{logger: waLog.Noop}
+	config := &types.WebhookConfig{
+		ID:               1,
+		Enabled:          true,
+		FilterExpression: `chat.is_group`,
+		Triggers: []types.WebhookTrigger{
+			{TriggerType: "keyword", TriggerValue: `^INV-\d+$`, MatchType: "regex", Enabled: true},
+			{TriggerType: "sender", TriggerValue: "15550000001", MatchType: "exact", Enabled: true},
+			{TriggerType: TriggerSendFailed, Enabled: true},
+			{TriggerType: "all", Enabled: false},
+		},
+	}
+	sample := &types.WebhookTestRequest{ChatJID: "120363000000000000@g.us", Sender: "15550000001@s.whatsapp.net", Content: "invoice INV-12"}
+
+	result, err := wm.DryRun(config, sample)
+	if err != nil {
+		t.Fatalf("DryRun: %v", err)
+	}
+	if !result.Fires || result.Trigger == nil || result.Trigger.TriggerType != "sender" {
+		t.Errorf("DryRun = %+v, want the sender trigger to fire", result)
+	}
+	if result.Expression == nil || !result.Expression.Result {
+		t.Errorf("expression = %+v, want true for a group", result.Expression)
+	}
+
+	want := []struct {
+		matched bool
+		reason  string
+	}{
+		{false, `content "invoice INV-12" does not match "^INV-\\d+$"`},
+		{true, `sender "15550000001" equals "15550000001"`},
+		{false, "fires on send_failed events"},
+		{false, "trigger is disabled"},
+	}
+	for i, w := range want {
+		got := result.Triggers[i]
+		if got.Matched != w.matched || !strings.Contains(got.Reason, w.reason) {
+			t.Errorf("trigger %d = %v %q, want %v %q", i, got.Matched, got.Reason, w.matched, w.reason)
+		}
+	}
+
+	// The expression holds back a direct chat even though the sender matches
+	sample.ChatJID = "15550000001@s.whatsapp.net"
+	if result, _ := wm.DryRun(config, sample); result.Fires || result.Expression.Result {
+		t.Errorf("direct chat DryRun = %+v, want no fire", result)
+	}
+}
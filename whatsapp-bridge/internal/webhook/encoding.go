@@ -0,0 +1,111 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+
+	"whatsapp-bridge/internal/types"
+)
+
+// Content types a webhook can receive its payload in. Receivers that cannot
+// parse nested JSON, such as legacy form handlers and SMS gateways, can take
+// the payload as form fields or as text rendered from a template.
+const (
+	ContentTypeJSON = "application/json"
+	ContentTypeForm = "application/x-www-form-urlencoded"
+	ContentTypeText = "text/plain"
+)
+
+// DefaultBodyTemplate is the text/plain body when a webhook sets no template
+const DefaultBodyTemplate = "{message_sender_name}: {message_content}"
+
+// MaxBodyTemplateLength limits a webhook's text/plain body template
+const MaxBodyTemplateLength = 4096
+
+var placeholderPattern = regexp.MustCompile(`\{[a-z0-9_]+\}`)
+
+// encodePayload renders the payload as the webhook's content type and
+// returns the request body with its Content-Type header
+func encodePayload(config *types.WebhookConfig, payload *types.WebhookPayload) ([]byte, string, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch config.ContentType {
+	case "", ContentTypeJSON:
+		return body, ContentTypeJSON, nil
+	case ContentTypeForm:
+		fields, err := flattenPayload(body)
+		if err != nil {
+			return nil, "", err
+		}
+		return []byte(fields.Encode()), ContentTypeForm, nil
+	case ContentTypeText:
+		fields, err := flattenPayload(body)
+		if err != nil {
+			return nil, "", err
+		}
+		template := config.BodyTemplate
+		if template == "" {
+			template = DefaultBodyTemplate
+		}
+		return []byte(renderBodyTemplate(template, fields)), ContentTypeText + "; charset=utf-8", nil
+	}
+	return nil, "", fmt.Errorf("unsupported content type: %s", config.ContentType)
+}
+
+// renderBodyTemplate fills a text/plain body template. Each {field} is
+// replaced by the payload field of that name, with nested names joined by
+// underscores: {event_type}, {message_content}, {message_chat_name},
+// {metadata_delivery_attempt}. Fields the payload lacks render empty.
+func renderBodyTemplate(template string, fields url.Values) string {
+	return placeholderPattern.ReplaceAllStringFunc(template, func(placeholder string) string {
+		return fields.Get(placeholder[1 : len(placeholder)-1])
+	})
+}
+
+// flattenPayload turns a JSON payload into flat fields named by their path,
+// joined by underscores; list items are numbered from 0
+func flattenPayload(body []byte) (url.Values, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var tree interface{}
+	if err := decoder.Decode(&tree); err != nil {
+		return nil, err
+	}
+
+	fields := url.Values{}
+	flatten(fields, "", tree)
+	return fields, nil
+}
+
+func flatten(fields url.Values, prefix string, value interface{}) {
+	join := func(name string) string {
+		if prefix == "" {
+			return name
+		}
+		return prefix + "_" + name
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for name, child := range v {
+			flatten(fields, join(name), child)
+		}
+	case []interface{}:
+		for i, child := range v {
+			flatten(fields, join(strconv.Itoa(i)), child)
+		}
+	case string:
+		fields.Set(prefix, v)
+	case json.Number:
+		fields.Set(prefix, v.String())
+	case bool:
+		fields.Set(prefix, strconv.FormatBool(v))
+	}
+}
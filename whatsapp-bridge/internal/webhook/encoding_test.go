@@ -0,0 +1,85 @@
+package webhook
+
+import (
+	"net/url"
+	"testing"
+
+	"whatsapp-bridge/internal/types"
+)
+
+func TestEncodePayload(t *testing.T) {
+	payload := &types.WebhookPayload{
+		SchemaVersion: PayloadV2,
+		EventType:     "message_received",
+		Message: types.WebhookMessageInfo{
+			ChatName:   "Team",
+			SenderName: "Ana",
+			Content:    "invoice #12 & receipt",
+			Context:    &types.MessageContext{Mentions: []string{"111@s.whatsapp.net"}},
+		},
+		Metadata: types.WebhookMetadata{DeliveryAttempt: 2},
+	}
+
+	body, contentType, err := encodePayload(&types.WebhookConfig{}, payload)
+	if err != nil || contentType != ContentTypeJSON || body[0] != '{' {
+		t.Errorf("default encoding = %s, %q, %v, want JSON", body, contentType, err)
+	}
+
+	body, contentType, err = encodePayload(&types.WebhookConfig{ContentType: ContentTypeForm}, payload)
+	if err != nil || contentType != ContentTypeForm {
+		t.Fatalf("form encoding = %q, %v", contentType, err)
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		t.Fatalf("form body %q: %v", body, err)
+	}
+	want := map[string]string{
+		"event_type":                 "message_received",
+		"schema_version":             "2",
+		"message_content":            "invoice #12 & receipt",
+		"message_is_from_me":         "false",
+		"message_context_mentions_0": "111@s.whatsapp.net",
+		"metadata_delivery_attempt":  "2",
+	}
+	for field, value := range want {
+		if got := form.Get(field); got != value {
+			t.Errorf("form field %s = %q, want %q", field, got, value)
+		}
+	}
+
+	config := &types.WebhookConfig{ContentType: ContentTypeText, BodyTemplate: "[{message_chat_name}] {message_sender_name}: {message_content}{message_push_name}"}
+	body, contentType, _ = encodePayload(config, payload)
+	if string(body) != "[Team] Ana: invoice #12 & receipt" || contentType != "text/plain; charset=utf-8" {
+		t.Errorf("text body = %q, %q", body, contentType)
+	}
+
+	config.BodyTemplate = ""
+	if body, _, _ := encodePayload(config, payload); string(body) != "Ana: invoice #12 & receipt" {
+		t.Errorf("default template body = %q", body)
+	}
+}
+
+func TestValidateContentType(t *testing.T) {
+	t.Setenv("DISABLE_SSRF_CHECK", "true")
+	wm := &Manager{}
+
+	tests := []struct {
+		name    string
+		config  types.WebhookConfig
+		wantErr bool
+	}{
+		{"form", types.WebhookConfig{ContentType: ContentTypeForm}, false},
+		{"text with template", types.WebhookConfig{ContentType: ContentTypeText, BodyTemplate: "{message_content}"}, false},
+		{"xml", types.WebhookConfig{ContentType: "application/xml"}, true},
+		{"template without text", types.WebhookConfig{BodyTemplate: "{message_content}"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			config.Name, config.WebhookURL = "sms", "http://127.0.0.1/hook"
+			if err := wm.ValidateWebhookConfig(&config); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateWebhookConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
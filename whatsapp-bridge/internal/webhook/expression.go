@@ -0,0 +1,578 @@
+package webhook
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Filter expressions are a single-string alternative to trigger rows, e.g.
+//
+//	chat.is_group && contains(content, "invoice") && sender.user != "123"
+//
+// The language is deliberately small: string/number/bool literals, dotted
+// variable names, ! && || == != < <= > >=, parentheses, and a fixed set of
+// pure functions. There is no assignment, looping or reflection, so a stored
+// expression cannot do anything beyond inspecting the message.
+
+// maxExpressionLength bounds stored expressions to keep parsing cheap
+const maxExpressionLength = 1024
+
+// ExpressionEnv holds the variables visible to a filter expression
+type ExpressionEnv map[string]interface{}
+
+// Expression is a compiled filter expression
+type Expression struct {
+	source string
+	root   exprNode
+}
+
+// String returns the original expression source
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Eval evaluates the expression and requires a boolean result
+func (e *Expression) Eval(env ExpressionEnv) (bool, error) {
+	v, err := e.root.eval(env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression must evaluate to a boolean, got %T", v)
+	}
+	return b, nil
+}
+
+// CompileExpression parses a filter expression and checks that every
+// variable and function it references is known.
+func CompileExpression(source string) (*Expression, error) {
+	if len(source) > maxExpressionLength {
+		return nil, fmt.Errorf("expression must be at most %d characters", maxExpressionLength)
+	}
+
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &exprParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+
+	return &Expression{source: source, root: root}, nil
+}
+
+// expressionVariables lists the variables available to filter expressions
+var expressionVariables = map[string]bool{
+	"content":        true,
+	"media_type":     true,
+	"is_from_me":     true,
+	"chat.jid":       true,
+	"chat.name":      true,
+	"chat.is_group":  true,
+	"sender.jid":     true,
+	"sender.user":    true,
+	"sender.name":    true,
+	"message.id":     true,
+	"message.length": true,
+}
+
+// expressionFunctions maps function names to their arity and implementation
+var expressionFunctions = map[string]struct {
+	arity int
+	fn    func(args []interface{}) (interface{}, error)
+}{
+	"contains": {2, func(a []interface{}) (interface{}, error) {
+		s, sub, err := twoStrings("contains", a)
+		return strings.Contains(strings.ToLower(s), strings.ToLower(sub)), err
+	}},
+	"starts_with": {2, func(a []interface{}) (interface{}, error) {
+		s, prefix, err := twoStrings("starts_with", a)
+		return strings.HasPrefix(strings.ToLower(s), strings.ToLower(prefix)), err
+	}},
+	"ends_with": {2, func(a []interface{}) (interface{}, error) {
+		s, suffix, err := twoStrings("ends_with", a)
+		return strings.HasSuffix(strings.ToLower(s), strings.ToLower(suffix)), err
+	}},
+	// A literal pattern is compiled once with the expression (see
+	// callNode); this runs for patterns computed per message
+	"matches": {2, func(a []interface{}) (interface{}, error) {
+		s, pattern, err := twoStrings("matches", a)
+		if err != nil {
+			return false, err
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return false, fmt.Errorf("matches: invalid regex: %v", err)
+		}
+		return re.MatchString(s), nil
+	}},
+	"lower": {1, func(a []interface{}) (interface{}, error) {
+		s, ok := a[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("lower: expected string, got %T", a[0])
+		}
+		return strings.ToLower(s), nil
+	}},
+	"len": {1, func(a []interface{}) (interface{}, error) {
+		s, ok := a[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("len: expected string, got %T", a[0])
+		}
+		return float64(len([]rune(s))), nil
+	}},
+}
+
+func twoStrings(name string, args []interface{}) (string, string, error) {
+	a, ok1 := args[0].(string)
+	b, ok2 := args[1].(string)
+	if !ok1 || !ok2 {
+		return "", "", fmt.Errorf("%s: expected (string, string), got (%T, %T)", name, args[0], args[1])
+	}
+	return a, b, nil
+}
+
+// Tokenizer
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	runes := []rune(src)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '(':
+			tokens = append(tokens, token{tokLParen, "(", i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokRParen, ")", i})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{tokComma, ",", i})
+			i++
+
+		case r == '"' || r == '\'':
+			start := i
+			quote := r
+			var sb strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != quote; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				sb.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++ // closing quote
+			tokens = append(tokens, token{tokString, sb.String(), start})
+
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokNumber, string(runes[start:i]), start})
+
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokIdent, string(runes[start:i]), start})
+
+		default:
+			start := i
+			two := ""
+			if i+1 < len(runes) {
+				two = string(runes[i : i+2])
+			}
+			switch two {
+			case "&&", "||", "==", "!=", "<=", ">=":
+				tokens = append(tokens, token{tokOp, two, start})
+				i += 2
+				continue
+			}
+			switch r {
+			case '!', '<', '>':
+				tokens = append(tokens, token{tokOp, string(r), start})
+				i++
+			default:
+				return nil, fmt.Errorf("unexpected character %q at position %d", r, start)
+			}
+		}
+	}
+
+	return append(tokens, token{tokEOF, "end of expression", len(runes)}), nil
+}
+
+// Parser (precedence: || < && < comparison < unary !)
+
+type exprParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *exprParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOp && p.peek().text == "||" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOp && p.peek().text == "&&" {
+		p.next()
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind == tokOp {
+		switch tok.text {
+		case "==", "!=", "<", "<=", ">", ">=":
+			p.next()
+			right, err := p.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+			return &compareNode{op: tok.text, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if tok := p.peek(); tok.kind == tokOp && tok.text == "!" {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokString:
+		return &literalNode{value: tok.text}, nil
+
+	case tokNumber:
+		n, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", tok.text, tok.pos)
+		}
+		return &literalNode{value: n}, nil
+
+	case tokLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, fmt.Errorf("expected ) at position %d", closing.pos)
+		}
+		return inner, nil
+
+	case tokIdent:
+		switch tok.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		}
+
+		if p.peek().kind == tokLParen {
+			return p.parseCall(tok)
+		}
+
+		if !expressionVariables[tok.text] {
+			return nil, fmt.Errorf("unknown variable %q at position %d", tok.text, tok.pos)
+		}
+		return &variableNode{name: tok.text}, nil
+	}
+
+	return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+}
+
+func (p *exprParser) parseCall(name token) (exprNode, error) {
+	fn, ok := expressionFunctions[name.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q at position %d", name.text, name.pos)
+	}
+	p.next() // (
+
+	var args []exprNode
+	if p.peek().kind != tokRParen {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.peek().kind != tokComma {
+				break
+			}
+			p.next()
+		}
+	}
+	if closing := p.next(); closing.kind != tokRParen {
+		return nil, fmt.Errorf("expected ) at position %d", closing.pos)
+	}
+
+	if len(args) != fn.arity {
+		return nil, fmt.Errorf("%s expects %d arguments, got %d", name.text, fn.arity, len(args))
+	}
+	call := &callNode{name: name.text, args: args}
+	if lit, ok := args[len(args)-1].(*literalNode); ok && name.text == "matches" {
+		pattern, ok := lit.value.(string)
+		if !ok {
+			return nil, fmt.Errorf("matches: pattern at position %d must be a string", name.pos)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("matches: invalid regex at position %d: %v", name.pos, err)
+		}
+		call.re = re
+	}
+	return call, nil
+}
+
+// AST nodes
+
+type exprNode interface {
+	eval(env ExpressionEnv) (interface{}, error)
+}
+
+type literalNode struct{ value interface{} }
+
+func (n *literalNode) eval(ExpressionEnv) (interface{}, error) { return n.value, nil }
+
+type variableNode struct{ name string }
+
+func (n *variableNode) eval(env ExpressionEnv) (interface{}, error) {
+	v, ok := env[n.name]
+	if !ok {
+		return nil, fmt.Errorf("variable %q is not set", n.name)
+	}
+	if i, ok := v.(int); ok {
+		return float64(i), nil
+	}
+	return v, nil
+}
+
+type notNode struct{ operand exprNode }
+
+func (n *notNode) eval(env ExpressionEnv) (interface{}, error) {
+	v, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return nil, fmt.Errorf("! expects a boolean, got %T", v)
+	}
+	return !b, nil
+}
+
+type logicalNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n *logicalNode) eval(env ExpressionEnv) (interface{}, error) {
+	lv, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	l, ok := lv.(bool)
+	if !ok {
+		return nil, fmt.Errorf("%s expects booleans, got %T", n.op, lv)
+	}
+
+	// Short-circuit
+	if n.op == "&&" && !l {
+		return false, nil
+	}
+	if n.op == "||" && l {
+		return true, nil
+	}
+
+	rv, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	r, ok := rv.(bool)
+	if !ok {
+		return nil, fmt.Errorf("%s expects booleans, got %T", n.op, rv)
+	}
+	return r, nil
+}
+
+type compareNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n *compareNode) eval(env ExpressionEnv) (interface{}, error) {
+	lv, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	rv, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch l := lv.(type) {
+	case string:
+		r, ok := rv.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare string with %T", rv)
+		}
+		return compareOrdered(n.op, strings.Compare(l, r))
+	case float64:
+		r, ok := rv.(float64)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare number with %T", rv)
+		}
+		c := 0
+		if l < r {
+			c = -1
+		} else if l > r {
+			c = 1
+		}
+		return compareOrdered(n.op, c)
+	case bool:
+		r, ok := rv.(bool)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare boolean with %T", rv)
+		}
+		switch n.op {
+		case "==":
+			return l == r, nil
+		case "!=":
+			return l != r, nil
+		}
+		return nil, fmt.Errorf("operator %s not supported for booleans", n.op)
+	}
+
+	return nil, fmt.Errorf("cannot compare %T", lv)
+}
+
+func compareOrdered(op string, c int) (interface{}, error) {
+	switch op {
+	case "==":
+		return c == 0, nil
+	case "!=":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	case ">=":
+		return c >= 0, nil
+	}
+	return nil, fmt.Errorf("unknown operator %s", op)
+}
+
+type callNode struct {
+	name string
+	args []exprNode
+	re   *regexp.Regexp // matches with a literal pattern, compiled once
+}
+
+func (n *callNode) eval(env ExpressionEnv) (interface{}, error) {
+	if n.re != nil {
+		v, err := n.args[0].eval(env)
+		if err != nil {
+			return nil, err
+		}
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("matches: expected (string, string), got (%T, string)", v)
+		}
+		return n.re.MatchString(s), nil
+	}
+
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	return expressionFunctions[n.name].fn(args)
+}
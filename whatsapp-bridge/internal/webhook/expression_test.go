@@ -0,0 +1,110 @@
+package webhook
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpressionEval(t *testing.T) {
+	env := ExpressionEnv{
+		"content":        "Please pay INVOICE #42",
+		"media_type":     "",
+		"is_from_me":     false,
+		"chat.jid":       "120363@g.us",
+		"chat.name":      "Billing",
+		"chat.is_group":  true,
+		"sender.jid":     "456@s.whatsapp.net",
+		"sender.user":    "456",
+		"sender.name":    "Alice",
+		"message.id":     "ABC",
+		"message.length": 22,
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`chat.is_group && contains(content, "invoice") && sender.user != "123"`, true},
+		{`chat.is_group && sender.user == "456"`, true},
+		{`!chat.is_group || is_from_me`, false},
+		{`starts_with(content, "please") && ends_with(content, "#42")`, true},
+		{`matches(content, "#[0-9]+$")`, true},
+		{`matches(content, lower("^PLEASE"))`, false},
+		{`lower(chat.name) == "billing"`, true},
+		{`len(content) > 10 && message.length >= 22`, true},
+		{`media_type == "" && (sender.name == "Bob" || sender.name == 'Alice')`, true},
+		{`true && false`, false},
+		{`"a" < "b"`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := CompileExpression(tt.expr)
+			if err != nil {
+				t.Fatalf("CompileExpression(%q) error: %v", tt.expr, err)
+			}
+			got, err := expr.Eval(env)
+			if err != nil {
+				t.Fatalf("Eval(%q) error: %v", tt.expr, err)
+			}
+			if got != tt.want {
+				t.Errorf("Eval(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileExpressionErrors(t *testing.T) {
+	tests := []struct {
+		expr        string
+		errContains string
+	}{
+		{`contains(content)`, "expects 2 arguments"},
+		{`exec("rm -rf /")`, "unknown function"},
+		{`sender.password == "x"`, "unknown variable"},
+		{`content == "unterminated`, "unterminated"},
+		{`(chat.is_group`, "expected )"},
+		{`chat.is_group &&`, "unexpected"},
+		{`content = "x"`, "unexpected character"},
+		{`matches(content, "[unclosed")`, "invalid regex"},
+		{strings.Repeat("a", maxExpressionLength+1), "at most"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := CompileExpression(tt.expr)
+			if err == nil {
+				t.Fatalf("CompileExpression(%q) = nil, want error containing %q", tt.expr, tt.errContains)
+			}
+			if !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("CompileExpression(%q) error = %v, want error containing %q", tt.expr, err, tt.errContains)
+			}
+		})
+	}
+}
+
+func TestExpressionTypeErrors(t *testing.T) {
+	expr, err := CompileExpression(`content && true`)
+	if err != nil {
+		t.Fatalf("CompileExpression error: %v", err)
+	}
+	if _, err := expr.Eval(ExpressionEnv{"content": "x"}); err == nil {
+		t.Error("Eval should fail when && is applied to a string")
+	}
+}
+
+func TestMatchesCompilesLiteralPatternOnce(t *testing.T) {
+	expr, err := CompileExpression(`matches(content, "^inv-[0-9]+$")`)
+	if err != nil {
+		t.Fatalf("CompileExpression error: %v", err)
+	}
+	call, ok := expr.root.(*callNode)
+	if !ok || call.re == nil {
+		t.Fatalf("root = %#v, want a matches call with its pattern compiled", expr.root)
+	}
+	for content, want := range map[string]bool{"inv-42": true, "invoice": false} {
+		if got, err := expr.Eval(ExpressionEnv{"content": content}); err != nil || got != want {
+			t.Errorf("Eval(%q) = %v, %v, want %v", content, got, err, want)
+		}
+	}
+}
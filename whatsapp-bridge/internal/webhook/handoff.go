@@ -0,0 +1,125 @@
+package webhook
+
+import (
+	"time"
+
+	"whatsapp-bridge/internal/types"
+	"whatsapp-bridge/internal/whatsapp"
+)
+
+// queuedDelivery is a payload matched for a webhook, about to be queued
+type queuedDelivery struct {
+	config  *types.WebhookConfig
+	trigger types.WebhookTrigger
+	payload types.WebhookPayload
+}
+
+// enqueue stores the deliveries, counting their trigger matches and removing
+// the dispatch of the message they came from in the same transaction, then
+// sends them. Resumed deliveries were counted when first queued. A stored delivery stays
+// until it is delivered or given up on, so a restart resends what was cut
+// short. When storing fails the deliveries are still sent, just not kept.
+// Deliveries for chats stored without content are kept without it, so one
+// resumed after a restart is sent without it too; pairing codes are never
+// kept, so one resumed after a restart is sent redacted.
+func (wm *Manager) enqueue(dispatchID int64, queued []queuedDelivery) {
+	if dispatchID == 0 && len(queued) == 0 {
+		return
+	}
+
+	deliveries := make([]*types.WebhookDelivery, len(queued))
+	for i, q := range queued {
+		payload := q.payload
+		if wm.delivery.withoutContent(payload.Message.ChatJID) {
+			payload = storedPayload(payload)
+		}
+		payload.Metadata.Pairing = keptPairing(payload.Metadata.Pairing)
+		deliveries[i] = &types.WebhookDelivery{
+			WebhookConfigID: q.config.ID,
+			ChatJID:         q.payload.Message.ChatJID,
+			MessageID:       q.payload.Message.ID,
+			Trigger:         q.trigger,
+			Payload:         payload,
+			CreatedAt:       time.Now(),
+		}
+	}
+	if err := wm.messageStore.QueueWebhookDeliveries(dispatchID, deliveries); err != nil {
+		wm.logger.Warnf("Failed to keep webhook deliveries, sending them anyway: %v", err)
+		for _, d := range deliveries {
+			d.ID = 0
+		}
+	}
+
+	for i, d := range deliveries {
+		d.Payload = queued[i].payload
+		wm.send(queued[i].config, *d)
+	}
+}
+
+// send delivers a queued delivery in order with the chat's earlier ones and
+// removes it once it was delivered or given up on
+func (wm *Manager) send(config *types.WebhookConfig, d types.WebhookDelivery) {
+	wm.deliver(config, d.ChatJID, func() {
+		d.Payload.Metadata.DeliveryID = d.ID
+		wm.delivery.DeliverWebhook(config, &d.Payload, d.MessageID, d.ChatJID, &d.Trigger)
+		if d.ID == 0 {
+			return
+		}
+		if err := wm.messageStore.FinishWebhookDelivery(d.ID); err != nil {
+			wm.logger.Warnf("Failed to finish webhook delivery %d: %v", d.ID, err)
+		}
+	})
+}
+
+// ResumeDeliveries sends what an earlier run left unsent: deliveries that
+// were queued but not finished, then messages stored before their webhooks
+// were matched. Call it once, before messages arrive. A delivery cut short
+// after the receiver got it is sent again with the same delivery_id, so
+// receivers can drop repeats.
+func (wm *Manager) ResumeDeliveries() {
+	deliveries, err := wm.messageStore.ListWebhookDeliveries()
+	if err != nil {
+		wm.logger.Errorf("Failed to load unsent webhook deliveries: %v", err)
+	}
+	for _, d := range deliveries {
+		config := wm.configByID(d.WebhookConfigID)
+		if config == nil || !config.Enabled {
+			// The webhook was deleted or disabled in the meantime
+			if err := wm.messageStore.FinishWebhookDelivery(d.ID); err != nil {
+				wm.logger.Warnf("Failed to drop webhook delivery %d: %v", d.ID, err)
+			}
+			continue
+		}
+		wm.send(config, d)
+	}
+
+	dispatches, err := wm.messageStore.ListWebhookDispatches()
+	if err != nil {
+		wm.logger.Errorf("Failed to load messages with unsent webhooks: %v", err)
+	}
+	for _, d := range dispatches {
+		msg, err := whatsapp.DecodeWebhookEvent(d.Event)
+		if err != nil {
+			wm.logger.Warnf("Dropping webhooks of message %s: %v", d.MessageID, err)
+			wm.enqueue(d.ID, nil)
+			continue
+		}
+		wm.ProcessMessage(nil, msg, d.ChatName, d.ID)
+	}
+
+	if n := len(deliveries) + len(dispatches); n > 0 {
+		wm.logger.Infof("Resumed %d webhook deliveries and %d messages left unsent by the last run", len(deliveries), len(dispatches))
+	}
+}
+
+// configByID returns the loaded webhook config with the ID, or nil
+func (wm *Manager) configByID(id int) *types.WebhookConfig {
+	wm.mutex.RLock()
+	defer wm.mutex.RUnlock()
+	for _, config := range wm.configs {
+		if config.ID == id {
+			return config
+		}
+	}
+	return nil
+}
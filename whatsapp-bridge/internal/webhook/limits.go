@@ -0,0 +1,79 @@
+package webhook
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"whatsapp-bridge/internal/types"
+)
+
+// Oversize actions: what happens to a payload over a webhook's
+// MaxPayloadBytes
+const (
+	OversizeReference = "reference" // send it without the content, to fetch by ref
+	OversizeDrop      = "drop"      // do not send it
+)
+
+// MinPayloadBytes is the smallest payload limit a webhook may set, enough
+// for a payload whose content has been left out
+const MinPayloadBytes = 1024
+
+// fitPayload applies a webhook's size limits to a rendered payload. Content
+// over MaxContentLength is cut at a character boundary. A payload still
+// over MaxPayloadBytes loses its content, context and annotations, which the
+// receiver can fetch from /api/messages/{ref}; if that is not enough, or the
+// webhook drops oversize payloads, an error says why nothing should be sent.
+func fitPayload(config *types.WebhookConfig, payload *types.WebhookPayload) error {
+	msg := &payload.Message
+	if config.MaxContentLength > 0 && len(msg.Content) > config.MaxContentLength {
+		msg.ContentLength = len(msg.Content)
+		msg.Content = cutContent(msg.Content, config.MaxContentLength)
+		msg.ContentTruncated = true
+	}
+
+	if config.MaxPayloadBytes <= 0 {
+		return nil
+	}
+	size, err := payloadSize(config, payload)
+	if err != nil || size <= config.MaxPayloadBytes {
+		return err
+	}
+	if config.OversizeAction == OversizeDrop {
+		return fmt.Errorf("payload of %d bytes exceeds max_payload_bytes %d", size, config.MaxPayloadBytes)
+	}
+
+	if msg.ContentLength == 0 {
+		msg.ContentLength = len(msg.Content)
+	}
+	msg.Content = ""
+	msg.ContentTruncated = false
+	msg.ContentOmitted = true
+	msg.Context = nil
+	msg.Annotations = nil
+
+	size, err = payloadSize(config, payload)
+	if err != nil {
+		return err
+	}
+	if size > config.MaxPayloadBytes {
+		return fmt.Errorf("payload of %d bytes exceeds max_payload_bytes %d even without its content", size, config.MaxPayloadBytes)
+	}
+	return nil
+}
+
+// payloadSize is the size of the request body the payload encodes to
+func payloadSize(config *types.WebhookConfig, payload *types.WebhookPayload) (int, error) {
+	body, _, err := encodePayload(config, payload)
+	if err != nil {
+		return 0, err
+	}
+	return len(body), nil
+}
+
+// cutContent cuts s to at most n bytes without splitting a character
+func cutContent(s string, n int) string {
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
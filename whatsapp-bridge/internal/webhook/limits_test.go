@@ -0,0 +1,83 @@
+package webhook
+
+import (
+	"strings"
+	"testing"
+
+	"whatsapp-bridge/internal/types"
+)
+
+func TestFitPayload(t *testing.T) {
+	long := strings.Repeat("ü", 3000) // 6000 bytes
+	payload := func() *types.WebhookPayload {
+		return &types.WebhookPayload{
+			EventType: "message_received",
+			Message: types.WebhookMessageInfo{
+				ID:          "M1",
+				Ref:         "ref1",
+				Content:     long,
+				Annotations: map[string]string{"ocr": "text"},
+			},
+		}
+	}
+
+	// Content is cut without splitting a character
+	p := payload()
+	if err := fitPayload(&types.WebhookConfig{MaxContentLength: 101}, p); err != nil {
+		t.Fatalf("fitPayload: %v", err)
+	}
+	if len(p.Message.Content) != 100 || !p.Message.ContentTruncated || p.Message.ContentLength != len(long) {
+		t.Errorf("cut message = %d bytes, truncated %v, length %d", len(p.Message.Content), p.Message.ContentTruncated, p.Message.ContentLength)
+	}
+
+	// A payload over the limit is sent by reference
+	p = payload()
+	config := &types.WebhookConfig{MaxPayloadBytes: 2048}
+	if err := fitPayload(config, p); err != nil {
+		t.Fatalf("fitPayload: %v", err)
+	}
+	if p.Message.Content != "" || !p.Message.ContentOmitted || p.Message.Annotations != nil || p.Message.Ref != "ref1" {
+		t.Errorf("oversize message = %+v, want content left out and the ref kept", p.Message)
+	}
+	if size, _ := payloadSize(config, p); size > config.MaxPayloadBytes {
+		t.Errorf("payload is %d bytes, over the %d limit", size, config.MaxPayloadBytes)
+	}
+
+	// Cutting the content first can keep it under the limit
+	p = payload()
+	if err := fitPayload(&types.WebhookConfig{MaxContentLength: 500, MaxPayloadBytes: 2048}, p); err != nil || p.Message.ContentOmitted || !p.Message.ContentTruncated {
+		t.Errorf("cut message = %+v, %v, want it truncated but sent", p.Message, err)
+	}
+
+	p = payload()
+	if err := fitPayload(&types.WebhookConfig{MaxPayloadBytes: 2048, OversizeAction: OversizeDrop}, p); err == nil {
+		t.Error("oversize payload was not dropped")
+	}
+}
+
+func TestValidateSizeLimits(t *testing.T) {
+	t.Setenv("DISABLE_SSRF_CHECK", "true")
+	wm := &Manager{}
+
+	tests := []struct {
+		name    string
+		config  types.WebhookConfig
+		wantErr bool
+	}{
+		{"content limit", types.WebhookConfig{MaxContentLength: 1000}, false},
+		{"drop", types.WebhookConfig{MaxPayloadBytes: 65536, OversizeAction: OversizeDrop}, false},
+		{"negative content limit", types.WebhookConfig{MaxContentLength: -1}, true},
+		{"tiny payload limit", types.WebhookConfig{MaxPayloadBytes: 100}, true},
+		{"unknown action", types.WebhookConfig{MaxPayloadBytes: 65536, OversizeAction: "split"}, true},
+		{"action without limit", types.WebhookConfig{OversizeAction: OversizeDrop}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			config.Name, config.WebhookURL = "crm", "http://127.0.0.1/hook"
+			if err := wm.ValidateWebhookConfig(&config); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateWebhookConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,12 +2,19 @@ package webhook
 
 import (
 	"fmt"
+	"net/url"
 	"regexp"
 	"strings"
 	"sync"
 	"time"
 
 	"whatsapp-bridge/internal/database"
+	"whatsapp-bridge/internal/metrics"
+	"whatsapp-bridge/internal/msgrate"
+	"whatsapp-bridge/internal/msgref"
+	"whatsapp-bridge/internal/recovery"
+	"whatsapp-bridge/internal/redact"
+	"whatsapp-bridge/internal/tenant"
 	"whatsapp-bridge/internal/types"
 	"whatsapp-bridge/internal/whatsapp"
 
@@ -15,6 +22,40 @@ import (
 	waLog "go.mau.fi/whatsmeow/util/log"
 )
 
+// Event trigger types subscribe a webhook to bridge events instead of messages
+const (
+	TriggerSendFailed        = "send_failed"
+	TriggerMessageSent       = "message_sent"
+	TriggerOrderReceived     = "order_received"
+	TriggerAccountRestricted = "account_restricted"
+	TriggerContactBlocked    = "contact_blocked"
+	TriggerContactUnblocked  = "contact_unblocked"
+	TriggerSelfTest          = "selftest"
+	TriggerPairingCode       = "pairing_code_generated"
+	TriggerMessagePinned     = "message_pinned"
+	TriggerMessageUnpinned   = "message_unpinned"
+	TriggerEventLag          = "event_lag_high"
+	TriggerMessageReaction   = "message_reaction"
+	TriggerDiskSpaceLow      = "disk_space_low"
+	TriggerMessageAnnotated  = "message_annotated"
+
+	TriggerGroupSubject     = "group_subject_changed"
+	TriggerGroupDescription = "group_description_changed"
+	TriggerGroupPicture     = "group_picture_changed"
+	TriggerGroupSettings    = "group_settings_changed"
+)
+
+// isEventTrigger reports whether a trigger type names an event rather than a message match
+func isEventTrigger(triggerType string) bool {
+	switch triggerType {
+	case TriggerSendFailed, TriggerMessageSent, TriggerOrderReceived, TriggerAccountRestricted, TriggerContactBlocked, TriggerContactUnblocked, TriggerSelfTest, TriggerPairingCode,
+		TriggerMessagePinned, TriggerMessageUnpinned, TriggerEventLag, TriggerMessageReaction, TriggerDiskSpaceLow, TriggerGroupSubject, TriggerGroupDescription, TriggerGroupPicture, TriggerGroupSettings,
+		TriggerMessageAnnotated:
+		return true
+	}
+	return false
+}
+
 // Manager handles webhook processing and delivery
 type Manager struct {
 	messageStore *database.MessageStore
@@ -22,16 +63,57 @@ type Manager struct {
 	configs      []*types.WebhookConfig
 	mutex        sync.RWMutex
 	delivery     *DeliveryService
+
+	// Compiled filter expressions keyed by webhook config ID
+	expressions map[int]*Expression
+
+	// Routing profiles restrict which webhooks fire for which chats
+	routingProfiles []*types.RoutingProfile
+	chatTags        map[string]map[string][]string // tenant -> chat JID -> tags
+
+	// Base URL of the bridge API that payloads link to, e.g. http://localhost:8080
+	publicURL string
+
+	// Rolling message counts for message_rate triggers; nil disables them
+	rates      *msgrate.Tracker
+	rateAlerts rateAlerts
+
+	// Deliveries run in order per webhook and chat (see ordering.go)
+	deliveries chatQueue
 }
 
 // NewManager creates a new webhook manager
 func NewManager(messageStore *database.MessageStore, logger waLog.Logger) *Manager {
-	return &Manager{
+	wm := &Manager{
 		messageStore: messageStore,
 		logger:       logger,
 		configs:      make([]*types.WebhookConfig, 0),
 		delivery:     NewDeliveryService(messageStore, logger),
+		chatTags:     make(map[string]map[string][]string),
 	}
+	metrics.NewGaugeFunc("bridge_webhook_deliveries_waiting", "Webhook deliveries queued behind an earlier one for the same chat",
+		func() float64 { return float64(wm.deliveries.depth()) })
+	return wm
+}
+
+// deliver queues a delivery to a webhook behind the earlier ones for the
+// same chat, so a consumer receives a chat's events in order even when an
+// earlier one is being retried
+func (wm *Manager) deliver(config *types.WebhookConfig, chatJID string, fn func()) {
+	key := fmt.Sprintf("%d|%s", config.ID, chatJID)
+	wm.deliveries.run(key, fmt.Sprintf("webhook %d delivery", config.ID), fn)
+}
+
+// SetPublicURL sets the base URL of the bridge API that payloads link to,
+// such as the media_download_url of media messages
+func (wm *Manager) SetPublicURL(base string) {
+	wm.publicURL = strings.TrimSuffix(base, "/")
+}
+
+// SetRateTracker enables message_rate triggers, which fire while a chat or
+// sender is over a message rate
+func (wm *Manager) SetRateTracker(t *msgrate.Tracker) {
+	wm.rates = t
 }
 
 // LoadWebhookConfigs loads webhook configurations from database
@@ -45,6 +127,20 @@ func (wm *Manager) LoadWebhookConfigs() error {
 	}
 
 	wm.configs = configs
+	wm.expressions = make(map[int]*Expression)
+	for _, config := range configs {
+		redact.Register(config.SecretToken)
+		if config.FilterExpression == "" {
+			continue
+		}
+		expr, err := CompileExpression(config.FilterExpression)
+		if err != nil {
+			// Stored before validation existed or edited by hand; never match
+			wm.logger.Warnf("Webhook %d has invalid filter expression, disabling it: %v", config.ID, err)
+			expr, _ = CompileExpression("false")
+		}
+		wm.expressions[config.ID] = expr
+	}
 	wm.logger.Infof("Loaded %d webhook configurations", len(configs))
 
 	// Debug logging
@@ -59,6 +155,114 @@ func (wm *Manager) LoadWebhookConfigs() error {
 	return nil
 }
 
+// SetDeliveryHook registers fn to be called whenever a webhook for a message
+// is delivered successfully. Must be called before messages are processed.
+func (wm *Manager) SetDeliveryHook(fn func(chatJID, messageID string)) {
+	wm.delivery.onDelivered = fn
+}
+
+// SetMetadataOnly registers fn to report whether a chat is stored without
+// message content, so its payloads are logged and queued without it. Must
+// be called before messages are processed.
+func (wm *Manager) SetMetadataOnly(fn func(chatJID string) bool) {
+	wm.delivery.metadataOnly = fn
+}
+
+// LoadRoutingProfiles loads routing profiles and chat tags from database
+func (wm *Manager) LoadRoutingProfiles() error {
+	profiles, err := wm.messageStore.GetAllRoutingProfiles()
+	if err != nil {
+		return fmt.Errorf("failed to load routing profiles: %v", err)
+	}
+
+	chatTags, err := wm.messageStore.GetAllChatTags()
+	if err != nil {
+		return fmt.Errorf("failed to load chat tags: %v", err)
+	}
+
+	wm.mutex.Lock()
+	defer wm.mutex.Unlock()
+
+	wm.routingProfiles = profiles
+	wm.chatTags = chatTags
+	wm.logger.Infof("Loaded %d routing profiles", len(profiles))
+
+	return nil
+}
+
+// GetRoutingProfiles returns a copy of current routing profiles
+func (wm *Manager) GetRoutingProfiles() []*types.RoutingProfile {
+	wm.mutex.RLock()
+	defer wm.mutex.RUnlock()
+
+	profiles := make([]*types.RoutingProfile, len(wm.routingProfiles))
+	copy(profiles, wm.routingProfiles)
+	return profiles
+}
+
+// routeFilter returns a predicate reporting whether a webhook may fire for chatJID
+// under the loaded routing profiles. Profiles only route webhooks of their own
+// tenant, using that tenant's chat tags. Caller must hold wm.mutex.
+func (wm *Manager) routeFilter(chatJID string) func(config *types.WebhookConfig) bool {
+	if len(wm.routingProfiles) == 0 {
+		return func(*types.WebhookConfig) bool { return true }
+	}
+
+	routed := make(map[int]bool)       // webhooks owned by any enabled profile
+	allowed := make(map[int]bool)      // webhooks of profiles attached to this chat
+	exclusive := make(map[string]bool) // tenants with an exclusive profile attached to this chat
+
+	for _, profile := range wm.routingProfiles {
+		if !profile.Enabled {
+			continue
+		}
+		for _, id := range profile.WebhookIDs {
+			routed[id] = true
+		}
+
+		owner := tenant.Owner(profile.Tenant)
+		attached := false
+		for _, jid := range profile.ChatJIDs {
+			if jid == chatJID {
+				attached = true
+				break
+			}
+		}
+		for _, tag := range profile.Tags {
+			if attached || hasTag(wm.chatTags[owner][chatJID], tag) {
+				attached = true
+				break
+			}
+		}
+		if !attached {
+			continue
+		}
+
+		for _, id := range profile.WebhookIDs {
+			allowed[id] = true
+		}
+		if profile.Exclusive {
+			exclusive[owner] = true
+		}
+	}
+
+	return func(config *types.WebhookConfig) bool {
+		if allowed[config.ID] {
+			return true
+		}
+		return !routed[config.ID] && !exclusive[tenant.Owner(config.Tenant)]
+	}
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
 // GetWebhookConfigs returns a copy of current webhook configurations
 func (wm *Manager) GetWebhookConfigs() []*types.WebhookConfig {
 	wm.mutex.RLock()
@@ -80,30 +284,83 @@ func (wm *Manager) MatchesTriggers(msg *events.Message, chatName string) []*type
 	// Extract message content
 	content := whatsapp.ExtractTextContent(msg.Message)
 	mediaType, _, _, _, _, _, _ := whatsapp.ExtractMediaInfo(msg.Message)
+	routeAllowed := wm.routeFilter(msg.Info.Chat.String())
 
 	for _, config := range wm.configs {
-		if !config.Enabled {
+		if !config.Enabled || !routeAllowed(config) {
 			continue
 		}
 
-		matched := false
-		for _, trigger := range config.Triggers {
-			if !trigger.Enabled {
-				continue
-			}
+		if wm.matchConfig(config, msg, content, mediaType, chatName) != nil {
+			matchedConfigs = append(matchedConfigs, config)
+		}
+	}
 
-			if wm.matchesTrigger(trigger, msg, content, mediaType, chatName) {
-				matched = true
-				break
-			}
+	return matchedConfigs
+}
+
+// matchConfig returns the trigger that makes config fire for msg, or nil.
+// A filter expression, when set, must evaluate to true; a config with an
+// expression and no enabled triggers fires on the expression alone and is
+// reported with a synthetic "expression" trigger. Caller must hold wm.mutex.
+func (wm *Manager) matchConfig(config *types.WebhookConfig, msg *events.Message, content, mediaType, chatName string) *types.WebhookTrigger {
+	return wm.matchConfigWith(config, wm.expressions[config.ID], msg, content, mediaType, chatName)
+}
+
+// matchConfigWith is matchConfig with the config's compiled filter
+// expression given rather than looked up
+func (wm *Manager) matchConfigWith(config *types.WebhookConfig, expr *Expression, msg *events.Message, content, mediaType, chatName string) *types.WebhookTrigger {
+	if expr != nil {
+		ok, err := expr.Eval(MessageExpressionEnv(msg, content, mediaType, chatName))
+		if err != nil {
+			wm.logger.Warnf("Webhook %d filter expression failed: %v", config.ID, err)
+			return nil
+		}
+		if !ok {
+			return nil
 		}
+	}
 
-		if matched {
-			matchedConfigs = append(matchedConfigs, config)
+	hasTriggers := false
+	for i := range config.Triggers {
+		trigger := config.Triggers[i]
+		if !trigger.Enabled || isEventTrigger(trigger.TriggerType) {
+			continue
+		}
+		hasTriggers = true
+		if wm.matchesTrigger(trigger, msg, content, mediaType, chatName) {
+			return &trigger
 		}
 	}
 
-	return matchedConfigs
+	if !hasTriggers && expr != nil {
+		return &types.WebhookTrigger{
+			WebhookConfigID: config.ID,
+			TriggerType:     "expression",
+			TriggerValue:    config.FilterExpression,
+			MatchType:       "expression",
+			Enabled:         true,
+		}
+	}
+
+	return nil
+}
+
+// MessageExpressionEnv exposes a message to filter expressions
+func MessageExpressionEnv(msg *events.Message, content, mediaType, chatName string) ExpressionEnv {
+	return ExpressionEnv{
+		"content":        content,
+		"media_type":     mediaType,
+		"is_from_me":     msg.Info.IsFromMe,
+		"chat.jid":       msg.Info.Chat.String(),
+		"chat.name":      chatName,
+		"chat.is_group":  msg.Info.Chat.Server == "g.us",
+		"sender.jid":     msg.Info.Sender.String(),
+		"sender.user":    msg.Info.Sender.User,
+		"sender.name":    msg.Info.PushName,
+		"message.id":     msg.Info.ID,
+		"message.length": len([]rune(content)),
+	}
 }
 
 // matchesTrigger checks if a single trigger matches the message
@@ -127,12 +384,56 @@ func (wm *Manager) matchesTrigger(trigger types.WebhookTrigger, msg *events.Mess
 	case "media_type":
 		return wm.matchesString(mediaType, trigger.TriggerValue, trigger.MatchType)
 
+	case "message_rate":
+		return wm.messageRate(trigger, msg) != nil
+
+	case TriggerSendFailed, TriggerOrderReceived:
+		return false
+
 	default:
 		wm.logger.Warnf("Unknown trigger type: %s", trigger.TriggerType)
 		return false
 	}
 }
 
+// messageRate returns the rate of the message's chat or sender, as the
+// trigger's match type says, when the message takes it over the trigger's
+// count/window (e.g. "50/5m"), or nil when it does not. The trigger fires
+// once as the rate goes over and, for the same chat or sender, not again
+// until it has dropped back and a window has passed. Our own messages
+// never match.
+func (wm *Manager) messageRate(trigger types.WebhookTrigger, msg *events.Message) *types.MessageRate {
+	rate, limit, window, err := wm.currentRate(trigger, msg)
+	if err != nil {
+		wm.logger.Warnf("Invalid message_rate trigger %q: %v", trigger.TriggerValue, err)
+		return nil
+	}
+	if rate == nil || !wm.rateAlerts.fire(trigger, rate.JID, msg, rate.Count > limit, window, time.Now()) {
+		return nil
+	}
+	return rate
+}
+
+// currentRate returns the rate a message_rate trigger watches for the
+// message, with the trigger's threshold, or a nil rate when the trigger
+// does not apply to the message
+func (wm *Manager) currentRate(trigger types.WebhookTrigger, msg *events.Message) (*types.MessageRate, int, time.Duration, error) {
+	if wm.rates == nil || msg.Info.IsFromMe {
+		return nil, 0, 0, nil
+	}
+	limit, window, err := msgrate.ParseThreshold(trigger.TriggerValue)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	jid := msg.Info.Chat.ToNonAD().String()
+	if trigger.MatchType == msgrate.ScopeSender {
+		jid = msg.Info.Sender.ToNonAD().String()
+	}
+	rate := wm.rates.Rate(trigger.MatchType, jid, window)
+	return &rate, limit, window, nil
+}
+
 // matchesString performs string matching based on match type
 func (wm *Manager) matchesString(text, pattern, matchType string) bool {
 	switch matchType {
@@ -156,13 +457,16 @@ func (wm *Manager) matchesString(text, pattern, matchType string) bool {
 	}
 }
 
-// ProcessMessage processes a message and sends webhooks if triggers match
-func (wm *Manager) ProcessMessage(client interface{}, msg *events.Message, chatName string) {
+// ProcessMessage processes a message and sends webhooks if triggers match.
+// dispatchID is the record kept with the message that its webhooks are still
+// to be sent (0 when there is none); it is removed once they are queued.
+func (wm *Manager) ProcessMessage(client interface{}, msg *events.Message, chatName string, dispatchID int64) {
 	startTime := time.Now()
 
 	// Find matching webhook configurations
 	matchedConfigs := wm.MatchesTriggers(msg, chatName)
 	if len(matchedConfigs) == 0 {
+		wm.enqueue(dispatchID, nil)
 		return
 	}
 
@@ -179,28 +483,48 @@ func (wm *Manager) ProcessMessage(client interface{}, msg *events.Message, chatN
 	// Build base payload
 	basePayload := types.WebhookPayload{
 		EventType: "message_received",
-		Timestamp: msg.Info.Timestamp.Format(time.RFC3339),
+		Timestamp: msg.Info.Timestamp.UTC().Format(time.RFC3339),
 		Message: types.WebhookMessageInfo{
 			ID:         msg.Info.ID,
+			Ref:        msgref.Encode(msg.Info.Chat.String(), msg.Info.ID),
 			ChatJID:    msg.Info.Chat.String(),
 			ChatName:   chatName,
 			Sender:     msg.Info.Sender.String(),
 			SenderName: senderName,
 			Content:    content,
-			Timestamp:  msg.Info.Timestamp.Format(time.RFC3339),
+			Timestamp:  msg.Info.Timestamp.UTC().Format(time.RFC3339),
 			PushName:   msg.Info.PushName,
 			IsFromMe:   msg.Info.IsFromMe,
 			MediaType:  mediaType,
 			Filename:   filename,
+			Context:    whatsapp.ExtractMessageContext(msg.Message, msg.Info.Chat.String()),
+			Reaction:   whatsapp.ExtractReaction(msg),
 		},
 		Metadata: types.WebhookMetadata{
 			ProcessingTimeMs: time.Since(startTime).Milliseconds(),
 		},
 	}
 
+	// Our own messages carry how far they have got
+	if msg.Info.IsFromMe {
+		if sent, err := wm.messageStore.GetOutgoingMessage(msg.Info.ID); err != nil {
+			wm.logger.Warnf("Failed to load send status of %s: %v", msg.Info.ID, err)
+		} else if sent != nil {
+			basePayload.Message.Receipt = outgoingReceipt(sent)
+		}
+	}
+
 	// Add media download URL if it's a media message
 	if mediaType != "" {
-		basePayload.Message.MediaDownloadURL = "http://localhost:8080/api/download"
+		basePayload.Message.MediaDownloadURL = wm.mediaDownloadURL(msg.Info.Chat.String(), msg.Info.ID)
+
+		// Include enrichment results such as a voice note transcript
+		annotations, err := wm.messageStore.GetAnnotations(msg.Info.Chat.String(), msg.Info.ID)
+		if err != nil {
+			wm.logger.Warnf("Failed to load annotations for %s: %v", msg.Info.ID, err)
+		} else if len(annotations) > 0 {
+			basePayload.Message.Annotations = annotations
+		}
 	}
 
 	// Add group info if it's a group chat
@@ -213,18 +537,12 @@ func (wm *Manager) ProcessMessage(client interface{}, msg *events.Message, chatN
 	}
 
 	// Send webhooks for each matched configuration
+	var queued []queuedDelivery
 	for _, config := range matchedConfigs {
 		// Find the specific trigger that matched
-		var matchedTrigger *types.WebhookTrigger
-		content := whatsapp.ExtractTextContent(msg.Message)
-		mediaType, _, _, _, _, _, _ := whatsapp.ExtractMediaInfo(msg.Message)
-
-		for _, trigger := range config.Triggers {
-			if trigger.Enabled && wm.matchesTrigger(trigger, msg, content, mediaType, chatName) {
-				matchedTrigger = &trigger
-				break
-			}
-		}
+		wm.mutex.RLock()
+		matchedTrigger := wm.matchConfig(config, msg, content, mediaType, chatName)
+		wm.mutex.RUnlock()
 
 		if matchedTrigger == nil {
 			continue
@@ -242,8 +560,414 @@ func (wm *Manager) ProcessMessage(client interface{}, msg *events.Message, chatN
 			MatchType: matchedTrigger.MatchType,
 		}
 		payload.Metadata.DeliveryAttempt = 1
+		if matchedTrigger.TriggerType == "message_rate" {
+			payload.Metadata.Rate = wm.messageRate(*matchedTrigger, msg)
+		}
 
-		// Send webhook asynchronously
-		go wm.delivery.DeliverWebhook(config, &payload, msg.Info.ID, msg.Info.Chat.String(), matchedTrigger)
+		queued = append(queued, queuedDelivery{config: config, trigger: *matchedTrigger, payload: payload})
+	}
+
+	// Send webhooks asynchronously, in order with the chat's earlier messages
+	wm.enqueue(dispatchID, queued)
+}
+
+// eventMatch is a webhook trigger subscribed to a bridge event
+type eventMatch struct {
+	config  *types.WebhookConfig
+	trigger types.WebhookTrigger
+}
+
+// eventMatches returns the enabled webhooks with an enabled trigger of the
+// given event type that may fire for the chat. A non-empty owner limits the
+// event to webhooks that tenant is allowed to see.
+func (wm *Manager) eventMatches(triggerType, chatJID, owner string) []eventMatch {
+	wm.mutex.RLock()
+	defer wm.mutex.RUnlock()
+
+	routeAllowed := wm.routeFilter(chatJID)
+	var matches []eventMatch
+	for _, config := range wm.configs {
+		if !config.Enabled || !routeAllowed(config) {
+			continue
+		}
+		if owner != "" && !tenant.Visible(tenant.Owner(config.Tenant), owner) {
+			continue
+		}
+		for _, trigger := range config.Triggers {
+			if trigger.Enabled && trigger.TriggerType == triggerType {
+				matches = append(matches, eventMatch{config, trigger})
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// deliverEvent sends a copy of the payload to each matched webhook
+func (wm *Manager) deliverEvent(matches []eventMatch, basePayload types.WebhookPayload) {
+	var queued []queuedDelivery
+	for _, m := range matches {
+		payload := basePayload
+		payload.WebhookConfig = types.WebhookConfigInfo{
+			ID:   m.config.ID,
+			Name: m.config.Name,
+		}
+		payload.Trigger = types.WebhookTriggerInfo{
+			Type:      m.trigger.TriggerType,
+			Value:     m.trigger.TriggerValue,
+			MatchType: m.trigger.MatchType,
+		}
+
+		queued = append(queued, queuedDelivery{config: m.config, trigger: m.trigger, payload: payload})
+	}
+	wm.enqueue(0, queued)
+}
+
+// mediaDownloadURL links to a stored message's media, or is empty when the
+// media cannot be downloaded, e.g. when only the message's metadata is kept
+func (wm *Manager) mediaDownloadURL(chatJID, id string) string {
+	media, err := wm.messageStore.GetMessageMedia(chatJID, id)
+	if err != nil {
+		wm.logger.Warnf("Failed to load media of %s: %v", id, err)
+		return ""
+	}
+	if media == nil {
+		return ""
+	}
+	return wm.publicURL + "/api/download?message_ref=" + url.QueryEscape(msgref.Encode(chatJID, id))
+}
+
+// ProcessSendFailure delivers a send_failed event to webhooks with an enabled
+// send_failed trigger that may fire for the message's chat. Only the sending
+// tenant's webhooks, and the operator's, are notified.
+func (wm *Manager) ProcessSendFailure(msg *types.OutgoingMessage) {
+	matches := wm.eventMatches(TriggerSendFailed, msg.ChatJID, tenant.Owner(msg.Tenant))
+	if len(matches) == 0 {
+		return
+	}
+
+	wm.deliverEvent(matches, types.WebhookPayload{
+		EventType: "send_failed",
+		Timestamp: msg.UpdatedAt.UTC().Format(time.RFC3339),
+		Message: types.WebhookMessageInfo{
+			ID:        msg.MessageID,
+			ChatJID:   msg.ChatJID,
+			Content:   msg.Content,
+			Timestamp: msg.CreatedAt.UTC().Format(time.RFC3339),
+			IsFromMe:  true,
+			Receipt:   outgoingReceipt(msg),
+		},
+		Metadata: types.WebhookMetadata{
+			SendError: msg.Error,
+			Tenant:    msg.Tenant,
+			Origin:    msg.Origin,
+		},
+	})
+}
+
+// ProcessMessageSent delivers a message_sent event to webhooks with an enabled
+// message_sent trigger that may fire for the message's chat, once the server
+// has accepted a message sent through the bridge. Only the sending tenant's
+// webhooks, and the operator's, are notified.
+func (wm *Manager) ProcessMessageSent(msg *types.OutgoingMessage) {
+	matches := wm.eventMatches(TriggerMessageSent, msg.ChatJID, tenant.Owner(msg.Tenant))
+	if len(matches) == 0 {
+		return
+	}
+
+	wm.deliverEvent(matches, types.WebhookPayload{
+		EventType: "message_sent",
+		Timestamp: msg.UpdatedAt.UTC().Format(time.RFC3339),
+		Message: types.WebhookMessageInfo{
+			ID:        msg.MessageID,
+			Ref:       msgref.Encode(msg.ChatJID, msg.MessageID),
+			ChatJID:   msg.ChatJID,
+			Content:   msg.Content,
+			Timestamp: msg.CreatedAt.UTC().Format(time.RFC3339),
+			IsFromMe:  true,
+			Receipt:   outgoingReceipt(msg),
+		},
+		Metadata: types.WebhookMetadata{
+			Tenant: msg.Tenant,
+			Origin: msg.Origin,
+		},
+	})
+}
+
+// ProcessOrder delivers an order_received event to webhooks with an enabled
+// order_received trigger that may fire for the order's chat
+func (wm *Manager) ProcessOrder(order *types.CommerceMessage) {
+	matches := wm.eventMatches(TriggerOrderReceived, order.ChatJID, "")
+	if len(matches) == 0 {
+		return
+	}
+
+	wm.deliverEvent(matches, types.WebhookPayload{
+		EventType: "order_received",
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Message: types.WebhookMessageInfo{
+			ID:        order.MessageID,
+			ChatJID:   order.ChatJID,
+			Sender:    order.SenderJID,
+			Content:   order.Note,
+			Timestamp: order.Timestamp.UTC().Format(time.RFC3339),
+		},
+		Metadata: types.WebhookMetadata{
+			Commerce: order,
+		},
+	})
+}
+
+// ProcessAccountRestriction delivers an account_restricted event to webhooks
+// with an enabled account_restricted trigger when WhatsApp bans or rate
+// limits the account. The event concerns no chat, so routing profiles only
+// hold it back from webhooks they route exclusively.
+func (wm *Manager) ProcessAccountRestriction(r types.AccountRestriction) {
+	matches := wm.eventMatches(TriggerAccountRestricted, "", "")
+	if len(matches) == 0 {
+		return
+	}
+
+	wm.deliverEvent(matches, types.WebhookPayload{
+		EventType: "account_restricted",
+		Timestamp: r.Since.UTC().Format(time.RFC3339),
+		Metadata: types.WebhookMetadata{
+			Restriction: &r,
+		},
+	})
+}
+
+// ProcessEventLag delivers an event_lag_high event to webhooks with an
+// enabled event_lag_high trigger when the bridge falls behind on handling
+// WhatsApp events. Like account_restricted it concerns no chat.
+func (wm *Manager) ProcessEventLag(q types.ConnectionQuality) {
+	matches := wm.eventMatches(TriggerEventLag, "", "")
+	if len(matches) == 0 {
+		return
+	}
+
+	wm.deliverEvent(matches, types.WebhookPayload{
+		EventType: TriggerEventLag,
+		Timestamp: q.SampledAt.UTC().Format(time.RFC3339),
+		Metadata: types.WebhookMetadata{
+			Quality: &q,
+		},
+	})
+}
+
+// ProcessDiskSpaceLow delivers a disk_space_low event to webhooks with an
+// enabled disk_space_low trigger when free space on the store volume falls
+// below the threshold and the bridge pauses media downloads and history sync
+func (wm *Manager) ProcessDiskSpaceLow(status types.DiskStatus) {
+	matches := wm.eventMatches(TriggerDiskSpaceLow, "", "")
+	if len(matches) == 0 {
+		return
+	}
+
+	wm.deliverEvent(matches, types.WebhookPayload{
+		EventType: TriggerDiskSpaceLow,
+		Timestamp: status.CheckedAt.UTC().Format(time.RFC3339),
+		Metadata: types.WebhookMetadata{
+			Disk: &status,
+		},
+	})
+}
+
+// ProcessBlocklistChange delivers a contact_blocked or contact_unblocked event
+// to webhooks with the matching enabled trigger that may fire for the contact's chat
+func (wm *Manager) ProcessBlocklistChange(change types.BlocklistChange) {
+	trigger := TriggerContactBlocked
+	if change.Action == database.BlocklistUnblock {
+		trigger = TriggerContactUnblocked
+	}
+
+	matches := wm.eventMatches(trigger, change.JID, "")
+	if len(matches) == 0 {
+		return
+	}
+
+	wm.deliverEvent(matches, types.WebhookPayload{
+		EventType: trigger,
+		Timestamp: change.ChangedAt.UTC().Format(time.RFC3339),
+		Message: types.WebhookMessageInfo{
+			ChatJID:   change.JID,
+			Timestamp: change.ChangedAt.UTC().Format(time.RFC3339),
+		},
+	})
+}
+
+// ProcessPairingCode delivers a pairing_code_generated event to webhooks with
+// an enabled pairing_code_generated trigger whenever a phone pairing code is
+// issued or renewed, so the code can reach whoever is linking the device.
+// The code grants access to the account, so only the operator's webhooks
+// are notified.
+func (wm *Manager) ProcessPairingCode(code types.PairingCode) {
+	matches := wm.eventMatches(TriggerPairingCode, "", tenant.Default)
+	if len(matches) == 0 {
+		return
+	}
+
+	wm.deliverEvent(matches, types.WebhookPayload{
+		EventType: TriggerPairingCode,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Metadata: types.WebhookMetadata{
+			Pairing: &code,
+		},
+	})
+}
+
+// ProcessMessagePin delivers a message_pinned or message_unpinned event to
+// webhooks with the matching trigger that may fire for the chat, whoever in
+// the chat made the change
+func (wm *Manager) ProcessMessagePin(pin *types.PinnedMessage, unpinned bool) {
+	event := TriggerMessagePinned
+	if unpinned {
+		event = TriggerMessageUnpinned
+	}
+	matches := wm.eventMatches(event, pin.ChatJID, "")
+	if len(matches) == 0 {
+		return
+	}
+
+	wm.deliverEvent(matches, types.WebhookPayload{
+		EventType: event,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Message: types.WebhookMessageInfo{
+			ID:        pin.MessageID,
+			ChatJID:   pin.ChatJID,
+			Sender:    pin.SenderJID,
+			Timestamp: pin.PinnedAt.UTC().Format(time.RFC3339),
+		},
+		Metadata: types.WebhookMetadata{
+			Pin: pin,
+		},
+	})
+}
+
+// ProcessMessageReaction delivers a message_reaction event to webhooks with
+// an enabled message_reaction trigger that may fire for the chat, whenever
+// someone in it reacts to a message or takes their reaction back
+func (wm *Manager) ProcessMessageReaction(reaction *types.MessageReaction) {
+	matches := wm.eventMatches(TriggerMessageReaction, reaction.ChatJID, "")
+	if len(matches) == 0 {
+		return
+	}
+
+	wm.deliverEvent(matches, types.WebhookPayload{
+		EventType: TriggerMessageReaction,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Message: types.WebhookMessageInfo{
+			ID:        reaction.MessageID,
+			ChatJID:   reaction.ChatJID,
+			Sender:    reaction.SenderJID,
+			Timestamp: reaction.ReactedAt.UTC().Format(time.RFC3339),
+			Reaction:  reaction,
+		},
+	})
+}
+
+// ProcessMessageAnnotation delivers a message_annotated event, carrying text
+// extracted from a message's media such as a voice note transcript, to
+// webhooks with an enabled message_annotated trigger that may fire for the
+// chat. The message's own webhooks do not wait for it; this follows them.
+func (wm *Manager) ProcessMessageAnnotation(a types.MessageAnnotation) {
+	matches := wm.eventMatches(TriggerMessageAnnotated, a.ChatJID, "")
+	if len(matches) == 0 {
+		return
+	}
+
+	wm.deliverEvent(matches, types.WebhookPayload{
+		EventType: TriggerMessageAnnotated,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Message: types.WebhookMessageInfo{
+			ID:          a.MessageID,
+			Ref:         msgref.Encode(a.ChatJID, a.MessageID),
+			ChatJID:     a.ChatJID,
+			Sender:      a.Sender,
+			Timestamp:   a.Timestamp.UTC().Format(time.RFC3339),
+			Annotations: map[string]string{a.Kind: a.Text},
+		},
+	})
+}
+
+// ProcessGroupChange delivers a group_subject_changed,
+// group_description_changed, group_picture_changed or group_settings_changed
+// event, with the old and new values, to webhooks with the matching trigger
+// that may fire for the group
+func (wm *Manager) ProcessGroupChange(change types.GroupChange) {
+	event := TriggerGroupSettings
+	switch change.Field {
+	case whatsapp.GroupFieldSubject:
+		event = TriggerGroupSubject
+	case whatsapp.GroupFieldDescription:
+		event = TriggerGroupDescription
+	case whatsapp.GroupFieldPicture:
+		event = TriggerGroupPicture
+	}
+	matches := wm.eventMatches(event, change.GroupJID, "")
+	if len(matches) == 0 {
+		return
+	}
+
+	wm.deliverEvent(matches, types.WebhookPayload{
+		EventType: event,
+		Timestamp: change.ChangedAt.UTC().Format(time.RFC3339),
+		Message: types.WebhookMessageInfo{
+			ChatJID:   change.GroupJID,
+			Sender:    change.ChangedBy,
+			Timestamp: change.ChangedAt.UTC().Format(time.RFC3339),
+		},
+		Metadata: types.WebhookMetadata{
+			GroupChange: &change,
+		},
+	})
+}
+
+// DeliverSelfTest sends a selftest event for a canary message to each webhook
+// with an enabled selftest trigger and waits for the results. Unlike other
+// events there are no retries: the point is to see whether delivery works now.
+func (wm *Manager) DeliverSelfTest(msg types.WebhookMessageInfo) []types.SelfTestDelivery {
+	matches := wm.eventMatches(TriggerSelfTest, msg.ChatJID, "")
+	results := make([]types.SelfTestDelivery, len(matches))
+
+	var wg sync.WaitGroup
+	for i, m := range matches {
+		payload := renderPayload(types.WebhookPayload{
+			EventType: TriggerSelfTest,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			WebhookConfig: types.WebhookConfigInfo{
+				ID:   m.config.ID,
+				Name: m.config.Name,
+			},
+			Trigger: types.WebhookTriggerInfo{
+				Type: m.trigger.TriggerType,
+			},
+			Message:  msg,
+			Metadata: types.WebhookMetadata{DeliveryAttempt: 1},
+		}, m.config.PayloadVersion)
+		results[i] = types.SelfTestDelivery{WebhookID: m.config.ID, Name: m.config.Name}
+
+		if err := fitPayload(m.config, &payload); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		body, contentType, err := encodePayload(m.config, &payload)
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+
+		wg.Add(1)
+		config, result := m.config, &results[i]
+		recovery.Go(fmt.Sprintf("webhook %d selftest", config.ID), func() {
+			defer wg.Done()
+			success, status, response := wm.delivery.sendHTTPRequest(config, body, contentType)
+			result.Success, result.StatusCode = success, status
+			if !success {
+				result.Error = response
+			}
+		})
 	}
+	wg.Wait()
+	return results
 }
@@ -0,0 +1,427 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	waTypes "go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	waLog "go.mau.fi/whatsmeow/util/log"
+
+	"whatsapp-bridge/internal/database"
+	"whatsapp-bridge/internal/msgrate"
+	"whatsapp-bridge/internal/redact"
+	"whatsapp-bridge/internal/tenant"
+	"whatsapp-bridge/internal/types"
+)
+
+func TestRouteFilter(t *testing.T) {
+	wm := &Manager{
+		routingProfiles: []*types.RoutingProfile{
+			{ID: 1, Name: "vip", Enabled: true, Exclusive: true, WebhookIDs: []int{10}, ChatJIDs: []string{"vip@g.us"}},
+			{ID: 2, Name: "sales", Enabled: true, WebhookIDs: []int{20}, Tags: []string{"sales"}},
+			{ID: 3, Name: "off", Enabled: false, WebhookIDs: []int{30}, ChatJIDs: []string{"other@g.us"}},
+		},
+		chatTags: map[string]map[string][]string{
+			"default": {"lead@s.whatsapp.net": {"sales"}},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		chatJID   string
+		webhookID int
+		want      bool
+	}{
+		{"vip chat gets vip webhook", "vip@g.us", 10, true},
+		{"vip chat is exclusive", "vip@g.us", 99, false},
+		{"vip chat skips other profile", "vip@g.us", 20, false},
+		{"tagged chat gets tag webhook", "lead@s.whatsapp.net", 20, true},
+		{"tagged chat keeps unrouted webhook", "lead@s.whatsapp.net", 99, true},
+		{"tagged chat skips vip webhook", "lead@s.whatsapp.net", 10, false},
+		{"plain chat gets unrouted webhook", "other@g.us", 99, true},
+		{"plain chat skips routed webhook", "other@g.us", 10, false},
+		{"disabled profile does not route", "other@g.us", 30, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := wm.routeFilter(tt.chatJID)(&types.WebhookConfig{ID: tt.webhookID})
+			if got != tt.want {
+				t.Errorf("routeFilter(%s)(%d) = %v, want %v", tt.chatJID, tt.webhookID, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouteFilterTenants(t *testing.T) {
+	wm := &Manager{
+		routingProfiles: []*types.RoutingProfile{
+			{ID: 1, Name: "support-vip", Enabled: true, Exclusive: true, Tenant: "support", WebhookIDs: []int{10}, Tags: []string{"vip"}},
+			{ID: 2, Name: "sales-leads", Enabled: true, Tenant: "sales", WebhookIDs: []int{20}, Tags: []string{"lead"}},
+		},
+		chatTags: map[string]map[string][]string{
+			"support": {"a@s.whatsapp.net": {"vip"}},
+			"sales":   {"b@s.whatsapp.net": {"vip", "lead"}},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		chatJID string
+		config  types.WebhookConfig
+		want    bool
+	}{
+		{"own tag attaches profile", "a@s.whatsapp.net", types.WebhookConfig{ID: 10, Tenant: "support"}, true},
+		{"exclusive profile blocks own tenant", "a@s.whatsapp.net", types.WebhookConfig{ID: 11, Tenant: "support"}, false},
+		{"exclusive profile leaves other tenants alone", "a@s.whatsapp.net", types.WebhookConfig{ID: 21, Tenant: "sales"}, true},
+		{"other tenant's tag does not attach", "b@s.whatsapp.net", types.WebhookConfig{ID: 10, Tenant: "support"}, false},
+		{"tenant tag attaches its profile", "b@s.whatsapp.net", types.WebhookConfig{ID: 20, Tenant: "sales"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := wm.routeFilter(tt.chatJID)(&tt.config); got != tt.want {
+				t.Errorf("routeFilter(%s)(%d) = %v, want %v", tt.chatJID, tt.config.ID, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeliverSelfTest(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload types.WebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.EventType != TriggerSelfTest {
+			http.Error(w, "bad payload", http.StatusBadRequest)
+		}
+	}))
+	defer ok.Close()
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusBadGateway)
+	}))
+	defer broken.Close()
+
+	selftest := []types.WebhookTrigger{{TriggerType: TriggerSelfTest, Enabled: true}}
+	wm := &Manager{
+		logger:   waLog.Noop,
+		delivery: &DeliveryService{httpClient: &http.Client{Timeout: 5 * time.Second}, logger: waLog.Noop},
+		configs: []*types.WebhookConfig{
+			{ID: 1, Enabled: true, WebhookURL: ok.URL, Triggers: selftest},
+			{ID: 2, Enabled: true, WebhookURL: broken.URL, Triggers: selftest},
+			{ID: 3, Enabled: false, WebhookURL: ok.URL, Triggers: selftest},
+			{ID: 4, Enabled: true, WebhookURL: ok.URL, Triggers: []types.WebhookTrigger{{TriggerType: "all", Enabled: true}}},
+		},
+	}
+
+	results := wm.DeliverSelfTest(types.WebhookMessageInfo{ID: "ABC", ChatJID: "1@s.whatsapp.net"})
+	if len(results) != 2 {
+		t.Fatalf("delivered to %d webhooks, want 2: %+v", len(results), results)
+	}
+	if !results[0].Success || results[0].WebhookID != 1 {
+		t.Errorf("results[0] = %+v, want success for webhook 1", results[0])
+	}
+	if results[1].Success || results[1].StatusCode != http.StatusBadGateway {
+		t.Errorf("results[1] = %+v, want a 502 failure", results[1])
+	}
+}
+
+func TestPairingCodeOperatorOnly(t *testing.T) {
+	pairing := []types.WebhookTrigger{{TriggerType: TriggerPairingCode, Enabled: true}}
+	wm := &Manager{
+		configs: []*types.WebhookConfig{
+			{ID: 1, Enabled: true, Triggers: pairing},
+			{ID: 2, Enabled: true, Tenant: "default", Triggers: pairing},
+			{ID: 3, Enabled: true, Tenant: "support", Triggers: pairing},
+			{ID: 4, Enabled: true, Triggers: []types.WebhookTrigger{{TriggerType: "all", Enabled: true}}},
+		},
+	}
+
+	var ids []int
+	for _, m := range wm.eventMatches(TriggerPairingCode, "", tenant.Default) {
+		ids = append(ids, m.config.ID)
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Errorf("pairing codes go to webhooks %v, want the operator's [1 2]", ids)
+	}
+}
+
+func TestMessageSentSenderOnly(t *testing.T) {
+	sent := []types.WebhookTrigger{{TriggerType: TriggerMessageSent, Enabled: true}}
+	wm := &Manager{
+		configs: []*types.WebhookConfig{
+			{ID: 1, Enabled: true, Triggers: sent},
+			{ID: 2, Enabled: true, Tenant: "support", Triggers: sent},
+			{ID: 3, Enabled: true, Tenant: "sales", Triggers: sent},
+			{ID: 4, Enabled: true, Tenant: "support", Triggers: []types.WebhookTrigger{{TriggerType: TriggerSendFailed, Enabled: true}}},
+		},
+	}
+
+	var ids []int
+	for _, m := range wm.eventMatches(TriggerMessageSent, "1@s.whatsapp.net", tenant.Owner("support")) {
+		ids = append(ids, m.config.ID)
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Errorf("support's sends go to webhooks %v, want the operator's and support's [1 2]", ids)
+	}
+}
+
+func TestTestWebhookSample(t *testing.T) {
+	var got types.WebhookPayload
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer receiver.Close()
+
+	config := &types.WebhookConfig{ID: 1, Enabled: true, WebhookURL: receiver.URL, Triggers: []types.WebhookTrigger{
+		{TriggerType: "keyword", TriggerValue: "invoice", MatchType: "contains", Enabled: true},
+	}}
+	wm := &Manager{
+		logger:   waLog.Noop,
+		delivery: &DeliveryService{httpClient: &http.Client{Timeout: 5 * time.Second}, logger: waLog.Noop},
+	}
+
+	sample := &types.WebhookTestRequest{ChatJID: "120363000000000000@g.us", ChatName: "Billing", Sender: "+1 555 000 0001", Content: "Invoice 12 attached"}
+	matched, err := wm.TestWebhook(config, sample)
+	if err != nil {
+		t.Fatalf("TestWebhook: %v", err)
+	}
+	if matched == nil || matched.TriggerValue != "invoice" {
+		t.Errorf("matched = %+v, want the invoice trigger", matched)
+	}
+	if got.Message.Content != sample.Content || got.Message.Sender != "15550000001@s.whatsapp.net" || got.Trigger.Value != "invoice" {
+		t.Errorf("payload message = %+v, trigger = %+v, want the sample", got.Message, got.Trigger)
+	}
+
+	sample.Content = "lunch?"
+	if matched, err := wm.TestWebhook(config, sample); err != nil || matched != nil {
+		t.Errorf("TestWebhook(lunch) = %+v, %v, want delivered but unmatched", matched, err)
+	}
+
+	if err := wm.ValidateTestRequest(&types.WebhookTestRequest{ChatJID: "120363000000000000@g.us"}); err == nil {
+		t.Error("group sample without a sender accepted")
+	}
+}
+
+func TestMessageRateTrigger(t *testing.T) {
+	t.Setenv("DISABLE_SSRF_CHECK", "true")
+	rates := msgrate.NewTracker()
+	wm := &Manager{logger: waLog.Noop, rates: rates}
+
+	group := waTypes.NewJID("120363000000000000", waTypes.GroupServer)
+	ana := waTypes.NewJID("15550000001", waTypes.DefaultUserServer)
+	msg := &events.Message{Info: waTypes.MessageInfo{
+		MessageSource: waTypes.MessageSource{Chat: group, Sender: ana, IsGroup: true},
+		Timestamp:     time.Now(),
+	}}
+	flood := types.WebhookTrigger{TriggerType: "message_rate", TriggerValue: "3/5m", MatchType: msgrate.ScopeSender, Enabled: true}
+
+	// Fires for the message that takes the rate over, and only that one
+	for i := 1; i <= 5; i++ {
+		msg.Info.ID = fmt.Sprintf("M%d", i)
+		rates.Record(group.String(), ana.String(), msg.Info.Timestamp)
+		if got, want := wm.matchesTrigger(flood, msg, "", "", ""), i == 4; got != want {
+			t.Errorf("after %d messages matched = %v, want %v", i, got, want)
+		}
+	}
+	msg.Info.ID = "M4"
+	if rate := wm.messageRate(flood, msg); rate == nil || rate.Count != 5 || rate.JID != ana.String() {
+		t.Errorf("messageRate of the message it fired for = %+v, want Ana at 5", rate)
+	}
+	if ok, why := wm.explainTrigger(flood, msg, "", ""); !ok || !strings.Contains(why, "5 messages") {
+		t.Errorf("explainTrigger = %v, %q", ok, why)
+	}
+
+	// Back under the threshold and over again: not within the cooldown,
+	// but once a window has passed
+	over := func(id string, count int, at time.Time) bool {
+		msg.Info.ID = id
+		return wm.rateAlerts.fire(flood, ana.String(), msg, count > 3, 5*time.Minute, at)
+	}
+	now := time.Now()
+	if over("M6", 2, now) || over("M7", 4, now.Add(time.Minute)) {
+		t.Error("fired again within the cooldown")
+	}
+	if over("M8", 2, now.Add(10*time.Minute)) || !over("M9", 4, now.Add(10*time.Minute)) {
+		t.Error("did not fire on crossing again after the cooldown")
+	}
+
+	for _, tt := range []struct {
+		trigger types.WebhookTrigger
+		wantErr bool
+	}{
+		{flood, false},
+		{types.WebhookTrigger{TriggerType: "message_rate", TriggerValue: "3/5m", MatchType: "contains"}, true},
+		{types.WebhookTrigger{TriggerType: "message_rate", TriggerValue: "lots", MatchType: msgrate.ScopeChat}, true},
+	} {
+		config := types.WebhookConfig{Name: "floods", WebhookURL: "http://127.0.0.1/hook", Triggers: []types.WebhookTrigger{tt.trigger}}
+		if err := wm.ValidateWebhookConfig(&config); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateWebhookConfig(%+v) error = %v, wantErr %v", tt.trigger, err, tt.wantErr)
+		}
+	}
+}
+
+func TestMetadataOnlyPayloadsKeptWithoutContent(t *testing.T) {
+	t.Setenv("DISABLE_SSRF_CHECK", "true")
+	t.Chdir(t.TempDir())
+	store, err := database.NewMessageStore()
+	if err != nil {
+		t.Fatalf("NewMessageStore: %v", err)
+	}
+	defer store.Close()
+
+	release := make(chan struct{})
+	var got types.WebhookPayload
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		_ = json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer receiver.Close()
+
+	wm := NewManager(store, waLog.Noop)
+	wm.SetMetadataOnly(func(chatJID string) bool { return chatJID == "1@s.whatsapp.net" })
+	config := &types.WebhookConfig{Name: "crm", Enabled: true, WebhookURL: receiver.URL}
+	if err := store.StoreWebhookConfig(config); err != nil {
+		t.Fatalf("StoreWebhookConfig: %v", err)
+	}
+	payload := types.WebhookPayload{
+		EventType: "message_received",
+		Message: types.WebhookMessageInfo{
+			ID: "M1", ChatJID: "1@s.whatsapp.net", Content: "my card is 4111", Filename: "card.jpg",
+			Annotations: map[string]string{"transcript": "my card"},
+		},
+		Metadata: types.WebhookMetadata{Commerce: &types.CommerceMessage{Kind: types.CommerceOrder}},
+	}
+	wm.enqueue(0, []queuedDelivery{{config: config, trigger: types.WebhookTrigger{TriggerType: "all"}, payload: payload}})
+
+	queued, err := store.ListWebhookDeliveries()
+	if err != nil || len(queued) != 1 {
+		t.Fatalf("ListWebhookDeliveries = %+v, %v", queued, err)
+	}
+	if m := queued[0].Payload.Message; m.Content != "" || m.Filename != "" || m.Annotations != nil || queued[0].Payload.Metadata.Commerce != nil || m.ID != "M1" {
+		t.Errorf("queued delivery kept content: %+v", queued[0].Payload)
+	}
+	close(release)
+
+	var logs []*types.WebhookLog
+	for deadline := time.Now().Add(5 * time.Second); len(logs) == 0 && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		logs, _ = store.GetWebhookLogs(config.ID, 10)
+	}
+	if len(logs) != 1 || logs[0].DeliveredAt == nil {
+		t.Fatalf("webhook logs = %+v", logs)
+	}
+	if strings.Contains(logs[0].Payload, "4111") || strings.Contains(logs[0].Payload, "card.jpg") || !strings.Contains(logs[0].Payload, "M1") {
+		t.Errorf("logged payload kept content: %s", logs[0].Payload)
+	}
+	// The receiver still gets the message as it was
+	if got.Message.Content != "my card is 4111" {
+		t.Errorf("delivered content = %q", got.Message.Content)
+	}
+}
+
+func TestPairingCodeNotKept(t *testing.T) {
+	t.Setenv("DISABLE_SSRF_CHECK", "true")
+	t.Chdir(t.TempDir())
+	store, err := database.NewMessageStore()
+	if err != nil {
+		t.Fatalf("NewMessageStore: %v", err)
+	}
+	defer store.Close()
+
+	release := make(chan struct{})
+	var got types.WebhookPayload
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		_ = json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer receiver.Close()
+
+	wm := NewManager(store, waLog.Noop)
+	config := &types.WebhookConfig{Name: "pairing", Enabled: true, WebhookURL: receiver.URL}
+	if err := store.StoreWebhookConfig(config); err != nil {
+		t.Fatalf("StoreWebhookConfig: %v", err)
+	}
+	payload := types.WebhookPayload{
+		EventType: TriggerPairingCode,
+		Metadata:  types.WebhookMetadata{Pairing: &types.PairingCode{Code: "ABCD-EFGH", PhoneNumber: "15550000001", Attempt: 1}},
+	}
+	wm.enqueue(0, []queuedDelivery{{config: config, trigger: types.WebhookTrigger{TriggerType: TriggerPairingCode}, payload: payload}})
+
+	queued, err := store.ListWebhookDeliveries()
+	if err != nil || len(queued) != 1 {
+		t.Fatalf("ListWebhookDeliveries = %+v, %v", queued, err)
+	}
+	if p := queued[0].Payload.Metadata.Pairing; p == nil || p.Code != redact.Placeholder || p.PhoneNumber != "15550000001" {
+		t.Errorf("queued pairing = %+v, want the code redacted", p)
+	}
+	close(release)
+
+	var logs []*types.WebhookLog
+	for deadline := time.Now().Add(5 * time.Second); len(logs) == 0 && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		logs, _ = store.GetWebhookLogs(config.ID, 10)
+	}
+	if len(logs) != 1 || logs[0].DeliveredAt == nil {
+		t.Fatalf("webhook logs = %+v", logs)
+	}
+	if strings.Contains(logs[0].Payload, "ABCD-EFGH") || !strings.Contains(logs[0].Payload, "15550000001") {
+		t.Errorf("logged payload = %s, want the code redacted", logs[0].Payload)
+	}
+	if got.Metadata.Pairing == nil || got.Metadata.Pairing.Code != "ABCD-EFGH" {
+		t.Errorf("delivered pairing = %+v, want the code", got.Metadata.Pairing)
+	}
+	if payload.Metadata.Pairing.Code != "ABCD-EFGH" {
+		t.Error("redacting changed the caller's pairing code")
+	}
+}
+
+func TestResumedDeliveriesNotCounted(t *testing.T) {
+	t.Setenv("DISABLE_SSRF_CHECK", "true")
+	t.Chdir(t.TempDir())
+	store, err := database.NewMessageStore()
+	if err != nil {
+		t.Fatalf("NewMessageStore: %v", err)
+	}
+	defer store.Close()
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer receiver.Close()
+
+	config := &types.WebhookConfig{
+		Name: "crm", Enabled: true, WebhookURL: receiver.URL,
+		Triggers: []types.WebhookTrigger{{TriggerType: "keyword", TriggerValue: "invoice", MatchType: "contains", Enabled: true}},
+	}
+	if err := store.StoreWebhookConfig(config); err != nil {
+		t.Fatalf("StoreWebhookConfig: %v", err)
+	}
+
+	// A delivery the last run queued, and counted, but did not send
+	left := &types.WebhookDelivery{WebhookConfigID: config.ID, ChatJID: "1@s.whatsapp.net", MessageID: "M1", Trigger: config.Triggers[0], CreatedAt: time.Now()}
+	if err := store.QueueWebhookDeliveries(0, []*types.WebhookDelivery{left}); err != nil {
+		t.Fatalf("QueueWebhookDeliveries: %v", err)
+	}
+
+	wm := NewManager(store, waLog.Noop)
+	if err := wm.LoadWebhookConfigs(); err != nil {
+		t.Fatalf("LoadWebhookConfigs: %v", err)
+	}
+	wm.ResumeDeliveries()
+
+	var logs []*types.WebhookLog
+	for deadline := time.Now().Add(5 * time.Second); len(logs) == 0 && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		logs, _ = store.GetWebhookLogs(config.ID, 10)
+	}
+	if len(logs) != 1 {
+		t.Fatalf("webhook logs = %+v, want the resumed delivery sent", logs)
+	}
+	got, err := store.GetWebhookConfig(config.ID)
+	if err != nil {
+		t.Fatalf("GetWebhookConfig: %v", err)
+	}
+	if got.Triggers[0].MatchCount != 1 {
+		t.Errorf("match count = %d after resuming, want the 1 counted when queued", got.Triggers[0].MatchCount)
+	}
+}
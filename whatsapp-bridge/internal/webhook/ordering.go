@@ -0,0 +1,71 @@
+package webhook
+
+import (
+	"sync"
+
+	"whatsapp-bridge/internal/recovery"
+)
+
+// chatQueue runs deliveries one at a time per key, in the order they were
+// queued, and deliveries for different keys in parallel. Keyed by webhook
+// and chat, it keeps a webhook from seeing a retried older event of a chat
+// after a newer one, while a slow chat does not hold up the others. The zero
+// value is ready to use.
+type chatQueue struct {
+	mu sync.Mutex
+	// waiting holds the deliveries queued behind the running one; a key is
+	// present while a worker is draining it
+	waiting map[string][]func()
+}
+
+// run queues a delivery for key, starting a worker unless one is running
+func (q *chatQueue) run(key, detail string, deliver func()) {
+	q.mu.Lock()
+	if q.waiting == nil {
+		q.waiting = make(map[string][]func())
+	}
+	if queued, busy := q.waiting[key]; busy {
+		q.waiting[key] = append(queued, deliver)
+		q.mu.Unlock()
+		return
+	}
+	q.waiting[key] = nil
+	q.mu.Unlock()
+
+	recovery.Go(detail, func() {
+		for deliver != nil {
+			q.deliver(detail, deliver)
+			deliver = q.next(key)
+		}
+	})
+}
+
+// deliver runs one delivery; a panic is logged and the queue moves on
+func (q *chatQueue) deliver(detail string, deliver func()) {
+	defer recovery.Handle(recovery.SourceJob, detail, nil)
+	deliver()
+}
+
+// next takes the next delivery for key, or releases the key when none is left
+func (q *chatQueue) next(key string) func() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	queued := q.waiting[key]
+	if len(queued) == 0 {
+		delete(q.waiting, key)
+		return nil
+	}
+	q.waiting[key] = queued[1:]
+	return queued[0]
+}
+
+// depth returns the deliveries waiting behind running ones
+func (q *chatQueue) depth() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for _, queued := range q.waiting {
+		n += len(queued)
+	}
+	return n
+}
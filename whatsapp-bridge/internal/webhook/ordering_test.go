@@ -0,0 +1,70 @@
+package webhook
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestChatQueueOrder(t *testing.T) {
+	var q chatQueue
+	var mu sync.Mutex
+	var got []int
+	var wg sync.WaitGroup
+
+	// The first delivery is slow, like one being retried; the later ones
+	// for the same chat must wait for it
+	release := make(chan struct{})
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		q.run("1|chat-a", "test", func() {
+			defer wg.Done()
+			if i == 0 {
+				<-release
+			}
+			mu.Lock()
+			got = append(got, i)
+			mu.Unlock()
+		})
+	}
+
+	// Another chat is not held up by the slow one
+	other := make(chan struct{})
+	q.run("1|chat-b", "test", func() { close(other) })
+	select {
+	case <-other:
+	case <-time.After(time.Second):
+		t.Fatal("delivery for another chat waited for chat-a")
+	}
+
+	if n := q.depth(); n != 4 {
+		t.Errorf("depth = %d, want 4", n)
+	}
+	close(release)
+	wg.Wait()
+
+	for i, v := range got {
+		if v != i {
+			t.Fatalf("deliveries ran as %v, want in order", got)
+		}
+	}
+}
+
+func TestChatQueueSurvivesPanic(t *testing.T) {
+	var q chatQueue
+	done := make(chan struct{})
+	block := make(chan struct{})
+
+	q.run("1|chat-a", "test", func() {
+		<-block
+		panic("delivery failed")
+	})
+	q.run("1|chat-a", "test", func() { close(done) })
+	close(block)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("queue stopped after a panic")
+	}
+}
@@ -0,0 +1,46 @@
+package webhook
+
+import (
+	"time"
+
+	"whatsapp-bridge/internal/types"
+)
+
+// Payload schema versions a webhook can select. Receivers stay on the version
+// they were written for; fields are only added or renamed in a new version.
+const (
+	// PayloadV1 is the original payload
+	PayloadV1 = 1
+	// PayloadV2 adds the message's context, reactions and receipts
+	PayloadV2 = 2
+
+	LatestPayloadVersion = PayloadV2
+)
+
+// renderPayload returns the payload as a webhook on the given schema version
+// receives it
+func renderPayload(payload types.WebhookPayload, version int) types.WebhookPayload {
+	if version == 0 {
+		version = PayloadV1
+	}
+	payload.SchemaVersion = version
+
+	if version < PayloadV2 {
+		payload.Message.Context = nil
+		payload.Message.Receipt = nil
+		// A message_reaction event is about its reaction; other events only
+		// carry it from v2
+		if payload.EventType != TriggerMessageReaction {
+			payload.Message.Reaction = nil
+		}
+	}
+	return payload
+}
+
+// outgoingReceipt returns the acknowledgment state of a message we sent
+func outgoingReceipt(msg *types.OutgoingMessage) *types.WebhookReceipt {
+	return &types.WebhookReceipt{
+		Status:    msg.Status,
+		UpdatedAt: msg.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
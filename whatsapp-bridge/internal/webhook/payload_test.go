@@ -0,0 +1,63 @@
+package webhook
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"whatsapp-bridge/internal/types"
+)
+
+func TestRenderPayload(t *testing.T) {
+	payload := types.WebhookPayload{
+		EventType: "message_received",
+		Message: types.WebhookMessageInfo{
+			ID:       "M2",
+			Context:  &types.MessageContext{Mentions: []string{"111@s.whatsapp.net"}},
+			Reaction: &types.MessageReaction{MessageID: "M1", Emoji: "👍"},
+			Receipt:  &types.WebhookReceipt{Status: "read"},
+		},
+	}
+
+	// Webhooks that never chose a version get v1, without the v2 fields
+	v1, _ := json.Marshal(renderPayload(payload, 0))
+	if !strings.Contains(string(v1), `"schema_version":1`) {
+		t.Errorf("v1 payload = %s, want schema_version 1", v1)
+	}
+	for _, field := range []string{`"context"`, `"reaction"`, `"receipt"`} {
+		if strings.Contains(string(v1), field) {
+			t.Errorf("v1 payload has %s: %s", field, v1)
+		}
+	}
+
+	v2 := renderPayload(payload, PayloadV2)
+	if v2.SchemaVersion != PayloadV2 || v2.Message.Context == nil || v2.Message.Reaction == nil || v2.Message.Receipt == nil {
+		t.Errorf("v2 payload = %+v, want every field", v2)
+	}
+
+	// Rendering v1 leaves the shared payload alone for other webhooks
+	if payload.Message.Reaction == nil {
+		t.Error("rendering v1 stripped the original payload")
+	}
+
+	// message_reaction events keep their reaction on every version
+	payload.EventType = TriggerMessageReaction
+	if v1 := renderPayload(payload, PayloadV1); v1.Message.Reaction == nil || v1.Message.Context != nil {
+		t.Errorf("v1 message_reaction payload = %+v, want the reaction alone", v1.Message)
+	}
+}
+
+func TestValidatePayloadVersion(t *testing.T) {
+	t.Setenv("DISABLE_SSRF_CHECK", "true")
+	wm := &Manager{}
+
+	config := &types.WebhookConfig{Name: "crm", WebhookURL: "http://127.0.0.1/hook"}
+	if err := wm.ValidateWebhookConfig(config); err != nil || config.PayloadVersion != PayloadV1 {
+		t.Errorf("unversioned webhook: err = %v, version = %d, want version 1", err, config.PayloadVersion)
+	}
+
+	config.PayloadVersion = LatestPayloadVersion + 1
+	if err := wm.ValidateWebhookConfig(config); err == nil {
+		t.Error("unknown payload version accepted")
+	}
+}
@@ -0,0 +1,77 @@
+package webhook
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow/types/events"
+
+	"whatsapp-bridge/internal/msgrate"
+	"whatsapp-bridge/internal/types"
+)
+
+// rateAlert is the state of one message_rate trigger for one chat or sender
+type rateAlert struct {
+	over    bool      // the rate was over the threshold when last checked
+	firedID string    // the message the trigger last fired for
+	firedAt time.Time // and when
+}
+
+// rateAlerts makes message_rate triggers fire once when a rate crosses its
+// threshold rather than for every message while it stays over, and then
+// not again for the same chat or sender until the trigger's window has
+// passed. The zero value is ready to use.
+type rateAlerts struct {
+	mu        sync.Mutex
+	alerts    map[string]*rateAlert // trigger and JID -> state
+	lastPrune time.Time
+}
+
+// fire reports whether a trigger fires for msg, given whether the rate it
+// watches is over its threshold. A message checked again, e.g. once to
+// decide whether to keep its webhooks for dispatch and once to send them,
+// gets the same answer.
+func (r *rateAlerts) fire(trigger types.WebhookTrigger, jid string, msg *events.Message, over bool, cooldown time.Duration, now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now.Sub(r.lastPrune) > time.Minute {
+		r.prune(now)
+	}
+
+	key := fmt.Sprintf("%d\x00%d\x00%s\x00%s\x00%s", trigger.WebhookConfigID, trigger.ID, trigger.MatchType, trigger.TriggerValue, jid)
+	alert := r.alerts[key]
+	if alert == nil {
+		if !over {
+			return false
+		}
+		if r.alerts == nil {
+			r.alerts = make(map[string]*rateAlert)
+		}
+		alert = &rateAlert{}
+		r.alerts[key] = alert
+	}
+	if alert.firedID == msg.Info.ID {
+		return true
+	}
+
+	crossed := over && !alert.over
+	alert.over = over
+	if !crossed || (!alert.firedAt.IsZero() && now.Sub(alert.firedAt) < cooldown) {
+		return false
+	}
+	alert.firedID, alert.firedAt = msg.Info.ID, now
+	return true
+}
+
+// prune forgets triggers back under their threshold whose last firing is
+// older than any window. Caller must hold r.mu.
+func (r *rateAlerts) prune(now time.Time) {
+	r.lastPrune = now
+	for key, alert := range r.alerts {
+		if !alert.over && now.Sub(alert.firedAt) > msgrate.MaxWindow {
+			delete(r.alerts, key)
+		}
+	}
+}
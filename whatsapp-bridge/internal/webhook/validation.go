@@ -1,7 +1,6 @@
 package webhook
 
 import (
-	"encoding/json"
 	"fmt"
 	"net"
 	"net/url"
@@ -10,7 +9,16 @@ import (
 	"strings"
 	"time"
 
+	"whatsapp-bridge/internal/msgrate"
+	"whatsapp-bridge/internal/phone"
+	"whatsapp-bridge/internal/secrets"
+	"whatsapp-bridge/internal/tenant"
 	"whatsapp-bridge/internal/types"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	waTypes "go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
 )
 
 // privateIPBlocks contains CIDR ranges for private/reserved IPs
@@ -123,13 +131,66 @@ func (wm *Manager) ValidateWebhookConfig(config *types.WebhookConfig) error {
 		return err
 	}
 
+	// A secret kept in a secret store must be readable before deliveries
+	// are signed with it
+	if secrets.IsReference(config.SecretToken) {
+		if _, err := secrets.Resolve(config.SecretToken); err != nil {
+			return fmt.Errorf("invalid secret token: %v", err)
+		}
+	}
+
+	// Webhooks that do not choose a payload version get the original one
+	if config.PayloadVersion == 0 {
+		config.PayloadVersion = PayloadV1
+	}
+	if config.PayloadVersion < PayloadV1 || config.PayloadVersion > LatestPayloadVersion {
+		return fmt.Errorf("invalid payload version: %d (must be %d to %d)", config.PayloadVersion, PayloadV1, LatestPayloadVersion)
+	}
+
+	switch config.ContentType {
+	case "", ContentTypeJSON, ContentTypeForm, ContentTypeText:
+	default:
+		return fmt.Errorf("invalid content type: %s (must be %s, %s or %s)", config.ContentType, ContentTypeJSON, ContentTypeForm, ContentTypeText)
+	}
+	if config.BodyTemplate != "" && config.ContentType != ContentTypeText {
+		return fmt.Errorf("body template requires content type %s", ContentTypeText)
+	}
+	if len(config.BodyTemplate) > MaxBodyTemplateLength {
+		return fmt.Errorf("body template must be at most %d characters", MaxBodyTemplateLength)
+	}
+
+	if config.MaxContentLength < 0 {
+		return fmt.Errorf("max content length must not be negative")
+	}
+	if config.MaxPayloadBytes != 0 && config.MaxPayloadBytes < MinPayloadBytes {
+		return fmt.Errorf("max payload bytes must be 0 or at least %d", MinPayloadBytes)
+	}
+	switch config.OversizeAction {
+	case "", OversizeReference, OversizeDrop:
+	default:
+		return fmt.Errorf("invalid oversize action: %s (must be %s or %s)", config.OversizeAction, OversizeReference, OversizeDrop)
+	}
+	if config.OversizeAction != "" && config.MaxPayloadBytes == 0 {
+		return fmt.Errorf("oversize action requires max payload bytes")
+	}
+
+	// Validate filter expression
+	if config.FilterExpression != "" {
+		if _, err := CompileExpression(config.FilterExpression); err != nil {
+			return fmt.Errorf("invalid filter expression: %v", err)
+		}
+	}
+
 	// Validate triggers
 	for _, trigger := range config.Triggers {
 		if trigger.TriggerType == "" {
 			return fmt.Errorf("trigger type is required")
 		}
 
-		validTypes := []string{"all", "chat_jid", "sender", "keyword", "media_type"}
+		validTypes := []string{"all", "chat_jid", "sender", "keyword", "media_type", "message_rate",
+			TriggerSendFailed, TriggerMessageSent, TriggerOrderReceived, TriggerAccountRestricted, TriggerContactBlocked, TriggerContactUnblocked,
+			TriggerSelfTest, TriggerPairingCode, TriggerMessagePinned, TriggerMessageUnpinned, TriggerEventLag, TriggerMessageReaction, TriggerDiskSpaceLow, TriggerMessageAnnotated,
+			TriggerGroupSubject, TriggerGroupDescription, TriggerGroupPicture, TriggerGroupSettings}
 		valid := false
 		for _, validType := range validTypes {
 			if trigger.TriggerType == validType {
@@ -141,6 +202,17 @@ func (wm *Manager) ValidateWebhookConfig(config *types.WebhookConfig) error {
 			return fmt.Errorf("invalid trigger type: %s", trigger.TriggerType)
 		}
 
+		// Rate triggers say which count to watch in place of a match type
+		if trigger.TriggerType == "message_rate" {
+			if trigger.MatchType != msgrate.ScopeChat && trigger.MatchType != msgrate.ScopeSender {
+				return fmt.Errorf("invalid match type for message_rate: %s (must be '%s' or '%s')", trigger.MatchType, msgrate.ScopeChat, msgrate.ScopeSender)
+			}
+			if _, _, err := msgrate.ParseThreshold(trigger.TriggerValue); err != nil {
+				return fmt.Errorf("invalid message_rate trigger: %v", err)
+			}
+			continue
+		}
+
 		validMatchTypes := []string{"exact", "contains", "regex"}
 		valid = false
 		for _, validType := range validMatchTypes {
@@ -165,11 +237,54 @@ func (wm *Manager) ValidateWebhookConfig(config *types.WebhookConfig) error {
 	return nil
 }
 
-// TestWebhook sends a test webhook to verify connectivity
-func (wm *Manager) TestWebhook(config *types.WebhookConfig) error {
+// ValidateRoutingProfile validates a routing profile against the loaded webhooks
+func (wm *Manager) ValidateRoutingProfile(profile *types.RoutingProfile) error {
+	if profile.Name == "" {
+		return fmt.Errorf("routing profile name is required")
+	}
+
+	if len(profile.Name) > 255 {
+		return fmt.Errorf("routing profile name must be less than 256 characters")
+	}
+
+	if len(profile.WebhookIDs) == 0 {
+		return fmt.Errorf("routing profile must include at least one webhook")
+	}
+
+	// A profile may only route its own tenant's webhooks
+	known := make(map[int]bool)
+	for _, config := range wm.GetWebhookConfigs() {
+		known[config.ID] = tenant.Owner(config.Tenant) == tenant.Owner(profile.Tenant)
+	}
+	for _, id := range profile.WebhookIDs {
+		if !known[id] {
+			return fmt.Errorf("webhook with ID %d not found", id)
+		}
+	}
+
+	for _, jid := range profile.ChatJIDs {
+		if !strings.Contains(jid, "@") {
+			return fmt.Errorf("invalid chat JID: %s", jid)
+		}
+	}
+
+	for _, tag := range profile.Tags {
+		if strings.TrimSpace(tag) == "" {
+			return fmt.Errorf("tags must not be empty")
+		}
+	}
+
+	return nil
+}
+
+// TestWebhook sends a test webhook to verify connectivity. A sample message,
+// when given, is sent in place of the default test message and run through
+// the webhook's filter expression and triggers: the trigger it matched is
+// returned, nil if the webhook would not fire for such a message.
+func (wm *Manager) TestWebhook(config *types.WebhookConfig, sample *types.WebhookTestRequest) (*types.WebhookTrigger, error) {
 	testPayload := types.WebhookPayload{
 		EventType: "test",
-		Timestamp: time.Now().Format(time.RFC3339),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
 		WebhookConfig: types.WebhookConfigInfo{
 			ID:   config.ID,
 			Name: config.Name,
@@ -181,7 +296,7 @@ func (wm *Manager) TestWebhook(config *types.WebhookConfig) error {
 			Sender:     "test",
 			SenderName: "Test User",
 			Content:    "This is a test message",
-			Timestamp:  time.Now().Format(time.RFC3339),
+			Timestamp:  time.Now().UTC().Format(time.RFC3339),
 			IsFromMe:   false,
 		},
 		Metadata: types.WebhookMetadata{
@@ -190,15 +305,93 @@ func (wm *Manager) TestWebhook(config *types.WebhookConfig) error {
 		},
 	}
 
-	payloadBytes, err := json.Marshal(testPayload)
+	var matched *types.WebhookTrigger
+	if sample != nil {
+		msg, err := sampleMessage(sample)
+		if err != nil {
+			return nil, err
+		}
+		wm.mutex.RLock()
+		matched = wm.matchConfig(config, msg, sample.Content, sample.MediaType, sample.ChatName)
+		wm.mutex.RUnlock()
+
+		testPayload.Message = types.WebhookMessageInfo{
+			ID:         msg.Info.ID,
+			ChatJID:    msg.Info.Chat.String(),
+			ChatName:   sample.ChatName,
+			Sender:     msg.Info.Sender.String(),
+			SenderName: sample.SenderName,
+			Content:    sample.Content,
+			Timestamp:  testPayload.Timestamp,
+			PushName:   sample.SenderName,
+			MediaType:  sample.MediaType,
+		}
+		if matched != nil {
+			testPayload.Trigger = types.WebhookTriggerInfo{
+				Type:      matched.TriggerType,
+				Value:     matched.TriggerValue,
+				MatchType: matched.MatchType,
+			}
+		}
+	}
+
+	testPayload = renderPayload(testPayload, config.PayloadVersion)
+	if err := fitPayload(config, &testPayload); err != nil {
+		return nil, fmt.Errorf("test payload not sent: %v", err)
+	}
+	payloadBytes, contentType, err := encodePayload(config, &testPayload)
 	if err != nil {
-		return fmt.Errorf("failed to marshal test payload: %v", err)
+		return nil, fmt.Errorf("failed to encode test payload: %v", err)
 	}
 
-	success, statusCode, responseBody := wm.delivery.sendHTTPRequest(config, payloadBytes)
+	success, statusCode, responseBody := wm.delivery.sendHTTPRequest(config, payloadBytes, contentType)
 	if !success {
-		return fmt.Errorf("test webhook failed: status %d, response: %s", statusCode, responseBody)
+		return nil, fmt.Errorf("test webhook failed: status %d, response: %s", statusCode, responseBody)
 	}
 
-	return nil
+	return matched, nil
+}
+
+// ValidateTestRequest validates a sample message for a webhook test
+func (wm *Manager) ValidateTestRequest(sample *types.WebhookTestRequest) error {
+	if sample.ChatJID == "" {
+		return fmt.Errorf("chat_jid is required in a sample message")
+	}
+	_, err := sampleMessage(sample)
+	return err
+}
+
+// sampleMessage builds the message a webhook test sample describes, as
+// triggers and filter expressions see it
+func sampleMessage(sample *types.WebhookTestRequest) (*events.Message, error) {
+	chat, err := waTypes.ParseJID(sample.ChatJID)
+	if err != nil || chat.User == "" {
+		return nil, fmt.Errorf("invalid sample chat_jid: %s", sample.ChatJID)
+	}
+	sender := chat
+	if sample.Sender != "" {
+		senderJID := sample.Sender
+		if !strings.Contains(senderJID, "@") {
+			number, err := phone.Parse(senderJID, "")
+			if err != nil {
+				return nil, fmt.Errorf("invalid sample sender: %v", err)
+			}
+			senderJID = number.JID()
+		}
+		if sender, err = waTypes.ParseJID(senderJID); err != nil || sender.User == "" {
+			return nil, fmt.Errorf("invalid sample sender: %s", sample.Sender)
+		}
+	} else if chat.Server == waTypes.GroupServer {
+		return nil, fmt.Errorf("sample sender is required for a group chat")
+	}
+
+	return &events.Message{
+		Info: waTypes.MessageInfo{
+			MessageSource: waTypes.MessageSource{Chat: chat, Sender: sender, IsGroup: chat.Server == waTypes.GroupServer},
+			ID:            "test-message-id",
+			PushName:      sample.SenderName,
+			Timestamp:     time.Now(),
+		},
+		Message: &waE2E.Message{Conversation: proto.String(sample.Content)},
+	}, nil
 }
@@ -0,0 +1,167 @@
+package whatsapp
+
+import (
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+
+	"whatsapp-bridge/internal/database"
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+// ackSweepInterval is how often stuck pending messages are looked for
+const ackSweepInterval = 30 * time.Second
+
+// SetSendFailedHook registers fn to be called whenever an outgoing message is
+// marked failed, either on a send error or when its server ack times out.
+func (c *Client) SetSendFailedHook(fn func(msg *localTypes.OutgoingMessage)) {
+	c.ackMu.Lock()
+	defer c.ackMu.Unlock()
+	c.sendFailedHook = fn
+}
+
+// SetSentHook registers fn to be called once the server has accepted an
+// outgoing message
+func (c *Client) SetSentHook(fn func(msg *localTypes.OutgoingMessage)) {
+	c.ackMu.Lock()
+	defer c.ackMu.Unlock()
+	c.sentHook = fn
+}
+
+// trackOutgoing records a message as pending before it is written to the socket.
+// origin names the automation making the send, if any.
+func (c *Client) trackOutgoing(messageStore *database.MessageStore, owner, origin string, messageID types.MessageID, chat types.JID, content string) {
+	err := messageStore.StoreOutgoingMessage(&localTypes.OutgoingMessage{
+		MessageID: string(messageID),
+		ChatJID:   chat.String(),
+		Content:   content,
+		Status:    database.OutgoingPending,
+		Tenant:    owner,
+		Origin:    origin,
+	})
+	if err != nil {
+		c.logger.Warnf("Failed to track outgoing message %s: %v", messageID, err)
+	}
+}
+
+// setOutgoingStatus advances a tracked message's status, firing the
+// send-failed hook when it becomes failed.
+func (c *Client) setOutgoingStatus(messageStore *database.MessageStore, messageID, status, errMsg string) {
+	updated, err := messageStore.UpdateOutgoingStatus(messageID, status, errMsg)
+	if err != nil {
+		c.logger.Warnf("Failed to update outgoing message %s: %v", messageID, err)
+		return
+	}
+	if !updated || status != database.OutgoingFailed {
+		return
+	}
+
+	msg, err := messageStore.GetOutgoingMessage(messageID)
+	if err != nil || msg == nil {
+		return
+	}
+	c.notifySendFailed(msg)
+}
+
+// notifySent fires the sent hook with a message's tracked state. A receipt
+// may already have moved it past server_ack by the time this runs.
+func (c *Client) notifySent(messageStore *database.MessageStore, messageID string) {
+	c.ackMu.RLock()
+	hook := c.sentHook
+	c.ackMu.RUnlock()
+	if hook == nil {
+		return
+	}
+
+	msg, err := messageStore.GetOutgoingMessage(messageID)
+	if err != nil || msg == nil {
+		return
+	}
+	hook(msg)
+}
+
+func (c *Client) notifySendFailed(msg *localTypes.OutgoingMessage) {
+	c.ackMu.RLock()
+	hook := c.sendFailedHook
+	c.ackMu.RUnlock()
+
+	if hook != nil {
+		hook(msg)
+	}
+}
+
+// HandleReceipt records delivery, read and played receipts from recipients
+// and advances tracked outgoing messages on them.
+func (c *Client) HandleReceipt(messageStore *database.MessageStore, evt *events.Receipt) {
+	// Receipts from our own other devices say nothing about the recipient,
+	// but a read one means the chat was read on the phone
+	if evt.IsFromMe {
+		c.noteEcho(evt)
+		c.handleSelfReadReceipt(messageStore, evt)
+		return
+	}
+
+	c.storeReceipts(messageStore, evt)
+
+	var status, errMsg string
+	switch evt.Type {
+	case types.ReceiptTypeDelivered:
+		status = database.OutgoingDelivered
+	case types.ReceiptTypeRead, types.ReceiptTypePlayed:
+		status = database.OutgoingRead
+	case types.ReceiptTypeServerError:
+		status, errMsg = database.OutgoingFailed, "server rejected message"
+	default:
+		return
+	}
+
+	for _, id := range evt.MessageIDs {
+		c.setOutgoingStatus(messageStore, string(id), status, errMsg)
+	}
+}
+
+// StartAckMonitor periodically fails outgoing messages that have been pending
+// for longer than timeout, i.e. whose server ack never arrived.
+func (c *Client) StartAckMonitor(messageStore *database.MessageStore, timeout time.Duration) {
+	go func() {
+		ticker := time.NewTicker(ackSweepInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			failed, err := messageStore.FailStalePendingMessages(time.Now().Add(-timeout), "server ack timeout")
+			if err != nil {
+				c.logger.Warnf("Failed to check pending messages: %v", err)
+			}
+			for _, msg := range failed {
+				c.logger.Warnf("Outgoing message %s to %s got no server ack within %v", msg.MessageID, msg.ChatJID, timeout)
+				c.notifySendFailed(msg)
+			}
+		}
+	}()
+}
+
+// receiptStatuses maps the receipts recipients send to the statuses kept
+// per message; other receipts, such as retries, are not kept
+var receiptStatuses = map[types.ReceiptType]string{
+	types.ReceiptTypeDelivered: database.ReceiptDelivered,
+	types.ReceiptTypeRead:      database.ReceiptRead,
+	types.ReceiptTypePlayed:    database.ReceiptPlayed,
+}
+
+// storeReceipts keeps a recipient's receipt for each message it covers
+func (c *Client) storeReceipts(messageStore *database.MessageStore, evt *events.Receipt) {
+	status, ok := receiptStatuses[evt.Type]
+	if !ok || len(evt.MessageIDs) == 0 {
+		return
+	}
+
+	ids := make([]string, len(evt.MessageIDs))
+	for i, id := range evt.MessageIDs {
+		ids[i] = string(id)
+	}
+	chatJID := evt.Chat.ToNonAD().String()
+	if err := messageStore.StoreMessageReceipts(chatJID, evt.Sender.ToNonAD().String(), status, evt.Timestamp, ids); err != nil {
+		c.logger.Warnf("Failed to store %s receipts in %s: %v", status, chatJID, err)
+	}
+}
@@ -0,0 +1,154 @@
+package whatsapp
+
+import (
+	"context"
+	"time"
+
+	"whatsapp-bridge/internal/database"
+	"whatsapp-bridge/internal/recovery"
+	localTypes "whatsapp-bridge/internal/types"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// Annotation kinds
+const (
+	AnnotationTranscript = "transcript" // speech-to-text of a voice note
+	AnnotationOCR        = "ocr"        // text found in an image, e.g. a receipt or screenshot
+)
+
+const (
+	// annotateTimeout bounds downloading a message's media and annotating it
+	annotateTimeout = 3 * time.Minute
+
+	// annotateWorkers bounds how many messages are annotated at once, and
+	// annotateQueueSize how many may wait; messages beyond that are not
+	// annotated
+	annotateWorkers   = 4
+	annotateQueueSize = 256
+)
+
+// annotateJob is a message waiting to be annotated
+type annotateJob struct {
+	messageStore *database.MessageStore
+	msg          *events.Message
+	kind         string
+	media        whatsmeow.DownloadableMessage
+	mimetype     string
+}
+
+// Annotator extracts text from a message's media, such as a speech-to-text
+// service transcribing voice notes or an OCR service reading images
+type Annotator interface {
+	Annotate(ctx context.Context, data []byte, mimetype string) (string, error)
+}
+
+// SetAnnotator registers the service that produces annotations of a kind.
+// A nil annotator disables that kind.
+func (c *Client) SetAnnotator(kind string, annotator Annotator) {
+	c.annotatorMu.Lock()
+	defer c.annotatorMu.Unlock()
+
+	if annotator == nil {
+		delete(c.annotators, kind)
+		return
+	}
+	if c.annotators == nil {
+		c.annotators = make(map[string]Annotator)
+	}
+	c.annotators[kind] = annotator
+}
+
+// SetAnnotatedHook registers fn to be called with each annotation once it
+// is stored
+func (c *Client) SetAnnotatedHook(fn func(a localTypes.MessageAnnotation)) {
+	c.annotatorMu.Lock()
+	defer c.annotatorMu.Unlock()
+	c.annotatedHook = fn
+}
+
+// annotationFor returns the annotation kind to produce for a message and the
+// media to feed the annotator, or an empty kind when none applies
+func (c *Client) annotationFor(msg *waE2E.Message) (string, whatsmeow.DownloadableMessage, string) {
+	c.annotatorMu.RLock()
+	defer c.annotatorMu.RUnlock()
+
+	if aud := msg.GetAudioMessage(); aud != nil && aud.GetPTT() && c.annotators[AnnotationTranscript] != nil {
+		return AnnotationTranscript, aud, aud.GetMimetype()
+	}
+	if img := msg.GetImageMessage(); img != nil && c.annotators[AnnotationOCR] != nil {
+		return AnnotationOCR, img, img.GetMimetype()
+	}
+	return "", nil, ""
+}
+
+// queueAnnotation hands a message to the annotation workers, starting them
+// on first use. A message that finds the queue full is not annotated.
+func (c *Client) queueAnnotation(messageStore *database.MessageStore, msg *events.Message, kind string, media whatsmeow.DownloadableMessage, mimetype string) {
+	c.annotateOnce.Do(func() {
+		c.annotateJobs = make(chan annotateJob, annotateQueueSize)
+		for range annotateWorkers {
+			go func() {
+				for job := range c.annotateJobs {
+					c.annotate(job)
+				}
+			}()
+		}
+	})
+
+	select {
+	case c.annotateJobs <- annotateJob{messageStore, msg, kind, media, mimetype}:
+	default:
+		c.logger.Warnf("Annotation queue is full; no %s for %s", kind, msg.Info.ID)
+	}
+}
+
+// annotate downloads a message's media, runs the annotator for the job's
+// kind over it, stores the resulting text and reports it to the annotated
+// hook. Failures are logged; the message itself is already stored.
+func (c *Client) annotate(job annotateJob) {
+	defer recovery.Handle(recovery.SourceJob, "message "+job.kind, nil)
+	msg, kind := job.msg, job.kind
+
+	c.annotatorMu.RLock()
+	annotator, hook := c.annotators[kind], c.annotatedHook
+	c.annotatorMu.RUnlock()
+	if annotator == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), annotateTimeout)
+	defer cancel()
+
+	data, err := c.Client.Download(ctx, job.media)
+	if err != nil {
+		c.logger.Warnf("Failed to download media of %s for %s: %v", msg.Info.ID, kind, err)
+		return
+	}
+
+	text, err := annotator.Annotate(ctx, data, job.mimetype)
+	if err != nil {
+		c.logger.Warnf("Failed to produce %s for %s: %v", kind, msg.Info.ID, err)
+		return
+	}
+	if text == "" {
+		return
+	}
+
+	if err := job.messageStore.StoreAnnotation(msg.Info.Chat.String(), msg.Info.ID, kind, text); err != nil {
+		c.logger.Warnf("Failed to store %s for %s: %v", kind, msg.Info.ID, err)
+		return
+	}
+	if hook != nil {
+		hook(localTypes.MessageAnnotation{
+			ChatJID:   msg.Info.Chat.String(),
+			MessageID: msg.Info.ID,
+			Sender:    msg.Info.Sender.String(),
+			Timestamp: msg.Info.Timestamp,
+			Kind:      kind,
+			Text:      text,
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package whatsapp
+
+import (
+	"context"
+	"testing"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"google.golang.org/protobuf/proto"
+)
+
+type stubAnnotator struct{}
+
+func (stubAnnotator) Annotate(context.Context, []byte, string) (string, error) { return "", nil }
+
+func TestAnnotationFor(t *testing.T) {
+	voiceNote := &waE2E.Message{AudioMessage: &waE2E.AudioMessage{PTT: proto.Bool(true), Mimetype: proto.String("audio/ogg; codecs=opus")}}
+	music := &waE2E.Message{AudioMessage: &waE2E.AudioMessage{}}
+	image := &waE2E.Message{ImageMessage: &waE2E.ImageMessage{Mimetype: proto.String("image/jpeg")}}
+
+	c := &Client{}
+	if kind, _, _ := c.annotationFor(voiceNote); kind != "" {
+		t.Errorf("Expected no annotation without annotators, got %q", kind)
+	}
+
+	c.SetAnnotator(AnnotationTranscript, stubAnnotator{})
+	if kind, media, mimetype := c.annotationFor(voiceNote); kind != AnnotationTranscript || media == nil || mimetype != "audio/ogg; codecs=opus" {
+		t.Errorf("voice note: got %q %v %q", kind, media, mimetype)
+	}
+	if kind, _, _ := c.annotationFor(music); kind != "" {
+		t.Errorf("Expected audio files other than voice notes to be skipped, got %q", kind)
+	}
+	if kind, _, _ := c.annotationFor(image); kind != "" {
+		t.Errorf("Expected images to be skipped without OCR, got %q", kind)
+	}
+
+	c.SetAnnotator(AnnotationOCR, stubAnnotator{})
+	if kind, _, mimetype := c.annotationFor(image); kind != AnnotationOCR || mimetype != "image/jpeg" {
+		t.Errorf("image: got %q %q", kind, mimetype)
+	}
+
+	c.SetAnnotator(AnnotationTranscript, nil)
+	if kind, _, _ := c.annotationFor(voiceNote); kind != "" {
+		t.Errorf("Expected transcription to be disabled, got %q", kind)
+	}
+}
@@ -0,0 +1,122 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"whatsapp-bridge/internal/database"
+	"whatsapp-bridge/internal/recovery"
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+const (
+	// autoDownloadWorkers is how many downloads run at once
+	autoDownloadWorkers = 4
+
+	// autoDownloadQueueSize bounds the media waiting for a download worker;
+	// media arriving while it is full is left for on-demand download
+	autoDownloadQueueSize = 256
+
+	// autoDownloadTimeout bounds downloading one message's media
+	autoDownloadTimeout = 5 * time.Minute
+)
+
+// AutoDownloadTypes are the media types that can be downloaded as they arrive
+var AutoDownloadTypes = []string{"image", "video", "gif", "audio", "document", "sticker", "animated_sticker"}
+
+// autoDownloadJob is one message's media waiting for a download worker
+type autoDownloadJob struct {
+	media *localTypes.MessageMedia
+}
+
+// SetAutoDownload makes incoming media of the given types, "all" meaning
+// every type, download in the background as it arrives. Media larger than
+// maxBytes is skipped; 0 means no limit. Call before StartAutoDownload.
+func (c *Client) SetAutoDownload(mediaTypes []string, maxBytes uint64) error {
+	enabled := make(map[string]bool)
+	for _, t := range mediaTypes {
+		if t == "all" {
+			for _, known := range AutoDownloadTypes {
+				enabled[known] = true
+			}
+			continue
+		}
+		if !isAutoDownloadType(t) {
+			return fmt.Errorf("unknown media type: %s (must be 'all' or one of %s)", t, strings.Join(AutoDownloadTypes, ", "))
+		}
+		enabled[t] = true
+	}
+
+	c.autoDownloadTypes = enabled
+	c.autoDownloadMax = maxBytes
+	return nil
+}
+
+func isAutoDownloadType(t string) bool {
+	for _, known := range AutoDownloadTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// StartAutoDownload launches the workers that download the media types set
+// with SetAutoDownload. It does nothing when none are set.
+func (c *Client) StartAutoDownload(messageStore *database.MessageStore) {
+	if len(c.autoDownloadTypes) == 0 || c.autoDownloads != nil {
+		return
+	}
+
+	c.autoDownloads = make(chan autoDownloadJob, autoDownloadQueueSize)
+	for range autoDownloadWorkers {
+		go func() {
+			for job := range c.autoDownloads {
+				c.runAutoDownload(messageStore, job)
+			}
+		}()
+	}
+}
+
+// autoDownloadFor reports whether media of this type and size is downloaded
+// as it arrives. A size of 0 is unknown and not held against the limit. No
+// media is while the store volume is low on space; it can still be fetched
+// on demand once space is freed.
+func (c *Client) autoDownloadFor(mediaType string, fileLength uint64) bool {
+	if c.autoDownloads == nil || !c.autoDownloadTypes[mediaType] || c.spaceLow() {
+		return false
+	}
+	return c.autoDownloadMax == 0 || fileLength <= c.autoDownloadMax
+}
+
+// queueAutoDownload hands media to the download workers, reporting false
+// when the queue is full; that media is left for on-demand download
+func (c *Client) queueAutoDownload(media *localTypes.MessageMedia) bool {
+	select {
+	case c.autoDownloads <- autoDownloadJob{media: media}:
+		return true
+	default:
+		c.logger.Warnf("Media download queue is full; not downloading %s in %s", media.MessageID, media.ChatJID)
+		return false
+	}
+}
+
+// runAutoDownload downloads one message's media and records where it was
+// saved. Failures are logged; the media can still be fetched on demand.
+func (c *Client) runAutoDownload(messageStore *database.MessageStore, job autoDownloadJob) {
+	defer recovery.Handle(recovery.SourceJob, "media download", nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), autoDownloadTimeout)
+	defer cancel()
+
+	downloaded, err := c.DownloadMessageMedia(ctx, job.media)
+	if err != nil {
+		c.logger.Warnf("Failed to download media of %s in %s: %v", job.media.MessageID, job.media.ChatJID, err)
+		return
+	}
+	if err := messageStore.SetMediaPath(job.media.ChatJID, job.media.MessageID, downloaded.Path); err != nil {
+		c.logger.Warnf("Failed to record media path of %s: %v", job.media.MessageID, err)
+	}
+}
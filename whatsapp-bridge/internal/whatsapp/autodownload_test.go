@@ -0,0 +1,64 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	waLog "go.mau.fi/whatsmeow/util/log"
+
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+func TestAutoDownloadFor(t *testing.T) {
+	c := &Client{logger: waLog.Noop}
+	if err := c.SetAutoDownload([]string{"image", "voice"}, 0); err == nil {
+		t.Error("SetAutoDownload accepted an unknown media type")
+	}
+
+	if err := c.SetAutoDownload([]string{"image", "audio"}, 1024); err != nil {
+		t.Fatalf("SetAutoDownload: %v", err)
+	}
+	if c.autoDownloadFor("image", 100) {
+		t.Error("media queued before the worker started")
+	}
+
+	c.autoDownloads = make(chan autoDownloadJob, 1)
+	tests := []struct {
+		mediaType string
+		size      uint64
+		want      bool
+	}{
+		{"image", 100, true},
+		{"audio", 1024, true},
+		{"audio", 1025, false},
+		{"image", 0, true}, // size unknown
+		{"video", 100, false},
+	}
+	for _, tt := range tests {
+		if got := c.autoDownloadFor(tt.mediaType, tt.size); got != tt.want {
+			t.Errorf("autoDownloadFor(%s, %d) = %v, want %v", tt.mediaType, tt.size, got, tt.want)
+		}
+	}
+
+	// Nothing is downloaded, on arrival or on demand, while disk space is low
+	low := true
+	c.SetSpaceGuard(func() bool { return low })
+	if c.autoDownloadFor("image", 100) {
+		t.Error("media queued while low on space")
+	}
+	if _, err := c.DownloadMessageMedia(context.Background(), &localTypes.MessageMedia{ChatJID: "1@s.whatsapp.net", MessageID: "M0", MediaType: "image"}); !errors.Is(err, ErrLowOnSpace) {
+		t.Errorf("DownloadMessageMedia while low on space: %v", err)
+	}
+	low = false
+
+	// A full queue leaves the media for on-demand download
+	media := &localTypes.MessageMedia{ChatJID: "1@s.whatsapp.net", MessageID: "M1", MediaType: "image"}
+	if !c.queueAutoDownload(media) || c.queueAutoDownload(media) {
+		t.Error("queueAutoDownload did not accept one job and refuse the next")
+	}
+
+	if err := c.SetAutoDownload([]string{"all"}, 0); err != nil || !c.autoDownloadFor("animated_sticker", 1<<30) {
+		t.Errorf("all types without a size limit: %v", err)
+	}
+}
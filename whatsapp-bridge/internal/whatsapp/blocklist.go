@@ -0,0 +1,114 @@
+package whatsapp
+
+import (
+	"context"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+
+	"whatsapp-bridge/internal/database"
+	"whatsapp-bridge/internal/recovery"
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+// SendErrBlocked is reported for sends to a contact on the account's blocklist
+const SendErrBlocked = "contact_blocked"
+
+// SetBlocklistHook registers fn to be called for each contact blocked or
+// unblocked, whether from the phone or another linked device.
+func (c *Client) SetBlocklistHook(fn func(change localTypes.BlocklistChange)) {
+	c.blocklistMu.Lock()
+	defer c.blocklistMu.Unlock()
+	c.blocklistHook = fn
+}
+
+// HandleBlocklist mirrors a blocklist change. When the server only says the
+// list was modified, the whole list is fetched again in the background.
+func (c *Client) HandleBlocklist(messageStore *database.MessageStore, evt *events.Blocklist) {
+	if evt.Action == events.BlocklistActionModify {
+		recovery.Go("blocklist sync", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+			defer cancel()
+			c.SyncBlocklist(ctx, messageStore)
+		})
+		return
+	}
+
+	now := time.Now()
+	for _, change := range evt.Changes {
+		jid := change.JID.ToNonAD().String()
+		changed, err := messageStore.ApplyBlocklistChange(jid, string(change.Action), now)
+		if err != nil {
+			c.logger.Warnf("Failed to store blocklist change for %s: %v", jid, err)
+			continue
+		}
+		if changed {
+			c.blocklistChanged(localTypes.BlocklistChange{JID: jid, Action: string(change.Action), ChangedAt: now})
+		}
+	}
+}
+
+// SyncBlocklist fetches the full blocklist and reports any changes missed
+// while the bridge was offline. The first sync, made when the bridge first
+// connects, seeds the mirror silently.
+func (c *Client) SyncBlocklist(ctx context.Context, messageStore *database.MessageStore) {
+	list, err := c.GetBlocklist(ctx)
+	if err != nil {
+		c.logger.Warnf("Failed to fetch blocklist: %v", err)
+		return
+	}
+
+	jids := make([]string, 0, len(list.JIDs))
+	for _, jid := range list.JIDs {
+		jids = append(jids, jid.ToNonAD().String())
+	}
+
+	seeded, err := messageStore.SeedBlocklist(jids, time.Now())
+	if err != nil {
+		c.logger.Warnf("Failed to seed blocklist: %v", err)
+		return
+	}
+	if seeded {
+		c.logger.Infof("Blocklist mirror seeded with %d contacts", len(jids))
+		return
+	}
+
+	changes, err := messageStore.ReplaceBlocklist(jids, time.Now())
+	if err != nil {
+		c.logger.Warnf("Failed to store blocklist: %v", err)
+		return
+	}
+	for _, change := range changes {
+		c.blocklistChanged(change)
+	}
+}
+
+func (c *Client) blocklistChanged(change localTypes.BlocklistChange) {
+	c.logger.Infof("Contact %s: %s", change.Action, change.JID)
+
+	c.blocklistMu.RLock()
+	hook := c.blocklistHook
+	c.blocklistMu.RUnlock()
+
+	if hook != nil {
+		hook(change)
+	}
+}
+
+// isBlocked reports whether a recipient is on the blocklist mirror, under
+// either its phone number or its LID. Lookup failures count as not blocked.
+func (c *Client) isBlocked(ctx context.Context, messageStore *database.MessageStore, jid types.JID) bool {
+	if blocked, _ := messageStore.IsBlocked(jid.String()); blocked {
+		return true
+	}
+	if jid.Server != types.DefaultUserServer || c.Store == nil || c.Store.LIDs == nil {
+		return false
+	}
+	lid, err := c.Store.LIDs.GetLIDForPN(ctx, jid)
+	if err != nil || lid.IsEmpty() {
+		return false
+	}
+	blocked, _ := messageStore.IsBlocked(lid.String())
+	return blocked
+}
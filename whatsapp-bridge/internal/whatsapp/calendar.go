@@ -0,0 +1,76 @@
+package whatsapp
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types/events"
+
+	"whatsapp-bridge/internal/database"
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+// calendarEvent reads an event planned in a chat. Edits, cancellation
+// included, come encrypted with the secret of the message that created the
+// event and are decrypted here. It returns nil for other messages and for
+// edits that cannot be decrypted.
+func (c *Client) calendarEvent(msg *events.Message) *localTypes.CalendarEvent {
+	if enc := msg.Message.GetSecretEncryptedMessage(); enc.GetSecretEncType() == waE2E.SecretEncryptedMessage_EVENT_EDIT {
+		if c.Client == nil {
+			return nil
+		}
+		edit, err := c.Client.DecryptSecretEncryptedMessage(context.Background(), msg)
+		if err != nil {
+			c.logger.Warnf("Failed to decrypt event edit %s in %s: %v", msg.Info.ID, msg.Info.Chat, err)
+			return nil
+		}
+		return parseCalendarEvent(msg, edit.GetEventMessage(), enc.GetTargetMessageKey().GetID())
+	}
+	return parseCalendarEvent(msg, msg.Message.GetEventMessage(), msg.Info.ID)
+}
+
+// parseCalendarEvent turns an event message into a row for the event
+// created by messageID
+func parseCalendarEvent(msg *events.Message, ev *waE2E.EventMessage, messageID string) *localTypes.CalendarEvent {
+	if ev == nil || messageID == "" || ev.GetStartTime() == 0 {
+		return nil
+	}
+
+	cal := &localTypes.CalendarEvent{
+		ChatJID:     msg.Info.Chat.ToNonAD().String(),
+		MessageID:   messageID,
+		CreatorJID:  msg.Info.Sender.ToNonAD().String(),
+		Name:        ev.GetName(),
+		Description: ev.GetDescription(),
+		JoinLink:    ev.GetJoinLink(),
+		StartTime:   time.Unix(ev.GetStartTime(), 0).UTC(),
+		Canceled:    ev.GetIsCanceled(),
+		CreatedAt:   msg.Info.Timestamp.UTC(),
+		UpdatedAt:   msg.Info.Timestamp.UTC(),
+	}
+	if end := ev.GetEndTime(); end > ev.GetStartTime() {
+		endTime := time.Unix(end, 0).UTC()
+		cal.EndTime = &endTime
+	}
+	if loc := ev.GetLocation(); loc != nil {
+		var parts []string
+		for _, part := range []string{loc.GetName(), loc.GetAddress()} {
+			if part = strings.TrimSpace(part); part != "" {
+				parts = append(parts, part)
+			}
+		}
+		cal.Location = strings.Join(parts, ", ")
+		cal.Latitude = loc.GetDegreesLatitude()
+		cal.Longitude = loc.GetDegreesLongitude()
+	}
+	return cal
+}
+
+// storeCalendarEvent records an event or an edit of one
+func (c *Client) storeCalendarEvent(messageStore *database.MessageStore, ev *localTypes.CalendarEvent) {
+	if err := messageStore.StoreCalendarEvent(ev); err != nil {
+		c.logger.Warnf("Failed to store event %s in %s: %v", ev.MessageID, ev.ChatJID, err)
+	}
+}
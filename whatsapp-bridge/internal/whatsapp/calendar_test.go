@@ -0,0 +1,62 @@
+package whatsapp
+
+import (
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestCalendarEvent(t *testing.T) {
+	c := &Client{}
+	group := types.NewJID("120363000000000000", types.GroupServer)
+	ana := types.NewJID("15550000001", types.DefaultUserServer)
+	start := time.Date(2024, 6, 1, 18, 0, 0, 0, time.UTC)
+
+	msg := &events.Message{
+		Info: types.MessageInfo{
+			MessageSource: types.MessageSource{Chat: group, Sender: ana, IsGroup: true},
+			ID:            "3EB0A1",
+			Timestamp:     start.Add(-48 * time.Hour),
+		},
+		Message: &waE2E.Message{EventMessage: &waE2E.EventMessage{
+			Name:        proto.String("Bouldering"),
+			Description: proto.String("Bring shoes"),
+			Location: &waE2E.LocationMessage{
+				Name:             proto.String("Boulderhalle"),
+				Address:          proto.String("Hauptstraße 1, Berlin"),
+				DegreesLatitude:  proto.Float64(52.52),
+				DegreesLongitude: proto.Float64(13.405),
+			},
+			StartTime: proto.Int64(start.Unix()),
+			EndTime:   proto.Int64(start.Add(2 * time.Hour).Unix()),
+		}},
+	}
+
+	ev := c.calendarEvent(msg)
+	if ev == nil {
+		t.Fatal("calendarEvent = nil, want the event")
+	}
+	if ev.ChatJID != group.String() || ev.MessageID != "3EB0A1" || ev.CreatorJID != ana.String() || ev.Name != "Bouldering" {
+		t.Errorf("event = %+v", ev)
+	}
+	if !ev.StartTime.Equal(start) || ev.EndTime == nil || ev.EndTime.Sub(start) != 2*time.Hour {
+		t.Errorf("event runs %v to %v, want 18:00 to 20:00", ev.StartTime, ev.EndTime)
+	}
+	if ev.Location != "Boulderhalle, Hauptstraße 1, Berlin" || ev.Latitude != 52.52 {
+		t.Errorf("location = %q at %v", ev.Location, ev.Latitude)
+	}
+
+	// No end time means none, not the epoch
+	msg.Message.EventMessage.EndTime = nil
+	if ev := c.calendarEvent(msg); ev == nil || ev.EndTime != nil {
+		t.Errorf("event without end = %+v", ev)
+	}
+
+	if ev := c.calendarEvent(&events.Message{Message: &waE2E.Message{Conversation: proto.String("hi")}}); ev != nil {
+		t.Errorf("text message read as event: %+v", ev)
+	}
+}
@@ -0,0 +1,378 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"whatsapp-bridge/internal/database"
+	localTypes "whatsapp-bridge/internal/types"
+
+	"go.mau.fi/whatsmeow"
+	waBinary "go.mau.fi/whatsmeow/binary"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// Catalog paging and product image limits
+const (
+	maxCatalogPageSize    = 100
+	maxCatalogLookupPages = 20
+	maxProductImageBytes  = 5 << 20
+	productImageTimeout   = 15 * time.Second
+
+	// How long products seen while paging are trusted, and how soon a
+	// product missing from a fully paged catalog is looked for again
+	catalogCacheTTL = 10 * time.Minute
+	catalogRecheck  = time.Minute
+
+	// How long an uploaded product image is reused before uploading again
+	productImageTTL = 24 * time.Hour
+)
+
+// productCache remembers the linked account's catalog as it is paged, so a
+// product lookup fetches only the pages it has not seen yet, and the image
+// uploaded for each product, so sending it again does not re-upload it
+type productCache struct {
+	mu          sync.Mutex
+	products    map[string]localTypes.CatalogProduct
+	cursor      string    // next page to fetch
+	pages       int       // pages fetched since paging last started
+	complete    bool      // last page or the page limit reached
+	started     time.Time // when the cached products were first fetched
+	completedAt time.Time
+	images      map[string]productImage // by product ID
+}
+
+// productImage is the media uploaded for a product's catalog image
+type productImage struct {
+	url      string
+	image    *waE2E.ImageMessage
+	uploaded time.Time
+}
+
+// lookup returns a cached product, or the cursor of the next page to fetch.
+// done is true when the product is not in the catalog as recently paged.
+func (p *productCache) lookup(productID string, now time.Time) (product *localTypes.CatalogProduct, cursor string, done bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.started.IsZero() || now.Sub(p.started) > catalogCacheTTL {
+		p.products = make(map[string]localTypes.CatalogProduct)
+		p.cursor, p.pages, p.complete = "", 0, false
+		p.started = now
+	}
+	if cached, ok := p.products[productID]; ok {
+		return &cached, "", false
+	}
+	if p.complete {
+		if now.Sub(p.completedAt) < catalogRecheck {
+			return nil, "", true
+		}
+		// Page again from the start for products added since
+		p.cursor, p.pages, p.complete = "", 0, false
+	}
+	return nil, p.cursor, false
+}
+
+// add records a page fetched at cursor and advances paging past it
+func (p *productCache) add(cursor string, catalog *localTypes.Catalog, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.products == nil {
+		p.products = make(map[string]localTypes.CatalogProduct)
+	}
+	for _, product := range catalog.Products {
+		p.products[product.ID] = product
+	}
+	// A concurrent lookup already fetched this page
+	if p.complete || cursor != p.cursor {
+		return
+	}
+	p.pages++
+	p.cursor = catalog.NextCursor
+	if p.cursor == "" || p.pages >= maxCatalogLookupPages {
+		p.complete = true
+		p.completedAt = now
+	}
+}
+
+// valid reports whether the upload is of the image at url and recent enough to reuse
+func (p *productImage) valid(url string, now time.Time) bool {
+	return p.url == url && now.Sub(p.uploaded) < productImageTTL
+}
+
+// image returns a copy of the media uploaded for a product's image, or nil
+// when it must be uploaded again
+func (p *productCache) image(productID, url string, now time.Time) *waE2E.ImageMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cached, ok := p.images[productID]
+	if !ok || !cached.valid(url, now) {
+		return nil
+	}
+	return proto.Clone(cached.image).(*waE2E.ImageMessage)
+}
+
+// storeImage records the media uploaded for a product's image
+func (p *productCache) storeImage(productID, url string, image *waE2E.ImageMessage, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.images == nil {
+		p.images = make(map[string]productImage)
+	}
+	p.images[productID] = productImage{url: url, image: proto.Clone(image).(*waE2E.ImageMessage), uploaded: now}
+}
+
+// GetCatalog fetches one page of a business catalog. An empty jidStr means
+// the linked account's own catalog; cursor is the NextCursor of the previous page.
+func (c *Client) GetCatalog(ctx context.Context, jidStr string, limit int, cursor string) (*localTypes.Catalog, error) {
+	if !c.IsConnected() {
+		return nil, fmt.Errorf("not connected to WhatsApp")
+	}
+
+	owner, err := c.catalogOwner(jidStr)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > maxCatalogPageSize {
+		limit = maxCatalogPageSize
+	}
+
+	params := []waBinary.Node{
+		{Tag: "limit", Content: []byte(strconv.Itoa(limit))},
+		{Tag: "width", Content: []byte("100")},
+		{Tag: "height", Content: []byte("100")},
+	}
+	if cursor != "" {
+		params = append(params, waBinary.Node{Tag: "after", Content: []byte(cursor)})
+	}
+
+	resp, err := c.DangerousInternals().SendIQ(ctx, whatsmeow.DangerousInfoQuery{
+		Namespace: "w:biz:catalog",
+		Type:      "get",
+		To:        types.ServerJID,
+		Content: []waBinary.Node{{
+			Tag:     "product_catalog",
+			Attrs:   waBinary.Attrs{"jid": owner, "allow_shop_source": "true"},
+			Content: params,
+		}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch catalog: %v", err)
+	}
+
+	return parseCatalog(owner, resp), nil
+}
+
+// catalogOwner resolves the catalog owner, defaulting to the linked account
+func (c *Client) catalogOwner(jidStr string) (types.JID, error) {
+	if jidStr == "" {
+		if c.Store.ID == nil {
+			return types.JID{}, fmt.Errorf("not logged in")
+		}
+		return c.Store.ID.ToNonAD(), nil
+	}
+	jid, err := parseRecipient(jidStr)
+	if err != nil {
+		return types.JID{}, fmt.Errorf("invalid JID: %v", err)
+	}
+	return jid, nil
+}
+
+// parseCatalog converts a w:biz:catalog response into a catalog page
+func parseCatalog(owner types.JID, resp *waBinary.Node) *localTypes.Catalog {
+	catalogNode := resp.GetChildByTag("product_catalog")
+	catalog := &localTypes.Catalog{
+		OwnerJID: owner.String(),
+		Products: []localTypes.CatalogProduct{},
+	}
+
+	for _, productNode := range catalogNode.GetChildrenByTag("product") {
+		catalog.Products = append(catalog.Products, parseCatalogProduct(&productNode))
+	}
+
+	paging := catalogNode.GetChildByTag("paging")
+	catalog.NextCursor = nodeText(paging.GetChildByTag("after"))
+
+	return catalog
+}
+
+// parseCatalogProduct converts a product node from a catalog response
+func parseCatalogProduct(node *waBinary.Node) localTypes.CatalogProduct {
+	price, _ := strconv.ParseInt(nodeText(node.GetChildByTag("price")), 10, 64)
+
+	return localTypes.CatalogProduct{
+		ID:           nodeText(node.GetChildByTag("id")),
+		RetailerID:   nodeText(node.GetChildByTag("retailer_id")),
+		Name:         nodeText(node.GetChildByTag("name")),
+		Description:  nodeText(node.GetChildByTag("description")),
+		URL:          nodeText(node.GetChildByTag("url")),
+		Price1000:    price,
+		Currency:     nodeText(node.GetChildByTag("currency")),
+		ImageURL:     nodeText(node.GetChildByTag("media", "image", "original_image_url")),
+		Hidden:       node.AttrGetter().OptionalString("is_hidden") == "true",
+		ReviewStatus: nodeText(node.GetChildByTag("status_info", "status")),
+	}
+}
+
+// nodeText returns a node's content as a string
+func nodeText(node waBinary.Node) string {
+	switch content := node.Content.(type) {
+	case []byte:
+		return string(content)
+	case string:
+		return content
+	default:
+		return ""
+	}
+}
+
+// findCatalogProduct looks up a product in the linked account's catalog,
+// fetching only the pages not already cached
+func (c *Client) findCatalogProduct(ctx context.Context, productID string) (*localTypes.CatalogProduct, error) {
+	for {
+		product, cursor, done := c.catalogCache.lookup(productID, time.Now())
+		if product != nil || done {
+			return product, nil
+		}
+		catalog, err := c.GetCatalog(ctx, "", maxCatalogPageSize, cursor)
+		if err != nil {
+			return nil, err
+		}
+		c.catalogCache.add(cursor, catalog, time.Now())
+	}
+}
+
+// SendProduct sends a product message for an item in the linked account's catalog
+// on behalf of the owner tenant
+func (c *Client) SendProduct(ctx context.Context, messageStore *database.MessageStore, owner string, req localTypes.SendProductRequest) localTypes.SendResult {
+	if !c.IsConnected() {
+		return sendFailure(SendErrNotConnected, true, "Not connected to WhatsApp")
+	}
+
+	recipientJID, err := parseRecipient(req.Recipient)
+	if err != nil {
+		return sendFailure(SendErrInvalidRecipient, false, "Error parsing JID: %v", err)
+	}
+	if !c.checkRegistered(ctx, recipientJID) {
+		return sendFailure(SendErrNotOnWhatsApp, false, "Recipient %s is not on WhatsApp", recipientJID.User)
+	}
+
+	product, err := c.findCatalogProduct(ctx, req.ProductID)
+	if err != nil {
+		code, retryable := classifySendError(err)
+		return sendFailure(code, retryable, "Error looking up product: %v", err)
+	}
+	if product == nil {
+		return sendFailure(SendErrProductNotFound, false, "Product %s is not in the catalog", req.ProductID)
+	}
+
+	snapshot := &waE2E.ProductMessage_ProductSnapshot{
+		ProductID:       proto.String(product.ID),
+		Title:           proto.String(product.Name),
+		Description:     proto.String(product.Description),
+		CurrencyCode:    proto.String(product.Currency),
+		PriceAmount1000: proto.Int64(product.Price1000),
+		RetailerID:      proto.String(product.RetailerID),
+		URL:             proto.String(product.URL),
+	}
+
+	if product.ImageURL != "" {
+		image := c.catalogCache.image(product.ID, product.ImageURL, time.Now())
+		if image == nil {
+			image, err = c.uploadProductImage(ctx, product.ImageURL)
+			if err != nil {
+				code, retryable := classifySendError(err)
+				return sendFailure(code, retryable, "Error uploading product image: %v", err)
+			}
+			c.catalogCache.storeImage(product.ID, product.ImageURL, image, time.Now())
+		}
+		snapshot.ProductImage = image
+		snapshot.ProductImageCount = proto.Uint32(1)
+	}
+
+	msg := &waE2E.Message{
+		ProductMessage: &waE2E.ProductMessage{
+			Product:          snapshot,
+			BusinessOwnerJID: proto.String(c.Store.ID.ToNonAD().String()),
+		},
+	}
+	if req.Body != "" {
+		msg.ProductMessage.Body = proto.String(req.Body)
+	}
+	if req.Footer != "" {
+		msg.ProductMessage.Footer = proto.String(req.Footer)
+	}
+
+	content := req.Body
+	if content == "" {
+		content = product.Name
+	}
+	return c.sendTracked(ctx, messageStore, owner, recipientJID, msg, content)
+}
+
+// uploadProductImage downloads a catalog image and re-uploads it as message media
+func (c *Client) uploadProductImage(ctx context.Context, imageURL string) (*waE2E.ImageMessage, error) {
+	httpClient := &http.Client{Timeout: productImageTimeout}
+	resp, err := httpClient.Get(imageURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download image: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download image: HTTP %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxProductImageBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %v", err)
+	}
+	if len(data) > maxProductImageBytes {
+		return nil, fmt.Errorf("image exceeds %d bytes", maxProductImageBytes)
+	}
+
+	mimeType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(mimeType, "image/") {
+		mimeType = "image/jpeg"
+	}
+
+	uploaded, err := c.Upload(ctx, data, whatsmeow.MediaImage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &waE2E.ImageMessage{
+		Mimetype:      proto.String(mimeType),
+		URL:           &uploaded.URL,
+		DirectPath:    &uploaded.DirectPath,
+		MediaKey:      uploaded.MediaKey,
+		FileEncSHA256: uploaded.FileEncSHA256,
+		FileSHA256:    uploaded.FileSHA256,
+		FileLength:    &uploaded.FileLength,
+	}, nil
+}
+
+// SendCatalog sends a link to the linked account's catalog, which WhatsApp
+// renders as a catalog preview, on behalf of the owner tenant
+func (c *Client) SendCatalog(ctx context.Context, messageStore *database.MessageStore, owner string, req localTypes.SendCatalogRequest) localTypes.SendResult {
+	if c.Store.ID == nil {
+		return sendFailure(SendErrNotConnected, true, "Not logged in")
+	}
+
+	link := "https://wa.me/c/" + c.Store.ID.User
+	message := link
+	if req.Message != "" {
+		message = req.Message + "\n" + link
+	}
+	return c.SendMessageAs(ctx, messageStore, owner, req.Recipient, message, "")
+}
@@ -0,0 +1,142 @@
+package whatsapp
+
+import (
+	"testing"
+	"time"
+
+	waBinary "go.mau.fi/whatsmeow/binary"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+func textNode(tag, text string) waBinary.Node {
+	return waBinary.Node{Tag: tag, Content: []byte(text)}
+}
+
+func TestParseCatalog(t *testing.T) {
+	owner := types.NewJID("111", types.DefaultUserServer)
+	resp := &waBinary.Node{
+		Tag: "iq",
+		Content: []waBinary.Node{{
+			Tag: "product_catalog",
+			Content: []waBinary.Node{
+				{
+					Tag:   "product",
+					Attrs: waBinary.Attrs{"is_hidden": "true"},
+					Content: []waBinary.Node{
+						textNode("id", "P1"),
+						textNode("retailer_id", "SKU-1"),
+						textNode("name", "Mug"),
+						textNode("price", "12500"),
+						textNode("currency", "EUR"),
+						{Tag: "media", Content: []waBinary.Node{{
+							Tag:     "image",
+							Content: []waBinary.Node{textNode("original_image_url", "https://example.com/mug.jpg")},
+						}}},
+						{Tag: "status_info", Content: []waBinary.Node{textNode("status", "APPROVED")}},
+					},
+				},
+				{Tag: "product", Content: []waBinary.Node{textNode("id", "P2"), textNode("name", "Cup")}},
+				{Tag: "paging", Content: []waBinary.Node{textNode("after", "CURSOR2")}},
+			},
+		}},
+	}
+
+	catalog := parseCatalog(owner, resp)
+	if catalog.OwnerJID != owner.String() || catalog.NextCursor != "CURSOR2" {
+		t.Errorf("Unexpected catalog page: %+v", catalog)
+	}
+	if len(catalog.Products) != 2 {
+		t.Fatalf("Expected 2 products, got %d", len(catalog.Products))
+	}
+
+	mug := catalog.Products[0]
+	if mug.ID != "P1" || mug.RetailerID != "SKU-1" || mug.Price1000 != 12500 || mug.Currency != "EUR" {
+		t.Errorf("Unexpected product fields: %+v", mug)
+	}
+	if !mug.Hidden || mug.ImageURL != "https://example.com/mug.jpg" || mug.ReviewStatus != "APPROVED" {
+		t.Errorf("Unexpected product metadata: %+v", mug)
+	}
+	if catalog.Products[1].Hidden {
+		t.Errorf("Expected second product to be visible")
+	}
+
+	empty := parseCatalog(owner, &waBinary.Node{Tag: "iq"})
+	if len(empty.Products) != 0 || empty.NextCursor != "" {
+		t.Errorf("Expected empty catalog, got %+v", empty)
+	}
+}
+
+func TestProductCache(t *testing.T) {
+	var cache productCache
+	now := time.Now()
+
+	// Nothing cached: fetch the first page
+	if product, cursor, done := cache.lookup("P3", now); product != nil || cursor != "" || done {
+		t.Fatalf("empty cache lookup = %v %q %v", product, cursor, done)
+	}
+	cache.add("", &localTypes.Catalog{Products: []localTypes.CatalogProduct{{ID: "P1"}, {ID: "P2"}}, NextCursor: "C2"}, now)
+
+	// Products on fetched pages are served without fetching again
+	if product, _, _ := cache.lookup("P1", now); product == nil || product.ID != "P1" {
+		t.Errorf("lookup(P1) = %v, want the cached product", product)
+	}
+	// Others resume paging where it stopped
+	if product, cursor, done := cache.lookup("P3", now); product != nil || cursor != "C2" || done {
+		t.Fatalf("lookup(P3) = %v %q %v, want the second page", product, cursor, done)
+	}
+	cache.add("C2", &localTypes.Catalog{Products: []localTypes.CatalogProduct{{ID: "P3"}}}, now)
+	if product, _, _ := cache.lookup("P3", now); product == nil {
+		t.Error("lookup(P3) missed after its page was fetched")
+	}
+
+	// Fully paged: a missing product is not looked for again right away
+	if product, _, done := cache.lookup("P9", now); product != nil || !done {
+		t.Errorf("lookup(P9) = %v %v, want not found", product, done)
+	}
+	if _, cursor, done := cache.lookup("P9", now.Add(catalogRecheck+time.Second)); cursor != "" || done {
+		t.Errorf("lookup(P9) after recheck = %q %v, want paging from the start", cursor, done)
+	}
+
+	// A page fetched twice concurrently advances paging once
+	cache.add("", &localTypes.Catalog{NextCursor: "C2"}, now)
+	cache.add("", &localTypes.Catalog{NextCursor: "C2"}, now)
+	if _, cursor, _ := cache.lookup("P9", now.Add(catalogRecheck+time.Second)); cursor != "C2" {
+		t.Errorf("cursor = %q after a duplicate page, want C2", cursor)
+	}
+
+	// Everything is fetched again once the cache is stale
+	if product, cursor, _ := cache.lookup("P1", now.Add(catalogCacheTTL+time.Second)); product != nil || cursor != "" {
+		t.Errorf("stale lookup(P1) = %v %q, want a fresh first page", product, cursor)
+	}
+}
+
+func TestProductImageCache(t *testing.T) {
+	var cache productCache
+	now := time.Now()
+
+	if image := cache.image("P1", "https://example.com/a.jpg", now); image != nil {
+		t.Fatal("image cached before any upload")
+	}
+	cache.storeImage("P1", "https://example.com/a.jpg", &waE2E.ImageMessage{DirectPath: proto.String("/v/a")}, now)
+
+	image := cache.image("P1", "https://example.com/a.jpg", now.Add(time.Hour))
+	if image.GetDirectPath() != "/v/a" {
+		t.Fatalf("cached image = %v, want the upload", image)
+	}
+	// Callers get their own copy
+	image.DirectPath = proto.String("/changed")
+	if cache.image("P1", "https://example.com/a.jpg", now).GetDirectPath() != "/v/a" {
+		t.Error("changing a returned image changed the cache")
+	}
+
+	if cache.image("P1", "https://example.com/b.jpg", now) != nil {
+		t.Error("reused the upload after the product image changed")
+	}
+	if cache.image("P1", "https://example.com/a.jpg", now.Add(productImageTTL+time.Second)) != nil {
+		t.Error("reused an expired upload")
+	}
+}
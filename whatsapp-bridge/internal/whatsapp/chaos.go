@@ -0,0 +1,147 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	waBinary "go.mau.fi/whatsmeow/binary"
+	"go.mau.fi/whatsmeow/proto/waCommon"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/proto/waHistorySync"
+	"go.mau.fi/whatsmeow/proto/waWeb"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+// Fault injection for testing reconnect handling and API consumers. These
+// drive whatsmeow's own handlers wherever possible, so the bridge reacts as
+// it would to the real failure. Only served in dev mode.
+
+// MaxChaosHistoryMessages caps the messages in a simulated history sync
+const MaxChaosHistoryMessages = 100
+
+var streamErrorCode = regexp.MustCompile(`^[0-9]{3}$`)
+
+// chaosSyncTypes maps the accepted history sync type names
+var chaosSyncTypes = map[string]waHistorySync.HistorySync_HistorySyncType{
+	"initial_bootstrap": waHistorySync.HistorySync_INITIAL_BOOTSTRAP,
+	"recent":            waHistorySync.HistorySync_RECENT,
+	"full":              waHistorySync.HistorySync_FULL,
+	"on_demand":         waHistorySync.HistorySync_ON_DEMAND,
+}
+
+// SimulateDisconnect drops the connection as if the server or network closed
+// it: a Disconnected event is dispatched and whatsmeow's automatic reconnect
+// runs. With offline > 0 the bridge instead stays offline that long and then
+// reconnects, which lets the connection watchdog be exercised.
+func (c *Client) SimulateDisconnect(offline time.Duration) {
+	internals := c.DangerousInternals()
+
+	c.logger.Warnf("Chaos: simulating a dropped connection (offline for %v)", offline)
+	c.Client.Disconnect()
+	internals.ResetExpectedDisconnect()
+	go internals.DispatchEvent(&events.Disconnected{})
+
+	if offline <= 0 {
+		go internals.AutoReconnect(context.Background())
+		return
+	}
+	time.AfterFunc(offline, func() {
+		if c.IsConnected() {
+			return
+		}
+		c.logger.Infof("Chaos: reconnecting after simulated outage")
+		if err := c.Client.Connect(); err != nil {
+			c.logger.Errorf("Chaos: reconnect failed: %v", err)
+		}
+	})
+}
+
+// SimulateKeepAliveTimeout dispatches failures consecutive keepalive
+// timeouts, as whatsmeow does when pings go unanswered
+func (c *Client) SimulateKeepAliveTimeout(failures int) {
+	c.logger.Warnf("Chaos: simulating %d keepalive timeouts", failures)
+	lastSuccess := time.Now()
+	for i := 1; i <= failures; i++ {
+		c.DangerousInternals().DispatchEvent(&events.KeepAliveTimeout{ErrorCount: i, LastSuccess: lastSuccess})
+	}
+}
+
+// SimulateStreamError feeds a stream error with the given code through
+// whatsmeow's stream error handling. Like a real one, it is followed by the
+// server closing the connection, except for 515, on which whatsmeow
+// reconnects by itself.
+func (c *Client) SimulateStreamError(code string) error {
+	if !streamErrorCode.MatchString(code) {
+		return fmt.Errorf("code must be a three digit stream error code")
+	}
+
+	c.logger.Warnf("Chaos: simulating stream error %s", code)
+	c.DangerousInternals().HandleStreamError(context.Background(), &waBinary.Node{
+		Tag:   "stream:error",
+		Attrs: waBinary.Attrs{"code": code},
+	})
+	if code != "515" {
+		c.SimulateDisconnect(0)
+	}
+	return nil
+}
+
+// SimulateHistorySync dispatches a history sync of the given type carrying
+// count text messages in chatJID, newest first. The messages are stored like
+// real ones; their IDs start with CHAOS.
+func (c *Client) SimulateHistorySync(syncType, chatJID string, count int) error {
+	st, ok := chaosSyncTypes[syncType]
+	if !ok {
+		names := make([]string, 0, len(chaosSyncTypes))
+		for name := range chaosSyncTypes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("sync_type must be one of %s", strings.Join(names, ", "))
+	}
+	if count < 1 || count > MaxChaosHistoryMessages {
+		return fmt.Errorf("messages must be between 1 and %d", MaxChaosHistoryMessages)
+	}
+	chat, err := types.ParseJID(chatJID)
+	if err != nil {
+		return fmt.Errorf("invalid chat_jid: %v", err)
+	}
+
+	c.logger.Warnf("Chaos: simulating a %s history sync of %d messages in %s", syncType, count, chat)
+	c.DangerousInternals().DispatchEvent(&events.HistorySync{Data: chaosHistorySync(st, chat, count, time.Now())})
+	return nil
+}
+
+// chaosHistorySync builds a history sync with one conversation of count
+// messages, a minute apart and ending at now
+func chaosHistorySync(syncType waHistorySync.HistorySync_HistorySyncType, chat types.JID, count int, now time.Time) *waHistorySync.HistorySync {
+	messages := make([]*waHistorySync.HistorySyncMsg, count)
+	for i := range messages {
+		ts := now.Add(-time.Duration(i) * time.Minute)
+		messages[i] = &waHistorySync.HistorySyncMsg{
+			Message: &waWeb.WebMessageInfo{
+				Key: &waCommon.MessageKey{
+					RemoteJID: proto.String(chat.String()),
+					FromMe:    proto.Bool(false),
+					ID:        proto.String(fmt.Sprintf("CHAOS%d%03d", now.Unix(), i)),
+				},
+				Message:          &waE2E.Message{Conversation: proto.String(fmt.Sprintf("Simulated history message %d", count-i))},
+				MessageTimestamp: proto.Uint64(uint64(ts.Unix())),
+			},
+		}
+	}
+
+	return &waHistorySync.HistorySync{
+		SyncType: syncType.Enum(),
+		Conversations: []*waHistorySync.Conversation{{
+			ID:       proto.String(chat.String()),
+			Messages: messages,
+		}},
+	}
+}
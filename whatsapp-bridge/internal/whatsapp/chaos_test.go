@@ -0,0 +1,52 @@
+package whatsapp
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/proto/waHistorySync"
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestChaosHistorySync(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	chat := types.NewJID("123", types.DefaultUserServer)
+
+	data := chaosHistorySync(waHistorySync.HistorySync_RECENT, chat, 3, now)
+	if data.GetSyncType() != waHistorySync.HistorySync_RECENT || len(data.Conversations) != 1 {
+		t.Fatalf("sync = %v with %d conversations", data.GetSyncType(), len(data.Conversations))
+	}
+
+	conv := data.Conversations[0]
+	if conv.GetID() != "123@s.whatsapp.net" || len(conv.Messages) != 3 {
+		t.Fatalf("conversation %s has %d messages", conv.GetID(), len(conv.Messages))
+	}
+
+	newest, oldest := conv.Messages[0].Message, conv.Messages[2].Message
+	if newest.GetMessageTimestamp() != uint64(now.Unix()) || oldest.GetMessageTimestamp() != uint64(now.Add(-2*time.Minute).Unix()) {
+		t.Errorf("timestamps = %d..%d, want newest first a minute apart", newest.GetMessageTimestamp(), oldest.GetMessageTimestamp())
+	}
+	if !strings.HasPrefix(newest.GetKey().GetID(), "CHAOS") || newest.GetKey().GetID() == oldest.GetKey().GetID() {
+		t.Errorf("message IDs %s, %s", newest.GetKey().GetID(), oldest.GetKey().GetID())
+	}
+	if newest.GetMessage().GetConversation() != "Simulated history message 3" {
+		t.Errorf("newest content = %q", newest.GetMessage().GetConversation())
+	}
+}
+
+func TestSimulateValidation(t *testing.T) {
+	c := &Client{}
+	for _, code := range []string{"", "5033", "abc"} {
+		if err := c.SimulateStreamError(code); err == nil {
+			t.Errorf("SimulateStreamError(%q) accepted", code)
+		}
+	}
+
+	if err := c.SimulateHistorySync("sometimes", "123@s.whatsapp.net", 5); err == nil {
+		t.Error("unknown sync type accepted")
+	}
+	if err := c.SimulateHistorySync("recent", "123@s.whatsapp.net", MaxChaosHistoryMessages+1); err == nil {
+		t.Error("too many messages accepted")
+	}
+}
@@ -22,9 +22,16 @@ import (
 	"google.golang.org/protobuf/proto"
 
 	"whatsapp-bridge/internal/config"
+	"whatsapp-bridge/internal/msgrate"
+	"whatsapp-bridge/internal/redact"
+	"whatsapp-bridge/internal/retry"
 	localTypes "whatsapp-bridge/internal/types"
 )
 
+// DefaultTimeout bounds WhatsApp calls made outside an API request, such as
+// from event handlers and background jobs
+const DefaultTimeout = 30 * time.Second
+
 // Client wraps the whatsmeow client with additional functionality
 // for message handling, media operations, and group management.
 type Client struct {
@@ -45,6 +52,100 @@ type Client struct {
 	pairingExpiry     time.Time
 	pairingComplete   bool
 	pairingError      error
+	qrCode            string
+	qrExpiry          time.Time
+	pairingSubs       map[chan localTypes.PairingEvent]struct{}
+
+	// Phone code renewal and notification (see pairing.go)
+	pairingPhone    string
+	pairingAttempt  int
+	pairingTimer    *time.Timer
+	pairingGen      int
+	pairingCodeHook func(code localTypes.PairingCode)
+	requestPairCode func(ctx context.Context, phoneNumber string) (string, error)
+
+	// Receipt policy (see receipts.go)
+	receiptMu     sync.RWMutex
+	receiptPolicy localTypes.ReceiptPolicy
+
+	// Per-newsletter handling (see newsletters.go)
+	newsletterMu       sync.RWMutex
+	newsletterSettings map[string]localTypes.NewsletterSettings
+
+	// Metadata-only storage (see storage.go)
+	storageMu     sync.RWMutex
+	storagePolicy localTypes.StoragePolicy
+
+	// Chats stored and webhooked (see scope.go)
+	scopeMu   sync.RWMutex
+	chatScope localTypes.ChatScope
+
+	// Recipients recently confirmed on WhatsApp (see senderrors.go)
+	registeredMu sync.Mutex
+	registered   map[string]time.Time
+
+	// Own catalog products and their uploaded images (see catalog.go)
+	catalogCache productCache
+
+	// Convert mp3, m4a and wav media to voice notes (see voice.go)
+	voiceTranscode bool
+
+	// Media enrichment hooks keyed by annotation kind, and the workers
+	// running them (see annotations.go)
+	annotatorMu   sync.RWMutex
+	annotators    map[string]Annotator
+	annotatedHook func(a localTypes.MessageAnnotation)
+	annotateOnce  sync.Once
+	annotateJobs  chan annotateJob
+
+	// Background download of incoming media (see autodownload.go); set
+	// before connecting and read-only after
+	autoDownloads     chan autoDownloadJob
+	autoDownloadTypes map[string]bool
+	autoDownloadMax   uint64
+
+	// Reports the store volume short of space (see storage.go); set before
+	// connecting and read-only after
+	lowOnSpace func() bool
+
+	// History syncs held back while the store volume is low on space
+	deferredSyncMu sync.Mutex
+	deferredSyncs  []*events.HistorySync
+
+	// Rolling incoming message counts per chat and sender; set before
+	// connecting and read-only after
+	rates *msgrate.Tracker
+
+	// Outgoing acknowledgment tracking (see acks.go)
+	ackMu          sync.RWMutex
+	sendFailedHook func(msg *localTypes.OutgoingMessage)
+	sentHook       func(msg *localTypes.OutgoingMessage)
+
+	// Blocklist change notifications (see blocklist.go)
+	blocklistMu   sync.RWMutex
+	blocklistHook func(change localTypes.BlocklistChange)
+
+	// Group subject, description, picture and settings changes (see groupchanges.go)
+	groupChangeMu   sync.RWMutex
+	groupChangeHook func(change localTypes.GroupChange)
+
+	// Temporary ban and rate limit state (see restriction.go)
+	restrictionMu  sync.Mutex
+	restriction    *localTypes.AccountRestriction
+	restrictedHook func(r localTypes.AccountRestriction)
+
+	// Ping round trip and event handling lag (see quality.go)
+	qualityMu    sync.Mutex
+	quality      *localTypes.ConnectionQuality
+	handling     map[uint64]time.Time // start of each event still being handled
+	handlingSeq  uint64
+	slowestEvent time.Duration // since the previous sample
+	lagThreshold time.Duration
+	eventLagHook func(q localTypes.ConnectionQuality)
+
+	// Receipts for self-test messages (see selftest.go)
+	echoMu sync.Mutex
+	echoes map[types.MessageID]*echo
 }
 
 // NewClient creates a new WhatsApp client with default configuration.
@@ -57,7 +158,7 @@ func NewClient(logger waLog.Logger) (*Client, error) {
 // Configures history sync limits and creates/opens the session database.
 func NewClientWithConfig(logger waLog.Logger, cfg *config.Config) (*Client, error) {
 	// Create database connection for storing session data
-	dbLog := waLog.Stdout("Database", "INFO", true)
+	dbLog := redact.Logger(waLog.Stdout("Database", "INFO", true))
 
 	// Create directory for database if it doesn't exist
 	if err := os.MkdirAll("store", 0755); err != nil {
@@ -107,6 +208,10 @@ func NewClientWithConfig(logger waLog.Logger, cfg *config.Config) (*Client, erro
 		Client:    client,
 		logger:    logger,
 		startedAt: time.Now(),
+		receiptPolicy: localTypes.ReceiptPolicy{
+			ReadReceipts:     cfg.ReadReceipts,
+			DeliveryReceipts: cfg.DeliveryReceipts,
+		},
 	}
 
 	// Explicit auto-reconnect with failure circuit breaker
@@ -142,14 +247,19 @@ func (c *Client) Connect() error {
 			return fmt.Errorf("failed to connect: %v", err)
 		}
 
-		// Print QR code for pairing with phone
+		// Print QR code for pairing with phone (also published to /ui/pair subscribers)
 		for evt := range qrChan {
 			if evt.Event == "code" {
+				c.setQRCode(evt.Code, evt.Timeout)
 				fmt.Println("\nScan this QR code with your WhatsApp app:")
 				qrterminal.GenerateHalfBlock(evt.Code, qrterminal.L, os.Stdout)
 			} else if evt.Event == "success" {
+				c.setQRCode("", 0)
 				connected <- true
 				break
+			} else {
+				c.setQRCode("", 0)
+				c.publishPairingEvent(localTypes.PairingEvent{Event: evt.Event})
 			}
 		}
 
@@ -184,7 +294,7 @@ func (c *Client) Connect() error {
 
 // SetPresence sets the client's online status.
 // Valid values: "available" (online) or "unavailable" (offline).
-func (c *Client) SetPresence(presence string) error {
+func (c *Client) SetPresence(ctx context.Context, presence string) error {
 	var p types.Presence
 	switch presence {
 	case "available":
@@ -194,22 +304,22 @@ func (c *Client) SetPresence(presence string) error {
 	default:
 		return fmt.Errorf("invalid presence: %s (must be 'available' or 'unavailable')", presence)
 	}
-	return c.SendPresence(context.Background(), p)
+	return c.SendPresence(ctx, p)
 }
 
 // SubscribeToPresence subscribes to presence updates for a contact.
 // After subscribing, presence events will be received via event handlers.
-func (c *Client) SubscribeToPresence(jidStr string) error {
+func (c *Client) SubscribeToPresence(ctx context.Context, jidStr string) error {
 	jid, err := types.ParseJID(jidStr)
 	if err != nil {
 		return fmt.Errorf("invalid JID: %v", err)
 	}
-	return c.Client.SubscribePresence(context.Background(), jid)
+	return c.Client.SubscribePresence(ctx, jid)
 }
 
 // GetProfilePicture retrieves the profile picture URL for a user or group.
 // Set preview=true for thumbnail, false for full resolution image.
-func (c *Client) GetProfilePicture(jidStr string, preview bool) (*localTypes.ProfilePictureInfo, error) {
+func (c *Client) GetProfilePicture(ctx context.Context, jidStr string, preview bool) (*localTypes.ProfilePictureInfo, error) {
 	jid, err := types.ParseJID(jidStr)
 	if err != nil {
 		return nil, fmt.Errorf("invalid JID: %v", err)
@@ -219,7 +329,11 @@ func (c *Client) GetProfilePicture(jidStr string, preview bool) (*localTypes.Pro
 		Preview: preview,
 	}
 
-	info, err := c.GetProfilePictureInfo(context.Background(), jid, params)
+	var info *types.ProfilePictureInfo
+	err = retry.Do(ctx, fetchPolicy, func() (err error) {
+		info, err = c.GetProfilePictureInfo(ctx, jid, params)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get profile picture: %v", err)
 	}
@@ -237,8 +351,8 @@ func (c *Client) GetProfilePicture(jidStr string, preview bool) (*localTypes.Pro
 }
 
 // GetBlockedUsers returns the list of currently blocked users.
-func (c *Client) GetBlockedUsers() ([]localTypes.BlockedUser, error) {
-	blocklist, err := c.GetBlocklist(context.Background())
+func (c *Client) GetBlockedUsers(ctx context.Context) ([]localTypes.BlockedUser, error) {
+	blocklist, err := c.GetBlocklist(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get blocklist: %v", err)
 	}
@@ -252,7 +366,7 @@ func (c *Client) GetBlockedUsers() ([]localTypes.BlockedUser, error) {
 
 // UpdateBlockedUser blocks or unblocks a user.
 // Action must be "block" or "unblock".
-func (c *Client) UpdateBlockedUser(jidStr string, action string) error {
+func (c *Client) UpdateBlockedUser(ctx context.Context, jidStr string, action string) error {
 	jid, err := types.ParseJID(jidStr)
 	if err != nil {
 		return fmt.Errorf("invalid JID: %v", err)
@@ -268,7 +382,7 @@ func (c *Client) UpdateBlockedUser(jidStr string, action string) error {
 		return fmt.Errorf("invalid action: %s (must be 'block' or 'unblock')", action)
 	}
 
-	_, err = c.UpdateBlocklist(context.Background(), jid, blockAction)
+	_, err = c.UpdateBlocklist(ctx, jid, blockAction)
 	if err != nil {
 		return fmt.Errorf("failed to update blocklist: %v", err)
 	}
@@ -276,32 +390,32 @@ func (c *Client) UpdateBlockedUser(jidStr string, action string) error {
 }
 
 // FollowNewsletterChannel subscribes to a WhatsApp newsletter/channel.
-func (c *Client) FollowNewsletterChannel(jidStr string) error {
+func (c *Client) FollowNewsletterChannel(ctx context.Context, jidStr string) error {
 	jid, err := types.ParseJID(jidStr)
 	if err != nil {
 		return fmt.Errorf("invalid JID: %v", err)
 	}
-	return c.FollowNewsletter(context.Background(), jid)
+	return c.FollowNewsletter(ctx, jid)
 }
 
 // UnfollowNewsletterChannel unsubscribes from a WhatsApp newsletter/channel.
-func (c *Client) UnfollowNewsletterChannel(jidStr string) error {
+func (c *Client) UnfollowNewsletterChannel(ctx context.Context, jidStr string) error {
 	jid, err := types.ParseJID(jidStr)
 	if err != nil {
 		return fmt.Errorf("invalid JID: %v", err)
 	}
-	return c.UnfollowNewsletter(context.Background(), jid)
+	return c.UnfollowNewsletter(ctx, jid)
 }
 
 // CreateNewsletterChannel creates a new WhatsApp newsletter/channel.
 // Returns the created newsletter's JID, name, and description.
-func (c *Client) CreateNewsletterChannel(name, description string) (*localTypes.NewsletterInfo, error) {
+func (c *Client) CreateNewsletterChannel(ctx context.Context, name, description string) (*localTypes.NewsletterInfo, error) {
 	params := whatsmeow.CreateNewsletterParams{
 		Name:        name,
 		Description: description,
 	}
 
-	meta, err := c.CreateNewsletter(context.Background(), params)
+	meta, err := c.CreateNewsletter(ctx, params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create newsletter: %v", err)
 	}
@@ -313,11 +427,67 @@ func (c *Client) CreateNewsletterChannel(name, description string) (*localTypes.
 	}, nil
 }
 
+// GetNewsletterMessageStats fetches view and reaction counts for a single channel post.
+// Returns nil if the post does not exist.
+func (c *Client) GetNewsletterMessageStats(ctx context.Context, jidStr string, serverID int) (*localTypes.NewsletterMessageStats, error) {
+	jid, err := types.ParseJID(jidStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JID: %v", err)
+	}
+
+	// Posts are paged backwards from "before", so ask for the single post preceding serverID+1
+	msgs, err := c.GetNewsletterMessages(ctx, jid, &whatsmeow.GetNewsletterMessagesParams{
+		Count:  1,
+		Before: types.MessageServerID(serverID + 1),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get newsletter messages: %v", err)
+	}
+
+	return newsletterMessageStats(jid, msgs, serverID), nil
+}
+
+// newsletterMessageStats picks post serverID out of msgs and totals its
+// reactions. Returns nil if the post is not among them.
+func newsletterMessageStats(jid types.JID, msgs []*types.NewsletterMessage, serverID int) *localTypes.NewsletterMessageStats {
+	for _, msg := range msgs {
+		if int(msg.MessageServerID) != serverID {
+			continue
+		}
+
+		stats := &localTypes.NewsletterMessageStats{
+			JID:            jid.String(),
+			ServerID:       int(msg.MessageServerID),
+			MessageID:      string(msg.MessageID),
+			Type:           msg.Type,
+			Timestamp:      msg.Timestamp,
+			Content:        ExtractTextContent(msg.Message),
+			ViewsCount:     msg.ViewsCount,
+			ReactionCounts: make(map[string]int, len(msg.ReactionCounts)),
+		}
+		for emoji, count := range msg.ReactionCounts {
+			stats.ReactionCounts[emoji] = count
+			stats.TotalReactions += count
+		}
+		return stats
+	}
+	return nil
+}
+
+// ReactToNewsletterMessage sends (or removes, with an empty reaction) a reaction to a channel post.
+func (c *Client) ReactToNewsletterMessage(ctx context.Context, jidStr string, serverID int, reaction string) error {
+	jid, err := types.ParseJID(jidStr)
+	if err != nil {
+		return fmt.Errorf("invalid JID: %v", err)
+	}
+	return c.NewsletterSendReaction(ctx, jid, types.MessageServerID(serverID), reaction, "")
+}
+
 // Phase 6: Chat Features
 
 // SendTypingIndicator sends a typing/recording indicator to a chat.
 // State can be "typing" (composing), "paused" (stopped typing), or "recording" (voice message).
-func (c *Client) SendTypingIndicator(chatJID string, state string) error {
+func (c *Client) SendTypingIndicator(ctx context.Context, chatJID string, state string) error {
 	jid, err := types.ParseJID(chatJID)
 	if err != nil {
 		return fmt.Errorf("invalid chat JID: %v", err)
@@ -340,46 +510,72 @@ func (c *Client) SendTypingIndicator(chatJID string, state string) error {
 		return fmt.Errorf("invalid state: %s (must be 'typing', 'paused', or 'recording')", state)
 	}
 
-	return c.SendChatPresence(context.Background(), jid, chatState, media)
+	return c.SendChatPresence(ctx, jid, chatState, media)
 }
 
 // SetAboutText updates the user's profile "About" status text.
 // This is the text shown in the profile, not ephemeral status broadcasts.
-func (c *Client) SetAboutText(text string) error {
-	return c.SetStatusMessage(context.Background(), text)
+func (c *Client) SetAboutText(ctx context.Context, text string) error {
+	return c.SetStatusMessage(ctx, text)
 }
 
 // SetDisappearingTimer sets the disappearing messages timer for a chat.
 // Valid durations: "off", "24h", "7d", "90d".
 // In groups, only admins can change this setting.
-func (c *Client) SetDisappearingTimer(chatJID string, duration string) error {
+func (c *Client) SetDisappearingTimer(ctx context.Context, chatJID string, duration string) error {
 	jid, err := types.ParseJID(chatJID)
 	if err != nil {
 		return fmt.Errorf("invalid chat JID: %v", err)
 	}
 
-	var timer time.Duration
+	timer, err := parseDisappearingDuration(duration)
+	if err != nil {
+		return err
+	}
+
+	return c.Client.SetDisappearingTimer(ctx, jid, timer, time.Now())
+}
+
+// SetDefaultDisappearingTimer sets the disappearing messages timer applied to
+// new chats. Valid durations: "off", "24h", "7d", "90d". Existing chats keep
+// their own timers.
+func (c *Client) SetDefaultDisappearingTimer(ctx context.Context, duration string) error {
+	timer, err := parseDisappearingDuration(duration)
+	if err != nil {
+		return err
+	}
+
+	return c.Client.SetDefaultDisappearingTimer(ctx, timer)
+}
+
+// ValidDisappearingDuration reports whether duration is one the disappearing
+// timer setters accept
+func ValidDisappearingDuration(duration string) bool {
+	_, err := parseDisappearingDuration(duration)
+	return err == nil
+}
+
+// parseDisappearingDuration converts "off", "24h", "7d" or "90d" to a timer duration.
+func parseDisappearingDuration(duration string) (time.Duration, error) {
 	switch duration {
 	case "off", "0":
-		timer = 0
+		return 0, nil
 	case "24h":
-		timer = 24 * time.Hour
+		return 24 * time.Hour, nil
 	case "7d":
-		timer = 7 * 24 * time.Hour
+		return 7 * 24 * time.Hour, nil
 	case "90d":
-		timer = 90 * 24 * time.Hour
+		return 90 * 24 * time.Hour, nil
 	default:
-		return fmt.Errorf("invalid duration: %s (must be 'off', '24h', '7d', or '90d')", duration)
+		return 0, fmt.Errorf("invalid duration: %s (must be 'off', '24h', '7d', or '90d')", duration)
 	}
-
-	return c.Client.SetDisappearingTimer(context.Background(), jid, timer, time.Now())
 }
 
 // GetPrivacySettings fetches the current privacy settings for the user.
 // Returns a map of privacy setting categories and their values.
 // Valid values: "all", "contacts", "contact_blacklist", "none", "known", "match_last_seen".
-func (c *Client) GetPrivacySettings() (map[string]string, error) {
-	settings, err := c.Client.TryFetchPrivacySettings(context.Background(), false)
+func (c *Client) GetPrivacySettings(ctx context.Context) (map[string]string, error) {
+	settings, err := c.Client.TryFetchPrivacySettings(ctx, false)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch privacy settings: %v", err)
 	}
@@ -400,30 +596,30 @@ func (c *Client) GetPrivacySettings() (map[string]string, error) {
 }
 
 // PinChat pins a chat to the top of the chat list.
-func (c *Client) PinChat(chatJID string) error {
+func (c *Client) PinChat(ctx context.Context, chatJID string) error {
 	jid, err := types.ParseJID(chatJID)
 	if err != nil {
 		return fmt.Errorf("invalid chat JID: %v", err)
 	}
 
 	patch := appstate.BuildPin(jid, true)
-	return c.Client.SendAppState(context.Background(), patch)
+	return c.Client.SendAppState(ctx, patch)
 }
 
 // UnpinChat unpins a chat from the top of the chat list.
-func (c *Client) UnpinChat(chatJID string) error {
+func (c *Client) UnpinChat(ctx context.Context, chatJID string) error {
 	jid, err := types.ParseJID(chatJID)
 	if err != nil {
 		return fmt.Errorf("invalid chat JID: %v", err)
 	}
 
 	patch := appstate.BuildPin(jid, false)
-	return c.Client.SendAppState(context.Background(), patch)
+	return c.Client.SendAppState(ctx, patch)
 }
 
 // MuteChat mutes a chat for the specified duration.
 // Duration examples: "forever" (0), "15m", "1h", "8h", "1w".
-func (c *Client) MuteChat(chatJID string, duration string) error {
+func (c *Client) MuteChat(ctx context.Context, chatJID string, duration string) error {
 	jid, err := types.ParseJID(chatJID)
 	if err != nil {
 		return fmt.Errorf("invalid chat JID: %v", err)
@@ -446,51 +642,57 @@ func (c *Client) MuteChat(chatJID string, duration string) error {
 	}
 
 	patch := appstate.BuildMute(jid, true, muteDuration)
-	return c.Client.SendAppState(context.Background(), patch)
+	return c.Client.SendAppState(ctx, patch)
 }
 
 // UnmuteChat unmutes a chat.
-func (c *Client) UnmuteChat(chatJID string) error {
+func (c *Client) UnmuteChat(ctx context.Context, chatJID string) error {
 	jid, err := types.ParseJID(chatJID)
 	if err != nil {
 		return fmt.Errorf("invalid chat JID: %v", err)
 	}
 
 	patch := appstate.BuildMute(jid, false, 0)
-	return c.Client.SendAppState(context.Background(), patch)
+	return c.Client.SendAppState(ctx, patch)
 }
 
 // ArchiveChat archives a chat.
-func (c *Client) ArchiveChat(chatJID string) error {
+func (c *Client) ArchiveChat(ctx context.Context, chatJID string) error {
 	jid, err := types.ParseJID(chatJID)
 	if err != nil {
 		return fmt.Errorf("invalid chat JID: %v", err)
 	}
 
 	patch := appstate.BuildArchive(jid, true, time.Time{}, nil)
-	return c.Client.SendAppState(context.Background(), patch)
+	return c.Client.SendAppState(ctx, patch)
 }
 
 // UnarchiveChat unarchives a chat.
-func (c *Client) UnarchiveChat(chatJID string) error {
+func (c *Client) UnarchiveChat(ctx context.Context, chatJID string) error {
 	jid, err := types.ParseJID(chatJID)
 	if err != nil {
 		return fmt.Errorf("invalid chat JID: %v", err)
 	}
 
 	patch := appstate.BuildArchive(jid, false, time.Time{}, nil)
-	return c.Client.SendAppState(context.Background(), patch)
+	return c.Client.SendAppState(ctx, patch)
 }
 
 // Connection state tracking methods
 
-// MarkConnected records a successful connection event.
+// MarkConnected records a successful connection event. Connecting at all
+// means any temporary ban has ended.
 func (c *Client) MarkConnected() {
 	c.connMu.Lock()
-	defer c.connMu.Unlock()
+	if !c.disconnectedAt.IsZero() {
+		reconnects.Inc()
+	}
 	c.lastConnectedAt = time.Now()
 	c.disconnectedAt = time.Time{}
 	c.autoReconnectErrors = 0
+	c.connMu.Unlock()
+
+	c.liftTemporaryBan()
 }
 
 // MarkDisconnected records a disconnection event.
@@ -511,16 +713,19 @@ func (c *Client) ConnectionState() (startedAt, lastConnected, disconnectedAt tim
 
 // Phase 7: Phone Number Pairing
 
-// PairWithPhone initiates phone number pairing and returns 8-digit code
-func (c *Client) PairWithPhone(phoneNumber string) (string, error) {
+// PairWithPhone initiates phone number pairing and returns 8-digit code.
+// Until pairing succeeds, fails or is cancelled, an expired code is
+// replaced automatically (see pairing.go).
+func (c *Client) PairWithPhone(ctx context.Context, phoneNumber string) (string, error) {
 	c.pairingMutex.Lock()
-	defer c.pairingMutex.Unlock()
 
 	if c.pairingInProgress {
+		c.pairingMutex.Unlock()
 		return "", fmt.Errorf("pairing already in progress")
 	}
 
 	if c.Store.ID != nil {
+		c.pairingMutex.Unlock()
 		return "", fmt.Errorf("device already linked")
 	}
 
@@ -528,27 +733,23 @@ func (c *Client) PairWithPhone(phoneNumber string) (string, error) {
 	c.pairingInProgress = true
 	c.pairingComplete = false
 	c.pairingError = nil
+	c.pairingPhone = phoneNumber
+	c.pairingAttempt = 0
+	gen := c.pairingGen
+	c.pairingMutex.Unlock()
 
-	// Connect if not connected
-	if !c.IsConnected() {
-		if err := c.Client.Connect(); err != nil {
+	code, err := c.issuePairingCode(ctx, gen)
+	if err != nil {
+		c.pairingMutex.Lock()
+		if gen == c.pairingGen {
 			c.pairingInProgress = false
-			return "", fmt.Errorf("failed to connect: %v", err)
 		}
+		c.pairingMutex.Unlock()
+		return "", err
 	}
 
-	// Request pairing code
-	code, err := c.Client.PairPhone(context.Background(), phoneNumber, true, whatsmeow.PairClientChrome, "Chrome (Linux)")
-	if err != nil {
-		c.pairingInProgress = false
-		return "", fmt.Errorf("failed to request pairing code: %v", err)
-	}
-
-	c.pairingCode = code
-	c.pairingExpiry = time.Now().Add(160 * time.Second)
-
-	c.logger.Infof("Pairing code generated: %s (expires in 160s)", code)
-	return code, nil
+	c.notifyPairingCode(code)
+	return code.Code, nil
 }
 
 // GetPairingStatus returns current pairing state
@@ -574,7 +775,15 @@ func (c *Client) HandlePairingSuccess() {
 
 	c.pairingComplete = true
 	c.pairingInProgress = false
+	c.stopPairingRenewalLocked()
+	c.qrCode = ""
 	c.logger.Infof("Pairing successful!")
+
+	evt := localTypes.PairingEvent{Event: "success"}
+	if c.Store.ID != nil {
+		evt.JID = c.Store.ID.ToNonAD().String()
+	}
+	c.publishPairingEventLocked(evt)
 }
 
 // HandlePairingError called when pairing fails
@@ -584,5 +793,75 @@ func (c *Client) HandlePairingError(err error) {
 
 	c.pairingError = err
 	c.pairingInProgress = false
+	c.stopPairingRenewalLocked()
 	c.logger.Errorf("Pairing failed: %v", err)
+	c.publishPairingEventLocked(localTypes.PairingEvent{Event: "error", Error: err.Error()})
+}
+
+// GetQRCode returns the most recent login QR code and its remaining lifetime.
+// The code is empty when no QR login is pending.
+func (c *Client) GetQRCode() (code string, expiresIn int) {
+	c.pairingMutex.Lock()
+	defer c.pairingMutex.Unlock()
+
+	if c.qrCode == "" {
+		return "", 0
+	}
+	if remaining := time.Until(c.qrExpiry); remaining > 0 {
+		expiresIn = int(remaining.Seconds())
+	}
+	return c.qrCode, expiresIn
+}
+
+// SubscribePairing registers a listener for QR and pairing status updates.
+// The returned cancel function must be called to release the subscription.
+func (c *Client) SubscribePairing() (<-chan localTypes.PairingEvent, func()) {
+	ch := make(chan localTypes.PairingEvent, 8)
+
+	c.pairingMutex.Lock()
+	if c.pairingSubs == nil {
+		c.pairingSubs = make(map[chan localTypes.PairingEvent]struct{})
+	}
+	c.pairingSubs[ch] = struct{}{}
+	c.pairingMutex.Unlock()
+
+	cancel := func() {
+		c.pairingMutex.Lock()
+		defer c.pairingMutex.Unlock()
+		if _, ok := c.pairingSubs[ch]; ok {
+			delete(c.pairingSubs, ch)
+			close(ch)
+		}
+	}
+	return ch, cancel
+}
+
+// setQRCode records the current login QR code and notifies subscribers.
+func (c *Client) setQRCode(code string, timeout time.Duration) {
+	c.pairingMutex.Lock()
+	defer c.pairingMutex.Unlock()
+
+	c.qrCode = code
+	c.qrExpiry = time.Now().Add(timeout)
+	if code != "" {
+		c.publishPairingEventLocked(localTypes.PairingEvent{Event: "code", ExpiresIn: int(timeout.Seconds())})
+	}
+}
+
+// publishPairingEvent notifies all pairing subscribers of an event.
+func (c *Client) publishPairingEvent(evt localTypes.PairingEvent) {
+	c.pairingMutex.Lock()
+	defer c.pairingMutex.Unlock()
+	c.publishPairingEventLocked(evt)
+}
+
+// publishPairingEventLocked delivers evt without blocking; slow subscribers miss events.
+// Caller must hold pairingMutex.
+func (c *Client) publishPairingEventLocked(evt localTypes.PairingEvent) {
+	for ch := range c.pairingSubs {
+		select {
+		case ch <- evt:
+		default:
+		}
+	}
 }
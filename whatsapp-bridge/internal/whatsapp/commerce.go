@@ -0,0 +1,125 @@
+package whatsapp
+
+import (
+	"strings"
+
+	"whatsapp-bridge/internal/database"
+	localTypes "whatsapp-bridge/internal/types"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// ExtractCommerce parses order, product and payment messages into a structured
+// row. Returns nil for any other message type.
+func ExtractCommerce(msg *events.Message) *localTypes.CommerceMessage {
+	m := msg.Message
+	if m == nil {
+		return nil
+	}
+
+	cm := &localTypes.CommerceMessage{
+		MessageID: msg.Info.ID,
+		ChatJID:   msg.Info.Chat.String(),
+		SenderJID: msg.Info.Sender.ToNonAD().String(),
+		IsFromMe:  msg.Info.IsFromMe,
+		Timestamp: msg.Info.Timestamp,
+	}
+
+	switch {
+	case m.GetOrderMessage() != nil:
+		order := m.GetOrderMessage()
+		cm.Kind = localTypes.CommerceOrder
+		cm.ReferenceID = order.GetOrderID()
+		cm.Title = order.GetOrderTitle()
+		cm.Note = order.GetMessage()
+		cm.SellerJID = order.GetSellerJID()
+		cm.ItemCount = int(order.GetItemCount())
+		cm.Amount1000 = order.GetTotalAmount1000()
+		cm.Currency = order.GetTotalCurrencyCode()
+		if order.Status != nil {
+			cm.Status = strings.ToLower(order.GetStatus().String())
+		}
+
+	case m.GetProductMessage() != nil:
+		pm := m.GetProductMessage()
+		product := pm.GetProduct()
+		price := product.GetSalePriceAmount1000()
+		if price == 0 {
+			price = product.GetPriceAmount1000()
+		}
+		cm.Kind = localTypes.CommerceProduct
+		cm.ReferenceID = product.GetProductID()
+		cm.Title = product.GetTitle()
+		cm.Note = pm.GetBody()
+		cm.SellerJID = pm.GetBusinessOwnerJID()
+		cm.ItemCount = 1
+		cm.Items = []localTypes.CommerceItem{{
+			ProductID:  product.GetProductID(),
+			RetailerID: product.GetRetailerID(),
+			Title:      product.GetTitle(),
+			Quantity:   1,
+			Price1000:  price,
+			Currency:   product.GetCurrencyCode(),
+		}}
+		cm.Amount1000 = price
+		cm.Currency = product.GetCurrencyCode()
+
+	case m.GetRequestPaymentMessage() != nil:
+		req := m.GetRequestPaymentMessage()
+		cm.Kind = localTypes.CommercePaymentRequest
+		cm.Note = ExtractTextContent(req.GetNoteMessage())
+		cm.Amount1000, cm.Currency = paymentAmount(req)
+
+	case m.GetSendPaymentMessage() != nil:
+		sent := m.GetSendPaymentMessage()
+		cm.Kind = localTypes.CommercePaymentSent
+		cm.ReferenceID = sent.GetRequestMessageKey().GetID()
+		cm.Note = ExtractTextContent(sent.GetNoteMessage())
+
+	case m.GetPaymentInviteMessage() != nil:
+		cm.Kind = localTypes.CommercePaymentInvite
+		cm.Status = strings.ToLower(m.GetPaymentInviteMessage().GetServiceType().String())
+
+	case m.GetDeclinePaymentRequestMessage() != nil:
+		cm.Kind = localTypes.CommercePaymentDeclined
+		cm.ReferenceID = m.GetDeclinePaymentRequestMessage().GetKey().GetID()
+
+	case m.GetCancelPaymentRequestMessage() != nil:
+		cm.Kind = localTypes.CommercePaymentCanceled
+		cm.ReferenceID = m.GetCancelPaymentRequestMessage().GetKey().GetID()
+
+	default:
+		return nil
+	}
+
+	return cm
+}
+
+// paymentAmount returns a payment request's amount in thousandths, preferring
+// the newer Money field over the legacy amount1000/currency pair
+func paymentAmount(req *waE2E.RequestPaymentMessage) (int64, string) {
+	if money := req.GetAmount(); money != nil && money.GetOffset() > 0 {
+		return money.GetValue() * 1000 / int64(money.GetOffset()), money.GetCurrencyCode()
+	}
+	return int64(req.GetAmount1000()), req.GetCurrencyCodeIso4217()
+}
+
+// storeCommerce records a commerce message and raises order_received for
+// incoming orders
+func (c *Client) storeCommerce(messageStore *database.MessageStore, webhookManager interface{}, cm *localTypes.CommerceMessage, persist, deliver bool) {
+	if persist {
+		if err := messageStore.StoreCommerceMessage(cm); err != nil {
+			c.logger.Warnf("Failed to store %s message: %v", cm.Kind, err)
+		}
+	}
+
+	if cm.Kind != localTypes.CommerceOrder || cm.IsFromMe || webhookManager == nil || !deliver {
+		return
+	}
+	if wm, ok := webhookManager.(interface {
+		ProcessOrder(order *localTypes.CommerceMessage)
+	}); ok {
+		wm.ProcessOrder(cm)
+	}
+}
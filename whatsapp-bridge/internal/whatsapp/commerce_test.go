@@ -0,0 +1,83 @@
+package whatsapp
+
+import (
+	"testing"
+
+	localTypes "whatsapp-bridge/internal/types"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+func commerceEvent(m *waE2E.Message) *events.Message {
+	return &events.Message{
+		Info: types.MessageInfo{
+			MessageSource: types.MessageSource{
+				Chat:   types.NewJID("111", types.DefaultUserServer),
+				Sender: types.NewJID("111", types.DefaultUserServer),
+			},
+			ID: "MSG1",
+		},
+		Message: m,
+	}
+}
+
+func TestExtractCommerce(t *testing.T) {
+	status := waE2E.OrderMessage_INQUIRY
+	order := ExtractCommerce(commerceEvent(&waE2E.Message{
+		OrderMessage: &waE2E.OrderMessage{
+			OrderID:           proto.String("ORD-1"),
+			ItemCount:         proto.Int32(3),
+			Status:            &status,
+			OrderTitle:        proto.String("Coffee beans"),
+			SellerJID:         proto.String("222@s.whatsapp.net"),
+			TotalAmount1000:   proto.Int64(25500),
+			TotalCurrencyCode: proto.String("EUR"),
+		},
+	}))
+	if order == nil || order.Kind != localTypes.CommerceOrder {
+		t.Fatalf("Expected order, got %+v", order)
+	}
+	if order.ReferenceID != "ORD-1" || order.ItemCount != 3 || order.Amount1000 != 25500 || order.Currency != "EUR" || order.Status != "inquiry" {
+		t.Errorf("Unexpected order fields: %+v", order)
+	}
+
+	product := ExtractCommerce(commerceEvent(&waE2E.Message{
+		ProductMessage: &waE2E.ProductMessage{
+			Product: &waE2E.ProductMessage_ProductSnapshot{
+				ProductID:           proto.String("P1"),
+				Title:               proto.String("Mug"),
+				CurrencyCode:        proto.String("USD"),
+				PriceAmount1000:     proto.Int64(12000),
+				SalePriceAmount1000: proto.Int64(9000),
+			},
+		},
+	}))
+	if product == nil || product.Kind != localTypes.CommerceProduct || len(product.Items) != 1 {
+		t.Fatalf("Expected product with one item, got %+v", product)
+	}
+	if product.Items[0].Price1000 != 9000 || product.Amount1000 != 9000 {
+		t.Errorf("Expected sale price to win, got %+v", product.Items[0])
+	}
+
+	request := ExtractCommerce(commerceEvent(&waE2E.Message{
+		RequestPaymentMessage: &waE2E.RequestPaymentMessage{
+			Amount: &waE2E.Money{Value: proto.Int64(1234), Offset: proto.Uint32(100), CurrencyCode: proto.String("INR")},
+			NoteMessage: &waE2E.Message{
+				Conversation: proto.String("Dinner"),
+			},
+		},
+	}))
+	if request == nil || request.Kind != localTypes.CommercePaymentRequest {
+		t.Fatalf("Expected payment request, got %+v", request)
+	}
+	if request.Amount1000 != 12340 || request.Currency != "INR" || request.Note != "Dinner" {
+		t.Errorf("Unexpected payment request fields: %+v", request)
+	}
+
+	if cm := ExtractCommerce(commerceEvent(&waE2E.Message{Conversation: proto.String("hi")})); cm != nil {
+		t.Errorf("Expected nil for text message, got %+v", cm)
+	}
+}
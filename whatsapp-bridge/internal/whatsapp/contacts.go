@@ -0,0 +1,132 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"google.golang.org/protobuf/proto"
+
+	"whatsapp-bridge/internal/database"
+	"whatsapp-bridge/internal/phone"
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+// SendContacts sends one contact card, or several as a contacts array, on
+// behalf of the owner tenant
+func (c *Client) SendContacts(ctx context.Context, messageStore *database.MessageStore, owner, recipient string, cards []localTypes.ContactCard) localTypes.SendResult {
+	if !c.IsConnected() {
+		return sendFailure(SendErrNotConnected, true, "Not connected to WhatsApp")
+	}
+
+	recipientJID, err := parseRecipient(recipient)
+	if err != nil {
+		return sendFailure(SendErrInvalidRecipient, false, "Error parsing JID: %v", err)
+	}
+
+	msg, content, err := buildContactsMessage(cards)
+	if err != nil {
+		return sendFailure(SendErrInvalidContact, false, "%v", err)
+	}
+
+	if !c.checkRegistered(ctx, recipientJID) {
+		return sendFailure(SendErrNotOnWhatsApp, false, "Recipient %s is not on WhatsApp", recipientJID.User)
+	}
+
+	return c.sendTracked(ctx, messageStore, owner, recipientJID, msg, content)
+}
+
+// buildContactsMessage builds a contact message for one card and a contacts
+// array for several. content names the contacts for the send log.
+func buildContactsMessage(cards []localTypes.ContactCard) (*waE2E.Message, string, error) {
+	if len(cards) == 0 {
+		return nil, "", fmt.Errorf("no contact to send")
+	}
+
+	contacts := make([]*waE2E.ContactMessage, len(cards))
+	names := make([]string, len(cards))
+	for i, card := range cards {
+		contact, err := buildContactCard(card)
+		if err != nil {
+			if len(cards) > 1 {
+				return nil, "", fmt.Errorf("contact %d: %v", i+1, err)
+			}
+			return nil, "", err
+		}
+		contacts[i] = contact
+		names[i] = contact.GetDisplayName()
+	}
+
+	if len(contacts) == 1 {
+		return &waE2E.Message{ContactMessage: contacts[0]}, names[0], nil
+	}
+	return &waE2E.Message{
+		ContactsArrayMessage: &waE2E.ContactsArrayMessage{
+			DisplayName: proto.String(strconv.Itoa(len(contacts)) + " contacts"),
+			Contacts:    contacts,
+		},
+	}, strings.Join(names, ", "), nil
+}
+
+// buildContactCard turns a card into a contact message. A raw vCard is sent
+// as given, named by its FN line unless a name is given; otherwise a vCard
+// is written from the name and phone number, with the waid parameter that
+// lets the app offer to message the contact.
+func buildContactCard(card localTypes.ContactCard) (*waE2E.ContactMessage, error) {
+	name := strings.TrimSpace(card.Name)
+
+	if vcard := strings.TrimSpace(card.VCard); vcard != "" {
+		if !strings.HasPrefix(strings.ToUpper(vcard), "BEGIN:VCARD") || !strings.Contains(strings.ToUpper(vcard), "END:VCARD") {
+			return nil, fmt.Errorf("vcard must start with BEGIN:VCARD and end with END:VCARD")
+		}
+		if name == "" {
+			name = vcardName(vcard)
+		}
+		if name == "" {
+			return nil, fmt.Errorf("name is required when the vcard has no FN line")
+		}
+		return &waE2E.ContactMessage{DisplayName: proto.String(name), Vcard: proto.String(vcard)}, nil
+	}
+
+	if name == "" || card.Phone == "" {
+		return nil, fmt.Errorf("name and phone, or vcard, are required")
+	}
+	number, err := phone.Parse(card.Phone, "")
+	if err != nil {
+		return nil, err
+	}
+
+	escaped := vcardEscape(name)
+	vcard := strings.Join([]string{
+		"BEGIN:VCARD",
+		"VERSION:3.0",
+		"N:;" + escaped + ";;;",
+		"FN:" + escaped,
+		"TEL;type=CELL;type=VOICE;waid=" + number.Digits + ":" + number.E164,
+		"END:VCARD",
+	}, "\n")
+	return &waE2E.ContactMessage{DisplayName: proto.String(name), Vcard: proto.String(vcard)}, nil
+}
+
+// vcardName returns the formatted name (FN) of a vCard, or ""
+func vcardName(vcard string) string {
+	for _, line := range strings.Split(vcard, "\n") {
+		line = strings.TrimRight(line, "\r")
+		property, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		property, _, _ = strings.Cut(property, ";")
+		if strings.EqualFold(property, "FN") {
+			return strings.TrimSpace(strings.NewReplacer(`\,`, ",", `\;`, ";", `\\`, `\`).Replace(value))
+		}
+	}
+	return ""
+}
+
+// vcardEscape escapes a vCard text value
+func vcardEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,49 @@
+package whatsapp
+
+import (
+	"strings"
+	"testing"
+
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+func TestBuildContactsMessage(t *testing.T) {
+	msg, content, err := buildContactsMessage([]localTypes.ContactCard{{Name: "Ana, Support", Phone: "+1 (555) 010-2030"}})
+	if err != nil {
+		t.Fatalf("buildContactsMessage: %v", err)
+	}
+	contact := msg.GetContactMessage()
+	if contact.GetDisplayName() != "Ana, Support" || content != "Ana, Support" {
+		t.Errorf("display name = %q, content = %q", contact.GetDisplayName(), content)
+	}
+	for _, want := range []string{"FN:Ana\\, Support\n", "TEL;type=CELL;type=VOICE;waid=15550102030:+15550102030\n"} {
+		if !strings.Contains(contact.GetVcard(), want) {
+			t.Errorf("vcard is missing %q:\n%s", want, contact.GetVcard())
+		}
+	}
+
+	// A raw vCard is sent as is and named by its FN
+	raw := "BEGIN:VCARD\r\nVERSION:3.0\r\nFN:Ben\\; Sales\r\nTEL:+15550102031\r\nEND:VCARD"
+	msg, _, err = buildContactsMessage([]localTypes.ContactCard{{VCard: raw}, {Name: "Cleo", Phone: "15550102032"}})
+	if err != nil {
+		t.Fatalf("buildContactsMessage(array): %v", err)
+	}
+	array := msg.GetContactsArrayMessage()
+	if array == nil || len(array.GetContacts()) != 2 || array.GetDisplayName() != "2 contacts" {
+		t.Fatalf("contacts array = %v", msg)
+	}
+	if got := array.GetContacts()[0]; got.GetDisplayName() != "Ben; Sales" || got.GetVcard() != raw {
+		t.Errorf("raw card = %q, %q", got.GetDisplayName(), got.GetVcard())
+	}
+
+	for _, cards := range [][]localTypes.ContactCard{
+		{{Name: "Ana"}},
+		{{Name: "Ana", Phone: "12"}},
+		{{VCard: "FN:Ana"}},
+		{{VCard: "BEGIN:VCARD\nTEL:+15550102030\nEND:VCARD"}},
+	} {
+		if _, _, err := buildContactsMessage(cards); err == nil {
+			t.Errorf("buildContactsMessage(%+v) accepted an unusable card", cards)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package whatsapp
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+
+	"whatsapp-bridge/internal/database"
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+// webhookEvent is a received message as kept until its webhooks are matched
+type webhookEvent struct {
+	Info    json.RawMessage `json:"info"`
+	Message []byte          `json:"message"`
+}
+
+// EncodeWebhookEvent encodes a received message to keep until its webhooks
+// are matched. The sender's verified business certificate is left out.
+func EncodeWebhookEvent(msg *events.Message) ([]byte, error) {
+	info := msg.Info
+	info.VerifiedName = nil
+	infoJSON, err := json.Marshal(info)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message info: %v", err)
+	}
+	message, err := proto.Marshal(msg.Message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message: %v", err)
+	}
+	return json.Marshal(webhookEvent{Info: infoJSON, Message: message})
+}
+
+// DecodeWebhookEvent decodes a message encoded by EncodeWebhookEvent
+func DecodeWebhookEvent(data []byte) (*events.Message, error) {
+	var ev webhookEvent
+	if err := json.Unmarshal(data, &ev); err != nil {
+		return nil, fmt.Errorf("failed to decode webhook event: %v", err)
+	}
+	msg := &events.Message{Message: &waE2E.Message{}}
+	if err := json.Unmarshal(ev.Info, &msg.Info); err != nil {
+		return nil, fmt.Errorf("failed to decode message info: %v", err)
+	}
+	if err := proto.Unmarshal(ev.Message, msg.Message); err != nil {
+		return nil, fmt.Errorf("failed to decode message: %v", err)
+	}
+	return msg, nil
+}
+
+// webhooksMatch reports whether any webhook fires for msg, so a message is
+// only kept for dispatch when it has webhooks to send
+func webhooksMatch(webhookManager interface{}, msg *events.Message, chatName string) bool {
+	wm, ok := webhookManager.(interface {
+		MatchesTriggers(msg *events.Message, chatName string) []*localTypes.WebhookConfig
+	})
+	return ok && len(wm.MatchesTriggers(msg, chatName)) > 0
+}
+
+// ingestMessage stores a received message together with the record that its
+// webhooks are still to be sent, when they are to be sent at all. With
+// redact, e.g. for chats not stored or stored without content, the record
+// keeps the message's info but not the message itself; resumed after a
+// restart it then only fires webhooks that do not look at the content. It
+// returns the dispatch ID to hand the webhook manager, or 0 when nothing was
+// recorded.
+func (c *Client) ingestMessage(messageStore *database.MessageStore, msg *events.Message, chatName string, dispatch, redact bool, store func(*database.Ingest) error) int64 {
+	ingest, err := messageStore.BeginIngest()
+	if err != nil {
+		c.logger.Warnf("Failed to store message: %v", err)
+		return 0
+	}
+	defer ingest.Rollback()
+
+	// A failed insert does not abort the transaction, so the webhooks still go
+	// out as they did before the message was kept
+	if err := store(ingest); err != nil {
+		c.logger.Warnf("Failed to store message: %v", err)
+	}
+
+	var d localTypes.WebhookDispatch
+	if dispatch {
+		d = localTypes.WebhookDispatch{
+			ChatJID:   msg.Info.Chat.String(),
+			MessageID: msg.Info.ID,
+			ChatName:  chatName,
+			CreatedAt: msg.Info.Timestamp,
+		}
+		event := msg
+		if redact {
+			event = &events.Message{Info: msg.Info, Message: &waE2E.Message{}}
+		}
+		if d.Event, err = EncodeWebhookEvent(event); err != nil {
+			c.logger.Warnf("Failed to keep webhooks of %s: %v", msg.Info.ID, err)
+		} else if err := ingest.QueueWebhookDispatch(&d); err != nil {
+			c.logger.Warnf("Failed to keep webhooks of %s: %v", msg.Info.ID, err)
+			d.ID = 0
+		}
+	}
+
+	if err := ingest.Commit(); err != nil {
+		c.logger.Warnf("Failed to store message: %v", err)
+		return 0
+	}
+	return d.ID
+}
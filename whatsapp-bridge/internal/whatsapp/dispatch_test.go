@@ -0,0 +1,118 @@
+package whatsapp
+
+import (
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	waLog "go.mau.fi/whatsmeow/util/log"
+	"google.golang.org/protobuf/proto"
+
+	"whatsapp-bridge/internal/database"
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+func TestWebhookEventRoundTrip(t *testing.T) {
+	chat := types.NewJID("123456789", types.GroupServer)
+	sender := types.NewJID("15550102030", types.DefaultUserServer)
+	msg := &events.Message{
+		Info: types.MessageInfo{
+			MessageSource: types.MessageSource{Chat: chat, Sender: sender, IsGroup: true},
+			ID:            "3EB0ABC",
+			PushName:      "Ann",
+			Timestamp:     time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC),
+			VerifiedName:  &types.VerifiedName{},
+		},
+		Message: &waE2E.Message{Conversation: proto.String("order 42 is late")},
+	}
+
+	data, err := EncodeWebhookEvent(msg)
+	if err != nil {
+		t.Fatalf("EncodeWebhookEvent: %v", err)
+	}
+	got, err := DecodeWebhookEvent(data)
+	if err != nil {
+		t.Fatalf("DecodeWebhookEvent: %v", err)
+	}
+
+	if got.Info.Chat != chat || got.Info.Sender != sender || !got.Info.IsGroup {
+		t.Errorf("source = %+v", got.Info.MessageSource)
+	}
+	if !got.Info.SenderAlt.IsEmpty() {
+		t.Errorf("empty SenderAlt decoded as %s", got.Info.SenderAlt)
+	}
+	if got.Info.ID != "3EB0ABC" || got.Info.PushName != "Ann" || !got.Info.Timestamp.Equal(msg.Info.Timestamp) {
+		t.Errorf("info = %+v", got.Info)
+	}
+	if got.Info.VerifiedName != nil {
+		t.Error("verified name was kept")
+	}
+	if text := ExtractTextContent(got.Message); text != "order 42 is late" {
+		t.Errorf("content = %q", text)
+	}
+
+	if _, err := DecodeWebhookEvent([]byte("not an event")); err == nil {
+		t.Error("decoding garbage succeeded")
+	}
+}
+
+// matcher fires webhooks for messages whose text is "match"
+type matcher struct{}
+
+func (matcher) MatchesTriggers(msg *events.Message, chatName string) []*localTypes.WebhookConfig {
+	if ExtractTextContent(msg.Message) == "match" {
+		return []*localTypes.WebhookConfig{{ID: 1}}
+	}
+	return nil
+}
+
+func TestIngestMessageDispatch(t *testing.T) {
+	t.Chdir(t.TempDir())
+	store, err := database.NewMessageStore()
+	if err != nil {
+		t.Fatalf("NewMessageStore: %v", err)
+	}
+	defer store.Close()
+	c := &Client{logger: waLog.Noop}
+
+	message := func(id, text string) *events.Message {
+		return &events.Message{
+			Info: types.MessageInfo{
+				MessageSource: types.MessageSource{Chat: types.NewJID("15550102030", types.DefaultUserServer)},
+				ID:            id,
+				Timestamp:     time.Now(),
+			},
+			Message: &waE2E.Message{Conversation: proto.String(text)},
+		}
+	}
+	ingest := func(msg *events.Message, redact bool) int64 {
+		return c.ingestMessage(store, msg, "Ann", webhooksMatch(matcher{}, msg, "Ann"), redact, func(*database.Ingest) error { return nil })
+	}
+
+	if id := ingest(message("M1", "no webhook for this"), false); id != 0 {
+		t.Errorf("message no webhook fires for kept for dispatch as %d", id)
+	}
+	if id := ingest(message("M2", "match"), false); id == 0 {
+		t.Error("message a webhook fires for not kept for dispatch")
+	}
+	if id := ingest(message("M3", "match"), true); id == 0 {
+		t.Error("redacted message not kept for dispatch")
+	}
+
+	dispatches, err := store.ListWebhookDispatches()
+	if err != nil || len(dispatches) != 2 {
+		t.Fatalf("ListWebhookDispatches = %+v, %v", dispatches, err)
+	}
+	for _, d := range dispatches {
+		msg, err := DecodeWebhookEvent(d.Event)
+		if err != nil {
+			t.Fatalf("DecodeWebhookEvent: %v", err)
+		}
+		text := ExtractTextContent(msg.Message)
+		if msg.Info.ID != d.MessageID || (d.MessageID == "M2") != (text == "match") {
+			t.Errorf("dispatch of %s kept %q", d.MessageID, text)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"google.golang.org/protobuf/proto"
+
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+// mediaDir is where downloaded message media is saved, one directory per chat
+const mediaDir = "store/media"
+
+// DownloadMessageMedia downloads and decrypts a stored message's media into
+// store/media/{chat}/ and returns where it was saved. Media downloaded
+// before, to there or to its recorded local path, is not fetched again.
+// Nothing is fetched while the store volume is low on space.
+func (c *Client) DownloadMessageMedia(ctx context.Context, media *localTypes.MessageMedia) (*localTypes.DownloadedMedia, error) {
+	path := media.LocalPath
+	if path == "" {
+		path = mediaPath(media)
+	}
+	result := &localTypes.DownloadedMedia{Path: path, MediaType: media.MediaType, Filename: media.Filename}
+	if info, err := os.Stat(path); err == nil && (media.FileLength == 0 || uint64(info.Size()) == media.FileLength) {
+		result.Size = info.Size()
+		return result, nil
+	}
+
+	if c.spaceLow() {
+		return nil, ErrLowOnSpace
+	}
+	if !c.IsConnected() {
+		return nil, fmt.Errorf("not connected to WhatsApp")
+	}
+	msg := downloadableMessage(media)
+	if msg == nil {
+		return nil, fmt.Errorf("cannot download %s media", media.MediaType)
+	}
+	data, err := c.Client.DownloadAny(ctx, msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download media: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create media directory: %v", err)
+	}
+	// Written aside and renamed so a failed write never looks downloaded
+	tmp := path + ".part"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return nil, fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	result.Size = int64(len(data))
+	return result, nil
+}
+
+// mediaPath is where a message's media is saved. The message ID keeps files
+// with the same name apart.
+func mediaPath(media *localTypes.MessageMedia) string {
+	name := media.Filename
+	if name == "" {
+		name = media.MediaType
+	}
+	return filepath.Join(mediaDir, safeFileName(media.ChatJID), safeFileName(media.MessageID+"_"+safeFileName(name)))
+}
+
+// downloadableMessage rebuilds the part of a message that carried stored
+// media, or returns nil for a media type that cannot be downloaded
+func downloadableMessage(media *localTypes.MessageMedia) *waE2E.Message {
+	url, length := proto.String(media.URL), proto.Uint64(media.FileLength)
+	switch media.MediaType {
+	case "image":
+		return &waE2E.Message{ImageMessage: &waE2E.ImageMessage{
+			URL: url, MediaKey: media.MediaKey, FileSHA256: media.FileSHA256, FileEncSHA256: media.FileEncSHA256, FileLength: length,
+		}}
+	case "video", "gif":
+		return &waE2E.Message{VideoMessage: &waE2E.VideoMessage{
+			URL: url, MediaKey: media.MediaKey, FileSHA256: media.FileSHA256, FileEncSHA256: media.FileEncSHA256, FileLength: length,
+		}}
+	case "audio":
+		return &waE2E.Message{AudioMessage: &waE2E.AudioMessage{
+			URL: url, MediaKey: media.MediaKey, FileSHA256: media.FileSHA256, FileEncSHA256: media.FileEncSHA256, FileLength: length,
+		}}
+	case "document":
+		return &waE2E.Message{DocumentMessage: &waE2E.DocumentMessage{
+			URL: url, MediaKey: media.MediaKey, FileSHA256: media.FileSHA256, FileEncSHA256: media.FileEncSHA256, FileLength: length,
+		}}
+	case "sticker", "animated_sticker":
+		return &waE2E.Message{StickerMessage: &waE2E.StickerMessage{
+			URL: url, MediaKey: media.MediaKey, FileSHA256: media.FileSHA256, FileEncSHA256: media.FileEncSHA256, FileLength: length,
+		}}
+	}
+	return nil
+}
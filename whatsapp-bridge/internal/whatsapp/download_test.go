@@ -0,0 +1,33 @@
+package whatsapp
+
+import (
+	"path/filepath"
+	"testing"
+
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+func TestDownloadableMessage(t *testing.T) {
+	media := &localTypes.MessageMedia{
+		ChatJID:    "120363000000000000@g.us",
+		MessageID:  "M1",
+		MediaType:  "gif",
+		Filename:   "../../etc/passwd",
+		URL:        "https://mmg.whatsapp.net/x",
+		MediaKey:   []byte{1},
+		FileLength: 42,
+	}
+
+	msg := downloadableMessage(media)
+	if video := msg.GetVideoMessage(); video == nil || video.GetURL() != media.URL || video.GetFileLength() != 42 {
+		t.Errorf("gif message = %v, want a video message with the stored URL", msg)
+	}
+	if got, want := mediaPath(media), filepath.Join(mediaDir, "120363000000000000@g.us", "M1_passwd"); got != want {
+		t.Errorf("mediaPath = %q, want %q", got, want)
+	}
+
+	media.MediaType = "location"
+	if msg := downloadableMessage(media); msg != nil {
+		t.Errorf("location read as downloadable: %v", msg)
+	}
+}
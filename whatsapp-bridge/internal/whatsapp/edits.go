@@ -0,0 +1,75 @@
+package whatsapp
+
+import (
+	"time"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types/events"
+
+	"whatsapp-bridge/internal/database"
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+// messageChange is an edit or a delete-for-everyone made to an earlier
+// message in a chat
+type messageChange struct {
+	chatJID   string
+	messageID string
+	by        string
+	at        time.Time
+	revoked   bool   // false for an edit
+	content   string // edits only
+	context   *localTypes.MessageContext
+}
+
+// changeToMessage reads an edit or revoke of an earlier message. It returns
+// nil for other messages, and for edits with no text to keep.
+func changeToMessage(msg *events.Message) *messageChange {
+	pm := msg.Message.GetProtocolMessage()
+	if pm == nil || pm.GetKey().GetID() == "" {
+		return nil
+	}
+
+	// Messages are stored under the chat as the event names it
+	chatJID := msg.Info.Chat.String()
+	change := &messageChange{
+		chatJID:   chatJID,
+		messageID: pm.GetKey().GetID(),
+		by:        msg.Info.Sender.ToNonAD().String(),
+		at:        msg.Info.Timestamp.UTC(),
+	}
+	if ms := pm.GetTimestampMS(); ms > 0 {
+		change.at = time.UnixMilli(ms).UTC()
+	}
+
+	switch pm.GetType() {
+	case waE2E.ProtocolMessage_REVOKE:
+		change.revoked = true
+	case waE2E.ProtocolMessage_MESSAGE_EDIT:
+		change.content = ExtractTextContent(pm.GetEditedMessage())
+		if change.content == "" {
+			return nil
+		}
+		change.context = ExtractMessageContext(pm.GetEditedMessage(), chatJID)
+	default:
+		return nil
+	}
+	return change
+}
+
+// storeMessageChange applies an edit or revoke to the archived message
+func (c *Client) storeMessageChange(messageStore *database.MessageStore, change *messageChange) {
+	if change.revoked {
+		if err := messageStore.RevokeMessage(change.chatJID, change.messageID, change.by, change.at); err != nil {
+			c.logger.Warnf("Failed to mark %s in %s revoked: %v", change.messageID, change.chatJID, err)
+		}
+		return
+	}
+
+	stored, err := messageStore.EditMessage(change.chatJID, change.messageID, change.content, change.context, change.at)
+	if err != nil {
+		c.logger.Warnf("Failed to apply edit to %s in %s: %v", change.messageID, change.chatJID, err)
+	} else if !stored {
+		c.logger.Debugf("Edit to %s in %s is for a message not in the archive", change.messageID, change.chatJID)
+	}
+}
@@ -0,0 +1,57 @@
+package whatsapp
+
+import (
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/proto/waCommon"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestChangeToMessage(t *testing.T) {
+	chat := types.NewJID("15550000001", types.DefaultUserServer)
+	sent := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	changeEvent := func(pm *waE2E.ProtocolMessage) *events.Message {
+		pm.Key = &waCommon.MessageKey{ID: proto.String("3EB0M1")}
+		return &events.Message{
+			Info: types.MessageInfo{
+				MessageSource: types.MessageSource{Chat: chat, Sender: chat},
+				ID:            "3EB0E1",
+				Timestamp:     sent,
+			},
+			Message: &waE2E.Message{ProtocolMessage: pm},
+		}
+	}
+
+	edit := changeToMessage(changeEvent(&waE2E.ProtocolMessage{
+		Type:          waE2E.ProtocolMessage_MESSAGE_EDIT.Enum(),
+		EditedMessage: &waE2E.Message{Conversation: proto.String("see you at 6")},
+		TimestampMS:   proto.Int64(sent.Add(time.Minute).UnixMilli()),
+	}))
+	if edit == nil {
+		t.Fatal("changeToMessage = nil, want the edit")
+	}
+	if edit.revoked || edit.chatJID != chat.String() || edit.messageID != "3EB0M1" || edit.content != "see you at 6" || !edit.at.Equal(sent.Add(time.Minute)) {
+		t.Errorf("edit = %+v", edit)
+	}
+
+	revoke := changeToMessage(changeEvent(&waE2E.ProtocolMessage{Type: waE2E.ProtocolMessage_REVOKE.Enum()}))
+	if revoke == nil || !revoke.revoked || revoke.by != chat.String() || !revoke.at.Equal(sent) {
+		t.Errorf("revoke = %+v", revoke)
+	}
+
+	// An edit with nothing to keep, and other protocol messages, are not changes
+	if c := changeToMessage(changeEvent(&waE2E.ProtocolMessage{Type: waE2E.ProtocolMessage_MESSAGE_EDIT.Enum()})); c != nil {
+		t.Errorf("empty edit = %+v", c)
+	}
+	if c := changeToMessage(changeEvent(&waE2E.ProtocolMessage{Type: waE2E.ProtocolMessage_EPHEMERAL_SETTING.Enum()})); c != nil {
+		t.Errorf("ephemeral setting read as change: %+v", c)
+	}
+	if c := changeToMessage(&events.Message{Message: &waE2E.Message{Conversation: proto.String("hi")}}); c != nil {
+		t.Errorf("text message read as change: %+v", c)
+	}
+}
@@ -0,0 +1,205 @@
+package whatsapp
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+
+	"whatsapp-bridge/internal/database"
+	"whatsapp-bridge/internal/retry"
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+// Group fields reported in a GroupChange
+const (
+	GroupFieldSubject      = "subject"
+	GroupFieldDescription  = "description"
+	GroupFieldPicture      = "picture"
+	GroupFieldAnnounce     = "announce"      // only admins may send messages
+	GroupFieldLocked       = "locked"        // only admins may edit the group info
+	GroupFieldEphemeral    = "ephemeral"     // disappearing message timer in seconds, 0 when off
+	GroupFieldJoinApproval = "join_approval" // admins approve new members
+)
+
+// SetGroupChangeHook registers fn to be called for each change to a group's
+// subject, description, picture or settings, whoever made it
+func (c *Client) SetGroupChangeHook(fn func(change localTypes.GroupChange)) {
+	c.groupChangeMu.Lock()
+	defer c.groupChangeMu.Unlock()
+	c.groupChangeHook = fn
+}
+
+// HandleGroupInfo records the subject, description and settings changes in
+// a group info event and reports those that differ from what was stored.
+// Membership changes in the same event are left alone.
+func (c *Client) HandleGroupInfo(messageStore *database.MessageStore, evt *events.GroupInfo) {
+	for _, change := range groupInfoChanges(evt) {
+		c.applyGroupChange(messageStore, change)
+	}
+}
+
+// HandlePicture records a group's new picture. Contact picture changes are
+// ignored.
+func (c *Client) HandlePicture(messageStore *database.MessageStore, evt *events.Picture) {
+	if evt.JID.Server != types.GroupServer {
+		return
+	}
+
+	change := localTypes.GroupChange{
+		GroupJID:  evt.JID.String(),
+		Field:     GroupFieldPicture,
+		ChangedAt: evt.Timestamp,
+	}
+	if !evt.Remove {
+		change.NewValue = evt.PictureID
+	}
+	if !evt.Author.IsEmpty() {
+		change.ChangedBy = evt.Author.ToNonAD().String()
+	}
+	if change.ChangedAt.IsZero() {
+		change.ChangedAt = time.Now()
+	}
+	c.applyGroupChange(messageStore, change)
+}
+
+// seedGroupSettings records the description, settings and picture of a
+// group seen for the first time, without reporting them, so its first change
+// is reported with the value it replaces rather than an empty one. info is
+// fetched when nil.
+func (c *Client) seedGroupSettings(messageStore *database.MessageStore, jid types.JID, info *types.GroupInfo) {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancel()
+
+	if info == nil {
+		err := retry.Do(ctx, fetchPolicy, func() (err error) {
+			info, err = c.Client.GetGroupInfo(ctx, jid)
+			return err
+		})
+		if err != nil {
+			c.logger.Warnf("Failed to get info of group %s: %v", jid, err)
+			return
+		}
+	}
+	values := groupSettingValues(info)
+
+	// No picture is an empty value; a failed lookup leaves it unknown
+	picture, err := c.GetProfilePictureInfo(ctx, jid, &whatsmeow.GetProfilePictureParams{Preview: true})
+	switch {
+	case err == nil && picture != nil:
+		values[GroupFieldPicture] = picture.ID
+	case err == nil:
+		values[GroupFieldPicture] = ""
+	default:
+		c.logger.Warnf("Failed to get picture of group %s: %v", jid, err)
+	}
+
+	if err := messageStore.SeedGroupSettings(jid.String(), values, time.Now()); err != nil {
+		c.logger.Warnf("Failed to store settings of group %s: %v", jid, err)
+	}
+}
+
+// groupSettingValues lists the description and settings in a group's info
+// by GroupField
+func groupSettingValues(info *types.GroupInfo) map[string]string {
+	timer := uint32(0)
+	if info.IsEphemeral {
+		timer = info.DisappearingTimer
+	}
+	return map[string]string{
+		GroupFieldDescription:  info.Topic,
+		GroupFieldAnnounce:     strconv.FormatBool(info.IsAnnounce),
+		GroupFieldLocked:       strconv.FormatBool(info.IsLocked),
+		GroupFieldEphemeral:    strconv.FormatUint(uint64(timer), 10),
+		GroupFieldJoinApproval: strconv.FormatBool(info.IsJoinApprovalRequired),
+	}
+}
+
+// groupInfoChanges lists the subject, description and settings changes in a
+// group info event, with their new values
+func groupInfoChanges(evt *events.GroupInfo) []localTypes.GroupChange {
+	var changedBy string
+	switch {
+	case evt.SenderPN != nil && !evt.SenderPN.IsEmpty():
+		changedBy = evt.SenderPN.ToNonAD().String()
+	case evt.Sender != nil && !evt.Sender.IsEmpty():
+		changedBy = evt.Sender.ToNonAD().String()
+	}
+	at := evt.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	var changes []localTypes.GroupChange
+	add := func(field, value string) {
+		changes = append(changes, localTypes.GroupChange{
+			GroupJID:  evt.JID.String(),
+			Field:     field,
+			NewValue:  value,
+			ChangedBy: changedBy,
+			ChangedAt: at,
+		})
+	}
+
+	if evt.Name != nil {
+		add(GroupFieldSubject, evt.Name.Name)
+	}
+	if evt.Topic != nil {
+		topic := evt.Topic.Topic
+		if evt.Topic.TopicDeleted {
+			topic = ""
+		}
+		add(GroupFieldDescription, topic)
+	}
+	if evt.Announce != nil {
+		add(GroupFieldAnnounce, strconv.FormatBool(evt.Announce.IsAnnounce))
+	}
+	if evt.Locked != nil {
+		add(GroupFieldLocked, strconv.FormatBool(evt.Locked.IsLocked))
+	}
+	if evt.Ephemeral != nil {
+		timer := uint32(0)
+		if evt.Ephemeral.IsEphemeral {
+			timer = evt.Ephemeral.DisappearingTimer
+		}
+		add(GroupFieldEphemeral, strconv.FormatUint(uint64(timer), 10))
+	}
+	if evt.MembershipApprovalMode != nil {
+		add(GroupFieldJoinApproval, strconv.FormatBool(evt.MembershipApprovalMode.IsJoinApprovalRequired))
+	}
+	return changes
+}
+
+// applyGroupChange stores a group change, fills in the value it replaces
+// and reports it unless the stored value already matched. The subject is
+// kept as the chat's name.
+func (c *Client) applyGroupChange(messageStore *database.MessageStore, change localTypes.GroupChange) {
+	var err error
+	known := false
+	if change.Field == GroupFieldSubject {
+		change.OldValue, err = messageStore.RenameChat(change.GroupJID, change.NewValue)
+		known = change.OldValue != ""
+	} else {
+		change.OldValue, known, err = messageStore.StoreGroupSetting(change.GroupJID, change.Field, change.NewValue, change.ChangedAt)
+	}
+	if err != nil {
+		c.logger.Warnf("Failed to store %s of group %s: %v", change.Field, change.GroupJID, err)
+		return
+	}
+	if known && change.OldValue == change.NewValue {
+		return
+	}
+
+	c.logger.Infof("Group %s %s changed", change.GroupJID, change.Field)
+
+	c.groupChangeMu.RLock()
+	hook := c.groupChangeHook
+	c.groupChangeMu.RUnlock()
+
+	if hook != nil {
+		hook(change)
+	}
+}
@@ -0,0 +1,94 @@
+package whatsapp
+
+import (
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	waLog "go.mau.fi/whatsmeow/util/log"
+
+	"whatsapp-bridge/internal/database"
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+func TestGroupInfoChanges(t *testing.T) {
+	group := types.NewJID("120363000000000000", types.GroupServer)
+	lid := types.NewJID("99999", types.HiddenUserServer)
+	ana := types.NewJID("15550000001", types.DefaultUserServer)
+	at := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+
+	changes := groupInfoChanges(&events.GroupInfo{
+		JID:       group,
+		Sender:    &lid,
+		SenderPN:  &ana,
+		Timestamp: at,
+		Name:      &types.GroupName{Name: "Launch"},
+		Topic:     &types.GroupTopic{Topic: "old notes", TopicDeleted: true},
+		Announce:  &types.GroupAnnounce{IsAnnounce: true},
+		Ephemeral: &types.GroupEphemeral{IsEphemeral: true, DisappearingTimer: 86400},
+		Join:      []types.JID{ana},
+	})
+
+	want := map[string]string{
+		GroupFieldSubject:     "Launch",
+		GroupFieldDescription: "",
+		GroupFieldAnnounce:    "true",
+		GroupFieldEphemeral:   "86400",
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("groupInfoChanges = %+v, want %d changes", changes, len(want))
+	}
+	for _, change := range changes {
+		if value, ok := want[change.Field]; !ok || change.NewValue != value {
+			t.Errorf("%s = %q, want %q", change.Field, change.NewValue, value)
+		}
+		if change.GroupJID != group.String() || change.ChangedBy != ana.String() || !change.ChangedAt.Equal(at) {
+			t.Errorf("change = %+v, want Ana's change to the group at %v", change, at)
+		}
+	}
+
+	// Membership alone is not a group info change
+	if changes := groupInfoChanges(&events.GroupInfo{JID: group, Leave: []types.JID{ana}}); len(changes) != 0 {
+		t.Errorf("membership change read as %+v", changes)
+	}
+}
+
+func TestSeededGroupSettings(t *testing.T) {
+	t.Chdir(t.TempDir())
+	store, err := database.NewMessageStore()
+	if err != nil {
+		t.Fatalf("NewMessageStore: %v", err)
+	}
+	defer store.Close()
+
+	c := &Client{logger: waLog.Noop}
+	var reported []localTypes.GroupChange
+	c.SetGroupChangeHook(func(change localTypes.GroupChange) { reported = append(reported, change) })
+
+	group := "120363000000000000@g.us"
+	at := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	info := &types.GroupInfo{
+		GroupTopic:     types.GroupTopic{Topic: "notes"},
+		GroupAnnounce:  types.GroupAnnounce{IsAnnounce: true},
+		GroupEphemeral: types.GroupEphemeral{IsEphemeral: false, DisappearingTimer: 86400},
+	}
+	if err := store.SeedGroupSettings(group, groupSettingValues(info), at); err != nil {
+		t.Fatalf("SeedGroupSettings: %v", err)
+	}
+	// A later seed does not overwrite what is stored
+	if err := store.SeedGroupSettings(group, map[string]string{GroupFieldAnnounce: "false"}, at); err != nil {
+		t.Fatalf("SeedGroupSettings: %v", err)
+	}
+
+	c.applyGroupChange(store, localTypes.GroupChange{GroupJID: group, Field: GroupFieldAnnounce, NewValue: "false", ChangedAt: at})
+	c.applyGroupChange(store, localTypes.GroupChange{GroupJID: group, Field: GroupFieldEphemeral, NewValue: "0", ChangedAt: at})
+	c.applyGroupChange(store, localTypes.GroupChange{GroupJID: group, Field: GroupFieldDescription, NewValue: "agenda", ChangedAt: at})
+
+	if len(reported) != 2 {
+		t.Fatalf("reported %+v, want the announce and description changes", reported)
+	}
+	if reported[0].OldValue != "true" || reported[1].OldValue != "notes" {
+		t.Errorf("old values = %q, %q, want the seeded true and notes", reported[0].OldValue, reported[1].OldValue)
+	}
+}
@@ -7,7 +7,11 @@ import (
 	"time"
 
 	"whatsapp-bridge/internal/database"
+	"whatsapp-bridge/internal/recovery"
+	"whatsapp-bridge/internal/retry"
+	localTypes "whatsapp-bridge/internal/types"
 
+	"go.mau.fi/whatsmeow/proto/waE2E"
 	"go.mau.fi/whatsmeow/types"
 	"go.mau.fi/whatsmeow/types/events"
 )
@@ -23,6 +27,10 @@ func (c *Client) GetChatName(messageStore *database.MessageStore, jid types.JID,
 		return existingName
 	}
 
+	// Name lookups run while handling an event, with no request to bound them
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancel()
+
 	// Need to determine chat name
 	var name string
 
@@ -62,23 +70,34 @@ func (c *Client) GetChatName(messageStore *database.MessageStore, jid types.JID,
 		}
 
 		// If we didn't get a name, try group info
+		var groupInfo *types.GroupInfo
 		if name == "" {
-			groupInfo, err := c.Client.GetGroupInfo(context.Background(), jid)
+			err := retry.Do(ctx, fetchPolicy, func() (err error) {
+				groupInfo, err = c.Client.GetGroupInfo(ctx, jid)
+				return err
+			})
 			if err == nil && groupInfo.Name != "" {
 				name = groupInfo.Name
 			} else {
 				// Fallback name for groups
+				groupInfo = nil
 				name = fmt.Sprintf("Group %s", jid.User)
 			}
 		}
 
+		// A group seen for the first time has its settings recorded, so
+		// their first change can be reported with the old value
+		if c.IsConnected() {
+			recovery.Go("group settings", func() { c.seedGroupSettings(messageStore, jid, groupInfo) })
+		}
+
 		c.logger.Infof("Using group name: %s", name)
 	} else {
 		// This is an individual contact
 		c.logger.Infof("Getting name for contact: %s", chatJID)
 
 		// Just use contact info (full name)
-		contact, err := c.Store.Contacts.GetContact(context.Background(), jid)
+		contact, err := c.Store.Contacts.GetContact(ctx, jid)
 		if err == nil && contact.FullName != "" {
 			name = contact.FullName
 		} else if sender != "" {
@@ -95,8 +114,16 @@ func (c *Client) GetChatName(messageStore *database.MessageStore, jid types.JID,
 	return name
 }
 
-// HandleMessage processes regular incoming messages with media support and webhook processing
-func (c *Client) HandleMessage(messageStore *database.MessageStore, webhookManager interface{}, msg *events.Message) {
+// HandleMessage processes regular incoming messages with media support and webhook processing.
+// Returns the resolved chat name.
+func (c *Client) HandleMessage(messageStore *database.MessageStore, webhookManager interface{}, msg *events.Message) string {
+	// Chats outside the configured scope are neither stored nor webhooked
+	if !c.InChatScope(msg.Info.Chat) {
+		return ""
+	}
+
+	c.countMessage(msg)
+
 	// Save message to database
 	chatJID := msg.Info.Chat.String()
 	sender := msg.Info.Sender.User
@@ -104,10 +131,73 @@ func (c *Client) HandleMessage(messageStore *database.MessageStore, webhookManag
 	// Get appropriate chat name (pass nil for conversation since we don't have one for regular messages)
 	name := c.GetChatName(messageStore, msg.Info.Chat, chatJID, nil, sender)
 
+	// Newsletters can opt out of the archive and webhook stream
+	persist, deliver := true, true
+	if msg.Info.Chat.Server == types.NewsletterServer {
+		settings := c.NewsletterSettingsFor(chatJID)
+		persist, deliver = settings.Persist, settings.Webhooks
+	}
+
 	// Update chat in database with the message timestamp (keeps last message time updated)
-	err := messageStore.StoreChat(chatJID, name, msg.Info.Timestamp)
-	if err != nil {
-		c.logger.Warnf("Failed to store chat: %v", err)
+	if persist {
+		if err := messageStore.StoreChat(chatJID, name, msg.Info.Timestamp); err != nil {
+			c.logger.Warnf("Failed to store chat: %v", err)
+		}
+	}
+
+	metadataOnly := c.MetadataOnly(chatJID)
+
+	// Live location updates carry no text; keep them as track points
+	if live := msg.Message.GetLiveLocationMessage(); live != nil && persist && !metadataOnly {
+		c.storeLiveLocation(messageStore, msg, live)
+	}
+
+	// Orders, catalog items and payments are kept as structured rows
+	if cm := ExtractCommerce(msg); cm != nil {
+		c.storeCommerce(messageStore, webhookManager, cm, persist && !metadataOnly, deliver)
+	}
+
+	// Pins are kept per chat; the pin message itself carries no content
+	if pin, unpinned := c.messagePin(msg); pin != nil {
+		c.storeMessagePin(messageStore, webhookManager, pin, unpinned, persist, deliver)
+	}
+
+	// Reactions are kept against the message they react to
+	if reaction := ExtractReaction(msg); reaction != nil {
+		c.storeMessageReaction(messageStore, webhookManager, reaction, persist && !metadataOnly, deliver)
+	}
+
+	// Events planned in a chat are kept for the calendar feed
+	if persist && !metadataOnly {
+		if ev := c.calendarEvent(msg); ev != nil {
+			c.storeCalendarEvent(messageStore, ev)
+		}
+	}
+
+	// Polls are kept so the votes that follow can be counted
+	if persist && !metadataOnly {
+		if poll := pollCreation(msg); poll != nil {
+			c.storePoll(messageStore, poll)
+		} else if msg.Message.GetPollUpdateMessage() != nil {
+			c.storePollVote(messageStore, msg)
+		}
+	}
+
+	// Messages kept in disappearing chats are flagged so they are not
+	// treated as disappearing
+	if keep := keepInChat(msg); keep != nil && persist {
+		c.storeMessageKeep(messageStore, keep)
+	}
+
+	// Edits replace the archived text, keeping what it said before; deletes
+	// for everyone only flag the message
+	if change := changeToMessage(msg); change != nil && persist {
+		c.storeMessageChange(messageStore, change)
+	}
+
+	// Sticker packs are kept so they can be listed and downloaded later
+	if pack := ExtractStickerPack(msg); pack != nil && persist && !metadataOnly {
+		c.storeStickerPack(messageStore, pack)
 	}
 
 	// Extract text content
@@ -118,7 +208,7 @@ func (c *Client) HandleMessage(messageStore *database.MessageStore, webhookManag
 
 	// Skip if there's no content and no media
 	if content == "" && mediaType == "" {
-		return
+		return name
 	}
 
 	// Get sender name (PushName from WhatsApp)
@@ -127,43 +217,96 @@ func (c *Client) HandleMessage(messageStore *database.MessageStore, webhookManag
 		senderName = sender // fallback to JID
 	}
 
-	// Store message in database
-	err = messageStore.StoreMessage(
-		msg.Info.ID,
-		chatJID,
-		sender,
-		senderName,
-		content,
-		msg.Info.Timestamp,
-		msg.Info.IsFromMe,
-		mediaType,
-		filename,
-		url,
-		mediaKey,
-		fileSHA256,
-		fileEncSHA256,
-		fileLength,
-	)
+	// Store message in database, together with the record that its webhooks
+	// are still to be sent, so a restart before they go out still sends them.
+	// Only messages some webhook fires for are recorded.
+	dispatch := webhookManager != nil && deliver && webhooksMatch(webhookManager, msg, name)
+	dispatchID := c.ingestMessage(messageStore, msg, name, dispatch, !persist || metadataOnly, func(ingest *database.Ingest) error {
+		if persist && metadataOnly {
+			return ingest.StoreMessageMetadata(msg.Info.ID, chatJID, sender, senderName, msg.Info.Timestamp, msg.Info.IsFromMe, mediaType, fileLength)
+		} else if persist {
+			return ingest.StoreMessage(
+				msg.Info.ID,
+				chatJID,
+				sender,
+				senderName,
+				content,
+				msg.Info.Timestamp,
+				msg.Info.IsFromMe,
+				mediaType,
+				filename,
+				url,
+				mediaKey,
+				fileSHA256,
+				fileEncSHA256,
+				fileLength,
+				ExtractMessageContext(msg.Message, chatJID),
+			)
+		}
+		return nil
+	})
 
-	if err != nil {
-		c.logger.Warnf("Failed to store message: %v", err)
+	// Disappearing messages are removed locally when their timer runs out
+	if expiresAt, ok := messageExpiry(msg); ok && persist {
+		c.storeMessageExpiry(messageStore, msg, expiresAt)
+	}
+
+	// Keep the chat's unread count current
+	if persist {
+		c.trackReadState(messageStore, msg)
+	}
+
+	// Media with an enrichment hook (e.g. voice notes to transcribe) is
+	// annotated in the background; the annotation follows the message's
+	// webhooks as a message_annotated event
+	if kind, media, mimetype := c.annotationFor(msg.Message); kind != "" && persist && !metadataOnly {
+		c.queueAnnotation(messageStore, msg, kind, media, mimetype)
+	}
+
+	// Media set to download automatically is saved in the background; the
+	// webhook does not wait for it, as its media_download_url fetches the
+	// media on demand until the file is saved
+	if mediaType != "" && persist && !metadataOnly && c.autoDownloadFor(mediaType, fileLength) {
+		media := &localTypes.MessageMedia{
+			ChatJID:       chatJID,
+			MessageID:     msg.Info.ID,
+			MediaType:     mediaType,
+			Filename:      filename,
+			URL:           url,
+			MediaKey:      mediaKey,
+			FileSHA256:    fileSHA256,
+			FileEncSHA256: fileEncSHA256,
+			FileLength:    fileLength,
+		}
+		c.queueAutoDownload(media)
 	}
 
 	// Process webhooks if manager is available
-	if webhookManager != nil {
+	if webhookManager != nil && deliver {
 		// Cast to webhook manager and process message
 		if wm, ok := webhookManager.(interface {
-			ProcessMessage(client interface{}, msg *events.Message, chatName string)
+			ProcessMessage(client interface{}, msg *events.Message, chatName string, dispatchID int64)
 		}); ok {
-			wm.ProcessMessage(c, msg, name)
+			wm.ProcessMessage(c, msg, name, dispatchID)
 		}
 	}
+
+	return name
 }
 
 // HandleHistorySync processes history sync events
 func (c *Client) HandleHistorySync(messageStore *database.MessageStore, historySync *events.HistorySync) {
 	c.logger.Infof("Received history sync event with %d conversations", len(historySync.Data.Conversations))
 
+	// History syncs can be large; storing one on a nearly full volume risks
+	// the databases failing mid-write, so it waits for space to be freed
+	if c.spaceLow() {
+		c.logger.Warnf("Deferring history sync (%v, %d conversations): the store volume is low on disk space",
+			historySync.Data.GetSyncType(), len(historySync.Data.Conversations))
+		c.deferHistorySync(historySync)
+		return
+	}
+
 	syncedCount := 0
 	for _, conversation := range historySync.Data.Conversations {
 		// Parse JID from the conversation
@@ -180,8 +323,16 @@ func (c *Client) HandleHistorySync(messageStore *database.MessageStore, historyS
 			continue
 		}
 
+		if !c.InChatScope(jid) {
+			continue
+		}
+
 		// Get appropriate chat name by passing the history sync conversation directly
 		name := c.GetChatName(messageStore, jid, chatJID, conversation, "")
+		metadataOnly := c.MetadataOnly(chatJID)
+
+		// Start unread tracking from the phone's state
+		c.importReadState(messageStore, historySync, conversation)
 
 		// Process messages
 		messages := conversation.Messages
@@ -229,7 +380,9 @@ func (c *Client) HandleHistorySync(messageStore *database.MessageStore, historyS
 				}
 
 				// Log the message content for debugging
-				c.logger.Infof("Message content: %v, Media Type: %v", content, mediaType)
+				if !metadataOnly {
+					c.logger.Infof("Message content: %v, Media Type: %v", content, mediaType)
+				}
 
 				// Skip messages with no content and no media
 				if content == "" && mediaType == "" {
@@ -270,6 +423,16 @@ func (c *Client) HandleHistorySync(messageStore *database.MessageStore, historyS
 				// For history sync, use sender as senderName fallback (PushName not directly available)
 				senderName := sender
 
+				if metadataOnly {
+					err = messageStore.StoreMessageMetadata(msgID, chatJID, sender, senderName, timestamp, isFromMe, mediaType, fileLength)
+					if err != nil {
+						c.logger.Warnf("Failed to store history message metadata: %v", err)
+					} else {
+						syncedCount++
+					}
+					continue
+				}
+
 				err = messageStore.StoreMessage(
 					msgID,
 					chatJID,
@@ -285,6 +448,7 @@ func (c *Client) HandleHistorySync(messageStore *database.MessageStore, historyS
 					fileSHA256,
 					fileEncSHA256,
 					fileLength,
+					ExtractMessageContext(msg.Message.Message, chatJID),
 				)
 				if err != nil {
 					c.logger.Warnf("Failed to store history message: %v", err)
@@ -305,3 +469,23 @@ func (c *Client) HandleHistorySync(messageStore *database.MessageStore, historyS
 
 	c.logger.Infof("History sync complete. Stored %d messages.", syncedCount)
 }
+
+// storeLiveLocation records a live location update as a track point for its sender
+func (c *Client) storeLiveLocation(messageStore *database.MessageStore, msg *events.Message, live *waE2E.LiveLocationMessage) {
+	err := messageStore.StoreLocationPoint(&localTypes.LocationPoint{
+		ChatJID:        msg.Info.Chat.String(),
+		SenderJID:      msg.Info.Sender.ToNonAD().String(),
+		MessageID:      msg.Info.ID,
+		Latitude:       live.GetDegreesLatitude(),
+		Longitude:      live.GetDegreesLongitude(),
+		AccuracyMeters: int(live.GetAccuracyInMeters()),
+		SpeedMps:       float64(live.GetSpeedInMps()),
+		Heading:        int(live.GetDegreesClockwiseFromMagneticNorth()),
+		Caption:        live.GetCaption(),
+		Sequence:       live.GetSequenceNumber(),
+		Timestamp:      msg.Info.Timestamp,
+	})
+	if err != nil {
+		c.logger.Warnf("Failed to store live location: %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package whatsapp
+
+import (
+	"time"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types/events"
+
+	"whatsapp-bridge/internal/database"
+)
+
+// messageKeep is a "keep in chat" made in a disappearing chat: the kept
+// message stays after the timer, for everyone, until the keep is undone
+type messageKeep struct {
+	chatJID   string
+	messageID string
+	keptBy    string
+	at        time.Time
+	kept      bool // false when the keep was undone
+}
+
+// keepInChat reads a keep or undo made in a chat. It returns nil for other
+// messages.
+func keepInChat(msg *events.Message) *messageKeep {
+	k := msg.Message.GetKeepInChatMessage()
+	if k == nil || k.GetKey().GetID() == "" {
+		return nil
+	}
+
+	keep := &messageKeep{
+		chatJID:   msg.Info.Chat.ToNonAD().String(),
+		messageID: k.GetKey().GetID(),
+		keptBy:    msg.Info.Sender.ToNonAD().String(),
+		at:        msg.Info.Timestamp.UTC(),
+	}
+	if ms := k.GetTimestampMS(); ms > 0 {
+		keep.at = time.UnixMilli(ms).UTC()
+	}
+
+	switch k.GetKeepType() {
+	case waE2E.KeepType_KEEP_FOR_ALL:
+		keep.kept = true
+	case waE2E.KeepType_UNDO_KEEP_FOR_ALL:
+		keep.kept = false
+	default:
+		return nil
+	}
+	return keep
+}
+
+// storeMessageKeep marks or unmarks the kept message in the archive
+func (c *Client) storeMessageKeep(messageStore *database.MessageStore, keep *messageKeep) {
+	if err := messageStore.SetMessageKept(keep.chatJID, keep.messageID, keep.keptBy, keep.at, keep.kept); err != nil {
+		c.logger.Warnf("Failed to update kept state of %s in %s: %v", keep.messageID, keep.chatJID, err)
+	}
+}
+
+// messageExpiry returns when a message sent with a disappearing timer
+// disappears from the chat; ok is false for other messages
+func messageExpiry(msg *events.Message) (expiresAt time.Time, ok bool) {
+	seconds := messageContextInfo(msg.Message).GetExpiration()
+	if seconds == 0 {
+		return time.Time{}, false
+	}
+	return msg.Info.Timestamp.Add(time.Duration(seconds) * time.Second).UTC(), true
+}
+
+// storeMessageExpiry records when a disappearing message expires, for the
+// local cleanup to remove it then unless it is kept
+func (c *Client) storeMessageExpiry(messageStore *database.MessageStore, msg *events.Message, expiresAt time.Time) {
+	if err := messageStore.SetMessageExpiry(msg.Info.Chat.String(), msg.Info.ID, expiresAt); err != nil {
+		c.logger.Warnf("Failed to record expiry of %s in %s: %v", msg.Info.ID, msg.Info.Chat, err)
+	}
+}
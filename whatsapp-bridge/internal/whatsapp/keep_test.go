@@ -0,0 +1,58 @@
+package whatsapp
+
+import (
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/proto/waCommon"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestKeepInChat(t *testing.T) {
+	ana := types.NewJID("15550000001", types.DefaultUserServer)
+	keepEvent := func(keepType waE2E.KeepType) *events.Message {
+		return &events.Message{
+			Info: types.MessageInfo{
+				MessageSource: types.MessageSource{Chat: ana, Sender: ana},
+				Timestamp:     time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC),
+			},
+			Message: &waE2E.Message{
+				KeepInChatMessage: &waE2E.KeepInChatMessage{
+					Key:      &waCommon.MessageKey{ID: proto.String("M1")},
+					KeepType: keepType.Enum(),
+				},
+			},
+		}
+	}
+
+	keep := keepInChat(keepEvent(waE2E.KeepType_KEEP_FOR_ALL))
+	if keep == nil || !keep.kept || keep.chatJID != ana.String() || keep.messageID != "M1" || keep.keptBy != ana.String() {
+		t.Errorf("keep = %+v, want M1 kept by Ana", keep)
+	}
+	if keep := keepInChat(keepEvent(waE2E.KeepType_UNDO_KEEP_FOR_ALL)); keep == nil || keep.kept {
+		t.Errorf("undo = %+v, want the keep undone", keep)
+	}
+	if keep := keepInChat(&events.Message{Message: &waE2E.Message{Conversation: proto.String("hi")}}); keep != nil {
+		t.Errorf("text message read as keep: %+v", keep)
+	}
+}
+
+func TestMessageExpiry(t *testing.T) {
+	sent := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	event := func(msg *waE2E.Message) *events.Message {
+		return &events.Message{Info: types.MessageInfo{Timestamp: sent}, Message: msg}
+	}
+
+	image := event(&waE2E.Message{ImageMessage: &waE2E.ImageMessage{
+		ContextInfo: &waE2E.ContextInfo{Expiration: proto.Uint32(86400)},
+	}})
+	if expiresAt, ok := messageExpiry(image); !ok || !expiresAt.Equal(sent.Add(24*time.Hour)) {
+		t.Errorf("messageExpiry(image) = %v, %v, want a day after it was sent", expiresAt, ok)
+	}
+	if _, ok := messageExpiry(event(&waE2E.Message{Conversation: proto.String("hi")})); ok {
+		t.Error("message without a timer has an expiry")
+	}
+}
@@ -0,0 +1,307 @@
+package whatsapp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"golang.org/x/net/html"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// linkPreviewTimeout bounds fetching a page and its image for a preview
+	linkPreviewTimeout = 10 * time.Second
+
+	// maxPreviewPageBytes is how much of a page is read looking for its
+	// title and description
+	maxPreviewPageBytes = 512 << 10
+
+	// maxPreviewImageBytes is the largest preview image downloaded
+	maxPreviewImageBytes = 5 << 20
+
+	// previewThumbnailSize is the longest edge of a preview thumbnail
+	previewThumbnailSize = 300
+
+	// maxPreviewDescription is the longest description shown in a preview
+	maxPreviewDescription = 300
+)
+
+// linkToken matches a web link in message text
+var linkToken = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+type linkPreviewKey struct{}
+
+// WithLinkPreview returns a context whose text sends carry a preview of the
+// first link in the text, like the official client shows
+func WithLinkPreview(ctx context.Context) context.Context {
+	return context.WithValue(ctx, linkPreviewKey{}, true)
+}
+
+// LinkPreviewFrom reports whether WithLinkPreview asked for a preview
+func LinkPreviewFrom(ctx context.Context) bool {
+	on, _ := ctx.Value(linkPreviewKey{}).(bool)
+	return on
+}
+
+// linkPreview is what a page says about itself
+type linkPreview struct {
+	URL         string // the link as written in the text
+	Title       string
+	Description string
+	ImageURL    string
+	Thumbnail   []byte // JPEG, empty when the page has no usable image
+}
+
+// firstLink returns the first web link in text, without punctuation that
+// ends the sentence around it
+func firstLink(text string) string {
+	link := linkToken.FindString(text)
+	return strings.TrimRight(link, ".,;:!?)]}'")
+}
+
+// attachLinkPreview turns a text message into one previewing the first link
+// in its text. Without a link, or when the page cannot be read, the message
+// is left as it is: the preview is not worth failing the send for.
+func (c *Client) attachLinkPreview(ctx context.Context, msg *waE2E.Message, text string) {
+	link := firstLink(text)
+	if link == "" || (msg.Conversation == nil && msg.ExtendedTextMessage == nil) {
+		return
+	}
+
+	preview, err := fetchLinkPreview(ctx, link)
+	if err != nil {
+		c.logger.Warnf("Sending without a link preview: %v", err)
+		return
+	}
+	addLinkPreview(msg, preview)
+}
+
+// addLinkPreview adds the preview to a text message, moving a plain
+// conversation to an extended text message first. An extended text message
+// keeps its context, such as mentions and quotes.
+func addLinkPreview(msg *waE2E.Message, preview *linkPreview) {
+	ext := msg.ExtendedTextMessage
+	if ext == nil {
+		ext = &waE2E.ExtendedTextMessage{Text: msg.Conversation}
+		msg.ExtendedTextMessage = ext
+		msg.Conversation = nil
+	}
+	ext.MatchedText = proto.String(preview.URL)
+	ext.Title = proto.String(preview.Title)
+	ext.Description = proto.String(preview.Description)
+	ext.PreviewType = waE2E.ExtendedTextMessage_NONE.Enum()
+	if len(preview.Thumbnail) > 0 {
+		ext.JPEGThumbnail = preview.Thumbnail
+		ext.PreviewType = waE2E.ExtendedTextMessage_IMAGE.Enum()
+	}
+}
+
+// fetchLinkPreview reads a page's title, description and image. Open Graph
+// tags are preferred, falling back to the page's own title and description.
+// The image is optional: a preview without one is still returned.
+func fetchLinkPreview(ctx context.Context, link string) (*linkPreview, error) {
+	ctx, cancel := context.WithTimeout(ctx, linkPreviewTimeout)
+	defer cancel()
+
+	page, err := url.Parse(link)
+	if err != nil {
+		return nil, fmt.Errorf("invalid link %s: %v", link, err)
+	}
+	body, contentType, err := fetchPreviewResource(ctx, page.String(), maxPreviewPageBytes)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.Contains(contentType, "html") {
+		return nil, fmt.Errorf("%s is not a web page (%s)", link, contentType)
+	}
+
+	preview := parsePreviewPage(body)
+	if preview.Title == "" {
+		return nil, fmt.Errorf("%s has no title", link)
+	}
+	preview.URL = link
+
+	if preview.ImageURL != "" {
+		if ref, err := url.Parse(preview.ImageURL); err == nil {
+			preview.ImageURL = page.ResolveReference(ref).String()
+			if data, _, err := fetchPreviewResource(ctx, preview.ImageURL, maxPreviewImageBytes); err == nil {
+				preview.Thumbnail, _ = previewThumbnail(data)
+			}
+		}
+	}
+	return preview, nil
+}
+
+// parsePreviewPage reads the preview fields from a page's head
+func parsePreviewPage(page []byte) *linkPreview {
+	preview := &linkPreview{}
+	var title, description string
+
+	z := html.NewTokenizer(bytes.NewReader(page))
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			// End of the page, or of as much as was read
+			return finishPreview(preview, title, description)
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			switch tok.Data {
+			case "title":
+				if title == "" && z.Next() == html.TextToken {
+					title = string(z.Text())
+				}
+			case "meta":
+				key, content := metaAttrs(tok)
+				switch key {
+				case "og:title":
+					preview.Title = content
+				case "og:description":
+					preview.Description = content
+				case "og:image", "og:image:url", "og:image:secure_url":
+					if preview.ImageURL == "" {
+						preview.ImageURL = content
+					}
+				case "description":
+					description = content
+				}
+			case "body":
+				return finishPreview(preview, title, description)
+			}
+		}
+	}
+}
+
+// metaAttrs returns the name (or Open Graph property) of a meta tag and its content
+func metaAttrs(tok html.Token) (key, content string) {
+	for _, a := range tok.Attr {
+		switch strings.ToLower(a.Key) {
+		case "property", "name":
+			if key == "" {
+				key = strings.ToLower(a.Val)
+			}
+		case "content":
+			content = a.Val
+		}
+	}
+	return key, content
+}
+
+// finishPreview falls back to the page's own title and description and tidies
+// up the text
+func finishPreview(preview *linkPreview, title, description string) *linkPreview {
+	if preview.Title == "" {
+		preview.Title = title
+	}
+	if preview.Description == "" {
+		preview.Description = description
+	}
+	preview.Title = strings.Join(strings.Fields(preview.Title), " ")
+	preview.Description = strings.Join(strings.Fields(preview.Description), " ")
+	if r := []rune(preview.Description); len(r) > maxPreviewDescription {
+		preview.Description = strings.TrimSpace(string(r[:maxPreviewDescription-1])) + "…"
+	}
+	return preview
+}
+
+// previewThumbnail scales a JPEG, PNG or GIF image to fit
+// previewThumbnailSize and encodes it as a JPEG
+func previewThumbnail(data []byte) ([]byte, error) {
+	if err := checkImageSize(data); err != nil {
+		return nil, err
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+	}
+	b := src.Bounds()
+	if b.Dx() == 0 || b.Dy() == 0 {
+		return nil, fmt.Errorf("%w: image is empty", ErrUnsupportedImage)
+	}
+
+	w, h := b.Dx(), b.Dy()
+	if edge := max(w, h); edge > previewThumbnailSize {
+		w, h = max(w*previewThumbnailSize/edge, 1), max(h*previewThumbnailSize/edge, 1)
+	}
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, downscale(src, b, w, h), &jpeg.Options{Quality: 75}); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %v", err)
+	}
+	return out.Bytes(), nil
+}
+
+// fetchPreviewResource downloads up to limit bytes of a page or image. Links
+// are written by API callers, so private and reserved addresses are refused
+// to keep previews from reading the bridge's network.
+func fetchPreviewResource(ctx context.Context, link string, limit int64) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid link %s: %v", link, err)
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return nil, "", fmt.Errorf("unsupported link %s", link)
+	}
+	req.Header.Set("User-Agent", "WhatsApp-Bridge-LinkPreview/1.0")
+
+	resp, err := PublicHTTPClient(linkPreviewTimeout).Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch %s: %v", link, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("failed to fetch %s: HTTP %d", link, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %v", link, err)
+	}
+	return data, resp.Header.Get("Content-Type"), nil
+}
+
+// PublicHTTPClient returns a client for URLs API callers write, such as
+// link previews and profile photo sources. It will not connect to private or
+// reserved addresses, checked on every connection so redirects and DNS
+// answers cannot get around it. DISABLE_SSRF_CHECK=true lifts the check, as
+// it does for webhooks.
+func PublicHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if os.Getenv("DISABLE_SSRF_CHECK") != "true" {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+				return fmt.Errorf("refused to connect to private address %s", host)
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// publicIP reports whether ip is a public unicast address
+func publicIP(ip net.IP) bool {
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !cgnat.Contains(ip)
+}
+
+// cgnat is the shared address space carriers and cloud metadata services use
+var _, cgnat, _ = net.ParseCIDR("100.64.0.0/10")
@@ -0,0 +1,146 @@
+package whatsapp
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestFirstLink(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"See https://example.com/post?id=7.", "https://example.com/post?id=7"},
+		{"(details at http://example.org/a)", "http://example.org/a"},
+		{"two links: https://a.example and https://b.example", "https://a.example"},
+		{"no link, just example.com", ""},
+	}
+	for _, tt := range tests {
+		if got := firstLink(tt.text); got != tt.want {
+			t.Errorf("firstLink(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestParsePreviewPage(t *testing.T) {
+	og := parsePreviewPage([]byte(`<!doctype html><html><head>
+		<title>Plain title</title>
+		<meta name="description" content="Plain description">
+		<meta property="og:title" content="  Spring   sale ">
+		<meta property="og:description" content="Everything 20% off">
+		<meta property="og:image" content="/img/sale.png">
+		</head><body><meta property="og:title" content="ignored"></body></html>`))
+	if og.Title != "Spring sale" || og.Description != "Everything 20% off" || og.ImageURL != "/img/sale.png" {
+		t.Errorf("open graph page = %+v", og)
+	}
+
+	plain := parsePreviewPage([]byte(`<html><head><title>Plain title</title><meta name="Description" content="Plain description"></head></html>`))
+	if plain.Title != "Plain title" || plain.Description != "Plain description" || plain.ImageURL != "" {
+		t.Errorf("plain page = %+v", plain)
+	}
+
+	long := parsePreviewPage([]byte(`<title>t</title><meta name="description" content="` + strings.Repeat("x", 400) + `">`))
+	if n := len([]rune(long.Description)); n != maxPreviewDescription {
+		t.Errorf("long description has %d characters", n)
+	}
+}
+
+func TestFetchLinkPreview(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 600, 300))
+	for x := 0; x < 600; x++ {
+		for y := 0; y < 300; y++ {
+			img.Set(x, y, color.RGBA{200, 40, 40, 255})
+		}
+	}
+	var pngData bytes.Buffer
+	png.Encode(&pngData, img)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/post":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(`<html><head><meta property="og:title" content="Launch day"><meta property="og:image" content="/cover.png"></head></html>`))
+		case "/cover.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(pngData.Bytes())
+		case "/file.zip":
+			w.Header().Set("Content-Type", "application/zip")
+			w.Write([]byte("PK"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	// The test server listens on loopback, which previews refuse by default
+	if _, err := fetchLinkPreview(context.Background(), srv.URL+"/post"); err == nil || !strings.Contains(err.Error(), "private address") {
+		t.Fatalf("loopback fetch err = %v, want it refused", err)
+	}
+	t.Setenv("DISABLE_SSRF_CHECK", "true")
+
+	preview, err := fetchLinkPreview(context.Background(), srv.URL+"/post")
+	if err != nil {
+		t.Fatalf("fetchLinkPreview: %v", err)
+	}
+	if preview.Title != "Launch day" || preview.ImageURL != srv.URL+"/cover.png" {
+		t.Errorf("preview = %+v", preview)
+	}
+	thumb, _, err := image.Decode(bytes.NewReader(preview.Thumbnail))
+	if err != nil {
+		t.Fatalf("thumbnail: %v", err)
+	}
+	if b := thumb.Bounds(); b.Dx() != previewThumbnailSize || b.Dy() != previewThumbnailSize/2 {
+		t.Errorf("thumbnail is %dx%d", b.Dx(), b.Dy())
+	}
+
+	if _, err := fetchLinkPreview(context.Background(), srv.URL+"/file.zip"); err == nil {
+		t.Error("preview of a zip file succeeded")
+	}
+	if _, err := fetchLinkPreview(context.Background(), srv.URL+"/missing"); err == nil {
+		t.Error("preview of a missing page succeeded")
+	}
+
+	// The preview moves the text to an extended message, which keeps mentions
+	msg := &waE2E.Message{Conversation: proto.String("Read " + srv.URL + "/post @15550102030")}
+	addLinkPreview(msg, preview)
+	addMentions(msg, []string{"15550102030@s.whatsapp.net"})
+	ext := msg.GetExtendedTextMessage()
+	if msg.Conversation != nil || ext.GetTitle() != "Launch day" || ext.GetPreviewType() != waE2E.ExtendedTextMessage_IMAGE ||
+		len(ext.GetJPEGThumbnail()) == 0 || len(ext.GetContextInfo().GetMentionedJID()) != 1 {
+		t.Errorf("message = %v", msg)
+	}
+
+	// A message already extended, here by a quote, keeps its context
+	quoted := &waE2E.Message{ExtendedTextMessage: &waE2E.ExtendedTextMessage{
+		Text:        proto.String("Read " + srv.URL + "/post"),
+		ContextInfo: &waE2E.ContextInfo{StanzaID: proto.String("Q1")},
+	}}
+	addLinkPreview(quoted, preview)
+	if ext := quoted.GetExtendedTextMessage(); ext.GetTitle() != "Launch day" || ext.GetContextInfo().GetStanzaID() != "Q1" {
+		t.Errorf("quoted message = %v", quoted)
+	}
+}
+
+func TestPreviewThumbnailSize(t *testing.T) {
+	// A small image claiming a huge canvas is refused before it is decoded
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, image.NewPaletted(image.Rect(0, 0, 1, 1), color.Palette{color.Black}), nil); err != nil {
+		t.Fatalf("gif.Encode: %v", err)
+	}
+	huge := buf.Bytes()
+	copy(huge[6:10], []byte{0xff, 0xff, 0xff, 0xff}) // logical screen 65535x65535
+	if _, err := previewThumbnail(huge); err == nil || !strings.Contains(err.Error(), "megapixels") {
+		t.Errorf("previewThumbnail(65535x65535) error = %v, want the pixel cap", err)
+	}
+}
@@ -2,13 +2,22 @@ package whatsapp
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"math"
 	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"go.mau.fi/whatsmeow/proto/waE2E"
+
+	"whatsapp-bridge/internal/textfmt"
+	localTypes "whatsapp-bridge/internal/types"
 )
 
 // ExtractTextContent extracts text content from a WhatsApp message
@@ -28,6 +37,71 @@ func ExtractTextContent(msg *waE2E.Message) string {
 	return ""
 }
 
+// ExtractMessageContext extracts what a message carries besides its text:
+// the users it mentions, its formatting and the message it quotes. Media
+// messages carry these too, with the formatting in their caption.
+// It returns nil when there is none of these.
+func ExtractMessageContext(msg *waE2E.Message, chatJID string) *localTypes.MessageContext {
+	if msg == nil {
+		return nil
+	}
+	text := ExtractTextContent(msg)
+	if text == "" {
+		text = mediaCaption(msg)
+	}
+
+	mc := &localTypes.MessageContext{Formatting: textfmt.Spans(text)}
+	if info := messageContextInfo(msg); info != nil {
+		mc.Mentions = info.GetMentionedJID()
+		if id := info.GetStanzaID(); id != "" {
+			quoted := &localTypes.QuotedMessage{ID: id, Sender: info.GetParticipant(), ChatJID: info.GetRemoteJID()}
+			if quoted.ChatJID == "" {
+				quoted.ChatJID = chatJID // replies within the chat leave it out
+			}
+			mc.Quoted = quoted
+		}
+	}
+
+	if len(mc.Mentions) == 0 && len(mc.Formatting) == 0 && mc.Quoted == nil {
+		return nil
+	}
+	return mc
+}
+
+// mediaCaption returns the caption of an image, video or document
+func mediaCaption(msg *waE2E.Message) string {
+	switch {
+	case msg.GetImageMessage() != nil:
+		return msg.GetImageMessage().GetCaption()
+	case msg.GetVideoMessage() != nil:
+		return msg.GetVideoMessage().GetCaption()
+	case msg.GetDocumentMessage() != nil:
+		return msg.GetDocumentMessage().GetCaption()
+	}
+	return ""
+}
+
+// messageContextInfo returns the context info of a message's content, which
+// carries its mentions, the message it quotes and its disappearing timer
+func messageContextInfo(msg *waE2E.Message) *waE2E.ContextInfo {
+	for _, info := range []*waE2E.ContextInfo{
+		msg.GetExtendedTextMessage().GetContextInfo(),
+		msg.GetImageMessage().GetContextInfo(),
+		msg.GetVideoMessage().GetContextInfo(),
+		msg.GetPtvMessage().GetContextInfo(),
+		msg.GetAudioMessage().GetContextInfo(),
+		msg.GetDocumentMessage().GetContextInfo(),
+		msg.GetStickerMessage().GetContextInfo(),
+		msg.GetContactMessage().GetContextInfo(),
+		msg.GetLocationMessage().GetContextInfo(),
+	} {
+		if info != nil {
+			return info
+		}
+	}
+	return nil
+}
+
 // ExtractMediaInfo extracts media information from a WhatsApp message
 func ExtractMediaInfo(msg *waE2E.Message) (mediaType string, filename string, url string, mediaKey []byte, fileSHA256 []byte, fileEncSHA256 []byte, fileLength uint64) {
 	if msg == nil {
@@ -40,12 +114,26 @@ func ExtractMediaInfo(msg *waE2E.Message) (mediaType string, filename string, ur
 			img.GetURL(), img.GetMediaKey(), img.GetFileSHA256(), img.GetFileEncSHA256(), img.GetFileLength()
 	}
 
-	// Check for video message
+	// Check for video message; GIFs arrive as MP4 videos flagged for looped playback
 	if vid := msg.GetVideoMessage(); vid != nil {
-		return "video", "video_" + time.Now().Format("20060102_150405") + ".mp4",
+		kind := "video"
+		if vid.GetGifPlayback() {
+			kind = "gif"
+		}
+		return kind, kind + "_" + time.Now().Format("20060102_150405") + ".mp4",
 			vid.GetURL(), vid.GetMediaKey(), vid.GetFileSHA256(), vid.GetFileEncSHA256(), vid.GetFileLength()
 	}
 
+	// Check for sticker message
+	if stk := msg.GetStickerMessage(); stk != nil {
+		kind := "sticker"
+		if stk.GetIsAnimated() {
+			kind = "animated_sticker"
+		}
+		return kind, kind + "_" + time.Now().Format("20060102_150405") + ".webp",
+			stk.GetURL(), stk.GetMediaKey(), stk.GetFileSHA256(), stk.GetFileEncSHA256(), stk.GetFileLength()
+	}
+
 	// Check for audio message
 	if aud := msg.GetAudioMessage(); aud != nil {
 		return "audio", "audio_" + time.Now().Format("20060102_150405") + ".ogg",
@@ -65,6 +153,39 @@ func ExtractMediaInfo(msg *waE2E.Message) (mediaType string, filename string, ur
 	return "", "", "", nil, nil, nil, 0
 }
 
+// gifConvertTime bounds how long a GIF to MP4 conversion may run
+const gifConvertTime = 60 * time.Second
+
+// convertGIF re-encodes a GIF as an MP4 video. WhatsApp does not play GIF
+// files; it sends them as short videos with GifPlayback set.
+func convertGIF(ctx context.Context, data []byte) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "gif-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "in.gif")
+	dst := filepath.Join(dir, "out.mp4")
+	if err := os.WriteFile(src, data, 0600); err != nil {
+		return nil, err
+	}
+
+	convertCtx, cancel := context.WithTimeout(ctx, gifConvertTime)
+	defer cancel()
+	// H.264 needs even dimensions and yuv420p for the phone players
+	cmd := exec.CommandContext(convertCtx, "ffmpeg", "-nostdin", "-loglevel", "error", "-i", src,
+		"-movflags", "faststart", "-pix_fmt", "yuv420p", "-an",
+		"-vf", "scale=trunc(iw/2)*2:trunc(ih/2)*2", dst)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, fmt.Errorf("ffmpeg is not installed (install ffmpeg to send GIFs)")
+		}
+		return nil, fmt.Errorf("ffmpeg failed: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return os.ReadFile(dst)
+}
+
 // AnalyzeOggOpus tries to extract duration and generate a simple waveform from an Ogg Opus file
 func AnalyzeOggOpus(data []byte) (duration uint32, waveform []byte, err error) {
 	// Try to detect if this is a valid Ogg file by checking for the "OggS" signature
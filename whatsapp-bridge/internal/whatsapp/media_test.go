@@ -0,0 +1,78 @@
+package whatsapp
+
+import (
+	"strings"
+	"testing"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestExtractMediaInfoAnimated(t *testing.T) {
+	tests := []struct {
+		name     string
+		msg      *waE2E.Message
+		wantType string
+		wantExt  string
+	}{
+		{"video", &waE2E.Message{VideoMessage: &waE2E.VideoMessage{}}, "video", ".mp4"},
+		{"gif", &waE2E.Message{VideoMessage: &waE2E.VideoMessage{GifPlayback: proto.Bool(true)}}, "gif", ".mp4"},
+		{"sticker", &waE2E.Message{StickerMessage: &waE2E.StickerMessage{}}, "sticker", ".webp"},
+		{"animated sticker", &waE2E.Message{StickerMessage: &waE2E.StickerMessage{IsAnimated: proto.Bool(true)}}, "animated_sticker", ".webp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mediaType, filename, _, _, _, _, _ := ExtractMediaInfo(tt.msg)
+			if mediaType != tt.wantType {
+				t.Errorf("media type = %q, want %q", mediaType, tt.wantType)
+			}
+			if !strings.HasPrefix(filename, tt.wantType+"_") || !strings.HasSuffix(filename, tt.wantExt) {
+				t.Errorf("filename = %q, want %s_*%s", filename, tt.wantType, tt.wantExt)
+			}
+		})
+	}
+}
+
+func TestExtractMessageContext(t *testing.T) {
+	chat := "120363000000000000@g.us"
+
+	if mc := ExtractMessageContext(&waE2E.Message{Conversation: proto.String("plain")}, chat); mc != nil {
+		t.Errorf("plain text context = %+v, want nil", mc)
+	}
+
+	msg := &waE2E.Message{ExtendedTextMessage: &waE2E.ExtendedTextMessage{
+		Text: proto.String("@15551234567 see *this*"),
+		ContextInfo: &waE2E.ContextInfo{
+			MentionedJID: []string{"15551234567@s.whatsapp.net"},
+			StanzaID:     proto.String("QUOTED1"),
+			Participant:  proto.String("15557654321@s.whatsapp.net"),
+		},
+	}}
+	mc := ExtractMessageContext(msg, chat)
+	if mc == nil {
+		t.Fatal("context = nil")
+	}
+	if len(mc.Mentions) != 1 || mc.Mentions[0] != "15551234567@s.whatsapp.net" {
+		t.Errorf("mentions = %v", mc.Mentions)
+	}
+	if len(mc.Formatting) != 1 || mc.Formatting[0].Style != "bold" || mc.Formatting[0].Start != 18 || mc.Formatting[0].End != 22 {
+		t.Errorf("formatting = %+v, want bold 18-22", mc.Formatting)
+	}
+	if q := mc.Quoted; q == nil || q.ID != "QUOTED1" || q.Sender != "15557654321@s.whatsapp.net" || q.ChatJID != chat {
+		t.Errorf("quoted = %+v, want QUOTED1 from 15557654321 in this chat", q)
+	}
+
+	// Media captions carry mentions, formatting and quotes too
+	image := &waE2E.Message{ImageMessage: &waE2E.ImageMessage{
+		Caption: proto.String("_look_ @15551234567"),
+		ContextInfo: &waE2E.ContextInfo{
+			MentionedJID: []string{"15551234567@s.whatsapp.net"},
+			StanzaID:     proto.String("QUOTED2"),
+		},
+	}}
+	mc = ExtractMessageContext(image, chat)
+	if mc == nil || len(mc.Mentions) != 1 || len(mc.Formatting) != 1 || mc.Formatting[0].Style != "italic" || mc.Quoted == nil || mc.Quoted.ID != "QUOTED2" {
+		t.Errorf("image context = %+v", mc)
+	}
+}
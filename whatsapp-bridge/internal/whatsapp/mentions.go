@@ -0,0 +1,114 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+
+	"whatsapp-bridge/internal/phone"
+)
+
+// mentionToken matches an @phone token in message text, e.g. "@15550102030"
+// or "@+15550102030", unless preceded by a word character as in an email
+// address
+var mentionToken = regexp.MustCompile(`(^|[^\w@])@(\+?\d{7,15})\b`)
+
+type mentionsKey struct{}
+
+// WithMentions returns a context whose sends @-mention the given user JIDs
+func WithMentions(ctx context.Context, jids []string) context.Context {
+	return context.WithValue(ctx, mentionsKey{}, jids)
+}
+
+// MentionsFrom returns the user JIDs set by WithMentions
+func MentionsFrom(ctx context.Context) []string {
+	jids, _ := ctx.Value(mentionsKey{}).([]string)
+	return jids
+}
+
+// ParseMentions reads the users a message mentions, given as JIDs or phone
+// numbers, as user JIDs
+func ParseMentions(mentions []string) ([]string, error) {
+	var jids []string
+	for _, m := range mentions {
+		jid, err := parseRecipient(strings.TrimSpace(m))
+		if err != nil {
+			return nil, fmt.Errorf("invalid mention %q: %v", m, err)
+		}
+		if jid.Server != types.DefaultUserServer && jid.Server != types.HiddenUserServer {
+			return nil, fmt.Errorf("invalid mention %q: not a user", m)
+		}
+		jids = appendMention(jids, jid.ToNonAD().String())
+	}
+	return jids, nil
+}
+
+// ConvertMentionTokens rewrites the @phone tokens in text the way WhatsApp
+// writes mentions, "@" and the number's digits, and returns the user JIDs
+// they mention. Tokens that are not valid phone numbers are left as they are.
+func ConvertMentionTokens(text string) (string, []string) {
+	var jids []string
+	text = mentionToken.ReplaceAllStringFunc(text, func(token string) string {
+		m := mentionToken.FindStringSubmatch(token)
+		number, err := phone.Parse(m[2], "")
+		if err != nil {
+			return token
+		}
+		jids = appendMention(jids, number.JID())
+		return m[1] + "@" + number.Digits
+	})
+	return text, jids
+}
+
+// SplitMentions shares mentions out among the parts of a split message: each
+// part mentions the users tagged in its text, and the first part also those
+// not tagged in any
+func SplitMentions(parts []string, jids []string) [][]string {
+	perPart := make([][]string, len(parts))
+	for _, jid := range jids {
+		tag := "@" + strings.SplitN(jid, "@", 2)[0]
+		tagged := false
+		for i, part := range parts {
+			if strings.Contains(part, tag) {
+				perPart[i] = append(perPart[i], jid)
+				tagged = true
+			}
+		}
+		if !tagged && len(parts) > 0 {
+			perPart[0] = append(perPart[0], jid)
+		}
+	}
+	return perPart
+}
+
+func appendMention(jids []string, jid string) []string {
+	for _, j := range jids {
+		if j == jid {
+			return jids
+		}
+	}
+	return append(jids, jid)
+}
+
+// addMentions marks a built message as mentioning jids. Text moves from a
+// plain conversation to an extended text message, which can carry them.
+func addMentions(msg *waE2E.Message, jids []string) {
+	info := &waE2E.ContextInfo{MentionedJID: jids}
+	switch {
+	case msg.Conversation != nil:
+		msg.ExtendedTextMessage = &waE2E.ExtendedTextMessage{Text: msg.Conversation, ContextInfo: info}
+		msg.Conversation = nil
+	case msg.ExtendedTextMessage != nil:
+		msg.ExtendedTextMessage.ContextInfo = info
+	case msg.ImageMessage != nil:
+		msg.ImageMessage.ContextInfo = info
+	case msg.VideoMessage != nil:
+		msg.VideoMessage.ContextInfo = info
+	case msg.DocumentMessage != nil:
+		msg.DocumentMessage.ContextInfo = info
+	}
+}
@@ -0,0 +1,67 @@
+package whatsapp
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestConvertMentionTokens(t *testing.T) {
+	text, jids := ConvertMentionTokens("@+15550102030 and @15550102031 please review; mail ana@15550102032.example, not @12")
+	if text != "@15550102030 and @15550102031 please review; mail ana@15550102032.example, not @12" {
+		t.Errorf("text = %q", text)
+	}
+	want := []string{"15550102030@s.whatsapp.net", "15550102031@s.whatsapp.net"}
+	if !reflect.DeepEqual(jids, want) {
+		t.Errorf("jids = %v, want %v", jids, want)
+	}
+
+	if _, jids := ConvertMentionTokens("@+15550102030 thanks, @+15550102030"); len(jids) != 1 {
+		t.Errorf("repeated tag mentioned %d times, want once", len(jids))
+	}
+}
+
+func TestParseMentions(t *testing.T) {
+	jids, err := ParseMentions([]string{"+1 (555) 010-2030", "15550102030@s.whatsapp.net", "123456789@lid"})
+	if err != nil {
+		t.Fatalf("ParseMentions: %v", err)
+	}
+	want := []string{"15550102030@s.whatsapp.net", "123456789@lid"}
+	if !reflect.DeepEqual(jids, want) {
+		t.Errorf("ParseMentions = %v, want %v", jids, want)
+	}
+
+	if _, err := ParseMentions([]string{"120363000000000000@g.us"}); err == nil {
+		t.Error("ParseMentions accepted a group")
+	}
+}
+
+func TestSplitMentions(t *testing.T) {
+	ana, ben := "15550000001@s.whatsapp.net", "15550000002@s.whatsapp.net"
+	got := SplitMentions([]string{"(1/2) hi all", "(2/2) @15550000002 over to you"}, []string{ana, ben})
+	want := [][]string{{ana}, {ben}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SplitMentions = %v, want %v", got, want)
+	}
+}
+
+func TestAddMentions(t *testing.T) {
+	jids := []string{"15550000001@s.whatsapp.net"}
+
+	msg := &waE2E.Message{Conversation: proto.String("@15550000001 hi")}
+	addMentions(msg, jids)
+	if msg.Conversation != nil || msg.GetExtendedTextMessage().GetText() != "@15550000001 hi" {
+		t.Errorf("text not moved to extended text: %v", msg)
+	}
+	if got := msg.GetExtendedTextMessage().GetContextInfo().GetMentionedJID(); !reflect.DeepEqual(got, jids) {
+		t.Errorf("MentionedJID = %v", got)
+	}
+
+	msg = &waE2E.Message{ImageMessage: &waE2E.ImageMessage{Caption: proto.String("@15550000001 look")}}
+	addMentions(msg, jids)
+	if got := msg.GetImageMessage().GetContextInfo().GetMentionedJID(); !reflect.DeepEqual(got, jids) {
+		t.Errorf("caption MentionedJID = %v", got)
+	}
+}
@@ -7,8 +7,14 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"whatsapp-bridge/internal/automation"
 	"whatsapp-bridge/internal/database"
+	"whatsapp-bridge/internal/msgref"
+	"whatsapp-bridge/internal/phone"
+	"whatsapp-bridge/internal/retry"
+	"whatsapp-bridge/internal/tenant"
 	bridgeTypes "whatsapp-bridge/internal/types"
 
 	"go.mau.fi/whatsmeow"
@@ -24,8 +30,8 @@ var allowedMediaDirs = []string{
 	"/tmp",
 }
 
-// validateMediaPath checks if the path is within allowed directories
-func validateMediaPath(mediaPath string) error {
+// ValidateMediaPath checks if the path is within allowed directories
+func ValidateMediaPath(mediaPath string) error {
 	if mediaPath == "" {
 		return nil
 	}
@@ -61,31 +67,42 @@ func validateMediaPath(mediaPath string) error {
 	return fmt.Errorf("media path outside allowed directories")
 }
 
-// SendMessage sends a WhatsApp message with optional media
-func (c *Client) SendMessage(messageStore *database.MessageStore, recipient string, message string, mediaPath string) bridgeTypes.SendResult {
+// parseRecipient accepts a full JID or a phone number for a personal chat,
+// in any format phone.Parse understands
+func parseRecipient(recipient string) (types.JID, error) {
+	if strings.Contains(recipient, "@") {
+		return types.ParseJID(recipient)
+	}
+	number, err := phone.Parse(recipient, "")
+	if err != nil {
+		return types.JID{}, err
+	}
+	return types.NewJID(number.Digits, types.DefaultUserServer), nil
+}
+
+// SendMessage sends a WhatsApp message with optional media on behalf of the default tenant
+func (c *Client) SendMessage(ctx context.Context, messageStore *database.MessageStore, recipient string, message string, mediaPath string) bridgeTypes.SendResult {
+	return c.SendMessageAs(ctx, messageStore, tenant.Default, recipient, message, mediaPath)
+}
+
+// SendMessageAs sends a WhatsApp message with optional media, recording owner
+// as the tenant that sent it
+func (c *Client) SendMessageAs(ctx context.Context, messageStore *database.MessageStore, owner, recipient, message, mediaPath string) bridgeTypes.SendResult {
 	if !c.IsConnected() {
-		return bridgeTypes.SendResult{Success: false, Error: "Not connected to WhatsApp"}
+		return sendFailure(SendErrNotConnected, true, "Not connected to WhatsApp")
 	}
 
-	// Create JID for recipient
-	var recipientJID types.JID
-	var err error
+	recipientJID, err := parseRecipient(recipient)
+	if err != nil {
+		return sendFailure(SendErrInvalidRecipient, false, "Error parsing JID: %v", err)
+	}
 
-	// Check if recipient is a JID
-	isJID := strings.Contains(recipient, "@")
+	if utf8.RuneCountInString(message) > MaxTextLength {
+		return sendFailure(SendErrTooLarge, false, "Message exceeds %d characters", MaxTextLength)
+	}
 
-	if isJID {
-		// Parse the JID string
-		recipientJID, err = types.ParseJID(recipient)
-		if err != nil {
-			return bridgeTypes.SendResult{Success: false, Error: fmt.Sprintf("Error parsing JID: %v", err)}
-		}
-	} else {
-		// Create JID from phone number
-		recipientJID = types.JID{
-			User:   recipient,
-			Server: "s.whatsapp.net", // For personal chats
-		}
+	if !c.checkRegistered(ctx, recipientJID) {
+		return sendFailure(SendErrNotOnWhatsApp, false, "Recipient %s is not on WhatsApp", recipientJID.User)
 	}
 
 	msg := &waE2E.Message{}
@@ -93,20 +110,23 @@ func (c *Client) SendMessage(messageStore *database.MessageStore, recipient stri
 	// Check if we have media to send
 	if mediaPath != "" {
 		// Validate media path (prevent path traversal)
-		if err := validateMediaPath(mediaPath); err != nil {
-			return bridgeTypes.SendResult{Success: false, Error: fmt.Sprintf("Invalid media path: %v", err)}
+		if err := ValidateMediaPath(mediaPath); err != nil {
+			return sendFailure(SendErrInvalidMedia, false, "Invalid media path: %v", err)
 		}
 
 		// Read media file
 		mediaData, err := os.ReadFile(mediaPath)
 		if err != nil {
-			return bridgeTypes.SendResult{Success: false, Error: fmt.Sprintf("Error reading media file: %v", err)}
+			return sendFailure(SendErrInvalidMedia, false, "Error reading media file: %v", err)
 		}
 
 		// Determine media type and mime type based on file extension
 		fileExt := strings.ToLower(mediaPath[strings.LastIndex(mediaPath, ".")+1:])
 		var mediaType whatsmeow.MediaType
 		var mimeType string
+		var gifPlayback, sticker bool
+		var voiceSeconds uint32
+		var voiceWaveform []byte
 
 		// Handle different media types
 		switch fileExt {
@@ -118,16 +138,37 @@ func (c *Client) SendMessage(messageStore *database.MessageStore, recipient stri
 			mediaType = whatsmeow.MediaImage
 			mimeType = "image/png"
 		case "gif":
-			mediaType = whatsmeow.MediaImage
-			mimeType = "image/gif"
+			// Sent as a looping video, see convertGIF
+			mediaType = whatsmeow.MediaVideo
+			mimeType = "video/mp4"
+			gifPlayback = true
+			mediaData, err = convertGIF(ctx, mediaData)
+			if err != nil {
+				return sendFailure(SendErrInvalidMedia, false, "Error converting GIF: %v", err)
+			}
 		case "webp":
+			// WebP images are sent as stickers, animated or not
 			mediaType = whatsmeow.MediaImage
 			mimeType = "image/webp"
+			sticker = true
 
 		// Audio types
 		case "ogg":
 			mediaType = whatsmeow.MediaAudio
 			mimeType = "audio/ogg; codecs=opus"
+		case "mp3", "m4a", "wav":
+			if !c.voiceTranscode {
+				mediaType = whatsmeow.MediaDocument
+				mimeType = "application/octet-stream"
+				break
+			}
+			// Sent as a voice note, see transcodeVoiceNote
+			mediaType = whatsmeow.MediaAudio
+			mimeType = "audio/ogg; codecs=opus"
+			mediaData, voiceSeconds, voiceWaveform, err = transcodeVoiceNote(ctx, mediaData, fileExt)
+			if err != nil {
+				return sendFailure(SendErrInvalidMedia, false, "Error converting audio to a voice note: %v", err)
+			}
 
 		// Video types
 		case "mp4":
@@ -147,14 +188,26 @@ func (c *Client) SendMessage(messageStore *database.MessageStore, recipient stri
 		}
 
 		// Upload media to WhatsApp servers
-		resp, err := c.Upload(context.Background(), mediaData, mediaType)
+		resp, err := c.Upload(ctx, mediaData, mediaType)
 		if err != nil {
-			return bridgeTypes.SendResult{Success: false, Error: fmt.Sprintf("Error uploading media: %v", err)}
+			code, retryable := classifySendError(err)
+			return sendFailure(code, retryable, "Error uploading media: %v", err)
 		}
 
 		// Create the appropriate message type based on media type
-		switch mediaType {
-		case whatsmeow.MediaImage:
+		switch {
+		case sticker:
+			msg.StickerMessage = &waE2E.StickerMessage{
+				Mimetype:      proto.String(mimeType),
+				URL:           &resp.URL,
+				DirectPath:    &resp.DirectPath,
+				MediaKey:      resp.MediaKey,
+				FileEncSHA256: resp.FileEncSHA256,
+				FileSHA256:    resp.FileSHA256,
+				FileLength:    &resp.FileLength,
+				IsAnimated:    proto.Bool(isAnimatedWebP(mediaData)),
+			}
+		case mediaType == whatsmeow.MediaImage:
 			msg.ImageMessage = &waE2E.ImageMessage{
 				Caption:       proto.String(message),
 				Mimetype:      proto.String(mimeType),
@@ -165,19 +218,22 @@ func (c *Client) SendMessage(messageStore *database.MessageStore, recipient stri
 				FileSHA256:    resp.FileSHA256,
 				FileLength:    &resp.FileLength,
 			}
-		case whatsmeow.MediaAudio:
+		case mediaType == whatsmeow.MediaAudio:
 			// Handle ogg audio files
 			var seconds uint32 = 30 // Default fallback
 			var waveform []byte = nil
 
-			// Try to analyze the ogg file
-			if strings.Contains(mimeType, "ogg") {
+			// Try to analyze the ogg file, unless it was measured while converting it
+			if voiceWaveform != nil {
+				seconds = voiceSeconds
+				waveform = voiceWaveform
+			} else if strings.Contains(mimeType, "ogg") {
 				analyzedSeconds, analyzedWaveform, err := AnalyzeOggOpus(mediaData)
 				if err == nil {
 					seconds = analyzedSeconds
 					waveform = analyzedWaveform
 				} else {
-					return bridgeTypes.SendResult{Success: false, Error: fmt.Sprintf("Failed to analyze Ogg Opus file: %v", err)}
+					return sendFailure(SendErrInvalidMedia, false, "Failed to analyze Ogg Opus file: %v", err)
 				}
 			}
 
@@ -193,7 +249,7 @@ func (c *Client) SendMessage(messageStore *database.MessageStore, recipient stri
 				PTT:           proto.Bool(true),
 				Waveform:      waveform,
 			}
-		case whatsmeow.MediaVideo:
+		case mediaType == whatsmeow.MediaVideo:
 			msg.VideoMessage = &waE2E.VideoMessage{
 				Caption:       proto.String(message),
 				Mimetype:      proto.String(mimeType),
@@ -203,8 +259,9 @@ func (c *Client) SendMessage(messageStore *database.MessageStore, recipient stri
 				FileEncSHA256: resp.FileEncSHA256,
 				FileSHA256:    resp.FileSHA256,
 				FileLength:    &resp.FileLength,
+				GifPlayback:   proto.Bool(gifPlayback),
 			}
-		case whatsmeow.MediaDocument:
+		case mediaType == whatsmeow.MediaDocument:
 			msg.DocumentMessage = &waE2E.DocumentMessage{
 				Title:         proto.String(mediaPath[strings.LastIndex(mediaPath, "/")+1:]),
 				Caption:       proto.String(message),
@@ -219,40 +276,90 @@ func (c *Client) SendMessage(messageStore *database.MessageStore, recipient stri
 		}
 	} else {
 		msg.Conversation = proto.String(message)
+		if LinkPreviewFrom(ctx) {
+			c.attachLinkPreview(ctx, msg, message)
+		}
 	}
 
-	// Send message
-	sendResp, err := c.Client.SendMessage(context.Background(), recipientJID, msg)
+	if mentioned := MentionsFrom(ctx); len(mentioned) > 0 {
+		addMentions(msg, mentioned)
+	}
+
+	return c.sendTracked(ctx, messageStore, owner, recipientJID, msg, message)
+}
+
+// sendTracked sends a built message, tracking it as pending until the server
+// acks it, and records it in the message history. Blocked contacts are
+// refused before anything is sent.
+func (c *Client) sendTracked(ctx context.Context, messageStore *database.MessageStore, owner string, recipientJID types.JID, msg *waE2E.Message, content string) bridgeTypes.SendResult {
+	if c.isBlocked(ctx, messageStore, recipientJID) {
+		return sendFailure(SendErrBlocked, false, "Recipient %s is blocked", recipientJID.User)
+	}
+
+	metadataOnly := c.MetadataOnly(recipientJID.String())
+	if metadataOnly {
+		content = ""
+	}
+
+	messageID := c.GenerateMessageID()
+	c.trackOutgoing(messageStore, owner, automation.Origin(ctx), messageID, recipientJID, content)
+
+	var sendResp whatsmeow.SendResponse
+	err := retry.Do(ctx, sendPolicy, func() (err error) {
+		sendResp, err = c.Client.SendMessage(ctx, recipientJID, msg, whatsmeow.SendRequestExtra{ID: messageID})
+		return err
+	})
 	if err != nil {
-		return bridgeTypes.SendResult{Success: false, Error: fmt.Sprintf("Error sending message: %v", err)}
-	}
-
-	_ = messageStore.StoreMessage(
-		sendResp.ID, // Use the ID from SendResponse
-		recipientJID.String(),
-		c.Store.ID.User,       // Use the client's user ID as sender
-		c.Store.ID.User,       // SenderName - use our own user ID for sent messages
-		msg.GetConversation(), // Use the conversation text
-		sendResp.Timestamp,    // Use the Timestamp from SendResponse
-		true,                  // IsFromMe is true since we are sending this message
-		"",
-		"",
-		"",
-		nil, // Replace "" with nil for []byte arguments
-		nil, // Replace "" with nil for []byte arguments
-		nil, // Replace "" with nil for []byte arguments
-		0,
-	)
+		c.setOutgoingStatus(messageStore, string(messageID), database.OutgoingFailed, err.Error())
+		c.noteSendError(err)
+		code, retryable := classifySendError(err)
+		return sendFailure(code, retryable, "Error sending message: %v", err)
+	}
+	c.setOutgoingStatus(messageStore, string(messageID), database.OutgoingServerAck, "")
+	c.notifySent(messageStore, string(messageID))
+	messagesSent.Inc()
+
+	// Text goes out as a plain conversation, or as extended text when it
+	// carries mentions
+	text := msg.GetConversation()
+	if text == "" {
+		text = msg.GetExtendedTextMessage().GetText()
+	}
+	if metadataOnly {
+		if text != "" {
+			_ = messageStore.StoreMessageMetadata(string(sendResp.ID), recipientJID.String(), c.Store.ID.User, c.Store.ID.User, sendResp.Timestamp, true, "", 0)
+		}
+	} else {
+		_ = messageStore.StoreMessage(
+			sendResp.ID, // Use the ID from SendResponse
+			recipientJID.String(),
+			c.Store.ID.User,    // Use the client's user ID as sender
+			c.Store.ID.User,    // SenderName - use our own user ID for sent messages
+			text,               // Use the conversation text
+			sendResp.Timestamp, // Use the Timestamp from SendResponse
+			true,               // IsFromMe is true since we are sending this message
+			"",
+			"",
+			"",
+			nil, // Replace "" with nil for []byte arguments
+			nil, // Replace "" with nil for []byte arguments
+			nil, // Replace "" with nil for []byte arguments
+			0,
+			ExtractMessageContext(msg, recipientJID.String()),
+		)
+	}
 
 	return bridgeTypes.SendResult{
-		Success:   true,
-		MessageID: string(sendResp.ID),
-		Timestamp: sendResp.Timestamp,
+		Success:    true,
+		MessageID:  string(sendResp.ID),
+		MessageRef: msgref.Encode(recipientJID.String(), string(sendResp.ID)),
+		Status:     database.OutgoingServerAck,
+		Timestamp:  sendResp.Timestamp,
 	}
 }
 
 // SendReaction sends an emoji reaction to a message
-func (c *Client) SendReaction(chatJID, messageID, emoji string) error {
+func (c *Client) SendReaction(ctx context.Context, chatJID, messageID, emoji string) error {
 	if !c.IsConnected() {
 		return fmt.Errorf("not connected to WhatsApp")
 	}
@@ -266,7 +373,7 @@ func (c *Client) SendReaction(chatJID, messageID, emoji string) error {
 	senderJID := c.Store.ID.ToNonAD()
 
 	msg := c.Client.BuildReaction(chat, senderJID, msgID, emoji)
-	_, err = c.Client.SendMessage(context.Background(), chat, msg)
+	_, err = c.Client.SendMessage(ctx, chat, msg)
 	if err != nil {
 		return fmt.Errorf("failed to send reaction: %v", err)
 	}
@@ -275,7 +382,7 @@ func (c *Client) SendReaction(chatJID, messageID, emoji string) error {
 }
 
 // EditMessage edits a previously sent message
-func (c *Client) EditMessage(chatJID, messageID, newContent string) error {
+func (c *Client) EditMessage(ctx context.Context, chatJID, messageID, newContent string) error {
 	if !c.IsConnected() {
 		return fmt.Errorf("not connected to WhatsApp")
 	}
@@ -291,7 +398,7 @@ func (c *Client) EditMessage(chatJID, messageID, newContent string) error {
 		Conversation: proto.String(newContent),
 	}
 	msg := c.Client.BuildEdit(chat, msgID, newMsg)
-	_, err = c.Client.SendMessage(context.Background(), chat, msg)
+	_, err = c.Client.SendMessage(ctx, chat, msg)
 	if err != nil {
 		return fmt.Errorf("failed to edit message: %v", err)
 	}
@@ -300,7 +407,7 @@ func (c *Client) EditMessage(chatJID, messageID, newContent string) error {
 }
 
 // DeleteMessage revokes/deletes a message
-func (c *Client) DeleteMessage(chatJID, messageID, senderJID string) error {
+func (c *Client) DeleteMessage(ctx context.Context, chatJID, messageID, senderJID string) error {
 	if !c.IsConnected() {
 		return fmt.Errorf("not connected to WhatsApp")
 	}
@@ -323,7 +430,7 @@ func (c *Client) DeleteMessage(chatJID, messageID, senderJID string) error {
 	}
 
 	msg := c.Client.BuildRevoke(chat, sender, msgID)
-	_, err = c.Client.SendMessage(context.Background(), chat, msg)
+	_, err = c.Client.SendMessage(ctx, chat, msg)
 	if err != nil {
 		return fmt.Errorf("failed to delete message: %v", err)
 	}
@@ -332,7 +439,7 @@ func (c *Client) DeleteMessage(chatJID, messageID, senderJID string) error {
 }
 
 // GetGroupInfo retrieves group metadata
-func (c *Client) GetGroupInfo(groupJID string) (*types.GroupInfo, error) {
+func (c *Client) GetGroupInfo(ctx context.Context, groupJID string) (*types.GroupInfo, error) {
 	if !c.IsConnected() {
 		return nil, fmt.Errorf("not connected to WhatsApp")
 	}
@@ -342,11 +449,16 @@ func (c *Client) GetGroupInfo(groupJID string) (*types.GroupInfo, error) {
 		return nil, fmt.Errorf("invalid group JID: %v", err)
 	}
 
-	return c.Client.GetGroupInfo(context.Background(), jid)
+	var info *types.GroupInfo
+	err = retry.Do(ctx, fetchPolicy, func() (err error) {
+		info, err = c.Client.GetGroupInfo(ctx, jid)
+		return err
+	})
+	return info, err
 }
 
 // MarkMessagesRead marks messages as read
-func (c *Client) MarkMessagesRead(chatJID string, messageIDs []string, senderJID string) error {
+func (c *Client) MarkMessagesRead(ctx context.Context, chatJID string, messageIDs []string, senderJID string) error {
 	if !c.IsConnected() {
 		return fmt.Errorf("not connected to WhatsApp")
 	}
@@ -369,37 +481,74 @@ func (c *Client) MarkMessagesRead(chatJID string, messageIDs []string, senderJID
 		}
 	}
 
-	return c.Client.MarkRead(context.Background(), ids, time.Now(), chat, sender)
+	return c.Client.MarkRead(ctx, ids, time.Now(), chat, sender)
 }
 
 // Phase 2: Group Management
 
-// CreateGroup creates a new WhatsApp group
-func (c *Client) CreateGroup(name string, participants []string) (*types.GroupInfo, error) {
+// CreateGroup creates a new WhatsApp group.
+// The disappearing timer, announce-only and join approval settings are sent with
+// the create request, so the group never exists without them. WhatsApp does not
+// accept a description at creation; it is set immediately afterwards, and if that
+// fails the created group is returned together with the error.
+func (c *Client) CreateGroup(ctx context.Context, req bridgeTypes.CreateGroupRequest) (*types.GroupInfo, error) {
 	if !c.IsConnected() {
 		return nil, fmt.Errorf("not connected to WhatsApp")
 	}
 
+	create, err := newCreateGroupRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := c.Client.CreateGroup(ctx, create)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Description != "" {
+		if err := c.Client.SetGroupTopic(ctx, info.JID, "", "", req.Description); err != nil {
+			return info, fmt.Errorf("failed to set group description: %v", err)
+		}
+		info.Topic = req.Description
+	}
+
+	return info, nil
+}
+
+// newCreateGroupRequest builds the create request carrying a new group's
+// members and initial settings
+func newCreateGroupRequest(req bridgeTypes.CreateGroupRequest) (whatsmeow.ReqCreateGroup, error) {
 	// Parse participant JIDs
-	participantJIDs := make([]types.JID, len(participants))
-	for i, p := range participants {
+	participantJIDs := make([]types.JID, len(req.Participants))
+	for i, p := range req.Participants {
 		jid, err := types.ParseJID(p)
 		if err != nil {
-			return nil, fmt.Errorf("invalid participant JID %s: %v", p, err)
+			return whatsmeow.ReqCreateGroup{}, fmt.Errorf("invalid participant JID %s: %v", p, err)
 		}
 		participantJIDs[i] = jid
 	}
 
-	req := whatsmeow.ReqCreateGroup{
-		Name:         name,
+	create := whatsmeow.ReqCreateGroup{
+		Name:         req.Name,
 		Participants: participantJIDs,
 	}
+	create.IsAnnounce = req.AnnounceOnly
+	create.IsJoinApprovalRequired = req.ApprovalRequired
 
-	return c.Client.CreateGroup(context.Background(), req)
+	if req.Disappearing != "" {
+		timer, err := parseDisappearingDuration(req.Disappearing)
+		if err != nil {
+			return whatsmeow.ReqCreateGroup{}, err
+		}
+		create.IsEphemeral = timer > 0
+		create.DisappearingTimer = uint32(timer.Seconds())
+	}
+	return create, nil
 }
 
 // AddGroupParticipants adds members to a group
-func (c *Client) AddGroupParticipants(groupJID string, participants []string) ([]types.GroupParticipant, error) {
+func (c *Client) AddGroupParticipants(ctx context.Context, groupJID string, participants []string) ([]types.GroupParticipant, error) {
 	if !c.IsConnected() {
 		return nil, fmt.Errorf("not connected to WhatsApp")
 	}
@@ -418,11 +567,91 @@ func (c *Client) AddGroupParticipants(groupJID string, participants []string) ([
 		participantJIDs[i] = jid
 	}
 
-	return c.Client.UpdateGroupParticipants(context.Background(), group, participantJIDs, whatsmeow.ParticipantChangeAdd)
+	return c.Client.UpdateGroupParticipants(ctx, group, participantJIDs, whatsmeow.ParticipantChangeAdd)
+}
+
+// participantErrorReason maps WhatsApp's participant error codes to a stable reason string.
+func participantErrorReason(code int) string {
+	switch code {
+	case 0:
+		return ""
+	case 401:
+		return "not_authorized"
+	case 403:
+		return "privacy_restricted"
+	case 404:
+		return "not_on_whatsapp"
+	case 408:
+		return "recently_left"
+	case 409:
+		return "already_member"
+	case 500:
+		return "group_full"
+	default:
+		return "unknown"
+	}
+}
+
+// AddGroupParticipantsWithFallback adds members to a group and reports a
+// per-user resolution. With inviteFallback, users whose privacy settings
+// prevent adding them are sent the group invite link in a direct message.
+func (c *Client) AddGroupParticipantsWithFallback(ctx context.Context, messageStore *database.MessageStore, groupJID string, participants []string, inviteFallback bool) ([]bridgeTypes.ParticipantAddResult, error) {
+	added, err := c.AddGroupParticipants(ctx, groupJID, participants)
+	if err != nil {
+		return nil, err
+	}
+
+	var inviteLink string
+	var linkErr error
+
+	results := make([]bridgeTypes.ParticipantAddResult, len(added))
+	for i, p := range added {
+		result := bridgeTypes.ParticipantAddResult{
+			JID:        p.JID.String(),
+			Added:      p.Error == 0,
+			ErrorCode:  p.Error,
+			Reason:     participantErrorReason(p.Error),
+			Resolution: "none",
+		}
+
+		switch {
+		case result.Added:
+			result.Resolution = "added"
+
+		case p.Error == 403 && inviteFallback:
+			// Fetch the link once, on first need
+			if inviteLink == "" && linkErr == nil {
+				group, _ := types.ParseJID(groupJID)
+				inviteLink, linkErr = c.Client.GetGroupInviteLink(ctx, group, false)
+			}
+
+			if linkErr != nil {
+				result.Resolution = "invite_failed"
+				result.InviteError = fmt.Sprintf("failed to get invite link: %v", linkErr)
+				break
+			}
+
+			recipient := p.JID
+			if !p.PhoneNumber.IsEmpty() {
+				recipient = p.PhoneNumber
+			}
+			sent := c.SendMessage(ctx, messageStore, recipient.String(), fmt.Sprintf("You're invited to join a WhatsApp group: %s", inviteLink), "")
+			if sent.Success {
+				result.Resolution = "invite_sent"
+			} else {
+				result.Resolution = "invite_failed"
+				result.InviteError = sent.Error
+			}
+		}
+
+		results[i] = result
+	}
+
+	return results, nil
 }
 
 // RemoveGroupParticipants removes members from a group
-func (c *Client) RemoveGroupParticipants(groupJID string, participants []string) ([]types.GroupParticipant, error) {
+func (c *Client) RemoveGroupParticipants(ctx context.Context, groupJID string, participants []string) ([]types.GroupParticipant, error) {
 	if !c.IsConnected() {
 		return nil, fmt.Errorf("not connected to WhatsApp")
 	}
@@ -441,11 +670,11 @@ func (c *Client) RemoveGroupParticipants(groupJID string, participants []string)
 		participantJIDs[i] = jid
 	}
 
-	return c.Client.UpdateGroupParticipants(context.Background(), group, participantJIDs, whatsmeow.ParticipantChangeRemove)
+	return c.Client.UpdateGroupParticipants(ctx, group, participantJIDs, whatsmeow.ParticipantChangeRemove)
 }
 
 // PromoteGroupParticipant promotes a participant to admin
-func (c *Client) PromoteGroupParticipant(groupJID string, participant string) ([]types.GroupParticipant, error) {
+func (c *Client) PromoteGroupParticipant(ctx context.Context, groupJID string, participant string) ([]types.GroupParticipant, error) {
 	if !c.IsConnected() {
 		return nil, fmt.Errorf("not connected to WhatsApp")
 	}
@@ -460,11 +689,11 @@ func (c *Client) PromoteGroupParticipant(groupJID string, participant string) ([
 		return nil, fmt.Errorf("invalid participant JID: %v", err)
 	}
 
-	return c.Client.UpdateGroupParticipants(context.Background(), group, []types.JID{jid}, whatsmeow.ParticipantChangePromote)
+	return c.Client.UpdateGroupParticipants(ctx, group, []types.JID{jid}, whatsmeow.ParticipantChangePromote)
 }
 
 // DemoteGroupParticipant demotes an admin to regular participant
-func (c *Client) DemoteGroupParticipant(groupJID string, participant string) ([]types.GroupParticipant, error) {
+func (c *Client) DemoteGroupParticipant(ctx context.Context, groupJID string, participant string) ([]types.GroupParticipant, error) {
 	if !c.IsConnected() {
 		return nil, fmt.Errorf("not connected to WhatsApp")
 	}
@@ -479,11 +708,11 @@ func (c *Client) DemoteGroupParticipant(groupJID string, participant string) ([]
 		return nil, fmt.Errorf("invalid participant JID: %v", err)
 	}
 
-	return c.Client.UpdateGroupParticipants(context.Background(), group, []types.JID{jid}, whatsmeow.ParticipantChangeDemote)
+	return c.Client.UpdateGroupParticipants(ctx, group, []types.JID{jid}, whatsmeow.ParticipantChangeDemote)
 }
 
 // LeaveGroup leaves a WhatsApp group
-func (c *Client) LeaveGroup(groupJID string) error {
+func (c *Client) LeaveGroup(ctx context.Context, groupJID string) error {
 	if !c.IsConnected() {
 		return fmt.Errorf("not connected to WhatsApp")
 	}
@@ -493,11 +722,11 @@ func (c *Client) LeaveGroup(groupJID string) error {
 		return fmt.Errorf("invalid group JID: %v", err)
 	}
 
-	return c.Client.LeaveGroup(context.Background(), group)
+	return c.Client.LeaveGroup(ctx, group)
 }
 
 // SetGroupName updates the group name
-func (c *Client) SetGroupName(groupJID string, name string) error {
+func (c *Client) SetGroupName(ctx context.Context, groupJID string, name string) error {
 	if !c.IsConnected() {
 		return fmt.Errorf("not connected to WhatsApp")
 	}
@@ -507,11 +736,11 @@ func (c *Client) SetGroupName(groupJID string, name string) error {
 		return fmt.Errorf("invalid group JID: %v", err)
 	}
 
-	return c.Client.SetGroupName(context.Background(), group, name)
+	return c.Client.SetGroupName(ctx, group, name)
 }
 
 // SetGroupTopic updates the group description/topic
-func (c *Client) SetGroupTopic(groupJID string, topic string) error {
+func (c *Client) SetGroupTopic(ctx context.Context, groupJID string, topic string) error {
 	if !c.IsConnected() {
 		return fmt.Errorf("not connected to WhatsApp")
 	}
@@ -521,13 +750,14 @@ func (c *Client) SetGroupTopic(groupJID string, topic string) error {
 		return fmt.Errorf("invalid group JID: %v", err)
 	}
 
-	return c.Client.SetGroupTopic(context.Background(), group, "", "", topic)
+	return c.Client.SetGroupTopic(ctx, group, "", "", topic)
 }
 
 // Phase 3: Polls
 
-// CreatePoll creates and sends a poll to a chat
-func (c *Client) CreatePoll(chatJID string, question string, options []string, multiSelect bool) (bridgeTypes.SendResult, error) {
+// CreatePoll creates and sends a poll to a chat. The poll is stored so the
+// votes it gets can be counted.
+func (c *Client) CreatePoll(ctx context.Context, messageStore *database.MessageStore, chatJID string, question string, options []string, multiSelect bool) (bridgeTypes.SendResult, error) {
 	if !c.IsConnected() {
 		return bridgeTypes.SendResult{Success: false, Error: "not connected to WhatsApp"}, fmt.Errorf("not connected to WhatsApp")
 	}
@@ -547,11 +777,21 @@ func (c *Client) CreatePoll(chatJID string, question string, options []string, m
 	pollMsg := c.Client.BuildPollCreation(question, options, selectableCount)
 
 	// Send the poll
-	resp, err := c.Client.SendMessage(context.Background(), chat, pollMsg)
+	resp, err := c.Client.SendMessage(ctx, chat, pollMsg)
 	if err != nil {
 		return bridgeTypes.SendResult{Success: false, Error: fmt.Sprintf("failed to send poll: %v", err)}, err
 	}
 
+	c.storePoll(messageStore, &bridgeTypes.Poll{
+		ChatJID:         chat.ToNonAD().String(),
+		MessageID:       string(resp.ID),
+		CreatorJID:      c.Store.ID.ToNonAD().String(),
+		Question:        question,
+		Options:         options,
+		SelectableCount: selectableCount,
+		CreatedAt:       resp.Timestamp.UTC(),
+	})
+
 	return bridgeTypes.SendResult{
 		Success:   true,
 		MessageID: string(resp.ID),
@@ -564,7 +804,7 @@ func (c *Client) CreatePoll(chatJID string, question string, options []string, m
 // RequestChatHistory requests older messages for a specific chat.
 // The response will come asynchronously via the HistorySync event handler.
 // This requires knowing the oldest message in the chat to request messages before it.
-func (c *Client) RequestChatHistory(chatJID string, oldestMsgID string, oldestMsgFromMe bool, oldestMsgTimestamp int64, count int) error {
+func (c *Client) RequestChatHistory(ctx context.Context, chatJID string, oldestMsgID string, oldestMsgFromMe bool, oldestMsgTimestamp int64, count int) error {
 	if !c.IsConnected() {
 		return fmt.Errorf("not connected to WhatsApp")
 	}
@@ -603,7 +843,10 @@ func (c *Client) RequestChatHistory(chatJID string, oldestMsgID string, oldestMs
 
 	// Send the request to the phone
 	// The response comes as events.HistorySync with type ON_DEMAND
-	_, err = c.Client.SendMessage(context.Background(), chat, msg, whatsmeow.SendRequestExtra{Peer: true})
+	err = retry.Do(ctx, historyPolicy, func() error {
+		_, err := c.Client.SendMessage(ctx, chat, msg, whatsmeow.SendRequestExtra{Peer: true})
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("failed to send history request: %v", err)
 	}
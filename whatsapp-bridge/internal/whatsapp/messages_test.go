@@ -4,6 +4,8 @@ import (
 	"os"
 	"strings"
 	"testing"
+
+	bridgeTypes "whatsapp-bridge/internal/types"
 )
 
 func TestValidateMediaPath(t *testing.T) {
@@ -35,19 +37,19 @@ func TestValidateMediaPath(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := validateMediaPath(tt.path)
+			err := ValidateMediaPath(tt.path)
 
 			if tt.wantErr {
 				if err == nil {
-					t.Errorf("validateMediaPath(%s) = nil, want error containing %q", tt.path, tt.errContains)
+					t.Errorf("ValidateMediaPath(%s) = nil, want error containing %q", tt.path, tt.errContains)
 					return
 				}
 				if tt.errContains != "" && !strings.Contains(strings.ToLower(err.Error()), strings.ToLower(tt.errContains)) {
-					t.Errorf("validateMediaPath(%s) error = %v, want error containing %q", tt.path, err, tt.errContains)
+					t.Errorf("ValidateMediaPath(%s) error = %v, want error containing %q", tt.path, err, tt.errContains)
 				}
 			} else {
 				if err != nil {
-					t.Errorf("validateMediaPath(%s) = %v, want nil", tt.path, err)
+					t.Errorf("ValidateMediaPath(%s) = %v, want nil", tt.path, err)
 				}
 			}
 		})
@@ -64,9 +66,9 @@ func TestValidateMediaPath_DisableCheck(t *testing.T) {
 
 	// Should now allow paths outside allowed directories
 	// Note: Path traversal attempts still blocked
-	err := validateMediaPath("/home/user/file.txt")
+	err := ValidateMediaPath("/home/user/file.txt")
 	if err != nil {
-		t.Errorf("With DISABLE_PATH_CHECK=true, validateMediaPath should allow external paths, got: %v", err)
+		t.Errorf("With DISABLE_PATH_CHECK=true, ValidateMediaPath should allow external paths, got: %v", err)
 	}
 }
 
@@ -78,8 +80,54 @@ func TestValidateMediaPath_TraversalAlwaysBlocked(t *testing.T) {
 	// Even with DISABLE_PATH_CHECK=true, path traversal should be blocked
 	os.Setenv("DISABLE_PATH_CHECK", "true")
 
-	err := validateMediaPath("/app/media/../../../etc/passwd")
+	err := ValidateMediaPath("/app/media/../../../etc/passwd")
 	if err == nil {
 		t.Error("Path traversal should be blocked even with DISABLE_PATH_CHECK=true")
 	}
 }
+
+func TestParticipantErrorReason(t *testing.T) {
+	tests := []struct {
+		code int
+		want string
+	}{
+		{0, ""},
+		{403, "privacy_restricted"},
+		{408, "recently_left"},
+		{409, "already_member"},
+		{999, "unknown"},
+	}
+
+	for _, tt := range tests {
+		if got := participantErrorReason(tt.code); got != tt.want {
+			t.Errorf("participantErrorReason(%d) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestNewCreateGroupRequest(t *testing.T) {
+	create, err := newCreateGroupRequest(bridgeTypes.CreateGroupRequest{
+		Name:             "Launch crew",
+		Participants:     []string{"15550000001@s.whatsapp.net"},
+		Disappearing:     "7d",
+		AnnounceOnly:     true,
+		ApprovalRequired: true,
+	})
+	if err != nil {
+		t.Fatalf("newCreateGroupRequest: %v", err)
+	}
+	if create.Name != "Launch crew" || len(create.Participants) != 1 || create.Participants[0].User != "15550000001" {
+		t.Errorf("request = %+v", create)
+	}
+	if !create.IsEphemeral || create.DisappearingTimer != 7*24*60*60 || !create.IsAnnounce || !create.IsJoinApprovalRequired {
+		t.Errorf("settings = ephemeral %v timer %d announce %v approval %v, want all applied at creation",
+			create.IsEphemeral, create.DisappearingTimer, create.IsAnnounce, create.IsJoinApprovalRequired)
+	}
+
+	if create, err := newCreateGroupRequest(bridgeTypes.CreateGroupRequest{Name: "Plain", Disappearing: "off"}); err != nil || create.IsEphemeral || create.DisappearingTimer != 0 {
+		t.Errorf("timer off = %+v, %v", create, err)
+	}
+	if _, err := newCreateGroupRequest(bridgeTypes.CreateGroupRequest{Name: "Odd", Disappearing: "3d"}); err == nil {
+		t.Error("unsupported timer accepted")
+	}
+}
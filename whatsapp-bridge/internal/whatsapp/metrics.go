@@ -0,0 +1,41 @@
+package whatsapp
+
+import (
+	"go.mau.fi/whatsmeow/types/events"
+
+	"whatsapp-bridge/internal/metrics"
+	"whatsapp-bridge/internal/msgrate"
+)
+
+// Message and connection counters, served at /api/metrics and pushed to
+// StatsD when configured
+var (
+	messagesReceived = metrics.NewCounter("bridge_messages_total", "Messages received and sent", "direction", "received")
+	messagesSent     = metrics.NewCounter("bridge_messages_total", "Messages received and sent", "direction", "sent")
+	reconnects       = metrics.NewCounter("bridge_reconnects_total", "Connections to WhatsApp re-established after a disconnect")
+
+	// Connection quality, see quality.go
+	pingRTT      = metrics.NewGauge("bridge_whatsapp_ping_rtt_seconds", "Round trip of the latest ping to WhatsApp")
+	pingFailures = metrics.NewCounter("bridge_whatsapp_ping_failures_total", "Pings to WhatsApp that got no answer")
+	eventLag     = metrics.NewGauge("bridge_event_lag_seconds", "Longest an event took to handle in the latest sample period")
+	lagAlerts    = metrics.NewCounter("bridge_event_lag_alerts_total", "Times event handling lag rose above the alert threshold")
+)
+
+// SetRateTracker makes incoming messages count towards per-chat and
+// per-sender message rates. Call before connecting.
+func (c *Client) SetRateTracker(t *msgrate.Tracker) {
+	c.rates = t
+}
+
+// countMessage counts a handled message in the metrics and, if it came
+// from someone else, in the message rates
+func (c *Client) countMessage(msg *events.Message) {
+	if msg.Info.IsFromMe {
+		messagesSent.Inc()
+		return
+	}
+	messagesReceived.Inc()
+	if c.rates != nil {
+		c.rates.Record(msg.Info.Chat.ToNonAD().String(), msg.Info.Sender.ToNonAD().String(), msg.Info.Timestamp)
+	}
+}
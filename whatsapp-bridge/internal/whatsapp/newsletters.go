@@ -0,0 +1,61 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+
+	"go.mau.fi/whatsmeow/types"
+
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+// defaultNewsletterSettings applies to newsletters without stored settings.
+var defaultNewsletterSettings = localTypes.NewsletterSettings{Persist: true, Webhooks: true}
+
+// NewsletterSettings returns a copy of all per-newsletter settings keyed by JID.
+func (c *Client) NewsletterSettings() map[string]localTypes.NewsletterSettings {
+	c.newsletterMu.RLock()
+	defer c.newsletterMu.RUnlock()
+
+	settings := make(map[string]localTypes.NewsletterSettings, len(c.newsletterSettings))
+	for jid, s := range c.newsletterSettings {
+		settings[jid] = s
+	}
+	return settings
+}
+
+// SetNewsletterSettings replaces all per-newsletter settings.
+func (c *Client) SetNewsletterSettings(settings map[string]localTypes.NewsletterSettings) {
+	c.newsletterMu.Lock()
+	defer c.newsletterMu.Unlock()
+	c.newsletterSettings = settings
+}
+
+// NewsletterSettingsFor returns the settings for a newsletter, or the defaults
+// (persisted and webhooked) when none are stored.
+func (c *Client) NewsletterSettingsFor(jid string) localTypes.NewsletterSettings {
+	c.newsletterMu.RLock()
+	defer c.newsletterMu.RUnlock()
+
+	if s, ok := c.newsletterSettings[jid]; ok {
+		return s
+	}
+	return defaultNewsletterSettings
+}
+
+// MuteNewsletterChannel mutes or unmutes notifications for a followed newsletter.
+func (c *Client) MuteNewsletterChannel(ctx context.Context, jidStr string, mute bool) error {
+	if !c.IsConnected() {
+		return fmt.Errorf("not connected to WhatsApp")
+	}
+
+	jid, err := types.ParseJID(jidStr)
+	if err != nil {
+		return fmt.Errorf("invalid JID: %v", err)
+	}
+	if jid.Server != types.NewsletterServer {
+		return fmt.Errorf("not a newsletter JID: %s", jidStr)
+	}
+
+	return c.NewsletterToggleMute(ctx, jid, mute)
+}
@@ -0,0 +1,40 @@
+package whatsapp
+
+import (
+	"testing"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestNewsletterMessageStats(t *testing.T) {
+	channel := types.NewJID("120363000000000001", types.NewsletterServer)
+	msgs := []*types.NewsletterMessage{
+		{MessageServerID: 41, MessageID: "OLDER"},
+		{
+			MessageServerID: 42,
+			MessageID:       "POST",
+			Type:            "text",
+			ViewsCount:      1200,
+			ReactionCounts:  map[string]int{"👍": 30, "❤️": 12},
+			Message:         &waE2E.Message{Conversation: proto.String("Launch day")},
+		},
+	}
+
+	stats := newsletterMessageStats(channel, msgs, 42)
+	if stats == nil {
+		t.Fatal("post 42 not found")
+	}
+	if stats.MessageID != "POST" || stats.JID != channel.String() || stats.Content != "Launch day" || stats.ViewsCount != 1200 {
+		t.Errorf("stats = %+v", stats)
+	}
+	if stats.TotalReactions != 42 || stats.ReactionCounts["👍"] != 30 {
+		t.Errorf("reactions = %v, total %d; want 42 in all", stats.ReactionCounts, stats.TotalReactions)
+	}
+
+	// The page before a missing post holds an older one, which is not it
+	if stats := newsletterMessageStats(channel, msgs[:1], 42); stats != nil {
+		t.Errorf("stats for a missing post = %+v", stats)
+	}
+}
@@ -0,0 +1,168 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+
+	"whatsapp-bridge/internal/redact"
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+const (
+	// PairingCodeLifetime is how long WhatsApp accepts a phone pairing code
+	PairingCodeLifetime = 160 * time.Second
+
+	// MaxPairingCodes caps the codes issued in one pairing session, the
+	// first one included, so an abandoned session does not renew forever
+	MaxPairingCodes = 5
+)
+
+// ErrNoPairing is returned when cancelling while no phone pairing is in progress
+var ErrNoPairing = errors.New("no pairing in progress")
+
+// SetPairingCodeHook registers fn to be called with every phone pairing code
+// issued, including automatic renewals. It is called without pairing state
+// locked.
+func (c *Client) SetPairingCodeHook(fn func(code localTypes.PairingCode)) {
+	c.pairingMutex.Lock()
+	defer c.pairingMutex.Unlock()
+	c.pairingCodeHook = fn
+}
+
+// CancelPairing ends the phone pairing session: the current code is
+// discarded and no longer renewed. WhatsApp cannot be told to revoke a code,
+// but one already shown expires within PairingCodeLifetime.
+func (c *Client) CancelPairing() error {
+	c.pairingMutex.Lock()
+	defer c.pairingMutex.Unlock()
+
+	if !c.pairingInProgress {
+		return ErrNoPairing
+	}
+	c.pairingInProgress = false
+	c.stopPairingRenewalLocked()
+	c.pairingCode = ""
+	c.pairingExpiry = time.Time{}
+
+	c.logger.Infof("Phone pairing cancelled")
+	c.publishPairingEventLocked(localTypes.PairingEvent{Event: "cancelled"})
+	return nil
+}
+
+// errPairingSuperseded is returned for a code that arrived after its
+// session was cancelled, completed or given a newer code
+var errPairingSuperseded = errors.New("pairing ended while the code was requested")
+
+// issuePairingCode requests a code for the session's phone number and, if
+// the session is still at generation gen, records it and schedules its
+// renewal. The request goes over the network, so it is made without
+// c.pairingMutex held, which must not be held by the caller either.
+func (c *Client) issuePairingCode(ctx context.Context, gen int) (localTypes.PairingCode, error) {
+	c.pairingMutex.Lock()
+	request, phone := c.requestPairCode, c.pairingPhone
+	c.pairingMutex.Unlock()
+	if request == nil {
+		request = c.pairPhone
+	}
+	code, err := request(ctx, phone)
+
+	c.pairingMutex.Lock()
+	defer c.pairingMutex.Unlock()
+	if gen != c.pairingGen || !c.pairingInProgress {
+		return localTypes.PairingCode{}, errPairingSuperseded
+	}
+	if err != nil {
+		return localTypes.PairingCode{}, err
+	}
+
+	c.pairingAttempt++
+	c.pairingCode = code
+	c.pairingExpiry = time.Now().Add(PairingCodeLifetime)
+	redact.Register(code)
+
+	c.stopPairingRenewalLocked()
+	gen = c.pairingGen
+	c.pairingTimer = time.AfterFunc(PairingCodeLifetime, func() { c.renewPairingCode(gen) })
+
+	expiresIn := int(PairingCodeLifetime.Seconds())
+	c.logger.Infof("Pairing code %d of %d generated: %s (expires in %ds)", c.pairingAttempt, MaxPairingCodes, code, expiresIn)
+	c.publishPairingEventLocked(localTypes.PairingEvent{Event: "pair_code", PairCode: code, ExpiresIn: expiresIn, Attempt: c.pairingAttempt})
+
+	return localTypes.PairingCode{
+		Code:        code,
+		PhoneNumber: c.pairingPhone,
+		ExpiresAt:   c.pairingExpiry.UTC(),
+		Attempt:     c.pairingAttempt,
+	}, nil
+}
+
+// pairPhone connects if needed and asks WhatsApp for a pairing code
+func (c *Client) pairPhone(ctx context.Context, phoneNumber string) (string, error) {
+	if !c.IsConnected() {
+		if err := c.Client.Connect(); err != nil {
+			return "", fmt.Errorf("failed to connect: %v", err)
+		}
+	}
+
+	code, err := c.Client.PairPhone(ctx, phoneNumber, true, whatsmeow.PairClientChrome, "Chrome (Linux)")
+	if err != nil {
+		return "", fmt.Errorf("failed to request pairing code: %v", err)
+	}
+	return code, nil
+}
+
+// renewPairingCode replaces an expired code while the session is still
+// waiting for the user. gen identifies the code whose timer fired; a timer
+// for a code that has since been replaced, cancelled or used does nothing.
+func (c *Client) renewPairingCode(gen int) {
+	c.pairingMutex.Lock()
+	if gen != c.pairingGen || !c.pairingInProgress {
+		c.pairingMutex.Unlock()
+		return
+	}
+
+	if c.pairingAttempt >= MaxPairingCodes {
+		c.pairingMutex.Unlock()
+		c.HandlePairingError(fmt.Errorf("pairing code expired %d times without being entered", MaxPairingCodes))
+		return
+	}
+
+	c.pairingMutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancel()
+	code, err := c.issuePairingCode(ctx, gen)
+	if errors.Is(err, errPairingSuperseded) {
+		return
+	}
+	if err != nil {
+		c.HandlePairingError(fmt.Errorf("failed to renew expired pairing code: %w", err))
+		return
+	}
+	c.notifyPairingCode(code)
+}
+
+// stopPairingRenewalLocked cancels the pending renewal and invalidates its
+// timer if it is already running. c.pairingMutex must be held.
+func (c *Client) stopPairingRenewalLocked() {
+	if c.pairingTimer != nil {
+		c.pairingTimer.Stop()
+		c.pairingTimer = nil
+	}
+	c.pairingGen++
+}
+
+// notifyPairingCode passes a newly issued code to the pairing code hook
+func (c *Client) notifyPairingCode(code localTypes.PairingCode) {
+	c.pairingMutex.Lock()
+	hook := c.pairingCodeHook
+	c.pairingMutex.Unlock()
+
+	if hook != nil {
+		hook(code)
+	}
+}
@@ -0,0 +1,161 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	waLog "go.mau.fi/whatsmeow/util/log"
+
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+func newPairingClient(t *testing.T) (*Client, *[]localTypes.PairingCode) {
+	t.Helper()
+	c := &Client{logger: waLog.Noop}
+
+	requests := 0
+	c.requestPairCode = func(ctx context.Context, phoneNumber string) (string, error) {
+		requests++
+		return fmt.Sprintf("CODE-%04d", requests), nil
+	}
+
+	var issued []localTypes.PairingCode
+	c.SetPairingCodeHook(func(code localTypes.PairingCode) { issued = append(issued, code) })
+
+	// Start a session as PairWithPhone does
+	c.pairingMutex.Lock()
+	c.pairingInProgress = true
+	c.pairingPhone = "15551234567"
+	gen := c.pairingGen
+	c.pairingMutex.Unlock()
+	code, err := c.issuePairingCode(context.Background(), gen)
+	if err != nil {
+		t.Fatalf("issuePairingCode() error = %v", err)
+	}
+	c.notifyPairingCode(code)
+	t.Cleanup(func() { _ = c.CancelPairing() })
+
+	return c, &issued
+}
+
+func TestRenewPairingCode(t *testing.T) {
+	c, issued := newPairingClient(t)
+
+	// A timer left over from an earlier code does nothing
+	c.renewPairingCode(c.pairingGen - 1)
+	if len(*issued) != 1 {
+		t.Fatalf("stale timer issued a code: %+v", *issued)
+	}
+
+	c.renewPairingCode(c.pairingGen)
+	if len(*issued) != 2 {
+		t.Fatalf("issued %d codes, want 2", len(*issued))
+	}
+	renewed := (*issued)[1]
+	if renewed.Code != "CODE-0002" || renewed.Attempt != 2 || renewed.PhoneNumber != "15551234567" || renewed.ExpiresAt.IsZero() {
+		t.Errorf("renewed code = %+v", renewed)
+	}
+	if inProgress, code, _, _, _ := c.GetPairingStatus(); !inProgress || code != "CODE-0002" {
+		t.Errorf("status = %v %q, want the renewed code in progress", inProgress, code)
+	}
+}
+
+func TestRenewPairingCodeGivesUp(t *testing.T) {
+	c, issued := newPairingClient(t)
+
+	for i := 1; i < MaxPairingCodes; i++ {
+		c.renewPairingCode(c.pairingGen)
+	}
+	if len(*issued) != MaxPairingCodes {
+		t.Fatalf("issued %d codes, want %d", len(*issued), MaxPairingCodes)
+	}
+
+	c.renewPairingCode(c.pairingGen)
+	inProgress, _, _, _, err := c.GetPairingStatus()
+	if inProgress || err == nil {
+		t.Errorf("after %d codes: in progress %v, error %v; want the session ended with an error", MaxPairingCodes, inProgress, err)
+	}
+	if len(*issued) != MaxPairingCodes {
+		t.Errorf("issued %d codes, want no more than %d", len(*issued), MaxPairingCodes)
+	}
+}
+
+func TestCancelPairing(t *testing.T) {
+	c, issued := newPairingClient(t)
+	events, unsubscribe := c.SubscribePairing()
+	defer unsubscribe()
+
+	gen := c.pairingGen
+	if err := c.CancelPairing(); err != nil {
+		t.Fatalf("CancelPairing() error = %v", err)
+	}
+	if evt := <-events; evt.Event != "cancelled" {
+		t.Errorf("published %+v, want a cancelled event", evt)
+	}
+	if inProgress, code, _, _, _ := c.GetPairingStatus(); inProgress || code != "" {
+		t.Errorf("status = %v %q after cancel", inProgress, code)
+	}
+
+	// The expired code's timer no longer renews it
+	c.renewPairingCode(gen)
+	if len(*issued) != 1 {
+		t.Errorf("issued %d codes after cancel, want 1", len(*issued))
+	}
+
+	if err := c.CancelPairing(); !errors.Is(err, ErrNoPairing) {
+		t.Errorf("second CancelPairing() error = %v, want ErrNoPairing", err)
+	}
+}
+
+func TestCancelPairingDuringRequest(t *testing.T) {
+	c, issued := newPairingClient(t)
+
+	// The request runs without pairing state locked, so a cancel made
+	// while it is outstanding neither waits for it nor is undone by it
+	c.requestPairCode = func(ctx context.Context, phoneNumber string) (string, error) {
+		if err := c.CancelPairing(); err != nil {
+			t.Errorf("CancelPairing() during the request: %v", err)
+		}
+		return "CODE-LATE", nil
+	}
+	c.renewPairingCode(c.pairingGen)
+
+	if len(*issued) != 1 {
+		t.Errorf("issued %d codes, want the late one discarded", len(*issued))
+	}
+	if inProgress, code, _, _, err := c.GetPairingStatus(); inProgress || code != "" || err != nil {
+		t.Errorf("status = %v %q %v, want cancelled", inProgress, code, err)
+	}
+}
+
+func TestPairingQRCode(t *testing.T) {
+	c := &Client{logger: waLog.Noop}
+	events, unsubscribe := c.SubscribePairing()
+
+	c.setQRCode("2@qr-payload", 60*time.Second)
+	if evt := <-events; evt.Event != "code" || evt.ExpiresIn != 60 {
+		t.Errorf("published %+v, want a code event expiring in 60s", evt)
+	}
+	if code, expiresIn := c.GetQRCode(); code != "2@qr-payload" || expiresIn <= 0 || expiresIn > 60 {
+		t.Errorf("GetQRCode() = %q, %d", code, expiresIn)
+	}
+
+	// Clearing the code is not an event of its own
+	c.setQRCode("", 0)
+	if code, _ := c.GetQRCode(); code != "" {
+		t.Errorf("GetQRCode() = %q after it was cleared", code)
+	}
+	c.HandlePairingError(errors.New("scan timed out"))
+	if evt := <-events; evt.Event != "error" || evt.Error != "scan timed out" {
+		t.Errorf("published %+v, want the pairing error", evt)
+	}
+
+	unsubscribe()
+	if _, open := <-events; open {
+		t.Error("subscription still open after unsubscribing")
+	}
+	unsubscribe() // a second call is harmless
+}
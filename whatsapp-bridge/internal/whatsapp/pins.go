@@ -0,0 +1,166 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+
+	"whatsapp-bridge/internal/database"
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+// DefaultPinDuration is how long a message stays pinned when no duration is
+// given, as in the app
+const DefaultPinDuration = 7 * 24 * time.Hour
+
+// pinDurations are the pin durations WhatsApp offers
+var pinDurations = map[string]time.Duration{
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+	"30d": 30 * 24 * time.Hour,
+}
+
+// ParsePinDuration reads a pin duration: "24h", "7d" or "30d". Empty means
+// DefaultPinDuration.
+func ParsePinDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return DefaultPinDuration, nil
+	}
+	d, ok := pinDurations[s]
+	if !ok {
+		return 0, fmt.Errorf("invalid duration: %s (must be '24h', '7d' or '30d')", s)
+	}
+	return d, nil
+}
+
+// PinMessage pins a message in a chat for everyone in it and returns the
+// pin to record. senderJID is the message's author; empty means one of our
+// own messages.
+func (c *Client) PinMessage(ctx context.Context, chatJID, messageID, senderJID string, duration time.Duration) (*localTypes.PinnedMessage, error) {
+	return c.sendPin(ctx, chatJID, messageID, senderJID, waE2E.PinInChatMessage_PIN_FOR_ALL, duration)
+}
+
+// UnpinMessage unpins a message in a chat for everyone in it
+func (c *Client) UnpinMessage(ctx context.Context, chatJID, messageID, senderJID string) error {
+	_, err := c.sendPin(ctx, chatJID, messageID, senderJID, waE2E.PinInChatMessage_UNPIN_FOR_ALL, 0)
+	return err
+}
+
+func (c *Client) sendPin(ctx context.Context, chatJID, messageID, senderJID string, pinType waE2E.PinInChatMessage_Type, duration time.Duration) (*localTypes.PinnedMessage, error) {
+	if !c.IsConnected() {
+		return nil, fmt.Errorf("not connected to WhatsApp")
+	}
+
+	chat, err := types.ParseJID(chatJID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chat JID: %v", err)
+	}
+
+	own := c.Store.ID.ToNonAD()
+	sender := own
+	if senderJID != "" {
+		sender, err = types.ParseJID(senderJID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sender JID: %v", err)
+		}
+	}
+
+	now := time.Now()
+	msg := &waE2E.Message{
+		PinInChatMessage: &waE2E.PinInChatMessage{
+			Key:               c.Client.BuildMessageKey(chat, sender, types.MessageID(messageID)),
+			Type:              pinType.Enum(),
+			SenderTimestampMS: proto.Int64(now.UnixMilli()),
+		},
+	}
+	if pinType == waE2E.PinInChatMessage_PIN_FOR_ALL {
+		msg.MessageContextInfo = &waE2E.MessageContextInfo{
+			MessageAddOnDurationInSecs: proto.Uint32(uint32(duration.Seconds())),
+		}
+	}
+
+	if _, err := c.Client.SendMessage(ctx, chat, msg); err != nil {
+		return nil, fmt.Errorf("failed to send pin: %v", err)
+	}
+
+	return &localTypes.PinnedMessage{
+		ChatJID:   chat.ToNonAD().String(),
+		MessageID: messageID,
+		SenderJID: sender.ToNonAD().String(),
+		PinnedBy:  own.String(),
+		PinnedAt:  now.UTC(),
+		ExpiresAt: now.Add(duration).UTC(),
+	}, nil
+}
+
+// messagePin reads a pin or unpin made in a chat. It returns nil for other
+// messages.
+func (c *Client) messagePin(msg *events.Message) (pin *localTypes.PinnedMessage, unpinned bool) {
+	p := msg.Message.GetPinInChatMessage()
+	if p == nil || p.GetKey().GetID() == "" {
+		return nil, false
+	}
+
+	chat := msg.Info.Chat.ToNonAD()
+	pin = &localTypes.PinnedMessage{
+		ChatJID:   chat.String(),
+		MessageID: p.GetKey().GetID(),
+		SenderJID: p.GetKey().GetParticipant(),
+		PinnedBy:  msg.Info.Sender.ToNonAD().String(),
+		PinnedAt:  msg.Info.Timestamp.UTC(),
+	}
+	if ms := p.GetSenderTimestampMS(); ms > 0 {
+		pin.PinnedAt = time.UnixMilli(ms).UTC()
+	}
+
+	// In direct chats the key only says whether the pinner wrote the message;
+	// if not, the author is the other side of the chat
+	if pin.SenderJID == "" && !msg.Info.IsGroup {
+		switch {
+		case p.GetKey().GetFromMe():
+			pin.SenderJID = pin.PinnedBy
+		case msg.Info.IsFromMe:
+			pin.SenderJID = chat.String()
+		case c.Store != nil && c.Store.ID != nil:
+			pin.SenderJID = c.Store.ID.ToNonAD().String()
+		}
+	}
+
+	duration := time.Duration(msg.Message.GetMessageContextInfo().GetMessageAddOnDurationInSecs()) * time.Second
+	if duration == 0 {
+		duration = DefaultPinDuration
+	}
+	pin.ExpiresAt = pin.PinnedAt.Add(duration)
+
+	return pin, p.GetType() == waE2E.PinInChatMessage_UNPIN_FOR_ALL
+}
+
+// storeMessagePin records or removes a pin made in a chat and raises
+// message_pinned or message_unpinned
+func (c *Client) storeMessagePin(messageStore *database.MessageStore, webhookManager interface{}, pin *localTypes.PinnedMessage, unpinned, persist, deliver bool) {
+	if persist {
+		var err error
+		if unpinned {
+			_, err = messageStore.DeletePinnedMessage(pin.ChatJID, pin.MessageID)
+		} else {
+			err = messageStore.StorePinnedMessage(pin)
+		}
+		if err != nil {
+			c.logger.Warnf("Failed to update pin of %s in %s: %v", pin.MessageID, pin.ChatJID, err)
+		}
+	}
+
+	if webhookManager == nil || !deliver {
+		return
+	}
+	if wm, ok := webhookManager.(interface {
+		ProcessMessagePin(pin *localTypes.PinnedMessage, unpinned bool)
+	}); ok {
+		wm.ProcessMessagePin(pin, unpinned)
+	}
+}
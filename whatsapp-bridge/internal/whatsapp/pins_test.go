@@ -0,0 +1,75 @@
+package whatsapp
+
+import (
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/proto/waCommon"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+func pinEvent(chat, sender types.JID, isFromMe bool, key *waCommon.MessageKey, pinType waE2E.PinInChatMessage_Type, seconds uint32) *events.Message {
+	return &events.Message{
+		Info: types.MessageInfo{
+			MessageSource: types.MessageSource{Chat: chat, Sender: sender, IsFromMe: isFromMe, IsGroup: chat.Server == types.GroupServer},
+			Timestamp:     time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC),
+		},
+		Message: &waE2E.Message{
+			PinInChatMessage:   &waE2E.PinInChatMessage{Key: key, Type: pinType.Enum()},
+			MessageContextInfo: &waE2E.MessageContextInfo{MessageAddOnDurationInSecs: proto.Uint32(seconds)},
+		},
+	}
+}
+
+func TestMessagePin(t *testing.T) {
+	c := &Client{}
+	group := types.NewJID("120363000000000000", types.GroupServer)
+	ana := types.NewJID("15550000001", types.DefaultUserServer)
+	ben := types.NewJID("15550000002", types.DefaultUserServer)
+
+	// Ben pins Ana's message in a group for a day
+	key := &waCommon.MessageKey{ID: proto.String("M1"), Participant: proto.String(ana.String())}
+	pin, unpinned := c.messagePin(pinEvent(group, ben, false, key, waE2E.PinInChatMessage_PIN_FOR_ALL, 86400))
+	if pin == nil || unpinned {
+		t.Fatalf("messagePin = %+v, %v, want a pin", pin, unpinned)
+	}
+	if pin.ChatJID != group.String() || pin.MessageID != "M1" || pin.SenderJID != ana.String() || pin.PinnedBy != ben.String() {
+		t.Errorf("pin = %+v", pin)
+	}
+	if got := pin.ExpiresAt.Sub(pin.PinnedAt); got != 24*time.Hour {
+		t.Errorf("pin lasts %v, want 24h", got)
+	}
+
+	// In a direct chat Ana unpins a message she wrote herself
+	key = &waCommon.MessageKey{ID: proto.String("M2"), FromMe: proto.Bool(true)}
+	pin, unpinned = c.messagePin(pinEvent(ana, ana, false, key, waE2E.PinInChatMessage_UNPIN_FOR_ALL, 0))
+	if pin == nil || !unpinned || pin.SenderJID != ana.String() {
+		t.Errorf("direct unpin = %+v, %v, want Ana's message unpinned", pin, unpinned)
+	}
+
+	// We pin Ana's message from the phone; no duration means the default
+	key = &waCommon.MessageKey{ID: proto.String("M3"), FromMe: proto.Bool(false)}
+	pin, _ = c.messagePin(pinEvent(ana, ben, true, key, waE2E.PinInChatMessage_PIN_FOR_ALL, 0))
+	if pin == nil || pin.SenderJID != ana.String() || pin.ExpiresAt.Sub(pin.PinnedAt) != DefaultPinDuration {
+		t.Errorf("own pin = %+v, want Ana's message pinned for the default duration", pin)
+	}
+
+	if pin, _ := c.messagePin(&events.Message{Message: &waE2E.Message{Conversation: proto.String("hi")}}); pin != nil {
+		t.Errorf("text message read as pin: %+v", pin)
+	}
+}
+
+func TestParsePinDuration(t *testing.T) {
+	if d, err := ParsePinDuration(""); err != nil || d != DefaultPinDuration {
+		t.Errorf("ParsePinDuration(\"\") = %v, %v", d, err)
+	}
+	if d, err := ParsePinDuration("30d"); err != nil || d != 30*24*time.Hour {
+		t.Errorf("ParsePinDuration(30d) = %v, %v", d, err)
+	}
+	if _, err := ParsePinDuration("1h"); err == nil {
+		t.Error("ParsePinDuration(1h) accepted a duration WhatsApp does not offer")
+	}
+}
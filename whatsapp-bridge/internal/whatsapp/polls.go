@@ -0,0 +1,190 @@
+package whatsapp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+
+	"whatsapp-bridge/internal/database"
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+// pollCreation reads a poll created in a chat, or returns nil for other
+// messages
+func pollCreation(msg *events.Message) *localTypes.Poll {
+	var pc *waE2E.PollCreationMessage
+	for _, candidate := range []*waE2E.PollCreationMessage{
+		msg.Message.GetPollCreationMessage(),
+		msg.Message.GetPollCreationMessageV2(),
+		msg.Message.GetPollCreationMessageV3(),
+		msg.Message.GetPollCreationMessageV5(),
+	} {
+		if candidate != nil {
+			pc = candidate
+			break
+		}
+	}
+	if pc == nil || len(pc.GetOptions()) == 0 {
+		return nil
+	}
+
+	options := make([]string, len(pc.GetOptions()))
+	for i, option := range pc.GetOptions() {
+		options[i] = option.GetOptionName()
+	}
+	return &localTypes.Poll{
+		ChatJID:         msg.Info.Chat.ToNonAD().String(),
+		MessageID:       msg.Info.ID,
+		CreatorJID:      msg.Info.Sender.ToNonAD().String(),
+		Question:        pc.GetName(),
+		Options:         options,
+		SelectableCount: int(pc.GetSelectableOptionsCount()),
+		CreatedAt:       msg.Info.Timestamp.UTC(),
+	}
+}
+
+// pollVoteOptions names the options a vote selects. Votes carry only the
+// SHA-256 of each option's name; hashes matching none of the poll's options
+// are dropped.
+func pollVoteOptions(options []string, selected [][]byte) []string {
+	hashes := whatsmeow.HashPollOptions(options)
+	names := []string{}
+	for _, hash := range selected {
+		for i, optionHash := range hashes {
+			if bytes.Equal(hash, optionHash) {
+				names = append(names, options[i])
+				break
+			}
+		}
+	}
+	return names
+}
+
+// storePoll records a poll so votes for it can be counted
+func (c *Client) storePoll(messageStore *database.MessageStore, poll *localTypes.Poll) {
+	if err := messageStore.StorePoll(poll); err != nil {
+		c.logger.Warnf("Failed to store poll %s in %s: %v", poll.MessageID, poll.ChatJID, err)
+	}
+}
+
+// storePollVote decrypts a vote and records it against its poll. Votes for
+// polls created before the bridge saw them cannot be matched to options and
+// are skipped.
+func (c *Client) storePollVote(messageStore *database.MessageStore, msg *events.Message) {
+	update := msg.Message.GetPollUpdateMessage()
+	chatJID := msg.Info.Chat.ToNonAD().String()
+	pollID := update.GetPollCreationMessageKey().GetID()
+	if c.Client == nil || pollID == "" {
+		return
+	}
+
+	poll, err := messageStore.GetPoll(chatJID, pollID)
+	if err != nil {
+		c.logger.Warnf("Failed to look up poll %s in %s: %v", pollID, chatJID, err)
+		return
+	}
+	if poll == nil {
+		c.logger.Debugf("Skipping vote for unknown poll %s in %s", pollID, chatJID)
+		return
+	}
+
+	vote, err := c.Client.DecryptPollVote(context.Background(), msg)
+	if err != nil {
+		c.logger.Warnf("Failed to decrypt vote %s for poll %s: %v", msg.Info.ID, pollID, err)
+		return
+	}
+
+	votedAt := msg.Info.Timestamp.UTC()
+	if ms := update.GetSenderTimestampMS(); ms > 0 {
+		votedAt = time.UnixMilli(ms).UTC()
+	}
+	err = messageStore.StorePollVote(&localTypes.PollVote{
+		ChatJID:  chatJID,
+		PollID:   pollID,
+		VoterJID: msg.Info.Sender.ToNonAD().String(),
+		Options:  pollVoteOptions(poll.Options, vote.GetSelectedOptions()),
+		VotedAt:  votedAt,
+	})
+	if err != nil {
+		c.logger.Warnf("Failed to store vote %s for poll %s: %v", msg.Info.ID, pollID, err)
+	}
+}
+
+// PollSelection names the options a vote picks by index, checking them
+// against the poll: each index must be one of its options, picked once, and
+// no more may be picked than the poll allows. No indices take a vote back.
+func PollSelection(poll *localTypes.Poll, indices []int) ([]string, error) {
+	if poll.SelectableCount > 0 && len(indices) > poll.SelectableCount {
+		return nil, fmt.Errorf("this poll allows at most %d option(s)", poll.SelectableCount)
+	}
+	picked := make(map[int]bool, len(indices))
+	names := make([]string, 0, len(indices))
+	for _, i := range indices {
+		if i < 0 || i >= len(poll.Options) {
+			return nil, fmt.Errorf("option %d does not exist; the poll has options 0 to %d", i, len(poll.Options)-1)
+		}
+		if picked[i] {
+			return nil, fmt.Errorf("option %d is picked twice", i)
+		}
+		picked[i] = true
+		names = append(names, poll.Options[i])
+	}
+	return names, nil
+}
+
+// VotePoll votes in a poll for the named options, replacing any earlier vote
+// of ours; no options take the vote back. The vote is encrypted with the
+// poll's secret, so only polls whose creation the bridge has seen can be
+// voted in. The vote is stored so the poll's results include it.
+func (c *Client) VotePoll(ctx context.Context, messageStore *database.MessageStore, poll *localTypes.Poll, options []string) (*localTypes.PollVote, error) {
+	if !c.IsConnected() {
+		return nil, fmt.Errorf("not connected to WhatsApp")
+	}
+
+	chat, err := types.ParseJID(poll.ChatJID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chat JID: %v", err)
+	}
+	creator, err := types.ParseJID(poll.CreatorJID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid poll creator JID: %v", err)
+	}
+
+	own := c.Store.ID.ToNonAD()
+	fromMe := creator.User == own.User || (!c.Store.LID.IsEmpty() && creator.User == c.Store.LID.User)
+	info := &types.MessageInfo{
+		MessageSource: types.MessageSource{
+			Chat:     chat,
+			Sender:   creator,
+			IsFromMe: fromMe,
+			IsGroup:  chat.Server == types.GroupServer,
+		},
+		ID: types.MessageID(poll.MessageID),
+	}
+
+	msg, err := c.Client.BuildPollVote(ctx, info, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build vote: %v", err)
+	}
+	if _, err := c.Client.SendMessage(ctx, chat, msg); err != nil {
+		return nil, fmt.Errorf("failed to send vote: %v", err)
+	}
+
+	vote := &localTypes.PollVote{
+		ChatJID:  poll.ChatJID,
+		PollID:   poll.MessageID,
+		VoterJID: own.String(),
+		Options:  options,
+		VotedAt:  time.UnixMilli(msg.GetPollUpdateMessage().GetSenderTimestampMS()).UTC(),
+	}
+	if err := messageStore.StorePollVote(vote); err != nil {
+		c.logger.Warnf("Failed to store our vote for poll %s: %v", poll.MessageID, err)
+	}
+	return vote, nil
+}
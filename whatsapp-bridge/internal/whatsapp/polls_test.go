@@ -0,0 +1,89 @@
+package whatsapp
+
+import (
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+func TestPollCreation(t *testing.T) {
+	group := types.NewJID("120363000000000000", types.GroupServer)
+	ana := types.NewJID("15550000001", types.DefaultUserServer)
+	sent := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	msg := &events.Message{
+		Info: types.MessageInfo{
+			MessageSource: types.MessageSource{Chat: group, Sender: ana, IsGroup: true},
+			ID:            "3EB0P1",
+			Timestamp:     sent,
+		},
+		Message: &waE2E.Message{PollCreationMessageV3: &waE2E.PollCreationMessage{
+			Name: proto.String("Lunch?"),
+			Options: []*waE2E.PollCreationMessage_Option{
+				{OptionName: proto.String("Pizza")},
+				{OptionName: proto.String("Sushi")},
+			},
+			SelectableOptionsCount: proto.Uint32(1),
+		}},
+	}
+
+	poll := pollCreation(msg)
+	if poll == nil {
+		t.Fatal("pollCreation = nil, want the poll")
+	}
+	if poll.ChatJID != group.String() || poll.MessageID != "3EB0P1" || poll.CreatorJID != ana.String() || poll.Question != "Lunch?" {
+		t.Errorf("poll = %+v", poll)
+	}
+	if len(poll.Options) != 2 || poll.Options[1] != "Sushi" || poll.SelectableCount != 1 || !poll.CreatedAt.Equal(sent) {
+		t.Errorf("poll = %+v", poll)
+	}
+
+	if poll := pollCreation(&events.Message{Message: &waE2E.Message{Conversation: proto.String("hi")}}); poll != nil {
+		t.Errorf("text message read as poll: %+v", poll)
+	}
+}
+
+func TestPollVoteOptions(t *testing.T) {
+	options := []string{"Pizza", "Sushi", "Tacos"}
+	hashes := whatsmeow.HashPollOptions([]string{"Tacos", "Burgers", "Pizza"})
+
+	got := pollVoteOptions(options, hashes)
+	if len(got) != 2 || got[0] != "Tacos" || got[1] != "Pizza" {
+		t.Errorf("pollVoteOptions = %v, want [Tacos Pizza]", got)
+	}
+
+	// An empty selection is a vote taken back
+	if got := pollVoteOptions(options, nil); got == nil || len(got) != 0 {
+		t.Errorf("empty vote = %#v, want an empty list", got)
+	}
+}
+
+func TestPollSelection(t *testing.T) {
+	poll := &localTypes.Poll{Options: []string{"Pizza", "Sushi", "Tacos"}, SelectableCount: 2}
+
+	got, err := PollSelection(poll, []int{2, 0})
+	if err != nil || len(got) != 2 || got[0] != "Tacos" || got[1] != "Pizza" {
+		t.Errorf("PollSelection = %v, %v", got, err)
+	}
+	if got, err := PollSelection(poll, nil); err != nil || len(got) != 0 {
+		t.Errorf("empty selection = %v, %v", got, err)
+	}
+	for _, bad := range [][]int{{3}, {-1}, {1, 1}, {0, 1, 2}} {
+		if _, err := PollSelection(poll, bad); err == nil {
+			t.Errorf("%v accepted", bad)
+		}
+	}
+
+	// 0 allows any number of options
+	poll.SelectableCount = 0
+	if _, err := PollSelection(poll, []int{0, 1, 2}); err != nil {
+		t.Errorf("multi-select: %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+// privacySetting is one writable privacy setting and the values WhatsApp
+// accepts for it
+type privacySetting struct {
+	name   types.PrivacySettingType
+	values []types.PrivacySetting
+}
+
+// privacySettings maps the keys used by GetPrivacySettings to the settings
+var privacySettings = map[string]privacySetting{
+	"group_add":     {types.PrivacySettingTypeGroupAdd, []types.PrivacySetting{types.PrivacySettingAll, types.PrivacySettingContacts, types.PrivacySettingContactBlacklist, types.PrivacySettingNone}},
+	"last_seen":     {types.PrivacySettingTypeLastSeen, []types.PrivacySetting{types.PrivacySettingAll, types.PrivacySettingContacts, types.PrivacySettingContactBlacklist, types.PrivacySettingNone}},
+	"status":        {types.PrivacySettingTypeStatus, []types.PrivacySetting{types.PrivacySettingAll, types.PrivacySettingContacts, types.PrivacySettingContactBlacklist, types.PrivacySettingNone}},
+	"profile":       {types.PrivacySettingTypeProfile, []types.PrivacySetting{types.PrivacySettingAll, types.PrivacySettingContacts, types.PrivacySettingContactBlacklist, types.PrivacySettingNone}},
+	"read_receipts": {types.PrivacySettingTypeReadReceipts, []types.PrivacySetting{types.PrivacySettingAll, types.PrivacySettingNone}},
+	"online":        {types.PrivacySettingTypeOnline, []types.PrivacySetting{types.PrivacySettingAll, types.PrivacySettingMatchLastSeen}},
+	"call_add":      {types.PrivacySettingTypeCallAdd, []types.PrivacySetting{types.PrivacySettingAll, types.PrivacySettingKnown}},
+}
+
+// ValidatePrivacySettings checks that every key names a writable privacy
+// setting and every value is one WhatsApp accepts for it.
+func ValidatePrivacySettings(changes map[string]string) error {
+	if len(changes) == 0 {
+		return fmt.Errorf("at least one privacy setting is required")
+	}
+	for key, value := range changes {
+		setting, ok := privacySettings[key]
+		if !ok {
+			return fmt.Errorf("unknown privacy setting: %s", key)
+		}
+		valid := false
+		allowed := make([]string, len(setting.values))
+		for i, v := range setting.values {
+			allowed[i] = string(v)
+			valid = valid || value == string(v)
+		}
+		if !valid {
+			return fmt.Errorf("invalid value %q for %s (must be one of: %s)", value, key, strings.Join(allowed, ", "))
+		}
+	}
+	return nil
+}
+
+// SetPrivacySettings applies privacy setting changes keyed as in
+// GetPrivacySettings, one at a time in key order, and returns the resulting
+// settings. Changes applied before a failure stay applied.
+func (c *Client) SetPrivacySettings(ctx context.Context, changes map[string]string) (map[string]string, error) {
+	if err := ValidatePrivacySettings(changes); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(changes))
+	for key := range changes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		setting := privacySettings[key]
+		if _, err := c.Client.SetPrivacySetting(ctx, setting.name, types.PrivacySetting(changes[key])); err != nil {
+			return nil, fmt.Errorf("failed to set %s: %v", key, err)
+		}
+	}
+
+	return c.GetPrivacySettings(ctx)
+}
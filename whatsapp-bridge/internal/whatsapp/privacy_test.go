@@ -0,0 +1,30 @@
+package whatsapp
+
+import "testing"
+
+func TestValidatePrivacySettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		changes map[string]string
+		wantErr bool
+	}{
+		{"lock down", map[string]string{"last_seen": "none", "profile": "contacts", "status": "contact_blacklist", "group_add": "contacts"}, false},
+		{"read receipts off", map[string]string{"read_receipts": "none"}, false},
+		{"online follows last seen", map[string]string{"online": "match_last_seen"}, false},
+		{"calls from known", map[string]string{"call_add": "known"}, false},
+		{"empty", map[string]string{}, true},
+		{"unknown setting", map[string]string{"about": "none"}, true},
+		{"read receipts for contacts", map[string]string{"read_receipts": "contacts"}, true},
+		{"online hidden", map[string]string{"online": "none"}, true},
+		{"blank value", map[string]string{"last_seen": ""}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePrivacySettings(tt.changes)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePrivacySettings(%v) error = %v, wantErr %v", tt.changes, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package whatsapp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // decoders for prepareProfilePhoto and link preview thumbnails
+	"image/jpeg"
+	_ "image/png"
+	"strings"
+	"unicode/utf8"
+
+	"go.mau.fi/whatsmeow/appstate"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// profilePhotoSize is the edge of the square JPEG uploaded as a profile
+// photo; larger images are scaled down to it
+const profilePhotoSize = 640
+
+// maxImagePixels caps the images decoded for profile photos and link
+// preview thumbnails, checked from the header before the pixels are read so
+// a small file cannot claim a huge canvas
+const maxImagePixels = 50_000_000
+
+// MaxPushNameLength is the longest display name WhatsApp accepts
+const MaxPushNameLength = 25
+
+// ErrUnsupportedImage is returned for profile photos that are not a JPEG, PNG
+// or GIF image
+var ErrUnsupportedImage = errors.New("unsupported image (use JPEG, PNG or GIF)")
+
+// SetProfilePhoto crops the image to a centred square, scales it down to
+// WhatsApp's profile photo size and makes it the account's profile photo.
+// Returns the new picture ID.
+func (c *Client) SetProfilePhoto(ctx context.Context, data []byte) (string, error) {
+	photo, err := prepareProfilePhoto(data)
+	if err != nil {
+		return "", err
+	}
+	// An empty target sets the account's own photo
+	return c.SetGroupPhoto(ctx, types.EmptyJID, photo)
+}
+
+// RemoveProfilePhoto removes the account's profile photo
+func (c *Client) RemoveProfilePhoto(ctx context.Context) error {
+	_, err := c.SetGroupPhoto(ctx, types.EmptyJID, nil)
+	return err
+}
+
+// ValidatePushName trims a display name and checks WhatsApp will accept it
+func ValidatePushName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("name is required")
+	}
+	if utf8.RuneCountInString(name) > MaxPushNameLength {
+		return "", fmt.Errorf("name exceeds %d characters", MaxPushNameLength)
+	}
+	return name, nil
+}
+
+// SetPushName changes the display name new chats see. The name is synced to
+// the account's other devices and announced with a fresh presence update.
+func (c *Client) SetPushName(ctx context.Context, name string) (string, error) {
+	name, err := ValidatePushName(name)
+	if err != nil {
+		return "", err
+	}
+
+	if err := c.SendAppState(ctx, appstate.BuildSettingPushName(name)); err != nil {
+		return "", fmt.Errorf("failed to sync push name: %v", err)
+	}
+
+	c.Store.PushName = name
+	if err := c.Store.Save(ctx); err != nil {
+		c.logger.Warnf("Failed to save push name locally: %v", err)
+	}
+	if err := c.SendDefaultPresence(); err != nil {
+		c.logger.Warnf("Failed to announce new push name: %v", err)
+	}
+	return name, nil
+}
+
+// prepareProfilePhoto decodes a JPEG, PNG or GIF image and re-encodes the
+// centred square crop as a JPEG of at most profilePhotoSize pixels a side.
+func prepareProfilePhoto(data []byte) ([]byte, error) {
+	if err := checkImageSize(data); err != nil {
+		return nil, err
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+	}
+
+	b := src.Bounds()
+	edge := min(b.Dx(), b.Dy())
+	if edge == 0 {
+		return nil, fmt.Errorf("%w: image is empty", ErrUnsupportedImage)
+	}
+	crop := image.Rect(0, 0, edge, edge).Add(image.Pt(b.Min.X+(b.Dx()-edge)/2, b.Min.Y+(b.Dy()-edge)/2))
+
+	size := min(edge, profilePhotoSize)
+	dst := downscale(src, crop, size, size)
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, dst, &jpeg.Options{Quality: 90}); err != nil {
+		return nil, fmt.Errorf("failed to encode profile photo: %v", err)
+	}
+	return out.Bytes(), nil
+}
+
+// checkImageSize reads an image's dimensions from its header and refuses
+// one too large to decode
+func checkImageSize(data []byte) error {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+	}
+	if int64(config.Width)*int64(config.Height) > maxImagePixels {
+		return fmt.Errorf("%w: %dx%d is over %d megapixels", ErrUnsupportedImage, config.Width, config.Height, maxImagePixels/1_000_000)
+	}
+	return nil
+}
+
+// downscale scales the from rectangle of src to a w x h image, averaging the
+// source pixels covered by each destination pixel
+func downscale(src image.Image, from image.Rectangle, w, h int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0 := from.Min.Y + y*from.Dy()/h
+		y1 := max(from.Min.Y+(y+1)*from.Dy()/h, y0+1)
+		for x := 0; x < w; x++ {
+			x0 := from.Min.X + x*from.Dx()/w
+			x1 := max(from.Min.X+(x+1)*from.Dx()/w, x0+1)
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(pr), g+uint64(pg), bl+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{uint16(r / n), uint16(g / n), uint16(bl / n), uint16(a / n)})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,90 @@
+package whatsapp
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestPrepareProfilePhoto(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		wantSize      int
+	}{
+		{"wide", 1200, 800, 640},
+		{"tall", 300, 500, 300},
+		{"square", 640, 640, 640},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := image.NewRGBA(image.Rect(0, 0, tt.width, tt.height))
+			for y := 0; y < tt.height; y++ {
+				for x := 0; x < tt.width; x++ {
+					src.Set(x, y, color.RGBA{200, 50, 50, 255})
+				}
+			}
+			var buf bytes.Buffer
+			if err := png.Encode(&buf, src); err != nil {
+				t.Fatalf("png.Encode: %v", err)
+			}
+
+			photo, err := prepareProfilePhoto(buf.Bytes())
+			if err != nil {
+				t.Fatalf("prepareProfilePhoto: %v", err)
+			}
+			img, err := jpeg.Decode(bytes.NewReader(photo))
+			if err != nil {
+				t.Fatalf("result is not a JPEG: %v", err)
+			}
+			if b := img.Bounds(); b.Dx() != tt.wantSize || b.Dy() != tt.wantSize {
+				t.Errorf("photo is %dx%d, want %dx%d", b.Dx(), b.Dy(), tt.wantSize, tt.wantSize)
+			}
+		})
+	}
+
+	if _, err := prepareProfilePhoto([]byte("not an image")); err == nil {
+		t.Error("expected an error for non-image data")
+	}
+
+	// A small file claiming a huge canvas is refused from its header
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, image.NewPaletted(image.Rect(0, 0, 1, 1), color.Palette{color.Black}), nil); err != nil {
+		t.Fatalf("gif.Encode: %v", err)
+	}
+	huge := buf.Bytes()
+	copy(huge[6:10], []byte{0xff, 0xff, 0xff, 0xff}) // logical screen 65535x65535
+	if _, err := prepareProfilePhoto(huge); err == nil || !strings.Contains(err.Error(), "megapixels") {
+		t.Errorf("prepareProfilePhoto(65535x65535) error = %v, want the pixel cap", err)
+	}
+}
+
+func TestValidatePushName(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"plain", "Acme Support", "Acme Support", false},
+		{"trimmed", "  Acme  ", "Acme", false},
+		{"multibyte at limit", strings.Repeat("é", MaxPushNameLength), strings.Repeat("é", MaxPushNameLength), false},
+		{"blank", "   ", "", true},
+		{"too long", strings.Repeat("a", MaxPushNameLength+1), "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidatePushName(tt.in)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ValidatePushName(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
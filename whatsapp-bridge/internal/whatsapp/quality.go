@@ -0,0 +1,150 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+// pingTimeout matches whatsmeow's keepalive deadline: a ping unanswered for
+// this long counts as failed
+const pingTimeout = 10 * time.Second
+
+// errOffline is the ping error while disconnected; it is not counted as a
+// failed ping, the disconnect is already reported elsewhere
+var errOffline = errors.New("not connected to WhatsApp")
+
+// TimeEvents wraps an event handler so the time each event takes to handle
+// is counted towards the event lag. whatsmeow hands events to handlers one at
+// a time, so a slow handler delays every event behind it.
+func (c *Client) TimeEvents(handler func(evt interface{})) func(evt interface{}) {
+	return func(evt interface{}) {
+		start := time.Now()
+		c.qualityMu.Lock()
+		if c.handling == nil {
+			c.handling = make(map[uint64]time.Time)
+		}
+		c.handlingSeq++
+		id := c.handlingSeq
+		c.handling[id] = start
+		c.qualityMu.Unlock()
+
+		defer func() {
+			took := time.Since(start)
+			c.qualityMu.Lock()
+			delete(c.handling, id)
+			if took > c.slowestEvent {
+				c.slowestEvent = took
+			}
+			c.qualityMu.Unlock()
+		}()
+		handler(evt)
+	}
+}
+
+// SetEventLagAlert registers fn to be called when the event lag rises above
+// threshold. It is called once each time the lag crosses it, not on every
+// sample while the lag stays high.
+func (c *Client) SetEventLagAlert(threshold time.Duration, fn func(q localTypes.ConnectionQuality)) {
+	c.qualityMu.Lock()
+	defer c.qualityMu.Unlock()
+	c.lagThreshold = threshold
+	c.eventLagHook = fn
+}
+
+// ConnectionQuality returns the latest sample, if one has been taken
+func (c *Client) ConnectionQuality() (localTypes.ConnectionQuality, bool) {
+	c.qualityMu.Lock()
+	defer c.qualityMu.Unlock()
+	if c.quality == nil {
+		return localTypes.ConnectionQuality{}, false
+	}
+	return *c.quality, true
+}
+
+// SampleConnectionQuality pings WhatsApp, the way whatsmeow's keepalive
+// does, and records the round trip together with the event lag since the
+// previous sample.
+func (c *Client) SampleConnectionQuality(ctx context.Context) localTypes.ConnectionQuality {
+	rtt, err := c.ping(ctx)
+	return c.recordQuality(rtt, err, time.Now())
+}
+
+// ping times one keepalive ping
+func (c *Client) ping(ctx context.Context) (time.Duration, error) {
+	if !c.IsConnected() {
+		return 0, errOffline
+	}
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.DangerousInternals().SendIQ(ctx, whatsmeow.DangerousInfoQuery{
+		Namespace: "w:p",
+		Type:      "get",
+		To:        types.ServerJID,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("ping failed: %v", err)
+	}
+	return time.Since(start), nil
+}
+
+// recordQuality stores a sample and runs the lag alert if the lag has just
+// crossed the threshold. The lag is the slowest event handled since the
+// previous sample, or how long an event still being handled has taken so far
+// if that is longer, so a handler that never returns is noticed too.
+func (c *Client) recordQuality(rtt time.Duration, pingErr error, now time.Time) localTypes.ConnectionQuality {
+	c.qualityMu.Lock()
+	threshold := c.lagThreshold
+	lag := c.slowestEvent
+	c.slowestEvent = 0
+	for _, start := range c.handling {
+		if running := now.Sub(start); running > lag {
+			lag = running
+		}
+	}
+
+	q := localTypes.ConnectionQuality{
+		PingRTTMs:           rtt.Milliseconds(),
+		EventLagMs:          lag.Milliseconds(),
+		EventLagThresholdMs: threshold.Milliseconds(),
+		Lagging:             threshold > 0 && lag > threshold,
+		SampledAt:           now.UTC(),
+	}
+	if pingErr != nil {
+		q.PingRTTMs = 0
+		q.PingError = pingErr.Error()
+	}
+	wasLagging := c.quality != nil && c.quality.Lagging
+	c.quality = &q
+	hook := c.eventLagHook
+	c.qualityMu.Unlock()
+
+	if pingErr != nil {
+		if !errors.Is(pingErr, errOffline) {
+			pingFailures.Inc()
+		}
+	} else {
+		pingRTT.Set(rtt.Seconds())
+	}
+	eventLag.Set(lag.Seconds())
+
+	switch {
+	case q.Lagging && !wasLagging:
+		lagAlerts.Inc()
+		c.logger.Warnf("⚠ Event handling lag %v is above %v; events are queueing in the bridge", lag.Round(time.Millisecond), threshold)
+		if hook != nil {
+			hook(q)
+		}
+	case !q.Lagging && wasLagging:
+		c.logger.Infof("✓ Event handling lag back to %v", lag.Round(time.Millisecond))
+	}
+	return q
+}
@@ -0,0 +1,74 @@
+package whatsapp
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	waLog "go.mau.fi/whatsmeow/util/log"
+
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+func TestConnectionQuality(t *testing.T) {
+	c := &Client{logger: waLog.Noop}
+
+	var alerts []localTypes.ConnectionQuality
+	c.SetEventLagAlert(50*time.Millisecond, func(q localTypes.ConnectionQuality) { alerts = append(alerts, q) })
+
+	if _, ok := c.ConnectionQuality(); ok {
+		t.Fatal("quality reported before any sample")
+	}
+
+	handle := c.TimeEvents(func(evt interface{}) {
+		switch v := evt.(type) {
+		case time.Duration:
+			time.Sleep(v)
+		case chan struct{}:
+			<-v
+		}
+	})
+
+	handle(time.Millisecond)
+	q := c.recordQuality(40*time.Millisecond, nil, time.Now())
+	if q.PingRTTMs != 40 || q.Lagging || q.EventLagThresholdMs != 50 || len(alerts) != 0 {
+		t.Fatalf("quick event: %+v, alerts %d", q, len(alerts))
+	}
+
+	// A slow event raises the alert once, however many samples see the lag
+	handle(60 * time.Millisecond)
+	q = c.recordQuality(0, errors.New("ping failed: timed out"), time.Now())
+	if !q.Lagging || q.EventLagMs < 60 || q.PingRTTMs != 0 || q.PingError == "" || len(alerts) != 1 {
+		t.Fatalf("slow event: %+v, alerts %d", q, len(alerts))
+	}
+
+	// An event still being handled counts for as long as it has run so far
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		handle(release)
+		close(finished)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	q = c.recordQuality(time.Millisecond, nil, time.Now().Add(100*time.Millisecond))
+	if !q.Lagging || q.EventLagMs < 100 || len(alerts) != 1 {
+		t.Fatalf("stuck event: %+v, alerts %d", q, len(alerts))
+	}
+	close(release)
+	<-finished
+
+	// Lag is measured afresh each sample; once it recovers a new rise alerts again
+	c.recordQuality(time.Millisecond, nil, time.Now())
+	if q = c.recordQuality(time.Millisecond, nil, time.Now()); q.Lagging || q.EventLagMs != 0 {
+		t.Fatalf("after recovery: %+v", q)
+	}
+	handle(60 * time.Millisecond)
+	c.recordQuality(time.Millisecond, nil, time.Now())
+	if len(alerts) != 2 {
+		t.Errorf("alerts = %d, want 2", len(alerts))
+	}
+
+	if got, ok := c.ConnectionQuality(); !ok || !got.Lagging {
+		t.Errorf("ConnectionQuality = %+v, %v", got, ok)
+	}
+}
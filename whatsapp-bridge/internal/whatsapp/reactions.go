@@ -0,0 +1,50 @@
+package whatsapp
+
+import (
+	"time"
+
+	"go.mau.fi/whatsmeow/types/events"
+
+	"whatsapp-bridge/internal/database"
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+// ExtractReaction reads a reaction made in a chat, or returns nil for other
+// messages. A reaction taken back has an empty emoji.
+func ExtractReaction(msg *events.Message) *localTypes.MessageReaction {
+	r := msg.Message.GetReactionMessage()
+	if r == nil || r.GetKey().GetID() == "" {
+		return nil
+	}
+
+	reaction := &localTypes.MessageReaction{
+		ChatJID:   msg.Info.Chat.ToNonAD().String(),
+		MessageID: r.GetKey().GetID(),
+		SenderJID: msg.Info.Sender.ToNonAD().String(),
+		Emoji:     r.GetText(),
+		ReactedAt: msg.Info.Timestamp.UTC(),
+	}
+	if ms := r.GetSenderTimestampMS(); ms > 0 {
+		reaction.ReactedAt = time.UnixMilli(ms).UTC()
+	}
+	return reaction
+}
+
+// storeMessageReaction records a reaction against the message it reacts to
+// and raises message_reaction
+func (c *Client) storeMessageReaction(messageStore *database.MessageStore, webhookManager interface{}, reaction *localTypes.MessageReaction, persist, deliver bool) {
+	if persist {
+		if err := messageStore.StoreMessageReaction(reaction); err != nil {
+			c.logger.Warnf("Failed to store reaction to %s in %s: %v", reaction.MessageID, reaction.ChatJID, err)
+		}
+	}
+
+	if webhookManager == nil || !deliver {
+		return
+	}
+	if wm, ok := webhookManager.(interface {
+		ProcessMessageReaction(reaction *localTypes.MessageReaction)
+	}); ok {
+		wm.ProcessMessageReaction(reaction)
+	}
+}
@@ -0,0 +1,54 @@
+package whatsapp
+
+import (
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/proto/waCommon"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestMessageReaction(t *testing.T) {
+	group := types.NewJID("120363000000000000", types.GroupServer)
+	ana := types.NewJID("15550000001", types.DefaultUserServer)
+	sent := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	reactionEvent := func(emoji string, senderMS int64) *events.Message {
+		r := &waE2E.ReactionMessage{
+			Key:  &waCommon.MessageKey{ID: proto.String("3EB0M1")},
+			Text: proto.String(emoji),
+		}
+		if senderMS > 0 {
+			r.SenderTimestampMS = proto.Int64(senderMS)
+		}
+		return &events.Message{
+			Info: types.MessageInfo{
+				MessageSource: types.MessageSource{Chat: group, Sender: ana, IsGroup: true},
+				ID:            "3EB0R1",
+				Timestamp:     sent,
+			},
+			Message: &waE2E.Message{ReactionMessage: r},
+		}
+	}
+
+	r := ExtractReaction(reactionEvent("👍", 0))
+	if r == nil {
+		t.Fatal("ExtractReaction = nil, want the reaction")
+	}
+	if r.ChatJID != group.String() || r.MessageID != "3EB0M1" || r.SenderJID != ana.String() || r.Emoji != "👍" || !r.ReactedAt.Equal(sent) {
+		t.Errorf("reaction = %+v", r)
+	}
+
+	// Taken back, timed by the sender's clock
+	r = ExtractReaction(reactionEvent("", sent.Add(time.Minute).UnixMilli()))
+	if r == nil || r.Emoji != "" || !r.ReactedAt.Equal(sent.Add(time.Minute)) {
+		t.Errorf("removal = %+v", r)
+	}
+
+	if r := ExtractReaction(&events.Message{Message: &waE2E.Message{Conversation: proto.String("hi")}}); r != nil {
+		t.Errorf("text message read as reaction: %+v", r)
+	}
+}
@@ -0,0 +1,115 @@
+package whatsapp
+
+import (
+	"slices"
+	"time"
+
+	"go.mau.fi/whatsmeow/proto/waHistorySync"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+
+	"whatsapp-bridge/internal/database"
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+// readStateFromConversation builds a chat's read state from a history sync
+// conversation. WhatsApp sends the unread count but no last-read marker, so
+// the marker is the newest message older than the unread incoming ones.
+func readStateFromConversation(conversation *waHistorySync.Conversation) *localTypes.ChatReadState {
+	state := &localTypes.ChatReadState{
+		ChatJID:        conversation.GetID(),
+		UnreadCount:    int(conversation.GetUnreadCount()),
+		UnreadMentions: int(conversation.GetUnreadMentionCount()),
+		MarkedUnread:   conversation.GetMarkedAsUnread(),
+	}
+
+	// Messages are ordered newest first
+	unread := conversation.GetUnreadCount()
+	var skipped uint32
+	for _, msg := range conversation.Messages {
+		webMsg := msg.GetMessage()
+		if webMsg == nil {
+			continue
+		}
+		if !webMsg.GetKey().GetFromMe() && skipped < unread {
+			skipped++
+			continue
+		}
+		state.LastReadMessageID = webMsg.GetKey().GetID()
+		if ts := webMsg.GetMessageTimestamp(); ts != 0 {
+			readAt := time.Unix(int64(ts), 0).UTC()
+			state.LastReadAt = &readAt
+		}
+		break
+	}
+
+	return state
+}
+
+// importReadState stores the phone's read state for a history sync
+// conversation. On-demand syncs carry old messages, not the current state.
+func (c *Client) importReadState(messageStore *database.MessageStore, historySync *events.HistorySync, conversation *waHistorySync.Conversation) {
+	if historySync.Data.GetSyncType() == waHistorySync.HistorySync_ON_DEMAND {
+		return
+	}
+	if err := messageStore.SetChatReadState(readStateFromConversation(conversation)); err != nil {
+		c.logger.Warnf("Failed to import read state for %s: %v", conversation.GetID(), err)
+	}
+}
+
+// trackReadState updates a chat's unread count for a real-time message:
+// incoming messages are unread, and our own message means the chat was read
+func (c *Client) trackReadState(messageStore *database.MessageStore, msg *events.Message) {
+	chatJID := msg.Info.Chat.String()
+
+	var err error
+	if msg.Info.IsFromMe {
+		err = messageStore.MarkChatRead(chatJID, msg.Info.ID, msg.Info.Timestamp)
+	} else {
+		err = messageStore.IncrementUnread(chatJID, c.mentionsMe(msg))
+	}
+	if err != nil {
+		c.logger.Warnf("Failed to track read state for %s: %v", chatJID, err)
+	}
+}
+
+// mentionsMe reports whether an incoming message @-mentions this account
+func (c *Client) mentionsMe(msg *events.Message) bool {
+	if c.Store == nil || c.Store.ID == nil {
+		return false
+	}
+	mentioned := msg.Message.GetExtendedTextMessage().GetContextInfo().GetMentionedJID()
+	return slices.Contains(mentioned, c.Store.ID.ToNonAD().String())
+}
+
+// HandleMarkChatAsRead applies a chat being marked read or unread on another
+// of the account's devices
+func (c *Client) HandleMarkChatAsRead(messageStore *database.MessageStore, evt *events.MarkChatAsRead) {
+	chatJID := evt.JID.String()
+
+	var err error
+	if evt.Action.GetRead() {
+		err = messageStore.MarkChatRead(chatJID, "", evt.Timestamp)
+	} else {
+		err = messageStore.SetChatMarkedUnread(chatJID)
+	}
+	if err != nil {
+		c.logger.Warnf("Failed to track read state for %s: %v", chatJID, err)
+	}
+}
+
+// handleSelfReadReceipt applies a read receipt from another of the account's
+// devices, i.e. the chat was read on the phone
+func (c *Client) handleSelfReadReceipt(messageStore *database.MessageStore, evt *events.Receipt) {
+	if evt.Type != types.ReceiptTypeRead && evt.Type != types.ReceiptTypeReadSelf {
+		return
+	}
+
+	var lastID string
+	if len(evt.MessageIDs) > 0 {
+		lastID = string(evt.MessageIDs[len(evt.MessageIDs)-1])
+	}
+	if err := messageStore.MarkChatRead(evt.Chat.String(), lastID, evt.Timestamp); err != nil {
+		c.logger.Warnf("Failed to track read state for %s: %v", evt.Chat, err)
+	}
+}
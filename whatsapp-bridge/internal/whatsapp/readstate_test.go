@@ -0,0 +1,52 @@
+package whatsapp
+
+import (
+	"testing"
+
+	"go.mau.fi/whatsmeow/proto/waCommon"
+	"go.mau.fi/whatsmeow/proto/waHistorySync"
+	"go.mau.fi/whatsmeow/proto/waWeb"
+	"google.golang.org/protobuf/proto"
+)
+
+func historyMessage(id string, fromMe bool, ts uint64) *waHistorySync.HistorySyncMsg {
+	return &waHistorySync.HistorySyncMsg{
+		Message: &waWeb.WebMessageInfo{
+			Key:              &waCommon.MessageKey{ID: proto.String(id), FromMe: proto.Bool(fromMe)},
+			MessageTimestamp: proto.Uint64(ts),
+		},
+	}
+}
+
+func TestReadStateFromConversation(t *testing.T) {
+	tests := []struct {
+		name     string
+		unread   uint32
+		messages []*waHistorySync.HistorySyncMsg
+		wantID   string
+	}{
+		{"all read", 0, []*waHistorySync.HistorySyncMsg{historyMessage("C", false, 300), historyMessage("B", false, 200)}, "C"},
+		{"two unread", 2, []*waHistorySync.HistorySyncMsg{historyMessage("D", false, 400), historyMessage("C", false, 300), historyMessage("B", true, 200)}, "B"},
+		{"own messages are never unread", 1, []*waHistorySync.HistorySyncMsg{historyMessage("D", true, 400), historyMessage("C", false, 300), historyMessage("B", false, 200)}, "D"},
+		{"marker outside the synced messages", 5, []*waHistorySync.HistorySyncMsg{historyMessage("C", false, 300)}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := readStateFromConversation(&waHistorySync.Conversation{
+				ID:          proto.String("123@s.whatsapp.net"),
+				UnreadCount: proto.Uint32(tt.unread),
+				Messages:    tt.messages,
+			})
+			if state.UnreadCount != int(tt.unread) {
+				t.Errorf("UnreadCount = %d, want %d", state.UnreadCount, tt.unread)
+			}
+			if state.LastReadMessageID != tt.wantID {
+				t.Errorf("LastReadMessageID = %q, want %q", state.LastReadMessageID, tt.wantID)
+			}
+			if tt.wantID == "" && state.LastReadAt != nil {
+				t.Errorf("LastReadAt = %v, want nil", state.LastReadAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package whatsapp
+
+import (
+	"context"
+
+	"go.mau.fi/whatsmeow/types"
+
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+// ReceiptPolicy returns a copy of the current receipt policy.
+func (c *Client) ReceiptPolicy() localTypes.ReceiptPolicy {
+	c.receiptMu.RLock()
+	defer c.receiptMu.RUnlock()
+
+	policy := c.receiptPolicy
+	policy.ChatReadReceipts = make(map[string]bool, len(c.receiptPolicy.ChatReadReceipts))
+	for jid, allowed := range c.receiptPolicy.ChatReadReceipts {
+		policy.ChatReadReceipts[jid] = allowed
+	}
+	return policy
+}
+
+// SetReceiptPolicy replaces the receipt policy. When delivery receipts change
+// and the client is connected, presence is re-sent so the new mode applies
+// immediately.
+func (c *Client) SetReceiptPolicy(policy localTypes.ReceiptPolicy) {
+	c.receiptMu.Lock()
+	changed := c.receiptPolicy.DeliveryReceipts != policy.DeliveryReceipts
+	c.receiptPolicy = policy
+	c.receiptMu.Unlock()
+
+	if changed && c.IsConnected() {
+		if err := c.SendDefaultPresence(); err != nil {
+			c.logger.Warnf("Failed to apply delivery receipt policy: %v", err)
+		}
+	}
+}
+
+// ReadReceiptsAllowed reports whether automatic read receipts may be sent to chatJID.
+// Per-chat overrides take precedence over the global setting.
+func (c *Client) ReadReceiptsAllowed(chatJID string) bool {
+	c.receiptMu.RLock()
+	defer c.receiptMu.RUnlock()
+
+	if allowed, ok := c.receiptPolicy.ChatReadReceipts[chatJID]; ok {
+		return allowed
+	}
+	return c.receiptPolicy.ReadReceipts
+}
+
+// SendDefaultPresence sends the presence implied by the receipt policy.
+//
+// whatsmeow acknowledges every incoming message itself; while the account is
+// "available" those acknowledgements are active delivery receipts, while
+// "unavailable" they are sent as inactive receipts that do not mark messages
+// delivered on the sender's phone. Suppressing delivery receipts therefore
+// keeps the bridge's presence unavailable.
+func (c *Client) SendDefaultPresence() error {
+	c.receiptMu.RLock()
+	delivery := c.receiptPolicy.DeliveryReceipts
+	c.receiptMu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancel()
+
+	if delivery {
+		return c.SendPresence(ctx, types.PresenceAvailable)
+	}
+	return c.SendPresence(ctx, types.PresenceUnavailable)
+}
@@ -0,0 +1,39 @@
+package whatsapp
+
+import (
+	"testing"
+
+	waLog "go.mau.fi/whatsmeow/util/log"
+
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+func TestReadReceiptsAllowed(t *testing.T) {
+	c := &Client{logger: waLog.Noop}
+	muted, open := "15550000001@s.whatsapp.net", "15550000002@s.whatsapp.net"
+	c.SetReceiptPolicy(localTypes.ReceiptPolicy{
+		ReadReceipts:     true,
+		ChatReadReceipts: map[string]bool{muted: false},
+	})
+
+	if c.ReadReceiptsAllowed(muted) {
+		t.Error("read receipts sent to a chat that suppresses them")
+	}
+	if !c.ReadReceiptsAllowed(open) {
+		t.Error("read receipts withheld from a chat without an override")
+	}
+
+	// Overrides win over the global setting both ways
+	c.SetReceiptPolicy(localTypes.ReceiptPolicy{ChatReadReceipts: map[string]bool{open: true}})
+	if !c.ReadReceiptsAllowed(open) || c.ReadReceiptsAllowed(muted) {
+		t.Errorf("with receipts off: open %v, muted %v; want only the overridden chat allowed",
+			c.ReadReceiptsAllowed(open), c.ReadReceiptsAllowed(muted))
+	}
+
+	// The policy handed out is a copy
+	policy := c.ReceiptPolicy()
+	policy.ChatReadReceipts[muted] = true
+	if c.ReadReceiptsAllowed(muted) {
+		t.Error("changing a returned policy changed the client's")
+	}
+}
@@ -0,0 +1,146 @@
+package whatsapp
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types/events"
+
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+// Restriction reasons reported in AccountRestriction.Reason
+const (
+	RestrictionTemporaryBan = "temporary_ban"
+	RestrictionRateLimited  = "rate_limited"
+)
+
+const (
+	// rateLimitBackoff is how long sending stays paused after WhatsApp reports
+	// the account is over its rate limit
+	rateLimitBackoff = 15 * time.Minute
+
+	// tempBanRetry is when to try connecting again after a temporary ban that
+	// came without an expiry
+	tempBanRetry = time.Hour
+)
+
+// SetRestrictedHook registers fn to be called when WhatsApp starts restricting
+// the account, or restricts it for a different reason than before. It is the
+// only record of past restrictions; the client keeps just the current one.
+func (c *Client) SetRestrictedHook(fn func(r localTypes.AccountRestriction)) {
+	c.restrictionMu.Lock()
+	defer c.restrictionMu.Unlock()
+	c.restrictedHook = fn
+}
+
+// Restriction returns the restriction in effect, if any. A restriction whose
+// expiry has passed is no longer in effect.
+func (c *Client) Restriction() (localTypes.AccountRestriction, bool) {
+	c.restrictionMu.Lock()
+	defer c.restrictionMu.Unlock()
+
+	r := c.restriction
+	if r == nil {
+		return localTypes.AccountRestriction{}, false
+	}
+	if r.Until != nil && time.Now().After(*r.Until) {
+		c.logger.Infof("Account restriction (%s) expired", r.Reason)
+		c.restriction = nil
+		return localTypes.AccountRestriction{}, false
+	}
+	return *r, true
+}
+
+// HandleTemporaryBan records a temporary ban reported while connecting.
+// whatsmeow does not reconnect after one, so a reconnect is scheduled for
+// when the ban expires.
+func (c *Client) HandleTemporaryBan(evt *events.TemporaryBan) {
+	c.logger.Errorf("✗ Account temporarily banned: %s", evt.String())
+	c.restrict(RestrictionTemporaryBan, int(evt.Code), evt.Code.String(), evt.Expire)
+
+	retry := evt.Expire
+	if retry <= 0 {
+		retry = tempBanRetry
+	}
+	time.AfterFunc(retry, func() {
+		if c.IsConnected() {
+			return
+		}
+		c.logger.Infof("Reconnecting after temporary ban")
+		if err := c.Client.Connect(); err != nil {
+			c.logger.Errorf("Reconnect after temporary ban: %v", err)
+		}
+	})
+}
+
+// HandleStreamError records a rate limit when the server closes the stream
+// with a 429. Other stream errors are left to the reconnect logic.
+func (c *Client) HandleStreamError(evt *events.StreamError) {
+	if evt.Code == "429" {
+		c.restrict(RestrictionRateLimited, 429, "stream closed for exceeding the rate limit", rateLimitBackoff)
+	}
+}
+
+// noteSendError records a rate limit when a send failed with one
+func (c *Client) noteSendError(err error) {
+	if isRateLimited(err) {
+		c.restrict(RestrictionRateLimited, 429, "sends rejected for exceeding the rate limit", rateLimitBackoff)
+	}
+}
+
+// isRateLimited reports whether WhatsApp rejected a request as over the
+// account's rate limit, either as an IQ error or a message ack error.
+func isRateLimited(err error) bool {
+	var iqErr *whatsmeow.IQError
+	if errors.As(err, &iqErr) {
+		return iqErr.Code == 429
+	}
+	return errors.Is(err, whatsmeow.ErrServerReturnedError) && strings.HasSuffix(err.Error(), " 429")
+}
+
+// restrict records a restriction lasting d (0 means until lifted) and runs the
+// restricted hook unless the same restriction was already in effect.
+func (c *Client) restrict(reason string, code int, description string, d time.Duration) {
+	now := time.Now()
+	r := &localTypes.AccountRestriction{
+		Reason:      reason,
+		Code:        code,
+		Description: description,
+		Since:       now,
+	}
+	if d > 0 {
+		until := now.Add(d)
+		r.Until = &until
+	}
+
+	c.restrictionMu.Lock()
+	prev := c.restriction
+	repeat := prev != nil && prev.Reason == reason && prev.Code == code
+	if repeat {
+		r.Since = prev.Since
+	}
+	c.restriction = r
+	hook := c.restrictedHook
+	c.restrictionMu.Unlock()
+
+	if repeat {
+		return
+	}
+	c.logger.Warnf("Account restricted (%s): %s; outgoing sends paused", reason, description)
+	if hook != nil {
+		hook(*r)
+	}
+}
+
+// liftTemporaryBan clears a temporary ban once a connection succeeds
+func (c *Client) liftTemporaryBan() {
+	c.restrictionMu.Lock()
+	defer c.restrictionMu.Unlock()
+	if c.restriction != nil && c.restriction.Reason == RestrictionTemporaryBan {
+		c.logger.Infof("✓ Temporary ban lifted")
+		c.restriction = nil
+	}
+}
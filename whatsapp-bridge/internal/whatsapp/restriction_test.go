@@ -0,0 +1,85 @@
+package whatsapp
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	waLog "go.mau.fi/whatsmeow/util/log"
+
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+func TestIsRateLimited(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"iq rate limit", whatsmeow.ErrIQRateOverLimit, true},
+		{"iq rate limit wrapped", fmt.Errorf("usync: %w", whatsmeow.ErrIQRateOverLimit), true},
+		{"ack rate limit", fmt.Errorf("%w 429", whatsmeow.ErrServerReturnedError), true},
+		{"other ack error", fmt.Errorf("%w 479", whatsmeow.ErrServerReturnedError), false},
+		{"other iq error", whatsmeow.ErrIQForbidden, false},
+		{"other", errors.New("boom"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRateLimited(tt.err); got != tt.want {
+				t.Errorf("isRateLimited(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRestriction(t *testing.T) {
+	c := &Client{logger: waLog.Noop}
+
+	var fired []localTypes.AccountRestriction
+	c.SetRestrictedHook(func(r localTypes.AccountRestriction) { fired = append(fired, r) })
+
+	if _, ok := c.Restriction(); ok {
+		t.Fatal("new client is restricted")
+	}
+
+	c.noteSendError(fmt.Errorf("%w 429", whatsmeow.ErrServerReturnedError))
+	r, ok := c.Restriction()
+	if !ok || r.Reason != RestrictionRateLimited || r.Until == nil {
+		t.Fatalf("Restriction() = %+v, %v, want rate limit with expiry", r, ok)
+	}
+
+	// The same restriction again only extends it
+	c.noteSendError(whatsmeow.ErrIQRateOverLimit)
+	if len(fired) != 1 {
+		t.Errorf("hook fired %d times, want 1", len(fired))
+	}
+
+	// Connecting does not lift a rate limit
+	c.MarkConnected()
+	if _, ok := c.Restriction(); !ok {
+		t.Error("rate limit lifted by connecting")
+	}
+
+	// An expired restriction is no longer in effect
+	past := time.Now().Add(-time.Second)
+	c.restriction.Until = &past
+	if _, ok := c.Restriction(); ok {
+		t.Error("expired restriction still in effect")
+	}
+
+	c.restrict(RestrictionTemporaryBan, 104, "sent the same message to too many people", 0)
+	if r, ok := c.Restriction(); !ok || r.Until != nil {
+		t.Fatalf("Restriction() = %+v, %v, want ban without expiry", r, ok)
+	}
+	if len(fired) != 2 || fired[1].Code != 104 {
+		t.Errorf("hook calls = %+v, want the ban reported", fired)
+	}
+
+	c.MarkConnected()
+	if _, ok := c.Restriction(); ok {
+		t.Error("temporary ban not lifted by connecting")
+	}
+}
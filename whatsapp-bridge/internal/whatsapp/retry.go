@@ -0,0 +1,45 @@
+package whatsapp
+
+import (
+	"time"
+
+	"whatsapp-bridge/internal/retry"
+)
+
+// Retry policies per operation. Only errors classifySendError marks retryable
+// (timeouts, disconnects, rate limits and server errors) are tried again, and
+// every retry stays within the caller's context deadline.
+var (
+	// sendPolicy resends with the same message ID, so a retry after a lost
+	// ack cannot deliver the message twice
+	sendPolicy = retry.Policy{
+		Attempts:      3,
+		Backoff:       time.Second,
+		MaxBackoff:    4 * time.Second,
+		RateLimitWait: 10 * time.Second,
+		Retryable:     isRetryable,
+	}
+
+	// fetchPolicy covers read-only lookups such as group info and profile pictures
+	fetchPolicy = retry.Policy{
+		Attempts:      3,
+		Backoff:       500 * time.Millisecond,
+		MaxBackoff:    4 * time.Second,
+		RateLimitWait: 15 * time.Second,
+		Retryable:     isRetryable,
+	}
+
+	// historyPolicy paces on-demand history requests, which the phone answers
+	// slowly and the server throttles aggressively
+	historyPolicy = retry.Policy{
+		Attempts:      2,
+		Backoff:       5 * time.Second,
+		RateLimitWait: 30 * time.Second,
+		Retryable:     isRetryable,
+	}
+)
+
+func isRetryable(err error) bool {
+	_, retryable := classifySendError(err)
+	return retryable
+}
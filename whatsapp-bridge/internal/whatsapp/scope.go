@@ -0,0 +1,72 @@
+package whatsapp
+
+import (
+	"fmt"
+	"slices"
+
+	"go.mau.fi/whatsmeow/types"
+
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+// ChatScope returns a copy of the chat scope.
+func (c *Client) ChatScope() localTypes.ChatScope {
+	c.scopeMu.RLock()
+	defer c.scopeMu.RUnlock()
+
+	scope := c.chatScope
+	scope.AllowChats = slices.Clone(c.chatScope.AllowChats)
+	scope.DenyChats = slices.Clone(c.chatScope.DenyChats)
+	return scope
+}
+
+// SetChatScope replaces the chat scope.
+func (c *Client) SetChatScope(scope localTypes.ChatScope) {
+	c.scopeMu.Lock()
+	defer c.scopeMu.Unlock()
+	c.chatScope = scope
+}
+
+// InChatScope reports whether messages in chat are stored and webhooked,
+// and answered by the auto-responders, auto-read and relayed.
+func (c *Client) InChatScope(chat types.JID) bool {
+	c.scopeMu.RLock()
+	defer c.scopeMu.RUnlock()
+	return inScope(c.chatScope, chat)
+}
+
+// inScope applies the deny list, then the allow list, then the group and
+// newsletter exclusions. An explicitly allowed group stays in scope even
+// when groups are excluded.
+func inScope(scope localTypes.ChatScope, chat types.JID) bool {
+	jid := chat.ToNonAD().String()
+	if slices.Contains(scope.DenyChats, jid) {
+		return false
+	}
+	if slices.Contains(scope.AllowChats, jid) {
+		return true
+	}
+	if len(scope.AllowChats) > 0 {
+		return false
+	}
+
+	switch chat.Server {
+	case types.GroupServer:
+		return !scope.ExcludeGroups
+	case types.NewsletterServer:
+		return !scope.ExcludeNewsletters
+	}
+	return true
+}
+
+// ValidateChatScope checks that every listed chat is a valid JID
+func ValidateChatScope(scope localTypes.ChatScope) error {
+	for _, list := range [][]string{scope.AllowChats, scope.DenyChats} {
+		for _, jid := range list {
+			if _, err := types.ParseJID(jid); err != nil || jid == "" {
+				return fmt.Errorf("invalid chat JID %q", jid)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package whatsapp
+
+import (
+	"testing"
+
+	"go.mau.fi/whatsmeow/types"
+
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+func TestInScope(t *testing.T) {
+	dm := types.NewJID("15551234567", types.DefaultUserServer)
+	group := types.NewJID("120363000000000001", types.GroupServer)
+	otherGroup := types.NewJID("120363000000000002", types.GroupServer)
+	newsletter := types.NewJID("120363000000000003", types.NewsletterServer)
+
+	tests := []struct {
+		name  string
+		scope localTypes.ChatScope
+		chat  types.JID
+		want  bool
+	}{
+		{"empty scope admits everything", localTypes.ChatScope{}, group, true},
+		{"denied chat", localTypes.ChatScope{DenyChats: []string{dm.String()}}, dm, false},
+		{"groups excluded", localTypes.ChatScope{ExcludeGroups: true}, group, false},
+		{"groups excluded keeps DMs", localTypes.ChatScope{ExcludeGroups: true}, dm, true},
+		{"newsletters excluded", localTypes.ChatScope{ExcludeNewsletters: true}, newsletter, false},
+		{"allowed group despite exclusion", localTypes.ChatScope{AllowChats: []string{group.String()}, ExcludeGroups: true}, group, true},
+		{"allow list drops unlisted chats", localTypes.ChatScope{AllowChats: []string{group.String()}}, otherGroup, false},
+		{"deny wins over allow", localTypes.ChatScope{AllowChats: []string{dm.String()}, DenyChats: []string{dm.String()}}, dm, false},
+		{"device suffix ignored", localTypes.ChatScope{DenyChats: []string{dm.String()}}, types.NewADJID(dm.User, 0, 5), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inScope(tt.scope, tt.chat); got != tt.want {
+				t.Errorf("inScope(%s) = %v, want %v", tt.chat, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package whatsapp
+
+import (
+	"context"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// echoRetention is how long an echo nobody has waited for yet is kept, in
+// case the receipt arrives before WaitForEcho is called
+const echoRetention = 2 * time.Minute
+
+// echo is the receipt of a message sent to the account's own chat
+type echo struct {
+	created time.Time
+	at      time.Time
+	done    chan struct{}
+}
+
+// SelfJID returns the account's own chat (message yourself)
+func (c *Client) SelfJID() (types.JID, bool) {
+	if c.Store == nil || c.Store.ID == nil {
+		return types.EmptyJID, false
+	}
+	return c.Store.ID.ToNonAD(), true
+}
+
+// WaitForEcho waits until another of the account's devices acknowledges a
+// message sent to the account's own chat, and returns when it did. Without
+// the phone online no echo arrives and ctx ends the wait.
+func (c *Client) WaitForEcho(ctx context.Context, messageID string) (time.Time, error) {
+	c.echoMu.Lock()
+	e := c.echoEntry(types.MessageID(messageID))
+	c.echoMu.Unlock()
+
+	defer func() {
+		c.echoMu.Lock()
+		delete(c.echoes, types.MessageID(messageID))
+		c.echoMu.Unlock()
+	}()
+
+	select {
+	case <-e.done:
+		return e.at, nil
+	case <-ctx.Done():
+		return time.Time{}, ctx.Err()
+	}
+}
+
+// noteEcho records receipts from the account's other devices for messages in
+// the account's own chat. The bridge's own device never acknowledges what it
+// sent, so only a receipt that came back through WhatsApp from another device
+// counts as an echo.
+func (c *Client) noteEcho(evt *events.Receipt) {
+	self, ok := c.SelfJID()
+	if !ok || evt.Chat.User != self.User || evt.Type == types.ReceiptTypeServerError || c.fromThisDevice(evt.Sender) {
+		return
+	}
+
+	c.echoMu.Lock()
+	defer c.echoMu.Unlock()
+	for _, id := range evt.MessageIDs {
+		e := c.echoEntry(id)
+		select {
+		case <-e.done:
+		default:
+			e.at = evt.Timestamp
+			if e.at.IsZero() {
+				e.at = time.Now()
+			}
+			close(e.done)
+		}
+	}
+}
+
+// fromThisDevice reports whether sender is the bridge's own device, by phone
+// number or LID
+func (c *Client) fromThisDevice(sender types.JID) bool {
+	own := c.Store.ID
+	if sender.Device != own.Device {
+		return false
+	}
+	return sender.User == own.User || (!c.Store.LID.IsEmpty() && sender.User == c.Store.LID.User)
+}
+
+// echoEntry returns the echo for a message, creating it if needed, and drops
+// stale ones. c.echoMu must be held.
+func (c *Client) echoEntry(id types.MessageID) *echo {
+	now := time.Now()
+	if c.echoes == nil {
+		c.echoes = make(map[types.MessageID]*echo)
+	}
+	for key, e := range c.echoes {
+		if now.Sub(e.created) > echoRetention {
+			delete(c.echoes, key)
+		}
+	}
+
+	e, ok := c.echoes[id]
+	if !ok {
+		e = &echo{created: now, done: make(chan struct{})}
+		c.echoes[id] = e
+	}
+	return e
+}
@@ -0,0 +1,70 @@
+package whatsapp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/store"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+func TestWaitForEcho(t *testing.T) {
+	own := types.NewJID("111", types.DefaultUserServer)
+	own.Device = 3
+	c := &Client{Client: &whatsmeow.Client{Store: &store.Device{ID: &own}}}
+
+	self, ok := c.SelfJID()
+	if !ok || self.String() != "111@s.whatsapp.net" {
+		t.Fatalf("SelfJID() = %v, %v", self, ok)
+	}
+
+	receipt := func(chat types.JID, id string) *events.Receipt {
+		evt := &events.Receipt{MessageIDs: []types.MessageID{types.MessageID(id)}, Timestamp: time.Now()}
+		evt.Chat = chat
+		evt.Sender = types.NewJID("111", types.DefaultUserServer) // the phone
+		evt.IsFromMe = true
+		return evt
+	}
+
+	// A receipt that arrives before the wait starts is kept
+	c.noteEcho(receipt(self, "EARLY"))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := c.WaitForEcho(ctx, "EARLY"); err != nil {
+		t.Errorf("WaitForEcho(EARLY) = %v", err)
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		c.noteEcho(receipt(types.NewJID("222", types.DefaultUserServer), "LATE"))
+		c.noteEcho(receipt(self, "LATE"))
+	}()
+	if _, err := c.WaitForEcho(ctx, "LATE"); err != nil {
+		t.Errorf("WaitForEcho(LATE) = %v", err)
+	}
+
+	// Nor is one this device sent itself
+	mine := receipt(self, "MINE")
+	mine.Sender = own
+	c.noteEcho(mine)
+	short, cancelShort := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelShort()
+	if _, err := c.WaitForEcho(short, "MINE"); err == nil {
+		t.Error("receipt from the bridge's own device counted as an echo")
+	}
+
+	// Receipts in other chats are not echoes
+	c.noteEcho(receipt(types.NewJID("222", types.DefaultUserServer), "OTHER"))
+	short, cancelShort = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelShort()
+	if _, err := c.WaitForEcho(short, "OTHER"); err == nil {
+		t.Error("receipt in another chat counted as an echo")
+	}
+
+	if len(c.echoes) != 0 {
+		t.Errorf("%d echoes left after waiting", len(c.echoes))
+	}
+}
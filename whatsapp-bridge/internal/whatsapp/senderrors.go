@@ -0,0 +1,128 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+
+	bridgeTypes "whatsapp-bridge/internal/types"
+)
+
+// Send error codes reported in SendResult.Code
+const (
+	SendErrNotConnected     = "not_connected"
+	SendErrInvalidRecipient = "invalid_recipient"
+	SendErrNotOnWhatsApp    = "not_on_whatsapp"
+	SendErrTimeout          = "timeout"
+	SendErrInvalidMedia     = "invalid_media"
+	SendErrMediaRejected    = "media_rejected"
+	SendErrTooLarge         = "message_too_large"
+	SendErrServerRejected   = "server_rejected"
+	SendErrProductNotFound  = "product_not_found"
+	SendErrInvalidContact   = "invalid_contact"
+	SendErrUnknown          = "send_failed"
+)
+
+// MaxTextLength is the longest text body, in characters, WhatsApp accepts in
+// a single message
+const MaxTextLength = 65536
+
+// registeredTTL is how long a positive IsOnWhatsApp lookup is trusted
+const registeredTTL = 24 * time.Hour
+
+// sendFailure builds a failed SendResult with a classified error code.
+func sendFailure(code string, retryable bool, format string, args ...interface{}) bridgeTypes.SendResult {
+	return bridgeTypes.SendResult{
+		Success:   false,
+		Error:     fmt.Sprintf(format, args...),
+		Code:      code,
+		Retryable: retryable,
+	}
+}
+
+// SendError builds a failed SendResult from the error of a send that only
+// reports an error, such as a reaction or a pin
+func SendError(err error) bridgeTypes.SendResult {
+	code, retryable := classifySendError(err)
+	return sendFailure(code, retryable, "%v", err)
+}
+
+// classifySendError maps whatsmeow send and upload errors to a send error code
+// and whether retrying the same request may succeed.
+func classifySendError(err error) (code string, retryable bool) {
+	var iqErr *whatsmeow.IQError
+	var discErr *whatsmeow.DisconnectedError
+
+	switch {
+	case errors.Is(err, whatsmeow.ErrNotConnected), errors.Is(err, whatsmeow.ErrNotLoggedIn), errors.As(err, &discErr):
+		return SendErrNotConnected, true
+	case errors.Is(err, whatsmeow.ErrMessageTimedOut), errors.Is(err, whatsmeow.ErrIQTimedOut),
+		errors.Is(err, context.DeadlineExceeded):
+		return SendErrTimeout, true
+	case errors.Is(err, whatsmeow.ErrUnknownServer), errors.Is(err, whatsmeow.ErrRecipientADJID),
+		errors.Is(err, whatsmeow.ErrBroadcastListUnsupported):
+		return SendErrInvalidRecipient, false
+	case errors.Is(err, whatsmeow.ErrServerReturnedError):
+		return SendErrServerRejected, false
+	case errors.As(err, &iqErr):
+		switch {
+		case iqErr.Code == 413:
+			return SendErrTooLarge, false
+		case iqErr.Code == 415:
+			return SendErrMediaRejected, false
+		case iqErr.Code == 429 || iqErr.Code >= 500:
+			return SendErrServerRejected, true
+		default:
+			return SendErrServerRejected, false
+		}
+	}
+
+	// Media uploads report HTTP failures as plain "upload failed with status code N"
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "status code 413"):
+		return SendErrTooLarge, false
+	case strings.Contains(msg, "status code 415"), strings.Contains(msg, "status code 400"):
+		return SendErrMediaRejected, false
+	case strings.Contains(msg, "status code 5"):
+		return SendErrServerRejected, true
+	}
+
+	return SendErrUnknown, false
+}
+
+// checkRegistered reports whether a phone-number JID is on WhatsApp. Lookup
+// failures are treated as registered so a flaky usync never blocks sending.
+func (c *Client) checkRegistered(ctx context.Context, jid types.JID) bool {
+	if jid.Server != types.DefaultUserServer {
+		return true
+	}
+
+	c.registeredMu.Lock()
+	checked, ok := c.registered[jid.User]
+	c.registeredMu.Unlock()
+	if ok && time.Since(checked) < registeredTTL {
+		return true
+	}
+
+	resp, err := c.IsOnWhatsApp(ctx, []string{"+" + jid.User})
+	if err != nil || len(resp) == 0 {
+		return true
+	}
+	if !resp[0].IsIn {
+		return false
+	}
+
+	c.registeredMu.Lock()
+	if c.registered == nil {
+		c.registered = make(map[string]time.Time)
+	}
+	c.registered[jid.User] = time.Now()
+	c.registeredMu.Unlock()
+	return true
+}
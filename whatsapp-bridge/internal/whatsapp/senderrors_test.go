@@ -0,0 +1,39 @@
+package whatsapp
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"go.mau.fi/whatsmeow"
+)
+
+func TestClassifySendError(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		wantCode      string
+		wantRetryable bool
+	}{
+		{"ack timeout", whatsmeow.ErrMessageTimedOut, SendErrTimeout, true},
+		{"iq timeout wrapped", fmt.Errorf("usync: %w", whatsmeow.ErrIQTimedOut), SendErrTimeout, true},
+		{"not connected", whatsmeow.ErrNotConnected, SendErrNotConnected, true},
+		{"disconnected", whatsmeow.ErrIQDisconnected, SendErrNotConnected, true},
+		{"unknown server", fmt.Errorf("%w example.com", whatsmeow.ErrUnknownServer), SendErrInvalidRecipient, false},
+		{"server ack error", fmt.Errorf("%w 479", whatsmeow.ErrServerReturnedError), SendErrServerRejected, false},
+		{"iq rate limit", whatsmeow.ErrIQRateOverLimit, SendErrServerRejected, true},
+		{"upload too large", errors.New("upload failed with status code 413"), SendErrTooLarge, false},
+		{"upload rejected type", errors.New("upload failed with status code 415"), SendErrMediaRejected, false},
+		{"upload server error", errors.New("upload failed with status code 503"), SendErrServerRejected, true},
+		{"other", errors.New("boom"), SendErrUnknown, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, retryable := classifySendError(tt.err)
+			if code != tt.wantCode || retryable != tt.wantRetryable {
+				t.Errorf("classifySendError(%v) = (%q, %v), want (%q, %v)", tt.err, code, retryable, tt.wantCode, tt.wantRetryable)
+			}
+		})
+	}
+}
@@ -0,0 +1,350 @@
+package whatsapp
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"whatsapp-bridge/internal/database"
+	localTypes "whatsapp-bridge/internal/types"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+// Sticker pack storage and limits. WhatsApp only accepts packs of 3 to 30
+// stickers, each a 512x512 WebP.
+const (
+	stickerPackDir     = "store/stickers/packs"
+	stickerCacheDir    = "store/stickers/cache"
+	minPackStickers    = 3
+	maxPackStickers    = 30
+	maxStickerBytes    = 1 << 20
+	stickerSize        = "512"
+	stickerConvertTime = 30 * time.Second
+)
+
+// ExtractStickerPack parses a sticker pack message. Returns nil for any other
+// message type.
+func ExtractStickerPack(msg *events.Message) *localTypes.StickerPack {
+	sp := msg.Message.GetStickerPackMessage()
+	if sp == nil || sp.GetStickerPackID() == "" {
+		return nil
+	}
+
+	pack := &localTypes.StickerPack{
+		ID:            sp.GetStickerPackID(),
+		Name:          sp.GetName(),
+		Publisher:     sp.GetPublisher(),
+		Description:   sp.GetPackDescription(),
+		Stickers:      []localTypes.StickerPackItem{},
+		ChatJID:       msg.Info.Chat.String(),
+		MessageID:     msg.Info.ID,
+		SenderJID:     msg.Info.Sender.ToNonAD().String(),
+		FileLength:    sp.GetFileLength(),
+		SeenAt:        msg.Info.Timestamp,
+		DirectPath:    sp.GetDirectPath(),
+		MediaKey:      sp.GetMediaKey(),
+		FileSHA256:    sp.GetFileSHA256(),
+		FileEncSHA256: sp.GetFileEncSHA256(),
+	}
+	for _, sticker := range sp.GetStickers() {
+		pack.Stickers = append(pack.Stickers, localTypes.StickerPackItem{
+			FileName:   sticker.GetFileName(),
+			Emojis:     sticker.GetEmojis(),
+			IsAnimated: sticker.GetIsAnimated(),
+			Mimetype:   sticker.GetMimetype(),
+		})
+	}
+	return pack
+}
+
+// storeStickerPack records a sticker pack so it can be listed and downloaded later
+func (c *Client) storeStickerPack(messageStore *database.MessageStore, pack *localTypes.StickerPack) {
+	if err := messageStore.StoreStickerPack(pack); err != nil {
+		c.logger.Warnf("Failed to store sticker pack %s: %v", pack.ID, err)
+	}
+}
+
+// DownloadStickerPack fetches a previously seen sticker pack and unpacks its
+// stickers into store/stickers/packs/{id}. Returns the directory and the
+// paths of the extracted files.
+func (c *Client) DownloadStickerPack(ctx context.Context, pack *localTypes.StickerPack) (string, []string, error) {
+	if !c.IsConnected() {
+		return "", nil, fmt.Errorf("not connected to WhatsApp")
+	}
+	if pack.DirectPath == "" || len(pack.MediaKey) == 0 {
+		return "", nil, fmt.Errorf("sticker pack %s has no downloadable media", pack.ID)
+	}
+
+	data, err := c.Client.Download(ctx, &waE2E.StickerPackMessage{
+		StickerPackID: proto.String(pack.ID),
+		DirectPath:    proto.String(pack.DirectPath),
+		MediaKey:      pack.MediaKey,
+		FileSHA256:    pack.FileSHA256,
+		FileEncSHA256: pack.FileEncSHA256,
+		FileLength:    proto.Uint64(pack.FileLength),
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to download sticker pack: %v", err)
+	}
+
+	dir := filepath.Join(stickerPackDir, safeFileName(pack.ID))
+	files, err := unzipStickers(data, dir)
+	if err != nil {
+		return "", nil, err
+	}
+	return dir, files, nil
+}
+
+// unzipStickers extracts the WebP files of a sticker pack archive into dir.
+// Entry names are flattened so an archive cannot write outside dir.
+func unzipStickers(data []byte, dir string) ([]string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sticker pack archive: %v", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create sticker directory: %v", err)
+	}
+
+	var files []string
+	for _, entry := range archive.File {
+		name := safeFileName(entry.Name)
+		if entry.FileInfo().IsDir() || !strings.EqualFold(filepath.Ext(name), ".webp") {
+			continue
+		}
+
+		rc, err := entry.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", entry.Name, err)
+		}
+		sticker, err := io.ReadAll(io.LimitReader(rc, maxStickerBytes+1))
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", entry.Name, err)
+		}
+		if len(sticker) > maxStickerBytes {
+			return nil, fmt.Errorf("sticker %s exceeds %d bytes", entry.Name, maxStickerBytes)
+		}
+
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, sticker, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %v", path, err)
+		}
+		files = append(files, path)
+	}
+	return files, nil
+}
+
+// safeFileName reduces a name to a single path element
+func safeFileName(name string) string {
+	name = filepath.Base(filepath.Clean("/" + name))
+	if name == "/" || name == "." {
+		return "_"
+	}
+	return name
+}
+
+// SendStickerPack builds a sticker pack from the images in a directory and
+// sends it on behalf of the owner tenant. PNG and JPEG images are converted
+// with cwebp and GIFs with gif2webp; converted files are cached by source hash.
+func (c *Client) SendStickerPack(ctx context.Context, messageStore *database.MessageStore, owner string, req localTypes.SendStickerPackRequest) localTypes.SendResult {
+	if !c.IsConnected() {
+		return sendFailure(SendErrNotConnected, true, "Not connected to WhatsApp")
+	}
+
+	recipientJID, err := parseRecipient(req.Recipient)
+	if err != nil {
+		return sendFailure(SendErrInvalidRecipient, false, "Error parsing JID: %v", err)
+	}
+	if err := ValidateMediaPath(req.Directory); err != nil {
+		return sendFailure(SendErrInvalidMedia, false, "Invalid sticker directory: %v", err)
+	}
+	if !c.checkRegistered(ctx, recipientJID) {
+		return sendFailure(SendErrNotOnWhatsApp, false, "Recipient %s is not on WhatsApp", recipientJID.User)
+	}
+
+	images, err := stickerSources(req.Directory)
+	if err != nil {
+		return sendFailure(SendErrInvalidMedia, false, "%v", err)
+	}
+	if len(images) < minPackStickers || len(images) > maxPackStickers {
+		return sendFailure(SendErrInvalidMedia, false, "A sticker pack needs %d to %d images, found %d", minPackStickers, maxPackStickers, len(images))
+	}
+
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	stickers := make([]*waE2E.StickerPackMessage_Sticker, 0, len(images))
+	for _, image := range images {
+		webp, err := convertSticker(ctx, image)
+		if err != nil {
+			return sendFailure(SendErrInvalidMedia, false, "Error converting %s: %v", filepath.Base(image), err)
+		}
+
+		hash := sha256.Sum256(webp)
+		name := base64.RawURLEncoding.EncodeToString(hash[:]) + ".webp"
+		w, err := zw.Create(name)
+		if err == nil {
+			_, err = w.Write(webp)
+		}
+		if err != nil {
+			return sendFailure(SendErrInvalidMedia, false, "Error building sticker pack: %v", err)
+		}
+
+		stickers = append(stickers, &waE2E.StickerPackMessage_Sticker{
+			FileName:   proto.String(name),
+			IsAnimated: proto.Bool(isAnimatedWebP(webp)),
+			Mimetype:   proto.String("image/webp"),
+		})
+	}
+	if err := zw.Close(); err != nil {
+		return sendFailure(SendErrInvalidMedia, false, "Error building sticker pack: %v", err)
+	}
+
+	resp, err := c.Upload(ctx, archive.Bytes(), whatsmeow.MediaStickerPack)
+	if err != nil {
+		code, retryable := classifySendError(err)
+		return sendFailure(code, retryable, "Error uploading sticker pack: %v", err)
+	}
+
+	packID := make([]byte, 16)
+	_, _ = rand.Read(packID)
+	origin := waE2E.StickerPackMessage_USER_CREATED
+
+	msg := &waE2E.Message{
+		StickerPackMessage: &waE2E.StickerPackMessage{
+			StickerPackID:     proto.String(hex.EncodeToString(packID)),
+			Name:              proto.String(req.Name),
+			Publisher:         proto.String(req.Publisher),
+			PackDescription:   proto.String(req.Description),
+			Stickers:          stickers,
+			TrayIconFileName:  stickers[0].FileName,
+			DirectPath:        proto.String(resp.DirectPath),
+			MediaKey:          resp.MediaKey,
+			FileSHA256:        resp.FileSHA256,
+			FileEncSHA256:     resp.FileEncSHA256,
+			FileLength:        proto.Uint64(resp.FileLength),
+			StickerPackSize:   proto.Uint64(uint64(archive.Len())),
+			MediaKeyTimestamp: proto.Int64(time.Now().Unix()),
+			StickerPackOrigin: &origin,
+		},
+	}
+
+	return c.sendTracked(ctx, messageStore, owner, recipientJID, msg, "")
+}
+
+// stickerSources lists the convertible images in a directory in name order
+func stickerSources(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sticker directory: %v", err)
+	}
+
+	var images []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".png", ".jpg", ".jpeg", ".gif", ".webp":
+			images = append(images, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(images)
+	return images, nil
+}
+
+// convertSticker returns an image as a 512x512 WebP sticker, converting it
+// once and serving later requests for the same image from the cache
+func convertSticker(ctx context.Context, path string) ([]byte, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".webp" {
+		return checkStickerSize(source)
+	}
+
+	hash := sha256.Sum256(source)
+	cached := filepath.Join(stickerCacheDir, hex.EncodeToString(hash[:])+".webp")
+	if data, err := os.ReadFile(cached); err == nil {
+		return data, nil
+	}
+
+	if err := os.MkdirAll(stickerCacheDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create sticker cache: %v", err)
+	}
+
+	// Write to a temporary name so an interrupted conversion is never cached
+	tmp := cached + ".tmp"
+	var cmd *exec.Cmd
+	convertCtx, cancel := context.WithTimeout(ctx, stickerConvertTime)
+	defer cancel()
+	if ext == ".gif" {
+		cmd = exec.CommandContext(convertCtx, "gif2webp", "-quiet", "-mt", path, "-o", tmp)
+	} else {
+		cmd = exec.CommandContext(convertCtx, "cwebp", "-quiet", "-resize", stickerSize, stickerSize, path, "-o", tmp)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		os.Remove(tmp)
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, fmt.Errorf("%s is not installed (install libwebp tools to build sticker packs)", cmd.Args[0])
+		}
+		return nil, fmt.Errorf("%s failed: %v: %s", cmd.Args[0], err, strings.TrimSpace(string(out)))
+	}
+
+	data, err := os.ReadFile(tmp)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := checkStickerSize(data); err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+	if err := os.Rename(tmp, cached); err != nil {
+		return nil, fmt.Errorf("failed to cache sticker: %v", err)
+	}
+	return data, nil
+}
+
+// checkStickerSize rejects stickers WhatsApp would refuse for their size
+func checkStickerSize(data []byte) ([]byte, error) {
+	if len(data) > maxStickerBytes {
+		return nil, fmt.Errorf("sticker is %d bytes, limit is %d", len(data), maxStickerBytes)
+	}
+	return data, nil
+}
+
+// isAnimatedWebP reports whether a WebP file has an animation chunk
+func isAnimatedWebP(data []byte) bool {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return false
+	}
+	for pos := 12; pos+8 <= len(data); {
+		chunk := string(data[pos : pos+4])
+		size := int(data[pos+4]) | int(data[pos+5])<<8 | int(data[pos+6])<<16 | int(data[pos+7])<<24
+		if chunk == "ANIM" {
+			return true
+		}
+		pos += 8 + size + size&1
+	}
+	return false
+}
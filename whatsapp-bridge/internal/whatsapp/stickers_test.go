@@ -0,0 +1,84 @@
+package whatsapp
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestExtractStickerPack(t *testing.T) {
+	pack := ExtractStickerPack(commerceEvent(&waE2E.Message{
+		StickerPackMessage: &waE2E.StickerPackMessage{
+			StickerPackID: proto.String("PACK1"),
+			Name:          proto.String("Cats"),
+			Publisher:     proto.String("Someone"),
+			DirectPath:    proto.String("/v/t62/x"),
+			MediaKey:      []byte{1},
+			FileLength:    proto.Uint64(4096),
+			Stickers: []*waE2E.StickerPackMessage_Sticker{
+				{FileName: proto.String("a.webp"), Emojis: []string{"🐱"}},
+				{FileName: proto.String("b.webp"), IsAnimated: proto.Bool(true)},
+			},
+		},
+	}))
+	if pack == nil || pack.ID != "PACK1" || pack.Name != "Cats" || pack.MessageID != "MSG1" || pack.FileLength != 4096 {
+		t.Fatalf("Unexpected pack: %+v", pack)
+	}
+	if len(pack.Stickers) != 2 || !pack.Stickers[1].IsAnimated {
+		t.Errorf("Unexpected stickers: %+v", pack.Stickers)
+	}
+
+	if ExtractStickerPack(commerceEvent(&waE2E.Message{Conversation: proto.String("hi")})) != nil {
+		t.Error("Expected nil for a text message")
+	}
+}
+
+func TestUnzipStickersStaysInDir(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range []string{"a.webp", "../../escape.webp", "readme.txt"} {
+		w, _ := zw.Create(name)
+		_, _ = w.Write([]byte("RIFF"))
+	}
+	_ = zw.Close()
+
+	dir := t.TempDir()
+	files, err := unzipStickers(buf.Bytes(), filepath.Join(dir, "pack"))
+	if err != nil {
+		t.Fatalf("unzipStickers: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 webp files, got %v", files)
+	}
+	for _, f := range files {
+		if filepath.Dir(f) != filepath.Join(dir, "pack") {
+			t.Errorf("File %s written outside the pack directory", f)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escape.webp")); err == nil {
+		t.Error("Archive entry escaped the pack directory")
+	}
+}
+
+func TestIsAnimatedWebP(t *testing.T) {
+	chunk := func(name string, size int) []byte {
+		b := append([]byte(name), byte(size), 0, 0, 0)
+		return append(b, make([]byte, size+size&1)...)
+	}
+	header := []byte("RIFF\x00\x00\x00\x00WEBP")
+
+	animated := append(append(append([]byte{}, header...), chunk("VP8X", 10)...), chunk("ANIM", 6)...)
+	still := append(append([]byte{}, header...), chunk("VP8 ", 3)...)
+
+	if !isAnimatedWebP(animated) {
+		t.Error("Expected animated WebP")
+	}
+	if isAnimatedWebP(still) || isAnimatedWebP([]byte("GIF89a")) {
+		t.Error("Expected still image")
+	}
+}
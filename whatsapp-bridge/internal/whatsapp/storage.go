@@ -0,0 +1,92 @@
+package whatsapp
+
+import (
+	"errors"
+
+	"go.mau.fi/whatsmeow/types/events"
+
+	"whatsapp-bridge/internal/database"
+	localTypes "whatsapp-bridge/internal/types"
+)
+
+// maxDeferredSyncs bounds the history syncs held in memory while the store
+// volume is low on space; past it the oldest is dropped
+const maxDeferredSyncs = 16
+
+// ErrLowOnSpace is returned for media downloads while the store volume is
+// short of space
+var ErrLowOnSpace = errors.New("media downloads are paused: the store volume is low on disk space")
+
+// StoragePolicy returns a copy of the current storage policy.
+func (c *Client) StoragePolicy() localTypes.StoragePolicy {
+	c.storageMu.RLock()
+	defer c.storageMu.RUnlock()
+
+	policy := c.storagePolicy
+	policy.ChatMetadataOnly = make(map[string]bool, len(c.storagePolicy.ChatMetadataOnly))
+	for jid, metadataOnly := range c.storagePolicy.ChatMetadataOnly {
+		policy.ChatMetadataOnly[jid] = metadataOnly
+	}
+	return policy
+}
+
+// SetStoragePolicy replaces the storage policy.
+func (c *Client) SetStoragePolicy(policy localTypes.StoragePolicy) {
+	c.storageMu.Lock()
+	defer c.storageMu.Unlock()
+	c.storagePolicy = policy
+}
+
+// MetadataOnly reports whether messages in chatJID are stored without their
+// content. Per-chat overrides take precedence over the global setting.
+func (c *Client) MetadataOnly(chatJID string) bool {
+	c.storageMu.RLock()
+	defer c.storageMu.RUnlock()
+
+	if metadataOnly, ok := c.storagePolicy.ChatMetadataOnly[chatJID]; ok {
+		return metadataOnly
+	}
+	return c.storagePolicy.MetadataOnly
+}
+
+// SetSpaceGuard makes media downloads and history sync ingestion pause while
+// low reports the store volume short of space. Call before connecting.
+func (c *Client) SetSpaceGuard(low func() bool) {
+	c.lowOnSpace = low
+}
+
+// spaceLow reports whether bulky writes are paused for lack of disk space
+func (c *Client) spaceLow() bool {
+	return c.lowOnSpace != nil && c.lowOnSpace()
+}
+
+// deferHistorySync holds a history sync back until ResumeHistorySync
+func (c *Client) deferHistorySync(historySync *events.HistorySync) {
+	c.deferredSyncMu.Lock()
+	defer c.deferredSyncMu.Unlock()
+
+	if len(c.deferredSyncs) == maxDeferredSyncs {
+		dropped := c.deferredSyncs[0]
+		c.deferredSyncs = c.deferredSyncs[1:]
+		c.logger.Warnf("Dropping deferred history sync (%v, %d conversations): %d more are waiting for disk space",
+			dropped.Data.GetSyncType(), len(dropped.Data.Conversations), maxDeferredSyncs)
+	}
+	c.deferredSyncs = append(c.deferredSyncs, historySync)
+}
+
+// ResumeHistorySync stores the history syncs held back while the store
+// volume was low on space, in the order they arrived. Call once space has
+// been freed; syncs still finding it low are held back again.
+func (c *Client) ResumeHistorySync(messageStore *database.MessageStore) {
+	c.deferredSyncMu.Lock()
+	deferred := c.deferredSyncs
+	c.deferredSyncs = nil
+	c.deferredSyncMu.Unlock()
+
+	if len(deferred) > 0 {
+		c.logger.Infof("Storing %d history syncs held back for disk space", len(deferred))
+	}
+	for _, historySync := range deferred {
+		c.HandleHistorySync(messageStore, historySync)
+	}
+}
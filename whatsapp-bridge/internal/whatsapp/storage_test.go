@@ -0,0 +1,64 @@
+package whatsapp
+
+import (
+	"testing"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/proto/waHistorySync"
+	"go.mau.fi/whatsmeow/types/events"
+	waLog "go.mau.fi/whatsmeow/util/log"
+	"google.golang.org/protobuf/proto"
+
+	"whatsapp-bridge/internal/database"
+)
+
+func TestDeferredHistorySync(t *testing.T) {
+	t.Chdir(t.TempDir())
+	store, err := database.NewMessageStore()
+	if err != nil {
+		t.Fatalf("NewMessageStore: %v", err)
+	}
+	defer store.Close()
+
+	c := &Client{logger: waLog.Noop}
+	low := true
+	c.SetSpaceGuard(func() bool { return low })
+
+	msg := historyMessage("A1", false, 1700000000)
+	msg.Message.Message = &waE2E.Message{Conversation: proto.String("hello")}
+	sync := &events.HistorySync{Data: &waHistorySync.HistorySync{
+		SyncType: waHistorySync.HistorySync_RECENT.Enum(),
+		Conversations: []*waHistorySync.Conversation{{
+			ID:       proto.String("120363000000000001@g.us"),
+			Name:     proto.String("Team"),
+			Messages: []*waHistorySync.HistorySyncMsg{msg},
+		}},
+	}}
+
+	// Held back while low, keeping only the newest maxDeferredSyncs
+	for i := 0; i < maxDeferredSyncs+2; i++ {
+		c.HandleHistorySync(store, sync)
+	}
+	if len(c.deferredSyncs) != maxDeferredSyncs {
+		t.Fatalf("deferred %d syncs, want %d", len(c.deferredSyncs), maxDeferredSyncs)
+	}
+	var stored int
+	if err := store.GetDB().QueryRow(`SELECT COUNT(*) FROM messages`).Scan(&stored); err != nil || stored != 0 {
+		t.Fatalf("stored %d messages while low (%v)", stored, err)
+	}
+
+	// Still low: resuming holds them back again
+	c.ResumeHistorySync(store)
+	if len(c.deferredSyncs) != maxDeferredSyncs {
+		t.Fatalf("deferred %d syncs after resuming while low, want %d", len(c.deferredSyncs), maxDeferredSyncs)
+	}
+
+	low = false
+	c.ResumeHistorySync(store)
+	if len(c.deferredSyncs) != 0 {
+		t.Errorf("%d syncs still deferred", len(c.deferredSyncs))
+	}
+	if err := store.GetDB().QueryRow(`SELECT COUNT(*) FROM messages WHERE id = 'A1'`).Scan(&stored); err != nil || stored != 1 {
+		t.Errorf("stored %d messages after recovery (%v), want 1", stored, err)
+	}
+}
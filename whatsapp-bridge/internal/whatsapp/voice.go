@@ -0,0 +1,108 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// voiceTranscodeTime bounds how long a voice note conversion may run
+	voiceTranscodeTime = 60 * time.Second
+
+	// voiceSampleRate is the rate audio is decoded at to measure it; plenty
+	// for 64 loudness bars
+	voiceSampleRate = 8000
+
+	// waveformBars is the length of a voice note waveform
+	waveformBars = 64
+)
+
+// SetVoiceTranscoding makes mp3, m4a and wav media go out as voice notes,
+// converted to Ogg Opus with ffmpeg, instead of as documents
+func (c *Client) SetVoiceTranscoding(enabled bool) {
+	c.voiceTranscode = enabled
+}
+
+// transcodeVoiceNote converts audio to the mono Ogg Opus WhatsApp plays as
+// a voice note, and measures its duration and waveform from the decoded
+// samples, so the note shows its real length and loudness.
+func transcodeVoiceNote(ctx context.Context, data []byte, ext string) (ogg []byte, seconds uint32, waveform []byte, err error) {
+	dir, err := os.MkdirTemp("", "voice-*")
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "in."+ext)
+	dst := filepath.Join(dir, "out.ogg")
+	pcm := filepath.Join(dir, "out.pcm")
+	if err := os.WriteFile(src, data, 0600); err != nil {
+		return nil, 0, nil, err
+	}
+
+	convertCtx, cancel := context.WithTimeout(ctx, voiceTranscodeTime)
+	defer cancel()
+	// One pass writes the voice note and raw samples to measure
+	cmd := exec.CommandContext(convertCtx, "ffmpeg", "-nostdin", "-loglevel", "error", "-i", src,
+		"-vn", "-map_metadata", "-1", "-ac", "1", "-ar", "48000", "-c:a", "libopus", "-b:a", "32k", "-application", "voip", dst,
+		"-vn", "-ac", "1", "-ar", fmt.Sprint(voiceSampleRate), "-f", "s16le", pcm)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, 0, nil, fmt.Errorf("ffmpeg is not installed (install ffmpeg to send %s voice notes)", ext)
+		}
+		return nil, 0, nil, fmt.Errorf("ffmpeg failed: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	samples, err := os.ReadFile(pcm)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	if ogg, err = os.ReadFile(dst); err != nil {
+		return nil, 0, nil, err
+	}
+	seconds, waveform = measureVoice(samples, voiceSampleRate)
+	return ogg, seconds, waveform, nil
+}
+
+// measureVoice returns the duration, rounded up to a whole second, and the
+// waveform of 16-bit little-endian mono samples: the loudness of 64 equal
+// slices, scaled 0-100 against the loudest
+func measureVoice(pcm []byte, rate int) (seconds uint32, waveform []byte) {
+	n := len(pcm) / 2
+	seconds = uint32(math.Ceil(float64(n) / float64(rate)))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	levels := make([]float64, waveformBars)
+	var loudest float64
+	for bar := range levels {
+		start, end := bar*n/waveformBars, (bar+1)*n/waveformBars
+		if end <= start {
+			continue
+		}
+		var sum float64
+		for i := start; i < end; i++ {
+			v := float64(int16(uint16(pcm[2*i]) | uint16(pcm[2*i+1])<<8))
+			sum += v * v
+		}
+		levels[bar] = math.Sqrt(sum / float64(end-start))
+		loudest = math.Max(loudest, levels[bar])
+	}
+
+	waveform = make([]byte, waveformBars)
+	if loudest == 0 {
+		return seconds, waveform
+	}
+	for bar, level := range levels {
+		waveform[bar] = byte(math.Round(level / loudest * 100))
+	}
+	return seconds, waveform
+}
@@ -0,0 +1,50 @@
+package whatsapp
+
+import (
+	"context"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func TestMeasureVoice(t *testing.T) {
+	// 2.5 seconds: silent first half, then a steady tone
+	const rate = 8000
+	samples := make([]int16, rate*5/2)
+	for i := len(samples) / 2; i < len(samples); i++ {
+		samples[i] = 12000
+		if i%2 == 0 {
+			samples[i] = -12000
+		}
+	}
+	pcm := make([]byte, 2*len(samples))
+	for i, v := range samples {
+		binary.LittleEndian.PutUint16(pcm[2*i:], uint16(v))
+	}
+
+	seconds, waveform := measureVoice(pcm, rate)
+	if seconds != 3 {
+		t.Errorf("seconds = %d, want 3", seconds)
+	}
+	if len(waveform) != waveformBars {
+		t.Fatalf("waveform has %d bars", len(waveform))
+	}
+	if waveform[0] != 0 || waveform[waveformBars/2-1] != 0 {
+		t.Errorf("silent bars = %v", waveform[:waveformBars/2])
+	}
+	if waveform[waveformBars/2] != 100 || waveform[waveformBars-1] != 100 {
+		t.Errorf("loud bars = %v", waveform[waveformBars/2:])
+	}
+
+	if seconds, waveform := measureVoice(nil, rate); seconds != 1 || len(waveform) != waveformBars || waveform[0] != 0 {
+		t.Errorf("empty audio = %d, %v", seconds, waveform)
+	}
+}
+
+func TestTranscodeVoiceNoteWithoutFFmpeg(t *testing.T) {
+	t.Setenv("PATH", "")
+	_, _, _, err := transcodeVoiceNote(context.Background(), []byte("ID3"), "mp3")
+	if err == nil || !strings.Contains(err.Error(), "ffmpeg is not installed") {
+		t.Errorf("err = %v, want a hint to install ffmpeg", err)
+	}
+}
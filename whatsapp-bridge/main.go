@@ -1,24 +1,50 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"go.mau.fi/whatsmeow/types/events"
 	waLog "go.mau.fi/whatsmeow/util/log"
 	"whatsapp-bridge/internal/api"
+	"whatsapp-bridge/internal/approval"
+	"whatsapp-bridge/internal/autoread"
+	"whatsapp-bridge/internal/businesshours"
+	"whatsapp-bridge/internal/commands"
 	"whatsapp-bridge/internal/config"
 	"whatsapp-bridge/internal/database"
+	"whatsapp-bridge/internal/diskspace"
+	"whatsapp-bridge/internal/doctor"
+	"whatsapp-bridge/internal/leader"
+	"whatsapp-bridge/internal/maintenance"
+	"whatsapp-bridge/internal/metrics"
+	"whatsapp-bridge/internal/msgrate"
+	"whatsapp-bridge/internal/ocr"
+	"whatsapp-bridge/internal/outbox"
+	"whatsapp-bridge/internal/phone"
+	"whatsapp-bridge/internal/recovery"
+	"whatsapp-bridge/internal/redact"
+	"whatsapp-bridge/internal/redis"
+	"whatsapp-bridge/internal/relay"
+	"whatsapp-bridge/internal/secrets"
+	"whatsapp-bridge/internal/transcribe"
+	"whatsapp-bridge/internal/types"
+	"whatsapp-bridge/internal/usage"
 	"whatsapp-bridge/internal/webhook"
 	"whatsapp-bridge/internal/whatsapp"
 )
 
 func main() {
 	// Set up logger
-	logger := waLog.Stdout("Client", "INFO", true)
+	logger := redact.Logger(waLog.Stdout("Client", "INFO", true))
 	logger.Infof("Starting WhatsApp client...")
 
 	// Security: Require API_KEY in production
@@ -36,6 +62,54 @@ func main() {
 
 	// Load configuration
 	cfg := config.NewConfig()
+	registerSecrets(cfg)
+
+	// API keys kept in a secret store are read now, so a wrong reference
+	// stops the bridge instead of locking every client out, and refreshed
+	// from then on so rotated keys apply without a restart
+	if err := resolveAPIKeys(); err != nil {
+		logger.Errorf("Failed to read API keys from the secret store: %v", err)
+		os.Exit(1)
+	}
+	recovery.Go("secret refresh", func() {
+		ticker := time.NewTicker(cfg.SecretsRefresh)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := secrets.Refresh(); err != nil {
+				logger.Warnf("Keeping previous secrets, refresh failed: %v", err)
+			}
+		}
+	})
+
+	api.SetLogger(logger.Sub("API"))
+	api.ConfigureRateLimit(cfg.RateLimit, cfg.RateLimitRoutes)
+
+	if err := phone.SetDefaultRegion(cfg.PhoneRegion); err != nil {
+		logger.Warnf("Ignoring PHONE_REGION %q: %v", cfg.PhoneRegion, err)
+	}
+
+	// Share rate limit counters with other API instances through Redis
+	var redisClient *redis.Client
+	if cfg.RedisURL != "" {
+		var err error
+		if redisClient, err = redis.Dial(cfg.RedisURL, logger.Sub("Redis")); err != nil {
+			logger.Warnf("Redis unavailable, using in-memory rate limiting: %v", err)
+		} else {
+			defer redisClient.Close()
+			api.UseSharedRateCounter(redisClient, logger.Sub("RateLimit"))
+			logger.Infof("Using Redis for shared rate limiting")
+		}
+	}
+
+	// Push the metrics served at /api/metrics to StatsD as well
+	if cfg.StatsDAddr != "" {
+		if statsd, err := metrics.NewStatsD(cfg.StatsDAddr, cfg.StatsDPrefix); err != nil {
+			logger.Warnf("StatsD disabled: %v", err)
+		} else {
+			statsd.Start(metrics.StatsDInterval)
+			logger.Infof("Pushing metrics to StatsD at %s every %v", cfg.StatsDAddr, metrics.StatsDInterval)
+		}
+	}
 
 	// Initialize database
 	messageStore, err := database.NewMessageStore()
@@ -45,6 +119,20 @@ func main() {
 	}
 	defer messageStore.Close()
 
+	loadCORSConfig(logger, messageStore)
+
+	// Check the configuration up front and report what needs fixing
+	doc := doctor.New(messageStore, cfg)
+	if redisClient != nil {
+		doc.SetRedis(redisClient)
+	}
+	runDoctor(logger, doc)
+
+	if cfg.ReadReplica {
+		runReadReplica(logger, cfg, messageStore, doc)
+		return
+	}
+
 	// Create WhatsApp client with config (Phase 4: HistorySyncConfig)
 	client, err := whatsapp.NewClientWithConfig(logger, cfg)
 	if err != nil {
@@ -52,20 +140,260 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Apply stored receipt policy (falls back to READ_RECEIPTS/DELIVERY_RECEIPTS)
+	var receiptPolicy types.ReceiptPolicy
+	if ok, err := messageStore.GetJSONSetting(database.SettingReceiptPolicy, &receiptPolicy); err != nil {
+		logger.Warnf("Failed to load receipt policy: %v", err)
+	} else if ok {
+		client.SetReceiptPolicy(receiptPolicy)
+	}
+
+	// Apply stored per-newsletter mute/persist/webhook settings
+	newsletterSettings := make(map[string]types.NewsletterSettings)
+	if ok, err := messageStore.GetJSONSetting(database.SettingNewsletters, &newsletterSettings); err != nil {
+		logger.Warnf("Failed to load newsletter settings: %v", err)
+	} else if ok {
+		client.SetNewsletterSettings(newsletterSettings)
+	}
+
+	// Apply stored metadata-only storage policy
+	var storagePolicy types.StoragePolicy
+	if ok, err := messageStore.GetJSONSetting(database.SettingStoragePolicy, &storagePolicy); err != nil {
+		logger.Warnf("Failed to load storage policy: %v", err)
+	} else if ok {
+		client.SetStoragePolicy(storagePolicy)
+	}
+
+	// Apply stored chat scope for history sync and real-time messages
+	var chatScope types.ChatScope
+	if ok, err := messageStore.GetJSONSetting(database.SettingChatScope, &chatScope); err != nil {
+		logger.Warnf("Failed to load chat scope: %v", err)
+	} else if ok {
+		client.SetChatScope(chatScope)
+	}
+
+	if cfg.VoiceNoteTranscode {
+		client.SetVoiceTranscoding(true)
+		logger.Infof("mp3, m4a and wav media are sent as voice notes")
+	}
+
+	// Transcribe incoming voice notes through an OpenAI-compatible endpoint
+	if cfg.TranscriptionURL != "" {
+		client.SetAnnotator(whatsapp.AnnotationTranscript, transcribe.NewClient(cfg.TranscriptionURL, cfg.TranscriptionAPIKey, cfg.TranscriptionModel))
+		logger.Infof("Voice note transcription enabled via %s", cfg.TranscriptionURL)
+	}
+
+	// Read text from incoming images (receipts, screenshots) through a vision endpoint
+	if cfg.OCRURL != "" {
+		client.SetAnnotator(whatsapp.AnnotationOCR, ocr.NewClient(cfg.OCRURL, cfg.OCRAPIKey, cfg.OCRModel))
+		logger.Infof("Image OCR enabled via %s", cfg.OCRURL)
+	}
+
+	// Download incoming media in the background as it arrives
+	if len(cfg.MediaAutoDownload) > 0 {
+		if err := client.SetAutoDownload(cfg.MediaAutoDownload, uint64(cfg.MediaAutoDownloadMaxMB)*1024*1024); err != nil {
+			logger.Warnf("Media auto-download disabled: %v", err)
+		} else {
+			logger.Infof("Media auto-download enabled for %s up to %d MB", strings.Join(cfg.MediaAutoDownload, ", "), cfg.MediaAutoDownloadMaxMB)
+		}
+	}
+
+	// Rolling per-chat and per-sender message rates for flood detection
+	rates := msgrate.NewTracker()
+	client.SetRateTracker(rates)
+
 	// Initialize webhook manager
 	webhookManager := webhook.NewManager(messageStore, logger)
+	webhookManager.SetPublicURL(cfg.PublicURL)
+	webhookManager.SetRateTracker(rates)
 	err = webhookManager.LoadWebhookConfigs()
 	if err != nil {
 		logger.Errorf("Failed to load webhook configs: %v", err)
 		os.Exit(1)
 	}
+	if err = webhookManager.LoadRoutingProfiles(); err != nil {
+		logger.Errorf("Failed to load routing profiles: %v", err)
+		os.Exit(1)
+	}
+
+	// Automatic read receipts for processed messages
+	autoReader := autoread.NewMarker(client, logger)
+	var autoReadConfig types.AutoReadConfig
+	if ok, err := messageStore.GetJSONSetting(database.SettingAutoRead, &autoReadConfig); err != nil {
+		logger.Warnf("Failed to load auto-read config: %v", err)
+	} else if ok {
+		if err := autoReader.SetConfig(autoReadConfig); err != nil {
+			logger.Warnf("Ignoring invalid auto-read config: %v", err)
+		}
+	}
+	webhookManager.SetDeliveryHook(autoReader.HandleWebhookDelivered)
+	webhookManager.SetMetadataOnly(client.MetadataOnly)
+
+	// Track server acks and receipts for outgoing messages
+	client.SetSendFailedHook(webhookManager.ProcessSendFailure)
+	client.SetSentHook(webhookManager.ProcessMessageSent)
+
+	// Temporary bans and rate limits are recorded for the health score, pause
+	// the outbox and raise account_restricted
+	client.SetRestrictedHook(func(r types.AccountRestriction) {
+		if err := messageStore.StoreAccountRestriction(r); err != nil {
+			logger.Warnf("Failed to record account restriction: %v", err)
+		}
+		webhookManager.ProcessAccountRestriction(r)
+	})
+
+	// Blocks and unblocks made on the phone are mirrored and raise contact_blocked/unblocked
+	client.SetBlocklistHook(webhookManager.ProcessBlocklistChange)
+
+	// Group subject, description, picture and settings changes raise group_*_changed
+	client.SetGroupChangeHook(webhookManager.ProcessGroupChange)
+
+	// Voice note transcripts and text read from images raise message_annotated
+	client.SetAnnotatedHook(webhookManager.ProcessMessageAnnotation)
+
+	// Phone pairing codes, including automatic renewals, raise pairing_code_generated
+	client.SetPairingCodeHook(webhookManager.ProcessPairingCode)
+
+	// Priority lanes for outgoing sends, persisted and rate limited
+	dispatcher := outbox.NewDispatcher(client, messageStore, logger)
+	var duplicateConfig types.DuplicateSendConfig
+	if ok, err := messageStore.GetJSONSetting(database.SettingDuplicateSend, &duplicateConfig); err != nil {
+		logger.Warnf("Failed to load duplicate send config: %v", err)
+	} else if ok {
+		if err := dispatcher.SetDuplicateConfig(duplicateConfig); err != nil {
+			logger.Warnf("Ignoring invalid duplicate send config: %v", err)
+		}
+	}
+	var loopBreakerConfig types.LoopBreakerConfig
+	if ok, err := messageStore.GetJSONSetting(database.SettingLoopBreaker, &loopBreakerConfig); err != nil {
+		logger.Warnf("Failed to load loop breaker config: %v", err)
+	} else if ok {
+		if err := dispatcher.LoopBreaker().SetConfig(loopBreakerConfig); err != nil {
+			logger.Warnf("Ignoring invalid loop breaker config: %v", err)
+		}
+	}
+	var sendRateConfig types.SendRateConfig
+	if ok, err := messageStore.GetJSONSetting(database.SettingSendRate, &sendRateConfig); err != nil {
+		logger.Warnf("Failed to load send rate config: %v", err)
+	} else if ok {
+		if err := dispatcher.SetRateConfig(sendRateConfig); err != nil {
+			logger.Warnf("Ignoring invalid send rate config: %v", err)
+		}
+	}
+	dispatcher.Start()
+
+	// Maintenance mode auto-responder
+	responder := maintenance.NewResponder(messageStore, dispatcher, logger)
+	var maintenanceConfig types.MaintenanceConfig
+	if ok, err := messageStore.GetJSONSetting(database.SettingMaintenance, &maintenanceConfig); err != nil {
+		logger.Warnf("Failed to load maintenance config: %v", err)
+	} else if ok {
+		if err := responder.SetConfig(maintenanceConfig); err != nil {
+			logger.Warnf("Ignoring invalid maintenance config: %v", err)
+		}
+	}
+
+	// Out-of-hours auto-responder
+	hoursResponder := businesshours.NewResponder(messageStore, dispatcher, logger)
+	var hoursConfig types.BusinessHoursConfig
+	if ok, err := messageStore.GetJSONSetting(database.SettingBusinessHours, &hoursConfig); err != nil {
+		logger.Warnf("Failed to load business hours config: %v", err)
+	} else if ok {
+		if err := hoursResponder.SetConfig(hoursConfig); err != nil {
+			logger.Warnf("Ignoring invalid business hours config: %v", err)
+		}
+	}
+
+	// Chat mirroring to and from peer bridges
+	relayer := relay.New(dispatcher, logger)
+	var relayConfig types.RelayConfig
+	if ok, err := messageStore.GetJSONSetting(database.SettingRelay, &relayConfig); err != nil {
+		logger.Warnf("Failed to load relay config: %v", err)
+	} else if ok {
+		if err := relayer.SetConfig(relayConfig); err != nil {
+			logger.Warnf("Ignoring invalid relay config: %v", err)
+		}
+	}
+
+	// Admin commands sent over WhatsApp
+	commandRouter := commands.NewRouter(client, dispatcher, responder, logger)
+	var commandConfig types.CommandConfig
+	if ok, err := messageStore.GetJSONSetting(database.SettingCommands, &commandConfig); err != nil {
+		logger.Warnf("Failed to load command config: %v", err)
+	} else if ok {
+		if err := commandRouter.SetConfig(commandConfig); err != nil {
+			logger.Warnf("Ignoring invalid command config: %v", err)
+		}
+	}
+
+	// Sends held until an approver accepts them
+	approvals := approval.New(messageStore, dispatcher, logger)
+	var approvalConfig types.ApprovalConfig
+	if ok, err := messageStore.GetJSONSetting(database.SettingApprovals, &approvalConfig); err != nil {
+		logger.Warnf("Failed to load approval config: %v", err)
+	} else if ok {
+		if err := approvals.SetConfig(approvalConfig); err != nil {
+			logger.Warnf("Ignoring invalid approval config: %v", err)
+		}
+	}
+	commandRouter.SetApprovals(approvals)
+
+	// Per-API-key usage accounting
+	meter := newUsageMeter(logger, messageStore)
 
 	// Setup event handling for messages and history sync
-	client.AddEventHandler(func(evt interface{}) {
+	// (a panic while handling one event is logged and the next event still runs,
+	// and the time each event takes counts towards the event lag)
+	client.AddEventHandler(recovery.EventHandler(client.TimeEvents(func(evt interface{}) {
 		switch v := evt.(type) {
 		case *events.Message:
-			// Process regular messages with webhook support
-			client.HandleMessage(messageStore, webhookManager, v)
+			if approvals.HandleReaction(v) {
+				// Approver deciding a held send: store only, answered by the queue
+				client.HandleMessage(messageStore, nil, v)
+				break
+			}
+			if commandRouter.HandleMessage(v) {
+				// Admin command: store only, answered by the router
+				client.HandleMessage(messageStore, nil, v)
+				break
+			}
+			// Chats outside the chat scope are neither stored nor
+			// webhooked, and get no auto-replies, auto-reads or relays
+			inScope := client.InChatScope(v.Info.Chat)
+			if responder.Active() {
+				// Maintenance: store only, no webhooks or auto-read rules
+				client.HandleMessage(messageStore, nil, v)
+				if inScope {
+					responder.HandleMessage(v)
+				}
+				break
+			}
+
+			// Process regular messages with webhook support; webhooks are
+			// sent in the background, so auto-read may hear of a delivery
+			// before it sees the message and matches the two up either way
+			chatName := client.HandleMessage(messageStore, webhookManager, v)
+			if !inScope {
+				break
+			}
+			autoReader.HandleMessage(v, chatName)
+			hoursResponder.HandleMessage(v)
+			relayer.HandleMessage(v)
+
+		case *events.Receipt:
+			client.HandleReceipt(messageStore, v)
+
+		case *events.MarkChatAsRead:
+			client.HandleMarkChatAsRead(messageStore, v)
+
+		case *events.Blocklist:
+			client.HandleBlocklist(messageStore, v)
+
+		case *events.GroupInfo:
+			client.HandleGroupInfo(messageStore, v)
+
+		case *events.Picture:
+			client.HandlePicture(messageStore, v)
 
 		case *events.HistorySync:
 			// Process history sync events with detailed logging
@@ -76,13 +404,21 @@ func main() {
 		case *events.Connected:
 			client.MarkConnected()
 			// Send presence to keep session active and receive real-time messages
-			if err := client.SetPresence("available"); err != nil {
+			// (stays unavailable when delivery receipts are suppressed)
+			if err := client.SendDefaultPresence(); err != nil {
 				logger.Warnf("Failed to set presence: %v", err)
 			} else {
-				logger.Infof("✓ Presence set to available")
+				logger.Infof("✓ Presence set (delivery receipts: %v)", client.ReceiptPolicy().DeliveryReceipts)
 			}
 			logger.Infof("✓ Connected to WhatsApp")
 
+			// Catch up on blocklist changes made while the bridge was offline
+			recovery.Go("blocklist sync", func() {
+				ctx, cancel := context.WithTimeout(context.Background(), whatsapp.DefaultTimeout)
+				defer cancel()
+				client.SyncBlocklist(ctx, messageStore)
+			})
+
 		case *events.LoggedOut:
 			logger.Warnf("✗ Device logged out - please scan QR code to log in again")
 
@@ -109,18 +445,55 @@ func main() {
 
 		case *events.StreamError:
 			logger.Errorf("✗ Stream error: %v", v.Code)
+			client.HandleStreamError(v)
+
+		case *events.TemporaryBan:
+			client.HandleTemporaryBan(v)
 
 		case *events.Disconnected:
 			client.MarkDisconnected()
 			logger.Warnf("⚠ Disconnected from WhatsApp - attempting reconnect")
 		}
+	})))
+
+	// Free space on the store volume: below the threshold media downloads
+	// and history sync pause, before SQLite writes start failing; history
+	// syncs received meanwhile are stored once space is freed
+	disk := diskspace.New("store", cfg.DiskFreeThresholdMB<<20, logger)
+	client.SetSpaceGuard(disk.Low)
+	disk.SetRecoveredHook(func(types.DiskStatus) {
+		recovery.Go("history sync", func() { client.ResumeHistorySync(messageStore) })
+	})
+	disk.Check()
+	recovery.Go("disk space", func() {
+		ticker := time.NewTicker(cfg.DiskCheckInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			disk.Check()
+		}
+	})
+
+	// Ping round trip and event lag, to tell a slow WhatsApp from a slow
+	// bridge; lag above the threshold raises event_lag_high
+	client.SetEventLagAlert(cfg.EventLagThreshold, webhookManager.ProcessEventLag)
+	recovery.Go("connection quality", func() {
+		ticker := time.NewTicker(cfg.QualitySampleInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			client.SampleConnectionQuality(context.Background())
+		}
 	})
 
-	// Connection watchdog: exit process if disconnected >3 min (forces container restart)
+	// Connection watchdog: exit process if disconnected >3 min (forces container restart).
+	// Not while temporarily banned: a restart would only reconnect into the ban,
+	// and the client reconnects by itself when the ban expires.
 	go func() {
 		ticker := time.NewTicker(30 * time.Second)
 		defer ticker.Stop()
 		for range ticker.C {
+			if r, ok := client.Restriction(); ok && r.Reason == whatsapp.RestrictionTemporaryBan {
+				continue
+			}
 			_, _, discAt, _ := client.ConnectionState()
 			if !discAt.IsZero() && time.Since(discAt) > 3*time.Minute {
 				logger.Errorf("WATCHDOG: disconnected for %v, exiting to force container restart", time.Since(discAt).Round(time.Second))
@@ -135,7 +508,7 @@ func main() {
 		defer ticker.Stop()
 		for range ticker.C {
 			if client.IsConnected() {
-				if err := client.SetPresence("available"); err != nil {
+				if err := client.SendDefaultPresence(); err != nil {
 					logger.Debugf("Presence ping failed: %v", err)
 				} else {
 					logger.Debugf("Presence ping sent")
@@ -145,18 +518,72 @@ func main() {
 	}()
 
 	// Start REST API server with webhook support (BEFORE connecting to avoid blocking)
-	server := api.NewServer(client, messageStore, webhookManager, cfg.APIPort)
-	server.Start()
-	fmt.Println("✓ REST API server started on port " + fmt.Sprintf("%d", cfg.APIPort))
+	server := api.NewServer(client, messageStore, webhookManager, autoReader, dispatcher, responder, hoursResponder, meter, relayer, cfg.APIPort)
+	server.SetRequestTimeout(cfg.RequestTimeout)
+	server.SetDisplayTimezone(cfg.DisplayTimezone)
+	server.SetDoctor(doc)
+	server.SetCommandRouter(commandRouter)
+	server.SetApprovals(approvals)
+	server.SetRateTracker(rates)
+	server.SetDiskMonitor(disk)
+	server.SetCalendarFeedToken(cfg.CalendarFeedToken)
+	if cfg.DevMode {
+		logger.Warnf("DEV_MODE is on: fault injection endpoints are served under /api/admin/chaos/")
+		server.SetDevMode(true)
+	}
+	configureListener(server, cfg)
+	if err := server.Start(); err != nil {
+		logger.Errorf("Failed to start REST API server: %v", err)
+		os.Exit(1)
+	}
+	fmt.Println("✓ REST API server started on " + server.Address())
 
 	// Connect to WhatsApp in background (non-blocking so server can start)
-	go func() {
-		if err := client.Connect(); err != nil {
-			logger.Errorf("Failed to connect to WhatsApp: %v", err)
-		} else {
-			fmt.Println("\n✓ Connected to WhatsApp!")
+	startSession := func() {
+		webhookManager.ResumeDeliveries()
+		// Only the session holder raises disk_space_low, including for a
+		// volume already low at startup
+		disk.SetLowHook(webhookManager.ProcessDiskSpaceLow)
+		if status, ok := disk.Status(); ok && status.Low {
+			webhookManager.ProcessDiskSpaceLow(status)
 		}
-	}()
+		client.StartAckMonitor(messageStore, cfg.SendAckTimeout)
+		client.StartAutoDownload(messageStore)
+		if cfg.HistoryArchiveMonths > 0 {
+			go runHistoryArchive(logger, messageStore, int(cfg.HistoryArchiveMonths))
+		}
+		if cfg.ExpireDisappearing {
+			go runDisappearingCleanup(logger, messageStore)
+		}
+		go func() {
+			if err := client.Connect(); err != nil {
+				logger.Errorf("Failed to connect to WhatsApp: %v", err)
+			} else {
+				fmt.Println("\n✓ Connected to WhatsApp!")
+			}
+		}()
+	}
+
+	// Hot standby: only the lease holder connects; a standby serves the API
+	// and takes over the session when the holder stops renewing
+	var elector *leader.Elector
+	if cfg.LeaderElection {
+		lease := leader.NewStoreLease(messageStore)
+		if redisClient != nil {
+			lease = leader.NewRedisLease(redisClient)
+		}
+		elector = leader.NewElector(lease, cfg.InstanceID, logger)
+		logger.Infof("Leader election enabled as %s; waiting for the lease before connecting", cfg.InstanceID)
+		go elector.Run(startSession, func() {
+			// Exit so the supervisor restarts this instance as a clean standby;
+			// the new leader owns the session from here on
+			logger.Errorf("Lost the leader lease, disconnecting and exiting")
+			client.Disconnect()
+			os.Exit(1)
+		})
+	} else {
+		startSession()
+	}
 
 	// Create a channel to keep the main goroutine alive
 	exitChan := make(chan os.Signal, 1)
@@ -165,7 +592,7 @@ func main() {
 	fmt.Println("REST server is running. Press Ctrl+C to disconnect and exit.")
 	fmt.Println("=" + fmt.Sprintf("%150s", ""))
 	fmt.Println("Monitor sync progress:")
-	fmt.Println("  curl -H 'X-API-Key: " + apiKey + "' http://localhost:" + fmt.Sprintf("%d", cfg.APIPort) + "/api/sync-status")
+	fmt.Println("  curl -H \"X-API-Key: $API_KEY\" " + curlTarget(cfg, "/api/sync-status"))
 	fmt.Println("=" + fmt.Sprintf("%150s", ""))
 
 	// Periodically log sync stats
@@ -184,4 +611,171 @@ func main() {
 	fmt.Println("Disconnecting...")
 	// Disconnect client
 	client.Disconnect()
+
+	// Hand the lease to the standby right away instead of letting it expire
+	if elector != nil {
+		elector.Stop()
+	}
+}
+
+// newUsageMeter creates the usage meter with the stored quotas applied
+func newUsageMeter(logger waLog.Logger, messageStore *database.MessageStore) *usage.Meter {
+	meter := usage.NewMeter(messageStore, logger)
+	var usageQuotas types.UsageQuotas
+	if ok, err := messageStore.GetJSONSetting(database.SettingUsageQuotas, &usageQuotas); err != nil {
+		logger.Warnf("Failed to load usage quotas: %v", err)
+	} else if ok {
+		if err := meter.SetQuotas(usageQuotas); err != nil {
+			logger.Warnf("Ignoring invalid usage quotas: %v", err)
+		}
+	}
+	return meter
+}
+
+// runHistoryArchive moves messages older than the given number of months
+// into the monthly archive tables at startup and then daily
+func runHistoryArchive(logger waLog.Logger, messageStore *database.MessageStore, months int) {
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+	for {
+		cutoff := time.Now().AddDate(0, -months, 0)
+		if moved, err := messageStore.ArchiveMessages(cutoff); err != nil {
+			logger.Warnf("History archive failed: %v", err)
+		} else if moved > 0 {
+			logger.Infof("Archived %d messages from before %s", moved, cutoff.Format("January 2006"))
+		}
+		<-ticker.C
+	}
+}
+
+// runDisappearingCleanup removes messages whose disappearing timer has run
+// out, at startup and then hourly
+func runDisappearingCleanup(logger waLog.Logger, messageStore *database.MessageStore) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		if removed, err := messageStore.DeleteExpiredMessages(time.Now()); err != nil {
+			logger.Warnf("Disappearing message cleanup failed: %v", err)
+		} else if removed > 0 {
+			logger.Infof("Removed %d disappeared messages", removed)
+		}
+		<-ticker.C
+	}
+}
+
+// runDoctor runs the startup configuration checks and logs every problem with
+// its remedy. Problems are reported, not fatal; GET /api/admin/doctor repeats
+// the checks.
+func runDoctor(logger waLog.Logger, doc *doctor.Doctor) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	report := doc.Run(ctx)
+	for _, check := range report.Checks {
+		line := check.Name + ": " + check.Message
+		if check.Remedy != "" {
+			line += " (fix: " + check.Remedy + ")"
+		}
+		switch check.Status {
+		case doctor.StatusFail:
+			logger.Errorf("✗ [doctor] %s", line)
+		case doctor.StatusWarn:
+			logger.Warnf("⚠ [doctor] %s", line)
+		}
+	}
+	if report.Healthy && report.Warnings == 0 {
+		logger.Infof("✓ Configuration checks passed")
+	} else {
+		logger.Warnf("Configuration checks: %d failed, %d warnings; see GET /api/admin/doctor", report.Failures, report.Warnings)
+	}
+}
+
+// loadCORSConfig applies the stored CORS configuration, if any, in place of
+// the defaults from the environment
+func loadCORSConfig(logger waLog.Logger, messageStore *database.MessageStore) {
+	var corsConfig types.CORSConfig
+	if ok, err := messageStore.GetJSONSetting(database.SettingCORS, &corsConfig); err != nil {
+		logger.Warnf("Failed to load CORS config: %v", err)
+	} else if ok {
+		if err := api.SetCORSConfig(corsConfig); err != nil {
+			logger.Warnf("Ignoring invalid CORS config: %v", err)
+		}
+	}
+}
+
+// runReadReplica serves read endpoints from the shared database without a
+// WhatsApp client, so reporting traffic stays off the connected bridge
+func runReadReplica(logger waLog.Logger, cfg *config.Config, messageStore *database.MessageStore, doc *doctor.Doctor) {
+	logger.Infof("Starting in read replica mode (no WhatsApp connection)")
+
+	server := api.NewReadReplicaServer(messageStore, newUsageMeter(logger, messageStore), cfg.APIPort)
+	server.SetDisplayTimezone(cfg.DisplayTimezone)
+	server.SetCalendarFeedToken(cfg.CalendarFeedToken)
+	server.SetDoctor(doc)
+	configureListener(server, cfg)
+	if err := server.Start(); err != nil {
+		logger.Errorf("Failed to start read replica API server: %v", err)
+		os.Exit(1)
+	}
+	fmt.Println("✓ Read replica API server started on " + server.Address())
+
+	exitChan := make(chan os.Signal, 1)
+	signal.Notify(exitChan, syscall.SIGINT, syscall.SIGTERM)
+	<-exitChan
+
+	fmt.Println("Shutting down read replica...")
+}
+
+// configureListener applies API_BIND or API_SOCKET to the API server
+func configureListener(server *api.Server, cfg *config.Config) {
+	if cfg.APISocket != "" {
+		server.SetUnixSocket(cfg.APISocket, cfg.APISocketMode)
+		return
+	}
+	server.SetListenAddress(cfg.APIBind)
+}
+
+// curlTarget returns the curl arguments that reach path on the local API
+func curlTarget(cfg *config.Config, path string) string {
+	if cfg.APISocket != "" {
+		return "--unix-socket " + cfg.APISocket + " http://localhost" + path
+	}
+	host := cfg.APIBind
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, strconv.Itoa(cfg.APIPort)) + path
+}
+
+// resolveAPIKeys reads the API keys given as secret store references
+func resolveAPIKeys() error {
+	keys := []string{os.Getenv("API_KEY")}
+	for _, pair := range strings.Split(os.Getenv("API_KEYS"), ",") {
+		if _, key, ok := strings.Cut(strings.TrimSpace(pair), ":"); ok {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range keys {
+		if _, err := secrets.Resolve(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// registerSecrets keeps the credentials given in the environment out of the
+// logs. Webhook secrets, relay keys and pairing codes are registered where
+// they are loaded or generated.
+func registerSecrets(cfg *config.Config) {
+	redact.Register(os.Getenv("API_KEY"), cfg.TranscriptionAPIKey, cfg.OCRAPIKey, cfg.CalendarFeedToken)
+	for _, pair := range strings.Split(os.Getenv("API_KEYS"), ",") {
+		if _, key, ok := strings.Cut(pair, ":"); ok {
+			redact.Register(strings.TrimSpace(key))
+		}
+	}
+	if u, err := url.Parse(cfg.RedisURL); err == nil {
+		if password, ok := u.User.Password(); ok {
+			redact.Register(password)
+		}
+	}
 }